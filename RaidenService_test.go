@@ -16,7 +16,9 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
 )
 
 func init() {
@@ -31,7 +33,7 @@ func TestPing(t *testing.T) {
 	defer r1.Stop()
 	defer r2.Stop()
 	ping := encoding.NewPing(32)
-	ping.Sign(r1.PrivateKey, ping)
+	ping.Sign(r1.Signer, ping)
 	err := r1.SendAndWait(r2.NodeAddress, ping, time.Second*10)
 	if err != nil {
 		t.Error(err)
@@ -59,7 +61,7 @@ func TestRestart(t *testing.T) {
 		}
 		log.Info(fmt.Sprintf("%d r2 create success", i))
 		ping := encoding.NewPing(32)
-		ping.Sign(r1.PrivateKey, ping)
+		ping.Sign(r1.Signer, ping)
 		err := r1.SendAndWait(r2.NodeAddress, ping, time.Second*10)
 		if err != nil {
 			t.Error(err)
@@ -116,7 +118,7 @@ func TestRestart3(t *testing.T) {
 		}
 		log.Info(fmt.Sprintf("%d r2 start success", i))
 		ping := encoding.NewPing(int64(i + 1))
-		err = ping.Sign(r1.PrivateKey, ping)
+		err = ping.Sign(r1.Signer, ping)
 		if err != nil {
 			t.Error(err)
 			return
@@ -136,3 +138,34 @@ func TestRestart3(t *testing.T) {
 	}
 
 }
+
+func TestResolveTokenKey(t *testing.T) {
+	r1, r2, r3 := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	t1, t2, t3 := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	registered := map[models.RegistryToken]bool{
+		{Registry: r1, Token: t1}: true,
+		{Registry: r2, Token: t1}: true,
+		{Registry: r2, Token: t2}: true,
+		{Registry: r3, Token: t2}: true,
+	}
+	registries := []common.Address{r1, r2, r3}
+	resolve := func(registry, token common.Address) models.RegistryToken {
+		return resolveTokenKey(registries, registry, token, func(key models.RegistryToken) bool {
+			return registered[key]
+		})
+	}
+	cases := []struct {
+		registry, token common.Address
+		want            models.RegistryToken
+	}{
+		{utils.EmptyAddress, t1, models.RegistryToken{Registry: r1, Token: t1}}, //on the default registry
+		{utils.EmptyAddress, t2, models.RegistryToken{Registry: r2, Token: t2}}, //first registry having it
+		{utils.EmptyAddress, t3, models.RegistryToken{Registry: r1, Token: t3}}, //unknown token
+		{r2, t1, models.RegistryToken{Registry: r2, Token: t1}},
+	}
+	for _, c := range cases {
+		if got := resolve(c.registry, c.token); got != c.want {
+			t.Errorf("resolve %s %s got %s, want %s", c.registry.String(), c.token.String(), got, c.want)
+		}
+	}
+}
@@ -85,6 +85,19 @@ func (am *AccountManager) GetPrivateKey(addr common.Address, password string) (p
 
 //PromptAccount get account private key by input password or password stored in file
 func PromptAccount(adviceAddress common.Address, keystorePath, passwordfile string) (addr common.Address, keybin []byte, err error) {
+	addr, password, err := PromptAccountPassword(adviceAddress, keystorePath, passwordfile)
+	if err != nil {
+		return
+	}
+	keybin, err = NewAccountManager(keystorePath).GetPrivateKey(addr, password)
+	return
+}
+
+/*
+PromptAccountPassword select an account and get its password by input or password stored in file,
+the password is checked by decrypting keystore file.
+*/
+func PromptAccountPassword(adviceAddress common.Address, keystorePath, passwordfile string) (addr common.Address, password string, err error) {
 	am := NewAccountManager(keystorePath)
 	if len(am.Accounts) == 0 {
 		err = fmt.Errorf("No Ethereum accounts found in the directory %s", keystorePath)
@@ -125,9 +138,9 @@ func PromptAccount(adviceAddress common.Address, keystorePath, passwordfile stri
 		if err != nil {
 			data = []byte(passwordfile)
 		}
-		password := string(data)
+		password = string(data)
 		log.Trace(fmt.Sprintf("password is %s", password))
-		keybin, err = am.GetPrivateKey(addr, password)
+		_, err = am.GetPrivateKey(addr, password)
 		if err != nil {
 			err = fmt.Errorf("Incorrect password for %s in file. Aborting ... %s", addr.String(), err)
 			return
@@ -135,8 +148,8 @@ func PromptAccount(adviceAddress common.Address, keystorePath, passwordfile stri
 	} else {
 		for i := 0; i < 3; i++ {
 			//retries three times
-			password := getpass.Prompt("Enter the password to unlock:")
-			keybin, err = am.GetPrivateKey(addr, password)
+			password = getpass.Prompt("Enter the password to unlock:")
+			_, err = am.GetPrivateKey(addr, password)
 			if err != nil && i == 3 {
 				log.Error(fmt.Sprintf("Exhausted passphrase unlock attempts for %s. Aborting ...", addr))
 				return
@@ -5,6 +5,8 @@ import (
 
 	"sync"
 
+	"sync/atomic"
+
 	"fmt"
 
 	"context"
@@ -23,7 +25,7 @@ type AlarmCallback func(blockNumber int64) error
 //AlarmTask notify when a block is mined.
 type AlarmTask struct {
 	client          *helper.SafeEthClient
-	LastBlockNumber int64
+	lastBlockNumber int64 //read by other goroutines, use atomic
 	quitChan        chan struct{}
 	stopped         bool
 	waitTime        time.Duration
@@ -36,7 +38,7 @@ func NewAlarmTask(client *helper.SafeEthClient) *AlarmTask {
 	t := &AlarmTask{
 		client:          client,
 		waitTime:        time.Second,
-		LastBlockNumber: -1,
+		lastBlockNumber: -1,
 		quitChan:        make(chan struct{}), //sync channel
 	}
 	return t
@@ -70,7 +72,7 @@ func (at *AlarmTask) RemoveCallback(cb AlarmCallback) {
 }
 
 func (at *AlarmTask) run() {
-	log.Debug(fmt.Sprintf("starting block number blocknubmer=%d", at.LastBlockNumber))
+	log.Debug(fmt.Sprintf("starting block number blocknubmer=%d", at.LastBlockNumber()))
 	defer rpanic.PanicRecover("alarm task")
 	for {
 		if at.stopped {
@@ -85,7 +87,7 @@ func (at *AlarmTask) run() {
 }
 
 func (at *AlarmTask) waitNewBlock() error {
-	currentBlock := at.LastBlockNumber
+	currentBlock := at.LastBlockNumber()
 	headerCh := make(chan *types.Header, 1)
 	//get the lastest number imediatelly
 	h, err := at.client.HeaderByNumber(context.Background(), nil)
@@ -113,7 +115,7 @@ func (at *AlarmTask) waitNewBlock() error {
 				log.Warn(fmt.Sprintf("alarm missed %d blocks", h.Number.Int64()-currentBlock))
 			}
 			currentBlock = h.Number.Int64()
-			at.LastBlockNumber = currentBlock
+			atomic.StoreInt64(&at.lastBlockNumber, currentBlock)
 			if currentBlock%10 == 0 {
 				log.Trace(fmt.Sprintf("new block :%d", currentBlock))
 			}
@@ -144,13 +146,18 @@ func (at *AlarmTask) waitNewBlock() error {
 	}
 }
 
+//LastBlockNumber is the latest block seen, -1 before Start, safe to call from any goroutine
+func (at *AlarmTask) LastBlockNumber() int64 {
+	return atomic.LoadInt64(&at.lastBlockNumber)
+}
+
 //Start this task
 func (at *AlarmTask) Start() error {
 	h, err := at.client.HeaderByNumber(context.Background(), nil)
 	if err != nil {
 		return fmt.Errorf("HeaderByNumber error %s", err)
 	}
-	at.LastBlockNumber = h.Number.Int64()
+	atomic.StoreInt64(&at.lastBlockNumber, h.Number.Int64())
 	go at.run()
 	return nil
 }
@@ -0,0 +1,207 @@
+package blockchain
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+EventIndexer saves every decoded contract event for query,
+the same event may be saved more than once, when it's received again after catching up.
+*/
+type EventIndexer interface {
+	SaveContractEvent(e *models.ContractEvent) error
+	IsContractEventHistoryIndexed() bool
+	MarkContractEventHistoryIndexed() error
+	GetContractEventHistoryNextBlock() int64
+	SaveContractEventHistoryNextBlock(blockNumber int64) error
+}
+
+func bigString(n *big.Int) string {
+	if n == nil {
+		return "0"
+	}
+	return n.String()
+}
+
+//contractEvent converts a decoded event to ContractEvent, nil if `ev` is not an event of our contracts
+func contractEvent(ev interface{}) (e *models.ContractEvent) {
+	switch ev2 := ev.(type) {
+	case *contracts.TokenNetworkRegistryTokenNetworkCreated:
+		e = models.NewContractEvent(params.NameTokenNetworkCreated, &ev2.Raw)
+		e.Data["token_address"] = ev2.Token_address.String()
+		e.Data["token_network_address"] = ev2.Token_network_address.String()
+	case *contracts.TokenNetworkChannelOpened:
+		e = models.NewContractEvent(params.NameChannelOpened, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Participant1, ev2.Participant2}
+		e.Data["settle_timeout"] = bigString(ev2.Settle_timeout)
+	case *contracts.TokenNetworkChannelOpenedAndDeposit:
+		e = models.NewContractEvent(params.NameChannelOpenedAndDeposit, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Participant1, ev2.Participant2}
+		e.Data["settle_timeout"] = bigString(ev2.Settle_timeout)
+		e.Data["participant1_deposit"] = bigString(ev2.Participant1_deposit)
+	case *contracts.TokenNetworkChannelNewDeposit:
+		e = models.NewContractEvent(params.NameChannelNewDeposit, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Participant}
+		e.Data["total_deposit"] = bigString(ev2.Total_deposit)
+	case *contracts.TokenNetworkChannelClosed:
+		e = models.NewContractEvent(params.NameChannelClosed, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Closing_participant}
+		e.Data["locksroot"] = common.Hash(ev2.Locksroot).String()
+		e.Data["transferred_amount"] = bigString(ev2.Transferred_amount)
+	case *contracts.TokenNetworkChannelSettled:
+		e = models.NewContractEvent(params.NameChannelSettled, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Data["participant1_amount"] = bigString(ev2.Participant1_amount)
+		e.Data["participant2_amount"] = bigString(ev2.Participant2_amount)
+	case *contracts.TokenNetworkChannelCooperativeSettled:
+		e = models.NewContractEvent(params.NameChannelCooperativeSettled, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Data["participant1_amount"] = bigString(ev2.Participant1_amount)
+		e.Data["participant2_amount"] = bigString(ev2.Participant2_amount)
+	case *contracts.TokenNetworkChannelWithdraw:
+		e = models.NewContractEvent(params.NameChannelWithdraw, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Participant1, ev2.Participant2}
+		e.Data["participant1_balance"] = bigString(ev2.Participant1_balance)
+		e.Data["participant2_balance"] = bigString(ev2.Participant2_balance)
+	case *contracts.TokenNetworkChannelUnlocked:
+		e = models.NewContractEvent(params.NameChannelUnlocked, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Payer_participant}
+		e.Data["lockhash"] = common.Hash(ev2.Lockhash).String()
+		e.Data["transferred_amount"] = bigString(ev2.Transferred_amount)
+	case *contracts.TokenNetworkBalanceProofUpdated:
+		e = models.NewContractEvent(params.NameBalanceProofUpdated, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Participant}
+		e.Data["locksroot"] = common.Hash(ev2.Locksroot).String()
+		e.Data["transferred_amount"] = bigString(ev2.Transferred_amount)
+	case *contracts.TokenNetworkChannelPunished:
+		e = models.NewContractEvent(params.NameChannelPunished, &ev2.Raw)
+		e.ChannelIdentifier = ev2.Channel_identifier
+		e.Participants = []common.Address{ev2.Beneficiary}
+	case *contracts.SecretRegistrySecretRevealed:
+		e = models.NewContractEvent(params.NameSecretRevealed, &ev2.Raw)
+		e.Data["secrethash"] = common.Hash(ev2.Secrethash).String()
+	}
+	return
+}
+
+//indexEvent saves `ev` by Indexer, errors are only logged, index never stops events from being processed
+func (be *Events) indexEvent(ev interface{}) {
+	if be.Indexer == nil {
+		return
+	}
+	e := contractEvent(ev)
+	if e == nil {
+		log.Error(fmt.Sprintf("index unknown event %T", ev))
+		return
+	}
+	err := be.Indexer.SaveContractEvent(e)
+	if err != nil {
+		log.Error(fmt.Sprintf("index event %s err %s", e.Key, err))
+	}
+}
+
+/*
+indexHistory indexes all events since the contracts are deployed until `lastBlockNumber`, it runs in background
+once after the index is added, events after `lastBlockNumber` are indexed by catching up already.
+events are saved block by block, the next block is saved too, so it resumes from there after restart.
+`tokenNetworks` are known ones, TokenNetworks is changed by the event loop, it's not read here.
+*/
+func (be *Events) indexHistory(lastBlockNumber int64, tokenNetworks []common.Address) {
+	defer rpanic.PanicRecover("index contract events history")
+	from := be.Indexer.GetContractEventHistoryNextBlock()
+	if from <= lastBlockNumber {
+		log.Info(fmt.Sprintf("index contract events from block %d to %d", from, lastBlockNumber))
+		events, err := be.getHistoryEvents(from, tokenNetworks)
+		if err != nil {
+			log.Error(fmt.Sprintf("index contract events history err %s, try again next start", err))
+			return
+		}
+		var es []*models.ContractEvent
+		for _, ev := range events {
+			e := contractEvent(ev)
+			if e != nil && e.BlockNumber <= lastBlockNumber {
+				es = append(es, e)
+			}
+		}
+		sort.SliceStable(es, func(i, j int) bool {
+			return es[i].BlockNumber < es[j].BlockNumber
+		})
+		for i, e := range es {
+			select {
+			case <-be.quitChan:
+				return
+			default:
+			}
+			if i > 0 && e.BlockNumber != es[i-1].BlockNumber {
+				//all events of the previous block are indexed
+				err = be.Indexer.SaveContractEventHistoryNextBlock(e.BlockNumber)
+				if err != nil {
+					log.Error(fmt.Sprintf("SaveContractEventHistoryNextBlock err %s", err))
+					return
+				}
+			}
+			err = be.Indexer.SaveContractEvent(e)
+			if err != nil {
+				log.Error(fmt.Sprintf("index event %s err %s, try again next start", e.Key, err))
+				return
+			}
+		}
+	}
+	err := be.Indexer.SaveContractEventHistoryNextBlock(lastBlockNumber + 1)
+	if err == nil {
+		err = be.Indexer.MarkContractEventHistoryIndexed()
+	}
+	if err != nil {
+		log.Error(fmt.Sprintf("MarkContractEventHistoryIndexed err %s", err))
+		return
+	}
+	log.Info(fmt.Sprintf("contract events before block %d indexed", lastBlockNumber+1))
+}
+
+//getHistoryEvents is getAllEventsSince for indexHistory, it doesn't change TokenNetworks
+func (be *Events) getHistoryEvents(fromBlock int64, tokenNetworks []common.Address) (events []interface{}, err error) {
+	created, err := be.getTokenNetworksCreated(fromBlock)
+	if err != nil {
+		return
+	}
+	all := make(map[common.Address]bool)
+	for _, tokenNetwork := range tokenNetworks {
+		all[tokenNetwork] = true
+	}
+	for _, e := range created {
+		events = append(events, e)
+		all[e.Token_network_address] = true
+	}
+	revealed, err := be.GetAllSecretRevealed(fromBlock)
+	if err != nil {
+		return
+	}
+	for _, e := range revealed {
+		events = append(events, e)
+	}
+	for tokenNetwork := range all {
+		var es []interface{}
+		es, err = be.getTokenNetworkEventsSince(fromBlock, tokenNetwork)
+		if err != nil {
+			return
+		}
+		events = append(events, es...)
+	}
+	return
+}
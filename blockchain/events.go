@@ -3,6 +3,7 @@ package blockchain
 import (
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
@@ -23,35 +24,37 @@ import (
 Events handles all contract events from blockchain
 */
 type Events struct {
-	client                *helper.SafeEthClient
-	lock                  sync.RWMutex
-	LogChannelMap         map[string]chan types.Log
-	RegistryAddress       common.Address //this address is unique
-	SecretRegistryAddress common.Address //get from db or from blockchain
-	Subscribes            map[string]ethereum.Subscription
-	StateChangeChannel    chan transfer.StateChange
+	client             *helper.SafeEthClient
+	lock               sync.RWMutex
+	LogChannelMap      map[string]chan types.Log
+	Registries         map[common.Address]bool //all registries attached
+	SecretRegistries   map[common.Address]bool //secret registries of all registries, get from db or from blockchain
+	Subscribes         map[string]ethereum.Subscription
+	StateChangeChannel chan transfer.StateChange
 	//启动过程中先把收到事件暂存在这个通道中,等启动完毕以后在保存到StateChangeChannel,保证事件被顺序处理.
 	startupStateChangeChannel chan mediatedtransfer.ContractStateChange
 	stopped                   bool // has stopped?
 	quitChan                  chan struct{}
 	TokenNetworks             map[common.Address]bool
 	historyEventsGot          bool
+	historyEventsSent         int32        //atomic, 1 after all history events have been sent
+	Indexer                   EventIndexer //saves every event got if not nil
 }
 
-//NewBlockChainEvents create BlockChainEvents
-func NewBlockChainEvents(client *helper.SafeEthClient, registryAddress, secretRegistryAddress common.Address, token2TokenNetwork map[common.Address]common.Address) *Events {
+//NewBlockChainEvents create BlockChainEvents, more registries can be added by AddRegistry before start
+func NewBlockChainEvents(client *helper.SafeEthClient, registryAddress, secretRegistryAddress common.Address, tokenNetworks []common.Address) *Events {
 	be := &Events{
 		client:                    client,
 		LogChannelMap:             make(map[string]chan types.Log),
 		Subscribes:                make(map[string]ethereum.Subscription),
-		RegistryAddress:           registryAddress,
-		SecretRegistryAddress:     secretRegistryAddress,
+		Registries:                map[common.Address]bool{registryAddress: true},
+		SecretRegistries:          map[common.Address]bool{secretRegistryAddress: true},
 		quitChan:                  make(chan struct{}),
 		TokenNetworks:             make(map[common.Address]bool),
 		startupStateChangeChannel: make(chan mediatedtransfer.ContractStateChange, 100),
 		StateChangeChannel:        make(chan transfer.StateChange, 10),
 	}
-	for _, tn := range token2TokenNetwork {
+	for _, tn := range tokenNetworks {
 		be.TokenNetworks[tn] = true
 	}
 	for name := range eventAbiMap {
@@ -60,6 +63,23 @@ func NewBlockChainEvents(client *helper.SafeEthClient, registryAddress, secretRe
 	return be
 }
 
+//AddRegistry listens to events of tokens registered on `registryAddress` too, must be called before Start
+func (be *Events) AddRegistry(registryAddress, secretRegistryAddress common.Address) {
+	be.Registries[registryAddress] = true
+	be.SecretRegistries[secretRegistryAddress] = true
+}
+
+//subscribeAddress returns the contract to subscribe events of, empty if there are several, events of others are ignored when received
+func subscribeAddress(contracts map[common.Address]bool) common.Address {
+	if len(contracts) != 1 {
+		return utils.EmptyAddress
+	}
+	for addr := range contracts {
+		return addr
+	}
+	return utils.EmptyAddress
+}
+
 var eventAbiMap = map[string]string{
 	params.NameTokenNetworkCreated:       contracts.TokenNetworkRegistryABI,
 	params.NameChannelOpened:             contracts.TokenNetworkABI,
@@ -98,10 +118,10 @@ func (be *Events) installEventListener() (err error) {
 	}()
 	for name := range eventAbiMap {
 		contractAddr := utils.EmptyAddress
-		if name == params.NameTokenNetworkCreated { //only registry's contract address is only one
-			contractAddr = be.RegistryAddress
+		if name == params.NameTokenNetworkCreated {
+			contractAddr = subscribeAddress(be.Registries)
 		} else if name == params.NameSecretRevealed {
-			contractAddr = be.SecretRegistryAddress
+			contractAddr = subscribeAddress(be.SecretRegistries)
 		}
 		sub, err = rpc.EventSubscribe(contractAddr, name, eventAbiMap[name], be.client, be.LogChannelMap[name])
 		if err != nil {
@@ -285,7 +305,12 @@ func (be *Events) startListenEvent() {
 							log.Error(fmt.Sprintf("newEventTokenNetworkCreated err=%s", err))
 							continue
 						}
+						if !be.Registries[ev.Raw.Address] {
+							log.Info(fmt.Sprintf("receive TokenNetworkCreated,but it's not our registry,ev=\n%s", utils.StringInterface(ev, 3)))
+							continue
+						}
 						be.TokenNetworks[ev.Token_network_address] = true
+						be.indexEvent(ev)
 						be.sendStateChange(EventTokenNetworkCreated2StateChange(ev))
 					case params.NameChannelOpened:
 						ev, err := newEventChannelOpen(&l)
@@ -297,6 +322,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive event ChannelOpened, but it's not our contract, ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelOpen2StateChange(ev))
 					case params.NameChannelOpenedAndDeposit:
 						ev, err := newEventChannelOpenAndDeposit(&l)
@@ -308,6 +334,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive event ChannelOpened, but it's not our contract, ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						nev, dev := EventChannelOpenAndDeposit2StateChange(ev)
 						be.sendStateChange(nev)
 						be.sendStateChange(dev)
@@ -321,6 +348,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive event channel new deposit ,but it's not our contract, ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelNewDeposit2StateChange(ev))
 					case params.NameChannelClosed:
 						ev, err := newEventChannelClosed(&l)
@@ -332,6 +360,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive NameChannelClosed ,but it's not our contract, ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelClosed2StateChange(ev))
 					case params.NameChannelSettled:
 						ev, err := newEventChannelSettled(&l)
@@ -343,6 +372,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive NameChannelSettled,but it's not our contract, ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelSettled2StateChange(ev))
 					case params.NameChannelCooperativeSettled:
 						ev, err := newEventChannelCooperativeSettled(&l)
@@ -354,6 +384,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive channel cooperative settledd,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelCooperativeSettled2StateChange(ev))
 					case params.NameChannelPunished:
 						ev, err := newEventChannelPunished(&l)
@@ -365,6 +396,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive channel punished event,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelPunished2StateChange(ev))
 					case params.NameSecretRevealed:
 						ev, err := newEventSecretRevealed(&l)
@@ -372,11 +404,24 @@ func (be *Events) startListenEvent() {
 							log.Error(fmt.Sprintf("newEventSecretRevealed err=%s", err))
 							continue
 						}
-						if ev.Raw.Address != be.SecretRegistryAddress {
+						if !be.SecretRegistries[ev.Raw.Address] {
 							log.Info(fmt.Sprintf("receive NameSecretRevealed,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventSecretRevealed2StateChange(ev))
+					case params.NameChannelUnlocked:
+						ev, err := newEventChannelUnlocked(&l)
+						if err != nil {
+							log.Error(fmt.Sprintf("newEventChannelUnlocked err=%s", err))
+							continue
+						}
+						if !be.TokenNetworks[ev.Raw.Address] {
+							log.Info(fmt.Sprintf("receive channel unlocked ,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
+							continue
+						}
+						be.indexEvent(ev)
+						be.sendStateChange(EventChannelUnlocked2StateChange(ev))
 					case params.NameBalanceProofUpdated:
 						ev, err := newEventBalanceProofUpdated(&l)
 						if err != nil {
@@ -387,6 +432,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive channel balance proof updated ,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventBalanceProofUpdated2StateChange(ev))
 					case params.NameChannelWithdraw:
 						ev, err := newEventChannelWithdraw(&l)
@@ -398,6 +444,7 @@ func (be *Events) startListenEvent() {
 							log.Info(fmt.Sprintf("receive channel withdraw ,but it's not our contract,ev=\n%s", utils.StringInterface(ev, 3)))
 							continue
 						}
+						be.indexEvent(ev)
 						be.sendStateChange(EventChannelWithdraw2StateChange(ev))
 					default:
 						log.Crit(fmt.Sprintf("receive unkown event %s,it must be a bug", name))
@@ -439,23 +486,32 @@ func (be *Events) sendStateChange(st mediatedtransfer.ContractStateChange) {
 
 }
 
-//GetAllTokenNetworks returns all the token network,events 本身需要知道所有的 tokennetwork, 这样才能处理相关事件.
+//GetAllTokenNetworks returns all the token network of all registries,events 本身需要知道所有的 tokennetwork, 这样才能处理相关事件.
 func (be *Events) GetAllTokenNetworks(fromBlock int64) (events []*contracts.TokenNetworkRegistryTokenNetworkCreated, err error) {
-	logs, err := rpc.EventGetInternal(rpc.GetQueryConext(), be.RegistryAddress, ethrpc.BlockNumber(fromBlock), ethrpc.LatestBlockNumber,
-		params.NameTokenNetworkCreated, eventAbiMap[params.NameTokenNetworkCreated], be.client)
-	if err != nil {
-		return
+	events, err = be.getTokenNetworksCreated(fromBlock)
+	for _, e := range events {
+		be.TokenNetworks[e.Token_network_address] = true
 	}
-	for _, l := range logs {
-		e, err := newEventTokenNetworkCreated(&l)
+	return
+}
+
+//getTokenNetworksCreated is GetAllTokenNetworks without adding them to TokenNetworks, so it can run outside of the event loop
+func (be *Events) getTokenNetworksCreated(fromBlock int64) (events []*contracts.TokenNetworkRegistryTokenNetworkCreated, err error) {
+	for registry := range be.Registries {
+		var logs []types.Log
+		logs, err = rpc.EventGetInternal(rpc.GetQueryConext(), registry, ethrpc.BlockNumber(fromBlock), ethrpc.LatestBlockNumber,
+			params.NameTokenNetworkCreated, eventAbiMap[params.NameTokenNetworkCreated], be.client)
 		if err != nil {
-			log.Error(fmt.Sprintf("newEventTokenNetworkCreated err %s", err))
-			continue
+			return
+		}
+		for _, l := range logs {
+			e, err := newEventTokenNetworkCreated(&l)
+			if err != nil {
+				log.Error(fmt.Sprintf("newEventTokenNetworkCreated err %s", err))
+				continue
+			}
+			events = append(events, e)
 		}
-		events = append(events, e)
-	}
-	for _, e := range events {
-		be.TokenNetworks[e.Token_network_address] = true
 	}
 	return
 }
@@ -660,119 +716,178 @@ func (be *Events) GetChannelBalanceProofUpdated(fromBlock int64, tokenNetworkAdd
 }
 
 /*
-GetAllSecretRevealed return all secret reveal events
+GetAllSecretRevealed return all secret reveal events of all secret registries
 */
 func (be *Events) GetAllSecretRevealed(fromBlock int64) (events []*contracts.SecretRegistrySecretRevealed, err error) {
-	logs, err := rpc.EventGetInternal(rpc.GetQueryConext(), be.SecretRegistryAddress, ethrpc.BlockNumber(fromBlock), ethrpc.LatestBlockNumber,
-		params.NameSecretRevealed, eventAbiMap[params.NameSecretRevealed], be.client)
-	if err != nil {
-		return
-	}
-	for _, l := range logs {
-		e, err := newEventSecretRevealed(&l)
+	for secretRegistry := range be.SecretRegistries {
+		var logs []types.Log
+		logs, err = rpc.EventGetInternal(rpc.GetQueryConext(), secretRegistry, ethrpc.BlockNumber(fromBlock), ethrpc.LatestBlockNumber,
+			params.NameSecretRevealed, eventAbiMap[params.NameSecretRevealed], be.client)
 		if err != nil {
-			log.Error(fmt.Sprintf("newEventSecretRevealed err %s", err))
-			continue
+			return
+		}
+		for _, l := range logs {
+			e, err := newEventSecretRevealed(&l)
+			if err != nil {
+				log.Error(fmt.Sprintf("newEventSecretRevealed err %s", err))
+				continue
+			}
+			events = append(events, e)
 		}
-		events = append(events, e)
 	}
 	return
 }
 
 /*
-GetAllStateChangeSince returns all the statechanges that raiden should know when it's offline
-tokennetwork合约上发生的所有事情我们都应该按顺序通知使用者
+getAllEventsSince returns all the decoded events of our contracts since `lastBlockNumber`,
+registry and secret registry first, then every token network.
 */
-func (be *Events) GetAllStateChangeSince(lastBlockNumber int64) (stateChangs []mediatedtransfer.ContractStateChange, err error) {
+func (be *Events) getAllEventsSince(lastBlockNumber int64) (events []interface{}, err error) {
 	events0, err := be.GetAllTokenNetworks(lastBlockNumber)
 	if err != nil {
 		return
 	}
 	for _, e := range events0 {
-		stateChangs = append(stateChangs, EventTokenNetworkCreated2StateChange(e))
+		events = append(events, e)
 	}
-	var events []*contracts.SecretRegistrySecretRevealed
-	events, err = be.GetAllSecretRevealed(lastBlockNumber)
+	events1, err := be.GetAllSecretRevealed(lastBlockNumber)
 	if err != nil {
 		return
 	}
-	for _, e := range events {
-		stateChangs = append(stateChangs, EventSecretRevealed2StateChange(e))
+	for _, e := range events1 {
+		events = append(events, e)
 	}
-	/*
-		把历史发生的事件按照顺序通知给 raidenService,
-		如何处理在查询过程中新收到的事件呢?
-	*/
 	for tokenNetwork := range be.TokenNetworks {
-		var err error
-		events2, err := be.GetChannelNew(lastBlockNumber, tokenNetwork)
+		events2, err := be.getTokenNetworkEventsSince(lastBlockNumber, tokenNetwork)
 		if err != nil {
 			return nil, err
 		}
-		for _, e := range events2 {
+		events = append(events, events2...)
+	}
+	return
+}
+
+//getTokenNetworkEventsSince returns all events on `tokenNetwork` since `fromBlock`
+func (be *Events) getTokenNetworkEventsSince(fromBlock int64, tokenNetwork common.Address) (events []interface{}, err error) {
+	events2, err := be.GetChannelNew(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events2 {
+		events = append(events, e)
+	}
+	events3, err := be.GetChannelClosed(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events3 {
+		events = append(events, e)
+	}
+	events4, err := be.GetChannelSettled(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events4 {
+		events = append(events, e)
+	}
+	events5, err := be.GetChannelCooperativeSettled(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events5 {
+		events = append(events, e)
+	}
+	events6, err := be.GetChannelBalanceProofUpdated(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events6 {
+		events = append(events, e)
+	}
+	events7, err := be.GetChannelUnlocked(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events7 {
+		events = append(events, e)
+	}
+	events8, err := be.GetChannelWithdraw(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events8 {
+		events = append(events, e)
+	}
+	events9, err := be.GetChannelNewDeposit(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events9 {
+		events = append(events, e)
+	}
+	events10, err := be.GetChannelPunished(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events10 {
+		events = append(events, e)
+	}
+	events11, err := be.GetChannelNewAndDeposit(fromBlock, tokenNetwork)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range events11 {
+		events = append(events, e)
+	}
+	return
+}
+
+/*
+GetAllStateChangeSince returns all the statechanges that raiden should know when it's offline
+tokennetwork合约上发生的所有事情我们都应该按顺序通知使用者
+every event got is indexed too.
+*/
+func (be *Events) GetAllStateChangeSince(lastBlockNumber int64) (stateChangs []mediatedtransfer.ContractStateChange, err error) {
+	/*
+		把历史发生的事件按照顺序通知给 raidenService,
+		如何处理在查询过程中新收到的事件呢?
+	*/
+	events, err := be.getAllEventsSince(lastBlockNumber)
+	if err != nil {
+		return
+	}
+	stateChangs = be.eventsToStateChanges(events)
+	return
+}
+
+//eventsToStateChanges indexes `events` and converts them to statechanges
+func (be *Events) eventsToStateChanges(events []interface{}) (stateChangs []mediatedtransfer.ContractStateChange) {
+	for _, ev := range events {
+		be.indexEvent(ev)
+		switch e := ev.(type) {
+		case *contracts.TokenNetworkRegistryTokenNetworkCreated:
+			stateChangs = append(stateChangs, EventTokenNetworkCreated2StateChange(e))
+		case *contracts.SecretRegistrySecretRevealed:
+			stateChangs = append(stateChangs, EventSecretRevealed2StateChange(e))
+		case *contracts.TokenNetworkChannelOpened:
 			stateChangs = append(stateChangs, EventChannelOpen2StateChange(e))
-		}
-		events3, err := be.GetChannelClosed(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events3 {
+		case *contracts.TokenNetworkChannelClosed:
 			stateChangs = append(stateChangs, EventChannelClosed2StateChange(e))
-		}
-		events4, err := be.GetChannelSettled(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events4 {
+		case *contracts.TokenNetworkChannelSettled:
 			stateChangs = append(stateChangs, EventChannelSettled2StateChange(e))
-		}
-		events5, err := be.GetChannelCooperativeSettled(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events5 {
+		case *contracts.TokenNetworkChannelCooperativeSettled:
 			stateChangs = append(stateChangs, EventChannelCooperativeSettled2StateChange(e))
-		}
-		events6, err := be.GetChannelBalanceProofUpdated(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events6 {
+		case *contracts.TokenNetworkBalanceProofUpdated:
 			stateChangs = append(stateChangs, EventBalanceProofUpdated2StateChange(e))
-		}
-		events7, err := be.GetChannelUnlocked(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events7 {
+		case *contracts.TokenNetworkChannelUnlocked:
 			stateChangs = append(stateChangs, EventChannelUnlocked2StateChange(e))
-		}
-		events8, err := be.GetChannelWithdraw(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events8 {
+		case *contracts.TokenNetworkChannelWithdraw:
 			stateChangs = append(stateChangs, EventChannelWithdraw2StateChange(e))
-		}
-		events9, err := be.GetChannelNewDeposit(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events9 {
+		case *contracts.TokenNetworkChannelNewDeposit:
 			stateChangs = append(stateChangs, EventChannelNewDeposit2StateChange(e))
-		}
-		events10, err := be.GetChannelPunished(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events10 {
+		case *contracts.TokenNetworkChannelPunished:
 			stateChangs = append(stateChangs, EventChannelPunished2StateChange(e))
-		}
-		events11, err := be.GetChannelNewAndDeposit(lastBlockNumber, tokenNetwork)
-		if err != nil {
-			return nil, err
-		}
-		for _, e := range events11 {
+		case *contracts.TokenNetworkChannelOpenedAndDeposit:
 			st1, st2 := EventChannelOpenAndDeposit2StateChange(e)
 			stateChangs = append(stateChangs, st1, st2)
 		}
@@ -780,6 +895,17 @@ func (be *Events) GetAllStateChangeSince(lastBlockNumber int64) (stateChangs []m
 	return
 }
 
+//GetTokenNetworkStateChangesSince returns statechanges of all events on `tokenNetwork` since `fromBlock`, in the order they happened
+func (be *Events) GetTokenNetworkStateChangesSince(fromBlock int64, tokenNetwork common.Address) (stateChanges []mediatedtransfer.ContractStateChange, err error) {
+	events, err := be.getTokenNetworkEventsSince(fromBlock, tokenNetwork)
+	if err != nil {
+		return
+	}
+	stateChanges = be.eventsToStateChanges(events)
+	sortContractStateChange(stateChanges)
+	return
+}
+
 /*
 Start listening events send to  channel can duplicate but cannot lose.
 1. first resend events may lost (duplicat is ok)
@@ -791,6 +917,7 @@ Start listening events send to  channel can duplicate but cannot lose.
 */
 func (be *Events) Start(LastBlockNumber int64) error {
 	log.Info(fmt.Sprintf("get state change since %d", LastBlockNumber))
+	atomic.StoreInt32(&be.historyEventsSent, 0)
 	err := be.installEventListener()
 	if err != nil {
 		return err
@@ -799,6 +926,14 @@ func (be *Events) Start(LastBlockNumber int64) error {
 	if err != nil {
 		return err
 	}
+	//only once after the index is added, events since LastBlockNumber are indexed by catching up
+	if be.Indexer != nil && !be.Indexer.IsContractEventHistoryIndexed() {
+		var tokenNetworks []common.Address
+		for tokenNetwork := range be.TokenNetworks {
+			tokenNetworks = append(tokenNetworks, tokenNetwork)
+		}
+		go be.indexHistory(LastBlockNumber, tokenNetworks)
+	}
 	be.historyEventsGot = true
 
 	go func() {
@@ -822,6 +957,12 @@ func (be *Events) Start(LastBlockNumber int64) error {
 		for _, st := range oldstateChanges {
 			be.sendStateChange(st)
 		}
+		atomic.StoreInt32(&be.historyEventsSent, 1)
 	}()
 	return nil
 }
+
+//IsCatchingUp returns true until all events happened when this node was offline have been sent
+func (be *Events) IsCatchingUp() bool {
+	return atomic.LoadInt32(&be.historyEventsSent) == 0
+}
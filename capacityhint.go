@@ -0,0 +1,131 @@
+package smartraiden
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+/*
+capacity hints tell channel partners roughly how much this node can send through each of its channels,
+so that they don't route through depleted channels.
+only enabled nodes send and use hints, a hint covers a range of `Precision` powers of two,
+channels with hidden partners are never told.
+*/
+
+//capacityBuckets returns buckets of my channels of a token which can be told to partners
+func (rs *RaidenService) capacityBuckets(g *graph.ChannelGraph) (buckets []encoding.CapacityBucket) {
+	c := rs.Config.CapacityHints
+	for partner, ch := range g.PartenerAddress2Channel {
+		if c.Hidden[partner] || !ch.CanTransfer() {
+			continue
+		}
+		buckets = append(buckets, encoding.CapacityBucket{
+			Partner: partner,
+			Bucket:  graph.CapacityToBucket(ch.Distributable(), c.Precision),
+		})
+	}
+	return
+}
+
+/*
+advertiseCapacityHints sends hints of my channels to all online partners of every token,
+and to pathfinding service only if the user asked for it, again when half of the expiration passed.
+*/
+func (rs *RaidenService) advertiseCapacityHints() {
+	postHints := rs.Pathfinder != nil && rs.Config.PathfinderPostHints
+	if !rs.Config.CapacityHints.Enable && !postHints {
+		return
+	}
+	now := time.Now()
+	for _, g := range rs.RegistryToken2ChannelGraph {
+		g.RemoveExpiredCapacityHints(now)
+	}
+	if now.Sub(rs.lastCapacityHint) < params.CapacityHintExpiration/2 {
+		return
+	}
+	rs.lastCapacityHint = now
+	expiration := now.Add(params.CapacityHintExpiration).Unix()
+	for key, g := range rs.RegistryToken2ChannelGraph {
+		//hints carry only the token, so they are of the token's first registry, see tokenKey
+		if key != rs.tokenKey(utils.EmptyAddress, key.Token) {
+			continue
+		}
+		buckets := rs.capacityBuckets(g)
+		if len(buckets) == 0 {
+			continue
+		}
+		var msgs []*encoding.CapacityHint
+		for len(buckets) > 0 {
+			n := len(buckets)
+			if n > params.MaxCapacityBucketsPerHint {
+				n = params.MaxCapacityBucketsPerHint
+			}
+			msg := encoding.NewCapacityHint(key.Token, expiration, uint8(rs.Config.CapacityHints.Precision), buckets[:n])
+			err := msg.Sign(rs.Signer, msg)
+			if err != nil {
+				log.Error(fmt.Sprintf("sign CapacityHint err %s", err))
+				return
+			}
+			msgs = append(msgs, msg)
+			buckets = buckets[n:]
+		}
+		if postHints {
+			go rs.postCapacityHints(msgs)
+		}
+		if !rs.Config.CapacityHints.Enable {
+			continue
+		}
+		for partner := range g.PartenerAddress2Channel {
+			if _, isOnline := rs.Protocol.GetNetworkStatus(partner); !isOnline {
+				continue
+			}
+			for _, msg := range msgs {
+				err := rs.sendAsync(partner, msg)
+				if err != nil {
+					log.Warn(fmt.Sprintf("send capacity hint to %s err %s", utils.APex2(partner), err))
+				}
+			}
+		}
+	}
+}
+
+//receive capacity hints of a partner's channels
+func (mh *raidenMessageHandler) messageCapacityHint(msg *encoding.CapacityHint) error {
+	if !mh.raiden.Config.CapacityHints.Enable {
+		//don't use it, but the partner needn't retry
+		return nil
+	}
+	now := time.Now()
+	expiration := time.Unix(msg.Expiration, 0)
+	if !expiration.After(now) {
+		return fmt.Errorf("capacity hint of %s already expired", utils.APex2(msg.Sender))
+	}
+	if expiration.Sub(now) > params.MaxCapacityHintExpiration {
+		return fmt.Errorf("capacity hint of %s valid too long, expiration=%s", utils.APex2(msg.Sender), expiration)
+	}
+	if msg.Precision == 0 {
+		return fmt.Errorf("capacity hint of %s precision is 0", utils.APex2(msg.Sender))
+	}
+	g := mh.raiden.RegistryToken2ChannelGraph[mh.raiden.tokenKey(utils.EmptyAddress, msg.Token)]
+	if g == nil {
+		return fmt.Errorf("capacity hint of %s for unknown token %s", utils.APex2(msg.Sender), utils.APex2(msg.Token))
+	}
+	if g.GetPartenerAddress2Channel(msg.Sender) == nil {
+		return fmt.Errorf("capacity hint from %s, who is not my partner", utils.APex2(msg.Sender))
+	}
+	for _, b := range msg.Buckets {
+		if b.Partner == mh.raiden.NodeAddress {
+			//I know the channel better
+			continue
+		}
+		min, max := graph.CapacityBucketRange(b.Bucket, int(msg.Precision))
+		g.SetCapacityHint(msg.Sender, b.Partner, min, max, expiration)
+	}
+	return nil
+}
@@ -6,17 +6,15 @@ import (
 
 	"errors"
 
-	"crypto/ecdsa"
-
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -29,8 +27,7 @@ ExternalState operation on nettingchannelcontract
 type ExternalState struct {
 	funcRegisterChannelForHashlock FuncRegisterChannelForHashlock
 	TokenNetwork                   *rpc.TokenNetworkProxy
-	auth                           *bind.TransactOpts
-	privKey                        *ecdsa.PrivateKey
+	signer                         signer.Signer
 	Client                         *helper.SafeEthClient
 	ClosedBlock                    int64
 	SettledBlock                   int64
@@ -38,16 +35,16 @@ type ExternalState struct {
 	MyAddress                      common.Address
 	PartnerAddress                 common.Address
 	db                             channeltype.Db
+	UnlockCoster                   UnlockCoster //locks worth less than the cost are not unlocked, nil means unlock all
 }
 
 //NewChannelExternalState create a new channel external state
 func NewChannelExternalState(fun FuncRegisterChannelForHashlock,
-	tokenNetwork *rpc.TokenNetworkProxy, channelAddress *contracts.ChannelUniqueID, privkey *ecdsa.PrivateKey, client *helper.SafeEthClient, db channeltype.Db, closedBlock int64, MyAddress, PartnerAddress common.Address) *ExternalState {
+	tokenNetwork *rpc.TokenNetworkProxy, channelAddress *contracts.ChannelUniqueID, s signer.Signer, client *helper.SafeEthClient, db channeltype.Db, closedBlock int64, MyAddress, PartnerAddress common.Address) *ExternalState {
 	cs := &ExternalState{
 		funcRegisterChannelForHashlock: fun,
 		TokenNetwork:                   tokenNetwork,
-		auth:                           bind.NewKeyedTransactor(privkey),
-		privKey:                        privkey,
+		signer:                         s,
 		Client:                         client,
 		ChannelIdentifier:              *channelAddress,
 		db:                             db,
@@ -119,27 +116,48 @@ func (e *ExternalState) UpdateTransfer(bp *transfer.BalanceProofState) (result *
 
 /*
 Unlock call withdraw function of contract
+locks not worth the gas are skipped, others are unlocked by PlanUnlocks order,
+and the outcome of each lock is saved to db.
 调用者要确保不包含自己声明放弃过的锁
 */
 func (e *ExternalState) Unlock(unlockproofs []*channeltype.UnlockProof, argTransferdAmount *big.Int) (result *utils.AsyncResult) {
 	result = utils.NewAsyncResult()
 	transferAmount := new(big.Int).Set(argTransferdAmount)
+	var proofs []*channeltype.UnlockProof
+	for _, proof := range unlockproofs {
+		if e.db.IsThisLockHasUnlocked(e.ChannelIdentifier.ChannelIdentifier, proof.Lock.LockSecretHash) {
+			log.Info(fmt.Sprintf("withdraw secret has been used %s  %s", e.ChannelIdentifier.String(), utils.HPex(proof.Lock.LockSecretHash)))
+			continue
+		}
+		proofs = append(proofs, proof)
+	}
+	var minAmount *big.Int
+	if e.UnlockCoster != nil {
+		minAmount = e.UnlockCoster.UnlockCost(e.TokenNetwork.Address)
+	}
+	plan, skipped := PlanUnlocks(proofs, minAmount)
+	for _, proof := range skipped {
+		log.Info(fmt.Sprintf("withdraw skip %s on %s, amount %s is less than unlock cost %s", utils.HPex(proof.Lock.LockSecretHash),
+			utils.HPex(e.ChannelIdentifier.ChannelIdentifier), proof.Lock.Amount, minAmount))
+		e.saveUnlockOutcome(proof, channeltype.UnlockStatusSkipped, fmt.Sprintf("amount is less than unlock cost %s", minAmount))
+	}
+	for _, proof := range plan {
+		e.saveUnlockOutcome(proof, channeltype.UnlockStatusPending, "")
+	}
 	go func() {
 		log.Info(fmt.Sprintf("withdraw called %s", utils.HPex(e.ChannelIdentifier.ChannelIdentifier)))
 		failed := false
-		for _, proof := range unlockproofs {
-			if e.db.IsThisLockHasUnlocked(e.ChannelIdentifier.ChannelIdentifier, proof.Lock.LockSecretHash) {
-				log.Info(fmt.Sprintf("withdraw secret has been used %s  %s", e.ChannelIdentifier.String(), utils.HPex(proof.Lock.LockSecretHash)))
-				continue
-			}
+		for _, proof := range plan {
 			err := e.TokenNetwork.Unlock(e.PartnerAddress, transferAmount, proof.Lock, mtree.Proof2Bytes(proof.MerkleProof))
 			if err != nil {
 				failed = true
+				e.saveUnlockOutcome(proof, channeltype.UnlockStatusFailed, err.Error())
 			} else {
 				/*
 					allow try withdraw next time if not success?
 				*/
 				e.db.UnlockThisLock(e.ChannelIdentifier.ChannelIdentifier, proof.Lock.LockSecretHash)
+				e.saveUnlockOutcome(proof, channeltype.UnlockStatusUnlocked, "")
 				log.Info(fmt.Sprintf("withdraw success %s,proof=%s", utils.HPex(e.ChannelIdentifier.ChannelIdentifier), utils.StringInterface1(proof)))
 				/*
 					一旦 unlock 成功,那么 transferAmount 就会发生变化,下次必须用新的 transferAmount
@@ -156,6 +174,11 @@ func (e *ExternalState) Unlock(unlockproofs []*channeltype.UnlockProof, argTrans
 	return
 }
 
+func (e *ExternalState) saveUnlockOutcome(proof *channeltype.UnlockProof, status channeltype.UnlockStatus, errMsg string) {
+	e.db.SaveUnlockOutcome(channeltype.NewUnlockOutcome(e.ChannelIdentifier.ChannelIdentifier, proof.Lock.LockSecretHash,
+		proof.Lock.Amount, proof.Lock.Expiration, status, errMsg))
+}
+
 //Settle call settle function of contract
 func (e *ExternalState) Settle(MyTransferAmount, PartnerTransferAmount *big.Int, MyLocksroot, PartnerLocksroot common.Hash) (result *utils.AsyncResult) {
 	if e.SettledBlock != 0 {
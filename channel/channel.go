@@ -28,10 +28,12 @@ type Channel struct {
 	ExternState       *ExternalState
 	ChannelIdentifier contracts.ChannelUniqueID //this channel
 	TokenAddress      common.Address
+	RegistryAddress   common.Address //registry of token network of this channel
 	RevealTimeout     int
 	SettleTimeout     int
 	feeCharger        fee.Charger //calc fee for each transfer?
 	State             channeltype.State
+	Blocked           bool //found inconsistent with blockchain, no new transfers until it's resolved
 }
 
 /*
@@ -77,10 +79,10 @@ func (c *Channel) Distributable() *big.Int {
 
 /*
 CanTransfer  a closed channel and has no Balance channel cannot
-transfer tokens to partner.
+transfer tokens to partner, neither can a blocked channel.
 */
 func (c *Channel) CanTransfer() bool {
-	return channeltype.CanTransferMap[c.State]
+	return channeltype.CanTransferMap[c.State] && !c.Blocked
 }
 
 //CanContinueTransfer unfinished transfer can continue?
@@ -1094,12 +1096,13 @@ func (c *Channel) Settle() (result *utils.AsyncResult) {
 	return c.ExternState.Settle(MyTransferAmount, PartnerTransferAmount, MyLocksroot, PartnerLocksroot)
 }
 
-//GetNeedRegisterSecrets find all secres need to reveal on secret
-func (c *Channel) GetNeedRegisterSecrets(blockNumber int64) (secrets []common.Hash) {
+//GetNeedRegisterSecrets find all secres need to reveal on secret, and expiration of their locks
+func (c *Channel) GetNeedRegisterSecrets(blockNumber int64) (secrets map[common.Hash]int64) {
+	secrets = make(map[common.Hash]int64)
 	for _, l := range c.PartnerState.Lock2UnclaimedLocks {
 		if l.Lock.Expiration > blockNumber-int64(c.RevealTimeout) && l.Lock.Expiration < blockNumber {
 			//底层负责处理重复的问题
-			secrets = append(secrets, l.Secret)
+			secrets[l.Secret] = l.Lock.Expiration
 		}
 	}
 	return
@@ -1113,7 +1116,7 @@ func (c *Channel) CooperativeSettleChannel(res *encoding.SettleResponse) (result
 	if err != nil {
 		panic(err)
 	}
-	err = w.Sign(c.ExternState.privKey, w)
+	err = w.Sign(c.ExternState.signer, w)
 	if err != nil {
 		panic(err)
 	}
@@ -1142,7 +1145,7 @@ func (c *Channel) Withdraw(res *encoding.WithdrawResponse) (result *utils.AsyncR
 	if err != nil {
 		panic(err)
 	}
-	err = w.Sign(c.ExternState.privKey, w)
+	err = w.Sign(c.ExternState.signer, w)
 	if err != nil {
 		panic(err)
 	}
@@ -1183,6 +1186,7 @@ func NewChannelSerialization(c *Channel) *channeltype.Serialization {
 		Key:                    c.ChannelIdentifier.ChannelIdentifier[:],
 		ChannelIdentifier:      &c.ChannelIdentifier,
 		TokenAddressBytes:      c.TokenAddress[:],
+		RegistryAddress:        c.RegistryAddress,
 		PartnerAddressBytes:    c.PartnerState.Address[:],
 		OurAddress:             c.OurState.Address,
 		RevealTimeout:          c.RevealTimeout,
@@ -15,6 +15,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -80,7 +81,7 @@ func TestEndState(t *testing.T) {
 		Locksroot:         locksroot,
 	}
 	mtr := encoding.NewMediatedTransfer(bp, lock, utils.NewRandomAddress(), utils.NewRandomAddress(), utils.BigInt0)
-	mtr.Sign(bcs.PrivKey, mtr)
+	mtr.Sign(bcs.Signer, mtr)
 	err := state1.registerLockedTransfer(mtr)
 	if err != nil {
 		t.Error(err)
@@ -149,7 +150,7 @@ func TestEndState(t *testing.T) {
 	assert.EqualValues(t, state2.nonce(), 0)
 
 	secretMessage := encoding.NewUnlock(encoding.NewBalanceProof(2, x.Add(transferedAmount, lockAmount), utils.EmptyHash, channelAddress), lockSecret)
-	secretMessage.Sign(bcs.PrivKey, secretMessage)
+	secretMessage.Sign(bcs.Signer, secretMessage)
 	state1.registerSecretMessage(secretMessage)
 
 	assert.EqualValues(t, state1.ContractBalance, x.Add(balance1, big10))
@@ -181,7 +182,7 @@ func makeExternState() *ExternalState {
 			ChannelIdentifier: ch,
 			OpenBlockNumber:   testOpenBlockNumber,
 		},
-		bcs.PrivKey, bcs.Client,
+		bcs.Signer, bcs.Client,
 		channeltype.NewMockChannelDb(),
 		0,
 		bcs.NodeAddress, utils.NewRandomAddress())
@@ -206,7 +207,7 @@ func TestSenderCannotOverSpend(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	sentMediatedTransfer0.Sign(privkey1, sentMediatedTransfer0)
+	sentMediatedTransfer0.Sign(signer.NewKeySigner(privkey1), sentMediatedTransfer0)
 	testChannel.RegisterTransfer(blockNumber, sentMediatedTransfer0)
 	lock2 := &mtree.Lock{
 		Expiration:     expiration,
@@ -224,7 +225,7 @@ func TestSenderCannotOverSpend(t *testing.T) {
 		Locksroot:         locksroot2,
 	}
 	sentMediatedTransfer1 := encoding.NewMediatedTransfer(bp, lock2, address2, address1, utils.BigInt0)
-	sentMediatedTransfer1.Sign(privkey1, sentMediatedTransfer1)
+	sentMediatedTransfer1.Sign(signer.NewKeySigner(privkey1), sentMediatedTransfer1)
 	err = testChannel.RegisterTransfer(blockNumber, sentMediatedTransfer1)
 	if err != rerr.ErrInsufficientBalance {
 		t.Error(err)
@@ -247,7 +248,7 @@ func TestReceiverCannotSpendLockedAmount(t *testing.T) {
 	amount1 := balance2
 	expiration := blockNumber + int64(settleTimeout)
 	receiveMediatedTransfer0, _ := testChannel.CreateMediatedTransfer(address1, address2, utils.BigInt0, amount1, expiration, utils.Sha3([]byte("test_locked_amount_cannot_be_spent")))
-	receiveMediatedTransfer0.Sign(privkey2, receiveMediatedTransfer0)
+	receiveMediatedTransfer0.Sign(signer.NewKeySigner(privkey2), receiveMediatedTransfer0)
 	err := testChannel.RegisterTransfer(blockNumber, receiveMediatedTransfer0)
 	if err != nil {
 		t.Error(err)
@@ -269,7 +270,7 @@ func TestReceiverCannotSpendLockedAmount(t *testing.T) {
 		Locksroot:         locksroot2,
 	}
 	sendMediatedTransfer0 := encoding.NewMediatedTransfer(bp, lock2, address2, address1, utils.BigInt0)
-	sendMediatedTransfer0.Sign(privkey1, sendMediatedTransfer0)
+	sendMediatedTransfer0.Sign(signer.NewKeySigner(privkey1), sendMediatedTransfer0)
 	if testChannel.RegisterTransfer(blockNumber, sendMediatedTransfer0) != rerr.ErrInsufficientBalance {
 		t.Error("RegisterTransfer should be failed ")
 	}
@@ -317,7 +318,7 @@ func TestPythonChannel(t *testing.T) {
 	assert.NotEqual(t, err, nil)
 	var amount1 = big.NewInt(10)
 	directTransfer, _ := testchannel.CreateDirectTransfer(amount1)
-	directTransfer.Sign(privkey1, directTransfer)
+	directTransfer.Sign(signer.NewKeySigner(privkey1), directTransfer)
 	testchannel.RegisterTransfer(blockNumber, directTransfer)
 
 	assert.EqualValues(t, testchannel.ContractBalance(), balance1)
@@ -335,7 +336,7 @@ func TestPythonChannel(t *testing.T) {
 	var amount2 = big.NewInt(10)
 	expiration := blockNumber + int64(settleTimeout) - 5
 	mediatedTransfer, _ := testchannel.CreateMediatedTransfer(address1, address2, utils.BigInt0, amount2, expiration, hashlock)
-	mediatedTransfer.Sign(privkey1, mediatedTransfer)
+	mediatedTransfer.Sign(signer.NewKeySigner(privkey1), mediatedTransfer)
 	testchannel.RegisterTransfer(blockNumber, mediatedTransfer)
 
 	assert.EqualValues(t, testchannel.ContractBalance(), balance1)
@@ -358,7 +359,7 @@ func TestPythonChannel(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	secretMessage.Sign(privkey1, secretMessage)
+	secretMessage.Sign(signer.NewKeySigner(privkey1), secretMessage)
 	log.Info(fmt.Sprintf("secret message=%s", utils.StringInterface(secretMessage, 4)))
 	log.Info(fmt.Sprintf("bofore reg sec proof=%s", utils.StringInterface(testchannel.OurState.BalanceProofState, 2)))
 	err = testchannel.RegisterTransfer(blockNumber, secretMessage)
@@ -398,7 +399,7 @@ func TestChannelIncreaseNonceAndTransferedAmount(t *testing.T) {
 	var amount = big.NewInt(7)
 	for i := 0; i < 10; i++ {
 		directTransfer, _ := tch.CreateDirectTransfer(amount)
-		directTransfer.Sign(privkey1, directTransfer)
+		directTransfer.Sign(signer.NewKeySigner(privkey1), directTransfer)
 		tch.RegisterTransfer(blockNumber, directTransfer)
 		newNonce := tch.GetNextNonce()
 		newTransfered := tch.TransferAmount()
@@ -557,7 +558,7 @@ func TestInterwovenTransfers(t *testing.T) {
 		var mtr *encoding.MediatedTransfer
 		mtr, err = ch0.CreateMediatedTransfer(ch0.OurState.Address, ch1.OurState.Address, utils.BigInt0, amount, expiration, utils.Sha3(secret[:]))
 		assert.Equal(t, err, nil)
-		mtr.Sign(ch0.ExternState.privKey, mtr)
+		mtr.Sign(ch0.ExternState.signer, mtr)
 		err = ch0.RegisterTransfer(blockNumber, mtr)
 		assert.Equal(t, err, nil)
 		err = ch1.RegisterTransfer(blockNumber, mtr)
@@ -587,7 +588,7 @@ func TestInterwovenTransfers(t *testing.T) {
 				t.Error(err)
 				return
 			}
-			secretMessage.Sign(ch0.ExternState.privKey, secretMessage)
+			secretMessage.Sign(ch0.ExternState.signer, secretMessage)
 			err = ch0.RegisterTransfer(blockNumber, secretMessage)
 			assert.Equal(t, err, nil)
 			err = ch1.RegisterTransfer(blockNumber, secretMessage)
@@ -615,7 +616,7 @@ func TestTransfer(t *testing.T) {
 	var amount = big.NewInt(10)
 	directTransfer, err := ch0.CreateDirectTransfer(amount)
 	assert.Equal(t, err, nil)
-	directTransfer.Sign(ch0.ExternState.privKey, directTransfer)
+	directTransfer.Sign(ch0.ExternState.signer, directTransfer)
 	err = ch0.RegisterTransfer(10, directTransfer)
 	assert.Equal(t, err, nil)
 	err = ch1.RegisterTransfer(10, directTransfer)
@@ -644,7 +645,7 @@ func TestRegisterInvalidTransfer(t *testing.T) {
 	hashlock := utils.Sha3(secret[:])
 	transfer1, err := ch0.CreateMediatedTransfer(ch0.OurState.Address, ch1.OurState.Address, utils.BigInt0, amount, expiration, hashlock)
 	assert.Equal(t, err, nil)
-	transfer1.Sign(ch0.ExternState.privKey, transfer1)
+	transfer1.Sign(ch0.ExternState.signer, transfer1)
 	err = ch0.RegisterTransfer(blockNumber, transfer1)
 	assert.Equal(t, err, nil)
 	err = ch1.RegisterTransfer(blockNumber, transfer1)
@@ -653,7 +654,7 @@ func TestRegisterInvalidTransfer(t *testing.T) {
 		ch1, balance1, []*mtree.Lock{transfer1.GetLock()}, t)
 	// handcrafted transfer because channel.create_transfer won't create it
 	transfer2 := encoding.NewDirectTransfer(encoding.NewBalanceProof(ch0.GetNextNonce(), x.Add(ch1.Balance(), balance0).Add(x, amount), ch0.PartnerState.Tree.MerkleRoot(), &ch0.ChannelIdentifier))
-	transfer2.Sign(ch0.ExternState.privKey, transfer2)
+	transfer2.Sign(ch0.ExternState.signer, transfer2)
 	err = ch0.RegisterTransfer(blockNumber, transfer2)
 	assert.Equal(t, err != nil, true)
 	err = ch1.RegisterTransfer(blockNumber, transfer2)
@@ -702,7 +703,7 @@ func TestChannelMustAcceptExpiredLocks(t *testing.T) {
 		Locksroot:         utils.Sha3(lock.AsBytes()),
 	}
 	transfer := encoding.NewMediatedTransfer(bp, lock, utils.EmptyAddress, utils.EmptyAddress, utils.BigInt0)
-	transfer.Sign(privkey2, transfer)
+	transfer.Sign(signer.NewKeySigner(privkey2), transfer)
 	err := testChannel.RegisterTransfer(blockNumber+int64(settleTimeout)+1, transfer)
 	assert.Equal(t, err, nil)
 }
@@ -724,7 +725,7 @@ func TestRemoveExpiredHashlock(t *testing.T) {
 	expiration := blockNumber + int64(settleTimeout)
 	//smtr: the mediated transfer i sent out
 	smtr, _ := testChannel.CreateMediatedTransfer(address1, address2, utils.BigInt0, amount1, expiration, utils.Sha3([]byte("test_locked_amount_cannot_be_spent")))
-	smtr.Sign(privkey1, smtr)
+	smtr.Sign(signer.NewKeySigner(privkey1), smtr)
 	err := testChannel.RegisterTransfer(blockNumber, smtr)
 	if err != nil {
 		t.Error(err)
@@ -748,7 +749,7 @@ func TestRemoveExpiredHashlock(t *testing.T) {
 		Locksroot:         locksroot2,
 	}
 	rmtr := encoding.NewMediatedTransfer(bp, lock2, address1, address2, utils.BigInt0)
-	rmtr.Sign(privkey2, rmtr)
+	rmtr.Sign(signer.NewKeySigner(privkey2), rmtr)
 	err = testChannel.RegisterTransfer(blockNumber, rmtr)
 	if err != nil {
 		t.Error("RegisterTransfer error")
@@ -785,7 +786,7 @@ func TestRemoveExpiredHashlock(t *testing.T) {
 		Locksroot:         locksroot,
 	}
 	removeTransferFromPartner := encoding.NewRemoveExpiredHashlockTransfer(bp, rmtr.LockSecretHash)
-	removeTransferFromPartner.Sign(privkey2, removeTransferFromPartner)
+	removeTransferFromPartner.Sign(signer.NewKeySigner(privkey2), removeTransferFromPartner)
 	err = testChannel.RegisterRemoveExpiredHashlockTransfer(removeTransferFromPartner, blockNumber)
 	if err == nil {
 		t.Error("can not register")
@@ -801,7 +802,7 @@ func TestRemoveExpiredHashlock(t *testing.T) {
 		t.Error("must be removed for a expired hashlock®")
 		return
 	}
-	removeTransferFromMe.Sign(privkey1, removeTransferFromMe)
+	removeTransferFromMe.Sign(signer.NewKeySigner(privkey1), removeTransferFromMe)
 	err = testChannel.RegisterRemoveExpiredHashlockTransfer(removeTransferFromMe, expiration)
 	if err != nil {
 		t.Errorf(" err register mine remove transfer %s", err)
@@ -821,7 +822,7 @@ func TestChannel_RegisterAnnounceDisposedTransferResponse(t *testing.T) {
 	expiration := blockNumber + int64(ch0.SettleTimeout)
 	lockSecretHash := utils.Sha3([]byte("123"))
 	smtr, _ := ch0.CreateMediatedTransfer(ch0.OurState.Address, ch0.PartnerState.Address, utils.BigInt0, big.NewInt(1), expiration, lockSecretHash)
-	err := smtr.Sign(ch0.ExternState.privKey, smtr)
+	err := smtr.Sign(ch0.ExternState.signer, smtr)
 	if err != nil {
 		t.Error(err)
 		return
@@ -847,7 +848,7 @@ func TestChannel_RegisterAnnounceDisposedTransferResponse(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	err = req.Sign(ch1.ExternState.privKey, req)
+	err = req.Sign(ch1.ExternState.signer, req)
 	if err != nil {
 		t.Error(err)
 		return
@@ -867,7 +868,7 @@ func TestChannel_RegisterAnnounceDisposedTransferResponse(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	err = res.Sign(ch0.ExternState.privKey, res)
+	err = res.Sign(ch0.ExternState.signer, res)
 	if err != nil {
 		t.Error(err)
 		return
@@ -892,7 +893,7 @@ func TestChannel_RegisterWithdrawRequest(t *testing.T) {
 	secret := utils.Sha3([]byte("123"))
 	lockSecretHash := utils.Sha3(secret[:])
 	smtr, _ := ch0.CreateMediatedTransfer(ch0.OurState.Address, ch0.PartnerState.Address, utils.BigInt0, big.NewInt(1), expiration, lockSecretHash)
-	err := smtr.Sign(ch0.ExternState.privKey, smtr)
+	err := smtr.Sign(ch0.ExternState.signer, smtr)
 	if err != nil {
 		t.Error(err)
 		return
@@ -928,7 +929,7 @@ func TestChannel_RegisterWithdrawRequest(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	unlock.Sign(ch0.ExternState.privKey, unlock)
+	unlock.Sign(ch0.ExternState.signer, unlock)
 	err = ch0.RegisterTransfer(blockNumber, unlock)
 	if err != nil {
 		t.Error(err)
@@ -951,7 +952,7 @@ func TestChannel_RegisterWithdrawRequest(t *testing.T) {
 	}
 	log.Trace(fmt.Sprintf("ch0=%s", utils.StringInterface(NewChannelSerialization(ch0), 3)))
 	log.Trace(fmt.Sprintf("req=%s", req))
-	req.Sign(ch0.ExternState.privKey, req)
+	req.Sign(ch0.ExternState.signer, req)
 	err = ch0.RegisterWithdrawRequest(req)
 	if err != nil {
 		t.Error(err)
@@ -972,7 +973,7 @@ func TestChannel_RegisterWithdrawRequest(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	res.Sign(ch1.ExternState.privKey, res)
+	res.Sign(ch1.ExternState.signer, res)
 	err = ch0.RegisterWithdrawResponse(res)
 	if err != nil {
 		t.Error(err)
@@ -992,7 +993,7 @@ func TestChannel_RegisterCooperativeSettleRequest(t *testing.T) {
 	secret := utils.Sha3([]byte("123"))
 	lockSecretHash := utils.Sha3(secret[:])
 	smtr, _ := ch0.CreateMediatedTransfer(ch0.OurState.Address, ch0.PartnerState.Address, utils.BigInt0, big.NewInt(1), expiration, lockSecretHash)
-	err := smtr.Sign(ch0.ExternState.privKey, smtr)
+	err := smtr.Sign(ch0.ExternState.signer, smtr)
 	if err != nil {
 		t.Error(err)
 		return
@@ -1028,7 +1029,7 @@ func TestChannel_RegisterCooperativeSettleRequest(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	unlock.Sign(ch0.ExternState.privKey, unlock)
+	unlock.Sign(ch0.ExternState.signer, unlock)
 	err = ch0.RegisterTransfer(blockNumber, unlock)
 	if err != nil {
 		t.Error(err)
@@ -1051,7 +1052,7 @@ func TestChannel_RegisterCooperativeSettleRequest(t *testing.T) {
 	}
 	log.Trace(fmt.Sprintf("ch0=%s", utils.StringInterface(NewChannelSerialization(ch0), 3)))
 	log.Trace(fmt.Sprintf("req=%s", req))
-	req.Sign(ch0.ExternState.privKey, req)
+	req.Sign(ch0.ExternState.signer, req)
 	err = ch0.RegisterCooperativeSettleRequest(req)
 	if err != nil {
 		t.Error(err)
@@ -1072,7 +1073,7 @@ func TestChannel_RegisterCooperativeSettleRequest(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	res.Sign(ch1.ExternState.privKey, res)
+	res.Sign(ch1.ExternState.signer, res)
 	err = ch0.RegisterCooperativeSettleResponse(res)
 	if err != nil {
 		t.Error(err)
@@ -31,6 +31,10 @@ type Db interface {
 		get the latest channel status
 	*/
 	GetChannelByAddress(channelAddress common.Hash) (c *Serialization, err error)
+	/*
+		remember what happened to a lock when unlocking it on chain.
+	*/
+	SaveUnlockOutcome(o *UnlockOutcome)
 
 	/*
 	 要记录自己放在某个 channel 上放弃了某个锁,到时候一定不能unlock
@@ -81,6 +85,10 @@ func (f *MockChannelDb) GetChannelByAddress(channelAddress common.Hash) (c *Seri
 	return nil, errors.New("not found")
 }
 
+//SaveUnlockOutcome remember what happened to a lock when unlocking it on chain.
+func (f *MockChannelDb) SaveUnlockOutcome(o *UnlockOutcome) {
+}
+
 //func (f *MockChannelDb) IsLockSecretHashChannelIdentifierDisposed(lockSecretHash common.Hash, ChannelIdentifier common.Hash) bool {
 //	key := utils.Sha3(lockSecretHash[:], ChannelIdentifier[:])
 //	return f.Keys[key]
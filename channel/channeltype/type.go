@@ -56,6 +56,7 @@ type Serialization struct {
 	ClosedBlock            int64
 	SettledBlock           int64
 	SettleTimeout          int
+	RegistryAddress        common.Address //registry of token network of this channel, a token can be registered on more than one registry
 }
 
 //ChannleAddress address of channel
@@ -0,0 +1,55 @@
+package channeltype
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//UnlockStatus is the progress of a lock being unlocked on chain
+type UnlockStatus string
+
+const (
+	//UnlockStatusPending the lock is planned to be unlocked
+	UnlockStatusPending UnlockStatus = "pending"
+	//UnlockStatusSkipped the lock is worth less than the gas needed to unlock it
+	UnlockStatusSkipped UnlockStatus = "skipped"
+	//UnlockStatusUnlocked the lock has been unlocked on chain
+	UnlockStatusUnlocked UnlockStatus = "unlocked"
+	//UnlockStatusFailed the unlock transaction failed
+	UnlockStatusFailed UnlockStatus = "failed"
+)
+
+/*
+UnlockOutcome records what happened to a lock when unlocking it on chain after channel closed.
+*/
+type UnlockOutcome struct {
+	Key               string `storm:"id"`
+	ChannelIdentifier common.Hash
+	LockSecretHash    common.Hash
+	Amount            *big.Int
+	Expiration        int64
+	Status            UnlockStatus
+	Error             string
+	UpdatedAt         time.Time
+}
+
+//NewUnlockOutcome create a outcome of `lockSecretHash` on `channel`
+func NewUnlockOutcome(channel, lockSecretHash common.Hash, amount *big.Int, expiration int64, status UnlockStatus, errMsg string) *UnlockOutcome {
+	return &UnlockOutcome{
+		Key:               UnlockOutcomeKey(channel, lockSecretHash),
+		ChannelIdentifier: channel,
+		LockSecretHash:    lockSecretHash,
+		Amount:            amount,
+		Expiration:        expiration,
+		Status:            status,
+		Error:             errMsg,
+		UpdatedAt:         time.Now(),
+	}
+}
+
+//UnlockOutcomeKey is the db key of outcome of `lockSecretHash` on `channel`
+func UnlockOutcomeKey(channel, lockSecretHash common.Hash) string {
+	return channel.String() + "-" + lockSecretHash.String()
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -25,7 +26,7 @@ func newTestBlockChainService() *rpc.BlockChainService {
 	if err != nil {
 		log.Crit("Failed to create authorized transactor: ", err)
 	}
-	return rpc.NewBlockChainService(privkey, rpc.PrivateRopstenRegistryAddress, conn)
+	return rpc.NewBlockChainService(signer.NewKeySigner(privkey), rpc.PrivateRopstenRegistryAddress, conn)
 }
 
 var testFuncRegisterChannelForHashlock = func(channel *Channel, hashlock common.Hash) {}
@@ -44,7 +45,7 @@ func makeTestExternState() *ExternalState {
 	}
 	return NewChannelExternalState(testFuncRegisterChannelForHashlock,
 		tokenNetwork, channelIdentifer,
-		bcs.PrivKey, bcs.Client,
+		bcs.Signer, bcs.Client,
 		nil, 0,
 		bcs.NodeAddress, utils.NewRandomAddress(),
 	)
@@ -0,0 +1,44 @@
+package channel
+
+import (
+	"math/big"
+	"sort"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+UnlockCoster estimates how much it costs to unlock one lock on chain, in unit of token.
+*/
+type UnlockCoster interface {
+	/*
+		UnlockCost returns the cost of one unlock transaction on `tokenNetwork`.
+		nil means the cost is unknown and no lock should be skipped.
+	*/
+	UnlockCost(tokenNetwork common.Address) *big.Int
+}
+
+/*
+PlanUnlocks decides which locks are worth unlocking and in which order.
+Locks whose amount is less than `minAmount` are skipped, because unlocking them costs more gas than they are worth.
+The others are unlocked biggest first, so we get the most value if we run out of time or gas,
+and among locks of the same amount, the one expires earlier goes first.
+*/
+func PlanUnlocks(proofs []*channeltype.UnlockProof, minAmount *big.Int) (plan, skipped []*channeltype.UnlockProof) {
+	for _, proof := range proofs {
+		if minAmount != nil && proof.Lock.Amount.Cmp(minAmount) < 0 {
+			skipped = append(skipped, proof)
+			continue
+		}
+		plan = append(plan, proof)
+	}
+	sort.SliceStable(plan, func(i, j int) bool {
+		c := plan[i].Lock.Amount.Cmp(plan[j].Lock.Amount)
+		if c != 0 {
+			return c > 0
+		}
+		return plan[i].Lock.Expiration < plan[j].Lock.Expiration
+	})
+	return
+}
@@ -0,0 +1,41 @@
+package channel
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestUnlockProof(amount int64, expiration int64) *channeltype.UnlockProof {
+	return &channeltype.UnlockProof{
+		Lock: &mtree.Lock{
+			Expiration:     expiration,
+			Amount:         big.NewInt(amount),
+			LockSecretHash: utils.NewRandomHash(),
+		},
+	}
+}
+
+func TestPlanUnlocks(t *testing.T) {
+	p1 := newTestUnlockProof(5, 100)
+	p2 := newTestUnlockProof(20, 90)
+	p3 := newTestUnlockProof(20, 80)
+	p4 := newTestUnlockProof(1, 10)
+	proofs := []*channeltype.UnlockProof{p1, p2, p3, p4}
+
+	plan, skipped := PlanUnlocks(proofs, nil)
+	assert.EqualValues(t, 0, len(skipped))
+	assert.EqualValues(t, []*channeltype.UnlockProof{p3, p2, p1, p4}, plan)
+
+	plan, skipped = PlanUnlocks(proofs, big.NewInt(5))
+	assert.EqualValues(t, []*channeltype.UnlockProof{p4}, skipped)
+	assert.EqualValues(t, []*channeltype.UnlockProof{p3, p2, p1}, plan)
+
+	plan, skipped = PlanUnlocks(proofs, big.NewInt(100))
+	assert.EqualValues(t, 0, len(plan))
+	assert.EqualValues(t, 4, len(skipped))
+}
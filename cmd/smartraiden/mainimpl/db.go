@@ -0,0 +1,297 @@
+package mainimpl
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	ethutils "github.com/ethereum/go-ethereum/cmd/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/slonzok/getpass"
+	"github.com/theckman/go-flock"
+	"gopkg.in/urfave/cli.v1"
+)
+
+//dbFileNames are names of db file of every storage backend
+var dbFileNames = map[string]string{
+	models.BackendBolt:   "log.db",
+	models.BackendSQLite: "log.sqlite",
+}
+
+/*
+dbFile returns path of db under `dir` for storage `backend`.
+if `backend` is empty, the existing db is used, a bolt one is preferred, and a new db is a bolt db.
+*/
+func dbFile(dir, backend string) (string, error) {
+	if len(backend) == 0 {
+		backend = models.BackendBolt
+		if !utils.Exists(filepath.Join(dir, dbFileNames[models.BackendBolt])) &&
+			utils.Exists(filepath.Join(dir, dbFileNames[models.BackendSQLite])) {
+			backend = models.BackendSQLite
+		}
+	}
+	name, ok := dbFileNames[backend]
+	if !ok {
+		return "", fmt.Errorf("unknown db-backend %s, must be %s or %s", backend, models.BackendBolt, models.BackendSQLite)
+	}
+	return filepath.Join(dir, name), nil
+}
+
+//userDbDir is where db of `address` is under `dataDir`
+func userDbDir(dataDir string, address common.Address) string {
+	return filepath.Join(dataDir, hex.EncodeToString(address[:])[:8])
+}
+
+var dbFlags = []cli.Flag{
+	cli.StringFlag{
+		Name:  "address",
+		Usage: "The ethereum address whose database to use.",
+	},
+	cli.StringFlag{
+		Name:  "password-file",
+		Usage: "Text file containing password for provided account, needed when the database is encrypted",
+	},
+	cli.StringFlag{
+		Name:  "db-backend",
+		Usage: "storage engine of the database, bolt or sqlite, the existing one is used if not specified",
+	},
+	ethutils.DirectoryFlag{
+		Name:  "datadir",
+		Usage: "Directory for storing raiden data.",
+		Value: ethutils.DirectoryString{Value: params.DefaultDataDir()},
+	},
+}
+
+var dbCommand = cli.Command{
+	Name:  "db",
+	Usage: "export, restore or compact the node database, the node must not be running",
+	Subcommands: []cli.Command{
+		{
+			Name:   "export",
+			Usage:  "dump channels, StateManagers, secrets and transfer history as json",
+			Action: dbExport,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "out",
+					Usage: "file to write to, stdout if not specified",
+				},
+			}, dbFlags...),
+		},
+		{
+			Name:   "rotate-key",
+			Usage:  "re-encrypt the database by a new key, derived from the new password of account if it's changed",
+			Action: dbRotateKey,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "new-password-file",
+					Usage: "Text file containing the new password of account, the current password is used if not specified",
+				},
+				cli.BoolFlag{
+					Name:  "decrypt",
+					Usage: "decrypt the database instead",
+				},
+			}, dbFlags...),
+		},
+		{
+			Name:   "compact",
+			Usage:  "move completed records to archive, then rewrite the database file to reclaim space of deleted records",
+			Action: dbCompact,
+			Flags: append([]cli.Flag{
+				cli.Int64Flag{
+					Name:  "archive-ack-age",
+					Usage: "also archive acks saved more than this many blocks ago, 0 means never",
+				},
+			}, dbFlags...),
+		},
+		{
+			Name:   "restore",
+			Usage:  "replace the database by a backup from /api/1/admin/backup, channels are checked on chain on next start",
+			Action: dbRestore,
+			Flags: append([]cli.Flag{
+				cli.StringFlag{
+					Name:  "from",
+					Usage: "backup file to restore",
+				},
+			}, dbFlags...),
+		},
+	},
+}
+
+//dbPathOfCtx returns database path from `address` and `datadir`, and locks it like a running node does.
+func dbPathOfCtx(ctx *cli.Context) (dbPath string, locker *flock.Flock, err error) {
+	if !common.IsHexAddress(ctx.String("address")) {
+		err = fmt.Errorf("address must be specified")
+		return
+	}
+	address := common.HexToAddress(ctx.String("address"))
+	dataDir := ctx.String("datadir")
+	if len(dataDir) == 0 {
+		dataDir = filepath.Join(utils.GetHomePath(), ".smartraiden")
+	}
+	dbPath, err = dbFile(userDbDir(dataDir, address), ctx.String("db-backend"))
+	if err != nil {
+		return
+	}
+	err = os.MkdirAll(filepath.Dir(dbPath), os.ModePerm)
+	if err != nil {
+		return
+	}
+	locker = flock.NewFlock(dbPath + ".flock.Lock")
+	locked, err := locker.TryLock()
+	if err != nil {
+		return
+	}
+	if !locked {
+		err = fmt.Errorf("smartraiden is running at %s, stop it first", dbPath)
+	}
+	return
+}
+
+func dbExport(ctx *cli.Context) (err error) {
+	dbPath, locker, err := dbPathOfCtx(ctx)
+	if err != nil {
+		return
+	}
+	defer locker.Unlock()
+	db, err := openDbOfCtx(ctx, dbPath)
+	if err != nil {
+		return
+	}
+	e, err := db.Export()
+	db.CloseDB()
+	if err != nil {
+		return
+	}
+	out := os.Stdout
+	if len(ctx.String("out")) > 0 {
+		out, err = os.OpenFile(ctx.String("out"), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+		if err != nil {
+			return
+		}
+		defer out.Close()
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(e)
+}
+
+func dbRestore(ctx *cli.Context) (err error) {
+	from := ctx.String("from")
+	if len(from) == 0 {
+		return fmt.Errorf("from must be specified")
+	}
+	dbPath, locker, err := dbPathOfCtx(ctx)
+	if err != nil {
+		return
+	}
+	defer locker.Unlock()
+	err = models.RestoreDb(from, dbPath)
+	if err != nil {
+		return
+	}
+	fmt.Printf("%s restored to %s, channels will be checked on chain on next start\n", from, dbPath)
+	return
+}
+
+func dbCompact(ctx *cli.Context) (err error) {
+	dbPath, locker, err := dbPathOfCtx(ctx)
+	if err != nil {
+		return
+	}
+	defer locker.Unlock()
+	db, err := openDbOfCtx(ctx, dbPath)
+	if err != nil {
+		return
+	}
+	r, err := db.Archive(ctx.Int64("archive-ack-age"))
+	db.CloseDB()
+	if err != nil {
+		return
+	}
+	before, after, err := models.CompactDb(dbPath)
+	if err != nil {
+		return
+	}
+	fmt.Printf("%d StateManagers and %d acks archived, %s compacted from %d to %d bytes\n", r.StateManagers, r.Acks, dbPath, before, after)
+	return
+}
+
+/*
+readPasswordFile returns content of `passwordfile`,
+like `password-file` of keystore, `passwordfile` itself is the password if it cannot be read.
+*/
+func readPasswordFile(passwordfile string) (password string, err error) {
+	//#nosec
+	data, err := ioutil.ReadFile(passwordfile)
+	if err != nil {
+		data = []byte(passwordfile)
+	}
+	return string(data), nil
+}
+
+//openDbOfCtx opens db at `dbPath`, asks for password if it's encrypted
+func openDbOfCtx(ctx *cli.Context, dbPath string) (db *models.ModelDB, err error) {
+	if !utils.Exists(dbPath) {
+		return nil, fmt.Errorf("db %s doesn't exist", dbPath)
+	}
+	var password string
+	if len(ctx.String("password-file")) > 0 {
+		password, err = readPasswordFile(ctx.String("password-file"))
+		if err != nil {
+			return
+		}
+	}
+	db, err = models.OpenDbWithPassword(dbPath, password, false)
+	if err == models.ErrDbEncrypted {
+		password = getpass.Prompt("Enter the password of account to decrypt db:")
+		db, err = models.OpenDbWithPassword(dbPath, password, false)
+	}
+	return
+}
+
+func dbRotateKey(ctx *cli.Context) (err error) {
+	dbPath, locker, err := dbPathOfCtx(ctx)
+	if err != nil {
+		return
+	}
+	defer locker.Unlock()
+	var password string
+	if !ctx.Bool("decrypt") {
+		passwordfile := ctx.String("new-password-file")
+		if len(passwordfile) == 0 {
+			passwordfile = ctx.String("password-file")
+		}
+		if len(passwordfile) > 0 {
+			password, err = readPasswordFile(passwordfile)
+		} else {
+			password = getpass.Prompt("Enter the password of account to encrypt db:")
+		}
+		if err != nil {
+			return
+		}
+		if len(password) == 0 {
+			return fmt.Errorf("password cannot be empty")
+		}
+	}
+	db, err := openDbOfCtx(ctx, dbPath)
+	if err != nil {
+		return
+	}
+	defer db.CloseDB()
+	err = db.ChangeEncryptionPassword(password)
+	if err != nil {
+		return
+	}
+	if len(password) == 0 {
+		fmt.Printf("%s is decrypted\n", dbPath)
+	} else {
+		fmt.Printf("%s is encrypted by a new key\n", dbPath)
+	}
+	return
+}
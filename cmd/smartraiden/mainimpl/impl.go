@@ -4,18 +4,17 @@ import (
 	"fmt"
 	"os"
 
-	"encoding/hex"
-
 	"path"
 
-	"path/filepath"
-
 	"encoding/json"
 	"os/signal"
 	"time"
 
 	"net"
 	"strconv"
+	"strings"
+
+	"math/big"
 
 	"github.com/SmartMeshFoundation/SmartRaiden"
 	"github.com/SmartMeshFoundation/SmartRaiden/accounts"
@@ -27,10 +26,10 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/restful"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	ethutils "github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/node"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -59,12 +58,33 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 		cli.StringFlag{
 			Name: "eth-rpc-endpoint",
 			Usage: `"host:port" address of ethereum JSON-RPC server.\n'
-	           'Also accepts a protocol prefix (ws:// or ipc channel) with optional port',`,
+	           'Also accepts a protocol prefix (ws:// or ipc channel) with optional port',
+	           'several servers separated by comma fail over to each other',`,
 			Value: node.DefaultIPCEndpoint("geth"),
 		},
+		cli.IntFlag{
+			Name:  "eth-rpc-quorum",
+			Usage: "contract calls and receipts are accepted only when this many eth rpc endpoints agree",
+			Value: 1,
+		},
+		cli.StringFlag{
+			Name:  "eth-rpc-mode",
+			Usage: "auto, push or poll. auto subscribes new blocks and events, and polls them when the endpoint doesn't support subscription, such as http",
+			Value: helper.ModeAuto,
+		},
+		cli.DurationFlag{
+			Name:  "eth-poll-interval",
+			Usage: "time between two polls of new blocks and events",
+			Value: params.DefaultEthPollInterval,
+		},
+		cli.Int64Flag{
+			Name:  "eth-poll-chunk-size",
+			Usage: "max blocks of events got by one poll",
+			Value: params.DefaultEthPollChunkSize,
+		},
 		cli.StringFlag{
 			Name:  "registry-contract-address",
-			Usage: `hex encoded address of the registry contract.`,
+			Usage: `hex encoded address of the registry contract, several registries separated by comma can be attached, the first one is the default`,
 			Value: params.RopstenRegistryAddress.String(),
 		},
 		cli.StringFlag{
@@ -72,6 +92,10 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 			Usage: `"host:port" for the raiden service to listen on.`,
 			Value: fmt.Sprintf("0.0.0.0:%d", params.InitialPort),
 		},
+		cli.StringFlag{
+			Name:  "public-address",
+			Usage: `"host:port" announced to channel partners, so they can reach this node directly by udp.`,
+		},
 		cli.StringFlag{
 			Name:  "api-address",
 			Usage: `host:port" for the RPC server to listen on.`,
@@ -86,6 +110,10 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 			Name:  "password-file",
 			Usage: "Text file containing password for provided account",
 		},
+		cli.StringFlag{
+			Name:  "signer-endpoint",
+			Usage: "sign by a remote signer at this unix socket path or http url instead of keystore, see cmd/tools/signerd",
+		},
 		cli.BoolFlag{
 			Name:  "debugcrash",
 			Usage: "enable debug crash feature",
@@ -116,9 +144,63 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 			Name:  "enable-health-check",
 			Usage: "enable health check ",
 		},
+		cli.BoolFlag{
+			Name:  "api-auth",
+			Usage: "restful api requires bearer token, the first admin token is written to datadir",
+		},
+		cli.StringFlag{
+			Name:  "api-tls-cert",
+			Usage: "certificate file of restful api, serve https when provided",
+		},
+		cli.StringFlag{
+			Name:  "api-tls-key",
+			Usage: "private key file of restful api certificate",
+		},
+		cli.StringFlag{
+			Name:  "db-backend",
+			Usage: "storage engine of a new database, bolt or sqlite, an existing database is used if not specified",
+		},
+		cli.BoolFlag{
+			Name:  "db-encryption",
+			Usage: "encrypt the database by a key derived from password of account, an encrypted database always needs the password",
+		},
+		cli.Int64Flag{
+			Name:  "archive-ack-age",
+			Usage: "acks of received messages saved more than this many blocks ago are moved to archive of the database, 0 means never",
+		},
+		cli.StringSliceFlag{
+			Name:  "token-per-ether",
+			Usage: `"token=amount" how many smallest unit of token one ether is worth. locks of this token worth less than the unlock gas are not unlocked on chain.`,
+		},
+		cli.BoolFlag{
+			Name:  "capacity-hints",
+			Usage: "send coarse capacity of channels to partners and avoid depleted channels by hints received from partners",
+		},
+		cli.IntFlag{
+			Name:  "capacity-hint-precision",
+			Usage: "powers of two one capacity bucket covers, larger tells partners less",
+			Value: params.DefaultCapacityHintPrecision,
+		},
+		cli.StringSliceFlag{
+			Name:  "capacity-hint-hide",
+			Usage: "partner whose channel's capacity is never told to anyone",
+		},
+		cli.StringFlag{
+			Name:  "pathfinder",
+			Usage: "url of pathfinding service, routes of mediated transfers are asked from it when none is found in my own graph",
+		},
+		cli.BoolFlag{
+			Name:  "pathfinder-post-hints",
+			Usage: "tell pathfinding service coarse capacity of channels, partners in capacity-hint-hide are never told",
+		},
+		cli.StringFlag{
+			Name:  "pathfinder-max-query-fee",
+			Usage: "the most paid for one query of pathfinding service, queries are never paid if not specified",
+		},
 	}
 	app.Flags = append(app.Flags, debug.Flags...)
 	app.Action = mainCtx
+	app.Commands = []cli.Command{dbCommand}
 	app.Name = "smartraiden"
 	app.Version = "1.0"
 	app.Before = func(ctx *cli.Context) error {
@@ -139,23 +221,32 @@ func StartMain() (*smartraiden.RaidenAPI, error) {
 func mainCtx(ctx *cli.Context) (err error) {
 	log.Info(fmt.Sprintf("Welcom to smartraiden,version %s\n", ctx.App.Version))
 	log.Info(fmt.Sprintf("os.args=%q", os.Args))
-	cfg, err := config(ctx)
+	cfg, s, err := config(ctx)
 	if err != nil {
 		return
 	}
 	//log.Debug(fmt.Sprintf("Config:%s", utils.StringInterface(cfg, 2)))
 	ethEndpoint := ctx.String("eth-rpc-endpoint")
-	client, err := helper.NewSafeClient(ethEndpoint)
+	client, err := helper.NewSafeClientWithQuorum(ethEndpoint, ctx.Int("eth-rpc-quorum"))
 	if err != nil {
 		err = fmt.Errorf("cannot connect to geth :%s err=%s", ethEndpoint, err)
 		return
 	}
-	bcs := rpc.NewBlockChainService(cfg.PrivateKey, cfg.RegistryAddress, client)
+	err = client.SetPollConfig(helper.PollConfig{
+		Mode:      ctx.String("eth-rpc-mode"),
+		Interval:  ctx.Duration("eth-poll-interval"),
+		ChunkSize: ctx.Int64("eth-poll-chunk-size"),
+	})
+	if err != nil {
+		client.Close()
+		return
+	}
+	bcs := rpc.NewBlockChainService(s, cfg.RegistryAddress, client)
 	transport, err := buildTransport(cfg, bcs)
 	if err != nil {
 		return
 	}
-	raidenService, err := smartraiden.NewRaidenService(bcs, cfg.PrivateKey, transport, cfg)
+	raidenService, err := smartraiden.NewRaidenService(bcs, s, transport, cfg)
 	if err != nil {
 		transport.Stop()
 		return
@@ -200,14 +291,14 @@ func buildTransport(cfg *params.Config, bcs *rpc.BlockChainService) (transport n
 		policy := network.NewTokenBucket(10, 1, time.Now)
 		transport, err = network.NewUDPTransport(utils.APex2(bcs.NodeAddress), cfg.Host, cfg.Port, nil, policy)
 	case params.XMPPOnly:
-		transport = network.NewXMPPTransport(utils.APex2(bcs.NodeAddress), cfg.XMPPServer, bcs.PrivKey, network.DeviceTypeOther)
+		transport = network.NewXMPPTransport(utils.APex2(bcs.NodeAddress), cfg.XMPPServer, bcs.Signer, network.DeviceTypeOther)
 	case params.MixUDPXMPP:
 		policy := network.NewTokenBucket(10, 1, time.Now)
 		deviceType := network.DeviceTypeOther
 		if params.MobileMode {
 			deviceType = network.DeviceTypeMobile
 		}
-		transport, err = network.NewMixTranspoter(utils.APex2(bcs.NodeAddress), cfg.XMPPServer, cfg.Host, cfg.Port, bcs.PrivKey, nil, policy, deviceType)
+		transport, err = network.NewMixTranspoter(utils.APex2(bcs.NodeAddress), cfg.XMPPServer, cfg.Host, cfg.Port, bcs.Signer, nil, policy, deviceType)
 	}
 	return
 }
@@ -222,7 +313,7 @@ func regQuitHandler(api *smartraiden.RaidenAPI) {
 		utils.SystemExit(0)
 	}()
 }
-func config(ctx *cli.Context) (config *params.Config, err error) {
+func config(ctx *cli.Context) (config *params.Config, s signer.Signer, err error) {
 	config = &params.DefaultConfig
 	listenhost, listenport, err := net.SplitHostPort(ctx.String("listen-address"))
 	if err != nil {
@@ -237,27 +328,31 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 	if err != nil {
 		return
 	}
+	config.PublicAddress = ctx.String("public-address")
+	if len(config.PublicAddress) > 0 {
+		_, _, err = net.SplitHostPort(config.PublicAddress)
+		if err != nil {
+			return
+		}
+	}
 	config.UseConsole = ctx.Bool("console")
 	config.APIHost = apihost
 	config.APIPort, err = strconv.Atoi(apiport)
 	if err != nil {
 		return
 	}
-	address := common.HexToAddress(ctx.String("address"))
-	address, privkeyBin, err := accounts.PromptAccount(address, ctx.String("keystore-path"), ctx.String("password-file"))
-	if err != nil {
-		return
-	}
-	config.PrivateKeyHex = hex.EncodeToString(privkeyBin)
-	config.PrivateKey, err = crypto.ToECDSA(privkeyBin)
-	config.MyAddress = address
+	s, config.DbPassword, err = newSigner(ctx)
 	if err != nil {
-		err = fmt.Errorf("privkey error: %s", err)
 		return
 	}
+	config.MyAddress = s.Address()
 	registAddrStr := ctx.String("registry-contract-address")
 	if len(registAddrStr) > 0 {
-		config.RegistryAddress = common.HexToAddress(registAddrStr)
+		config.RegistryAddresses, err = parseRegistryAddresses(registAddrStr)
+		if err != nil {
+			return
+		}
+		config.RegistryAddress = config.RegistryAddresses[0]
 	}
 	dataDir := ctx.String("datadir")
 	if len(dataDir) == 0 {
@@ -271,9 +366,7 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 			return
 		}
 	}
-	userDbPath := hex.EncodeToString(config.MyAddress[:])
-	userDbPath = userDbPath[:8]
-	userDbPath = filepath.Join(config.DataDir, userDbPath)
+	userDbPath := userDbDir(config.DataDir, config.MyAddress)
 	if !utils.Exists(userDbPath) {
 		err = os.MkdirAll(userDbPath, os.ModePerm)
 		if err != nil {
@@ -281,8 +374,10 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 			return
 		}
 	}
-	databasePath := filepath.Join(userDbPath, "log.db")
-	config.DataBasePath = databasePath
+	config.DataBasePath, err = dbFile(userDbPath, ctx.String("db-backend"))
+	if err != nil {
+		return
+	}
 	if ctx.Bool("debugcrash") {
 		config.DebugCrash = true
 		conditionquit := ctx.String("conditionquit")
@@ -306,5 +401,128 @@ func config(ctx *cli.Context) (config *params.Config, err error) {
 		config.EnableHealthCheck = true
 	}
 	config.XMPPServer = ctx.String("xmpp-server")
+	config.APIAuth = ctx.Bool("api-auth")
+	config.APITLSCert = ctx.String("api-tls-cert")
+	config.APITLSKey = ctx.String("api-tls-key")
+	if (len(config.APITLSCert) > 0) != (len(config.APITLSKey) > 0) {
+		err = fmt.Errorf("api-tls-cert and api-tls-key must be provided together")
+		return
+	}
+	config.EncryptDb = ctx.Bool("db-encryption")
+	if config.EncryptDb && len(config.DbPassword) == 0 {
+		err = fmt.Errorf("db-encryption needs password of account, provide password-file when using signer-endpoint")
+		return
+	}
+	config.ArchiveAckAge = ctx.Int64("archive-ack-age")
+	if config.ArchiveAckAge < 0 {
+		err = fmt.Errorf("archive-ack-age must not be negative")
+		return
+	}
+	config.TokenPerEther, err = parseTokenPerEther(ctx.StringSlice("token-per-ether"))
+	if err != nil {
+		return
+	}
+	config.CapacityHints.Enable = ctx.Bool("capacity-hints")
+	config.CapacityHints.Precision = ctx.Int("capacity-hint-precision")
+	if config.CapacityHints.Precision < 1 || config.CapacityHints.Precision > 255 {
+		err = fmt.Errorf("capacity-hint-precision must be between 1 and 255")
+		return
+	}
+	config.CapacityHints.Hidden = make(map[common.Address]bool)
+	for _, addr := range ctx.StringSlice("capacity-hint-hide") {
+		if !common.IsHexAddress(addr) {
+			err = fmt.Errorf("capacity-hint-hide %s is not an address", addr)
+			return
+		}
+		config.CapacityHints.Hidden[common.HexToAddress(addr)] = true
+	}
+	config.PathfinderURL = ctx.String("pathfinder")
+	config.PathfinderPostHints = ctx.Bool("pathfinder-post-hints")
+	if config.PathfinderPostHints && len(config.PathfinderURL) == 0 {
+		err = fmt.Errorf("pathfinder-post-hints needs pathfinder")
+		return
+	}
+	if maxFee := ctx.String("pathfinder-max-query-fee"); len(maxFee) > 0 {
+		fee, ok := new(big.Int).SetString(maxFee, 10)
+		if !ok || fee.Sign() < 0 {
+			err = fmt.Errorf("pathfinder-max-query-fee %s is not a valid amount", maxFee)
+			return
+		}
+		config.PathfinderMaxQueryFee = fee
+	}
+	return
+}
+
+/*
+newSigner connects to remote signer when `signer-endpoint` is provided,
+otherwise unlocks the account in keystore.
+password of account is returned for db encryption, when using remote signer, it's read from `password-file` if provided.
+*/
+func newSigner(ctx *cli.Context) (s signer.Signer, password string, err error) {
+	address := common.HexToAddress(ctx.String("address"))
+	endpoint := ctx.String("signer-endpoint")
+	if len(endpoint) > 0 {
+		s, err = signer.NewRemoteSigner(endpoint)
+		if err != nil {
+			err = fmt.Errorf("connect to signer %s err %s", endpoint, err)
+			return
+		}
+		if address != utils.EmptyAddress && address != s.Address() {
+			err = fmt.Errorf("signer %s signs for %s, not %s", endpoint, s.Address().String(), address.String())
+			return
+		}
+		if len(ctx.String("password-file")) > 0 {
+			password, err = readPasswordFile(ctx.String("password-file"))
+		}
+		return
+	}
+	keystorePath := ctx.String("keystore-path")
+	address, password, err = accounts.PromptAccountPassword(address, keystorePath, ctx.String("password-file"))
+	if err != nil {
+		return
+	}
+	s, err = signer.NewKeystoreSigner(keystorePath, address, password)
+	return
+}
+
+//parseRegistryAddresses parses registries separated by comma
+func parseRegistryAddresses(s string) (registries []common.Address, err error) {
+	for _, r := range strings.Split(s, ",") {
+		r = strings.TrimSpace(r)
+		if len(r) == 0 {
+			continue
+		}
+		if !common.IsHexAddress(r) {
+			return nil, fmt.Errorf("invalid registry address %s", r)
+		}
+		registry := common.HexToAddress(r)
+		for _, r2 := range registries {
+			if r2 == registry {
+				return nil, fmt.Errorf("registry %s is repeated", r)
+			}
+		}
+		registries = append(registries, registry)
+	}
+	if len(registries) == 0 {
+		err = fmt.Errorf("no registry address")
+	}
+	return
+}
+
+func parseTokenPerEther(vs []string) (m map[common.Address]*big.Int, err error) {
+	m = make(map[common.Address]*big.Int)
+	for _, v := range vs {
+		ss := strings.Split(v, "=")
+		if len(ss) != 2 || !common.IsHexAddress(ss[0]) {
+			err = fmt.Errorf("token-per-ether %s format error", v)
+			return
+		}
+		amount, ok := new(big.Int).SetString(ss[1], 0)
+		if !ok || amount.Sign() <= 0 {
+			err = fmt.Errorf("token-per-ether %s amount error", v)
+			return
+		}
+		m[common.HexToAddress(ss[0])] = amount
+	}
 	return
 }
@@ -45,3 +45,16 @@ func TestStruct(t *testing.T) {
 func TestStartMain(t *testing.T) {
 	StartMain()
 }
+
+func TestParseRegistryAddresses(t *testing.T) {
+	r1, r2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+	rs, err := parseRegistryAddresses(r1.String() + ", " + r2.String())
+	if err != nil || len(rs) != 2 || rs[0] != r1 || rs[1] != r2 {
+		t.Errorf("parse err %v %v", rs, err)
+	}
+	for _, s := range []string{"", " , ", "0x123", r1.String() + "," + r1.String()} {
+		if _, err = parseRegistryAddresses(s); err == nil {
+			t.Errorf("%s should be invalid", s)
+		}
+	}
+}
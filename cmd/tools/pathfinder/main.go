@@ -0,0 +1,158 @@
+package main
+
+import (
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/blockchain"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/pathfinder"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/node"
+	"github.com/urfave/cli"
+)
+
+/*
+pathfinder is a reference pathfinding service for smartraiden `--pathfinder`.
+it builds graphs of all tokens of a registry from events on chain, learns capacity of channels from hints nodes post,
+and answers queries of paths. queries are charged by IOUs to `address` when query-fee is not 0, IOUs are only kept in memory.
+*/
+func main() {
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "eth-rpc-endpoint",
+			Usage: `"host:port" address of ethereum JSON-RPC server, several servers separated by comma fail over to each other`,
+			Value: node.DefaultIPCEndpoint("geth"),
+		},
+		cli.StringFlag{
+			Name:  "registry-contract-address",
+			Usage: `hex encoded address of the registry contract.`,
+			Value: params.RopstenRegistryAddress.String(),
+		},
+		cli.StringFlag{
+			Name:  "http",
+			Usage: `"host:port" to listen http`,
+			Value: "127.0.0.1:5200",
+		},
+		cli.StringFlag{
+			Name:  "address",
+			Usage: "The ethereum address IOUs pay to",
+			Value: utils.EmptyAddress.String(),
+		},
+		cli.Int64Flag{
+			Name:  "query-fee",
+			Usage: "how much one query costs, 0 means free",
+		},
+		cli.Int64Flag{
+			Name:  "hop-fee",
+			Usage: "fee every mediator is expected to charge, used to rank paths and tell the fee",
+		},
+	}
+	app.Action = mainctx
+	app.Name = "pathfinder"
+	app.Version = "0.1"
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Crit(err.Error())
+	}
+}
+
+func mainctx(ctx *cli.Context) error {
+	registryAddress := common.HexToAddress(ctx.String("registry-contract-address"))
+	address := common.HexToAddress(ctx.String("address"))
+	queryFee := big.NewInt(ctx.Int64("query-fee"))
+	hopFee := big.NewInt(ctx.Int64("hop-fee"))
+	if queryFee.Sign() < 0 || hopFee.Sign() < 0 {
+		return fmt.Errorf("fee must not be negative")
+	}
+	if queryFee.Sign() > 0 && address == utils.EmptyAddress {
+		return fmt.Errorf("address must be specified to charge queries")
+	}
+	client, err := helper.NewSafeClient(ctx.String("eth-rpc-endpoint"))
+	if err != nil {
+		return err
+	}
+	registry, err := contracts.NewTokenNetworkRegistry(registryAddress, client)
+	if err != nil {
+		return err
+	}
+	secretRegistryAddress, err := registry.Secret_registry_address(nil)
+	if err != nil {
+		return err
+	}
+	server := pathfinder.NewServer(address, hopFee, queryFee)
+	be := blockchain.NewBlockChainEvents(client, registryAddress, secretRegistryAddress, nil)
+	tokenNetworks, err := be.GetAllTokenNetworks(0)
+	if err != nil {
+		return err
+	}
+	tokenNetwork2Token := make(map[common.Address]common.Address)
+	for _, tn := range tokenNetworks {
+		tokenNetwork2Token[tn.Token_network_address] = tn.Token_address
+		server.AddToken(tn.Token_address)
+	}
+	err = be.Start(0)
+	if err != nil {
+		return err
+	}
+	defer be.Stop()
+	go func() {
+		for st := range be.StateChangeChannel {
+			handleStateChange(server, tokenNetwork2Token, st)
+		}
+	}()
+	l, err := net.Listen("tcp", ctx.String("http"))
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	go func() {
+		err := http.Serve(l, server)
+		if err != nil {
+			log.Error(fmt.Sprintf("serve http err %s", err))
+		}
+	}()
+	log.Info(fmt.Sprintf("pathfinder of registry %s listen on http://%s", registryAddress.String(), l.Addr()))
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+	return nil
+}
+
+//handleStateChange keeps channels of the server the same as on chain
+func handleStateChange(server *pathfinder.Server, tokenNetwork2Token map[common.Address]common.Address, st transfer.StateChange) {
+	switch st2 := st.(type) {
+	case *mediatedtransfer.ContractTokenAddedStateChange:
+		tokenNetwork2Token[st2.TokenNetworkAddress] = st2.TokenAddress
+		server.AddToken(st2.TokenAddress)
+	case *mediatedtransfer.ContractNewChannelStateChange:
+		token, ok := tokenNetwork2Token[st2.TokenNetworkAddress]
+		if !ok {
+			log.Warn(fmt.Sprintf("channel %s of unknown token network %s", st2.ChannelIdentifier.String(), utils.APex2(st2.TokenNetworkAddress)))
+			return
+		}
+		server.AddChannel(token, st2.ChannelIdentifier.ChannelIdentifier, st2.Participant1, st2.Participant2)
+	case *mediatedtransfer.ContractBalanceStateChange:
+		server.SetDeposit(st2.ChannelIdentifier, st2.ParticipantAddress, st2.Balance)
+	case *mediatedtransfer.ContractChannelWithdrawStateChange:
+		server.SetDeposit(st2.ChannelIdentifier.ChannelIdentifier, st2.Participant1, st2.Participant1Balance)
+		server.SetDeposit(st2.ChannelIdentifier.ChannelIdentifier, st2.Participant2, st2.Participant2Balance)
+	case *mediatedtransfer.ContractClosedStateChange:
+		server.RemoveChannel(st2.ChannelIdentifier)
+	case *mediatedtransfer.ContractSettledStateChange:
+		server.RemoveChannel(st2.ChannelIdentifier)
+	case *mediatedtransfer.ContractCooperativeSettledStateChange:
+		server.RemoveChannel(st2.ChannelIdentifier)
+	}
+}
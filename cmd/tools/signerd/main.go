@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/accounts"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rpc"
+	"gopkg.in/urfave/cli.v1"
+)
+
+/*
+signerd is a reference signer daemon for smartraiden `--signer-endpoint`.
+it unlocks an account in keystore and signs messages and transactions by json-rpc over unix socket or http,
+so the key lives in this process instead of smartraiden.
+anyone who can connect can sign, protect the unix socket by file permission, and only listen http on localhost.
+*/
+func main() {
+	app := cli.NewApp()
+	app.Flags = []cli.Flag{
+		cli.StringFlag{
+			Name:  "address",
+			Usage: "The ethereum address to sign for, a keystore file of it must exist.",
+			Value: utils.EmptyAddress.String(),
+		},
+		cli.StringFlag{
+			Name:  "keystore-path",
+			Usage: "If you have a non-standard path for the ethereum keystore directory provide it using this argument. ",
+			Value: params.DefaultKeyStoreDir(),
+		},
+		cli.StringFlag{
+			Name:  "password-file",
+			Usage: "Text file containing password for provided account",
+		},
+		cli.StringFlag{
+			Name:  "ipc",
+			Usage: "path of unix socket to listen",
+		},
+		cli.StringFlag{
+			Name:  "http",
+			Usage: `"host:port" to listen http, for example 127.0.0.1:5100`,
+		},
+	}
+	app.Action = mainctx
+	app.Name = "signerd"
+	app.Version = "0.1"
+	err := app.Run(os.Args)
+	if err != nil {
+		log.Crit(err.Error())
+	}
+}
+
+func mainctx(ctx *cli.Context) error {
+	ipcPath := ctx.String("ipc")
+	httpAddr := ctx.String("http")
+	if len(ipcPath) == 0 && len(httpAddr) == 0 {
+		return fmt.Errorf("ipc or http must be specified")
+	}
+	keystorePath := ctx.String("keystore-path")
+	address, password, err := accounts.PromptAccountPassword(common.HexToAddress(ctx.String("address")), keystorePath, ctx.String("password-file"))
+	if err != nil {
+		return err
+	}
+	s, err := signer.NewKeystoreSigner(keystorePath, address, password)
+	if err != nil {
+		return err
+	}
+	server, err := signer.NewServer(s)
+	if err != nil {
+		return err
+	}
+	defer server.Stop()
+	if len(ipcPath) > 0 {
+		var l net.Listener
+		l, err = rpc.CreateIPCListener(ipcPath)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go func() {
+			err := server.ServeListener(l)
+			if err != nil {
+				log.Error(fmt.Sprintf("serve ipc err %s", err))
+			}
+		}()
+		log.Info(fmt.Sprintf("signer for %s listen on %s", address.String(), ipcPath))
+	}
+	if len(httpAddr) > 0 {
+		var l net.Listener
+		l, err = net.Listen("tcp", httpAddr)
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		go func() {
+			err := http.Serve(l, server)
+			if err != nil {
+				log.Error(fmt.Sprintf("serve http err %s", err))
+			}
+		}()
+		log.Info(fmt.Sprintf("signer for %s listen on http://%s", address.String(), httpAddr))
+	}
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, os.Interrupt)
+	<-quit
+	return nil
+}
@@ -82,13 +82,16 @@ func (env *RaidenEnvReader) RefreshTokens() {
 	if err != nil {
 		panic(err)
 	}
-	var tokenAddrs []string
-	err = json.Unmarshal(body, &tokenAddrs)
+	var tokens []struct {
+		TokenAddress string `json:"token_address"`
+	}
+	err = json.Unmarshal(body, &tokens)
 	if err != nil {
 		panic(err)
 	}
 	env.Tokens = []*Token{}
-	for _, addr := range tokenAddrs {
+	for _, t := range tokens {
+		addr := t.TokenAddress
 		if env.HasToken(addr) {
 			continue
 		}
@@ -8,8 +8,6 @@ import (
 	"encoding/hex"
 	"path/filepath"
 
-	"crypto/ecdsa"
-
 	"bytes"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/accounts"
@@ -20,6 +18,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -85,7 +84,7 @@ func main() {
 type withDraw struct {
 	Address                common.Address
 	Conn                   *helper.SafeEthClient
-	PrivateKey             *ecdsa.PrivateKey
+	Signer                 signer.Signer
 	DbPath                 string
 	bcs                    *rpc.BlockChainService
 	db                     *models.ModelDB
@@ -126,7 +125,7 @@ func mainctx(ctx *cli.Context) error {
 	if err != nil {
 		log.Crit("private key is invalid, wrong password?")
 	}
-	w.PrivateKey = privateKey
+	w.Signer = signer.NewKeySigner(privateKey)
 
 	//db path
 	userDbPath := hex.EncodeToString(address[:])
@@ -137,7 +136,7 @@ func mainctx(ctx *cli.Context) error {
 		log.Crit("data directory is invalid ,doesn't contain db")
 	}
 	w.openDb()
-	w.bcs = rpc.NewBlockChainService(privateKey, w.db.GetRegistryAddress(), w.Conn)
+	w.bcs = rpc.NewBlockChainService(w.Signer, w.db.GetRegistryAddress(), w.Conn)
 	err = w.restoreChannel()
 	if err != nil {
 		log.Error(fmt.Sprintf("restore channel %s", err))
@@ -161,7 +160,7 @@ func (w *withDraw) channelSerilization2Channel(c *channeltype.Serialization, tok
 		c.PartnerContractBalance,
 		c.PartnerBalanceProof, mtree.NewMerkleTree(c.PartnerLeaves))
 	ExternState := channel.NewChannelExternalState(nil, tokenNetwork,
-		c.ChannelIdentifier, w.PrivateKey,
+		c.ChannelIdentifier, w.Signer,
 		w.Conn, w.db, c.ClosedBlock,
 		c.OurAddress, c.PartnerAddress())
 	ch, err = channel.NewChannel(OurState, PartnerState, ExternState, c.TokenAddress(), c.ChannelIdentifier, c.RevealTimeout, c.SettleTimeout)
@@ -229,7 +228,7 @@ func (w *withDraw) WithDrawOnChannel() {
 				return
 			}
 			if !isReg {
-				err = w.bcs.SecretRegistryProxy.RegisterSecret(w.Secret)
+				err = w.bcs.SecretRegistryProxy.RegisterSecret(w.Secret, 0)
 				if err != nil {
 					log.Error(fmt.Sprintf("RegisterSecret %s", err))
 				}
@@ -8,8 +8,6 @@ import (
 
 	"time"
 
-	"encoding/hex"
-
 	"sync"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/accounts"
@@ -19,6 +17,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/fee"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -36,21 +35,19 @@ func newTestRaiden() *RaidenService {
 
 func newTestRaidenWithPolicy(feePolicy fee.Charger) *RaidenService {
 	bcs := newTestBlockChainService()
-	transport := network.MakeTestMixTransport(utils.APex2(bcs.NodeAddress), bcs.PrivKey)
+	transport := network.MakeTestMixTransport(utils.APex2(bcs.NodeAddress), bcs.Signer)
 	config := params.DefaultConfig
 	config.MyAddress = bcs.NodeAddress
-	config.PrivateKey = bcs.PrivKey
 	config.DataDir = path.Join(os.TempDir(), utils.RandomString(10))
 	log.Info(fmt.Sprintf("DataDir=%s", config.DataDir))
 	config.RevealTimeout = 10
 	config.SettleTimeout = 600
-	config.PrivateKeyHex = hex.EncodeToString(crypto.FromECDSA(config.PrivateKey))
 	err := os.MkdirAll(config.DataDir, os.ModePerm)
 	if err != nil {
 		log.Error(err.Error())
 	}
 	config.DataBasePath = path.Join(config.DataDir, "log.db")
-	rd, err := NewRaidenService(bcs, bcs.PrivKey, transport, &config)
+	rd, err := NewRaidenService(bcs, bcs.Signer, transport, &config)
 	if err != nil {
 		log.Error(err.Error())
 	}
@@ -89,7 +86,7 @@ func newTestBlockChainService() *rpc.BlockChainService {
 	}
 	privkey, _ := testGetnextValidAccount()
 	//	log.Trace(fmt.Sprintf("privkey=%s,addr=%s", privkey, addr.String()))
-	return rpc.NewBlockChainService(privkey, rpc.PrivateRopstenRegistryAddress, conn)
+	return rpc.NewBlockChainService(signer.NewKeySigner(privkey), rpc.PrivateRopstenRegistryAddress, conn)
 }
 
 func makeTestRaidens() (r1, r2, r3 *RaidenService) {
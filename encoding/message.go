@@ -4,8 +4,6 @@ import (
 	"bytes"
 	"encoding/binary"
 
-	"crypto/ecdsa"
-
 	"math/big"
 
 	"errors"
@@ -18,6 +16,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -67,6 +66,14 @@ const (
 		refund 响应,
 	*/
 	AnnounceDisposedTransferResponseCmdID
+	/*
+		节点公布自己的公网 udp 地址
+	*/
+	NodeEndpointCmdID
+	/*
+		节点告知伙伴自己通道的大致容量
+	*/
+	CapacityHintCmdID
 )
 
 const signatureLength = 65
@@ -140,7 +147,7 @@ func (cmd *CmdStruct) Name() string {
 type SignedMessager interface {
 	Messager
 	GetSender() common.Address
-	Sign(priveKey *ecdsa.PrivateKey, pack MessagePacker) error
+	Sign(s signer.Signer, pack MessagePacker) error
 	verifySignature(data []byte) error
 }
 
@@ -182,6 +189,10 @@ func (t MessageType) String() string {
 		return "WithdrawRequest"
 	case WithdrawResponseCmdID:
 		return "WithdrawResponse"
+	case NodeEndpointCmdID:
+		return "NodeEndpoint"
+	case CapacityHintCmdID:
+		return "CapacityHint"
 	default:
 		return "<unknown>"
 	}
@@ -259,13 +270,17 @@ func (m *SignedMessage) GetSender() common.Address {
 }
 
 //Sign this message
-func (m *SignedMessage) Sign(priveKey *ecdsa.PrivateKey, pack MessagePacker) error {
+func (m *SignedMessage) Sign(s signer.Signer, pack MessagePacker) error {
 	if len(m.Signature) > 0 {
 		log.Warn("duplicate Sign")
 		return errors.New("duplicate Sign")
 	}
-	m.Signature = SignMessage(priveKey, pack)
-	m.Sender = crypto.PubkeyToAddress(priveKey.PublicKey)
+	sig, err := SignMessage(s, pack)
+	if err != nil {
+		return err
+	}
+	m.Signature = sig
+	m.Sender = s.Address()
 	return nil
 }
 
@@ -280,13 +295,9 @@ func (m *SignedMessage) verifySignature(data []byte) error {
 }
 
 //SignMessage signs a message
-func SignMessage(privKey *ecdsa.PrivateKey, pack MessagePacker) []byte {
+func SignMessage(s signer.Signer, pack MessagePacker) ([]byte, error) {
 	data := pack.Pack()
-	sig, err := utils.SignData(privKey, data)
-	if err != nil {
-		panic(fmt.Sprintf("SignMessage error %s", err))
-	}
-	return sig
+	return s.SignData(data)
 }
 
 //HashMessageWithoutSignature returns the raw hash of this message
@@ -561,17 +572,17 @@ func (m *EnvelopMessage) signData(datahash common.Hash) []byte {
 /*
 Sign data=(once+transferamount+locksroot+channel+hash(data))
 */
-func (m *EnvelopMessage) Sign(privKey *ecdsa.PrivateKey, msg MessagePacker) error {
+func (m *EnvelopMessage) Sign(s signer.Signer, msg MessagePacker) error {
 	data := msg.Pack() //before signed, Sign twice will be error
 	datahash := utils.Sha3(data)
 	//compute data to Sign
 	dataToSign := m.signData(datahash)
-	sig, err := utils.SignData(privKey, dataToSign)
+	sig, err := s.SignData(dataToSign)
 	if err != nil {
 		return err
 	}
 	m.Signature = sig
-	m.Sender = crypto.PubkeyToAddress(privKey.PublicKey)
+	m.Sender = s.Address()
 	return nil
 }
 
@@ -1066,17 +1077,17 @@ func (m *AnnounceDisposed) GetAdditionalHash() common.Hash {
 /*
 Sign data=(once+transferamount+locksroot+channel+hash(data))
 */
-func (m *AnnounceDisposed) Sign(privKey *ecdsa.PrivateKey, msg MessagePacker) error {
+func (m *AnnounceDisposed) Sign(s signer.Signer, msg MessagePacker) error {
 	data := msg.Pack() //before signed, Sign twice will be error
 	datahash := utils.Sha3(data)
 	//compute data to Sign
 	dataToSign := m.signData(datahash)
-	sig, err := utils.SignData(privKey, dataToSign)
+	sig, err := s.SignData(dataToSign)
 	if err != nil {
 		return err
 	}
 	m.Signature = sig
-	m.Sender = crypto.PubkeyToAddress(privKey.PublicKey)
+	m.Sender = s.Address()
 	return nil
 }
 
@@ -1287,17 +1298,17 @@ func (m *WithdrawRequest) signDataForContract() []byte {
 }
 
 //Sign is SignedMessager
-func (m *WithdrawRequest) Sign(key *ecdsa.PrivateKey, msg MessagePacker) (err error) {
-	m.Participant1Signature, err = utils.SignData(key, m.signDataForContract())
+func (m *WithdrawRequest) Sign(s signer.Signer, msg MessagePacker) (err error) {
+	m.Participant1Signature, err = s.SignData(m.signDataForContract())
 	if err != nil {
 		return
 	}
 	data := msg.Pack()
-	m.Signature, err = utils.SignData(key, data)
+	m.Signature, err = s.SignData(data)
 	if err != nil {
 		return
 	}
-	m.Sender = crypto.PubkeyToAddress(key.PublicKey)
+	m.Sender = s.Address()
 	return
 }
 
@@ -1425,14 +1436,14 @@ func (m *WithdrawResponse) signDataForContract() []byte {
 }
 
 //Sign is SignedMessager
-func (m *WithdrawResponse) Sign(key *ecdsa.PrivateKey, msg MessagePacker) (err error) {
-	m.Participant2Signature, err = utils.SignData(key, m.signDataForContract())
+func (m *WithdrawResponse) Sign(s signer.Signer, msg MessagePacker) (err error) {
+	m.Participant2Signature, err = s.SignData(m.signDataForContract())
 	if err != nil {
 		return
 	}
 	data := msg.Pack()
-	m.Signature, err = utils.SignData(key, data)
-	m.Sender = crypto.PubkeyToAddress(key.PublicKey)
+	m.Signature, err = s.SignData(data)
+	m.Sender = s.Address()
 	return
 }
 
@@ -1557,17 +1568,17 @@ func (m *SettleRequest) signDataForContract() []byte {
 }
 
 //Sign is SignedMessager
-func (m *SettleRequest) Sign(key *ecdsa.PrivateKey, msg MessagePacker) (err error) {
-	m.Participant1Signature, err = utils.SignData(key, m.signDataForContract())
+func (m *SettleRequest) Sign(s signer.Signer, msg MessagePacker) (err error) {
+	m.Participant1Signature, err = s.SignData(m.signDataForContract())
 	if err != nil {
 		return
 	}
 	data := msg.Pack()
-	m.Signature, err = utils.SignData(key, data)
+	m.Signature, err = s.SignData(data)
 	if err != nil {
 		return
 	}
-	m.Sender = crypto.PubkeyToAddress(key.PublicKey)
+	m.Sender = s.Address()
 	return
 }
 
@@ -1692,20 +1703,256 @@ func (m *SettleResponse) signDataForContract() []byte {
 }
 
 //Sign is SignedMessager
-func (m *SettleResponse) Sign(key *ecdsa.PrivateKey, msg MessagePacker) (err error) {
-	m.Participant2Signature, err = utils.SignData(key, m.signDataForContract())
+func (m *SettleResponse) Sign(s signer.Signer, msg MessagePacker) (err error) {
+	m.Participant2Signature, err = s.SignData(m.signDataForContract())
 	if err != nil {
 		return
 	}
 	data := msg.Pack()
-	m.Signature, err = utils.SignData(key, data)
+	m.Signature, err = s.SignData(data)
 	if err != nil {
 		return
 	}
-	m.Sender = crypto.PubkeyToAddress(key.PublicKey)
+	m.Sender = s.Address()
 	return
 }
 
+/*
+NodeEndpoint announces where a node can be reached directly by udp.
+It is signed by the announcing node and valid until `Expiration`(unix seconds),
+so it can be stored and used by others without trusting the relayer.
+*/
+type NodeEndpoint struct {
+	SignedMessage
+	HostPort   string //public "host:port" of udp listening
+	DeviceType string
+	Expiration int64
+}
+
+//NewNodeEndpoint create NodeEndpoint
+func NewNodeEndpoint(hostport, deviceType string, expiration int64) *NodeEndpoint {
+	p := &NodeEndpoint{
+		HostPort:   hostport,
+		DeviceType: deviceType,
+		Expiration: expiration,
+	}
+	p.CmdID = NodeEndpointCmdID
+	return p
+}
+
+func writeShortString(buf *bytes.Buffer, s string) error {
+	if len(s) > 255 {
+		return fmt.Errorf("string too long %s", s)
+	}
+	err := buf.WriteByte(byte(len(s)))
+	if err != nil {
+		return err
+	}
+	_, err = buf.WriteString(s)
+	return err
+}
+
+func readShortString(buf *bytes.Buffer) (s string, err error) {
+	l, err := buf.ReadByte()
+	if err != nil {
+		return
+	}
+	data := make([]byte, l)
+	n, err := buf.Read(data)
+	if err != nil && l > 0 {
+		return
+	}
+	if n != int(l) {
+		err = errPacketLength
+		return
+	}
+	return string(data), nil
+}
+
+//Pack is MessagePacker
+func (m *NodeEndpoint) Pack() []byte {
+	buf := new(bytes.Buffer)
+	//host and device type longer than 255 can't be packed, stop at the first error
+	err := binary.Write(buf, binary.LittleEndian, m.CmdID) //only one byte.
+	if err == nil {
+		err = binary.Write(buf, binary.BigEndian, m.Expiration)
+	}
+	if err == nil {
+		err = writeShortString(buf, m.HostPort)
+	}
+	if err == nil {
+		err = writeShortString(buf, m.DeviceType)
+	}
+	if err == nil {
+		_, err = buf.Write(m.Signature)
+	}
+	if err != nil {
+		log.Crit(fmt.Sprintf("NodeEndpoint Pack err %s", err))
+	}
+	return buf.Bytes()
+}
+
+//UnPack is MessageUnPacker
+func (m *NodeEndpoint) UnPack(data []byte) error {
+	var t int32
+	var err error
+	m.CmdID = NodeEndpointCmdID
+	buf := bytes.NewBuffer(data)
+	err = binary.Read(buf, binary.LittleEndian, &t)
+	if t != m.CmdID {
+		return fmt.Errorf("NodeEndpoint UnPack cmdid expect=%d,got=%d", NodeEndpointCmdID, t)
+	}
+	err = binary.Read(buf, binary.BigEndian, &m.Expiration)
+	if err != nil {
+		return err
+	}
+	m.HostPort, err = readShortString(buf)
+	if err != nil {
+		return err
+	}
+	m.DeviceType, err = readShortString(buf)
+	if err != nil {
+		return err
+	}
+	m.Signature = make([]byte, signatureLength)
+	n, err := buf.Read(m.Signature)
+	if err != nil {
+		return err
+	}
+	if n != signatureLength {
+		return errPacketLength
+	}
+	return m.verifySignature(data)
+}
+
+//String is fmt.Stringer
+func (m *NodeEndpoint) String() string {
+	return fmt.Sprintf("Message{type=NodeEndpoint hostport=%s,devicetype=%s,expiration=%d,sender=%s,has signature=%v}",
+		m.HostPort, m.DeviceType, m.Expiration, utils.APex2(m.Sender), len(m.Signature) != 0)
+}
+
+//CapacityBucket is the coarse capacity of the sender's side of one channel
+type CapacityBucket struct {
+	Partner common.Address
+	Bucket  uint8 //capacity is less than 2^Bucket
+}
+
+/*
+CapacityHint tells partners roughly how much the sender can send through its channels of `Token`,
+so that they can avoid depleted channels when finding a path.
+It is signed by the sender and valid until `Expiration`(unix seconds).
+A bucket covers `Precision` powers of two, see graph.CapacityBucketRange.
+*/
+type CapacityHint struct {
+	SignedMessage
+	Token      common.Address
+	Expiration int64
+	Precision  uint8
+	Buckets    []CapacityBucket
+}
+
+//NewCapacityHint create CapacityHint
+func NewCapacityHint(token common.Address, expiration int64, precision uint8, buckets []CapacityBucket) *CapacityHint {
+	p := &CapacityHint{
+		Token:      token,
+		Expiration: expiration,
+		Precision:  precision,
+		Buckets:    buckets,
+	}
+	p.CmdID = CapacityHintCmdID
+	return p
+}
+
+//Pack is MessagePacker
+func (m *CapacityHint) Pack() []byte {
+	buf := new(bytes.Buffer)
+	if len(m.Buckets) > 255 {
+		log.Crit(fmt.Sprintf("CapacityHint too many buckets %d", len(m.Buckets)))
+	}
+	err := binary.Write(buf, binary.LittleEndian, m.CmdID) //only one byte.
+	if err == nil {
+		_, err = buf.Write(m.Token[:])
+	}
+	if err == nil {
+		err = binary.Write(buf, binary.BigEndian, m.Expiration)
+	}
+	if err == nil {
+		err = buf.WriteByte(m.Precision)
+	}
+	if err == nil {
+		err = buf.WriteByte(byte(len(m.Buckets)))
+	}
+	for i := 0; err == nil && i < len(m.Buckets); i++ {
+		_, err = buf.Write(m.Buckets[i].Partner[:])
+		if err == nil {
+			err = buf.WriteByte(m.Buckets[i].Bucket)
+		}
+	}
+	if err == nil {
+		_, err = buf.Write(m.Signature)
+	}
+	if err != nil {
+		log.Crit(fmt.Sprintf("CapacityHint Pack err %s", err))
+	}
+	return buf.Bytes()
+}
+
+//UnPack is MessageUnPacker
+func (m *CapacityHint) UnPack(data []byte) error {
+	var t int32
+	var err error
+	m.CmdID = CapacityHintCmdID
+	buf := bytes.NewBuffer(data)
+	err = binary.Read(buf, binary.LittleEndian, &t)
+	if t != m.CmdID {
+		return fmt.Errorf("CapacityHint UnPack cmdid expect=%d,got=%d", CapacityHintCmdID, t)
+	}
+	err = binary.Read(buf, binary.BigEndian, &m.Token)
+	if err != nil {
+		return err
+	}
+	err = binary.Read(buf, binary.BigEndian, &m.Expiration)
+	if err != nil {
+		return err
+	}
+	m.Precision, err = buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	n, err := buf.ReadByte()
+	if err != nil {
+		return err
+	}
+	m.Buckets = nil
+	for i := 0; i < int(n); i++ {
+		var b CapacityBucket
+		err = binary.Read(buf, binary.BigEndian, &b.Partner)
+		if err != nil {
+			return err
+		}
+		b.Bucket, err = buf.ReadByte()
+		if err != nil {
+			return err
+		}
+		m.Buckets = append(m.Buckets, b)
+	}
+	m.Signature = make([]byte, signatureLength)
+	l, err := buf.Read(m.Signature)
+	if err != nil {
+		return err
+	}
+	if l != signatureLength {
+		return errPacketLength
+	}
+	return m.verifySignature(data)
+}
+
+//String is fmt.Stringer
+func (m *CapacityHint) String() string {
+	return fmt.Sprintf("Message{type=CapacityHint token=%s,expiration=%d,precision=%d,buckets=%d,sender=%s,has signature=%v}",
+		utils.APex2(m.Token), m.Expiration, m.Precision, len(m.Buckets), utils.APex2(m.Sender), len(m.Signature) != 0)
+}
+
 //MessageMap contains all message can send and receive.
 //DirectTransfer has been deprecated
 var MessageMap = map[int]Messager{
@@ -1723,6 +1970,8 @@ var MessageMap = map[int]Messager{
 	WithdrawResponseCmdID:                 new(WithdrawResponse),
 	SettleRequestCmdID:                    new(SettleRequest),
 	SettleResponseCmdID:                   new(SettleResponse),
+	NodeEndpointCmdID:                     new(NodeEndpoint),
+	CapacityHintCmdID:                     new(CapacityHint),
 }
 
 func init() {
@@ -1741,4 +1990,6 @@ func init() {
 	gob.Register(&WithdrawResponse{})
 	gob.Register(&SettleRequest{})
 	gob.Register(&SettleResponse{})
+	gob.Register(&NodeEndpoint{})
+	gob.Register(&CapacityHint{})
 }
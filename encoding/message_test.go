@@ -17,6 +17,7 @@ import (
 
 	"fmt"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/davecgh/go-spew/spew"
@@ -36,6 +37,10 @@ func GetTestPrivKey() *ecdsa.PrivateKey {
 	return privkey
 }
 
+func GetTestSigner() signer.Signer {
+	return signer.NewKeySigner(GetTestPrivKey())
+}
+
 func GetTestPubKey() ecdsa.PublicKey {
 	priv := GetTestPrivKey()
 	return priv.PublicKey
@@ -55,7 +60,12 @@ def test_signature():
 
 func TestSignature(t *testing.T) {
 	ping := NewPing(0x33)
-	ping.Signature = SignMessage(GetTestPrivKey(), ping)
+	var err error
+	ping.Signature, err = SignMessage(GetTestSigner(), ping)
+	if err != nil {
+		t.Error(err)
+		return
+	}
 	data := ping.Pack()
 	ping2 := new(Ping)
 	ping2.UnPack(data)
@@ -77,7 +87,7 @@ func TestSignature(t *testing.T) {
 	if len(ping.Pack()) > 65 {
 		t.Errorf("length error before signature")
 	}
-	err = ping.Sign(GetTestPrivKey(), ping)
+	err = ping.Sign(GetTestSigner(), ping)
 	if err != nil {
 		t.Error(err)
 	}
@@ -108,7 +118,7 @@ func TestEnvelopeMessage(t *testing.T) {
 	}
 	p := NewDirectTransfer(bp)
 	var sm SignedMessager = p
-	err := p.Sign(GetTestPrivKey(), p)
+	err := p.Sign(GetTestSigner(), p)
 	if err != nil {
 		t.Error(err)
 	}
@@ -132,7 +142,7 @@ func TestEnvelopeMessage(t *testing.T) {
 
 func TestHash(t *testing.T) {
 	ping := NewPing(32)
-	ping.Sign(GetTestPrivKey(), ping)
+	ping.Sign(GetTestSigner(), ping)
 	data := ping.Pack()
 	msgHash := utils.Sha3(data)
 	ping2 := NewPing(0)
@@ -151,7 +161,7 @@ func TestDirectTransfer(t *testing.T) {
 		Locksroot:         utils.EmptyHash,
 	}
 	d1 := NewDirectTransfer(bp)
-	d1.Sign(GetTestPrivKey(), d1)
+	d1.Sign(GetTestSigner(), d1)
 	d2 := new(DirectTransfer)
 	err := d2.UnPack(d1.Pack())
 	if err != nil {
@@ -176,7 +186,7 @@ func TestMediatedTransfer(t *testing.T) {
 		LockSecretHash: utils.Sha3([]byte("hashlock")),
 	}
 	m1 := NewMediatedTransfer(bp, lock, utils.NewRandomAddress(), utils.NewRandomAddress(), big.NewInt(33))
-	m1.Sign(GetTestPrivKey(), m1)
+	m1.Sign(GetTestSigner(), m1)
 	data := m1.Pack()
 	m2 := new(MediatedTransfer)
 	m2.UnPack(data)
@@ -200,7 +210,7 @@ func TestNewAnnounceDisposedTransfer(t *testing.T) {
 		},
 	}
 	m1 := NewAnnounceDisposed(bp)
-	err := m1.Sign(GetTestPrivKey(), m1)
+	err := m1.Sign(GetTestSigner(), m1)
 	if err != nil {
 		t.Error(err)
 		return
@@ -228,7 +238,7 @@ func TestNewSecret(t *testing.T) {
 		Locksroot:         utils.EmptyHash,
 	}
 	s1 := NewUnlock(bp, utils.Sha3([]byte("xxx")))
-	s1.Sign(GetTestPrivKey(), s1)
+	s1.Sign(GetTestSigner(), s1)
 	data := s1.Pack()
 	s2 := new(UnLock)
 	err := s2.UnPack(data)
@@ -243,7 +253,7 @@ func TestNewSecret(t *testing.T) {
 
 func TestNewRevealSecret(t *testing.T) {
 	s1 := NewRevealSecret(utils.Sha3([]byte("xxx")))
-	s1.Sign(GetTestPrivKey(), s1)
+	s1.Sign(GetTestSigner(), s1)
 	data := s1.Pack()
 	s2 := new(RevealSecret)
 	err := s2.UnPack(data)
@@ -258,7 +268,7 @@ func TestNewRevealSecret(t *testing.T) {
 
 func TestNewSecretRequest(t *testing.T) {
 	s1 := NewSecretRequest(utils.Sha3([]byte("xxx")), big.NewInt(506))
-	s1.Sign(GetTestPrivKey(), s1)
+	s1.Sign(GetTestSigner(), s1)
 	data := s1.Pack()
 	s2 := new(SecretRequest)
 	err := s2.UnPack(data)
@@ -279,7 +289,7 @@ func TestNewRemoveExpiredHashlockTransfer(t *testing.T) {
 		Locksroot:         utils.EmptyHash,
 	}
 	s1 := NewRemoveExpiredHashlockTransfer(bp, utils.Sha3([]byte("xxx")))
-	s1.Sign(GetTestPrivKey(), s1)
+	s1.Sign(GetTestSigner(), s1)
 	data := s1.Pack()
 	s2 := new(RemoveExpiredHashlockTransfer)
 	err := s2.UnPack(data)
@@ -315,7 +325,7 @@ func TestNewAnnounceDisposedTransferResponse(t *testing.T) {
 		Locksroot:         utils.NewRandomHash(),
 	}
 	m := NewAnnounceDisposedResponse(bp, utils.NewRandomHash())
-	err := m.Sign(GetTestPrivKey(), m)
+	err := m.Sign(GetTestSigner(), m)
 	if err != nil {
 		t.Error(err)
 		return
@@ -344,7 +354,7 @@ func TestWithdrawRequest(t *testing.T) {
 	bp.Participant2 = p2addr
 	bp.Participant2Balance = big.NewInt(30)
 	m := NewWithdrawRequest(bp)
-	err := m.Sign(p1key, m)
+	err := m.Sign(signer.NewKeySigner(p1key), m)
 	if err != nil {
 		t.Error(err)
 		return
@@ -376,7 +386,7 @@ func TestWithdrawResponse(t *testing.T) {
 
 	fmt.Printf("addr1=%s,addr2=%s\n", utils.APex2(p1addr), utils.APex2(p2addr))
 	m := NewWithdrawResponse(bp)
-	err := m.Sign(p2key, m)
+	err := m.Sign(signer.NewKeySigner(p2key), m)
 	if err != nil {
 		t.Error(err)
 		return
@@ -403,7 +413,7 @@ func TestSettleRequest(t *testing.T) {
 	bp.Participant2Balance = big.NewInt(30)
 	fmt.Printf("addr1=%s,addr2=%s\n", utils.APex2(p1addr), utils.APex2(p2addr))
 	m := NewSettleRequest(bp)
-	err := m.Sign(p1key, m)
+	err := m.Sign(signer.NewKeySigner(p1key), m)
 	if err != nil {
 		t.Error(err)
 		return
@@ -430,7 +440,7 @@ func TestSettleResponse(t *testing.T) {
 	bp.Participant2Balance = big.NewInt(30)
 	fmt.Printf("addr1=%s,addr2=%s\n", utils.APex2(p1addr), utils.APex2(p2addr))
 	m := NewSettleResponse(bp)
-	err := m.Sign(p2key, m)
+	err := m.Sign(signer.NewKeySigner(p2key), m)
 	if err != nil {
 		t.Error(err)
 		return
@@ -466,3 +476,54 @@ func TestBigInt(t *testing.T) {
 		t.Error("not equal")
 	}
 }
+
+func TestNodeEndpoint(t *testing.T) {
+	m := NewNodeEndpoint("1.2.3.4:40001", "mobile", 1000)
+	err := m.Sign(GetTestSigner(), m)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data := m.Pack()
+	m2 := new(NodeEndpoint)
+	err = m2.UnPack(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, m, m2)
+	assert.EqualValues(t, m2.Sender, GetTestAddress())
+	data[5]++
+	m3 := new(NodeEndpoint)
+	err = m3.UnPack(data)
+	if err == nil && m3.Sender == m.Sender {
+		t.Error("modified data should have a different sender")
+	}
+}
+
+func TestCapacityHint(t *testing.T) {
+	buckets := []CapacityBucket{
+		{utils.NewRandomAddress(), 0},
+		{utils.NewRandomAddress(), 64},
+	}
+	m := NewCapacityHint(utils.NewRandomAddress(), 1000, 4, buckets)
+	err := m.Sign(GetTestSigner(), m)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	data := m.Pack()
+	m2 := new(CapacityHint)
+	err = m2.UnPack(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, m, m2)
+	assert.EqualValues(t, m2.Sender, GetTestAddress())
+	m3 := new(CapacityHint)
+	err = m3.UnPack(data[:len(data)-10])
+	if err == nil {
+		t.Error("truncated data should fail")
+	}
+}
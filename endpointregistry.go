@@ -0,0 +1,126 @@
+package smartraiden
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+endpoint registry replaces the discovery contract:
+every node signs its public udp address and sends it to all its channel partners,
+partners save it to db and send messages directly by udp, fallback to xmpp.
+*/
+
+func (rs *RaidenService) deviceType() string {
+	if params.MobileMode {
+		return network.DeviceTypeMobile
+	}
+	return network.DeviceTypeOther
+}
+
+//load endpoints saved last time to transport
+func (rs *RaidenService) loadNodeEndpoints() {
+	er, ok := rs.Transport.(network.EndpointRegister)
+	if !ok {
+		return
+	}
+	now := time.Now().Unix()
+	_, err := rs.db.RemoveExpiredNodeEndpoints(now)
+	if err != nil {
+		log.Error(fmt.Sprintf("RemoveExpiredNodeEndpoints err %s", err))
+	}
+	es, err := rs.db.GetAllNodeEndpoints(now)
+	if err != nil {
+		log.Error(fmt.Sprintf("GetAllNodeEndpoints err %s", err))
+		return
+	}
+	for _, e := range es {
+		err = er.SetNodeEndpoint(e.Address, e.HostPort, e.DeviceType, time.Unix(e.Expiration, 0))
+		if err != nil {
+			log.Warn(fmt.Sprintf("load endpoint of %s err %s", utils.APex2(e.Address), err))
+		}
+	}
+}
+
+/*
+announce my endpoint to all my channel partners,
+announce again when half of the expiration passed.
+*/
+func (rs *RaidenService) announceNodeEndpoint() {
+	if rs.Config.PublicAddress == "" {
+		return
+	}
+	if time.Since(rs.lastEndpointAnnounce) < params.EndpointExpiration/2 {
+		return
+	}
+	rs.lastEndpointAnnounce = time.Now()
+	expiration := rs.lastEndpointAnnounce.Add(params.EndpointExpiration).Unix()
+	partners := make(map[common.Address]bool)
+	for _, g := range rs.RegistryToken2ChannelGraph {
+		for addr := range g.PartenerAddress2Channel {
+			partners[addr] = true
+		}
+	}
+	for addr := range partners {
+		msg := encoding.NewNodeEndpoint(rs.Config.PublicAddress, rs.deviceType(), expiration)
+		err := msg.Sign(rs.Signer, msg)
+		if err != nil {
+			log.Error(fmt.Sprintf("sign NodeEndpoint err %s", err))
+			return
+		}
+		err = rs.sendAsync(addr, msg)
+		if err != nil {
+			log.Warn(fmt.Sprintf("announce endpoint to %s err %s", utils.APex2(addr), err))
+		}
+	}
+}
+
+//isPartner returns true if we have a channel with `addr` on any token
+func (rs *RaidenService) isPartner(addr common.Address) bool {
+	for _, g := range rs.RegistryToken2ChannelGraph {
+		if g.GetPartenerAddress2Channel(addr) != nil {
+			return true
+		}
+	}
+	return false
+}
+
+//receive a partner's endpoint
+func (mh *raidenMessageHandler) messageNodeEndpoint(msg *encoding.NodeEndpoint) error {
+	//anyone else could fill our db and redirect our messages
+	if !mh.raiden.isPartner(msg.Sender) {
+		return fmt.Errorf("endpoint from %s, who is not my partner", utils.APex2(msg.Sender))
+	}
+	now := time.Now()
+	expiration := time.Unix(msg.Expiration, 0)
+	if !expiration.After(now) {
+		return fmt.Errorf("endpoint of %s already expired", utils.APex2(msg.Sender))
+	}
+	if expiration.Sub(now) > params.MaxEndpointExpiration {
+		return fmt.Errorf("endpoint of %s valid too long, expiration=%s", utils.APex2(msg.Sender), expiration)
+	}
+	_, _, err := net.SplitHostPort(msg.HostPort)
+	if err != nil {
+		return fmt.Errorf("endpoint of %s err %s", utils.APex2(msg.Sender), err)
+	}
+	e := models.NewNodeEndpoint(msg.Sender, msg.HostPort, msg.DeviceType, msg.Expiration, msg.Pack())
+	saved, err := mh.raiden.db.SaveNodeEndpoint(e)
+	if err != nil || !saved {
+		//older announcement, just ack
+		return err
+	}
+	er, ok := mh.raiden.Transport.(network.EndpointRegister)
+	if !ok {
+		return nil
+	}
+	return er.SetNodeEndpoint(msg.Sender, msg.HostPort, msg.DeviceType, expiration)
+}
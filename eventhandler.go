@@ -11,6 +11,8 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
@@ -76,19 +78,19 @@ func (eh *stateMachineEventHandler) eventSendRevealSecret(event *mediatedtransfe
 	eh.raiden.conditionQuit("EventSendRevealSecretBefore")
 	eh.raiden.registerSecret(event.Secret)
 	revealMessage := encoding.NewRevealSecret(event.Secret)
-	err = revealMessage.Sign(eh.raiden.PrivateKey, revealMessage)
+	err = revealMessage.Sign(eh.raiden.Signer, revealMessage)
 	err = eh.raiden.sendAsync(event.Receiver, revealMessage) //单独处理 reaveal secret
 	return err
 }
 func (eh *stateMachineEventHandler) eventSendMediatedTransfer(event *mediatedtransfer.EventSendMediatedTransfer, stateManager *transfer.StateManager) (err error) {
 	receiver := event.Receiver
-	g := eh.raiden.getToken2ChannelGraph(event.Token)
+	g := eh.stateManagerChannelGraph(stateManager, event.Token)
 	ch := g.GetPartenerAddress2Channel(receiver)
 	mtr, err := ch.CreateMediatedTransfer(event.Initiator, event.Target, event.Fee, event.Amount, event.Expiration, event.LockSecretHash)
 	if err != nil {
 		return
 	}
-	err = mtr.Sign(eh.raiden.PrivateKey, mtr)
+	err = mtr.Sign(eh.raiden.Signer, mtr)
 	err = ch.RegisterTransfer(eh.raiden.GetBlockNumber(), mtr)
 	if err != nil {
 		return
@@ -100,13 +102,13 @@ func (eh *stateMachineEventHandler) eventSendMediatedTransfer(event *mediatedtra
 }
 func (eh *stateMachineEventHandler) eventSendUnlock(event *mediatedtransfer.EventSendBalanceProof, stateManager *transfer.StateManager) (err error) {
 	receiver := event.Receiver
-	g := eh.raiden.getToken2ChannelGraph(event.Token)
+	g := eh.stateManagerChannelGraph(stateManager, event.Token)
 	ch := g.GetPartenerAddress2Channel(receiver)
 	tr, err := ch.CreateUnlock(event.LockSecretHash)
 	if err != nil {
 		return
 	}
-	err = tr.Sign(eh.raiden.PrivateKey, tr)
+	err = tr.Sign(eh.raiden.Signer, tr)
 	err = ch.RegisterTransfer(eh.raiden.GetBlockNumber(), tr)
 	if err != nil {
 		return
@@ -118,13 +120,13 @@ func (eh *stateMachineEventHandler) eventSendUnlock(event *mediatedtransfer.Even
 }
 func (eh *stateMachineEventHandler) eventSendAnnouncedDisposed(event *mediatedtransfer.EventSendAnnounceDisposed, stateManager *transfer.StateManager) (err error) {
 	receiver := event.Receiver
-	g := eh.raiden.getToken2ChannelGraph(event.Token)
+	g := eh.stateManagerChannelGraph(stateManager, event.Token)
 	ch := g.GetPartenerAddress2Channel(receiver)
 	mtr, err := ch.CreateAnnouceDisposed(event.LockSecretHash, eh.raiden.GetBlockNumber())
 	if err != nil {
 		return
 	}
-	err = mtr.Sign(eh.raiden.PrivateKey, mtr)
+	err = mtr.Sign(eh.raiden.Signer, mtr)
 	err = ch.RegisterAnnouceDisposed(mtr)
 	if err != nil {
 		return
@@ -139,13 +141,13 @@ func (eh *stateMachineEventHandler) eventSendAnnouncedDisposed(event *mediatedtr
 }
 func (eh *stateMachineEventHandler) eventSendAnnouncedDisposedResponse(event *mediatedtransfer.EventSendAnnounceDisposedResponse, stateManager *transfer.StateManager) (err error) {
 	receiver := event.Receiver
-	g := eh.raiden.getToken2ChannelGraph(event.Token)
+	g := eh.stateManagerChannelGraph(stateManager, event.Token)
 	ch := g.GetPartenerAddress2Channel(receiver)
 	mtr, err := ch.CreateAnnounceDisposedResponse(event.LockSecretHash, eh.raiden.GetBlockNumber())
 	if err != nil {
 		return
 	}
-	err = mtr.Sign(eh.raiden.PrivateKey, mtr)
+	err = mtr.Sign(eh.raiden.Signer, mtr)
 	err = ch.RegisterAnnounceDisposedResponse(mtr, eh.raiden.GetBlockNumber())
 	if err != nil {
 		return
@@ -155,8 +157,23 @@ func (eh *stateMachineEventHandler) eventSendAnnouncedDisposedResponse(event *me
 	err = eh.raiden.sendAsync(receiver, mtr)
 	return
 }
-func (eh *stateMachineEventHandler) eventContractSendRegisterSecret(event *mediatedtransfer.EventContractSendRegisterSecret) (err error) {
-	b, err := eh.raiden.Chain.SecretRegistryProxy.IsSecretRegistered(event.Secret)
+
+//stateManagerChannelGraph returns graph of `token` on the registry of `stateManager`
+func (eh *stateMachineEventHandler) stateManagerChannelGraph(stateManager *transfer.StateManager, token common.Address) *graph.ChannelGraph {
+	registry := utils.EmptyAddress
+	if stateManager != nil {
+		registry = stateManager.RegistryAddress
+	}
+	return eh.raiden.getRegistryToken2ChannelGraph(eh.raiden.tokenKey(registry, token))
+}
+
+//eventContractSendRegisterSecret registers secret on the secret registry of `registry`
+func (eh *stateMachineEventHandler) eventContractSendRegisterSecret(event *mediatedtransfer.EventContractSendRegisterSecret, registry common.Address) (err error) {
+	secretRegistry, err := eh.raiden.secretRegistryOf(registry)
+	if err != nil {
+		return err
+	}
+	b, err := secretRegistry.IsSecretRegistered(event.Secret)
 	if err != nil {
 		return err
 	}
@@ -164,7 +181,7 @@ func (eh *stateMachineEventHandler) eventContractSendRegisterSecret(event *media
 		log.Info(fmt.Sprintf("Secret %s already registered", utils.HPex(event.Secret)))
 		return
 	}
-	result := eh.raiden.Chain.SecretRegistryProxy.RegisterSecretAsync(event.Secret)
+	result := secretRegistry.RegisterSecretAsync(event.Secret, event.LockExpiration)
 	go func() {
 		var err error
 		err = <-result.Result
@@ -218,7 +235,7 @@ func (eh *stateMachineEventHandler) eventUnlockFailed(e2 *mediatedtransfer.Event
 		log.Warn(fmt.Sprintf("Get Event UnlockFailed ,but hashlock cannot be removed err:%s", err))
 		return
 	}
-	err = tr.Sign(eh.raiden.PrivateKey, tr)
+	err = tr.Sign(eh.raiden.Signer, tr)
 	err = ch.RegisterRemoveExpiredHashlockTransfer(tr, eh.raiden.GetBlockNumber())
 	if err != nil {
 		log.Error(fmt.Sprintf("register mine RegisterRemoveExpiredHashlockTransfer err %s", err))
@@ -244,7 +261,7 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		eh.raiden.conditionQuit("EventSendBalanceProofAfter")
 	case *mediatedtransfer.EventSendSecretRequest:
 		secretRequest := encoding.NewSecretRequest(e2.LockSecretHash, e2.Amount)
-		err = secretRequest.Sign(eh.raiden.PrivateKey, secretRequest)
+		err = secretRequest.Sign(eh.raiden.Signer, secretRequest)
 		eh.raiden.conditionQuit("EventSendSecretRequestBefore")
 		err = eh.raiden.sendAsync(e2.Receiver, secretRequest)
 		eh.raiden.conditionQuit("EventSendSecretRequestAfter")
@@ -284,6 +301,9 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		log.Error(fmt.Sprintf("EventWithdrawFailed hashlock=%s,reason=%s", utils.HPex(e2.LockSecretHash), e2.Reason))
 		err = eh.eventWithdrawFailed(e2, stateManager)
 	case *mediatedtransfer.EventWithdrawSuccess:
+		if e2.Fee != nil && e2.Fee.Sign() > 0 && stateManager != nil {
+			metrics.FeesEarned.Add(bigIntToFloat(e2.Fee), tokenLabel(stateManager.TokenAddress))
+		}
 		/*
 					  The withdraw is currently handled by the netting channel, once the close
 			     event is detected all locks will be withdrawn
@@ -296,7 +316,7 @@ func (eh *stateMachineEventHandler) OnEvent(event transfer.Event, stateManager *
 		err = eh.eventUnlockFailed(e2, stateManager)
 		eh.raiden.conditionQuit("EventSendRemoveExpiredHashlockTransferAfter")
 	case *mediatedtransfer.EventContractSendRegisterSecret:
-		err = eh.eventContractSendRegisterSecret(e2)
+		err = eh.eventContractSendRegisterSecret(e2, eh.raiden.tokenKey(stateManager.RegistryAddress, stateManager.TokenAddress).Registry)
 	case *mediatedtransfer.EventRemoveStateManager:
 		delete(eh.raiden.Transfer2StateManager, e2.Key)
 	default:
@@ -317,11 +337,13 @@ func (eh *stateMachineEventHandler) finishOneTransfer(ev transfer.Event) {
 		lockSecretHash = e2.LockSecretHash
 		tokenAddress = e2.Token
 		err = nil
+		metrics.TransfersSucceeded.Inc(tokenLabel(tokenAddress))
 	case *transfer.EventTransferSentFailed:
 		log.Warn(fmt.Sprintf("EventTransferSentFailed for id %d,because of %s", e2.LockSecretHash, e2.Reason))
 		lockSecretHash = e2.LockSecretHash
 		err = errors.New(e2.Reason)
 		tokenAddress = e2.Token
+		metrics.TransfersFailed.Inc(tokenLabel(tokenAddress))
 	default:
 		panic("unknow event")
 	}
@@ -337,36 +359,42 @@ func (eh *stateMachineEventHandler) finishOneTransfer(ev transfer.Event) {
 	}
 }
 func (eh *stateMachineEventHandler) HandleTokenAdded(st *mediatedtransfer.ContractTokenAddedStateChange) error {
-	if st.RegistryAddress != eh.raiden.RegistryAddress {
+	if !eh.raiden.isOurRegistry(st.RegistryAddress) {
 		panic("unkown registry")
 	}
 	tokenAddress := st.TokenAddress
 	tokenNetworkAddress := st.TokenNetworkAddress
-	log.Info(fmt.Sprintf("NewTokenAdd token=%s,tokennetwork=%s", tokenAddress.String(), tokenNetworkAddress.String()))
-	err := eh.raiden.db.AddToken(st.TokenAddress, st.TokenNetworkAddress)
+	log.Info(fmt.Sprintf("NewTokenAdd registry=%s,token=%s,tokennetwork=%s", st.RegistryAddress.String(), tokenAddress.String(), tokenNetworkAddress.String()))
+	err := eh.raiden.db.AddRegistryToken(st.RegistryAddress, st.TokenAddress, st.TokenNetworkAddress)
 	if err != nil {
 		return err
 	}
 	g := graph.NewChannelGraph(eh.raiden.NodeAddress, st.TokenAddress, nil)
-	eh.raiden.TokenNetwork2Token[tokenNetworkAddress] = tokenAddress
-	eh.raiden.Token2TokenNetwork[tokenAddress] = tokenNetworkAddress
-	eh.raiden.Token2ChannelGraph[tokenAddress] = g
+	g.RegistryAddress = st.RegistryAddress
+	key := models.RegistryToken{Registry: st.RegistryAddress, Token: tokenAddress}
+	eh.raiden.TokenNetwork2RegistryToken[tokenNetworkAddress] = key
+	eh.raiden.RegistryToken2TokenNetwork[key] = tokenNetworkAddress
+	eh.raiden.RegistryToken2ChannelGraph[key] = g
 	return nil
 }
 func (eh *stateMachineEventHandler) handleChannelNew(st *mediatedtransfer.ContractNewChannelStateChange) error {
 	tokenNetworkAddress := st.TokenNetworkAddress
 	participant1 := st.Participant1
 	participant2 := st.Participant2
-	tokenAddress := eh.raiden.TokenNetwork2Token[tokenNetworkAddress]
+	key, ok := eh.raiden.TokenNetwork2RegistryToken[tokenNetworkAddress]
+	if !ok {
+		log.Warn(fmt.Sprintf("NewChannel on unknown tokenNetwork=%s", utils.APex2(tokenNetworkAddress)))
+		return nil
+	}
 	log.Info(fmt.Sprintf("NewChannel tokenNetwork=%s,token=%s,participant1=%s,participant2=%s",
 		utils.APex2(tokenNetworkAddress),
-		utils.APex2(tokenAddress),
+		key,
 		utils.APex2(participant1),
 		utils.APex2(participant2),
 	))
-	g := eh.raiden.getToken2ChannelGraph(tokenAddress)
+	g := eh.raiden.getRegistryToken2ChannelGraph(key)
 	g.AddPath(participant1, participant2)
-	err := eh.raiden.db.NewNonParticipantChannel(tokenAddress, st.ChannelIdentifier.ChannelIdentifier, participant1, participant2)
+	err := eh.raiden.db.NewNonParticipantChannel(tokenNetworkAddress, st.ChannelIdentifier.ChannelIdentifier, participant1, participant2)
 	if err != nil {
 		log.Error(err.Error())
 		return err
@@ -409,8 +437,7 @@ func (eh *stateMachineEventHandler) handleClosed(st *mediatedtransfer.ContractCl
 	ch, err := eh.raiden.findChannelByAddress(channelAddress)
 	if err != nil {
 		//i'm not a participant
-		token := eh.raiden.TokenNetwork2Token[st.TokenNetworkAddress]
-		err = eh.raiden.db.RemoveNonParticipantChannel(token, st.ChannelIdentifier)
+		err = eh.raiden.db.RemoveNonParticipantChannel(st.TokenNetworkAddress, st.ChannelIdentifier)
 		return err
 	}
 	err = eh.ChannelStateTransition(ch, st)
@@ -431,6 +458,7 @@ func (eh *stateMachineEventHandler) handleClosed(st *mediatedtransfer.ContractCl
 func (eh *stateMachineEventHandler) removeSettledChannel(ch *channel.Channel) error {
 	g := eh.raiden.getChannelGraph(ch.ChannelIdentifier.ChannelIdentifier)
 	g.RemoveChannel(ch)
+	eh.raiden.settleScheduler.remove(ch.ChannelIdentifier.ChannelIdentifier)
 	cs := channel.NewChannelSerialization(ch)
 	err := eh.raiden.db.RemoveChannel(cs)
 	if err != nil {
@@ -440,7 +468,7 @@ func (eh *stateMachineEventHandler) removeSettledChannel(ch *channel.Channel) er
 	if err != nil {
 		return err
 	}
-	err = eh.raiden.db.RemoveNonParticipantChannel(ch.TokenAddress, ch.ChannelIdentifier.ChannelIdentifier)
+	err = eh.raiden.db.RemoveNonParticipantChannel(eh.raiden.RegistryToken2TokenNetwork[eh.raiden.channelKey(ch)], ch.ChannelIdentifier.ChannelIdentifier)
 	return err
 }
 func (eh *stateMachineEventHandler) handleSettled(st *mediatedtransfer.ContractSettledStateChange) error {
@@ -542,21 +570,18 @@ func (eh *stateMachineEventHandler) ChannelStateTransition(c *channel.Channel, s
 	switch st2 := st.(type) {
 	case *transfer.BlockStateChange:
 		if c.State == channeltype.StateClosed {
-			settlementEnd := c.ExternState.ClosedBlock + int64(c.SettleTimeout) //todo punish time
-			if st2.BlockNumber > settlementEnd {
-				//should not block todo fix it
-				//err = c.ExternState.Settle()
-			}
+			eh.raiden.settleScheduler.schedule(c, st2.BlockNumber)
 		}
 		//已经进入了 reveal timeout 阶段
 		if true {
 			//secret registery
 			//应该主动去注册密码
 			secrets := c.GetNeedRegisterSecrets(blockNumber)
-			for _, s := range secrets {
+			for s, expiration := range secrets {
 				err = eh.eventContractSendRegisterSecret(&mediatedtransfer.EventContractSendRegisterSecret{
-					Secret: s,
-				})
+					Secret:         s,
+					LockExpiration: expiration,
+				}, eh.raiden.channelKey(c).Registry)
 				if err != nil {
 					log.Error(fmt.Sprintf("eventContractSendRegisterSecret err %s", err))
 				}
@@ -0,0 +1,122 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+//ComponentStatus is health of one component of node
+type ComponentStatus struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Message string `json:"message,omitempty"`
+}
+
+//HealthReport health of all checked components, healthy only when all components are healthy
+type HealthReport struct {
+	Healthy    bool               `json:"healthy"`
+	Components []*ComponentStatus `json:"components"`
+}
+
+func (r *HealthReport) add(name string, err error) {
+	c := &ComponentStatus{
+		Name:    name,
+		Healthy: err == nil,
+	}
+	if err != nil {
+		c.Message = err.Error()
+		r.Healthy = false
+	}
+	r.Components = append(r.Components, c)
+}
+
+func statusError(s netshare.Status) error {
+	switch s {
+	case netshare.Connected:
+		return nil
+	case netshare.Closed:
+		return errors.New("closed")
+	case netshare.Reconnecting:
+		return errors.New("reconnecting")
+	}
+	return errors.New("disconnected")
+}
+
+//checkEventLoop make sure the main loop still handles requests
+func (rs *RaidenService) checkEventLoop(timeout time.Duration) error {
+	req := &apiReq{
+		ReqID:  utils.RandomString(10),
+		Name:   pingReqName,
+		result: make(chan *utils.AsyncResult, 1),
+	}
+	timeoutCh := time.After(timeout)
+	select {
+	case rs.UserReqChan <- req:
+	case <-timeoutCh:
+		return fmt.Errorf("no response in %s", timeout)
+	}
+	select {
+	case <-req.result:
+		return nil
+	case <-timeoutCh:
+		return fmt.Errorf("no response in %s", timeout)
+	}
+}
+
+func (rs *RaidenService) checkEthereum() error {
+	if rs.Chain == nil || rs.Chain.Client == nil {
+		return errors.New("no client")
+	}
+	return statusError(rs.Chain.Client.Status)
+}
+
+func (rs *RaidenService) checkLatestBlock() error {
+	t := rs.db.GetLastBlockNumberTime()
+	if t.IsZero() {
+		return errors.New("no block received")
+	}
+	age := time.Since(t)
+	if age > params.MaxBlockAge {
+		return fmt.Errorf("last block %d received %s ago", rs.GetBlockNumber(), age)
+	}
+	return nil
+}
+
+func (rs *RaidenService) checkCatchUp() error {
+	if rs.BlockChainEvents.IsCatchingUp() {
+		return errors.New("processing events happened when offline")
+	}
+	return nil
+}
+
+/*
+Liveness reports whether this node works at all,
+a node not alive should be restarted.
+*/
+func (rs *RaidenService) Liveness() *HealthReport {
+	r := &HealthReport{Healthy: true}
+	r.add("db", rs.db.CheckWritable())
+	r.add("eventloop", rs.checkEventLoop(params.HealthCheckTimeout))
+	return r
+}
+
+/*
+Readiness reports whether this node is able to make transfers,
+besides liveness, it needs connection to ethereum and xmpp, and has handled all events on chain.
+*/
+func (rs *RaidenService) Readiness() *HealthReport {
+	r := rs.Liveness()
+	r.add("ethereum", rs.checkEthereum())
+	r.add("block", rs.checkLatestBlock())
+	r.add("catchup", rs.checkCatchUp())
+	if cs, ok := rs.Transport.(network.ConnectionStatuser); ok {
+		r.add("xmpp", statusError(cs.ConnectionStatus()))
+	}
+	return r
+}
@@ -0,0 +1,37 @@
+package smartraiden
+
+import (
+	"errors"
+	"testing"
+
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func TestHealthReport(t *testing.T) {
+	r := &HealthReport{Healthy: true}
+	r.add("db", nil)
+	assert(t, true, r.Healthy)
+	r.add("xmpp", statusError(netshare.Reconnecting))
+	r.add("ethereum", statusError(netshare.Connected))
+	assert(t, false, r.Healthy)
+	assert(t, 3, len(r.Components))
+	assert(t, "reconnecting", r.Components[1].Message)
+	assert(t, true, r.Components[2].Healthy)
+	r.add("block", errors.New("stale"))
+	assert(t, false, r.Healthy)
+}
+
+func TestCheckEventLoop(t *testing.T) {
+	rs := &RaidenService{UserReqChan: make(chan *apiReq)}
+	err := rs.checkEventLoop(time.Millisecond * 10)
+	assert(t, err != nil, true)
+	go func() {
+		req := <-rs.UserReqChan
+		req.result <- utils.NewAsyncResultWithError(nil)
+	}()
+	err = rs.checkEventLoop(time.Second)
+	assert(t, err, nil)
+}
@@ -93,6 +93,10 @@ func (mh *raidenMessageHandler) onMessage(msg encoding.SignedMessager, hash comm
 		err = mh.messageWithdrawRequest(m2)
 	case *encoding.WithdrawResponse:
 		err = mh.messageWithdrawResponse(m2)
+	case *encoding.NodeEndpoint:
+		err = mh.messageNodeEndpoint(m2)
+	case *encoding.CapacityHint:
+		err = mh.messageCapacityHint(m2)
 	default:
 		log.Error(fmt.Sprintf("raidenMessageHandler unknown msg:%s", utils.StringInterface1(msg)))
 		return fmt.Errorf("unhandled message cmdid:%d", msg.Cmd())
@@ -289,7 +293,7 @@ func (mh *raidenMessageHandler) messageMediatedTransfer(msg *encoding.MediatedTr
 	if _, ok := mh.blockedTokens[token]; ok {
 		return rerr.ErrTransferUnwanted
 	}
-	graph := mh.raiden.getToken2ChannelGraph(token)
+	graph := mh.raiden.getChannelGraph(msg.ChannelIdentifier)
 	if graph == nil {
 		return fmt.Errorf("received transfer on unkown token :%s", utils.APex2(token))
 	}
@@ -366,7 +370,7 @@ func (mh *raidenMessageHandler) messageSettleRequest(msg *encoding.SettleRequest
 		}()
 		return nil
 	}
-	err = settleResponse.Sign(mh.raiden.PrivateKey, settleResponse)
+	err = settleResponse.Sign(mh.raiden.Signer, settleResponse)
 	if err != nil {
 		panic(fmt.Sprintf("sign message for settle response err %s", err))
 	}
@@ -450,7 +454,7 @@ func (mh *raidenMessageHandler) messageWithdrawRequest(msg *encoding.WithdrawReq
 		}()
 		return nil
 	}
-	err = withdrawResponse.Sign(mh.raiden.PrivateKey, withdrawResponse)
+	err = withdrawResponse.Sign(mh.raiden.Signer, withdrawResponse)
 	if err != nil {
 		panic(fmt.Sprintf("sign message for withdraw response err %s", err))
 	}
@@ -0,0 +1,289 @@
+/*
+Package metrics is a minimal metrics subsystem of a raiden node.
+Metrics are exported in prometheus text format, so any prometheus server can scrape a node directly.
+*/
+package metrics
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//kinds of metric
+const (
+	KindCounter = "counter"
+	KindGauge   = "gauge"
+)
+
+type sample struct {
+	labelValues []string
+	value       float64
+}
+
+/*
+Vec is a group of metrics with the same name and different label values.
+a Vec without label names is a single metric.
+*/
+type Vec struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	lock       sync.Mutex
+	samples    map[string]*sample
+}
+
+func newVec(kind, name, help string, labelNames ...string) *Vec {
+	return &Vec{
+		name:       name,
+		help:       help,
+		kind:       kind,
+		labelNames: labelNames,
+		samples:    make(map[string]*sample),
+	}
+}
+
+//NewCounterVec create a counter and register it to DefaultRegistry, counter only goes up.
+func NewCounterVec(name, help string, labelNames ...string) *Vec {
+	v := newVec(KindCounter, name, help, labelNames...)
+	DefaultRegistry.MustRegister(v)
+	return v
+}
+
+//NewGaugeVec create a gauge and register it to DefaultRegistry, gauge can go up and down.
+func NewGaugeVec(name, help string, labelNames ...string) *Vec {
+	v := newVec(KindGauge, name, help, labelNames...)
+	DefaultRegistry.MustRegister(v)
+	return v
+}
+
+//Name of this metric
+func (v *Vec) Name() string {
+	return v.name
+}
+
+func (v *Vec) getSample(labelValues []string) *sample {
+	if len(labelValues) != len(v.labelNames) {
+		panic(fmt.Sprintf("metric %s expect %d label values,got %d", v.name, len(v.labelNames), len(labelValues)))
+	}
+	key := strings.Join(labelValues, "\xff")
+	s, ok := v.samples[key]
+	if !ok {
+		s = &sample{labelValues: append([]string{}, labelValues...)}
+		v.samples[key] = s
+	}
+	return s
+}
+
+//Add `delta` to the metric of `labelValues`, counter cannot decrease.
+func (v *Vec) Add(delta float64, labelValues ...string) {
+	if v.kind == KindCounter && delta < 0 {
+		panic(fmt.Sprintf("counter %s cannot decrease", v.name))
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.getSample(labelValues).value += delta
+}
+
+//Inc add one to the metric of `labelValues`
+func (v *Vec) Inc(labelValues ...string) {
+	v.Add(1, labelValues...)
+}
+
+//Dec subtract one from the gauge of `labelValues`
+func (v *Vec) Dec(labelValues ...string) {
+	v.Add(-1, labelValues...)
+}
+
+//Set the gauge of `labelValues` to `value`
+func (v *Vec) Set(value float64, labelValues ...string) {
+	if v.kind == KindCounter {
+		panic(fmt.Sprintf("counter %s cannot be set", v.name))
+	}
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.getSample(labelValues).value = value
+}
+
+//Value of the metric `labelValues`, 0 if never updated
+func (v *Vec) Value(labelValues ...string) float64 {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	s, ok := v.samples[strings.Join(labelValues, "\xff")]
+	if !ok {
+		return 0
+	}
+	return s.value
+}
+
+//Reset remove all label values, gauges collected as a whole should be reset before collecting
+func (v *Vec) Reset() {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	v.samples = make(map[string]*sample)
+}
+
+func (v *Vec) write(w *bufio.Writer) {
+	v.lock.Lock()
+	defer v.lock.Unlock()
+	fmt.Fprintf(w, "# HELP %s %s\n", v.name, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.name, v.kind)
+	keys := make([]string, 0, len(v.samples))
+	for k := range v.samples {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s := v.samples[k]
+		w.WriteString(v.name)
+		if len(v.labelNames) > 0 {
+			w.WriteByte('{')
+			for i, l := range v.labelNames {
+				if i > 0 {
+					w.WriteByte(',')
+				}
+				fmt.Fprintf(w, "%s=\"%s\"", l, escapeLabelValue(s.labelValues[i]))
+			}
+			w.WriteByte('}')
+		}
+		w.WriteByte(' ')
+		w.WriteString(formatValue(s.value))
+		w.WriteByte('\n')
+	}
+}
+
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(s)
+}
+
+func formatValue(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+//Collector is called before metrics are written, it's the place to update gauges which are expensive to track all the time.
+type Collector func()
+
+//collectorEntry funcs are not comparable, so a collector is removed by its id
+type collectorEntry struct {
+	id int
+	c  Collector
+}
+
+//Registry holds all metrics of a node
+type Registry struct {
+	lock       sync.Mutex
+	vecs       []*Vec
+	names      map[string]bool
+	collectors []collectorEntry
+	nextID     int
+}
+
+//NewRegistry create an empty registry
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]bool),
+	}
+}
+
+//DefaultRegistry all metrics created by NewCounterVec and NewGaugeVec are registered here
+var DefaultRegistry = NewRegistry()
+
+//errDuplicateMetric two metrics with the same name
+var errDuplicateMetric = errors.New("duplicate metric")
+
+//Register `v`, name of metric must be unique
+func (r *Registry) Register(v *Vec) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.names[v.name] {
+		return fmt.Errorf("%s %s", errDuplicateMetric, v.name)
+	}
+	r.names[v.name] = true
+	r.vecs = append(r.vecs, v)
+	return nil
+}
+
+//MustRegister same as Register, panic when error
+func (r *Registry) MustRegister(v *Vec) {
+	err := r.Register(v)
+	if err != nil {
+		panic(err)
+	}
+}
+
+//AddCollector `c` will be called every time metrics are written, returns id for RemoveCollector
+func (r *Registry) AddCollector(c Collector) int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.nextID++
+	r.collectors = append(r.collectors, collectorEntry{id: r.nextID, c: c})
+	return r.nextID
+}
+
+//RemoveCollector the collector added with `id`, it won't be called any more
+func (r *Registry) RemoveCollector(id int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i, e := range r.collectors {
+		if e.id == id {
+			r.collectors = append(r.collectors[:i], r.collectors[i+1:]...)
+			return
+		}
+	}
+}
+
+//WriteText write all metrics to `w` in prometheus text format
+func (r *Registry) WriteText(w io.Writer) error {
+	r.lock.Lock()
+	collectors := append([]collectorEntry{}, r.collectors...)
+	vecs := append([]*Vec{}, r.vecs...)
+	r.lock.Unlock()
+	for _, e := range collectors {
+		e.c()
+	}
+	sort.Slice(vecs, func(i, j int) bool {
+		return vecs[i].name < vecs[j].name
+	})
+	bw := bufio.NewWriter(w)
+	for _, v := range vecs {
+		v.write(bw)
+	}
+	return bw.Flush()
+}
+
+//AddCollector add `c` to DefaultRegistry
+func AddCollector(c Collector) int {
+	return DefaultRegistry.AddCollector(c)
+}
+
+//RemoveCollector remove collector `id` from DefaultRegistry
+func RemoveCollector(id int) {
+	DefaultRegistry.RemoveCollector(id)
+}
+
+//WriteText write DefaultRegistry
+func WriteText(w io.Writer) error {
+	return DefaultRegistry.WriteText(w)
+}
+
+//ContentType of prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
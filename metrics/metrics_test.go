@@ -0,0 +1,73 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWriteText(t *testing.T) {
+	r := NewRegistry()
+	sent := newVec(KindCounter, "test_messages_sent_total", "Messages sent.", "type")
+	r.MustRegister(sent)
+	pending := newVec(KindGauge, "test_pending", "Pending \"transactions\".")
+	r.MustRegister(pending)
+	err := r.Register(newVec(KindGauge, "test_pending", ""))
+	assert.NotNil(t, err)
+	called := 0
+	id := r.AddCollector(func() {
+		called++
+	})
+	r.AddCollector(func() {
+		pending.Set(3)
+	})
+	sent.Inc("Ping")
+	sent.Add(2, "Ack")
+	sent.Inc("Ping")
+	assert.EqualValues(t, sent.Value("Ping"), 2)
+	buf := new(bytes.Buffer)
+	err = r.WriteText(buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected := `# HELP test_messages_sent_total Messages sent.
+# TYPE test_messages_sent_total counter
+test_messages_sent_total{type="Ack"} 2
+test_messages_sent_total{type="Ping"} 2
+# HELP test_pending Pending "transactions".
+# TYPE test_pending gauge
+test_pending 3
+`
+	assert.EqualValues(t, buf.String(), expected)
+	assert.EqualValues(t, 1, called)
+	r.RemoveCollector(id)
+	assert.Nil(t, r.WriteText(new(bytes.Buffer)))
+	assert.EqualValues(t, 1, called)
+}
+
+func TestVecPanic(t *testing.T) {
+	c := newVec(KindCounter, "test_counter", "", "token")
+	assert.Panics(t, func() {
+		c.Add(-1, "a")
+	})
+	assert.Panics(t, func() {
+		c.Set(1, "a")
+	})
+	assert.Panics(t, func() {
+		c.Inc()
+	})
+	g := newVec(KindGauge, "test_gauge", "", "state")
+	g.Inc("opened")
+	g.Inc("opened")
+	g.Dec("opened")
+	assert.EqualValues(t, g.Value("opened"), 1)
+	g.Set(0.5, `a"b`)
+	buf := new(bytes.Buffer)
+	w := bufio.NewWriter(buf)
+	g.write(w)
+	w.Flush()
+	assert.Contains(t, buf.String(), `test_gauge{state="a\"b"} 0.5`)
+}
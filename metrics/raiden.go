@@ -0,0 +1,47 @@
+package metrics
+
+//messages between nodes
+var (
+	//MessagesSent messages queued to send, retries excluded
+	MessagesSent = NewCounterVec("smartraiden_messages_sent_total", "Messages sent to other nodes, retries excluded.", "type")
+	//MessagesReceived messages received and decoded
+	MessagesReceived = NewCounterVec("smartraiden_messages_received_total", "Messages received from other nodes.", "type")
+	//MessageRetries retransmissions of messages not acked in time
+	MessageRetries = NewCounterVec("smartraiden_message_retries_total", "Retransmissions of messages not acked in time.", "type")
+	//AcksReceived acks of our messages
+	AcksReceived = NewCounterVec("smartraiden_acks_received_total", "Acks received for messages sent by this node.")
+	//AcksSent acks of messages we have handled
+	AcksSent = NewCounterVec("smartraiden_acks_sent_total", "Acks sent for messages handled by this node.")
+)
+
+//payments
+var (
+	//TransfersInitiated transfers started by this node
+	TransfersInitiated = NewCounterVec("smartraiden_transfers_initiated_total", "Transfers started by this node.", "token", "kind")
+	//TransfersMediated transfers this node is a mediator of
+	TransfersMediated = NewCounterVec("smartraiden_transfers_mediated_total", "Transfers mediated by this node.", "token")
+	//TransfersSucceeded transfers started by this node and succeeded
+	TransfersSucceeded = NewCounterVec("smartraiden_transfers_succeeded_total", "Transfers started by this node and succeeded.", "token")
+	//TransfersFailed transfers started by this node and failed
+	TransfersFailed = NewCounterVec("smartraiden_transfers_failed_total", "Transfers started by this node and failed.", "token")
+	//FeesEarned fees earned by mediating, in the smallest unit of token
+	FeesEarned = NewCounterVec("smartraiden_fees_earned_total", "Fees earned by mediating transfers, in the smallest unit of token.", "token")
+	//Channels number of channels in each state, collected when scraped
+	Channels = NewGaugeVec("smartraiden_channels", "Channels of this node by state.", "state")
+)
+
+//chain and network
+var (
+	//PendingTransactions on-chain transactions sent and not mined yet
+	PendingTransactions = NewGaugeVec("smartraiden_pending_transactions", "On-chain transactions waiting to be mined.")
+	//ChainBlockNumber latest block number reported by the alarm task
+	ChainBlockNumber = NewGaugeVec("smartraiden_chain_block_number", "Latest block number reported by ethereum node.")
+	//BlockNumber latest block number handled by this node
+	BlockNumber = NewGaugeVec("smartraiden_block_number", "Latest block number handled by this node.")
+	//BlockLag blocks not handled yet
+	BlockLag = NewGaugeVec("smartraiden_block_lag", "Blocks reported by ethereum node and not handled yet.")
+	//SecondsSinceLastBlock how long since a new block is handled
+	SecondsSinceLastBlock = NewGaugeVec("smartraiden_seconds_since_last_block", "Seconds since the last block was handled.")
+	//ConnectionStatus status of connection, value is netshare.Status, 1 means connected
+	ConnectionStatus = NewGaugeVec("smartraiden_connection_status", "Connection status: 0 disconnected, 1 connected, 2 closed, 3 reconnecting.", "peer")
+)
@@ -0,0 +1,67 @@
+package smartraiden
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
+	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//bigIntToFloat is precise enough for metrics
+func bigIntToFloat(i *big.Int) float64 {
+	f, _ := new(big.Float).SetInt(i).Float64()
+	return f
+}
+
+func tokenLabel(token common.Address) string {
+	return token.String()
+}
+
+/*
+collectMetrics update gauges which are only needed when metrics are scraped.
+it runs outside the main loop, so it only reads db and atomic values.
+*/
+func (rs *RaidenService) collectMetrics() {
+	cs, err := rs.db.GetChannelList(utils.EmptyAddress, utils.EmptyAddress)
+	if err != nil {
+		log.Warn(fmt.Sprintf("collect metrics GetChannelList err %s", err))
+	} else {
+		metrics.Channels.Reset()
+		for _, c := range cs {
+			metrics.Channels.Inc(c.State.String())
+		}
+	}
+	blockNumber := rs.GetBlockNumber()
+	metrics.BlockNumber.Set(float64(blockNumber))
+	if rs.AlarmTask != nil {
+		if chainBlockNumber := rs.AlarmTask.LastBlockNumber(); chainBlockNumber > 0 {
+			metrics.ChainBlockNumber.Set(float64(chainBlockNumber))
+			metrics.BlockLag.Set(float64(chainBlockNumber - blockNumber))
+		}
+	}
+	lastBlockTime := rs.db.GetLastBlockNumberTime()
+	if !lastBlockTime.IsZero() {
+		metrics.SecondsSinceLastBlock.Set(time.Since(lastBlockTime).Seconds())
+	}
+	ethStatus := netshare.Disconnected
+	if rs.Chain != nil && rs.Chain.Client != nil {
+		ethStatus = rs.Chain.Client.Status
+	}
+	metrics.ConnectionStatus.Set(float64(ethStatus), "eth")
+	if rs.Chain != nil && rs.Chain.Client != nil {
+		//endpoints are labelled by their order, urls may contain api keys
+		for i, es := range rs.Chain.Client.EndpointStatus() {
+			metrics.ConnectionStatus.Set(float64(es.Status), "eth"+strconv.Itoa(i))
+		}
+	}
+	if cs, ok := rs.Transport.(network.ConnectionStatuser); ok {
+		metrics.ConnectionStatus.Set(float64(cs.ConnectionStatus()), "xmpp")
+	}
+}
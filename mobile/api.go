@@ -13,6 +13,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden"
 	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/network"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
@@ -56,6 +57,7 @@ func (a *API) GetChannelList() (channels string, err error) {
 			PartnerLockedAmount: c.PartnerAmountLocked(),
 			State:               c.State,
 			TokenAddress:        c.TokenAddress().String(),
+			RegistryAddress:     c.RegistryAddress.String(),
 			SettleTimeout:       c.SettleTimeout,
 			RevealTimeout:       c.RevealTimeout,
 		}
@@ -84,6 +86,7 @@ func (a *API) GetOneChannel(channelAddress string) (channel string, err error) {
 		State:                    c.State,
 		SettleTimeout:            c.SettleTimeout,
 		TokenAddress:             c.TokenAddress().String(),
+		RegistryAddress:          c.RegistryAddress.String(),
 		LockedAmount:             c.OurAmountLocked(),
 		PartnerLockedAmount:      c.PartnerAmountLocked(),
 		ClosedBlock:              c.ClosedBlock,
@@ -124,6 +127,7 @@ func (a *API) OpenChannel(partnerAddress, tokenAddress string, settleTimeout int
 		State:               c.State,
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 	}
@@ -166,6 +170,7 @@ func (a *API) CloseChannel(channelAddress string, force bool) (channel string, e
 		State:               c.State,
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 	}
@@ -200,6 +205,7 @@ func (a *API) SettleChannel(channelAddres string) (channel string, err error) {
 		State:               c.State,
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 	}
@@ -236,6 +242,7 @@ func (a *API) DepositChannel(channelAddres string, balanceStr string) (channel s
 		State:               c.State,
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 	}
@@ -245,7 +252,7 @@ func (a *API) DepositChannel(channelAddres string, balanceStr string) (channel s
 
 //NetworkEvent GET /api/<version>/events/network
 func (a *API) NetworkEvent(fromBlock, toBlock int64) (eventsString string, err error) {
-	events, err := a.api.GetNetworkEvents(fromBlock, toBlock)
+	events, err := a.api.GetNetworkEvents(&models.ContractEventFilter{FromBlock: fromBlock, ToBlock: toBlock})
 	if err != nil {
 		log.Error(err.Error())
 		return
@@ -257,7 +264,7 @@ func (a *API) NetworkEvent(fromBlock, toBlock int64) (eventsString string, err e
 //TokensEvent GET /api/1/events/tokens/0x61c808d82a3ac53231750dadc13c777b59310bd9
 func (a *API) TokensEvent(fromBlock, toBlock int64, tokenAddress string) (eventsString string, err error) {
 	token := common.HexToAddress(tokenAddress)
-	events, err := a.api.GetTokenNetworkEvents(token, fromBlock, toBlock)
+	events, err := a.api.GetTokenNetworkEvents(utils.EmptyAddress, token, &models.ContractEventFilter{FromBlock: fromBlock, ToBlock: toBlock})
 	if err != nil {
 		log.Error(err.Error())
 		return
@@ -269,7 +276,7 @@ func (a *API) TokensEvent(fromBlock, toBlock int64, tokenAddress string) (events
 //ChannelsEvent GET /api/1/events/channels/0x2a65aca4d5fc5b5c859090a6c34d164135398226?from_block=1337
 func (a *API) ChannelsEvent(fromBlock, toBlock int64, channelAddress string) (eventsString string, err error) {
 	channel := common.HexToHash(channelAddress)
-	events, err := a.api.GetChannelEvents(channel, fromBlock, toBlock)
+	events, err := a.api.GetChannelEvents(channel, &models.ContractEventFilter{FromBlock: fromBlock, ToBlock: toBlock})
 	if err != nil {
 		log.Error(err.Error())
 		return
@@ -283,9 +290,13 @@ func (a *API) Address() (addr string) {
 	return a.api.Address().String()
 }
 
-//Tokens GET /api/1/tokens
+//Tokens GET /api/1/tokens, tokens of all registries
 func (a *API) Tokens() (tokens string) {
-	tokens, err := marshal(a.api.Tokens())
+	ts, err := a.api.GetRegistryTokens(utils.EmptyAddress)
+	if err != nil {
+		log.Error(fmt.Sprintf("GetRegistryTokens error %s", err))
+	}
+	tokens, err = marshal(ts)
 	if err != nil {
 		log.Error(fmt.Sprintf("marshal tokens error %s", err))
 	}
@@ -457,6 +468,17 @@ func (a *API) UpdateMeshNetworkNodes(nodesstr string) (err error) {
 	return nil
 }
 
+/*
+Backup writes a consistent snapshot of db while running, returns path of the snapshot.
+*/
+func (a *API) Backup() (path string, err error) {
+	path, err = a.api.Backup()
+	if err != nil {
+		log.Error(err.Error())
+	}
+	return
+}
+
 /*
 EthereumStatus  query the status between raiden and ethereum
 todo fix it ,r is useless
@@ -497,6 +519,51 @@ func (a *API) GetReceivedTransfers(from, to int64) (r string, err error) {
 	return
 }
 
+//transferPage is a page of transfer history
+type transferPage struct {
+	Transfers  interface{} `json:"transfers"`
+	NextCursor string      `json:"next_cursor"` //empty if it's the last page
+}
+
+/*
+FindSentTransfers returns a page of sent transfers selected by `filter`, which is a json of models.TransferFilter,
+for example {"token_address":"0x...","from_time":1536000000,"min_amount":100,"limit":50}.
+pass `next_cursor` of the result as `cursor` of filter to get the next page.
+*/
+func (a *API) FindSentTransfers(filter string) (r string, err error) {
+	f := new(models.TransferFilter)
+	err = json.Unmarshal([]byte(filter), f)
+	if err != nil {
+		return
+	}
+	trs, next, err := a.api.FindSentTransfers(f)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	r, err = marshal(&transferPage{Transfers: trs, NextCursor: next})
+	return
+}
+
+/*
+FindReceivedTransfers returns a page of received transfers selected by `filter`, which is a json of models.TransferFilter.
+pass `next_cursor` of the result as `cursor` of filter to get the next page.
+*/
+func (a *API) FindReceivedTransfers(filter string) (r string, err error) {
+	f := new(models.TransferFilter)
+	err = json.Unmarshal([]byte(filter), f)
+	if err != nil {
+		return
+	}
+	trs, next, err := a.api.FindReceivedTransfers(f)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	r, err = marshal(&transferPage{Transfers: trs, NextCursor: next})
+	return
+}
+
 // Subscription represents an event subscription where events are
 // delivered on a data channel.
 type Subscription struct {
@@ -6,7 +6,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -23,13 +22,12 @@ type SentRemoveExpiredHashlockTransfer struct {
 
 //IsSentRemoveExpiredHashlockTransferExist returns true when this message has been sent
 func (model *ModelDB) IsSentRemoveExpiredHashlockTransferExist(echohash common.Hash) bool {
-	var rss SentRemoveExpiredHashlockTransfer
-	err := model.db.One("EchoHashString", echohash.String(), &rss)
+	_, err := model.storage.GetSentRemoveExpiredHashlockTransfer(echohash)
 	return err == nil
 }
 
 //NewSentRemoveExpiredHashlockTransfer create a sending RemoveExpiredHashlockTransfer in db
-func (model *ModelDB) NewSentRemoveExpiredHashlockTransfer(msg *encoding.RemoveExpiredHashlockTransfer, receiver common.Address, tx storm.Node) {
+func (model *ModelDB) NewSentRemoveExpiredHashlockTransfer(msg *encoding.RemoveExpiredHashlockTransfer, receiver common.Address, tx TX) {
 	echohash := utils.Sha3(msg.Pack(), receiver[:])
 	tr := &SentRemoveExpiredHashlockTransfer{
 		EchoHash:       echohash,
@@ -39,7 +37,7 @@ func (model *ModelDB) NewSentRemoveExpiredHashlockTransfer(msg *encoding.RemoveE
 		IsComplete:     "false",
 	}
 	log.Trace(fmt.Sprintf("NewSentRemoveExpiredHashlockTransfer %s", utils.HPex(tr.EchoHash)))
-	err := tx.Save(tr)
+	err := model.storage.SaveSentRemoveExpiredHashlockTransfer(tr, tx)
 	if err != nil {
 		log.Error(fmt.Sprintf("NewSentRemoveExpiredHashlockTransfer err=%s", err))
 	}
@@ -47,13 +45,13 @@ func (model *ModelDB) NewSentRemoveExpiredHashlockTransfer(msg *encoding.RemoveE
 
 //UpdateSentRemoveExpiredHashlockTransfer mark message sent complete
 func (model *ModelDB) UpdateSentRemoveExpiredHashlockTransfer(echohash common.Hash) {
-	var sss SentRemoveExpiredHashlockTransfer
 	log.Trace(fmt.Sprintf("UpdateSentRemoveExpiredHashlockTransfer %s", utils.HPex(echohash)))
-	err := model.db.One("EchoHashString", echohash.String(), &sss)
+	sss, err := model.storage.GetSentRemoveExpiredHashlockTransfer(echohash)
 	if err != nil {
 		panic("UpdateSentRemoveExpiredHashlockTransfer  must exist")
 	}
-	err = model.db.UpdateField(&sss, "IsComplete", "true")
+	sss.IsComplete = "true"
+	err = model.storage.SaveSentRemoveExpiredHashlockTransfer(sss, nil)
 	if err != nil {
 		panic(fmt.Sprintf("UpdateSentRemoveExpiredHashlockTransfer err %s", err))
 	}
@@ -61,9 +59,8 @@ func (model *ModelDB) UpdateSentRemoveExpiredHashlockTransfer(echohash common.Ha
 
 //GetAllUncompleteSentRemoveExpiredHashlockTransfer returns all RemoveExpiredHashlockTransfer message that have not receive ack
 func (model *ModelDB) GetAllUncompleteSentRemoveExpiredHashlockTransfer() []*SentRemoveExpiredHashlockTransfer {
-	var msgs []*SentRemoveExpiredHashlockTransfer
-	err := model.db.Find("IsComplete", "false", &msgs)
-	if err != nil && err != storm.ErrNotFound {
+	msgs, err := model.storage.GetUncompleteSentRemoveExpiredHashlockTransfers()
+	if err != nil {
 		panic(fmt.Sprintf("GetAllUncompleteSentRemoveExpiredHashlockTransfer err=%s", err))
 	}
 	log.Trace(fmt.Sprintf("GetAllUncompleteSentRemoveExpiredHashlockTransfer=%s", utils.StringInterface(msgs, 7)))
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+)
+
+//API token scopes, admin can do anything, and every scope can read.
+const (
+	APIScopeRead     = "read"
+	APIScopePayments = "payments"
+	APIScopeChannels = "channels"
+	APIScopeAdmin    = "admin"
+)
+
+//IsValidAPIScope returns true when `scope` is a known scope
+func IsValidAPIScope(scope string) bool {
+	switch scope {
+	case APIScopeRead, APIScopePayments, APIScopeChannels, APIScopeAdmin:
+		return true
+	}
+	return false
+}
+
+/*
+APIToken is a bearer token of restful api.
+only the hash of token is saved, so token leaked from db cannot be used.
+*/
+type APIToken struct {
+	Name      string `storm:"id"`
+	TokenHash string `storm:"unique"`
+	Scopes    []string
+	CreatedAt time.Time
+}
+
+//HasScope returns true when this token is allowed to call api of `scope`
+func (t *APIToken) HasScope(scope string) bool {
+	for _, s := range t.Scopes {
+		if s == scope || s == APIScopeAdmin {
+			return true
+		}
+	}
+	return scope == APIScopeRead && len(t.Scopes) > 0
+}
+
+//HashAPIToken is the hash of `token` saved in db
+func HashAPIToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
+//NewAPIToken create a token named `name` which can call api of `scopes`
+func (model *ModelDB) NewAPIToken(name, token string, scopes []string) (t *APIToken, err error) {
+	_, err = model.GetAPIToken(name)
+	if err == nil {
+		return nil, fmt.Errorf("api token %s already exists", name)
+	}
+	t = &APIToken{
+		Name:      name,
+		TokenHash: HashAPIToken(token),
+		Scopes:    scopes,
+		CreatedAt: time.Now(),
+	}
+	err = model.storage.SaveAPIToken(t)
+	if err != nil {
+		log.Error(fmt.Sprintf("NewAPIToken err %s", err))
+	}
+	return
+}
+
+//GetAPIToken returns api token named `name`
+func (model *ModelDB) GetAPIToken(name string) (t *APIToken, err error) {
+	return model.storage.GetAPIToken(name)
+}
+
+//GetAPITokenByToken returns api token whose token is `token`
+func (model *ModelDB) GetAPITokenByToken(token string) (t *APIToken, err error) {
+	return model.storage.GetAPITokenByHash(HashAPIToken(token))
+}
+
+//GetAllAPITokens returns all api tokens
+func (model *ModelDB) GetAllAPITokens() (ts []*APIToken, err error) {
+	return model.storage.GetAllAPITokens()
+}
+
+//RemoveAPIToken remove api token named `name`
+func (model *ModelDB) RemoveAPIToken(name string) error {
+	t, err := model.GetAPIToken(name)
+	if err != nil {
+		return err
+	}
+	return model.storage.DeleteAPIToken(t)
+}
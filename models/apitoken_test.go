@@ -0,0 +1,46 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_APIToken(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	_, err := model.NewAPIToken("pay", "secret1", []string{APIScopePayments})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = model.NewAPIToken("pay", "secret2", []string{APIScopeAdmin})
+	if err == nil {
+		t.Error("name should be unique")
+		return
+	}
+	tk, err := model.GetAPITokenByToken("secret1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, tk.Name, "pay")
+	assert.EqualValues(t, tk.HasScope(APIScopePayments), true)
+	assert.EqualValues(t, tk.HasScope(APIScopeRead), true)
+	assert.EqualValues(t, tk.HasScope(APIScopeChannels), false)
+	assert.EqualValues(t, tk.HasScope(APIScopeAdmin), false)
+	_, err = model.GetAPITokenByToken("secret2")
+	if err == nil {
+		t.Error("should not found")
+		return
+	}
+	admin := &APIToken{Scopes: []string{APIScopeAdmin}}
+	assert.EqualValues(t, admin.HasScope(APIScopeChannels), true)
+	err = model.RemoveAPIToken("pay")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ts, err := model.GetAllAPITokens()
+	assert.EqualValues(t, len(ts), 0)
+}
@@ -0,0 +1,202 @@
+package models
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	gobcodec "github.com/asdine/storm/codec/gob"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+const keyRestored = "restored"
+
+/*
+Backup writes a consistent snapshot of db to file `to` while the node is running.
+the snapshot is written to a temporary file first, so `to` is either a complete db or doesn't exist.
+*/
+func (model *ModelDB) Backup(to string) error {
+	return model.storage.Backup(to)
+}
+
+//BackupDir returns default directory to put backups in
+func (model *ModelDB) BackupDir() string {
+	return filepath.Join(filepath.Dir(model.Name), "backups")
+}
+
+//NewBackupPath returns a new file name for backup in BackupDir
+func (model *ModelDB) NewBackupPath() string {
+	return filepath.Join(model.BackupDir(), fmt.Sprintf("%s.%s.bak", filepath.Base(model.Name), time.Now().Format("20060102150405")))
+}
+
+//ExportedSecret is a secret known in channel
+type ExportedSecret struct {
+	ChannelIdentifier common.Hash
+	Secret            common.Hash
+	IsPartners        bool //true if it's a secret of partner's lock
+}
+
+/*
+ExportedStateManager is a StateManager without its transition function, which cannot be encoded.
+*/
+type ExportedStateManager struct {
+	*transfer.StateManager
+	FuncStateTransition *struct{} `json:",omitempty"`
+}
+
+/*
+DbExport is everything needed to inspect a node's funds,
+it's only for reading by human or other tools, restore from a backup made by Backup.
+*/
+type DbExport struct {
+	Version           int
+	ExportedAt        time.Time
+	RegistryAddress   common.Address
+	BlockNumber       int64
+	Tokens            AddressMap
+	Channels          []*channeltype.Serialization
+	Secrets           []*ExportedSecret
+	StateManagers     []*ExportedStateManager
+	SentTransfers     []*SentTransfer
+	ReceivedTransfers []*ReceivedTransfer
+}
+
+//Export dumps channels, StateManagers, secrets and transfer history of db
+func (model *ModelDB) Export() (e *DbExport, err error) {
+	e = &DbExport{
+		Version:         dbVersion,
+		ExportedAt:      time.Now(),
+		RegistryAddress: model.GetRegistryAddress(),
+		BlockNumber:     model.GetLatestBlockNumber(),
+	}
+	e.Tokens, err = model.GetAllTokens()
+	if err != nil {
+		return
+	}
+	e.Channels, err = model.GetChannelList(common.Address{}, common.Address{})
+	if err != nil {
+		return
+	}
+	for _, c := range e.Channels {
+		for _, s := range c.OurKnownSecrets {
+			e.Secrets = append(e.Secrets, &ExportedSecret{c.ChannelIdentifier.ChannelIdentifier, s, false})
+		}
+		for _, s := range c.PartnerKnownSecrets {
+			e.Secrets = append(e.Secrets, &ExportedSecret{c.ChannelIdentifier.ChannelIdentifier, s, true})
+		}
+	}
+	for _, mgr := range model.GetAllStateManager() {
+		e.StateManagers = append(e.StateManagers, &ExportedStateManager{StateManager: mgr})
+	}
+	e.SentTransfers, err = model.GetSentTransferInBlockRange(-1, -1)
+	if err != nil {
+		return
+	}
+	e.ReceivedTransfers, err = model.GetReceivedTransferInBlockRange(-1, -1)
+	if err != nil {
+		return
+	}
+	return e, nil
+}
+
+/*
+RestoreDb replaces db at `dbPath` with backup `from`, and marks it restored,
+so channels in it will be checked against blockchain on next start.
+the backup is checked on a copy, so `from` is never modified.
+the replaced db is renamed, not removed.
+the node must not be running.
+*/
+func RestoreDb(from, dbPath string) (err error) {
+	if _, err = os.Stat(from); err != nil {
+		return
+	}
+	tmp := dbPath + ".restoring"
+	err = copyFile(from, tmp)
+	if err != nil {
+		return
+	}
+	err = markRestored(tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("%s is not a backup of smartraiden db: %s", from, err)
+	}
+	if _, err = os.Stat(dbPath); err == nil {
+		old := fmt.Sprintf("%s.%s.replaced", dbPath, time.Now().Format("20060102150405"))
+		err = os.Rename(dbPath, old)
+		if err != nil {
+			os.Remove(tmp)
+			return
+		}
+		log.Info(fmt.Sprintf("db %s replaced by restore is moved to %s", dbPath, old))
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+/*
+markRestored checks version of db at `dbPath` and sets restored flag in it.
+values of an encrypted backup are written as is, and checked when it's opened with password.
+*/
+func markRestored(dbPath string) (err error) {
+	s, err := openStorage(dbPath, &cryptCodec{MarshalUnmarshaler: gobcodec.Codec})
+	if err != nil {
+		return
+	}
+	defer s.Close()
+	var ver int
+	err = s.Get(bucketMeta, "version", &ver)
+	if err != nil && err != ErrDbEncrypted {
+		return
+	}
+	if ver > dbVersion {
+		return fmt.Errorf("backup version %d is newer than supported version %d", ver, dbVersion)
+	}
+	err = s.Set(bucketMeta, keyRestored, true)
+	if err != nil {
+		return
+	}
+	//backup is taken while running, it's not a crash
+	return s.Set(bucketMeta, "close", true)
+}
+
+func copyFile(from, to string) (err error) {
+	src, err := os.Open(from)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return
+	}
+	_, err = io.Copy(dst, src)
+	if err == nil {
+		err = dst.Sync()
+	}
+	if err2 := dst.Close(); err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(to)
+	}
+	return
+}
+
+//IsRestored returns true when db is restored from backup and channels are not checked against blockchain yet
+func (model *ModelDB) IsRestored() bool {
+	var restored bool
+	err := model.storage.Get(bucketMeta, keyRestored, &restored)
+	if err != nil && err != ErrNotFound {
+		log.Error(fmt.Sprintf("db err %s", err))
+	}
+	return restored
+}
+
+//MarkRestoreChecked marks that channels of a restored db have been checked against blockchain
+func (model *ModelDB) MarkRestoreChecked() error {
+	return model.storage.Delete(bucketMeta, keyRestored)
+}
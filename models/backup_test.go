@@ -0,0 +1,67 @@
+package models
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackupRestore(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	model, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := utils.NewRandomAddress()
+	tokenNetwork := utils.NewRandomAddress()
+	err = model.AddToken(token, tokenNetwork)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.SaveLatestBlockNumber(10)
+	bak := filepath.Join(filepath.Dir(dbPath), "log.db.bak")
+	err = model.Backup(bak)
+	if err != nil {
+		t.Fatal(err)
+	}
+	//changes after backup are lost after restore
+	model.SaveLatestBlockNumber(20)
+	assert.EqualValues(t, false, model.IsRestored())
+	e, err := model.Export()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 20, e.BlockNumber)
+	assert.EqualValues(t, tokenNetwork, e.Tokens[token])
+	e.StateManagers = append(e.StateManagers, &ExportedStateManager{StateManager: &transfer.StateManager{Name: "test"}})
+	_, err = json.Marshal(e)
+	assert.Nil(t, err)
+	model.CloseDB()
+
+	err = RestoreDb(bak, dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replaced, _ := filepath.Glob(dbPath + ".*.replaced")
+	assert.EqualValues(t, 1, len(replaced))
+	model, err = OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 10, model.GetLatestBlockNumber())
+	assert.EqualValues(t, true, model.IsRestored())
+	assert.EqualValues(t, false, model.IsDbCrashedLastTime())
+	err = model.MarkRestoreChecked()
+	assert.Nil(t, err)
+	assert.EqualValues(t, false, model.IsRestored())
+	model.CloseDB()
+
+	err = RestoreDb(dbPath+".notexist", dbPath)
+	assert.NotNil(t, err)
+}
@@ -2,6 +2,7 @@ package models
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"time"
 
@@ -15,7 +16,7 @@ const keyBlockTime = "blockTime"
 //GetLatestBlockNumber lastest block number
 func (model *ModelDB) GetLatestBlockNumber() int64 {
 	var number int64
-	err := model.db.Get(bucketBlockNumber, keyBlockNumber, &number)
+	err := model.storage.Get(bucketBlockNumber, keyBlockNumber, &number)
 	if err != nil {
 		log.Error(fmt.Sprintf("models GetLatestBlockNumber err=%s", err))
 	}
@@ -24,11 +25,12 @@ func (model *ModelDB) GetLatestBlockNumber() int64 {
 
 //SaveLatestBlockNumber block numer has been processed
 func (model *ModelDB) SaveLatestBlockNumber(blockNumber int64) {
-	err := model.db.Set(bucketBlockNumber, keyBlockNumber, blockNumber)
+	err := model.storage.Set(bucketBlockNumber, keyBlockNumber, blockNumber)
 	if err != nil {
 		log.Error(fmt.Sprintf("models SaveLatestBlockNumber err=%s", err))
 	}
-	err = model.db.Set(bucketBlockNumber, keyBlockTime, time.Now())
+	atomic.StoreInt64(&model.blockNumber, blockNumber)
+	err = model.storage.Set(bucketBlockNumber, keyBlockTime, time.Now())
 	if err != nil {
 		log.Error(fmt.Sprintf("models SaveLatestBlockTime err=%s", err))
 	}
@@ -37,7 +39,7 @@ func (model *ModelDB) SaveLatestBlockNumber(blockNumber int64) {
 //GetLastBlockNumberTime return when last block received
 func (model *ModelDB) GetLastBlockNumberTime() time.Time {
 	var t time.Time
-	err := model.db.Get(bucketBlockNumber, keyBlockTime, &t)
+	err := model.storage.Get(bucketBlockNumber, keyBlockTime, &t)
 	if err != nil {
 		log.Error(fmt.Sprintf("GetLastBlockNumberTime err %s", err))
 	}
@@ -9,14 +9,13 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/models/cb"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
 // NewChannel save a just created channel to db
 func (model *ModelDB) NewChannel(c *channeltype.Serialization) error {
 	//log.Trace(fmt.Sprintf("new channel %s", utils.StringInterface(c, 2)))
-	err := model.db.Save(c)
+	err := model.storage.SaveChannel(c, nil)
 	//notify new channel added
 	model.handleChannelCallback(model.newChannelCallbacks, c)
 	if err != nil {
@@ -28,7 +27,7 @@ func (model *ModelDB) NewChannel(c *channeltype.Serialization) error {
 //UpdateChannelNoTx update channel status without a Tx
 func (model *ModelDB) UpdateChannelNoTx(c *channeltype.Serialization) error {
 	//log.Trace(fmt.Sprintf("save channel %s", utils.StringInterface(c, 2)))
-	err := model.db.Save(c)
+	err := model.storage.SaveChannel(c, nil)
 	if err != nil {
 		log.Error(fmt.Sprintf("UpdateChannelNoTx err:%s", err))
 	}
@@ -79,9 +78,9 @@ func (model *ModelDB) UpdateChannelContractBalance(c *channeltype.Serialization)
 }
 
 //UpdateChannel update channel status in a Tx
-func (model *ModelDB) UpdateChannel(c *channeltype.Serialization, tx storm.Node) error {
+func (model *ModelDB) UpdateChannel(c *channeltype.Serialization, tx TX) error {
 	//log.Trace(fmt.Sprintf("statemanager save channel status =%s\n", utils.StringInterface(c, 2)))
-	err := tx.Save(c)
+	err := model.storage.SaveChannel(c, tx)
 	if err != nil {
 		log.Error(fmt.Sprintf("UpdateChannel err=%s", err))
 	}
@@ -105,68 +104,78 @@ func (model *ModelDB) RemoveChannel(c *channeltype.Serialization) error {
 		panic("only can remove a settled channel")
 	}
 	model.handleChannelCallback(model.channelSettledCallbacks, c)
-	return model.db.DeleteStruct(c)
+	return model.storage.DeleteChannel(c)
 }
 
 //GetChannel return a channel queried by (token,partner),this channel must not settled
 func (model *ModelDB) GetChannel(token, partner common.Address) (c *channeltype.Serialization, err error) {
-	var cs []*channeltype.Serialization
 	if token == utils.EmptyAddress {
 		panic("token is empty")
 	}
 	if partner == utils.EmptyAddress {
 		panic("partner is empty")
 	}
-	err = model.db.Find("TokenAddressBytes", token[:], &cs)
+	cs, err := model.storage.GetChannels(token, partner)
 	if err != nil {
 		return
 	}
 	for _, c2 := range cs {
-		if c2.PartnerAddress() == partner && c2.State != channeltype.StateSettled {
+		if c2.State != channeltype.StateSettled {
 			c = c2
 			return
 		}
 	}
-	return nil, storm.ErrNotFound
+	return nil, ErrNotFound
+}
+
+/*
+GetRegistryChannel return a channel not settled queried by (registry,token,partner),
+the partner may have channels of the same token on more than one registry.
+*/
+func (model *ModelDB) GetRegistryChannel(registry, token, partner common.Address) (c *channeltype.Serialization, err error) {
+	if token == utils.EmptyAddress {
+		panic("token is empty")
+	}
+	if partner == utils.EmptyAddress {
+		panic("partner is empty")
+	}
+	cs, err := model.storage.GetChannels(token, partner)
+	if err != nil {
+		return
+	}
+	for _, c2 := range cs {
+		if c2.State != channeltype.StateSettled && c2.RegistryAddress == registry {
+			c = c2
+			return
+		}
+	}
+	return nil, ErrNotFound
 }
 
 //GetChannelByAddress return a channel queried by channel address
 func (model *ModelDB) GetChannelByAddress(channelAddress common.Hash) (c *channeltype.Serialization, err error) {
-	var c2 channeltype.Serialization
-	err = model.db.One("Key", channelAddress[:], &c2)
-	if err == nil {
-		c = &c2
-	}
-	return
+	return model.storage.GetChannelByAddress(channelAddress)
 }
 
 //GetChannelList returns all related channels
 //one of token and partner must be empty
 func (model *ModelDB) GetChannelList(token, partner common.Address) (cs []*channeltype.Serialization, err error) {
-	if token == utils.EmptyAddress && partner == utils.EmptyAddress {
-		err = model.db.All(&cs)
-	} else if token == utils.EmptyAddress {
-		err = model.db.Find("PartnerAddressBytes", partner[:], &cs)
-	} else if partner == utils.EmptyAddress {
-		err = model.db.Find("TokenAddressBytes", token[:], &cs)
-	} else {
+	if token != utils.EmptyAddress && partner != utils.EmptyAddress {
 		panic("one of token and partner must be empty")
 	}
-	if err == storm.ErrNotFound {
-		err = nil
-	}
-	return
+	return model.storage.GetChannels(token, partner)
 }
 
 const bucketWithDraw = "bucketWithdraw"
 
 /*
 IsThisLockHasUnlocked return ture when  lockhash has unlocked on channel?
+key must be bytes as UnlockThisLock saves it, a hash key is encoded by codec and never matches.
 */
 func (model *ModelDB) IsThisLockHasUnlocked(channel common.Hash, lockHash common.Hash) bool {
 	var result bool
 	key := utils.Sha3(channel[:], lockHash[:])
-	err := model.db.Get(bucketWithDraw, key, &result)
+	err := model.storage.Get(bucketWithDraw, key.Bytes(), &result)
 	if err != nil {
 		return false
 	}
@@ -181,7 +190,7 @@ UnlockThisLock marks that I have withdrawed this secret on channel.
 */
 func (model *ModelDB) UnlockThisLock(channel common.Hash, lockHash common.Hash) {
 	key := utils.Sha3(channel[:], lockHash[:])
-	err := model.db.Set(bucketWithDraw, key.Bytes(), true)
+	err := model.storage.Set(bucketWithDraw, key.Bytes(), true)
 	if err != nil {
 		log.Error(fmt.Sprintf("UnlockThisLock write %s to db err %s", hex.EncodeToString(key.Bytes()), err))
 	}
@@ -195,7 +204,7 @@ IsThisLockRemoved return true when  a expired hashlock has been removed from cha
 func (model *ModelDB) IsThisLockRemoved(channel common.Hash, sender common.Address, lockHash common.Hash) bool {
 	var result bool
 	key := utils.Sha3(channel[:], lockHash[:], sender[:])
-	err := model.db.Get(bucketExpiredHashlock, key.Bytes(), &result)
+	err := model.storage.Get(bucketExpiredHashlock, key.Bytes(), &result)
 	if err != nil {
 		return false
 	}
@@ -210,7 +219,7 @@ RemoveLock remember this lock has been removed from channel status.
 */
 func (model *ModelDB) RemoveLock(channel common.Hash, sender common.Address, lockHash common.Hash) {
 	key := utils.Sha3(channel[:], lockHash[:], sender[:])
-	err := model.db.Set(bucketExpiredHashlock, key.Bytes(), true)
+	err := model.storage.Set(bucketExpiredHashlock, key.Bytes(), true)
 	if err != nil {
 		log.Error(fmt.Sprintf("UnlockThisLock write %s to db err %s", hex.EncodeToString(key.Bytes()), err))
 	}
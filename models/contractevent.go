@@ -0,0 +1,199 @@
+package models
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//keyContractEventHistoryIndexed in bucketMeta is true after events before the first start of index are indexed
+const keyContractEventHistoryIndexed = "contractEventHistoryIndexed"
+
+//keyContractEventHistoryNextBlock in bucketMeta is the first block whose events may not be indexed yet
+const keyContractEventHistoryNextBlock = "contractEventHistoryNextBlock"
+
+/*
+ContractEvent is an event of registry, token network or secret registry contract, decoded from its log.
+the same log is always saved with the same key, so saving it again replaces it.
+*/
+type ContractEvent struct {
+	Key         string         `json:"-" storm:"id"`
+	BlockNumber int64          `json:"block_number" storm:"index"`
+	LogIndex    uint           `json:"log_index"`
+	TxHash      common.Hash    `json:"tx_hash"`
+	EventType   string         `json:"event_type"`
+	Contract    common.Address `json:"contract_address"`
+	//ChannelIdentifier is empty for events of registry and secret registry
+	ChannelIdentifier common.Hash `json:"channel_identifier"`
+	//Participants are addresses named by the event
+	Participants []common.Address `json:"participants"`
+	//Data is other arguments of the event, numbers are decimal and addresses and hashes are hex
+	Data map[string]string `json:"data"`
+}
+
+//NewContractEvent creates an event of `eventType` from `l`, its arguments are set by caller
+func NewContractEvent(eventType string, l *types.Log) *ContractEvent {
+	return &ContractEvent{
+		Key:         fmt.Sprintf("%s-%d", l.TxHash.String(), l.Index),
+		BlockNumber: int64(l.BlockNumber),
+		LogIndex:    l.Index,
+		TxHash:      l.TxHash,
+		EventType:   eventType,
+		Contract:    l.Address,
+		Data:        make(map[string]string),
+	}
+}
+
+//hasParticipant returns true if `addr` is named by `e`
+func (e *ContractEvent) hasParticipant(addr common.Address) bool {
+	for _, p := range e.Participants {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+ParticipantChannel indexes a channel by one of its participants, it's saved with the ChannelOpened event of the channel,
+so events of a participant are found without reading every ChannelOpened event.
+*/
+type ParticipantChannel struct {
+	Key               string         `storm:"id"`
+	Participant       string         `storm:"index"` //hex, like indexes of transfers
+	Contract          common.Address //token network
+	ChannelIdentifier common.Hash
+}
+
+//participantChannels returns index of participants of `e`, nil if it doesn't open a channel
+func (e *ContractEvent) participantChannels() (pcs []*ParticipantChannel) {
+	if e.EventType != params.NameChannelOpened && e.EventType != params.NameChannelOpenedAndDeposit {
+		return nil
+	}
+	for _, p := range e.Participants {
+		pcs = append(pcs, &ParticipantChannel{
+			Key:               fmt.Sprintf("%s-%s", p.String(), e.ChannelIdentifier.String()),
+			Participant:       p.String(),
+			Contract:          e.Contract,
+			ChannelIdentifier: e.ChannelIdentifier,
+		})
+	}
+	return
+}
+
+/*
+ContractEventFilter selects contract events, zero values match any.
+events without participants, like ChannelSettled, are selected by `Participant` if their channel is opened with it.
+*/
+type ContractEventFilter struct {
+	FromBlock         int64          `json:"from_block"`
+	ToBlock           int64          `json:"to_block"` //not positive means no limit
+	EventTypes        []string       `json:"event_types"`
+	Contract          common.Address `json:"contract_address"`
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	Participant       common.Address `json:"participant"`
+	//channels of Participant, found by ModelDB before querying Storage
+	channels map[common.Hash]bool
+}
+
+//normalize returns a copy of `f` whose limits are explicit, for Storage
+func (f *ContractEventFilter) normalize() *ContractEventFilter {
+	nf := *f
+	if nf.FromBlock < 0 {
+		nf.FromBlock = 0
+	}
+	if nf.ToBlock <= 0 {
+		nf.ToBlock = math.MaxInt64
+	}
+	return &nf
+}
+
+//matchType returns true if `eventType` is selected by `f`
+func (f *ContractEventFilter) matchType(eventType string) bool {
+	if len(f.EventTypes) == 0 {
+		return true
+	}
+	for _, t := range f.EventTypes {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+//match returns true if `e` is selected by normalized filter `f`
+func (f *ContractEventFilter) match(e *ContractEvent) bool {
+	if e.BlockNumber < f.FromBlock || e.BlockNumber > f.ToBlock {
+		return false
+	}
+	if !f.matchType(e.EventType) {
+		return false
+	}
+	if f.Contract != utils.EmptyAddress && f.Contract != e.Contract {
+		return false
+	}
+	if f.ChannelIdentifier != utils.EmptyHash && f.ChannelIdentifier != e.ChannelIdentifier {
+		return false
+	}
+	if f.Participant != utils.EmptyAddress && !e.hasParticipant(f.Participant) && !f.channels[e.ChannelIdentifier] {
+		return false
+	}
+	return true
+}
+
+//SaveContractEvent save or replace a contract event
+func (model *ModelDB) SaveContractEvent(e *ContractEvent) error {
+	return model.storage.SaveContractEvent(e)
+}
+
+//FindContractEvents returns contract events selected by `f`, ordered by block number and log index
+func (model *ModelDB) FindContractEvents(f *ContractEventFilter) (events []*ContractEvent, err error) {
+	nf := f.normalize()
+	if nf.Participant != utils.EmptyAddress {
+		var channels []common.Hash
+		channels, err = model.storage.FindParticipantChannels(nf.Participant, nf.Contract)
+		if err != nil {
+			return
+		}
+		nf.channels = make(map[common.Hash]bool)
+		for _, c := range channels {
+			nf.channels[c] = true
+		}
+	}
+	return model.storage.FindContractEvents(nf)
+}
+
+//IsContractEventHistoryIndexed returns true if events happened before the first start of index are indexed
+func (model *ModelDB) IsContractEventHistoryIndexed() bool {
+	var indexed bool
+	err := model.storage.Get(bucketMeta, keyContractEventHistoryIndexed, &indexed)
+	if err != nil && err != ErrNotFound {
+		log.Error(fmt.Sprintf("get %s err %s", keyContractEventHistoryIndexed, err))
+	}
+	return indexed
+}
+
+//MarkContractEventHistoryIndexed records that all events happened before are indexed
+func (model *ModelDB) MarkContractEventHistoryIndexed() error {
+	return model.storage.Set(bucketMeta, keyContractEventHistoryIndexed, true)
+}
+
+//GetContractEventHistoryNextBlock returns the block to resume indexing events history from, 0 if not started
+func (model *ModelDB) GetContractEventHistoryNextBlock() int64 {
+	var n int64
+	err := model.storage.Get(bucketMeta, keyContractEventHistoryNextBlock, &n)
+	if err != nil && err != ErrNotFound {
+		log.Error(fmt.Sprintf("get %s err %s", keyContractEventHistoryNextBlock, err))
+	}
+	return n
+}
+
+//SaveContractEventHistoryNextBlock records that events before `blockNumber` are indexed
+func (model *ModelDB) SaveContractEventHistoryNextBlock(blockNumber int64) error {
+	return model.storage.Set(bucketMeta, keyContractEventHistoryNextBlock, blockNumber)
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestContractEvent(eventType string, blockNumber int64, index uint, contract common.Address, channel common.Hash, participants ...common.Address) *ContractEvent {
+	e := NewContractEvent(eventType, &types.Log{
+		Address:     contract,
+		BlockNumber: uint64(blockNumber),
+		TxHash:      utils.NewRandomHash(),
+		Index:       index,
+	})
+	e.ChannelIdentifier = channel
+	e.Participants = participants
+	return e
+}
+
+func TestContractEvents(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		registry := utils.NewRandomAddress()
+		tokenNetwork := utils.NewRandomAddress()
+		channel := utils.NewRandomHash()
+		p1, p2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+		created := newTestContractEvent(params.NameTokenNetworkCreated, 1, 0, registry, utils.EmptyHash)
+		created.Data["token_network_address"] = tokenNetwork.String()
+		opened := newTestContractEvent(params.NameChannelOpened, 5, 2, tokenNetwork, channel, p1, p2)
+		deposit := newTestContractEvent(params.NameChannelNewDeposit, 5, 1, tokenNetwork, channel, p2)
+		settled := newTestContractEvent(params.NameChannelSettled, 20, 0, tokenNetwork, channel)
+		other := newTestContractEvent(params.NameChannelOpened, 8, 0, tokenNetwork, utils.NewRandomHash(), utils.NewRandomAddress(), utils.NewRandomAddress())
+		for _, e := range []*ContractEvent{settled, opened, created, deposit, other} {
+			assert.Nil(t, model.SaveContractEvent(e))
+		}
+		//saving again replaces
+		assert.Nil(t, model.SaveContractEvent(opened))
+
+		events, err := model.FindContractEvents(&ContractEventFilter{})
+		assert.Nil(t, err)
+		if assert.Len(t, events, 5) {
+			assert.EqualValues(t, created, events[0])
+			assert.EqualValues(t, deposit.Key, events[1].Key)
+			assert.EqualValues(t, opened.Key, events[2].Key)
+			assert.EqualValues(t, settled.Key, events[4].Key)
+		}
+		events, err = model.FindContractEvents(&ContractEventFilter{Contract: registry})
+		assert.Nil(t, err)
+		assert.Len(t, events, 1)
+		events, err = model.FindContractEvents(&ContractEventFilter{FromBlock: 5, ToBlock: 8, EventTypes: []string{params.NameChannelOpened}})
+		assert.Nil(t, err)
+		assert.Len(t, events, 2)
+		events, err = model.FindContractEvents(&ContractEventFilter{ChannelIdentifier: channel, FromBlock: 6})
+		assert.Nil(t, err)
+		if assert.Len(t, events, 1) {
+			assert.EqualValues(t, settled.Key, events[0].Key)
+		}
+		//settled names no participant, it's selected as an event of channel opened with p1
+		events, err = model.FindContractEvents(&ContractEventFilter{Participant: p1})
+		assert.Nil(t, err)
+		assert.Len(t, events, 3)
+		events, err = model.FindContractEvents(&ContractEventFilter{Participant: utils.NewRandomAddress()})
+		assert.Nil(t, err)
+		assert.Len(t, events, 0)
+
+		assert.False(t, model.IsContractEventHistoryIndexed())
+		assert.EqualValues(t, 0, model.GetContractEventHistoryNextBlock())
+		assert.Nil(t, model.SaveContractEventHistoryNextBlock(30))
+		assert.EqualValues(t, 30, model.GetContractEventHistoryNextBlock())
+		assert.Nil(t, model.MarkContractEventHistoryIndexed())
+		assert.True(t, model.IsContractEventHistoryIndexed())
+	})
+}
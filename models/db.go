@@ -4,24 +4,23 @@ import (
 	"fmt"
 
 	"sync"
+	"sync/atomic"
 
 	"time"
 
 	"encoding/gob"
 
-	"os"
-
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/models/cb"
-	"github.com/asdine/storm"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	gobcodec "github.com/asdine/storm/codec/gob"
-	"github.com/coreos/bbolt"
 	"github.com/ethereum/go-ethereum/common"
 )
 
 //ModelDB is thread safe
 type ModelDB struct {
-	db                      *storm.DB
+	storage                 Storage
+	codec                   *cryptCodec
 	lock                    sync.Mutex
 	newTokenCallbacks       map[*cb.NewTokenCb]bool
 	newChannelCallbacks     map[*cb.ChannelCb]bool
@@ -34,11 +33,16 @@ type ModelDB struct {
 	SentTransferChan chan *SentTransfer
 	//ReceivedTransferChan  ReceivedTransfer notify, should never close
 	ReceivedTransferChan chan *ReceivedTransfer
+	//blockNumber is the latest block number saved, read atomically without touching db, for saving acks in a transaction
+	blockNumber int64
+	//lastWriteCheck is unix nano of the last write of CheckWritable, atomic
+	lastWriteCheck int64
 }
 
 var bucketMeta = "meta"
 
-const dbVersion = 1
+//dbVersion is the version of db this code works with, see migrations when changing it
+const dbVersion = 7
 
 func newModelDB() (db *ModelDB) {
 	return &ModelDB{
@@ -53,43 +57,63 @@ func newModelDB() (db *ModelDB) {
 
 }
 
-//OpenDb open or create a bolt db at dbPath
+//OpenDb open or create a db at dbPath, which is not encrypted
 func OpenDb(dbPath string) (model *ModelDB, err error) {
+	return OpenDbWithPassword(dbPath, "", false)
+}
+
+/*
+OpenDbWithPassword open or create a db at dbPath,
+an existing db is opened by the backend it's created with, a new one is a sqlite db if dbPath ends with `.sqlite`, otherwise a bolt db.
+`password` is the account password, which is needed to open an encrypted db.
+if `encrypt` is true, values of db are encrypted by a key derived from `password`.
+*/
+func OpenDbWithPassword(dbPath string, password string, encrypt bool) (model *ModelDB, err error) {
 	log.Trace(fmt.Sprintf("dbpath=%s", dbPath))
 	model = newModelDB()
 	needCreateDb := !common.FileExist(dbPath)
 	var ver int
-	model.db, err = storm.Open(dbPath, storm.BoltOptions(os.ModePerm, &bolt.Options{Timeout: 1 * time.Second}), storm.Codec(gobcodec.Codec))
+	model.codec = &cryptCodec{MarshalUnmarshaler: gobcodec.Codec}
+	model.storage, err = openStorage(dbPath, model.codec)
 	if err != nil {
 		err = fmt.Errorf("cannot create or open db:%s,makesure you have write permission err:%v", dbPath, err)
 		log.Crit(err.Error())
 		return
 	}
 	model.Name = dbPath
+	err = model.unlockEncryption(password, encrypt)
+	if err != nil {
+		model.storage.Close()
+		return
+	}
 	if needCreateDb {
-		err = model.db.Set(bucketMeta, "version", dbVersion)
+		err = model.storage.Set(bucketMeta, "version", dbVersion)
 		if err != nil {
 			log.Crit(fmt.Sprintf("unable to create db "))
 			return
 		}
-		err = model.db.Set(bucketToken, keyToken, make(AddressMap))
+		err = model.initDb()
 		if err != nil {
 			log.Crit(fmt.Sprintf("unable to create db "))
 			return
 		}
-		model.initDb()
 		model.MarkDbOpenedStatus()
 	} else {
-		err = model.db.Get(bucketMeta, "version", &ver)
+		err = model.storage.Get(bucketMeta, "version", &ver)
 		if err != nil {
 			log.Crit(fmt.Sprintf("wrong db file format "))
 			return
 		}
 		if ver != dbVersion {
-			log.Crit("db version not match")
+			err = model.migrate(ver)
+			if err != nil {
+				log.Error(err.Error())
+				model.storage.Close()
+				return
+			}
 		}
 		var closeFlag bool
-		err = model.db.Get(bucketMeta, "close", &closeFlag)
+		err = model.storage.Get(bucketMeta, "close", &closeFlag)
 		if err != nil {
 			log.Crit(fmt.Sprintf("db meta data error"))
 		}
@@ -97,10 +121,15 @@ func OpenDb(dbPath string) (model *ModelDB, err error) {
 			log.Error("database not closed  last..., try to restore?")
 		}
 	}
-
+	atomic.StoreInt64(&model.blockNumber, model.GetLatestBlockNumber())
 	return
 }
 
+//Backend returns the storage backend of db, BackendBolt or BackendSQLite
+func (model *ModelDB) Backend() string {
+	return model.storage.Backend()
+}
+
 /*
 MarkDbOpenedStatus First step   open the database
 Second step detection for normal closure IsDbCrashedLastTime
@@ -108,7 +137,7 @@ Third step  recovers the data according to the second step
 Fourth step mark the database for processing the data normally. MarkDbOpenedStatus
 */
 func (model *ModelDB) MarkDbOpenedStatus() {
-	err := model.db.Set(bucketMeta, "close", false)
+	err := model.storage.Set(bucketMeta, "close", false)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -117,18 +146,35 @@ func (model *ModelDB) MarkDbOpenedStatus() {
 //IsDbCrashedLastTime return true when quit but  db not closed
 func (model *ModelDB) IsDbCrashedLastTime() bool {
 	var closeFlag bool
-	err := model.db.Get(bucketMeta, "close", &closeFlag)
+	err := model.storage.Get(bucketMeta, "close", &closeFlag)
 	if err != nil {
 		log.Crit(fmt.Sprintf("db meta data error"))
 	}
 	return closeFlag != true
 }
 
+/*
+CheckWritable write a timestamp to db, returns error if db cannot be written.
+it writes at most once every params.DbWriteCheckInterval, checks between only read the timestamp, so frequent health checks don't sync db every time.
+*/
+func (model *ModelDB) CheckWritable() error {
+	now := time.Now()
+	if now.Sub(time.Unix(0, atomic.LoadInt64(&model.lastWriteCheck))) < params.DbWriteCheckInterval {
+		var t time.Time
+		return model.storage.Get(bucketMeta, "healthcheck", &t)
+	}
+	err := model.storage.Set(bucketMeta, "healthcheck", now)
+	if err == nil {
+		atomic.StoreInt64(&model.lastWriteCheck, now.UnixNano())
+	}
+	return err
+}
+
 //CloseDB close db
 func (model *ModelDB) CloseDB() {
 	model.lock.Lock()
-	err := model.db.Set(bucketMeta, "close", true)
-	err = model.db.Close()
+	err := model.storage.Set(bucketMeta, "close", true)
+	err = model.storage.Close()
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -137,7 +183,7 @@ func (model *ModelDB) CloseDB() {
 
 //SaveRegistryAddress save registry address to db
 func (model *ModelDB) SaveRegistryAddress(registryAddress common.Address) {
-	err := model.db.Set(bucketMeta, "registry", registryAddress)
+	err := model.storage.Set(bucketMeta, "registry", registryAddress)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -146,7 +192,7 @@ func (model *ModelDB) SaveRegistryAddress(registryAddress common.Address) {
 //GetRegistryAddress returns registry address in db
 func (model *ModelDB) GetRegistryAddress() common.Address {
 	var registry common.Address
-	err := model.db.Get(bucketMeta, "registry", &registry)
+	err := model.storage.Get(bucketMeta, "registry", &registry)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -155,7 +201,7 @@ func (model *ModelDB) GetRegistryAddress() common.Address {
 
 //SaveSecretRegistryAddress save secret registry contract address to db
 func (model *ModelDB) SaveSecretRegistryAddress(secretRegistryAddress common.Address) {
-	err := model.db.Set(bucketMeta, "secretregistry", secretRegistryAddress)
+	err := model.storage.Set(bucketMeta, "secretregistry", secretRegistryAddress)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -164,7 +210,7 @@ func (model *ModelDB) SaveSecretRegistryAddress(secretRegistryAddress common.Add
 //GetSecretRegistryAddress return secret registry contract address
 func (model *ModelDB) GetSecretRegistryAddress() common.Address {
 	var secretRegistry common.Address
-	err := model.db.Get(bucketMeta, "secretregistry", &secretRegistry)
+	err := model.storage.Get(bucketMeta, "secretregistry", &secretRegistry)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -174,11 +220,10 @@ func init() {
 	gob.Register(&ModelDB{}) //cannot save and restore by gob,only avoid noise by gob
 }
 
-func (model *ModelDB) initDb() {
-	err := model.db.Init(&SentTransfer{})
-	err = model.db.Init(&ReceivedTransfer{})
-	err = model.db.Set(bucketBlockNumber, keyBlockNumber, 0)
+func (model *ModelDB) initDb() error {
+	err := model.storage.Init()
 	if err != nil {
-		log.Error(fmt.Sprintf("db err %s", err))
+		return err
 	}
+	return model.storage.Set(bucketBlockNumber, keyBlockNumber, 0)
 }
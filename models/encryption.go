@@ -0,0 +1,243 @@
+package models
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/asdine/storm/codec"
+	"github.com/coreos/bbolt"
+	"golang.org/x/crypto/scrypt"
+)
+
+/*
+bucketEncryption holds salt and check value of the encryption key,
+they're saved as raw bytes, not by codec, so they can be read before the key is known.
+sqliteStorage saves them in a table of the same name.
+*/
+const bucketEncryption = "encryption"
+
+var (
+	keySalt  = []byte("salt")
+	keyCheck = []byte("check")
+	//encryptedPrefix marks an encrypted value, gob never encodes a value beginning with 0
+	encryptedPrefix = []byte{0, 'e', 'n', 'c', '1'}
+	checkPlainText  = []byte("smartraiden db encryption key check")
+	stormMetadata   = []byte("__storm_metadata")
+	//stormDbInfo is read by storm.Open before the key is known
+	stormDbInfo = "__storm_db"
+)
+
+var (
+	//ErrDbEncrypted db is encrypted, and no password is provided
+	ErrDbEncrypted = errors.New("db is encrypted, password of account is needed")
+	//ErrWrongDbPassword password cannot decrypt db
+	ErrWrongDbPassword = errors.New("wrong password for encrypted db, if password of account is changed, run `smartraiden db rotate-key` with the old one")
+)
+
+/*
+deriveDbKey derive the aes-256 key of db from account password.
+parameters of scrypt are lighter than keystore's, db is opened on every start of mobile app too.
+*/
+func deriveDbKey(password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 1<<15, 8, 1, 32)
+}
+
+/*
+cryptCodec encrypts every value written by Storage,
+keys and indexes are not encoded by codec as long as they are string, []byte or integer, so lookups still work.
+values not encrypted are read as is, so a db can be encrypted, decrypted or re-encrypted in place.
+*/
+type cryptCodec struct {
+	codec.MarshalUnmarshaler
+	lock sync.RWMutex
+	key  []byte //nil if not encrypted
+}
+
+func (c *cryptCodec) getKey() []byte {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.key
+}
+
+func (c *cryptCodec) setKey(key []byte) {
+	c.lock.Lock()
+	c.key = key
+	c.lock.Unlock()
+}
+
+//Marshal encodes v by the wrapped codec, then encrypts it
+func (c *cryptCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := c.MarshalUnmarshaler.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return encryptValue(data, c.getKey())
+}
+
+//Unmarshal decrypts b if it's encrypted, then decodes it by the wrapped codec
+func (c *cryptCodec) Unmarshal(b []byte, v interface{}) error {
+	data, err := decryptValue(b, c.getKey())
+	if err != nil {
+		return err
+	}
+	return c.MarshalUnmarshaler.Unmarshal(data, v)
+}
+
+func isEncrypted(v []byte) bool {
+	return bytes.HasPrefix(v, encryptedPrefix)
+}
+
+func encryptValue(data, key []byte) ([]byte, error) {
+	if key == nil {
+		return data, nil
+	}
+	enc, err := utils.Encrypt(data, key)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, encryptedPrefix...), enc...), nil
+}
+
+func decryptValue(v, key []byte) ([]byte, error) {
+	if !isEncrypted(v) {
+		return v, nil
+	}
+	if key == nil {
+		return nil, ErrDbEncrypted
+	}
+	//Decrypt decrypts in place, bolt's value must not be modified
+	enc := append([]byte{}, v[len(encryptedPrefix):]...)
+	return utils.Decrypt(enc, key)
+}
+
+//loadKey reads salt and check value of db, returns nil key if db is not encrypted.
+func loadKey(s Storage, password string) (key []byte, err error) {
+	salt, check, err := s.EncryptionKeyInfo()
+	if err != nil || salt == nil {
+		return
+	}
+	if len(password) == 0 {
+		return nil, ErrDbEncrypted
+	}
+	key, err = deriveDbKey(password, salt)
+	if err != nil {
+		return
+	}
+	check, err = decryptValue(check, key)
+	if err != nil {
+		return
+	}
+	if !bytes.Equal(check, checkPlainText) {
+		return nil, ErrWrongDbPassword
+	}
+	return
+}
+
+/*
+unlockEncryption sets key of codec when db is encrypted,
+and encrypts db when `encrypt` is true and it's not encrypted yet.
+*/
+func (model *ModelDB) unlockEncryption(password string, encrypt bool) error {
+	key, err := loadKey(model.storage, password)
+	if err != nil {
+		return err
+	}
+	if key != nil {
+		model.codec.setKey(key)
+		return nil
+	}
+	if !encrypt {
+		return nil
+	}
+	if len(password) == 0 {
+		return errors.New("db encryption needs password of account")
+	}
+	log.Info("encrypt db, it may take a while")
+	return model.ChangeEncryptionPassword(password)
+}
+
+//IsEncrypted returns true if values of db are encrypted
+func (model *ModelDB) IsEncrypted() bool {
+	return model.codec.getKey() != nil
+}
+
+/*
+ChangeEncryptionPassword re-encrypts every value of db by a new key derived from `password` and a new salt,
+the key is rotated even if password is not changed.
+if password is empty, db is decrypted.
+everything is done in one transaction, and ModelDB can be used as usual afterwards.
+*/
+func (model *ModelDB) ChangeEncryptionPassword(password string) error {
+	var newKey, salt, check []byte
+	var err error
+	if len(password) > 0 {
+		salt = make([]byte, 32)
+		if _, err = io.ReadFull(rand.Reader, salt); err != nil {
+			return err
+		}
+		newKey, err = deriveDbKey(password, salt)
+		if err != nil {
+			return err
+		}
+		check, err = encryptValue(checkPlainText, newKey)
+		if err != nil {
+			return err
+		}
+	}
+	model.lock.Lock()
+	defer model.lock.Unlock()
+	oldKey := model.codec.getKey()
+	err = model.storage.Reencrypt(oldKey, newKey, salt, check)
+	if err != nil {
+		return fmt.Errorf("change db encryption key err %s", err)
+	}
+	model.codec.setKey(newKey)
+	return nil
+}
+
+func reencryptValue(v, oldKey, newKey []byte) ([]byte, error) {
+	data, err := decryptValue(v, oldKey)
+	if err != nil {
+		return nil, err
+	}
+	return encryptValue(data, newKey)
+}
+
+/*
+reencryptBucket re-encrypts values directly in a storm bucket,
+nested buckets are indexes and metadata of storm, which are never encoded by codec.
+*/
+func reencryptBucket(b *bolt.Bucket, oldKey, newKey []byte) error {
+	type kv struct {
+		k, v []byte
+	}
+	var kvs []kv
+	err := b.ForEach(func(k, v []byte) error {
+		if v == nil || bytes.Equal(k, stormMetadata) {
+			return nil
+		}
+		data, err := reencryptValue(v, oldKey, newKey)
+		if err != nil {
+			return err
+		}
+		kvs = append(kvs, kv{append([]byte{}, k...), data})
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	//bucket cannot be modified in ForEach
+	for _, e := range kvs {
+		err = b.Put(e.k, e.v)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
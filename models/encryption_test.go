@@ -0,0 +1,158 @@
+package models
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/coreos/bbolt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestChannel(secret common.Hash) *channeltype.Serialization {
+	h := utils.NewRandomHash()
+	token := utils.NewRandomAddress()
+	partner := utils.NewRandomAddress()
+	return &channeltype.Serialization{
+		ChannelIdentifier: &contracts.ChannelUniqueID{
+			ChannelIdentifier: h,
+			OpenBlockNumber:   3,
+		},
+		Key:                 h[:],
+		TokenAddressBytes:   token[:],
+		PartnerAddressBytes: partner[:],
+		OurKnownSecrets:     []common.Hash{secret},
+	}
+}
+
+//plainValues returns how many values encoded by codec in file at `p` are not encrypted
+func plainValues(t *testing.T, p string) int {
+	db, err := bolt.Open(p, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	n := 0
+	err = db.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) == bucketEncryption || string(name) == stormDbInfo {
+				return nil
+			}
+			return b.ForEach(func(k, v []byte) error {
+				if v != nil && !bytes.Equal(k, stormMetadata) && !isEncrypted(v) {
+					n++
+				}
+				return nil
+			})
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func TestEncryptedDb(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	secret := utils.NewRandomHash()
+	model, err := OpenDbWithPassword(dbPath, "123", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, true, model.IsEncrypted())
+	c := newTestChannel(secret)
+	err = model.NewChannel(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.CloseDB()
+	assert.EqualValues(t, 0, plainValues(t, dbPath))
+
+	_, err = OpenDb(dbPath)
+	assert.EqualValues(t, ErrDbEncrypted, err)
+	_, err = OpenDbWithPassword(dbPath, "456", false)
+	assert.EqualValues(t, ErrWrongDbPassword, err)
+
+	model, err = OpenDbWithPassword(dbPath, "123", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, true, model.IsEncrypted())
+	cs, err := model.GetChannelList(c.TokenAddress(), utils.EmptyAddress)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 1, len(cs))
+	assert.EqualValues(t, secret, cs[0].OurKnownSecrets[0])
+	model.CloseDB()
+}
+
+func TestEncryptExistingDbAndRotate(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	secret := utils.NewRandomHash()
+	model, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := newTestChannel(secret)
+	err = model.NewChannel(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.SaveLatestBlockNumber(30)
+	model.CloseDB()
+	assert.NotEqual(t, 0, plainValues(t, dbPath))
+
+	//encrypt a plain db
+	model, err = OpenDbWithPassword(dbPath, "123", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.CloseDB()
+	assert.EqualValues(t, 0, plainValues(t, dbPath))
+
+	//rotate key
+	model, err = OpenDbWithPassword(dbPath, "123", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = model.ChangeEncryptionPassword("456")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := model.GetChannelByAddress(c.ChannelIdentifier.ChannelIdentifier)
+	assert.Nil(t, err)
+	assert.EqualValues(t, secret, ch.OurKnownSecrets[0])
+	model.CloseDB()
+	_, err = OpenDbWithPassword(dbPath, "123", false)
+	assert.EqualValues(t, ErrWrongDbPassword, err)
+	model, err = OpenDbWithPassword(dbPath, "456", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 30, model.GetLatestBlockNumber())
+
+	//decrypt
+	err = model.ChangeEncryptionPassword("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	model.CloseDB()
+	assert.NotEqual(t, 0, plainValues(t, dbPath))
+	model, err = OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, false, model.IsEncrypted())
+	cs, err := model.GetChannelList(utils.EmptyAddress, c.PartnerAddress())
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(cs))
+	model.CloseDB()
+}
@@ -0,0 +1,118 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+NodeEndpoint is a signed record of where a node can be reached by udp.
+Data is the original signed message,so the record can be verified again after restart.
+*/
+type NodeEndpoint struct {
+	Key        string `storm:"id"`
+	Address    common.Address
+	HostPort   string
+	DeviceType string
+	Expiration int64 `storm:"index"` //unix seconds
+	Data       []byte
+}
+
+//NewNodeEndpoint create a NodeEndpoint record
+func NewNodeEndpoint(addr common.Address, hostport, deviceType string, expiration int64, data []byte) *NodeEndpoint {
+	return &NodeEndpoint{
+		Key:        addr.String(),
+		Address:    addr,
+		HostPort:   hostport,
+		DeviceType: deviceType,
+		Expiration: expiration,
+		Data:       data,
+	}
+}
+
+/*
+verify checks that Data is signed by Address and says the same as the other fields,
+a record changed in db after it's saved, like a forged one with a far expiration, is not used.
+*/
+func (e *NodeEndpoint) verify() error {
+	msg := new(encoding.NodeEndpoint)
+	err := msg.UnPack(e.Data)
+	if err != nil {
+		return fmt.Errorf("endpoint of %s err %s", utils.APex2(e.Address), err)
+	}
+	if msg.Sender != e.Address || msg.HostPort != e.HostPort || msg.DeviceType != e.DeviceType || msg.Expiration != e.Expiration {
+		return fmt.Errorf("endpoint of %s doesn't match its signed data", utils.APex2(e.Address))
+	}
+	return nil
+}
+
+/*
+SaveNodeEndpoint save or replace endpoint of a node.
+an older record never replaces a newer one, returns false when `e` is ignored.
+*/
+func (model *ModelDB) SaveNodeEndpoint(e *NodeEndpoint) (saved bool, err error) {
+	old, err := model.GetNodeEndpoint(e.Address)
+	if err == nil && old.Expiration >= e.Expiration {
+		return false, nil
+	}
+	err = model.storage.SaveNodeEndpoint(e)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveNodeEndpoint err %s", err))
+		return
+	}
+	return true, nil
+}
+
+//GetNodeEndpoint returns the endpoint of `addr`, error if its signature is not valid
+func (model *ModelDB) GetNodeEndpoint(addr common.Address) (e *NodeEndpoint, err error) {
+	e, err = model.storage.GetNodeEndpoint(addr)
+	if err != nil {
+		return
+	}
+	err = e.verify()
+	if err != nil {
+		log.Warn(err.Error())
+		return nil, err
+	}
+	return
+}
+
+//GetAllNodeEndpoints returns all endpoints not expired at `now`, those whose signature is not valid are skipped
+func (model *ModelDB) GetAllNodeEndpoints(now int64) (es []*NodeEndpoint, err error) {
+	all, err := model.storage.GetAllNodeEndpoints()
+	for _, e := range all {
+		if e.Expiration <= now {
+			continue
+		}
+		if verr := e.verify(); verr != nil {
+			log.Warn(verr.Error())
+			continue
+		}
+		es = append(es, e)
+	}
+	return
+}
+
+//RemoveExpiredNodeEndpoints removes all endpoints expired at `now`
+func (model *ModelDB) RemoveExpiredNodeEndpoints(now int64) (n int, err error) {
+	all, err := model.storage.GetAllNodeEndpoints()
+	if err != nil {
+		return
+	}
+	for _, e := range all {
+		if e.Expiration > now {
+			continue
+		}
+		err = model.storage.DeleteNodeEndpoint(e)
+		if err != nil {
+			return
+		}
+		log.Trace(fmt.Sprintf("remove expired endpoint of %s", utils.APex2(e.Address)))
+		n++
+	}
+	return
+}
@@ -0,0 +1,103 @@
+package models
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+//newSignedNodeEndpoint makes the record saved when endpoint signed by `key` is received
+func newSignedNodeEndpoint(t *testing.T, key *ecdsa.PrivateKey, hostport, deviceType string, expiration int64) *NodeEndpoint {
+	msg := encoding.NewNodeEndpoint(hostport, deviceType, expiration)
+	err := msg.Sign(signer.NewKeySigner(key), msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewNodeEndpoint(crypto.PubkeyToAddress(key.PublicKey), hostport, deviceType, expiration, msg.Pack())
+}
+
+func TestModelDB_NodeEndpoint(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	key, _ := crypto.GenerateKey()
+	addr := crypto.PubkeyToAddress(key.PublicKey)
+	_, err := model.GetNodeEndpoint(addr)
+	if err == nil {
+		t.Error("should not found")
+		return
+	}
+	saved, err := model.SaveNodeEndpoint(newSignedNodeEndpoint(t, key, "1.2.3.4:40001", "other", 100))
+	if err != nil || !saved {
+		t.Errorf("save err=%v,saved=%v", err, saved)
+		return
+	}
+	//an older record should be ignored
+	saved, err = model.SaveNodeEndpoint(newSignedNodeEndpoint(t, key, "1.2.3.5:40001", "other", 50))
+	if err != nil || saved {
+		t.Errorf("save err=%v,saved=%v", err, saved)
+		return
+	}
+	e, err := model.GetNodeEndpoint(addr)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, e.HostPort, "1.2.3.4:40001")
+	key2, _ := crypto.GenerateKey()
+	_, err = model.SaveNodeEndpoint(newSignedNodeEndpoint(t, key2, "1.2.3.6:40001", "mobile", 200))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	es, err := model.GetAllNodeEndpoints(150)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(es), 1)
+	n, err := model.RemoveExpiredNodeEndpoints(150)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, n, 1)
+	_, err = model.GetNodeEndpoint(addr)
+	assert.EqualValues(t, err != nil, true)
+}
+
+func TestModelDB_NodeEndpointForged(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	key, _ := crypto.GenerateKey()
+	e := newSignedNodeEndpoint(t, key, "1.2.3.4:40001", "other", 100)
+	//changed in db after saved, its signed data says otherwise
+	forged := *e
+	forged.HostPort = "6.6.6.6:40001"
+	forged.Expiration = 1000
+	assert.Nil(t, model.storage.SaveNodeEndpoint(&forged))
+	_, err := model.GetNodeEndpoint(e.Address)
+	assert.NotNil(t, err)
+	es, err := model.GetAllNodeEndpoints(0)
+	assert.Nil(t, err)
+	assert.Len(t, es, 0)
+	//signed by another one
+	other := *e
+	other.Address = utils.NewRandomAddress()
+	other.Key = other.Address.String()
+	assert.Nil(t, model.storage.SaveNodeEndpoint(&other))
+	_, err = model.GetNodeEndpoint(other.Address)
+	assert.NotNil(t, err)
+	//a valid record replaces the forged one, whatever its expiration says
+	saved, err := model.SaveNodeEndpoint(e)
+	assert.Nil(t, err)
+	assert.True(t, saved)
+	got, err := model.GetNodeEndpoint(e.Address)
+	if assert.Nil(t, err) {
+		assert.EqualValues(t, "1.2.3.4:40001", got.HostPort)
+	}
+}
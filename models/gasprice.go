@@ -0,0 +1,47 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//keyGasPriceConfig in bucketMeta is the gas price config set by user
+const keyGasPriceConfig = "gasPriceConfig"
+
+//bucketGasSpent is wei spent on gas of transactions of every token, keyed by token address
+const bucketGasSpent = "gasSpent"
+
+//GetGasPriceConfig returns gas price config saved, ErrNotFound if never saved
+func (model *ModelDB) GetGasPriceConfig() (c *params.GasPriceConfig, err error) {
+	c = new(params.GasPriceConfig)
+	err = model.storage.Get(bucketMeta, keyGasPriceConfig, c)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveGasPriceConfig save or replace gas price config
+func (model *ModelDB) SaveGasPriceConfig(c *params.GasPriceConfig) error {
+	return model.storage.Set(bucketMeta, keyGasPriceConfig, c)
+}
+
+//GetGasSpent returns wei spent on gas of transactions of `token`
+func (model *ModelDB) GetGasSpent(token common.Address) *big.Int {
+	spent := new(big.Int)
+	err := model.storage.Get(bucketGasSpent, token.String(), spent)
+	if err != nil && err != ErrNotFound {
+		log.Error(fmt.Sprintf("GetGasSpent %s err %s", token.String(), err))
+	}
+	return spent
+}
+
+//AddGasSpent adds `amount` wei to gas spent of `token`
+func (model *ModelDB) AddGasSpent(token common.Address, amount *big.Int) error {
+	spent := model.GetGasSpent(token)
+	return model.storage.Set(bucketGasSpent, token.String(), spent.Add(spent, amount))
+}
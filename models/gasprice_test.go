@@ -0,0 +1,37 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGasPrice(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		_, err := model.GetGasPriceConfig()
+		assert.Equal(t, ErrNotFound, err)
+		token := utils.NewRandomAddress()
+		c := params.DefaultGasPriceConfig()
+		c.Strategy = params.GasPriceDeadline
+		c.SpendingCaps = map[common.Address]*big.Int{token: big.NewInt(100)}
+		err = model.SaveGasPriceConfig(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		c2, err := model.GetGasPriceConfig()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.EqualValues(t, c, c2)
+
+		assert.EqualValues(t, 0, model.GetGasSpent(token).Int64())
+		assert.Nil(t, model.AddGasSpent(token, big.NewInt(30)))
+		assert.Nil(t, model.AddGasSpent(token, big.NewInt(40)))
+		assert.EqualValues(t, 70, model.GetGasSpent(token).Int64())
+		assert.EqualValues(t, 0, model.GetGasSpent(utils.NewRandomAddress()).Int64())
+	})
+}
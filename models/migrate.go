@@ -0,0 +1,237 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+migration upgrades a bolt db from version N to N+1.
+it runs in a bolt transaction, if it returns an error, nothing is changed.
+sqlite db is created at version 2, its schema changes are made by sqliteStorage.MigrateStep.
+*/
+type migration func(tx storm.Node) error
+
+/*
+migrations[i] upgrades db from version i+1 to i+2,
+so len(migrations) must always be dbVersion-1.
+never modify or remove a migration once released, append a new one and increase dbVersion instead.
+*/
+var migrations = []migration{
+	migrateV1ToV2,
+	migrateV2ToV3,
+	migrateV3ToV4,
+	migrateV4ToV5,
+	migrateV5ToV6,
+	migrateV6ToV7,
+}
+
+/*
+migrateV1ToV2 init buckets and indexes of settle jobs, unlock outcomes and api tokens,
+which are created by initDb for new db only.
+*/
+func migrateV1ToV2(tx storm.Node) error {
+	err := tx.Init(&SettleJob{})
+	if err != nil {
+		return err
+	}
+	err = tx.Init(&channeltype.UnlockOutcome{})
+	if err != nil {
+		return err
+	}
+	return tx.Init(&APIToken{})
+}
+
+/*
+migrateV2ToV3 creates index Time of transfers, which is new in version 3,
+and saves every transfer again to put it in the index.
+*/
+func migrateV2ToV3(tx storm.Node) error {
+	var sent []*SentTransfer
+	err := tx.Init(&SentTransfer{})
+	if err == nil {
+		err = tx.All(&sent)
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range sent {
+		err = tx.Save(t)
+		if err != nil {
+			return err
+		}
+	}
+	var received []*ReceivedTransfer
+	err = tx.Init(&ReceivedTransfer{})
+	if err == nil {
+		err = tx.All(&received)
+	}
+	if err != nil {
+		return err
+	}
+	for _, t := range received {
+		err = tx.Save(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateV3ToV4 creates bucket and index of contract events, which are new in version 4
+func migrateV3ToV4(tx storm.Node) error {
+	return tx.Init(&ContractEvent{})
+}
+
+//migrateV4ToV5 puts every transfer in indexes of token, partner and channel, which are new in version 5
+func migrateV4ToV5(tx storm.Node) error {
+	var sent []*SentTransfer
+	err := tx.All(&sent)
+	if err != nil {
+		return err
+	}
+	for _, t := range sent {
+		t.setIndexes()
+		err = tx.Save(t)
+		if err != nil {
+			return err
+		}
+	}
+	var received []*ReceivedTransfer
+	err = tx.All(&received)
+	if err != nil {
+		return err
+	}
+	for _, t := range received {
+		t.setIndexes()
+		err = tx.Save(t)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+migrateV5ToV6 namespaces tokens by registry, a token can be registered on more than one registry since version 6.
+token networks are saved with their registry and token, channels with their registry,
+and channels of non participants are keyed by token network instead of token.
+*/
+func migrateV5ToV6(tx storm.Node) error {
+	var registry common.Address
+	tokens := make(AddressMap)
+	tokenRegistries := make(AddressMap)
+	err := tx.Get(bucketMeta, "registry", &registry)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	err = tx.Get(bucketToken, keyToken, &tokens)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	err = tx.Get(bucketMeta, keyTokenRegistries, &tokenRegistries)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	registries, m := registriesOfV5Tokens(tokens, tokenRegistries, registry)
+	err = tx.Set(bucketMeta, keyRegistryTokens, m)
+	if err != nil {
+		return err
+	}
+	err = tx.Delete(bucketMeta, keyTokenRegistries)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	for token, tokenNetwork := range tokens {
+		var cm ChannelParticipantMap
+		err = tx.Get(bucketChannel, token[:], &cm)
+		if err == storm.ErrNotFound {
+			continue
+		}
+		if err == nil {
+			err = tx.Set(bucketChannel, tokenNetwork[:], cm)
+		}
+		if err == nil {
+			err = tx.Delete(bucketChannel, token[:])
+		}
+		if err != nil {
+			return err
+		}
+	}
+	var cs []*channeltype.Serialization
+	err = tx.All(&cs)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	for _, c := range cs {
+		c.RegistryAddress = registries[c.TokenAddress()]
+		err = tx.Save(c)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateV6ToV7 indexes channels by participant, which is new in version 7, from ChannelOpened events
+func migrateV6ToV7(tx storm.Node) error {
+	err := tx.Init(&ParticipantChannel{})
+	if err != nil {
+		return err
+	}
+	var events []*ContractEvent
+	err = tx.All(&events)
+	if err != nil && err != storm.ErrNotFound {
+		return err
+	}
+	for _, e := range events {
+		for _, pc := range e.participantChannels() {
+			err = tx.Save(pc)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//backupPath returns where to put a copy of db before migrating from version `ver`
+func backupPath(dbPath string, ver int) string {
+	return fmt.Sprintf("%s.v%d.%s.bak", dbPath, ver, time.Now().Format("20060102150405"))
+}
+
+/*
+migrate upgrades db from version `ver` to dbVersion step by step.
+a backup of db is taken before the first step,
+and every step commits together with the new version number, so an interrupted migration resumes from the last finished step.
+*/
+func (model *ModelDB) migrate(ver int) (err error) {
+	if ver > dbVersion {
+		return fmt.Errorf("db version %d is newer than supported version %d, please upgrade smartraiden", ver, dbVersion)
+	}
+	if ver < 1 {
+		return fmt.Errorf("unknown db version %d", ver)
+	}
+	if ver == dbVersion {
+		return nil
+	}
+	bak := backupPath(model.Name, ver)
+	err = model.Backup(bak)
+	if err != nil {
+		return fmt.Errorf("backup db to %s before migration err %s", bak, err)
+	}
+	log.Info(fmt.Sprintf("db backup to %s before migrating from version %d to %d", bak, ver, dbVersion))
+	for ; ver < dbVersion; ver++ {
+		err = model.storage.MigrateStep(ver)
+		if err != nil {
+			return fmt.Errorf("migrate db from version %d to %d err %s, backup is at %s", ver, ver+1, err, bak)
+		}
+		log.Info(fmt.Sprintf("db migrated from version %d to %d", ver, ver+1))
+	}
+	return nil
+}
@@ -0,0 +1,344 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/asdine/storm"
+	gobcodec "github.com/asdine/storm/codec/gob"
+	"github.com/coreos/bbolt"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+/*
+makeDbOfVersion1 creates a db the same as OpenDb of version 1 does, with a token and a sent transfer in it.
+*/
+func makeDbOfVersion1(t *testing.T, dbPath string, registry, token, tokenNetwork common.Address) {
+	db, err := storm.Open(dbPath, storm.BoltOptions(os.ModePerm, &bolt.Options{Timeout: 1 * time.Second}), storm.Codec(gobcodec.Codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	err = db.Set(bucketMeta, "version", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Set(bucketMeta, "registry", registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Set(bucketToken, keyToken, AddressMap{token: tokenNetwork})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Init(&SentTransfer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Init(&ReceivedTransfer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Save(&SentTransfer{Key: "sent", BlockNumber: 3, TokenAddress: token, Amount: big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Set(bucketBlockNumber, keyBlockNumber, 33)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Set(bucketMeta, "close", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func tempDbPath(t *testing.T) string {
+	dir := path.Join(os.TempDir(), "migratetest-"+utils.RandomString(8))
+	err := os.MkdirAll(dir, os.ModePerm)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path.Join(dir, "log.db")
+}
+
+func getVersion(t *testing.T, model *ModelDB) int {
+	var ver int
+	err := model.storage.Get(bucketMeta, "version", &ver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ver
+}
+
+func TestMigrateFromVersion1(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	registry, token, tokenNetwork := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	makeDbOfVersion1(t, dbPath, registry, token, tokenNetwork)
+	model, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, dbVersion, getVersion(t, model))
+	tokens, err := model.GetAllTokens()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Contains(t, tokens, token)
+	registryTokens, err := model.GetRegistryTokens()
+	assert.Nil(t, err)
+	assert.EqualValues(t, RegistryTokenMap{tokenNetwork: {Registry: registry, Token: token}}, registryTokens)
+	assert.EqualValues(t, 33, model.GetLatestBlockNumber())
+	assert.EqualValues(t, false, model.IsDbCrashedLastTime())
+	sts, _, err := model.FindSentTransfers(&TransferFilter{Token: token})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(sts))
+	err = model.SaveSettleJob(NewSettleJob(utils.NewRandomHash(), token, utils.NewRandomAddress(), 100))
+	assert.Nil(t, err)
+	assert.Nil(t, model.SaveContractEvent(newTestContractEvent(params.NameTokenNetworkCreated, 3, 0, utils.NewRandomAddress(), utils.EmptyHash)))
+	events, err := model.FindContractEvents(&ContractEventFilter{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(events))
+	model.CloseDB()
+
+	baks, err := filepath.Glob(dbPath + ".v1.*.bak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, 1, len(baks))
+	//backup is still a db of version 1
+	bak, err := storm.Open(baks[0], storm.Codec(gobcodec.Codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ver int
+	err = bak.Get(bucketMeta, "version", &ver)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, ver)
+	bak.Close()
+
+	//open again, no more migration
+	model, err = OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.EqualValues(t, dbVersion, getVersion(t, model))
+	jobs, err := model.GetAllSettleJobs()
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(jobs))
+	model.CloseDB()
+	baks, _ = filepath.Glob(dbPath + ".v*.bak")
+	assert.EqualValues(t, 1, len(baks))
+}
+
+func TestMigrateFailRollback(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	makeDbOfVersion1(t, dbPath, utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress())
+	old := migrations
+	defer func() {
+		migrations = old
+	}()
+	migrations = []migration{
+		func(tx storm.Node) error {
+			err := tx.Set(bucketBlockNumber, keyBlockNumber, 44)
+			if err != nil {
+				return err
+			}
+			return errors.New("fail")
+		},
+	}
+	_, err := OpenDb(dbPath)
+	if err == nil {
+		t.Fatal("migration should fail")
+	}
+	db, err := storm.Open(dbPath, storm.Codec(gobcodec.Codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var ver, number int
+	err = db.Get(bucketMeta, "version", &ver)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, ver)
+	err = db.Get(bucketBlockNumber, keyBlockNumber, &number)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 33, number)
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	dbPath := tempDbPath(t)
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	makeDbOfVersion1(t, dbPath, utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress())
+	db, err := storm.Open(dbPath, storm.Codec(gobcodec.Codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Set(bucketMeta, "version", dbVersion+1)
+	db.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = OpenDb(dbPath)
+	if err == nil {
+		t.Fatal("should not open db of newer version")
+	}
+}
+
+func TestMigrationsMatchVersion(t *testing.T) {
+	assert.EqualValues(t, dbVersion-1, len(migrations))
+	assert.EqualValues(t, dbVersion-2, len(sqliteMigrations))
+}
+
+//TestMigrateSQLiteFromVersion2 makes a sqlite db of version 2 by sqliteSchema, which is the schema of version 2
+func TestMigrateSQLiteFromVersion2(t *testing.T) {
+	dbPath := filepath.Join(filepath.Dir(tempDbPath(t)), "log.sqlite")
+	defer os.RemoveAll(filepath.Dir(dbPath))
+	s, err := openSQLiteStorage(dbPath, &cryptCodec{MarshalUnmarshaler: gobcodec.Codec})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := utils.NewRandomAddress()
+	assert.Nil(t, s.Set(bucketMeta, "version", 2))
+	assert.Nil(t, s.Set(bucketMeta, "close", true))
+	data, err := s.codec.Marshal(&ReceivedTransfer{Key: "received", BlockNumber: 3, TokenAddress: token, Amount: big.NewInt(10)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.db.Exec("INSERT INTO received_transfers (id, block_number, value) VALUES (?, ?, ?)", "received", 3, data)
+	assert.Nil(t, err)
+	//channels of non participants were saved by token before version 6
+	tokenNetwork, p1, p2 := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	assert.Nil(t, s.SaveToken(token, tokenNetwork))
+	_, err = s.db.Exec("INSERT INTO nonparticipant_channels (token, channel_identifier, participants) VALUES (?, ?, ?)",
+		token[:], utils.NewRandomHash().Bytes(), append(p1[:], p2[:]...))
+	assert.Nil(t, err)
+	s.Close()
+
+	model, err := OpenDb(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer model.CloseDB()
+	assert.EqualValues(t, dbVersion, getVersion(t, model))
+	rts, _, err := model.FindReceivedTransfers(&TransferFilter{Token: token})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(rts))
+	model.NewReceivedTransfer(4, utils.NewRandomHash(), token, utils.NewRandomAddress(), 1, big.NewInt(1))
+	rts, _, err = model.FindReceivedTransfers(&TransferFilter{Token: token, FromTime: 1})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(rts))
+	assert.Nil(t, model.SaveContractEvent(newTestContractEvent(params.NameTokenNetworkCreated, 3, 0, utils.NewRandomAddress(), utils.EmptyHash)))
+	events, err := model.FindContractEvents(&ContractEventFilter{})
+	assert.Nil(t, err)
+	assert.EqualValues(t, 1, len(events))
+	edges, err := model.GetAllNonParticipantChannel(tokenNetwork)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []common.Address{p1, p2}, edges)
+}
+
+/*
+TestMigrateRealDbs opens dbs in testdata, which were made by OpenDb of their versions with the same data,
+a token, a channel, a sent and a received transfer, a settle job and a channel of non participants.
+*/
+func TestMigrateRealDbs(t *testing.T) {
+	registry := common.HexToAddress("0x0000000000000000000000000000000000000001")
+	token := common.HexToAddress("0x00000000000000000000000000000000000000a1")
+	tokenNetwork := common.HexToAddress("0x00000000000000000000000000000000000000b1")
+	partner := common.HexToAddress("0x00000000000000000000000000000000000000d1")
+	p1 := common.HexToAddress("0x00000000000000000000000000000000000000f1")
+	p2 := common.HexToAddress("0x00000000000000000000000000000000000000f2")
+	channel := common.HexToHash("0x00000000000000000000000000000000000000000000000000000000000000c1")
+	for _, name := range []string{"v1.db", "v2.db", "v2.sqlite", "v3.db", "v3.sqlite"} {
+		dbPath := filepath.Join(filepath.Dir(tempDbPath(t)), name)
+		err := copyFile(filepath.Join("testdata", name), dbPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		model, err := OpenDb(dbPath)
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		assert.EqualValues(t, dbVersion, getVersion(t, model), name)
+		assert.EqualValues(t, false, model.IsDbCrashedLastTime(), name)
+		assert.EqualValues(t, 33, model.GetLatestBlockNumber(), name)
+		registryTokens, err := model.GetRegistryTokens()
+		assert.Nil(t, err, name)
+		assert.EqualValues(t, RegistryTokenMap{tokenNetwork: {Registry: registry, Token: token}}, registryTokens, name)
+		c, err := model.GetChannelByAddress(channel)
+		if assert.Nil(t, err, name) {
+			assert.EqualValues(t, registry, c.RegistryAddress, name)
+			assert.EqualValues(t, partner, c.PartnerAddress(), name)
+			assert.EqualValues(t, 100, c.OurContractBalance.Int64(), name)
+		}
+		//transfers are in indexes of version 5
+		sts, _, err := model.FindSentTransfers(&TransferFilter{Partner: partner, ChannelIdentifier: channel})
+		assert.Nil(t, err, name)
+		assert.EqualValues(t, 1, len(sts), name)
+		rts, _, err := model.FindReceivedTransfers(&TransferFilter{Token: token})
+		assert.Nil(t, err, name)
+		assert.EqualValues(t, 1, len(rts), name)
+		jobs, err := model.GetAllSettleJobs()
+		assert.Nil(t, err, name)
+		assert.EqualValues(t, 1, len(jobs), name)
+		edges, err := model.GetAllNonParticipantChannel(tokenNetwork)
+		assert.Nil(t, err, name)
+		assert.EqualValues(t, []common.Address{p1, p2}, edges, name)
+		assert.Nil(t, model.SaveContractEvent(newTestContractEvent(params.NameChannelOpened, 40, 0, tokenNetwork, channel)), name)
+		model.CloseDB()
+		//backed up before migrating from its own version
+		baks, _ := filepath.Glob(fmt.Sprintf("%s.v%c.*.bak", dbPath, name[1]))
+		assert.EqualValues(t, 1, len(baks), name)
+		os.RemoveAll(filepath.Dir(dbPath))
+	}
+}
+
+//TestMigrateParticipantChannels makes dbs of version 6, whose ChannelOpened events are saved without index of participants
+func TestMigrateParticipantChannels(t *testing.T) {
+	for backend, name := range storageTestFiles {
+		t.Run(backend, func(t *testing.T) {
+			dbPath := filepath.Join(filepath.Dir(tempDbPath(t)), name)
+			defer os.RemoveAll(filepath.Dir(dbPath))
+			model, err := OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokenNetwork, channel := utils.NewRandomAddress(), utils.NewRandomHash()
+			p1, p2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+			assert.Nil(t, model.SaveContractEvent(newTestContractEvent(params.NameChannelOpened, 5, 0, tokenNetwork, channel, p1, p2)))
+			assert.Nil(t, model.SaveContractEvent(newTestContractEvent(params.NameChannelSettled, 9, 0, tokenNetwork, channel)))
+			switch s := model.storage.(type) {
+			case *boltStorage:
+				assert.Nil(t, s.db.Drop(&ParticipantChannel{}))
+			case *sqliteStorage:
+				_, err = s.db.Exec("DROP TABLE participant_channels")
+				assert.Nil(t, err)
+			}
+			assert.Nil(t, model.storage.Set(bucketMeta, "version", 6))
+			model.CloseDB()
+
+			model, err = OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.CloseDB()
+			assert.EqualValues(t, dbVersion, getVersion(t, model))
+			events, err := model.FindContractEvents(&ContractEventFilter{Participant: p2})
+			assert.Nil(t, err)
+			assert.Len(t, events, 2)
+			events, err = model.FindContractEvents(&ContractEventFilter{Participant: p1, Contract: utils.NewRandomAddress()})
+			assert.Nil(t, err)
+			assert.Len(t, events, 0)
+		})
+	}
+}
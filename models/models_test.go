@@ -14,6 +14,7 @@ import (
 	"encoding/gob"
 
 	"encoding/hex"
+	"time"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
@@ -37,7 +38,7 @@ func setupDb(t *testing.T) (model *ModelDB) {
 		t.Error(err)
 		return
 	}
-	t.Log(model.db)
+	t.Log(model.storage)
 	return
 }
 
@@ -193,3 +194,32 @@ func TestModelDB_IsThisLockRemoved(t *testing.T) {
 		return
 	}
 }
+
+func TestModelDB_CheckWritable(t *testing.T) {
+	model := setupDb(t)
+	err := model.CheckWritable()
+	if err != nil {
+		t.Error(err)
+	}
+	var written time.Time
+	err = model.storage.Get(bucketMeta, "healthcheck", &written)
+	if err != nil {
+		t.Error(err)
+	}
+	//checked again soon, only read
+	err = model.CheckWritable()
+	if err != nil {
+		t.Error(err)
+	}
+	var written2 time.Time
+	err = model.storage.Get(bucketMeta, "healthcheck", &written2)
+	if err != nil {
+		t.Error(err)
+	}
+	assert.True(t, written.Equal(written2), "written again")
+	model.CloseDB()
+	err = model.CheckWritable()
+	if err == nil {
+		t.Error("closed db should not be writable")
+	}
+}
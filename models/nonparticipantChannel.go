@@ -9,7 +9,6 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -53,16 +52,15 @@ type ChannelParticipantMap map[common.Hash][]byte
 const bucketChannel = "bucketChannel"
 
 //NewNonParticipantChannel 需要保存 channel identifier, 通道的事件都是与此有关系的
-func (model *ModelDB) NewNonParticipantChannel(token common.Address, channel common.Hash, participant1, participant2 common.Address) error {
-	var m ChannelParticipantMap
-	log.Trace(fmt.Sprintf("NewNonParticipantChannel token=%s,participant1=%s,participant2=%s",
-		utils.APex2(token),
+func (model *ModelDB) NewNonParticipantChannel(tokenNetwork common.Address, channel common.Hash, participant1, participant2 common.Address) error {
+	log.Trace(fmt.Sprintf("NewNonParticipantChannel tokenNetwork=%s,participant1=%s,participant2=%s",
+		utils.APex2(tokenNetwork),
 		utils.APex2(participant1),
 		utils.APex2(participant2),
 	))
-	err := model.db.Get(bucketChannel, token[:], &m)
+	m, err := model.storage.GetNonParticipantChannels(tokenNetwork)
 	if err != nil {
-		if err == storm.ErrNotFound {
+		if err == ErrNotFound {
 			m = make(ChannelParticipantMap)
 		} else {
 			return err
@@ -70,7 +68,7 @@ func (model *ModelDB) NewNonParticipantChannel(token common.Address, channel com
 
 	}
 	if participant1 == participant2 {
-		panic(fmt.Sprintf("channel error, p1 andf p2 is the same,token=%s,participant=%s", token.String(), participant1.String()))
+		panic(fmt.Sprintf("channel error, p1 andf p2 is the same,tokenNetwork=%s,participant=%s", tokenNetwork.String(), participant1.String()))
 	}
 	if bytes.Compare(participant1[:], participant2[:]) > 0 {
 		participant1, participant2 = participant2, participant1
@@ -82,19 +80,16 @@ func (model *ModelDB) NewNonParticipantChannel(token common.Address, channel com
 			utils.APex2(participant1), utils.APex2(participant2)))
 		return nil
 	}
-	m[key] = participant2bytes(participant1, participant2)
-	log.Trace(fmt.Sprintf("NewNonParticipantChannel token=%s,p1=%s,p2=%s,len(m)=%d", utils.APex2(token),
-		utils.APex2(participant1), utils.APex2(participant2), len(m)))
-	err = model.db.Set(bucketChannel, token[:], m)
-	return err
+	log.Trace(fmt.Sprintf("NewNonParticipantChannel tokenNetwork=%s,p1=%s,p2=%s,len(m)=%d", utils.APex2(tokenNetwork),
+		utils.APex2(participant1), utils.APex2(participant2), len(m)+1))
+	return model.storage.AddNonParticipantChannel(tokenNetwork, key, participant2bytes(participant1, participant2))
 }
 
 //RemoveNonParticipantChannel a channel is settled
-func (model *ModelDB) RemoveNonParticipantChannel(token common.Address, channel common.Hash) error {
-	var m ChannelParticipantMap
-	err := model.db.Get(bucketChannel, token[:], &m)
+func (model *ModelDB) RemoveNonParticipantChannel(tokenNetwork common.Address, channel common.Hash) error {
+	m, err := model.storage.GetNonParticipantChannels(tokenNetwork)
 	if err != nil {
-		if err == storm.ErrNotFound {
+		if err == ErrNotFound {
 			return nil
 		}
 		return err
@@ -103,19 +98,16 @@ func (model *ModelDB) RemoveNonParticipantChannel(token common.Address, channel
 		//startup ...
 		return fmt.Errorf("delete channel ,but channel don't exists")
 	}
-	delete(m, channel)
-	log.Trace(fmt.Sprintf("RemoveNonParticipantChannel token=%s,channel=%s", utils.APex2(token),
+	log.Trace(fmt.Sprintf("RemoveNonParticipantChannel tokenNetwork=%s,channel=%s", utils.APex2(tokenNetwork),
 		utils.HPex(channel)))
-	err = model.db.Set(bucketChannel, token[:], m)
-	return err
+	return model.storage.RemoveNonParticipantChannel(tokenNetwork, channel)
 }
 
-//GetAllNonParticipantChannel returna all channel on this `token`
-func (model *ModelDB) GetAllNonParticipantChannel(token common.Address) (edges []common.Address, err error) {
-	var m ChannelParticipantMap
-	err = model.db.Get(bucketChannel, token[:], &m)
-	log.Trace(fmt.Sprintf("GetAllNonParticipantChannel,token=%s,err=%v", utils.APex2(token), err))
-	if err == storm.ErrNotFound {
+//GetAllNonParticipantChannel returna all channel on this `tokenNetwork`
+func (model *ModelDB) GetAllNonParticipantChannel(tokenNetwork common.Address) (edges []common.Address, err error) {
+	m, err := model.storage.GetNonParticipantChannels(tokenNetwork)
+	log.Trace(fmt.Sprintf("GetAllNonParticipantChannel,tokenNetwork=%s,err=%v", utils.APex2(tokenNetwork), err))
+	if err == ErrNotFound {
 		err = nil
 		return
 	}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//bucketPathfinderIOU is amount of the latest IOU signed to a pathfinding service, keyed by receiver and token
+const bucketPathfinderIOU = "pathfinderIOU"
+
+func iouKey(receiver, token common.Address) string {
+	return receiver.String() + token.String()
+}
+
+//GetIOUAmount returns amount of the latest IOU of `token` signed to `receiver`, 0 if never signed
+func (model *ModelDB) GetIOUAmount(receiver, token common.Address) *big.Int {
+	amount := new(big.Int)
+	err := model.storage.Get(bucketPathfinderIOU, iouKey(receiver, token), amount)
+	if err != nil && err != ErrNotFound {
+		log.Error(fmt.Sprintf("GetIOUAmount %s %s err %s", receiver.String(), token.String(), err))
+	}
+	return amount
+}
+
+//SaveIOUAmount saves amount of the IOU just signed, which is cumulative
+func (model *ModelDB) SaveIOUAmount(receiver, token common.Address, amount *big.Int) error {
+	return model.storage.Set(bucketPathfinderIOU, iouKey(receiver, token), amount)
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIOUAmount(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		receiver, token := utils.NewRandomAddress(), utils.NewRandomAddress()
+		assert.EqualValues(t, 0, model.GetIOUAmount(receiver, token).Int64())
+		assert.Nil(t, model.SaveIOUAmount(receiver, token, big.NewInt(30)))
+		assert.EqualValues(t, 30, model.GetIOUAmount(receiver, token).Int64())
+		assert.EqualValues(t, 0, model.GetIOUAmount(token, receiver).Int64())
+	})
+}
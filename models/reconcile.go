@@ -0,0 +1,64 @@
+package models
+
+import (
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//keyReconcileReport in bucketMeta is the report of the last reconciliation of channels against blockchain
+const keyReconcileReport = "reconcileReport"
+
+//actions taken on a discrepancy
+const (
+	//ReconcileRepaired channel in db is updated to what's on chain
+	ReconcileRepaired = "repaired"
+	//ReconcileConflict channel can't be repaired safely, transfers on it are blocked
+	ReconcileConflict = "conflict"
+)
+
+//ChannelDiscrepancy is one difference found between a channel in db and the same channel on chain
+type ChannelDiscrepancy struct {
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	TokenAddress      common.Address `json:"token_address"`
+	PartnerAddress    common.Address `json:"partner_address"`
+	Field             string         `json:"field"`
+	Local             string         `json:"local"`
+	Chain             string         `json:"chain"`
+	Action            string         `json:"action"`
+}
+
+//ReconcileReport is the result of a reconciliation pass
+type ReconcileReport struct {
+	BlockNumber   int64                 `json:"block_number"`
+	Time          time.Time             `json:"time"`
+	Checked       int                   `json:"checked"`
+	Errors        []string              `json:"errors"` //channels not checked because of rpc errors
+	Discrepancies []*ChannelDiscrepancy `json:"discrepancies"`
+}
+
+//Conflicts returns channels which have discrepancies not repaired
+func (r *ReconcileReport) Conflicts() map[common.Hash]bool {
+	m := make(map[common.Hash]bool)
+	for _, d := range r.Discrepancies {
+		if d.Action == ReconcileConflict {
+			m[d.ChannelIdentifier] = true
+		}
+	}
+	return m
+}
+
+//GetReconcileReport returns report of the last reconciliation, ErrNotFound if never reconciled
+func (model *ModelDB) GetReconcileReport() (r *ReconcileReport, err error) {
+	r = new(ReconcileReport)
+	err = model.storage.Get(bucketMeta, keyReconcileReport, r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReconcileReport replaces report of the last reconciliation
+func (model *ModelDB) SaveReconcileReport(r *ReconcileReport) error {
+	return model.storage.Set(bucketMeta, keyReconcileReport, r)
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReconcileReport(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		_, err := model.GetReconcileReport()
+		assert.Equal(t, ErrNotFound, err)
+		id1, id2 := utils.NewRandomHash(), utils.NewRandomHash()
+		r := &ReconcileReport{
+			BlockNumber: 30,
+			Time:        time.Unix(1500000000, 0),
+			Checked:     2,
+			Discrepancies: []*ChannelDiscrepancy{
+				{ChannelIdentifier: id1, Field: "our_deposit", Local: "10", Chain: "20", Action: ReconcileRepaired},
+				{ChannelIdentifier: id2, Field: "partner_nonce", Local: "3", Chain: "5", Action: ReconcileConflict},
+			},
+		}
+		err = model.SaveReconcileReport(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r2, err := model.GetReconcileReport()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.EqualValues(t, r.BlockNumber, r2.BlockNumber)
+		assert.True(t, r.Time.Equal(r2.Time))
+		assert.EqualValues(t, r.Discrepancies, r2.Discrepancies)
+		assert.EqualValues(t, map[common.Hash]bool{id2: true}, r2.Conflicts())
+	})
+}
@@ -40,7 +40,7 @@ func init() {
 //MarkLockSecretHashDisposed mark `locksecrethash` disposed on channel `ChannelIdentifier`
 func (model *ModelDB) MarkLockSecretHashDisposed(lockSecretHash common.Hash, ChannelIdentifier common.Hash) error {
 	key := utils.Sha3(lockSecretHash[:], ChannelIdentifier[:])
-	err := model.db.Save(&SentAnnounceDisposed{
+	err := model.storage.SaveSentAnnounceDisposed(&SentAnnounceDisposed{
 		Key:               key[:],
 		LockSecretHash:    lockSecretHash[:],
 		ChannelIdentifier: ChannelIdentifier,
@@ -50,8 +50,7 @@ func (model *ModelDB) MarkLockSecretHashDisposed(lockSecretHash common.Hash, Cha
 
 //IsLockSecretHashDisposed this lockSecretHash has Announced Disposed
 func (model *ModelDB) IsLockSecretHashDisposed(lockSecretHash common.Hash) bool {
-	sad := new(SentAnnounceDisposed)
-	err := model.db.One("LockSecretHash", lockSecretHash[:], sad)
+	sad, err := model.storage.FindSentAnnounceDisposed(lockSecretHash)
 	if err != nil {
 		return false
 	}
@@ -61,9 +60,8 @@ func (model *ModelDB) IsLockSecretHashDisposed(lockSecretHash common.Hash) bool
 
 //IsLockSecretHashChannelIdentifierDisposed `lockSecretHash` and `ChannelIdentifier` is the id of AnnounceDisposed
 func (model *ModelDB) IsLockSecretHashChannelIdentifierDisposed(lockSecretHash common.Hash, ChannelIdentifier common.Hash) bool {
-	sad := new(SentAnnounceDisposed)
 	key := utils.Sha3(lockSecretHash[:], ChannelIdentifier[:])
-	err := model.db.One("Key", key[:], sad)
+	sad, err := model.storage.GetSentAnnounceDisposed(key[:])
 	if err != nil {
 		return false
 	}
@@ -86,14 +84,13 @@ func NewReceivedAnnounceDisposed(LockHash, ChannelIdentifier, additionalHash com
 
 //MarkLockHashCanPunish 收到了一个放弃声明,需要保存,在收到 unlock 事件的时候进行 punish
 func (model *ModelDB) MarkLockHashCanPunish(r *ReceivedAnnounceDisposed) error {
-	return model.db.Save(r)
+	return model.storage.SaveReceivedAnnounceDisposed(r)
 }
 
 //IsLockHashCanPunish can punish this unlock?
 func (model *ModelDB) IsLockHashCanPunish(lockHash, channelIdentifier common.Hash) bool {
-	sad := new(ReceivedAnnounceDisposed)
 	key := utils.Sha3(lockHash[:], channelIdentifier[:])
-	err := model.db.One("Key", key[:], sad)
+	_, err := model.storage.GetReceivedAnnounceDisposed(key[:])
 	if err != nil {
 		return false
 	}
@@ -103,9 +100,8 @@ func (model *ModelDB) IsLockHashCanPunish(lockHash, channelIdentifier common.Has
 
 //GetReceiviedAnnounceDisposed return a ReceivedAnnounceDisposed ,if not  exist,return nil
 func (model *ModelDB) GetReceiviedAnnounceDisposed(lockHash, channelIdentifier common.Hash) *ReceivedAnnounceDisposed {
-	sad := new(ReceivedAnnounceDisposed)
 	key := utils.Sha3(lockHash[:], channelIdentifier[:])
-	err := model.db.One("Key", key[:], sad)
+	sad, err := model.storage.GetReceivedAnnounceDisposed(key[:])
 	if err != nil {
 		return nil
 	}
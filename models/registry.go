@@ -0,0 +1,114 @@
+package models
+
+import (
+	"encoding/gob"
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+keyTokenRegistries in bucketMeta was token => registry the token is registered on,
+before version 6, when a token could be used on only one registry, it's replaced by keyRegistryTokens.
+*/
+const keyTokenRegistries = "tokenRegistries"
+
+//keyRegistryTokens in bucketMeta is token network => registry and token of it
+const keyRegistryTokens = "registryTokens"
+
+//keySecretRegistries in bucketMeta is registry => secret registry used by its token networks
+const keySecretRegistries = "secretRegistries"
+
+func (model *ModelDB) getAddressMap(key string) (m AddressMap, err error) {
+	m = make(AddressMap)
+	err = model.storage.Get(bucketMeta, key, &m)
+	if err == ErrNotFound {
+		return make(AddressMap), nil
+	}
+	return
+}
+
+/*
+RegistryToken is a token on one registry,
+a token registered on more than one registry has a token network, graph and channels on each of them.
+*/
+type RegistryToken struct {
+	Registry common.Address
+	Token    common.Address
+}
+
+//String is fmt.Stringer
+func (rt RegistryToken) String() string {
+	return fmt.Sprintf("{registry=%s,token=%s}", utils.APex2(rt.Registry), utils.APex2(rt.Token))
+}
+
+//RegistryTokenMap is token network => registry and token of it
+type RegistryTokenMap map[common.Address]RegistryToken
+
+//GetRegistryTokens returns token networks of all registries
+func (model *ModelDB) GetRegistryTokens() (m RegistryTokenMap, err error) {
+	m = make(RegistryTokenMap)
+	err = model.storage.Get(bucketMeta, keyRegistryTokens, &m)
+	if err == ErrNotFound {
+		return make(RegistryTokenMap), nil
+	}
+	return
+}
+
+/*
+AddRegistryToken add token network of `token` on `registry` to db,
+the first token network of a token is saved by AddToken too, for those only know tokens.
+*/
+func (model *ModelDB) AddRegistryToken(registry, token, tokenNetworkAddress common.Address) error {
+	m, err := model.GetRegistryTokens()
+	if err != nil {
+		return err
+	}
+	if _, ok := m[tokenNetworkAddress]; !ok {
+		m[tokenNetworkAddress] = RegistryToken{registry, token}
+		err = model.storage.Set(bucketMeta, keyRegistryTokens, m)
+		if err != nil {
+			return err
+		}
+	}
+	return model.AddToken(token, tokenNetworkAddress)
+}
+
+/*
+registriesOfV5Tokens returns registry and token of every token network saved before version 6, and registry of every token.
+a token had only one token network then, `tokenRegistries` of keyTokenRegistries doesn't include tokens saved before there were more than one registry,
+they are of `registry` in meta.
+*/
+func registriesOfV5Tokens(tokens, tokenRegistries AddressMap, registry common.Address) (registries AddressMap, m RegistryTokenMap) {
+	registries = make(AddressMap)
+	m = make(RegistryTokenMap)
+	for token, tokenNetwork := range tokens {
+		r, ok := tokenRegistries[token]
+		if !ok {
+			r = registry
+		}
+		registries[token] = r
+		m[tokenNetwork] = RegistryToken{r, token}
+	}
+	return
+}
+
+//GetSecretRegistries returns secret registry of every registry ever attached
+func (model *ModelDB) GetSecretRegistries() (AddressMap, error) {
+	return model.getAddressMap(keySecretRegistries)
+}
+
+//SaveSecretRegistryOf save secret registry of `registry`, it's needed when starting without ethereum connection
+func (model *ModelDB) SaveSecretRegistryOf(registry, secretRegistry common.Address) error {
+	m, err := model.GetSecretRegistries()
+	if err != nil {
+		return err
+	}
+	m[registry] = secretRegistry
+	return model.storage.Set(bucketMeta, keySecretRegistries, m)
+}
+
+func init() {
+	gob.Register(make(RegistryTokenMap))
+}
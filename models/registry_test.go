@@ -0,0 +1,40 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryTokens(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		rts, err := model.GetRegistryTokens()
+		assert.Nil(t, err)
+		assert.Len(t, rts, 0)
+		r1, r2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+		t1, t2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+		n1, n2, n3 := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+		assert.Nil(t, model.AddRegistryToken(r1, t1, n1))
+		assert.Nil(t, model.AddRegistryToken(r2, t2, n2))
+		assert.Nil(t, model.AddRegistryToken(r2, t1, n3), "t1 can be on r2 too")
+		assert.Nil(t, model.AddRegistryToken(r1, t1, n1))
+		rts, err = model.GetRegistryTokens()
+		assert.Nil(t, err)
+		assert.EqualValues(t, RegistryTokenMap{
+			n1: {Registry: r1, Token: t1},
+			n2: {Registry: r2, Token: t2},
+			n3: {Registry: r2, Token: t1},
+		}, rts)
+		tokens, err := model.GetAllTokens()
+		assert.Nil(t, err)
+		assert.EqualValues(t, AddressMap{t1: n1, t2: n2}, tokens)
+
+		s1 := utils.NewRandomAddress()
+		assert.Nil(t, model.SaveSecretRegistryOf(r1, s1))
+		assert.Nil(t, model.SaveSecretRegistryOf(r2, s1))
+		m, err := model.GetSecretRegistries()
+		assert.Nil(t, err)
+		assert.EqualValues(t, AddressMap{r1: s1, r2: s1}, m)
+	})
+}
@@ -0,0 +1,143 @@
+package models
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+)
+
+/*
+archived records are moved to these buckets of the same db,
+they're still in backups and encrypted like others, but never loaded with live records.
+*/
+const (
+	bucketArchivedStateManager = "archivedStateManager"
+	bucketArchivedAck          = "archivedAck"
+)
+
+//ArchiveResult is how many records are moved to archive
+type ArchiveResult struct {
+	StateManagers int `json:"state_managers"`
+	Acks          int `json:"acks"`
+}
+
+/*
+Archive moves completed StateManagers, and acks saved more than `ackBlockAge` blocks ago, to archive buckets.
+acks are not archived if `ackBlockAge` is not positive.
+a record is saved to archive before it's removed, so an interrupted archive is done again next time.
+*/
+func (model *ModelDB) Archive(ackBlockAge int64) (r *ArchiveResult, err error) {
+	r = new(ArchiveResult)
+	mgrs, err := model.storage.GetAllStateManagers()
+	if err != nil {
+		return
+	}
+	for _, mgr := range mgrs {
+		if !isStateManagerFinished(mgr) {
+			continue
+		}
+		err = model.storage.Set(bucketArchivedStateManager, fmt.Sprintf("%d", mgr.ID), mgr)
+		if err != nil {
+			return
+		}
+		err = model.storage.DeleteStateManager(mgr)
+		if err != nil {
+			return
+		}
+		r.StateManagers++
+	}
+	if ackBlockAge > 0 {
+		r.Acks, err = model.archiveAcks(model.GetLatestBlockNumber() - ackBlockAge)
+		if err != nil {
+			return
+		}
+	}
+	log.Info(fmt.Sprintf("db archived %d StateManagers and %d acks", r.StateManagers, r.Acks))
+	return
+}
+
+/*
+isStateManagerFinished returns true if the transfer of `mgr` succeeded or is cancelled,
+StateManagers saved before ManagerState is set on finish only have no CurrentState.
+*/
+func isStateManagerFinished(mgr *transfer.StateManager) bool {
+	if mgr.ManagerState == transfer.StateManagerTransferComplete {
+		return true
+	}
+	return mgr.CurrentState == nil && mgr.ManagerState != transfer.StateManagerStateInit
+}
+
+//archiveAcks moves acks saved before `blockNumber` to archive
+func (model *ModelDB) archiveAcks(blockNumber int64) (n int, err error) {
+	hashes, err := model.storage.GetAcksSavedBefore(blockNumber)
+	if err != nil {
+		return
+	}
+	for _, h := range hashes {
+		var data []byte
+		data, err = model.storage.GetAck(h)
+		if err != nil {
+			return
+		}
+		err = model.storage.Set(bucketArchivedAck, h[:], data)
+		if err != nil {
+			return
+		}
+		err = model.storage.DeleteAck(h)
+		if err != nil {
+			return
+		}
+		n++
+	}
+	return
+}
+
+//StorageStats is size of db and number of records in it
+type StorageStats struct {
+	Backend     string `json:"backend"`
+	Path        string `json:"path"`
+	FileSize    int64  `json:"file_size"`
+	BlockNumber int64  `json:"block_number"`
+	//Records is number of records of every bucket of bolt or table of sqlite, archived records are in buckets beginning with `archived`
+	Records map[string]int `json:"records"`
+}
+
+//Stats returns size of db file and number of records
+func (model *ModelDB) Stats() (s *StorageStats, err error) {
+	s = &StorageStats{
+		Backend:     model.storage.Backend(),
+		Path:        model.Name,
+		BlockNumber: model.GetLatestBlockNumber(),
+	}
+	fi, err := os.Stat(model.Name)
+	if err != nil {
+		return
+	}
+	s.FileSize = fi.Size()
+	s.Records, err = model.storage.CountRecords()
+	return
+}
+
+/*
+CompactDb rewrites db at `dbPath` to reclaim space of deleted and archived records,
+it returns file size before and after, db must not be opened.
+*/
+func CompactDb(dbPath string) (before, after int64, err error) {
+	fi, err := os.Stat(dbPath)
+	if err != nil {
+		return
+	}
+	before = fi.Size()
+	err = compactStorage(dbPath)
+	if err != nil {
+		return
+	}
+	fi, err = os.Stat(dbPath)
+	if err != nil {
+		return
+	}
+	after = fi.Size()
+	return
+}
@@ -0,0 +1,116 @@
+package models
+
+import (
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/initiator"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchive(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		done := newInitiatorStateManager("done", nil)
+		//no route, the transfer fails and initiator finishes
+		done.Dispatch(newInitiatorStateChange())
+		assert.EqualValues(t, transfer.StateManagerTransferComplete, done.ManagerState)
+		running := newInitiatorStateManager("running", &mediatedtransfer.InitiatorState{
+			OurAddress: utils.NewRandomAddress(),
+			Transfer:   newInitiatorStateChange().Tranfer,
+		})
+		running.ManagerState = transfer.StateManagerSendMessage
+		assert.Nil(t, model.AddStateManager(done))
+		assert.Nil(t, model.AddStateManager(running))
+		oldAck := utils.NewRandomHash()
+		model.SaveLatestBlockNumber(10)
+		model.SaveAckNoTx(oldAck, []byte("old"))
+		newAck := utils.NewRandomHash()
+		model.SaveLatestBlockNumber(100)
+		model.SaveAckNoTx(newAck, []byte("new"))
+
+		r, err := model.Archive(0)
+		assert.Nil(t, err)
+		assert.EqualValues(t, &ArchiveResult{StateManagers: 1}, r)
+		mgrs := model.GetAllStateManager()
+		if assert.Len(t, mgrs, 1) {
+			assert.EqualValues(t, "running", mgrs[0].Name)
+		}
+
+		r, err = model.Archive(50)
+		assert.Nil(t, err)
+		assert.EqualValues(t, &ArchiveResult{Acks: 1}, r)
+		hashes, err := model.storage.GetAcksSavedBefore(1000)
+		assert.Nil(t, err)
+		assert.EqualValues(t, len(hashes), 1)
+		assert.EqualValues(t, newAck, hashes[0])
+		//archived ack is still found for a message received again
+		assert.EqualValues(t, []byte("old"), model.GetAck(oldAck))
+
+		s, err := model.Stats()
+		assert.Nil(t, err)
+		assert.True(t, s.FileSize > 0)
+		assert.EqualValues(t, 100, s.BlockNumber)
+		assert.EqualValues(t, 1, s.Records[bucketArchivedStateManager])
+		assert.EqualValues(t, 1, s.Records[bucketArchivedAck])
+	})
+}
+
+func newInitiatorStateManager(name string, state transfer.State) *transfer.StateManager {
+	return transfer.NewStateManager(initiator.StateTransition, state, name, utils.NewRandomHash(), utils.NewRandomAddress())
+}
+
+func newInitiatorStateChange() *mediatedtransfer.ActionInitInitiatorStateChange {
+	secret := utils.NewRandomHash()
+	our, target := utils.NewRandomAddress(), utils.NewRandomAddress()
+	return &mediatedtransfer.ActionInitInitiatorStateChange{
+		OurAddress: our,
+		Tranfer: &mediatedtransfer.LockedTransferState{
+			Amount:       big.NewInt(10),
+			Initiator:    our,
+			Target:       target,
+			TargetAmount: big.NewInt(10),
+			Fee:          utils.BigInt0,
+		},
+		Routes:         route.NewRoutesState(nil),
+		BlockNumber:    1,
+		Secret:         secret,
+		LockSecretHash: utils.Sha3(secret[:]),
+	}
+}
+
+func TestCompactDb(t *testing.T) {
+	for backend, name := range storageTestFiles {
+		t.Run(backend, func(t *testing.T) {
+			dbPath := filepath.Join(filepath.Dir(tempDbPath(t)), name)
+			defer os.RemoveAll(filepath.Dir(dbPath))
+			model, err := OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i := 0; i < 500; i++ {
+				model.SaveAckNoTx(utils.NewRandomHash(), make([]byte, 1000))
+			}
+			kept := utils.NewRandomHash()
+			model.SaveAckNoTx(kept, []byte("kept"))
+			model.SaveLatestBlockNumber(100)
+			_, err = model.Archive(1)
+			assert.Nil(t, err)
+			model.CloseDB()
+			before, after, err := CompactDb(dbPath)
+			assert.Nil(t, err)
+			assert.True(t, after < before, "%d should be less than %d", after, before)
+			model, err = OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.CloseDB()
+			assert.EqualValues(t, []byte("kept"), model.GetAck(kept))
+		})
+	}
+}
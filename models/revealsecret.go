@@ -6,7 +6,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -51,14 +50,13 @@ func NewSentRevealSecret(msg *encoding.RevealSecret, receiver common.Address) *S
 
 //IsReceivedRevealSecretExist return true when this message has received before.
 func (model *ModelDB) IsReceivedRevealSecretExist(echohash common.Hash) bool {
-	var rss ReceivedRevealSecret
-	err := model.db.One("EchoHashString", echohash.String(), &rss)
+	_, err := model.storage.GetReceivedRevealSecret(echohash)
 	return err == nil
 }
 
 //NewReceivedRevealSecret marks receive a reveal secret message
 func (model *ModelDB) NewReceivedRevealSecret(secret *ReceivedRevealSecret) {
-	err := model.db.Save(secret)
+	err := model.storage.SaveReceivedRevealSecret(secret)
 	if err != nil {
 		panic("ReceivedRevealSecret should not exist ")
 	}
@@ -66,12 +64,12 @@ func (model *ModelDB) NewReceivedRevealSecret(secret *ReceivedRevealSecret) {
 
 //UpdateReceivedRevealSecretComplete marks a revealsecret message has been processed.
 func (model *ModelDB) UpdateReceivedRevealSecretComplete(echohash common.Hash) {
-	var rss ReceivedRevealSecret
-	err := model.db.One("EchoHashString", echohash.String(), &rss)
+	rss, err := model.storage.GetReceivedRevealSecret(echohash)
 	if err != nil {
 		panic("UpdateReceivedRevealSecretComplete revealsecret must exist")
 	}
-	err = model.db.UpdateField(&rss, "IsComplete", "true")
+	rss.IsComplete = "true"
+	err = model.storage.SaveReceivedRevealSecret(rss)
 	if err != nil {
 		panic(fmt.Sprintf("UpdateReceivedRevealSecretComplete err=%s", err))
 	}
@@ -79,9 +77,8 @@ func (model *ModelDB) UpdateReceivedRevealSecretComplete(echohash common.Hash) {
 
 //GetAllUncompleteReceivedRevealSecret return all reveal secret messages that have not been processed before quit.
 func (model *ModelDB) GetAllUncompleteReceivedRevealSecret() []*ReceivedRevealSecret {
-	var msgs []*ReceivedRevealSecret
-	err := model.db.Find("IsComplete", "false", &msgs)
-	if err != nil && err != storm.ErrNotFound {
+	msgs, err := model.storage.GetUncompleteReceivedRevealSecrets()
+	if err != nil {
 		panic(fmt.Sprintf("GetAllUncompleteReceivedRevealSecret err=%s", err))
 	}
 	return msgs
@@ -89,8 +86,7 @@ func (model *ModelDB) GetAllUncompleteReceivedRevealSecret() []*ReceivedRevealSe
 
 //IsSentRevealSecretExist return true when this message can be found in db
 func (model *ModelDB) IsSentRevealSecretExist(echohash common.Hash) bool {
-	var rss SentRevealSecret
-	err := model.db.One("EchoHashString", echohash.String(), &rss)
+	_, err := model.storage.GetSentRevealSecret(echohash)
 	return err == nil
 }
 
@@ -99,7 +95,7 @@ NewSentRevealSecret It is very likely to send reveal secret repeatedly, which ca
 */
 func (model *ModelDB) NewSentRevealSecret(secret *SentRevealSecret) {
 	log.Trace(fmt.Sprintf("NewSentRevealSecret %s", utils.HPex(secret.EchoHash)))
-	err := model.db.Save(secret)
+	err := model.storage.SaveSentRevealSecret(secret)
 	if err != nil {
 		log.Error(fmt.Sprintf("NewSentRevealSecret err=%s", err))
 	}
@@ -107,13 +103,13 @@ func (model *ModelDB) NewSentRevealSecret(secret *SentRevealSecret) {
 
 //UpdateSentRevealSecretComplete marks message has been sent complete
 func (model *ModelDB) UpdateSentRevealSecretComplete(echohash common.Hash) {
-	var sss SentRevealSecret
 	log.Trace(fmt.Sprintf("UpdateSentRevealSecretComplete %s", utils.HPex(echohash)))
-	err := model.db.One("EchoHashString", echohash.String(), &sss)
+	sss, err := model.storage.GetSentRevealSecret(echohash)
 	if err != nil {
 		panic("UpdateSentRevealSecretComplete revealsecret must exist")
 	}
-	err = model.db.UpdateField(&sss, "IsComplete", "true")
+	sss.IsComplete = "true"
+	err = model.storage.SaveSentRevealSecret(sss)
 	if err != nil {
 		panic(fmt.Sprintf("UpdateSentRevealSecretComplete err %s", err))
 	}
@@ -121,9 +117,8 @@ func (model *ModelDB) UpdateSentRevealSecretComplete(echohash common.Hash) {
 
 //GetAllUncompleteSentRevealSecret get all sending reveal secret messages that have not recevied ack
 func (model *ModelDB) GetAllUncompleteSentRevealSecret() []*SentRevealSecret {
-	var msgs []*SentRevealSecret
-	err := model.db.Find("IsComplete", "false", &msgs)
-	if err != nil && err != storm.ErrNotFound {
+	msgs, err := model.storage.GetUncompleteSentRevealSecrets()
+	if err != nil {
 		panic(fmt.Sprintf("GetAllUncompleteSentRevealSecret err=%s", err))
 	}
 	log.Trace(fmt.Sprintf("GetAllUncompleteSentRevealSecret=%s", utils.StringInterface(msgs, 7)))
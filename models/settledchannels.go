@@ -3,14 +3,8 @@ package models
 import (
 	"fmt"
 
-	"bytes"
-	"encoding/gob"
-
-	"encoding/hex"
-
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
-	"github.com/coreos/bbolt"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -20,47 +14,29 @@ import (
 */
 const bucketSettledChannel = "settled_channel"
 
-func unmarshal(b []byte, v interface{}) error {
-	r := bytes.NewReader(b)
-	dec := gob.NewDecoder(r)
-	return dec.Decode(v)
-}
 
 //NewSettledChannel save a settled channel to db
 func (model *ModelDB) NewSettledChannel(c *channeltype.Serialization) error {
 	if c.State != channeltype.StateSettled {
 		panic("only settled channel can saved to settledChannel")
 	}
-	key := fmt.Sprintf("%s-%d", c.ChannelIdentifier.ChannelIdentifier.String(), c.ChannelIdentifier.OpenBlockNumber)
-	return model.db.Set(bucketSettledChannel, key, c)
+	return model.storage.SaveSettledChannel(c)
+}
+
+func settledChannelKey(channelIdentifier common.Hash, openBlockNumber int64) string {
+	return fmt.Sprintf("%s-%d", channelIdentifier.String(), openBlockNumber)
 }
 
 //GetAllSettledChannel returns all settled channel
 func (model *ModelDB) GetAllSettledChannel() (chs []*channeltype.Serialization, err error) {
-	model.db.Bolt.View(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(bucketSettledChannel))
-		b.ForEach(func(k, v []byte) error {
-			if string(k) == "__storm_metadata" {
-				return nil
-			}
-			log.Trace(fmt.Sprintf("GetAllSettledChannel key=%s, value=%s\n", string(k), hex.EncodeToString(v)))
-			var c channeltype.Serialization
-			err = unmarshal(v, &c)
-			if err != nil {
-				return err
-			}
-			chs = append(chs, &c)
-			return nil
-		})
-		return nil
-	})
+	chs, err = model.storage.GetAllSettledChannels()
+	if err != nil {
+		log.Error(fmt.Sprintf("GetAllSettledChannel err %s", err))
+	}
 	return
 }
 
 //GetSettledChannel 返回某个指定的已经 settle 的 channel
 func (model *ModelDB) GetSettledChannel(channelIdentifier common.Hash, openBlockNumber int64) (c *channeltype.Serialization, err error) {
-	c = new(channeltype.Serialization)
-	key := fmt.Sprintf("%s-%d", channelIdentifier.String(), openBlockNumber)
-	err = model.db.Get(bucketSettledChannel, key, c)
-	return
+	return model.storage.GetSettledChannel(channelIdentifier, openBlockNumber)
 }
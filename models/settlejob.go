@@ -0,0 +1,65 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+SettleJob is a closed channel waiting to be settled on chain.
+it's saved to db, so a channel closed before restart is still settled.
+*/
+type SettleJob struct {
+	Key               string `storm:"id"`
+	ChannelIdentifier common.Hash
+	TokenAddress      common.Address
+	PartnerAddress    common.Address
+	SettleBlock       int64  //settle can only succeed after this block
+	Tries             int    //settle transactions sent and failed
+	NextTryBlock      int64  //retry no earlier than this block after a failure
+	LastError         string //error of last try
+}
+
+//NewSettleJob create a settle job for channel `channelIdentifier`
+func NewSettleJob(channelIdentifier common.Hash, token, partner common.Address, settleBlock int64) *SettleJob {
+	return &SettleJob{
+		Key:               channelIdentifier.String(),
+		ChannelIdentifier: channelIdentifier,
+		TokenAddress:      token,
+		PartnerAddress:    partner,
+		SettleBlock:       settleBlock,
+	}
+}
+
+//SaveSettleJob save or replace settle job
+func (model *ModelDB) SaveSettleJob(j *SettleJob) error {
+	err := model.storage.SaveSettleJob(j)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveSettleJob err %s", err))
+	}
+	return err
+}
+
+//GetSettleJob returns settle job of channel `channelIdentifier`
+func (model *ModelDB) GetSettleJob(channelIdentifier common.Hash) (j *SettleJob, err error) {
+	return model.storage.GetSettleJob(channelIdentifier)
+}
+
+//GetAllSettleJobs returns all settle jobs
+func (model *ModelDB) GetAllSettleJobs() (js []*SettleJob, err error) {
+	return model.storage.GetAllSettleJobs()
+}
+
+//RemoveSettleJob remove settle job of channel `channelIdentifier`, it's ok if there is no such job
+func (model *ModelDB) RemoveSettleJob(channelIdentifier common.Hash) error {
+	j, err := model.GetSettleJob(channelIdentifier)
+	if err == ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return model.storage.DeleteSettleJob(j)
+}
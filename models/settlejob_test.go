@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_SettleJob(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	ch := utils.NewRandomHash()
+	_, err := model.GetSettleJob(ch)
+	if err == nil {
+		t.Error("should not found")
+		return
+	}
+	j := NewSettleJob(ch, utils.NewRandomAddress(), utils.NewRandomAddress(), 100)
+	err = model.SaveSettleJob(j)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	j.Tries++
+	j.NextTryBlock = 102
+	err = model.SaveSettleJob(j)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	js, err := model.GetAllSettleJobs()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(js), 1)
+	assert.EqualValues(t, js[0].Tries, 1)
+	assert.EqualValues(t, js[0].NextTryBlock, 102)
+	assert.EqualValues(t, js[0].ChannelIdentifier, ch)
+	err = model.RemoveSettleJob(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	//remove twice is ok
+	err = model.RemoveSettleJob(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	js, err = model.GetAllSettleJobs()
+	assert.EqualValues(t, len(js), 0)
+}
@@ -2,20 +2,23 @@ package models
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
 const bucketAck = "ack"
 
+//bucketAckBlockNumber is the block number of every ack when it is saved, so old acks can be archived
+const bucketAckBlockNumber = "ackBlockNumber"
+
 //StartTx start a new tx of db
-func (model *ModelDB) StartTx() (tx storm.Node) {
+func (model *ModelDB) StartTx() (tx TX) {
 	var err error
-	tx, err = model.db.Begin(true)
+	tx, err = model.storage.Begin()
 	if err != nil {
 		panic(fmt.Sprintf("start transaction error %s", err))
 	}
@@ -24,7 +27,7 @@ func (model *ModelDB) StartTx() (tx storm.Node) {
 
 //AddStateManager add new StateManager
 func (model *ModelDB) AddStateManager(mgr *transfer.StateManager) error {
-	err := model.db.Save(mgr)
+	err := model.storage.SaveStateManager(mgr, nil)
 	if err != nil {
 		log.Error(fmt.Sprintf(" AddStateManager err=%s", err))
 	}
@@ -32,9 +35,9 @@ func (model *ModelDB) AddStateManager(mgr *transfer.StateManager) error {
 }
 
 //UpdateStateManaer update all fileds of StateManager
-func (model *ModelDB) UpdateStateManaer(mgr *transfer.StateManager, tx storm.Node) error {
+func (model *ModelDB) UpdateStateManaer(mgr *transfer.StateManager, tx TX) error {
 	//log.Trace(fmt.Sprintf("UpdateStateManaer %s\n", utils.StringInterface(mgr, 7)))
-	err := tx.Save(mgr)
+	err := model.storage.SaveStateManager(mgr, tx)
 	if err != nil {
 		log.Error(fmt.Sprintf("UpdateStateManaer err=%s", err))
 	}
@@ -43,10 +46,8 @@ func (model *ModelDB) UpdateStateManaer(mgr *transfer.StateManager, tx storm.Nod
 
 //GetAllStateManager return all StateManager in db
 func (model *ModelDB) GetAllStateManager() []*transfer.StateManager {
-	var mgrs []*transfer.StateManager
-	//err := model.db.Find("IsFinished", false, &mgrs)
-	err := model.db.All(&mgrs)
-	if err != nil && err != storm.ErrNotFound {
+	mgrs, err := model.storage.GetAllStateManagers()
+	if err != nil {
 		panic(fmt.Sprintf("GetAllUnfinishedStateManager err %s", err))
 	}
 	return mgrs
@@ -54,9 +55,12 @@ func (model *ModelDB) GetAllStateManager() []*transfer.StateManager {
 
 //GetAck get message related ack message
 func (model *ModelDB) GetAck(echohash common.Hash) []byte {
-	var data []byte
-	err := model.db.Get(bucketAck, echohash[:], &data)
-	if err != nil && err != storm.ErrNotFound {
+	data, err := model.storage.GetAck(echohash)
+	if err == ErrNotFound {
+		//message may be received again long after, its ack is archived then
+		err = model.storage.Get(bucketArchivedAck, echohash[:], &data)
+	}
+	if err != nil && err != ErrNotFound {
 		panic(fmt.Sprintf("GetAck err %s", err))
 	}
 	log.Trace(fmt.Sprintf("get ack %s from db,result=%d", utils.HPex(echohash), len(data)))
@@ -64,9 +68,9 @@ func (model *ModelDB) GetAck(echohash common.Hash) []byte {
 }
 
 //SaveAck save a new ack to db
-func (model *ModelDB) SaveAck(echohash common.Hash, ack []byte, tx storm.Node) {
+func (model *ModelDB) SaveAck(echohash common.Hash, ack []byte, tx TX) {
 	log.Trace(fmt.Sprintf("save ack %s to db", utils.HPex(echohash)))
-	err := tx.Set(bucketAck, echohash[:], ack)
+	err := model.storage.SaveAck(echohash, ack, atomic.LoadInt64(&model.blockNumber), tx)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -74,7 +78,7 @@ func (model *ModelDB) SaveAck(echohash common.Hash, ack []byte, tx storm.Node) {
 
 //SaveAckNoTx save a ack to db
 func (model *ModelDB) SaveAckNoTx(echohash common.Hash, ack []byte) {
-	err := model.db.Set(bucketAck, echohash[:], ack)
+	err := model.storage.SaveAck(echohash, ack, atomic.LoadInt64(&model.blockNumber), nil)
 	if err != nil {
 		log.Error(fmt.Sprintf("save ack to db err %s", err))
 	}
@@ -0,0 +1,233 @@
+package models
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/asdine/storm"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//ErrNotFound is returned by every Storage when a record doesn't exist
+var ErrNotFound = storm.ErrNotFound
+
+//storage backends of ModelDB
+const (
+	BackendBolt   = "bolt"
+	BackendSQLite = "sqlite"
+)
+
+//sqliteHeader is the first 16 bytes of every sqlite db file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+//TX is a read-write transaction of Storage, methods taking a nil TX work without a transaction.
+type TX interface {
+	Commit() error
+	Rollback() error
+}
+
+//ChannelStorage stores channels we take part in
+type ChannelStorage interface {
+	SaveChannel(c *channeltype.Serialization, tx TX) error
+	DeleteChannel(c *channeltype.Serialization) error
+	GetChannelByAddress(channelIdentifier common.Hash) (*channeltype.Serialization, error)
+	//GetChannels returns channels of `token` with `partner`, an empty address matches any
+	GetChannels(token, partner common.Address) ([]*channeltype.Serialization, error)
+}
+
+//TokenStorage stores registered tokens and nodes having channels on them
+type TokenStorage interface {
+	GetAllTokens() (AddressMap, error)
+	SaveToken(token, tokenNetwork common.Address) error
+	GetTokenNodes(token common.Address) ([]common.Address, error)
+	SaveTokenNodes(token common.Address, nodes []common.Address) error
+}
+
+//StateManagerStorage stores StateManagers of transfers
+type StateManagerStorage interface {
+	//SaveStateManager assigns a new ID to `mgr` if it has none
+	SaveStateManager(mgr *transfer.StateManager, tx TX) error
+	GetAllStateManagers() ([]*transfer.StateManager, error)
+	DeleteStateManager(mgr *transfer.StateManager) error
+}
+
+//AckStorage stores acks of received messages
+type AckStorage interface {
+	GetAck(echohash common.Hash) ([]byte, error)
+	//SaveAck saves `ack` with the block number it's saved at
+	SaveAck(echohash common.Hash, ack []byte, blockNumber int64, tx TX) error
+	//GetAcksSavedBefore returns echohash of acks saved before block `blockNumber`, including those saved without block number
+	GetAcksSavedBefore(blockNumber int64) ([]common.Hash, error)
+	DeleteAck(echohash common.Hash) error
+}
+
+//RevealSecretStorage stores reveal secret and remove expired hashlock messages until they're done
+type RevealSecretStorage interface {
+	GetReceivedRevealSecret(echohash common.Hash) (*ReceivedRevealSecret, error)
+	SaveReceivedRevealSecret(s *ReceivedRevealSecret) error
+	GetUncompleteReceivedRevealSecrets() ([]*ReceivedRevealSecret, error)
+	GetSentRevealSecret(echohash common.Hash) (*SentRevealSecret, error)
+	SaveSentRevealSecret(s *SentRevealSecret) error
+	GetUncompleteSentRevealSecrets() ([]*SentRevealSecret, error)
+	GetSentRemoveExpiredHashlockTransfer(echohash common.Hash) (*SentRemoveExpiredHashlockTransfer, error)
+	SaveSentRemoveExpiredHashlockTransfer(t *SentRemoveExpiredHashlockTransfer, tx TX) error
+	GetUncompleteSentRemoveExpiredHashlockTransfers() ([]*SentRemoveExpiredHashlockTransfer, error)
+}
+
+//TransferStorage stores history of transfers
+type TransferStorage interface {
+	GetSentTransfer(key string) (*SentTransfer, error)
+	SaveSentTransfer(t *SentTransfer) error
+	//FindSentTransfers returns sent transfers selected by normalized filter `f`, ordered by block number and key, at most f.Limit if it's positive
+	FindSentTransfers(f *TransferFilter) ([]*SentTransfer, error)
+	GetReceivedTransfer(key string) (*ReceivedTransfer, error)
+	SaveReceivedTransfer(t *ReceivedTransfer) error
+	FindReceivedTransfers(f *TransferFilter) ([]*ReceivedTransfer, error)
+}
+
+//SettledChannelStorage keeps settled channels for query
+type SettledChannelStorage interface {
+	SaveSettledChannel(c *channeltype.Serialization) error
+	GetSettledChannel(channelIdentifier common.Hash, openBlockNumber int64) (*channeltype.Serialization, error)
+	GetAllSettledChannels() ([]*channeltype.Serialization, error)
+}
+
+//NonParticipantChannelStorage stores all channels of a token network for routing
+type NonParticipantChannelStorage interface {
+	AddNonParticipantChannel(tokenNetwork common.Address, channel common.Hash, participants []byte) error
+	RemoveNonParticipantChannel(tokenNetwork common.Address, channel common.Hash) error
+	GetNonParticipantChannels(tokenNetwork common.Address) (ChannelParticipantMap, error)
+}
+
+//ContractEventStorage stores decoded events of contracts
+type ContractEventStorage interface {
+	SaveContractEvent(e *ContractEvent) error
+	//FindContractEvents returns contract events selected by normalized filter `f`, ordered by block number and log index
+	FindContractEvents(f *ContractEventFilter) ([]*ContractEvent, error)
+	//FindParticipantChannels returns channels opened with `participant`, on token network `contract` if it's not empty
+	FindParticipantChannels(participant, contract common.Address) ([]common.Hash, error)
+}
+
+//RecordStorage stores api tokens, settle jobs, unlock outcomes, node endpoints and announce disposed records
+type RecordStorage interface {
+	SaveAPIToken(t *APIToken) error
+	GetAPIToken(name string) (*APIToken, error)
+	GetAPITokenByHash(tokenHash string) (*APIToken, error)
+	GetAllAPITokens() ([]*APIToken, error)
+	DeleteAPIToken(t *APIToken) error
+	SaveSettleJob(j *SettleJob) error
+	GetSettleJob(channelIdentifier common.Hash) (*SettleJob, error)
+	GetAllSettleJobs() ([]*SettleJob, error)
+	DeleteSettleJob(j *SettleJob) error
+	SaveUnlockOutcome(o *channeltype.UnlockOutcome) error
+	GetUnlockOutcomes(channelIdentifier common.Hash) ([]*channeltype.UnlockOutcome, error)
+	SaveNodeEndpoint(e *NodeEndpoint) error
+	GetNodeEndpoint(addr common.Address) (*NodeEndpoint, error)
+	GetAllNodeEndpoints() ([]*NodeEndpoint, error)
+	DeleteNodeEndpoint(e *NodeEndpoint) error
+	SaveSentAnnounceDisposed(s *SentAnnounceDisposed) error
+	GetSentAnnounceDisposed(key []byte) (*SentAnnounceDisposed, error)
+	FindSentAnnounceDisposed(lockSecretHash common.Hash) (*SentAnnounceDisposed, error)
+	SaveReceivedAnnounceDisposed(r *ReceivedAnnounceDisposed) error
+	GetReceivedAnnounceDisposed(key []byte) (*ReceivedAnnounceDisposed, error)
+}
+
+/*
+KeyValueStorage stores small values in named buckets,
+it's where meta data, block number, unlocked and removed locks and xmpp subscriptions are kept.
+keys must be string or []byte.
+*/
+type KeyValueStorage interface {
+	Get(bucketName string, key interface{}, to interface{}) error
+	Set(bucketName string, key interface{}, value interface{}) error
+	Delete(bucketName string, key interface{}) error
+}
+
+/*
+Storage is where ModelDB keeps everything,
+ModelDB does logging, callbacks and notifications, Storage only saves and loads records.
+values are encoded by the codec given when opening, so they can be encrypted the same way on every backend.
+*/
+type Storage interface {
+	ChannelStorage
+	TokenStorage
+	StateManagerStorage
+	AckStorage
+	RevealSecretStorage
+	TransferStorage
+	SettledChannelStorage
+	NonParticipantChannelStorage
+	ContractEventStorage
+	RecordStorage
+	KeyValueStorage
+	//Backend returns BackendBolt or BackendSQLite
+	Backend() string
+	Begin() (TX, error)
+	//Init creates what a new db needs
+	Init() error
+	//MigrateStep upgrades db from version `ver` to `ver`+1 and saves the new version in one transaction
+	MigrateStep(ver int) error
+	//Backup writes a consistent snapshot of db to file `to`, while it's in use
+	Backup(to string) error
+	//EncryptionKeyInfo returns salt and check value of the encryption key, both nil if db is not encrypted
+	EncryptionKeyInfo() (salt, check []byte, err error)
+	//CountRecords returns number of records in every bucket of bolt or table of sqlite
+	CountRecords() (map[string]int, error)
+	//Reencrypt re-encrypts every value from `oldKey` to `newKey` and saves the new salt and check value, nil `newKey` means decrypt
+	Reencrypt(oldKey, newKey, salt, check []byte) error
+	Close() error
+}
+
+/*
+detectBackend returns backend of db at `dbPath`,
+an existing file is recognized by its header, a new one by its extension, `.sqlite` or `.sqlite3` means sqlite.
+*/
+func detectBackend(dbPath string) (string, error) {
+	f, err := os.Open(dbPath)
+	if os.IsNotExist(err) {
+		ext := strings.ToLower(filepath.Ext(dbPath))
+		if ext == ".sqlite" || ext == ".sqlite3" {
+			return BackendSQLite, nil
+		}
+		return BackendBolt, nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	header := make([]byte, len(sqliteHeader))
+	_, err = io.ReadFull(f, header)
+	if err == nil && bytes.Equal(header, sqliteHeader) {
+		return BackendSQLite, nil
+	}
+	return BackendBolt, nil
+}
+
+//openStorage opens or creates db at `dbPath` by the backend detected from it
+func openStorage(dbPath string, c *cryptCodec) (Storage, error) {
+	backend, err := detectBackend(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	if backend == BackendSQLite {
+		return openSQLiteStorage(dbPath, c)
+	}
+	return openBoltStorage(dbPath, c)
+}
+
+//compactStorage rewrites db at `dbPath` to reclaim space of deleted records, db must not be opened
+func compactStorage(dbPath string) error {
+	backend, err := detectBackend(dbPath)
+	if err != nil {
+		return err
+	}
+	if backend == BackendSQLite {
+		return compactSQLite(dbPath)
+	}
+	return compactBolt(dbPath)
+}
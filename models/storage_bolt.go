@@ -0,0 +1,936 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/asdine/storm"
+	"github.com/coreos/bbolt"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+boltStorage is the default Storage, every record is saved by storm in a bolt db.
+*/
+type boltStorage struct {
+	db    *storm.DB
+	codec *cryptCodec
+}
+
+func openBoltStorage(dbPath string, c *cryptCodec) (s *boltStorage, err error) {
+	s = &boltStorage{codec: c}
+	s.db, err = storm.Open(dbPath, storm.BoltOptions(os.ModePerm, &bolt.Options{Timeout: 1 * time.Second}), storm.Codec(c))
+	return
+}
+
+//node returns storm node of `tx`, or db when there is no tx
+func (s *boltStorage) node(tx TX) storm.Node {
+	if tx == nil {
+		return s.db
+	}
+	return tx.(storm.Node)
+}
+
+//Backend of bolt db
+func (s *boltStorage) Backend() string {
+	return BackendBolt
+}
+
+//Begin starts a read-write storm transaction
+func (s *boltStorage) Begin() (TX, error) {
+	return s.db.Begin(true)
+}
+
+//Init creates buckets and indexes of a new db
+func (s *boltStorage) Init() error {
+	err := s.db.Set(bucketToken, keyToken, make(AddressMap))
+	if err != nil {
+		return err
+	}
+	for _, data := range []interface{}{
+		&SentTransfer{},
+		&ReceivedTransfer{},
+		&SettleJob{},
+		&channeltype.UnlockOutcome{},
+		&APIToken{},
+		&ContractEvent{},
+		&ParticipantChannel{},
+	} {
+		err = s.db.Init(data)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//MigrateStep runs migrations[ver-1] in a bolt transaction
+func (s *boltStorage) MigrateStep(ver int) (err error) {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	err = migrations[ver-1](tx)
+	if err != nil {
+		return
+	}
+	err = tx.Set(bucketMeta, "version", ver+1)
+	if err != nil {
+		return
+	}
+	return tx.Commit()
+}
+
+/*
+Backup copies db in a read transaction,
+the snapshot is written to a temporary file first, so `to` is either a complete db or doesn't exist.
+*/
+func (s *boltStorage) Backup(to string) error {
+	tmp := to + ".tmp"
+	err := s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.CopyFile(tmp, 0600)
+	})
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, to)
+}
+
+//EncryptionKeyInfo reads salt and check value saved as raw bytes in bucketEncryption
+func (s *boltStorage) EncryptionKeyInfo() (salt, check []byte, err error) {
+	err = s.db.Bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketEncryption))
+		if b == nil || b.Get(keySalt) == nil {
+			return nil
+		}
+		//bolt's value is only valid in tx
+		salt = append([]byte{}, b.Get(keySalt)...)
+		check = append([]byte{}, b.Get(keyCheck)...)
+		return nil
+	})
+	return
+}
+
+//CountRecords counts keys of every bucket, except storm's metadata and index buckets
+func (s *boltStorage) CountRecords() (counts map[string]int, err error) {
+	counts = make(map[string]int)
+	err = s.db.Bolt.View(func(tx *bolt.Tx) error {
+		return tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) == bucketEncryption || string(name) == stormDbInfo {
+				return nil
+			}
+			n := 0
+			c := b.Cursor()
+			for k, v := c.First(); k != nil; k, v = c.Next() {
+				//v is nil for nested buckets
+				if v != nil && !bytes.Equal(k, stormMetadata) {
+					n++
+				}
+			}
+			counts[string(name)] = n
+			return nil
+		})
+	})
+	return
+}
+
+//Reencrypt re-encrypts values of all buckets except storm's and the encryption key's in one bolt transaction
+func (s *boltStorage) Reencrypt(oldKey, newKey, salt, check []byte) error {
+	return s.db.Bolt.Update(func(tx *bolt.Tx) error {
+		err := tx.ForEach(func(name []byte, b *bolt.Bucket) error {
+			if string(name) == bucketEncryption || string(name) == stormDbInfo {
+				return nil
+			}
+			return reencryptBucket(b, oldKey, newKey)
+		})
+		if err != nil {
+			return err
+		}
+		if newKey == nil {
+			err = tx.DeleteBucket([]byte(bucketEncryption))
+			if err == bolt.ErrBucketNotFound {
+				err = nil
+			}
+			return err
+		}
+		b, err := tx.CreateBucketIfNotExists([]byte(bucketEncryption))
+		if err != nil {
+			return err
+		}
+		err = b.Put(keySalt, salt)
+		if err != nil {
+			return err
+		}
+		return b.Put(keyCheck, check)
+	})
+}
+
+//Close bolt db
+func (s *boltStorage) Close() error {
+	return s.db.Close()
+}
+
+//Get a value from bucket
+func (s *boltStorage) Get(bucketName string, key interface{}, to interface{}) error {
+	return s.db.Get(bucketName, key, to)
+}
+
+//Set a value in bucket
+func (s *boltStorage) Set(bucketName string, key interface{}, value interface{}) error {
+	return s.db.Set(bucketName, key, value)
+}
+
+//Delete a value from bucket
+func (s *boltStorage) Delete(bucketName string, key interface{}) error {
+	return s.db.Delete(bucketName, key)
+}
+
+//SaveChannel save or replace a channel
+func (s *boltStorage) SaveChannel(c *channeltype.Serialization, tx TX) error {
+	return s.node(tx).Save(c)
+}
+
+//DeleteChannel removes a channel
+func (s *boltStorage) DeleteChannel(c *channeltype.Serialization) error {
+	return s.db.DeleteStruct(c)
+}
+
+//GetChannelByAddress returns channel by its identifier
+func (s *boltStorage) GetChannelByAddress(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c = new(channeltype.Serialization)
+	err = s.db.One("Key", channelIdentifier[:], c)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetChannels finds channels by index of token or partner
+func (s *boltStorage) GetChannels(token, partner common.Address) (cs []*channeltype.Serialization, err error) {
+	var all []*channeltype.Serialization
+	if token == (common.Address{}) && partner == (common.Address{}) {
+		err = s.db.All(&all)
+	} else if token == (common.Address{}) {
+		err = s.db.Find("PartnerAddressBytes", partner[:], &all)
+	} else {
+		err = s.db.Find("TokenAddressBytes", token[:], &all)
+	}
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, c := range all {
+		if token != (common.Address{}) && partner != (common.Address{}) && c.PartnerAddress() != partner {
+			continue
+		}
+		cs = append(cs, c)
+	}
+	return
+}
+
+//GetAllTokens returns the token map, it's saved as a whole
+func (s *boltStorage) GetAllTokens() (tokens AddressMap, err error) {
+	err = s.db.Get(bucketToken, keyToken, &tokens)
+	if err == storm.ErrNotFound {
+		return make(AddressMap), nil
+	}
+	return
+}
+
+//SaveToken adds or replaces `token` in the token map
+func (s *boltStorage) SaveToken(token, tokenNetwork common.Address) error {
+	m, err := s.GetAllTokens()
+	if err != nil {
+		return err
+	}
+	m[token] = tokenNetwork
+	return s.db.Set(bucketToken, keyToken, m)
+}
+
+//GetTokenNodes returns nodes of `token`
+func (s *boltStorage) GetTokenNodes(token common.Address) (nodes []common.Address, err error) {
+	err = s.db.Get(bucketTokenNodes, token[:], &nodes)
+	return
+}
+
+//SaveTokenNodes replaces nodes of `token`
+func (s *boltStorage) SaveTokenNodes(token common.Address, nodes []common.Address) error {
+	return s.db.Set(bucketTokenNodes, token[:], nodes)
+}
+
+//SaveStateManager save or replace StateManager, storm assigns ID of a new one
+func (s *boltStorage) SaveStateManager(mgr *transfer.StateManager, tx TX) error {
+	return s.node(tx).Save(mgr)
+}
+
+//GetAllStateManagers returns all StateManagers
+func (s *boltStorage) GetAllStateManagers() (mgrs []*transfer.StateManager, err error) {
+	err = s.db.All(&mgrs)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//DeleteStateManager removes a StateManager
+func (s *boltStorage) DeleteStateManager(mgr *transfer.StateManager) error {
+	return s.db.DeleteStruct(mgr)
+}
+
+//GetAck returns ack of message `echohash`
+func (s *boltStorage) GetAck(echohash common.Hash) (data []byte, err error) {
+	err = s.db.Get(bucketAck, echohash[:], &data)
+	return
+}
+
+//SaveAck save ack of message `echohash`, and its block number in bucketAckBlockNumber
+func (s *boltStorage) SaveAck(echohash common.Hash, ack []byte, blockNumber int64, tx TX) error {
+	err := s.node(tx).Set(bucketAck, echohash[:], ack)
+	if err != nil {
+		return err
+	}
+	return s.node(tx).Set(bucketAckBlockNumber, echohash[:], blockNumber)
+}
+
+//GetAcksSavedBefore walks bucketAck except storm's metadata, acks saved before block number is recorded have no entry in bucketAckBlockNumber
+func (s *boltStorage) GetAcksSavedBefore(blockNumber int64) (hashes []common.Hash, err error) {
+	err = s.db.Bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketAck))
+		if b == nil {
+			return nil
+		}
+		blocks := tx.Bucket([]byte(bucketAckBlockNumber))
+		return b.ForEach(func(k, v []byte) error {
+			if bytes.Equal(k, stormMetadata) {
+				return nil
+			}
+			var n int64
+			if blocks != nil && blocks.Get(k) != nil {
+				err := s.codec.Unmarshal(blocks.Get(k), &n)
+				if err != nil {
+					return err
+				}
+			}
+			if n < blockNumber {
+				hashes = append(hashes, common.BytesToHash(k))
+			}
+			return nil
+		})
+	})
+	return
+}
+
+//DeleteAck removes ack of message `echohash` and its block number
+func (s *boltStorage) DeleteAck(echohash common.Hash) error {
+	err := s.db.Delete(bucketAck, echohash[:])
+	if err != nil {
+		return err
+	}
+	err = s.db.Delete(bucketAckBlockNumber, echohash[:])
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return err
+}
+
+//GetReceivedRevealSecret returns received reveal secret of `echohash`
+func (s *boltStorage) GetReceivedRevealSecret(echohash common.Hash) (r *ReceivedRevealSecret, err error) {
+	r = new(ReceivedRevealSecret)
+	err = s.db.One("EchoHashString", echohash.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReceivedRevealSecret save or replace a received reveal secret
+func (s *boltStorage) SaveReceivedRevealSecret(r *ReceivedRevealSecret) error {
+	return s.db.Save(r)
+}
+
+//GetUncompleteReceivedRevealSecrets finds by index IsComplete
+func (s *boltStorage) GetUncompleteReceivedRevealSecrets() (msgs []*ReceivedRevealSecret, err error) {
+	err = s.db.Find("IsComplete", "false", &msgs)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//GetSentRevealSecret returns sent reveal secret of `echohash`
+func (s *boltStorage) GetSentRevealSecret(echohash common.Hash) (r *SentRevealSecret, err error) {
+	r = new(SentRevealSecret)
+	err = s.db.One("EchoHashString", echohash.String(), r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentRevealSecret save or replace a sent reveal secret
+func (s *boltStorage) SaveSentRevealSecret(r *SentRevealSecret) error {
+	return s.db.Save(r)
+}
+
+//GetUncompleteSentRevealSecrets finds by index IsComplete
+func (s *boltStorage) GetUncompleteSentRevealSecrets() (msgs []*SentRevealSecret, err error) {
+	err = s.db.Find("IsComplete", "false", &msgs)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//GetSentRemoveExpiredHashlockTransfer returns sent message of `echohash`
+func (s *boltStorage) GetSentRemoveExpiredHashlockTransfer(echohash common.Hash) (t *SentRemoveExpiredHashlockTransfer, err error) {
+	t = new(SentRemoveExpiredHashlockTransfer)
+	err = s.db.One("EchoHashString", echohash.String(), t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentRemoveExpiredHashlockTransfer save or replace a sent message
+func (s *boltStorage) SaveSentRemoveExpiredHashlockTransfer(t *SentRemoveExpiredHashlockTransfer, tx TX) error {
+	return s.node(tx).Save(t)
+}
+
+//GetUncompleteSentRemoveExpiredHashlockTransfers finds by index IsComplete
+func (s *boltStorage) GetUncompleteSentRemoveExpiredHashlockTransfers() (msgs []*SentRemoveExpiredHashlockTransfer, err error) {
+	err = s.db.Find("IsComplete", "false", &msgs)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//GetSentTransfer returns sent transfer by key
+func (s *boltStorage) GetSentTransfer(key string) (t *SentTransfer, err error) {
+	t = new(SentTransfer)
+	err = s.db.One("Key", key, t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentTransfer save or replace a sent transfer
+func (s *boltStorage) SaveSentTransfer(t *SentTransfer) error {
+	t.setIndexes()
+	return s.db.Save(t)
+}
+
+//transferScanChunk is how many transfers are read from an index at a time when paging history
+const transferScanChunk = 256
+
+//byTimeOnly returns true if normalized `f` selects a time range but nothing indexed by block number
+func (f *TransferFilter) byTimeOnly() bool {
+	return f.FromBlock == 0 && f.ToBlock == math.MaxInt64 && f.hasTimeRange() &&
+		f.Token == utils.EmptyAddress && f.Partner == utils.EmptyAddress && f.ChannelIdentifier == utils.EmptyHash
+}
+
+/*
+scanTransfers reads transfers selected by normalized `f` from its cursor on, in order of block number then key,
+by index of the channel, partner or token of `f` if it has one, otherwise by index BlockNumber.
+`read` loads and checks a chunk of index `field`, it returns block numbers of records read and true when the page is full.
+a page costs about the records it skips, not the whole history.
+*/
+func scanTransfers(f *TransferFilter, read func(field string, min, max interface{}, skip int) (blocks []int64, full bool, err error)) error {
+	field, value := "BlockNumber", ""
+	switch {
+	case f.ChannelIdentifier != utils.EmptyHash:
+		field, value = "ChannelIndex", f.ChannelIdentifier.String()
+	case f.Partner != utils.EmptyAddress:
+		field, value = "PartnerIndex", f.Partner.String()
+	case f.Token != utils.EmptyAddress:
+		field, value = "TokenIndex", f.Token.String()
+	}
+	indexAt := func(blockNumber int64) interface{} {
+		if len(value) == 0 {
+			return blockNumber
+		}
+		return transferIndexKey(value, blockNumber)
+	}
+	from := f.FromBlock
+	if f.cursorBlock > from {
+		from = f.cursorBlock
+	}
+	//records of block `from` already read
+	skip := 0
+	for {
+		blocks, full, err := read(field, indexAt(from), indexAt(f.ToBlock), skip)
+		if err == storm.ErrNotFound {
+			return nil
+		}
+		if err != nil || full || len(blocks) < transferScanChunk {
+			return err
+		}
+		last := blocks[len(blocks)-1]
+		if last != from {
+			from, skip = last, 0
+		}
+		for _, b := range blocks {
+			if b == last {
+				skip++
+			}
+		}
+	}
+}
+
+//FindSentTransfers pages by index, only a time range is loaded at once and sorted in memory
+func (s *boltStorage) FindSentTransfers(f *TransferFilter) (transfers []*SentTransfer, err error) {
+	if f.byTimeOnly() {
+		var all []*SentTransfer
+		err = s.db.Range("Time", f.FromTime, f.ToTime, &all)
+		if err == storm.ErrNotFound {
+			err = nil
+		}
+		for _, t := range all {
+			if f.matchSent(t) {
+				transfers = append(transfers, t)
+			}
+		}
+		sort.Slice(transfers, func(i, j int) bool {
+			return transfers[i].BlockNumber < transfers[j].BlockNumber ||
+				(transfers[i].BlockNumber == transfers[j].BlockNumber && transfers[i].Key < transfers[j].Key)
+		})
+		if f.Limit > 0 && len(transfers) > f.Limit {
+			transfers = transfers[:f.Limit]
+		}
+		return
+	}
+	err = scanTransfers(f, func(field string, min, max interface{}, skip int) (blocks []int64, full bool, err error) {
+		var chunk []*SentTransfer
+		err = s.db.Range(field, min, max, &chunk, storm.Skip(skip), storm.Limit(transferScanChunk))
+		for _, t := range chunk {
+			blocks = append(blocks, t.BlockNumber)
+			if f.matchSent(t) {
+				transfers = append(transfers, t)
+				if f.Limit > 0 && len(transfers) >= f.Limit {
+					return blocks, true, err
+				}
+			}
+		}
+		return
+	})
+	return
+}
+
+//GetReceivedTransfer returns received transfer by key
+func (s *boltStorage) GetReceivedTransfer(key string) (t *ReceivedTransfer, err error) {
+	t = new(ReceivedTransfer)
+	err = s.db.One("Key", key, t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReceivedTransfer save or replace a received transfer
+func (s *boltStorage) SaveReceivedTransfer(t *ReceivedTransfer) error {
+	t.setIndexes()
+	return s.db.Save(t)
+}
+
+//FindReceivedTransfers pages by index, only a time range is loaded at once and sorted in memory
+func (s *boltStorage) FindReceivedTransfers(f *TransferFilter) (transfers []*ReceivedTransfer, err error) {
+	if f.byTimeOnly() {
+		var all []*ReceivedTransfer
+		err = s.db.Range("Time", f.FromTime, f.ToTime, &all)
+		if err == storm.ErrNotFound {
+			err = nil
+		}
+		for _, t := range all {
+			if f.matchReceived(t) {
+				transfers = append(transfers, t)
+			}
+		}
+		sort.Slice(transfers, func(i, j int) bool {
+			return transfers[i].BlockNumber < transfers[j].BlockNumber ||
+				(transfers[i].BlockNumber == transfers[j].BlockNumber && transfers[i].Key < transfers[j].Key)
+		})
+		if f.Limit > 0 && len(transfers) > f.Limit {
+			transfers = transfers[:f.Limit]
+		}
+		return
+	}
+	err = scanTransfers(f, func(field string, min, max interface{}, skip int) (blocks []int64, full bool, err error) {
+		var chunk []*ReceivedTransfer
+		err = s.db.Range(field, min, max, &chunk, storm.Skip(skip), storm.Limit(transferScanChunk))
+		for _, t := range chunk {
+			blocks = append(blocks, t.BlockNumber)
+			if f.matchReceived(t) {
+				transfers = append(transfers, t)
+				if f.Limit > 0 && len(transfers) >= f.Limit {
+					return blocks, true, err
+				}
+			}
+		}
+		return
+	})
+	return
+}
+
+//SaveContractEvent save or replace a contract event, and index its channel by participants in the same transaction if it opens one
+func (s *boltStorage) SaveContractEvent(e *ContractEvent) (err error) {
+	tx, err := s.db.Begin(true)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+	err = tx.Save(e)
+	if err != nil {
+		return
+	}
+	for _, pc := range e.participantChannels() {
+		err = tx.Save(pc)
+		if err != nil {
+			return
+		}
+	}
+	return tx.Commit()
+}
+
+//FindParticipantChannels loads by index Participant
+func (s *boltStorage) FindParticipantChannels(participant, contract common.Address) (channels []common.Hash, err error) {
+	var pcs []*ParticipantChannel
+	err = s.db.Find("Participant", participant.String(), &pcs)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, pc := range pcs {
+		if contract == utils.EmptyAddress || contract == pc.Contract {
+			channels = append(channels, pc.ChannelIdentifier)
+		}
+	}
+	return
+}
+
+//FindContractEvents loads by index BlockNumber, then filters and sorts in memory
+func (s *boltStorage) FindContractEvents(f *ContractEventFilter) (events []*ContractEvent, err error) {
+	var all []*ContractEvent
+	err = s.db.Range("BlockNumber", f.FromBlock, f.ToBlock, &all)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, e := range all {
+		if f.match(e) {
+			events = append(events, e)
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].BlockNumber < events[j].BlockNumber ||
+			(events[i].BlockNumber == events[j].BlockNumber && events[i].LogIndex < events[j].LogIndex)
+	})
+	return
+}
+
+//SaveSettledChannel save a settled channel in bucketSettledChannel
+func (s *boltStorage) SaveSettledChannel(c *channeltype.Serialization) error {
+	return s.db.Set(bucketSettledChannel, settledChannelKey(c.ChannelIdentifier.ChannelIdentifier, c.ChannelIdentifier.OpenBlockNumber), c)
+}
+
+//GetSettledChannel returns a settled channel
+func (s *boltStorage) GetSettledChannel(channelIdentifier common.Hash, openBlockNumber int64) (c *channeltype.Serialization, err error) {
+	c = new(channeltype.Serialization)
+	err = s.db.Get(bucketSettledChannel, settledChannelKey(channelIdentifier, openBlockNumber), c)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllSettledChannels decodes every value of bucketSettledChannel
+func (s *boltStorage) GetAllSettledChannels() (chs []*channeltype.Serialization, err error) {
+	err = s.db.Bolt.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(bucketSettledChannel))
+		if b == nil {
+			return nil
+		}
+		return b.ForEach(func(k, v []byte) error {
+			if v == nil || string(k) == string(stormMetadata) {
+				return nil
+			}
+			var c channeltype.Serialization
+			err := s.codec.Unmarshal(v, &c)
+			if err != nil {
+				return fmt.Errorf("settled channel %s err %s", string(k), err)
+			}
+			chs = append(chs, &c)
+			return nil
+		})
+	})
+	return
+}
+
+//AddNonParticipantChannel adds a channel to the map of `tokenNetwork`, which is saved as a whole
+func (s *boltStorage) AddNonParticipantChannel(tokenNetwork common.Address, channel common.Hash, participants []byte) error {
+	m, err := s.GetNonParticipantChannels(tokenNetwork)
+	if err == storm.ErrNotFound {
+		m = make(ChannelParticipantMap)
+	} else if err != nil {
+		return err
+	}
+	m[channel] = participants
+	return s.db.Set(bucketChannel, tokenNetwork[:], m)
+}
+
+//RemoveNonParticipantChannel removes a channel from the map of `tokenNetwork`
+func (s *boltStorage) RemoveNonParticipantChannel(tokenNetwork common.Address, channel common.Hash) error {
+	m, err := s.GetNonParticipantChannels(tokenNetwork)
+	if err != nil {
+		return err
+	}
+	delete(m, channel)
+	return s.db.Set(bucketChannel, tokenNetwork[:], m)
+}
+
+//GetNonParticipantChannels returns the map of `tokenNetwork`
+func (s *boltStorage) GetNonParticipantChannels(tokenNetwork common.Address) (m ChannelParticipantMap, err error) {
+	err = s.db.Get(bucketChannel, tokenNetwork[:], &m)
+	return
+}
+
+//SaveAPIToken save or replace an api token
+func (s *boltStorage) SaveAPIToken(t *APIToken) error {
+	return s.db.Save(t)
+}
+
+//GetAPIToken returns api token by name
+func (s *boltStorage) GetAPIToken(name string) (t *APIToken, err error) {
+	t = new(APIToken)
+	err = s.db.One("Name", name, t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAPITokenByHash finds api token by unique index TokenHash
+func (s *boltStorage) GetAPITokenByHash(tokenHash string) (t *APIToken, err error) {
+	t = new(APIToken)
+	err = s.db.One("TokenHash", tokenHash, t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllAPITokens returns all api tokens
+func (s *boltStorage) GetAllAPITokens() (ts []*APIToken, err error) {
+	err = s.db.All(&ts)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//DeleteAPIToken removes an api token
+func (s *boltStorage) DeleteAPIToken(t *APIToken) error {
+	return s.db.DeleteStruct(t)
+}
+
+//SaveSettleJob save or replace a settle job
+func (s *boltStorage) SaveSettleJob(j *SettleJob) error {
+	return s.db.Save(j)
+}
+
+//GetSettleJob returns settle job of a channel
+func (s *boltStorage) GetSettleJob(channelIdentifier common.Hash) (j *SettleJob, err error) {
+	j = new(SettleJob)
+	err = s.db.One("Key", channelIdentifier.String(), j)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllSettleJobs returns all settle jobs
+func (s *boltStorage) GetAllSettleJobs() (js []*SettleJob, err error) {
+	err = s.db.All(&js)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//DeleteSettleJob removes a settle job
+func (s *boltStorage) DeleteSettleJob(j *SettleJob) error {
+	return s.db.DeleteStruct(j)
+}
+
+//SaveUnlockOutcome save or replace an unlock outcome
+func (s *boltStorage) SaveUnlockOutcome(o *channeltype.UnlockOutcome) error {
+	return s.db.Save(o)
+}
+
+//GetUnlockOutcomes filters all unlock outcomes by channel
+func (s *boltStorage) GetUnlockOutcomes(channelIdentifier common.Hash) (outcomes []*channeltype.UnlockOutcome, err error) {
+	var all []*channeltype.UnlockOutcome
+	err = s.db.All(&all)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	if err != nil {
+		return
+	}
+	for _, o := range all {
+		if o.ChannelIdentifier == channelIdentifier {
+			outcomes = append(outcomes, o)
+		}
+	}
+	return
+}
+
+//SaveNodeEndpoint save or replace an endpoint
+func (s *boltStorage) SaveNodeEndpoint(e *NodeEndpoint) error {
+	return s.db.Save(e)
+}
+
+//GetNodeEndpoint returns endpoint of `addr`
+func (s *boltStorage) GetNodeEndpoint(addr common.Address) (e *NodeEndpoint, err error) {
+	e = new(NodeEndpoint)
+	err = s.db.One("Key", addr.String(), e)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllNodeEndpoints returns all endpoints, expired or not
+func (s *boltStorage) GetAllNodeEndpoints() (es []*NodeEndpoint, err error) {
+	err = s.db.All(&es)
+	if err == storm.ErrNotFound {
+		err = nil
+	}
+	return
+}
+
+//DeleteNodeEndpoint removes an endpoint
+func (s *boltStorage) DeleteNodeEndpoint(e *NodeEndpoint) error {
+	return s.db.DeleteStruct(e)
+}
+
+//SaveSentAnnounceDisposed save or replace a SentAnnounceDisposed
+func (s *boltStorage) SaveSentAnnounceDisposed(sad *SentAnnounceDisposed) error {
+	return s.db.Save(sad)
+}
+
+//GetSentAnnounceDisposed returns SentAnnounceDisposed by key
+func (s *boltStorage) GetSentAnnounceDisposed(key []byte) (sad *SentAnnounceDisposed, err error) {
+	sad = new(SentAnnounceDisposed)
+	err = s.db.One("Key", key, sad)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//FindSentAnnounceDisposed finds SentAnnounceDisposed by index LockSecretHash
+func (s *boltStorage) FindSentAnnounceDisposed(lockSecretHash common.Hash) (sad *SentAnnounceDisposed, err error) {
+	sad = new(SentAnnounceDisposed)
+	err = s.db.One("LockSecretHash", lockSecretHash[:], sad)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReceivedAnnounceDisposed save or replace a ReceivedAnnounceDisposed
+func (s *boltStorage) SaveReceivedAnnounceDisposed(r *ReceivedAnnounceDisposed) error {
+	return s.db.Save(r)
+}
+
+//GetReceivedAnnounceDisposed returns ReceivedAnnounceDisposed by key
+func (s *boltStorage) GetReceivedAnnounceDisposed(key []byte) (r *ReceivedAnnounceDisposed, err error) {
+	r = new(ReceivedAnnounceDisposed)
+	err = s.db.One("Key", key, r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+/*
+compactBolt copies every bucket of db at `dbPath` to a new file and replaces db by it,
+bolt never shrinks its file, pages of deleted records are only reused.
+*/
+func compactBolt(dbPath string) (err error) {
+	src, err := bolt.Open(dbPath, 0600, &bolt.Options{Timeout: 1 * time.Second, ReadOnly: true})
+	if err != nil {
+		return
+	}
+	defer src.Close()
+	tmp := dbPath + ".compact"
+	os.Remove(tmp)
+	dst, err := bolt.Open(tmp, 0600, &bolt.Options{Timeout: 1 * time.Second})
+	if err != nil {
+		return
+	}
+	err = src.View(func(stx *bolt.Tx) error {
+		return dst.Update(func(dtx *bolt.Tx) error {
+			return stx.ForEach(func(name []byte, b *bolt.Bucket) error {
+				nb, err := dtx.CreateBucket(name)
+				if err != nil {
+					return err
+				}
+				return copyBoltBucket(nb, b)
+			})
+		})
+	})
+	err2 := dst.Close()
+	if err == nil {
+		err = err2
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+//copyBoltBucket copies keys and nested buckets of `from` to `to`
+func copyBoltBucket(to, from *bolt.Bucket) error {
+	//keys are copied in order, so pages can be filled up
+	to.FillPercent = 1.0
+	err := to.SetSequence(from.Sequence())
+	if err != nil {
+		return err
+	}
+	return from.ForEach(func(k, v []byte) error {
+		if v != nil {
+			return to.Put(k, v)
+		}
+		nb, err := to.CreateBucket(k)
+		if err != nil {
+			return err
+		}
+		return copyBoltBucket(nb, from.Bucket(k))
+	})
+}
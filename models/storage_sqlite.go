@@ -0,0 +1,1247 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	_ "github.com/mattn/go-sqlite3" //sqlite3 driver of database/sql
+)
+
+/*
+sqliteSchema is tables of sqliteStorage.
+channels, transfers and the other records queried by more than their key have their own tables,
+records only queried by key, and at most one index, are kept in table records, by bucket.
+like bolt, only value columns are encoded by codec, key and index columns are saved as raw bytes.
+*/
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS channels (
+	channel_identifier BLOB PRIMARY KEY,
+	token BLOB NOT NULL,
+	partner BLOB NOT NULL,
+	value BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS channels_token ON channels (token);
+CREATE INDEX IF NOT EXISTS channels_partner ON channels (partner);
+CREATE TABLE IF NOT EXISTS settled_channels (
+	channel_identifier BLOB NOT NULL,
+	open_block_number INTEGER NOT NULL,
+	value BLOB NOT NULL,
+	PRIMARY KEY (channel_identifier, open_block_number)
+);
+CREATE TABLE IF NOT EXISTS tokens (
+	token BLOB PRIMARY KEY,
+	token_network BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS nonparticipant_channels (
+	token BLOB NOT NULL,
+	channel_identifier BLOB NOT NULL,
+	participants BLOB NOT NULL,
+	PRIMARY KEY (token, channel_identifier)
+);
+CREATE TABLE IF NOT EXISTS state_managers (
+	id INTEGER PRIMARY KEY,
+	value BLOB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS sent_transfers (
+	id TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	value BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS sent_transfers_block_number ON sent_transfers (block_number);
+CREATE TABLE IF NOT EXISTS received_transfers (
+	id TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	value BLOB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS received_transfers_block_number ON received_transfers (block_number);
+CREATE TABLE IF NOT EXISTS records (
+	bucket TEXT NOT NULL,
+	id BLOB NOT NULL,
+	idx BLOB,
+	value BLOB NOT NULL,
+	PRIMARY KEY (bucket, id)
+);
+CREATE INDEX IF NOT EXISTS records_idx ON records (bucket, idx);
+CREATE TABLE IF NOT EXISTS encryption (
+	name TEXT PRIMARY KEY,
+	value BLOB NOT NULL
+);
+`
+
+/*
+sqliteMigrations[i] upgrades sqlite db from version i+2 to i+3, it runs in a transaction like migrations of bolt db.
+sqliteSchema is the schema of version 2, so a new db runs all of them too.
+*/
+var sqliteMigrations = []func(s *sqliteStorage, tx TX) error{
+	(*sqliteStorage).migrateV2ToV3,
+	(*sqliteStorage).migrateV3ToV4,
+	(*sqliteStorage).migrateV4ToV5,
+	(*sqliteStorage).migrateV5ToV6,
+	(*sqliteStorage).migrateV6ToV7,
+}
+
+//sqliteValueTables are tables with a value column encoded by codec
+var sqliteValueTables = []string{"channels", "settled_channels", "state_managers", "sent_transfers", "received_transfers", "contract_events", "records"}
+
+//buckets in table records, besides buckets of KeyValueStorage
+const (
+	recordReceivedRevealSecret              = "ReceivedRevealSecret"
+	recordSentRevealSecret                  = "SentRevealSecret"
+	recordSentRemoveExpiredHashlockTransfer = "SentRemoveExpiredHashlockTransfer"
+	recordAPIToken                          = "APIToken"
+	recordSettleJob                         = "SettleJob"
+	recordUnlockOutcome                     = "UnlockOutcome"
+	recordNodeEndpoint                      = "NodeEndpoint"
+	recordSentAnnounceDisposed              = "SentAnnounceDisposed"
+	recordReceivedAnnounceDisposed          = "ReceivedAnnounceDisposed"
+)
+
+/*
+sqliteStorage saves records in a sqlite db.
+there is only one connection, so a transaction blocks others like bolt's writable transaction does.
+*/
+type sqliteStorage struct {
+	db    *sql.DB
+	codec *cryptCodec
+}
+
+//sqlConn is *sql.DB or *sql.Tx
+type sqlConn interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func openSQLiteStorage(dbPath string, c *cryptCodec) (s *sqliteStorage, err error) {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=1000&_txlock=immediate")
+	if err != nil {
+		return
+	}
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(sqliteSchema)
+	if err != nil {
+		db.Close()
+		return
+	}
+	return &sqliteStorage{db: db, codec: c}, nil
+}
+
+//conn returns `tx`, or db when there is no tx
+func (s *sqliteStorage) conn(tx TX) sqlConn {
+	if tx == nil {
+		return s.db
+	}
+	return tx.(*sql.Tx)
+}
+
+//inTx runs `fn` in a new transaction
+func (s *sqliteStorage) inTx(fn func(tx TX) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	err = fn(tx)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+//getValue decodes value of the only row of `query` to `to`
+func (s *sqliteStorage) getValue(to interface{}, query string, args ...interface{}) error {
+	var data []byte
+	err := s.db.QueryRow(query, args...).Scan(&data)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.codec.Unmarshal(data, to)
+}
+
+//getValues decodes values of all rows of `query` and appends them to `to`, which must be a pointer to slice of pointers
+func (s *sqliteStorage) getValues(to interface{}, query string, args ...interface{}) error {
+	return s.queryValues(s.db, to, query, args...)
+}
+
+//queryValues is getValues on `conn`
+func (s *sqliteStorage) queryValues(conn sqlConn, to interface{}, query string, args ...interface{}) error {
+	rows, err := conn.Query(query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	slice := reflect.ValueOf(to).Elem()
+	elemType := slice.Type().Elem().Elem()
+	for rows.Next() {
+		var data []byte
+		err = rows.Scan(&data)
+		if err != nil {
+			return err
+		}
+		v := reflect.New(elemType)
+		err = s.codec.Unmarshal(data, v.Interface())
+		if err != nil {
+			return err
+		}
+		slice.Set(reflect.Append(slice, v))
+	}
+	return rows.Err()
+}
+
+func (s *sqliteStorage) putRecord(tx TX, bucket string, id, idx []byte, value interface{}) error {
+	data, err := s.codec.Marshal(value)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn(tx).Exec("INSERT OR REPLACE INTO records (bucket, id, idx, value) VALUES (?, ?, ?, ?)", bucket, id, idx, data)
+	return err
+}
+
+func (s *sqliteStorage) getRecord(bucket string, id []byte, to interface{}) error {
+	return s.getValue(to, "SELECT value FROM records WHERE bucket = ? AND id = ?", bucket, id)
+}
+
+//getRecordTx is getRecord on `conn`, for reading in a transaction
+func (s *sqliteStorage) getRecordTx(conn sqlConn, bucket string, id []byte, to interface{}) error {
+	var data []byte
+	err := conn.QueryRow("SELECT value FROM records WHERE bucket = ? AND id = ?", bucket, id).Scan(&data)
+	if err == sql.ErrNoRows {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return s.codec.Unmarshal(data, to)
+}
+
+func (s *sqliteStorage) findRecords(bucket string, idx []byte, to interface{}) error {
+	return s.getValues(to, "SELECT value FROM records WHERE bucket = ? AND idx = ? ORDER BY id", bucket, idx)
+}
+
+func (s *sqliteStorage) allRecords(bucket string, to interface{}) error {
+	return s.getValues(to, "SELECT value FROM records WHERE bucket = ? ORDER BY id", bucket)
+}
+
+func (s *sqliteStorage) deleteRecord(bucket string, id []byte) error {
+	_, err := s.db.Exec("DELETE FROM records WHERE bucket = ? AND id = ?", bucket, id)
+	return err
+}
+
+//blockNumberIndex encodes `blockNumber` as index of table records, big endian bytes are compared in order like numbers
+func blockNumberIndex(blockNumber int64) []byte {
+	idx := make([]byte, 8)
+	binary.BigEndian.PutUint64(idx, uint64(blockNumber))
+	return idx
+}
+
+//recordID converts key of KeyValueStorage to id of table records
+func recordID(key interface{}) ([]byte, error) {
+	switch k := key.(type) {
+	case []byte:
+		return k, nil
+	case string:
+		return []byte(k), nil
+	}
+	return nil, fmt.Errorf("key of type %T is not supported", key)
+}
+
+//Backend of sqlite db
+func (s *sqliteStorage) Backend() string {
+	return BackendSQLite
+}
+
+//Begin starts a transaction, which takes the write lock of db immediately
+func (s *sqliteStorage) Begin() (TX, error) {
+	return s.db.Begin()
+}
+
+//Init brings tables created when opening up to date
+func (s *sqliteStorage) Init() error {
+	return s.inTx(func(tx TX) error {
+		for _, m := range sqliteMigrations {
+			err := m(s, tx)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+//MigrateStep runs sqliteMigrations[ver-2], sqlite db is never created before version 2
+func (s *sqliteStorage) MigrateStep(ver int) error {
+	if ver < 2 {
+		return fmt.Errorf("no migration of sqlite db from version %d", ver)
+	}
+	return s.inTx(func(tx TX) error {
+		err := sqliteMigrations[ver-2](s, tx)
+		if err != nil {
+			return err
+		}
+		return s.putRecord(tx, bucketMeta, []byte("version"), nil, ver+1)
+	})
+}
+
+/*
+migrateV2ToV3 adds columns of transfers for filtering history, with their indexes,
+and fills them from values.
+*/
+func (s *sqliteStorage) migrateV2ToV3(tx TX) error {
+	conn := s.conn(tx)
+	for _, table := range []string{"sent_transfers", "received_transfers"} {
+		_, err := conn.Exec(fmt.Sprintf(`
+ALTER TABLE %[1]s ADD COLUMN token BLOB;
+ALTER TABLE %[1]s ADD COLUMN partner BLOB;
+ALTER TABLE %[1]s ADD COLUMN channel_identifier BLOB;
+ALTER TABLE %[1]s ADD COLUMN time INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX %[1]s_token ON %[1]s (token, block_number);
+CREATE INDEX %[1]s_partner ON %[1]s (partner, block_number);
+CREATE INDEX %[1]s_channel_identifier ON %[1]s (channel_identifier, block_number);
+CREATE INDEX %[1]s_time ON %[1]s (time);
+`, table))
+		if err != nil {
+			return err
+		}
+	}
+	var sent []*SentTransfer
+	err := s.queryValues(conn, &sent, "SELECT value FROM sent_transfers")
+	if err != nil {
+		return err
+	}
+	for _, t := range sent {
+		err = s.saveSentTransfer(t, tx)
+		if err != nil {
+			return err
+		}
+	}
+	var received []*ReceivedTransfer
+	err = s.queryValues(conn, &received, "SELECT value FROM received_transfers")
+	if err != nil {
+		return err
+	}
+	for _, t := range received {
+		err = s.saveReceivedTransfer(t, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateV3ToV4 creates table of contract events
+func (s *sqliteStorage) migrateV3ToV4(tx TX) error {
+	_, err := s.conn(tx).Exec(`
+CREATE TABLE contract_events (
+	id TEXT PRIMARY KEY,
+	block_number INTEGER NOT NULL,
+	log_index INTEGER NOT NULL,
+	event_type TEXT NOT NULL,
+	contract BLOB NOT NULL,
+	channel_identifier BLOB NOT NULL,
+	value BLOB NOT NULL
+);
+CREATE INDEX contract_events_block_number ON contract_events (block_number, log_index);
+CREATE INDEX contract_events_contract ON contract_events (contract, block_number);
+CREATE INDEX contract_events_channel_identifier ON contract_events (channel_identifier, block_number);
+`)
+	return err
+}
+
+//migrateV4ToV5 changes nothing, transfers of sqlite are indexed by their columns since version 3
+func (s *sqliteStorage) migrateV4ToV5(tx TX) error {
+	return nil
+}
+
+/*
+migrateV5ToV6 namespaces tokens by registry like migrateV5ToV6 of bolt db,
+token of channels of non participants is replaced by its token network.
+*/
+func (s *sqliteStorage) migrateV5ToV6(tx TX) error {
+	conn := s.conn(tx)
+	_, err := conn.Exec(`
+ALTER TABLE nonparticipant_channels RENAME COLUMN token TO token_network;
+UPDATE nonparticipant_channels SET token_network = (SELECT token_network FROM tokens WHERE tokens.token = nonparticipant_channels.token_network)
+	WHERE token_network IN (SELECT token FROM tokens);
+`)
+	if err != nil {
+		return err
+	}
+	var registry common.Address
+	tokens := make(AddressMap)
+	tokenRegistries := make(AddressMap)
+	err = s.getRecordTx(conn, bucketMeta, []byte("registry"), &registry)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	err = s.getRecordTx(conn, bucketMeta, []byte(keyTokenRegistries), &tokenRegistries)
+	if err != nil && err != ErrNotFound {
+		return err
+	}
+	rows, err := conn.Query("SELECT token, token_network FROM tokens")
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var token, tokenNetwork []byte
+		err = rows.Scan(&token, &tokenNetwork)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		tokens[common.BytesToAddress(token)] = common.BytesToAddress(tokenNetwork)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	registries, m := registriesOfV5Tokens(tokens, tokenRegistries, registry)
+	err = s.putRecord(tx, bucketMeta, []byte(keyRegistryTokens), nil, m)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("DELETE FROM records WHERE bucket = ? AND id = ?", bucketMeta, []byte(keyTokenRegistries))
+	if err != nil {
+		return err
+	}
+	var cs []*channeltype.Serialization
+	err = s.queryValues(conn, &cs, "SELECT value FROM channels")
+	if err != nil {
+		return err
+	}
+	for _, c := range cs {
+		c.RegistryAddress = registries[c.TokenAddress()]
+		err = s.SaveChannel(c, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//migrateV6ToV7 creates table of channels by participant, and fills it from ChannelOpened events
+func (s *sqliteStorage) migrateV6ToV7(tx TX) error {
+	conn := s.conn(tx)
+	_, err := conn.Exec(`
+CREATE TABLE participant_channels (
+	participant BLOB NOT NULL,
+	channel_identifier BLOB NOT NULL,
+	contract BLOB NOT NULL,
+	PRIMARY KEY (participant, channel_identifier)
+);
+`)
+	if err != nil {
+		return err
+	}
+	var opened []*ContractEvent
+	err = s.queryValues(conn, &opened, "SELECT value FROM contract_events WHERE event_type IN (?, ?)", params.NameChannelOpened, params.NameChannelOpenedAndDeposit)
+	if err != nil {
+		return err
+	}
+	for _, e := range opened {
+		err = s.saveContractEvent(e, tx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Backup writes a snapshot of db by `VACUUM INTO`,
+the snapshot is written to a temporary file first, so `to` is either a complete db or doesn't exist.
+*/
+func (s *sqliteStorage) Backup(to string) error {
+	tmp := to + ".tmp"
+	//VACUUM INTO refuses to overwrite
+	os.Remove(tmp)
+	_, err := s.db.Exec("VACUUM INTO ?", tmp)
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, to)
+}
+
+//EncryptionKeyInfo reads salt and check value from table encryption
+func (s *sqliteStorage) EncryptionKeyInfo() (salt, check []byte, err error) {
+	err = s.db.QueryRow("SELECT value FROM encryption WHERE name = ?", string(keySalt)).Scan(&salt)
+	if err == sql.ErrNoRows {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return
+	}
+	err = s.db.QueryRow("SELECT value FROM encryption WHERE name = ?", string(keyCheck)).Scan(&check)
+	return
+}
+
+//CountRecords counts rows of every table, rows of table records are counted by bucket
+func (s *sqliteStorage) CountRecords() (counts map[string]int, err error) {
+	counts = make(map[string]int)
+	for _, table := range []string{"channels", "settled_channels", "tokens", "nonparticipant_channels", "state_managers", "sent_transfers", "received_transfers", "contract_events", "participant_channels"} {
+		var n int
+		err = s.db.QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", table)).Scan(&n)
+		if err != nil {
+			return
+		}
+		counts[table] = n
+	}
+	rows, err := s.db.Query("SELECT bucket, COUNT(*) FROM records GROUP BY bucket")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket string
+		var n int
+		err = rows.Scan(&bucket, &n)
+		if err != nil {
+			return
+		}
+		counts[bucket] = n
+	}
+	err = rows.Err()
+	return
+}
+
+//Reencrypt re-encrypts value column of every table in one transaction
+func (s *sqliteStorage) Reencrypt(oldKey, newKey, salt, check []byte) error {
+	return s.inTx(func(tx TX) error {
+		conn := s.conn(tx)
+		for _, table := range sqliteValueTables {
+			err := reencryptTable(conn, table, oldKey, newKey)
+			if err != nil {
+				return fmt.Errorf("table %s err %s", table, err)
+			}
+		}
+		_, err := conn.Exec("DELETE FROM encryption")
+		if err != nil || newKey == nil {
+			return err
+		}
+		_, err = conn.Exec("INSERT INTO encryption (name, value) VALUES (?, ?), (?, ?)", string(keySalt), salt, string(keyCheck), check)
+		return err
+	})
+}
+
+func reencryptTable(conn sqlConn, table string, oldKey, newKey []byte) error {
+	type row struct {
+		rowid int64
+		value []byte
+	}
+	var all []row
+	rows, err := conn.Query(fmt.Sprintf("SELECT rowid, value FROM %s", table))
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var r row
+		err = rows.Scan(&r.rowid, &r.value)
+		if err != nil {
+			rows.Close()
+			return err
+		}
+		all = append(all, r)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return err
+	}
+	//the only connection is busy until rows are closed
+	for _, r := range all {
+		data, err := reencryptValue(r.value, oldKey, newKey)
+		if err != nil {
+			return err
+		}
+		_, err = conn.Exec(fmt.Sprintf("UPDATE %s SET value = ? WHERE rowid = ?", table), data, r.rowid)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//Close sqlite db
+func (s *sqliteStorage) Close() error {
+	return s.db.Close()
+}
+
+//compactSQLite rebuilds db at `dbPath` by `VACUUM` to reclaim space of deleted rows
+func compactSQLite(dbPath string) error {
+	db, err := sql.Open("sqlite3", dbPath+"?_busy_timeout=1000")
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	_, err = db.Exec("VACUUM")
+	return err
+}
+
+//Get a value from bucket in table records
+func (s *sqliteStorage) Get(bucketName string, key interface{}, to interface{}) error {
+	id, err := recordID(key)
+	if err != nil {
+		return err
+	}
+	return s.getRecord(bucketName, id, to)
+}
+
+//Set a value in bucket in table records
+func (s *sqliteStorage) Set(bucketName string, key interface{}, value interface{}) error {
+	id, err := recordID(key)
+	if err != nil {
+		return err
+	}
+	return s.putRecord(nil, bucketName, id, nil, value)
+}
+
+//Delete a value from bucket in table records
+func (s *sqliteStorage) Delete(bucketName string, key interface{}) error {
+	id, err := recordID(key)
+	if err != nil {
+		return err
+	}
+	return s.deleteRecord(bucketName, id)
+}
+
+//SaveChannel save or replace a channel
+func (s *sqliteStorage) SaveChannel(c *channeltype.Serialization, tx TX) error {
+	data, err := s.codec.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn(tx).Exec("INSERT OR REPLACE INTO channels (channel_identifier, token, partner, value) VALUES (?, ?, ?, ?)",
+		c.Key, c.TokenAddressBytes, c.PartnerAddressBytes, data)
+	return err
+}
+
+//DeleteChannel removes a channel
+func (s *sqliteStorage) DeleteChannel(c *channeltype.Serialization) error {
+	_, err := s.db.Exec("DELETE FROM channels WHERE channel_identifier = ?", c.Key)
+	return err
+}
+
+//GetChannelByAddress returns channel by its identifier
+func (s *sqliteStorage) GetChannelByAddress(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c = new(channeltype.Serialization)
+	err = s.getValue(c, "SELECT value FROM channels WHERE channel_identifier = ?", channelIdentifier[:])
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetChannels queries channels by index of token and partner
+func (s *sqliteStorage) GetChannels(token, partner common.Address) (cs []*channeltype.Serialization, err error) {
+	var conds []string
+	var args []interface{}
+	if token != (common.Address{}) {
+		conds = append(conds, "token = ?")
+		args = append(args, token[:])
+	}
+	if partner != (common.Address{}) {
+		conds = append(conds, "partner = ?")
+		args = append(args, partner[:])
+	}
+	query := "SELECT value FROM channels"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	err = s.getValues(&cs, query+" ORDER BY channel_identifier", args...)
+	return
+}
+
+//GetAllTokens returns all rows of table tokens
+func (s *sqliteStorage) GetAllTokens() (tokens AddressMap, err error) {
+	rows, err := s.db.Query("SELECT token, token_network FROM tokens")
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	tokens = make(AddressMap)
+	for rows.Next() {
+		var token, tokenNetwork []byte
+		err = rows.Scan(&token, &tokenNetwork)
+		if err != nil {
+			return
+		}
+		tokens[common.BytesToAddress(token)] = common.BytesToAddress(tokenNetwork)
+	}
+	err = rows.Err()
+	return
+}
+
+//SaveToken save or replace a token
+func (s *sqliteStorage) SaveToken(token, tokenNetwork common.Address) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO tokens (token, token_network) VALUES (?, ?)", token[:], tokenNetwork[:])
+	return err
+}
+
+//GetTokenNodes returns nodes of `token`
+func (s *sqliteStorage) GetTokenNodes(token common.Address) (nodes []common.Address, err error) {
+	err = s.getRecord(bucketTokenNodes, token[:], &nodes)
+	return
+}
+
+//SaveTokenNodes replaces nodes of `token`
+func (s *sqliteStorage) SaveTokenNodes(token common.Address, nodes []common.Address) error {
+	return s.putRecord(nil, bucketTokenNodes, token[:], nil, nodes)
+}
+
+//SaveStateManager save or replace StateManager, a new one gets the largest ID plus one, like storm's increment
+func (s *sqliteStorage) SaveStateManager(mgr *transfer.StateManager, tx TX) error {
+	if tx == nil {
+		return s.inTx(func(tx TX) error {
+			return s.SaveStateManager(mgr, tx)
+		})
+	}
+	conn := s.conn(tx)
+	if mgr.ID == 0 {
+		err := conn.QueryRow("SELECT COALESCE(MAX(id), 0) + 1 FROM state_managers").Scan(&mgr.ID)
+		if err != nil {
+			return err
+		}
+	}
+	data, err := s.codec.Marshal(mgr)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Exec("INSERT OR REPLACE INTO state_managers (id, value) VALUES (?, ?)", mgr.ID, data)
+	return err
+}
+
+//GetAllStateManagers returns all StateManagers by ID
+func (s *sqliteStorage) GetAllStateManagers() (mgrs []*transfer.StateManager, err error) {
+	err = s.getValues(&mgrs, "SELECT value FROM state_managers ORDER BY id")
+	return
+}
+
+//DeleteStateManager removes a StateManager
+func (s *sqliteStorage) DeleteStateManager(mgr *transfer.StateManager) error {
+	_, err := s.db.Exec("DELETE FROM state_managers WHERE id = ?", mgr.ID)
+	return err
+}
+
+//GetAck returns ack of message `echohash`
+func (s *sqliteStorage) GetAck(echohash common.Hash) (data []byte, err error) {
+	err = s.getRecord(bucketAck, echohash[:], &data)
+	return
+}
+
+//SaveAck save ack of message `echohash`, indexed by block number
+func (s *sqliteStorage) SaveAck(echohash common.Hash, ack []byte, blockNumber int64, tx TX) error {
+	return s.putRecord(tx, bucketAck, echohash[:], blockNumberIndex(blockNumber), ack)
+}
+
+//GetAcksSavedBefore finds acks by index of block number, acks saved before block number is recorded have no index
+func (s *sqliteStorage) GetAcksSavedBefore(blockNumber int64) (hashes []common.Hash, err error) {
+	rows, err := s.db.Query("SELECT id FROM records WHERE bucket = ? AND (idx IS NULL OR idx < ?) ORDER BY id", bucketAck, blockNumberIndex(blockNumber))
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id []byte
+		err = rows.Scan(&id)
+		if err != nil {
+			return
+		}
+		hashes = append(hashes, common.BytesToHash(id))
+	}
+	err = rows.Err()
+	return
+}
+
+//DeleteAck removes ack of message `echohash`
+func (s *sqliteStorage) DeleteAck(echohash common.Hash) error {
+	return s.deleteRecord(bucketAck, echohash[:])
+}
+
+//GetReceivedRevealSecret returns received reveal secret of `echohash`
+func (s *sqliteStorage) GetReceivedRevealSecret(echohash common.Hash) (r *ReceivedRevealSecret, err error) {
+	r = new(ReceivedRevealSecret)
+	err = s.getRecord(recordReceivedRevealSecret, []byte(echohash.String()), r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReceivedRevealSecret save or replace a received reveal secret
+func (s *sqliteStorage) SaveReceivedRevealSecret(r *ReceivedRevealSecret) error {
+	return s.putRecord(nil, recordReceivedRevealSecret, []byte(r.EchoHashString), []byte(r.IsComplete), r)
+}
+
+//GetUncompleteReceivedRevealSecrets finds by index IsComplete
+func (s *sqliteStorage) GetUncompleteReceivedRevealSecrets() (msgs []*ReceivedRevealSecret, err error) {
+	err = s.findRecords(recordReceivedRevealSecret, []byte("false"), &msgs)
+	return
+}
+
+//GetSentRevealSecret returns sent reveal secret of `echohash`
+func (s *sqliteStorage) GetSentRevealSecret(echohash common.Hash) (r *SentRevealSecret, err error) {
+	r = new(SentRevealSecret)
+	err = s.getRecord(recordSentRevealSecret, []byte(echohash.String()), r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentRevealSecret save or replace a sent reveal secret
+func (s *sqliteStorage) SaveSentRevealSecret(r *SentRevealSecret) error {
+	return s.putRecord(nil, recordSentRevealSecret, []byte(r.EchoHashString), []byte(r.IsComplete), r)
+}
+
+//GetUncompleteSentRevealSecrets finds by index IsComplete
+func (s *sqliteStorage) GetUncompleteSentRevealSecrets() (msgs []*SentRevealSecret, err error) {
+	err = s.findRecords(recordSentRevealSecret, []byte("false"), &msgs)
+	return
+}
+
+//GetSentRemoveExpiredHashlockTransfer returns sent message of `echohash`
+func (s *sqliteStorage) GetSentRemoveExpiredHashlockTransfer(echohash common.Hash) (t *SentRemoveExpiredHashlockTransfer, err error) {
+	t = new(SentRemoveExpiredHashlockTransfer)
+	err = s.getRecord(recordSentRemoveExpiredHashlockTransfer, []byte(echohash.String()), t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentRemoveExpiredHashlockTransfer save or replace a sent message
+func (s *sqliteStorage) SaveSentRemoveExpiredHashlockTransfer(t *SentRemoveExpiredHashlockTransfer, tx TX) error {
+	return s.putRecord(tx, recordSentRemoveExpiredHashlockTransfer, []byte(t.EchoHashString), []byte(t.IsComplete), t)
+}
+
+//GetUncompleteSentRemoveExpiredHashlockTransfers finds by index IsComplete
+func (s *sqliteStorage) GetUncompleteSentRemoveExpiredHashlockTransfers() (msgs []*SentRemoveExpiredHashlockTransfer, err error) {
+	err = s.findRecords(recordSentRemoveExpiredHashlockTransfer, []byte("false"), &msgs)
+	return
+}
+
+//GetSentTransfer returns sent transfer by key
+func (s *sqliteStorage) GetSentTransfer(key string) (t *SentTransfer, err error) {
+	t = new(SentTransfer)
+	err = s.getValue(t, "SELECT value FROM sent_transfers WHERE id = ?", key)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveSentTransfer save or replace a sent transfer
+func (s *sqliteStorage) SaveSentTransfer(t *SentTransfer) error {
+	return s.saveSentTransfer(t, nil)
+}
+
+func (s *sqliteStorage) saveSentTransfer(t *SentTransfer, tx TX) error {
+	data, err := s.codec.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn(tx).Exec("INSERT OR REPLACE INTO sent_transfers (id, block_number, token, partner, channel_identifier, time, value) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.Key, t.BlockNumber, t.TokenAddress[:], t.ToAddress[:], t.ChannelIdentifier[:], t.Time, data)
+	return err
+}
+
+/*
+transferQuery returns query of values of `table` selected by indexed columns,
+amount is not a column, it's checked by caller.
+*/
+func transferQuery(table string, f *TransferFilter) (query string, args []interface{}) {
+	conds := []string{"block_number BETWEEN ? AND ?"}
+	args = []interface{}{f.FromBlock, f.ToBlock}
+	if f.cursorBlock >= 0 {
+		conds = append(conds, "(block_number > ? OR (block_number = ? AND id > ?))")
+		args = append(args, f.cursorBlock, f.cursorBlock, f.cursorKey)
+	}
+	if f.hasTimeRange() {
+		conds = append(conds, "time > 0 AND time BETWEEN ? AND ?")
+		args = append(args, f.FromTime, f.ToTime)
+	}
+	if f.Token != utils.EmptyAddress {
+		conds = append(conds, "token = ?")
+		args = append(args, f.Token[:])
+	}
+	if f.Partner != utils.EmptyAddress {
+		conds = append(conds, "partner = ?")
+		args = append(args, f.Partner[:])
+	}
+	if f.ChannelIdentifier != utils.EmptyHash {
+		conds = append(conds, "channel_identifier = ?")
+		args = append(args, f.ChannelIdentifier[:])
+	}
+	query = fmt.Sprintf("SELECT value FROM %s WHERE %s ORDER BY block_number, id", table, strings.Join(conds, " AND "))
+	//without amount range, every row is selected
+	if f.Limit > 0 && f.MinAmount == nil && f.MaxAmount == nil {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+	return
+}
+
+//FindSentTransfers queries by indexed columns, then checks amount
+func (s *sqliteStorage) FindSentTransfers(f *TransferFilter) (transfers []*SentTransfer, err error) {
+	var all []*SentTransfer
+	query, args := transferQuery("sent_transfers", f)
+	err = s.getValues(&all, query, args...)
+	if err != nil {
+		return
+	}
+	for _, t := range all {
+		if f.Limit > 0 && len(transfers) >= f.Limit {
+			break
+		}
+		if f.matchSent(t) {
+			transfers = append(transfers, t)
+		}
+	}
+	return
+}
+
+//GetReceivedTransfer returns received transfer by key
+func (s *sqliteStorage) GetReceivedTransfer(key string) (t *ReceivedTransfer, err error) {
+	t = new(ReceivedTransfer)
+	err = s.getValue(t, "SELECT value FROM received_transfers WHERE id = ?", key)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//SaveReceivedTransfer save or replace a received transfer
+func (s *sqliteStorage) SaveReceivedTransfer(t *ReceivedTransfer) error {
+	return s.saveReceivedTransfer(t, nil)
+}
+
+func (s *sqliteStorage) saveReceivedTransfer(t *ReceivedTransfer, tx TX) error {
+	data, err := s.codec.Marshal(t)
+	if err != nil {
+		return err
+	}
+	_, err = s.conn(tx).Exec("INSERT OR REPLACE INTO received_transfers (id, block_number, token, partner, channel_identifier, time, value) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		t.Key, t.BlockNumber, t.TokenAddress[:], t.FromAddress[:], t.ChannelIdentifier[:], t.Time, data)
+	return err
+}
+
+//FindReceivedTransfers queries by indexed columns, then checks amount
+func (s *sqliteStorage) FindReceivedTransfers(f *TransferFilter) (transfers []*ReceivedTransfer, err error) {
+	var all []*ReceivedTransfer
+	query, args := transferQuery("received_transfers", f)
+	err = s.getValues(&all, query, args...)
+	if err != nil {
+		return
+	}
+	for _, t := range all {
+		if f.Limit > 0 && len(transfers) >= f.Limit {
+			break
+		}
+		if f.matchReceived(t) {
+			transfers = append(transfers, t)
+		}
+	}
+	return
+}
+
+//SaveContractEvent save or replace a contract event, and index its channel by participants in the same transaction if it opens one
+func (s *sqliteStorage) SaveContractEvent(e *ContractEvent) error {
+	return s.inTx(func(tx TX) error {
+		return s.saveContractEvent(e, tx)
+	})
+}
+
+func (s *sqliteStorage) saveContractEvent(e *ContractEvent, tx TX) error {
+	data, err := s.codec.Marshal(e)
+	if err != nil {
+		return err
+	}
+	conn := s.conn(tx)
+	_, err = conn.Exec("INSERT OR REPLACE INTO contract_events (id, block_number, log_index, event_type, contract, channel_identifier, value) VALUES (?, ?, ?, ?, ?, ?, ?)",
+		e.Key, e.BlockNumber, e.LogIndex, e.EventType, e.Contract[:], e.ChannelIdentifier[:], data)
+	if err != nil {
+		return err
+	}
+	for _, pc := range e.participantChannels() {
+		participant := common.HexToAddress(pc.Participant)
+		_, err = conn.Exec("INSERT OR REPLACE INTO participant_channels (participant, channel_identifier, contract) VALUES (?, ?, ?)",
+			participant[:], pc.ChannelIdentifier[:], pc.Contract[:])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//FindParticipantChannels queries by primary key participant
+func (s *sqliteStorage) FindParticipantChannels(participant, contract common.Address) (channels []common.Hash, err error) {
+	query, args := "SELECT channel_identifier FROM participant_channels WHERE participant = ?", []interface{}{participant[:]}
+	if contract != utils.EmptyAddress {
+		query += " AND contract = ?"
+		args = append(args, contract[:])
+	}
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channel []byte
+		err = rows.Scan(&channel)
+		if err != nil {
+			return
+		}
+		channels = append(channels, common.BytesToHash(channel))
+	}
+	err = rows.Err()
+	return
+}
+
+//FindContractEvents queries by indexed columns, then checks participant
+func (s *sqliteStorage) FindContractEvents(f *ContractEventFilter) (events []*ContractEvent, err error) {
+	conds := []string{"block_number BETWEEN ? AND ?"}
+	args := []interface{}{f.FromBlock, f.ToBlock}
+	if len(f.EventTypes) > 0 {
+		conds = append(conds, fmt.Sprintf("event_type IN (?%s)", strings.Repeat(", ?", len(f.EventTypes)-1)))
+		for _, t := range f.EventTypes {
+			args = append(args, t)
+		}
+	}
+	if f.Contract != utils.EmptyAddress {
+		conds = append(conds, "contract = ?")
+		args = append(args, f.Contract[:])
+	}
+	if f.ChannelIdentifier != utils.EmptyHash {
+		conds = append(conds, "channel_identifier = ?")
+		args = append(args, f.ChannelIdentifier[:])
+	}
+	var all []*ContractEvent
+	err = s.getValues(&all, fmt.Sprintf("SELECT value FROM contract_events WHERE %s ORDER BY block_number, log_index", strings.Join(conds, " AND ")), args...)
+	if err != nil {
+		return
+	}
+	for _, e := range all {
+		if f.match(e) {
+			events = append(events, e)
+		}
+	}
+	return
+}
+
+//SaveSettledChannel save a settled channel
+func (s *sqliteStorage) SaveSettledChannel(c *channeltype.Serialization) error {
+	data, err := s.codec.Marshal(c)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT OR REPLACE INTO settled_channels (channel_identifier, open_block_number, value) VALUES (?, ?, ?)",
+		c.ChannelIdentifier.ChannelIdentifier[:], c.ChannelIdentifier.OpenBlockNumber, data)
+	return err
+}
+
+//GetSettledChannel returns a settled channel
+func (s *sqliteStorage) GetSettledChannel(channelIdentifier common.Hash, openBlockNumber int64) (c *channeltype.Serialization, err error) {
+	c = new(channeltype.Serialization)
+	err = s.getValue(c, "SELECT value FROM settled_channels WHERE channel_identifier = ? AND open_block_number = ?", channelIdentifier[:], openBlockNumber)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllSettledChannels returns all settled channels
+func (s *sqliteStorage) GetAllSettledChannels() (chs []*channeltype.Serialization, err error) {
+	err = s.getValues(&chs, "SELECT value FROM settled_channels ORDER BY channel_identifier, open_block_number")
+	return
+}
+
+//AddNonParticipantChannel save or replace a channel of `tokenNetwork`
+func (s *sqliteStorage) AddNonParticipantChannel(tokenNetwork common.Address, channel common.Hash, participants []byte) error {
+	_, err := s.db.Exec("INSERT OR REPLACE INTO nonparticipant_channels (token_network, channel_identifier, participants) VALUES (?, ?, ?)",
+		tokenNetwork[:], channel[:], participants)
+	return err
+}
+
+//RemoveNonParticipantChannel removes a channel of `tokenNetwork`
+func (s *sqliteStorage) RemoveNonParticipantChannel(tokenNetwork common.Address, channel common.Hash) error {
+	_, err := s.db.Exec("DELETE FROM nonparticipant_channels WHERE token_network = ? AND channel_identifier = ?", tokenNetwork[:], channel[:])
+	return err
+}
+
+//GetNonParticipantChannels returns channels of `tokenNetwork`, ErrNotFound if there is none like bolt
+func (s *sqliteStorage) GetNonParticipantChannels(tokenNetwork common.Address) (m ChannelParticipantMap, err error) {
+	rows, err := s.db.Query("SELECT channel_identifier, participants FROM nonparticipant_channels WHERE token_network = ?", tokenNetwork[:])
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	m = make(ChannelParticipantMap)
+	for rows.Next() {
+		var channel, participants []byte
+		err = rows.Scan(&channel, &participants)
+		if err != nil {
+			return
+		}
+		m[common.BytesToHash(channel)] = participants
+	}
+	err = rows.Err()
+	if err == nil && len(m) == 0 {
+		return nil, ErrNotFound
+	}
+	return
+}
+
+//SaveAPIToken save or replace an api token
+func (s *sqliteStorage) SaveAPIToken(t *APIToken) error {
+	return s.putRecord(nil, recordAPIToken, []byte(t.Name), []byte(t.TokenHash), t)
+}
+
+//GetAPIToken returns api token by name
+func (s *sqliteStorage) GetAPIToken(name string) (t *APIToken, err error) {
+	t = new(APIToken)
+	err = s.getRecord(recordAPIToken, []byte(name), t)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAPITokenByHash finds api token by index TokenHash
+func (s *sqliteStorage) GetAPITokenByHash(tokenHash string) (t *APIToken, err error) {
+	var ts []*APIToken
+	err = s.findRecords(recordAPIToken, []byte(tokenHash), &ts)
+	if err != nil {
+		return
+	}
+	if len(ts) == 0 {
+		return nil, ErrNotFound
+	}
+	return ts[0], nil
+}
+
+//GetAllAPITokens returns all api tokens
+func (s *sqliteStorage) GetAllAPITokens() (ts []*APIToken, err error) {
+	err = s.allRecords(recordAPIToken, &ts)
+	return
+}
+
+//DeleteAPIToken removes an api token
+func (s *sqliteStorage) DeleteAPIToken(t *APIToken) error {
+	return s.deleteRecord(recordAPIToken, []byte(t.Name))
+}
+
+//SaveSettleJob save or replace a settle job
+func (s *sqliteStorage) SaveSettleJob(j *SettleJob) error {
+	return s.putRecord(nil, recordSettleJob, []byte(j.Key), nil, j)
+}
+
+//GetSettleJob returns settle job of a channel
+func (s *sqliteStorage) GetSettleJob(channelIdentifier common.Hash) (j *SettleJob, err error) {
+	j = new(SettleJob)
+	err = s.getRecord(recordSettleJob, []byte(channelIdentifier.String()), j)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllSettleJobs returns all settle jobs
+func (s *sqliteStorage) GetAllSettleJobs() (js []*SettleJob, err error) {
+	err = s.allRecords(recordSettleJob, &js)
+	return
+}
+
+//DeleteSettleJob removes a settle job
+func (s *sqliteStorage) DeleteSettleJob(j *SettleJob) error {
+	return s.deleteRecord(recordSettleJob, []byte(j.Key))
+}
+
+//SaveUnlockOutcome save or replace an unlock outcome, indexed by channel
+func (s *sqliteStorage) SaveUnlockOutcome(o *channeltype.UnlockOutcome) error {
+	return s.putRecord(nil, recordUnlockOutcome, []byte(o.Key), o.ChannelIdentifier[:], o)
+}
+
+//GetUnlockOutcomes finds unlock outcomes by index of channel
+func (s *sqliteStorage) GetUnlockOutcomes(channelIdentifier common.Hash) (outcomes []*channeltype.UnlockOutcome, err error) {
+	err = s.findRecords(recordUnlockOutcome, channelIdentifier[:], &outcomes)
+	return
+}
+
+//SaveNodeEndpoint save or replace an endpoint
+func (s *sqliteStorage) SaveNodeEndpoint(e *NodeEndpoint) error {
+	return s.putRecord(nil, recordNodeEndpoint, []byte(e.Key), nil, e)
+}
+
+//GetNodeEndpoint returns endpoint of `addr`
+func (s *sqliteStorage) GetNodeEndpoint(addr common.Address) (e *NodeEndpoint, err error) {
+	e = new(NodeEndpoint)
+	err = s.getRecord(recordNodeEndpoint, []byte(addr.String()), e)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//GetAllNodeEndpoints returns all endpoints, expired or not
+func (s *sqliteStorage) GetAllNodeEndpoints() (es []*NodeEndpoint, err error) {
+	err = s.allRecords(recordNodeEndpoint, &es)
+	return
+}
+
+//DeleteNodeEndpoint removes an endpoint
+func (s *sqliteStorage) DeleteNodeEndpoint(e *NodeEndpoint) error {
+	return s.deleteRecord(recordNodeEndpoint, []byte(e.Key))
+}
+
+//SaveSentAnnounceDisposed save or replace a SentAnnounceDisposed, indexed by LockSecretHash
+func (s *sqliteStorage) SaveSentAnnounceDisposed(sad *SentAnnounceDisposed) error {
+	return s.putRecord(nil, recordSentAnnounceDisposed, sad.Key, sad.LockSecretHash, sad)
+}
+
+//GetSentAnnounceDisposed returns SentAnnounceDisposed by key
+func (s *sqliteStorage) GetSentAnnounceDisposed(key []byte) (sad *SentAnnounceDisposed, err error) {
+	sad = new(SentAnnounceDisposed)
+	err = s.getRecord(recordSentAnnounceDisposed, key, sad)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
+
+//FindSentAnnounceDisposed finds SentAnnounceDisposed by index LockSecretHash
+func (s *sqliteStorage) FindSentAnnounceDisposed(lockSecretHash common.Hash) (sad *SentAnnounceDisposed, err error) {
+	var sads []*SentAnnounceDisposed
+	err = s.findRecords(recordSentAnnounceDisposed, lockSecretHash[:], &sads)
+	if err != nil {
+		return
+	}
+	if len(sads) == 0 {
+		return nil, ErrNotFound
+	}
+	return sads[0], nil
+}
+
+//SaveReceivedAnnounceDisposed save or replace a ReceivedAnnounceDisposed
+func (s *sqliteStorage) SaveReceivedAnnounceDisposed(r *ReceivedAnnounceDisposed) error {
+	return s.putRecord(nil, recordReceivedAnnounceDisposed, r.Key, nil, r)
+}
+
+//GetReceivedAnnounceDisposed returns ReceivedAnnounceDisposed by key
+func (s *sqliteStorage) GetReceivedAnnounceDisposed(key []byte) (r *ReceivedAnnounceDisposed, err error) {
+	r = new(ReceivedAnnounceDisposed)
+	err = s.getRecord(recordReceivedAnnounceDisposed, key, r)
+	if err != nil {
+		return nil, err
+	}
+	return
+}
@@ -0,0 +1,463 @@
+package models
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+//storageTestFiles are db files of every backend, backend is chosen by extension
+var storageTestFiles = map[string]string{
+	BackendBolt:   "log.db",
+	BackendSQLite: "log.sqlite",
+}
+
+//testAllStorages runs `f` against a new db of every backend
+func testAllStorages(t *testing.T, f func(t *testing.T, model *ModelDB)) {
+	for backend, name := range storageTestFiles {
+		t.Run(backend, func(t *testing.T) {
+			dbPath := filepath.Join(filepath.Dir(tempDbPath(t)), name)
+			defer os.RemoveAll(filepath.Dir(dbPath))
+			model, err := OpenDb(dbPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer model.CloseDB()
+			assert.EqualValues(t, backend, model.Backend())
+			f(t, model)
+		})
+	}
+}
+
+func TestStorageChannel(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		c := newTestChannel(utils.NewRandomHash())
+		c2 := newTestChannel(utils.NewRandomHash())
+		c2.TokenAddressBytes = c.TokenAddressBytes
+		assert.Nil(t, model.NewChannel(c))
+		assert.Nil(t, model.NewChannel(c2))
+
+		ch, err := model.GetChannel(c.TokenAddress(), c.PartnerAddress())
+		assert.Nil(t, err)
+		assert.EqualValues(t, c.Key, ch.Key)
+		_, err = model.GetChannel(c.TokenAddress(), utils.NewRandomAddress())
+		assert.EqualValues(t, ErrNotFound, err)
+		ch, err = model.GetChannelByAddress(c2.ChannelIdentifier.ChannelIdentifier)
+		assert.Nil(t, err)
+		assert.EqualValues(t, c2.PartnerAddress(), ch.PartnerAddress())
+		_, err = model.GetChannelByAddress(utils.NewRandomHash())
+		assert.EqualValues(t, ErrNotFound, err)
+
+		cs, err := model.GetChannelList(c.TokenAddress(), utils.EmptyAddress)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 2, len(cs))
+		cs, err = model.GetChannelList(utils.EmptyAddress, c2.PartnerAddress())
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, len(cs))
+		cs, err = model.GetChannelList(utils.NewRandomAddress(), utils.EmptyAddress)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(cs))
+
+		//channel and ack are saved together
+		echohash := utils.NewRandomHash()
+		c.State = channeltype.StateClosed
+		assert.Nil(t, model.UpdateChannelAndSaveAck(c, echohash, []byte("ack")))
+		ch, err = model.GetChannelByAddress(c.ChannelIdentifier.ChannelIdentifier)
+		assert.Nil(t, err)
+		assert.EqualValues(t, channeltype.StateClosed, ch.State)
+		assert.EqualValues(t, []byte("ack"), model.GetAck(echohash))
+
+		c.State = channeltype.StateSettled
+		assert.Nil(t, model.UpdateChannelState(c))
+		_, err = model.GetChannel(c.TokenAddress(), c.PartnerAddress())
+		assert.EqualValues(t, ErrNotFound, err)
+		assert.Nil(t, model.RemoveChannel(c))
+		cs, err = model.GetChannelList(utils.EmptyAddress, utils.EmptyAddress)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, len(cs))
+
+		assert.Nil(t, model.NewSettledChannel(c))
+		ch, err = model.GetSettledChannel(c.ChannelIdentifier.ChannelIdentifier, c.ChannelIdentifier.OpenBlockNumber)
+		assert.Nil(t, err)
+		assert.EqualValues(t, c.OurKnownSecrets, ch.OurKnownSecrets)
+		_, err = model.GetSettledChannel(c.ChannelIdentifier.ChannelIdentifier, c.ChannelIdentifier.OpenBlockNumber+1)
+		assert.EqualValues(t, ErrNotFound, err)
+		cs, err = model.GetAllSettledChannel()
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, len(cs))
+	})
+}
+
+func TestStorageToken(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		tokens, err := model.GetAllTokens()
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(tokens))
+		token, tokenNetwork := utils.NewRandomAddress(), utils.NewRandomAddress()
+		assert.Nil(t, model.AddToken(token, tokenNetwork))
+		assert.Nil(t, model.AddToken(token, utils.NewRandomAddress()))
+		tokens, err = model.GetAllTokens()
+		assert.Nil(t, err)
+		assert.EqualValues(t, AddressMap{token: tokenNetwork}, tokens)
+
+		assert.EqualValues(t, 0, len(model.GetTokenNodes(token)))
+		nodes := []common.Address{utils.NewRandomAddress(), utils.NewRandomAddress()}
+		assert.Nil(t, model.UpdateTokenNodes(token, nodes))
+		assert.EqualValues(t, nodes, model.GetTokenNodes(token))
+
+		p1, p2 := utils.NewRandomAddress(), utils.NewRandomAddress()
+		channel := utils.NewRandomHash()
+		edges, err := model.GetAllNonParticipantChannel(token)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(edges))
+		assert.Nil(t, model.NewNonParticipantChannel(token, channel, p1, p2))
+		assert.Nil(t, model.NewNonParticipantChannel(token, utils.NewRandomHash(), p1, utils.NewRandomAddress()))
+		edges, err = model.GetAllNonParticipantChannel(token)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 4, len(edges))
+		assert.Nil(t, model.RemoveNonParticipantChannel(token, channel))
+		assert.NotNil(t, model.RemoveNonParticipantChannel(token, channel))
+		edges, err = model.GetAllNonParticipantChannel(token)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 2, len(edges))
+	})
+}
+
+func TestStorageStateManager(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		mgr := &transfer.StateManager{Name: "first", Identifier: utils.NewRandomHash()}
+		assert.Nil(t, model.AddStateManager(mgr))
+		assert.EqualValues(t, 1, mgr.ID)
+		mgr2 := &transfer.StateManager{Name: "second"}
+		assert.Nil(t, model.AddStateManager(mgr2))
+		assert.EqualValues(t, 2, mgr2.ID)
+
+		tx := model.StartTx()
+		mgr.ManagerState = "finished"
+		assert.Nil(t, model.UpdateStateManaer(mgr, tx))
+		assert.Nil(t, tx.Rollback())
+		mgrs := model.GetAllStateManager()
+		assert.EqualValues(t, 2, len(mgrs))
+		assert.EqualValues(t, "", mgrs[0].ManagerState)
+
+		tx = model.StartTx()
+		assert.Nil(t, model.UpdateStateManaer(mgr, tx))
+		assert.Nil(t, tx.Commit())
+		mgrs = model.GetAllStateManager()
+		assert.EqualValues(t, "finished", mgrs[0].ManagerState)
+		assert.EqualValues(t, mgr.Identifier, mgrs[0].Identifier)
+	})
+}
+
+func TestStorageMessages(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		echohash := utils.NewRandomHash()
+		assert.EqualValues(t, 0, len(model.GetAck(echohash)))
+		model.SaveAckNoTx(echohash, []byte("ack"))
+		assert.EqualValues(t, []byte("ack"), model.GetAck(echohash))
+
+		rs := NewReceivedRevealSecret(encoding.NewRevealSecret(utils.NewRandomHash()), echohash)
+		assert.EqualValues(t, false, model.IsReceivedRevealSecretExist(echohash))
+		model.NewReceivedRevealSecret(rs)
+		assert.EqualValues(t, true, model.IsReceivedRevealSecretExist(echohash))
+		assert.EqualValues(t, 1, len(model.GetAllUncompleteReceivedRevealSecret()))
+		model.UpdateReceivedRevealSecretComplete(echohash)
+		assert.EqualValues(t, 0, len(model.GetAllUncompleteReceivedRevealSecret()))
+
+		ss := NewSentRevealSecret(encoding.NewRevealSecret(utils.NewRandomHash()), utils.NewRandomAddress())
+		model.NewSentRevealSecret(ss)
+		assert.EqualValues(t, true, model.IsSentRevealSecretExist(ss.EchoHash))
+		msgs := model.GetAllUncompleteSentRevealSecret()
+		assert.EqualValues(t, 1, len(msgs))
+		assert.EqualValues(t, ss.Message.LockSecret, msgs[0].Message.LockSecret)
+		model.UpdateSentRevealSecretComplete(ss.EchoHash)
+		assert.EqualValues(t, 0, len(model.GetAllUncompleteSentRevealSecret()))
+
+		bp := encoding.NewBalanceProof(1, big.NewInt(10), utils.NewRandomHash(), &contracts.ChannelUniqueID{ChannelIdentifier: utils.NewRandomHash()})
+		msg := encoding.NewRemoveExpiredHashlockTransfer(bp, utils.NewRandomHash())
+		receiver := utils.NewRandomAddress()
+		tx := model.StartTx()
+		model.NewSentRemoveExpiredHashlockTransfer(msg, receiver, tx)
+		assert.Nil(t, tx.Commit())
+		trs := model.GetAllUncompleteSentRemoveExpiredHashlockTransfer()
+		assert.EqualValues(t, 1, len(trs))
+		assert.EqualValues(t, true, model.IsSentRemoveExpiredHashlockTransferExist(trs[0].EchoHash))
+		model.UpdateSentRemoveExpiredHashlockTransfer(trs[0].EchoHash)
+		assert.EqualValues(t, 0, len(model.GetAllUncompleteSentRemoveExpiredHashlockTransfer()))
+	})
+}
+
+func TestStorageTransfer(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		channel := utils.NewRandomHash()
+		for i := int64(1); i <= 3; i++ {
+			model.NewSentTransfer(i*10, channel, utils.NewRandomAddress(), utils.NewRandomAddress(), i, big.NewInt(i))
+			model.NewReceivedTransfer(i*10, channel, utils.NewRandomAddress(), utils.NewRandomAddress(), i, big.NewInt(i))
+		}
+		st, err := model.GetSentTransfer(fmt.Sprintf("%s-%d", channel.String(), 2))
+		assert.Nil(t, err)
+		assert.EqualValues(t, big.NewInt(2), st.Amount)
+		_, err = model.GetSentTransfer(fmt.Sprintf("%s-%d", channel.String(), 4))
+		assert.EqualValues(t, ErrNotFound, err)
+		sts, err := model.GetSentTransferInBlockRange(15, 30)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 2, len(sts))
+		assert.EqualValues(t, 20, sts[0].BlockNumber)
+		rts, err := model.GetReceivedTransferInBlockRange(-1, -1)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 3, len(rts))
+		rt, err := model.GetReceivedTransfer(fmt.Sprintf("%s-%d", channel.String(), 3))
+		assert.Nil(t, err)
+		assert.EqualValues(t, 30, rt.BlockNumber)
+	})
+}
+
+func TestStorageFindTransfers(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		token := utils.NewRandomAddress()
+		partner := utils.NewRandomAddress()
+		channel := utils.NewRandomHash()
+		for i := int64(1); i <= 5; i++ {
+			model.NewSentTransfer(i*10, channel, token, partner, i, big.NewInt(i*100))
+			model.NewReceivedTransfer(i*10, channel, token, partner, i, big.NewInt(i*100))
+		}
+		other := utils.NewRandomHash()
+		model.NewSentTransfer(30, other, utils.NewRandomAddress(), utils.NewRandomAddress(), 1, big.NewInt(300))
+		//saved before time is recorded
+		assert.Nil(t, model.storage.SaveSentTransfer(&SentTransfer{Key: "old", BlockNumber: 5, TokenAddress: token, Amount: big.NewInt(1)}))
+
+		sts, next, err := model.FindSentTransfers(&TransferFilter{Token: token})
+		assert.Nil(t, err)
+		assert.EqualValues(t, "", next)
+		assert.EqualValues(t, 6, len(sts))
+		sts, _, err = model.FindSentTransfers(&TransferFilter{Token: token, FromTime: 1})
+		assert.Nil(t, err)
+		assert.EqualValues(t, 5, len(sts))
+		sts, _, err = model.FindSentTransfers(&TransferFilter{Partner: partner, MinAmount: big.NewInt(200), MaxAmount: big.NewInt(400)})
+		assert.Nil(t, err)
+		if assert.EqualValues(t, 3, len(sts)) {
+			assert.EqualValues(t, 20, sts[0].BlockNumber)
+			assert.EqualValues(t, 40, sts[2].BlockNumber)
+		}
+		rts, _, err := model.FindReceivedTransfers(&TransferFilter{ChannelIdentifier: channel, FromBlock: 20, ToBlock: 30})
+		assert.Nil(t, err)
+		assert.EqualValues(t, 2, len(rts))
+		rts, _, err = model.FindReceivedTransfers(&TransferFilter{FromTime: time.Now().Add(time.Hour).Unix()})
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(rts))
+
+		//pages of 2 by cursor, transfers in the same block are ordered by key
+		var keys []string
+		f := &TransferFilter{FromBlock: 20, Limit: 2}
+		for i := 0; i < 10; i++ {
+			sts, next, err = model.FindSentTransfers(f)
+			assert.Nil(t, err)
+			for _, st := range sts {
+				keys = append(keys, st.Key)
+			}
+			if next == "" {
+				break
+			}
+			f.Cursor = next
+		}
+		assert.EqualValues(t, 5, len(keys))
+		for i := 1; i < len(keys); i++ {
+			assert.NotEqual(t, keys[i-1], keys[i])
+		}
+		rts, next, err = model.FindReceivedTransfers(&TransferFilter{MinAmount: big.NewInt(200), Limit: 4})
+		assert.Nil(t, err)
+		assert.EqualValues(t, 4, len(rts))
+		assert.EqualValues(t, "", next)
+		_, _, err = model.FindReceivedTransfers(&TransferFilter{Cursor: "bad"})
+		assert.NotNil(t, err)
+	})
+}
+
+//TestStorageFindTransfersPages pages more transfers than one chunk of index, many of them in the same block
+func TestStorageFindTransfersPages(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		tokens := []common.Address{utils.NewRandomAddress(), utils.NewRandomAddress()}
+		channel := utils.NewRandomHash()
+		var want []string
+		n := transferScanChunk*2 + 10
+		for i := 0; i < n; i++ {
+			st := &SentTransfer{
+				Key:               fmt.Sprintf("%s-%04d", channel.String(), i),
+				BlockNumber:       int64(i / 100),
+				ChannelIdentifier: channel,
+				TokenAddress:      tokens[i%3/2],
+				ToAddress:         utils.NewRandomAddress(),
+				Amount:            big.NewInt(int64(i)),
+			}
+			assert.Nil(t, model.storage.SaveSentTransfer(st))
+			if st.TokenAddress == tokens[1] && st.BlockNumber >= 1 {
+				want = append(want, st.Key)
+			}
+		}
+		for _, f := range []*TransferFilter{
+			{Token: tokens[1], FromBlock: 1, Limit: 7},
+			{Token: tokens[1], FromBlock: 1, ChannelIdentifier: channel, Limit: 150},
+			{Token: tokens[1], FromBlock: 1},
+		} {
+			var keys []string
+			for {
+				sts, next, err := model.FindSentTransfers(f)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, st := range sts {
+					keys = append(keys, st.Key)
+				}
+				if next == "" {
+					break
+				}
+				f.Cursor = next
+			}
+			assert.EqualValues(t, want, keys)
+		}
+	})
+}
+
+func TestStorageRecords(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		_, err := model.NewAPIToken("ci", "secret", []string{APIScopeRead})
+		assert.Nil(t, err)
+		at, err := model.GetAPITokenByToken("secret")
+		assert.Nil(t, err)
+		assert.EqualValues(t, "ci", at.Name)
+		_, err = model.GetAPITokenByToken("other")
+		assert.EqualValues(t, ErrNotFound, err)
+		assert.Nil(t, model.RemoveAPIToken("ci"))
+		ats, err := model.GetAllAPITokens()
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(ats))
+
+		channel := utils.NewRandomHash()
+		assert.Nil(t, model.SaveSettleJob(NewSettleJob(channel, utils.NewRandomAddress(), utils.NewRandomAddress(), 100)))
+		j, err := model.GetSettleJob(channel)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 100, j.SettleBlock)
+		assert.Nil(t, model.RemoveSettleJob(channel))
+		assert.Nil(t, model.RemoveSettleJob(channel))
+		js, err := model.GetAllSettleJobs()
+		assert.Nil(t, err)
+		assert.EqualValues(t, 0, len(js))
+
+		lock := utils.NewRandomHash()
+		model.SaveUnlockOutcome(channeltype.NewUnlockOutcome(channel, lock, big.NewInt(10), 30, channeltype.UnlockStatusPending, ""))
+		model.SaveUnlockOutcome(channeltype.NewUnlockOutcome(utils.NewRandomHash(), lock, big.NewInt(10), 30, channeltype.UnlockStatusPending, ""))
+		outcomes, err := model.GetUnlockOutcomes(channel)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, len(outcomes))
+
+		key, _ := crypto.GenerateKey()
+		e := newSignedNodeEndpoint(t, key, "127.0.0.1:40001", "other", 100)
+		saved, err := model.SaveNodeEndpoint(e)
+		assert.Nil(t, err)
+		assert.EqualValues(t, true, saved)
+		es, err := model.GetAllNodeEndpoints(50)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, len(es))
+		n, err := model.RemoveExpiredNodeEndpoints(100)
+		assert.Nil(t, err)
+		assert.EqualValues(t, 1, n)
+		_, err = model.GetNodeEndpoint(e.Address)
+		assert.EqualValues(t, ErrNotFound, err)
+
+		lockSecretHash := utils.NewRandomHash()
+		assert.EqualValues(t, false, model.IsLockSecretHashDisposed(lockSecretHash))
+		assert.Nil(t, model.MarkLockSecretHashDisposed(lockSecretHash, channel))
+		assert.EqualValues(t, true, model.IsLockSecretHashDisposed(lockSecretHash))
+		assert.EqualValues(t, true, model.IsLockSecretHashChannelIdentifierDisposed(lockSecretHash, channel))
+		assert.EqualValues(t, false, model.IsLockSecretHashChannelIdentifierDisposed(lockSecretHash, utils.NewRandomHash()))
+		r := NewReceivedAnnounceDisposed(lock, channel, utils.NewRandomHash(), 3, []byte("signature"))
+		assert.Nil(t, model.MarkLockHashCanPunish(r))
+		assert.EqualValues(t, true, model.IsLockHashCanPunish(lock, channel))
+		assert.EqualValues(t, r.Signature, model.GetReceiviedAnnounceDisposed(lock, channel).Signature)
+	})
+}
+
+/*
+TestIsThisLockHasUnlockedKey UnlockThisLock has always saved the key as bytes,
+IsThisLockHasUnlocked read it by the hash itself, which is encoded by codec, and never found it.
+*/
+func TestIsThisLockHasUnlockedKey(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		channel, lock := utils.NewRandomHash(), utils.NewRandomHash()
+		key := utils.Sha3(channel[:], lock[:])
+		assert.Nil(t, model.storage.Set(bucketWithDraw, key.Bytes(), true))
+		assert.EqualValues(t, true, model.IsThisLockHasUnlocked(channel, lock))
+		assert.EqualValues(t, false, model.IsThisLockHasUnlocked(channel, utils.NewRandomHash()))
+	})
+}
+
+func TestStorageKeyValue(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		assert.EqualValues(t, 0, model.GetLatestBlockNumber())
+		model.SaveLatestBlockNumber(30)
+		assert.EqualValues(t, 30, model.GetLatestBlockNumber())
+		registry := utils.NewRandomAddress()
+		model.SaveRegistryAddress(registry)
+		assert.EqualValues(t, registry, model.GetRegistryAddress())
+		assert.Nil(t, model.CheckWritable())
+
+		channel, lock := utils.NewRandomHash(), utils.NewRandomHash()
+		assert.EqualValues(t, false, model.IsThisLockHasUnlocked(channel, lock))
+		model.UnlockThisLock(channel, lock)
+		assert.EqualValues(t, true, model.IsThisLockHasUnlocked(channel, lock))
+		sender := utils.NewRandomAddress()
+		model.RemoveLock(channel, sender, lock)
+		assert.EqualValues(t, true, model.IsThisLockRemoved(channel, sender, lock))
+
+		addr := utils.NewRandomAddress()
+		model.XMPPMarkAddrSubed(addr)
+		assert.EqualValues(t, true, model.XMPPIsAddrSubed(addr))
+		model.XMPPUnMarkAddr(addr)
+		assert.EqualValues(t, false, model.XMPPIsAddrSubed(addr))
+	})
+}
+
+func TestStorageBackupAndEncryption(t *testing.T) {
+	testAllStorages(t, func(t *testing.T, model *ModelDB) {
+		secret := utils.NewRandomHash()
+		c := newTestChannel(secret)
+		assert.Nil(t, model.NewChannel(c))
+		model.SaveLatestBlockNumber(10)
+		assert.Nil(t, model.ChangeEncryptionPassword("123"))
+		assert.EqualValues(t, true, model.IsEncrypted())
+		bak := model.Name + ".bak"
+		assert.Nil(t, model.Backup(bak))
+		assert.Nil(t, model.ChangeEncryptionPassword(""))
+		ch, err := model.GetChannelByAddress(c.ChannelIdentifier.ChannelIdentifier)
+		assert.Nil(t, err)
+		assert.EqualValues(t, secret, ch.OurKnownSecrets[0])
+
+		restored := filepath.Join(filepath.Dir(model.Name), "restored")
+		assert.Nil(t, RestoreDb(bak, restored))
+		_, err = OpenDb(restored)
+		assert.EqualValues(t, ErrDbEncrypted, err)
+		m2, err := OpenDbWithPassword(restored, "123", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer m2.CloseDB()
+		assert.EqualValues(t, model.Backend(), m2.Backend())
+		assert.EqualValues(t, true, m2.IsRestored())
+		assert.EqualValues(t, 10, m2.GetLatestBlockNumber())
+		ch, err = m2.GetChannelByAddress(c.ChannelIdentifier.ChannelIdentifier)
+		assert.Nil(t, err)
+		assert.EqualValues(t, secret, ch.OurKnownSecrets[0])
+	})
+}
@@ -20,22 +20,23 @@ type Product struct {
 func TestProduct(t *testing.T) {
 	model := setupDb(t)
 	defer model.CloseDB()
-	model.db.Init(&Product{})
+	db := model.storage.(*boltStorage).db
+	db.Init(&Product{})
 	p := &Product{
 		Name:               "123",
 		UniqueIntegerField: 123,
 	}
-	err := model.db.Save(p)
+	err := db.Save(p)
 	if err != nil {
 		t.Error(err)
 	}
 	var all []*Product
-	model.db.All(&all)
+	db.All(&all)
 	if len(all) != 1 {
 		t.Error("number error")
 	}
 	p.Pk++
-	err = model.db.Save(p)
+	err = db.Save(p)
 	if err == nil {
 		t.Error("should not save")
 	}
@@ -8,7 +8,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/models/cb"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -26,19 +25,12 @@ func init() {
 
 //GetAllTokens returna all tokens on this registry contract
 func (model *ModelDB) GetAllTokens() (tokens AddressMap, err error) {
-	err = model.db.Get(bucketToken, keyToken, &tokens)
-	if err != nil {
-		if err == storm.ErrNotFound {
-			tokens = make(AddressMap)
-		}
-	}
-	return
+	return model.storage.GetAllTokens()
 }
 
 //AddToken add a new token to db,
 func (model *ModelDB) AddToken(token common.Address, tokenNetworkAddress common.Address) error {
-	var m AddressMap
-	err := model.db.Get(bucketToken, keyToken, &m)
+	m, err := model.storage.GetAllTokens()
 	if err != nil {
 		return err
 	}
@@ -47,8 +39,7 @@ func (model *ModelDB) AddToken(token common.Address, tokenNetworkAddress common.
 		log.Info("AddToken ,but already exists,should be ignored when startup...")
 		return nil
 	}
-	m[token] = tokenNetworkAddress
-	err = model.db.Set(bucketToken, keyToken, m)
+	err = model.storage.SaveToken(token, tokenNetworkAddress)
 	model.handleTokenCallback(model.newTokenCallbacks, token)
 	return err
 }
@@ -69,12 +60,12 @@ func (model *ModelDB) handleTokenCallback(m map[*cb.NewTokenCb]bool, token commo
 
 //UpdateTokenNodes update all nodes that open channel
 func (model *ModelDB) UpdateTokenNodes(token common.Address, nodes []common.Address) error {
-	return model.db.Set(bucketTokenNodes, token[:], nodes)
+	return model.storage.SaveTokenNodes(token, nodes)
 }
 
 //GetTokenNodes return all nodes has channel with me
 func (model *ModelDB) GetTokenNodes(token common.Address) (nodes []common.Address) {
-	err := model.db.Get(bucketTokenNodes, token[:], &nodes)
+	nodes, err := model.storage.GetTokenNodes(token)
 	if err != nil {
 		log.Warn(fmt.Sprintf("GetTokenNodes for %s err=%s", token.String(), err))
 	}
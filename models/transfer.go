@@ -6,10 +6,12 @@ import (
 	"fmt"
 
 	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/asdine/storm"
 	"github.com/ethereum/go-ethereum/common"
 )
 
@@ -23,6 +25,11 @@ type SentTransfer struct {
 	TokenAddress      common.Address `json:"token_address"`
 	Nonce             int64          `json:"nonce"`
 	Amount            *big.Int       `json:"amount"`
+	Time              int64          `json:"time" storm:"index"` //unix time when it's saved, 0 if it's saved before time is recorded
+	//indexes of bolt to page transfers of a token, partner or channel by block number, see transferIndexKey
+	TokenIndex   string `json:"-" storm:"index"`
+	PartnerIndex string `json:"-" storm:"index"`
+	ChannelIndex string `json:"-" storm:"index"`
 }
 
 //ReceivedTransfer tokens I have received and where it comes from
@@ -35,6 +42,11 @@ type ReceivedTransfer struct {
 	FromAddress       common.Address `json:"from_address"`
 	Nonce             int64          `json:"nonce"`
 	Amount            *big.Int       `json:"amount"`
+	Time              int64          `json:"time" storm:"index"` //unix time when it's saved, 0 if it's saved before time is recorded
+	//indexes of bolt to page transfers of a token, partner or channel by block number, see transferIndexKey
+	TokenIndex   string `json:"-" storm:"index"`
+	PartnerIndex string `json:"-" storm:"index"`
+	ChannelIndex string `json:"-" storm:"index"`
 }
 
 /*
@@ -50,13 +62,14 @@ func (model *ModelDB) NewSentTransfer(blockNumber int64, channelAddr common.Hash
 		ToAddress:         toAddr,
 		Nonce:             nonce,
 		Amount:            amount,
+		Time:              time.Now().Unix(),
 	}
 	if ost, err := model.GetSentTransfer(key); err == nil {
 		log.Error(fmt.Sprintf("NewSentTransfer, but already exist, old=\n%s,new=\n%s",
 			utils.StringInterface(ost, 2), utils.StringInterface(st, 2)))
 		return
 	}
-	err := model.db.Save(st)
+	err := model.storage.SaveSentTransfer(st)
 	if err != nil {
 		log.Error(fmt.Sprintf("save SentTransfer err %s", err))
 	}
@@ -78,13 +91,14 @@ func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.
 		FromAddress:       fromAddr,
 		Nonce:             nonce,
 		Amount:            amount,
+		Time:              time.Now().Unix(),
 	}
 	if ost, err := model.GetReceivedTransfer(key); err == nil {
 		log.Error(fmt.Sprintf("NewReceivedTransfer, but already exist, old=\n%s,new=\n%s",
 			utils.StringInterface(ost, 2), utils.StringInterface(st, 2)))
 		return
 	}
-	err := model.db.Save(st)
+	err := model.storage.SaveReceivedTransfer(st)
 	if err != nil {
 		log.Error(fmt.Sprintf("save ReceivedTransfer err %s", err))
 	}
@@ -97,44 +111,191 @@ func (model *ModelDB) NewReceivedTransfer(blockNumber int64, channelAddr common.
 
 //GetSentTransfer return the sent transfer by key
 func (model *ModelDB) GetSentTransfer(key string) (*SentTransfer, error) {
-	var s SentTransfer
-	err := model.db.One("Key", key, &s)
-	return &s, err
+	return model.storage.GetSentTransfer(key)
 }
 
 //GetReceivedTransfer return the received transfer by key
 func (model *ModelDB) GetReceivedTransfer(key string) (*ReceivedTransfer, error) {
-	var r ReceivedTransfer
-	err := model.db.One("Key", key, &r)
-	return &r, err
+	return model.storage.GetReceivedTransfer(key)
 }
 
 //GetSentTransferInBlockRange returns the sent transfer between from and to blocks
 func (model *ModelDB) GetSentTransferInBlockRange(fromBlock, toBlock int64) (transfers []*SentTransfer, err error) {
-	if fromBlock < 0 {
-		fromBlock = 0
+	transfers, _, err = model.FindSentTransfers(&TransferFilter{FromBlock: fromBlock, ToBlock: toBlock})
+	return
+}
+
+//GetReceivedTransferInBlockRange returns the received transfer between from and to blocks
+func (model *ModelDB) GetReceivedTransferInBlockRange(fromBlock, toBlock int64) (transfers []*ReceivedTransfer, err error) {
+	transfers, _, err = model.FindReceivedTransfers(&TransferFilter{FromBlock: fromBlock, ToBlock: toBlock})
+	return
+}
+
+/*
+TransferFilter selects transfers of history, zero values match any.
+transfers are ordered by block number then key, a page of at most `Limit` transfers starts after `Cursor`,
+which is the next cursor returned with the previous page.
+*/
+type TransferFilter struct {
+	FromBlock int64 `json:"from_block"`
+	ToBlock   int64 `json:"to_block"` //not positive means no limit
+	//FromTime and ToTime are unix time, transfers saved before time is recorded never match a time range
+	FromTime          int64          `json:"from_time"`
+	ToTime            int64          `json:"to_time"` //not positive means no limit
+	Token             common.Address `json:"token_address"`
+	Partner           common.Address `json:"partner_address"` //ToAddress of sent transfers, FromAddress of received transfers
+	ChannelIdentifier common.Hash    `json:"channel_identifier"`
+	MinAmount         *big.Int       `json:"min_amount"`
+	MaxAmount         *big.Int       `json:"max_amount"`
+	Cursor            string         `json:"cursor"`
+	Limit             int            `json:"limit"` //not positive means no limit
+	//cursorBlock and cursorKey are parsed from Cursor by normalize
+	cursorBlock int64
+	cursorKey   string
+}
+
+//transferCursor is the position of a transfer in history
+func transferCursor(blockNumber int64, key string) string {
+	return fmt.Sprintf("%d/%s", blockNumber, key)
+}
+
+//normalize returns a copy of `f` whose limits are explicit and cursor is parsed, for Storage
+func (f *TransferFilter) normalize() (nf *TransferFilter, err error) {
+	c := *f
+	nf = &c
+	if nf.FromBlock < 0 {
+		nf.FromBlock = 0
 	}
-	if toBlock < 0 {
-		toBlock = math.MaxInt64
+	if nf.ToBlock <= 0 {
+		nf.ToBlock = math.MaxInt64
 	}
-	err = model.db.Range("BlockNumber", fromBlock, toBlock, &transfers)
-	if err == storm.ErrNotFound { //ingore not found error
-		err = nil
+	if nf.ToTime <= 0 {
+		nf.ToTime = math.MaxInt64
+	}
+	nf.cursorBlock = -1
+	if len(nf.Cursor) > 0 {
+		ss := strings.SplitN(nf.Cursor, "/", 2)
+		if len(ss) != 2 {
+			return nil, fmt.Errorf("invalid cursor %s", nf.Cursor)
+		}
+		nf.cursorBlock, err = strconv.ParseInt(ss[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cursor %s", nf.Cursor)
+		}
+		nf.cursorKey = ss[1]
 	}
 	return
 }
 
-//GetReceivedTransferInBlockRange returns the received transfer between from and to blocks
-func (model *ModelDB) GetReceivedTransferInBlockRange(fromBlock, toBlock int64) (transfers []*ReceivedTransfer, err error) {
-	if fromBlock < 0 {
-		fromBlock = 0
+//hasTimeRange returns true if `f` is normalized and selects a time range
+func (f *TransferFilter) hasTimeRange() bool {
+	return f.FromTime > 0 || f.ToTime != math.MaxInt64
+}
+
+//match returns true if a transfer with these fields is selected by normalized filter `f`
+func (f *TransferFilter) match(blockNumber int64, key string, token, partner common.Address, channel common.Hash, amount *big.Int, t int64) bool {
+	if blockNumber < f.FromBlock || blockNumber > f.ToBlock {
+		return false
+	}
+	if blockNumber < f.cursorBlock || (blockNumber == f.cursorBlock && key <= f.cursorKey) {
+		return false
+	}
+	if f.hasTimeRange() && (t == 0 || t < f.FromTime || t > f.ToTime) {
+		return false
+	}
+	if f.Token != utils.EmptyAddress && f.Token != token {
+		return false
+	}
+	if f.Partner != utils.EmptyAddress && f.Partner != partner {
+		return false
+	}
+	if f.ChannelIdentifier != utils.EmptyHash && f.ChannelIdentifier != channel {
+		return false
 	}
-	if toBlock < 0 {
-		toBlock = math.MaxInt64
+	if f.MinAmount != nil && (amount == nil || amount.Cmp(f.MinAmount) < 0) {
+		return false
+	}
+	if f.MaxAmount != nil && (amount == nil || amount.Cmp(f.MaxAmount) > 0) {
+		return false
+	}
+	return true
+}
+
+/*
+transferIndexKey is key of index TokenIndex, PartnerIndex or ChannelIndex of a transfer,
+storm orders keys of the same value by id, so transfers of `value` are in order of block number then key as history is paged.
+token, partner and channel are readable in the index even if the db is encrypted,
+but they are public on chain for a channel identifier, which is in the key of every transfer anyway.
+*/
+func transferIndexKey(value string, blockNumber int64) string {
+	return fmt.Sprintf("%s/%019d", value, blockNumber)
+}
+
+func (t *SentTransfer) setIndexes() {
+	t.TokenIndex = transferIndexKey(t.TokenAddress.String(), t.BlockNumber)
+	t.PartnerIndex = transferIndexKey(t.ToAddress.String(), t.BlockNumber)
+	t.ChannelIndex = transferIndexKey(t.ChannelIdentifier.String(), t.BlockNumber)
+}
+
+func (t *ReceivedTransfer) setIndexes() {
+	t.TokenIndex = transferIndexKey(t.TokenAddress.String(), t.BlockNumber)
+	t.PartnerIndex = transferIndexKey(t.FromAddress.String(), t.BlockNumber)
+	t.ChannelIndex = transferIndexKey(t.ChannelIdentifier.String(), t.BlockNumber)
+}
+
+func (f *TransferFilter) matchSent(t *SentTransfer) bool {
+	return f.match(t.BlockNumber, t.Key, t.TokenAddress, t.ToAddress, t.ChannelIdentifier, t.Amount, t.Time)
+}
+
+func (f *TransferFilter) matchReceived(t *ReceivedTransfer) bool {
+	return f.match(t.BlockNumber, t.Key, t.TokenAddress, t.FromAddress, t.ChannelIdentifier, t.Amount, t.Time)
+}
+
+/*
+FindSentTransfers returns a page of sent transfers selected by `f`,
+and the cursor of the next page, which is empty if it's the last page.
+*/
+func (model *ModelDB) FindSentTransfers(f *TransferFilter) (transfers []*SentTransfer, next string, err error) {
+	nf, err := f.normalize()
+	if err != nil {
+		return
+	}
+	if nf.Limit > 0 {
+		//one more to know whether there is a next page
+		nf.Limit++
+	}
+	transfers, err = model.storage.FindSentTransfers(nf)
+	if err != nil {
+		return
+	}
+	if f.Limit > 0 && len(transfers) > f.Limit {
+		transfers = transfers[:f.Limit]
+		last := transfers[len(transfers)-1]
+		next = transferCursor(last.BlockNumber, last.Key)
+	}
+	return
+}
+
+/*
+FindReceivedTransfers returns a page of received transfers selected by `f`,
+and the cursor of the next page, which is empty if it's the last page.
+*/
+func (model *ModelDB) FindReceivedTransfers(f *TransferFilter) (transfers []*ReceivedTransfer, next string, err error) {
+	nf, err := f.normalize()
+	if err != nil {
+		return
+	}
+	if nf.Limit > 0 {
+		nf.Limit++
+	}
+	transfers, err = model.storage.FindReceivedTransfers(nf)
+	if err != nil {
+		return
 	}
-	err = model.db.Range("BlockNumber", fromBlock, toBlock, &transfers)
-	if err == storm.ErrNotFound { //ingore not found error
-		err = nil
+	if f.Limit > 0 && len(transfers) > f.Limit {
+		transfers = transfers[:f.Limit]
+		last := transfers[len(transfers)-1]
+		next = transferCursor(last.BlockNumber, last.Key)
 	}
 	return
 }
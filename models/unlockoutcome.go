@@ -0,0 +1,22 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//SaveUnlockOutcome save or replace outcome of unlocking a lock on chain
+func (model *ModelDB) SaveUnlockOutcome(o *channeltype.UnlockOutcome) {
+	err := model.storage.SaveUnlockOutcome(o)
+	if err != nil {
+		log.Error(fmt.Sprintf("SaveUnlockOutcome %s err %s", o.Key, err))
+	}
+}
+
+//GetUnlockOutcomes returns outcomes of all locks unlocked or skipped on channel `channelIdentifier`
+func (model *ModelDB) GetUnlockOutcomes(channelIdentifier common.Hash) (outcomes []*channeltype.UnlockOutcome, err error) {
+	return model.storage.GetUnlockOutcomes(channelIdentifier)
+}
@@ -0,0 +1,35 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestModelDB_UnlockOutcome(t *testing.T) {
+	model := setupDb(t)
+	defer model.CloseDB()
+	ch := utils.NewRandomHash()
+	lock := utils.NewRandomHash()
+	os, err := model.GetUnlockOutcomes(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(os), 0)
+	model.SaveUnlockOutcome(channeltype.NewUnlockOutcome(ch, lock, big.NewInt(10), 30, channeltype.UnlockStatusPending, ""))
+	model.SaveUnlockOutcome(channeltype.NewUnlockOutcome(utils.NewRandomHash(), lock, big.NewInt(10), 30, channeltype.UnlockStatusSkipped, ""))
+	model.SaveUnlockOutcome(channeltype.NewUnlockOutcome(ch, lock, big.NewInt(10), 30, channeltype.UnlockStatusUnlocked, ""))
+	os, err = model.GetUnlockOutcomes(ch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert.EqualValues(t, len(os), 1)
+	assert.EqualValues(t, os[0].Status, channeltype.UnlockStatusUnlocked)
+	assert.EqualValues(t, os[0].LockSecretHash, lock)
+	assert.EqualValues(t, os[0].Amount, big.NewInt(10))
+}
@@ -11,7 +11,7 @@ const bucketXMPP = "bucketxmpp"
 
 //XMPPMarkAddrSubed mark `addr` subscribed
 func (model *ModelDB) XMPPMarkAddrSubed(addr common.Address) {
-	err := model.db.Set(bucketXMPP, addr[:], true)
+	err := model.storage.Set(bucketXMPP, addr[:], true)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
@@ -20,7 +20,7 @@ func (model *ModelDB) XMPPMarkAddrSubed(addr common.Address) {
 //XMPPIsAddrSubed return true when `addr` already subscirbed
 func (model *ModelDB) XMPPIsAddrSubed(addr common.Address) bool {
 	var r bool
-	err := model.db.Get(bucketXMPP, addr[:], &r)
+	err := model.storage.Get(bucketXMPP, addr[:], &r)
 	if err != nil {
 		log.Trace(fmt.Sprintf("db err %s", err))
 	}
@@ -29,7 +29,7 @@ func (model *ModelDB) XMPPIsAddrSubed(addr common.Address) bool {
 
 //XMPPUnMarkAddr mark `addr` has been unsubscribed
 func (model *ModelDB) XMPPUnMarkAddr(addr common.Address) {
-	err := model.db.Set(bucketXMPP, addr[:], false)
+	err := model.storage.Set(bucketXMPP, addr[:], false)
 	if err != nil {
 		log.Error(fmt.Sprintf("db err %s", err))
 	}
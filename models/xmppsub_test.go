@@ -8,7 +8,7 @@ import (
 
 func TestModelDB_XMPPIsAddrSubed(t *testing.T) {
 	db := setupDb(t)
-	defer db.db.Close()
+	defer db.storage.Close()
 	addr := utils.NewRandomAddress()
 	if db.XMPPIsAddrSubed(addr) {
 		t.Error("should not marked")
@@ -1,7 +1,6 @@
 package network
 
 import (
-	"crypto/ecdsa"
 	"math/rand"
 	"time"
 
@@ -11,6 +10,7 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -46,13 +46,13 @@ func randomPort() int {
 }
 
 //MakeTestXMPPTransport create a test xmpp transport
-func MakeTestXMPPTransport(name string, key *ecdsa.PrivateKey) *XMPPTransport {
-	return NewXMPPTransport(name, params.DefaultTestXMPPServer, key, DeviceTypeOther)
+func MakeTestXMPPTransport(name string, s signer.Signer) *XMPPTransport {
+	return NewXMPPTransport(name, params.DefaultTestXMPPServer, s, DeviceTypeOther)
 }
 
 //MakeTestMixTransport creat a test mix transport
-func MakeTestMixTransport(name string, key *ecdsa.PrivateKey) *MixTransporter {
-	t, err := NewMixTranspoter(name, params.DefaultTestXMPPServer, "127.0.0.1", randomPort(), key, nil, NewTokenBucket(10, 2, time.Now), DeviceTypeOther)
+func MakeTestMixTransport(name string, s signer.Signer) *MixTransporter {
+	t, err := NewMixTranspoter(name, params.DefaultTestXMPPServer, "127.0.0.1", randomPort(), s, nil, NewTokenBucket(10, 2, time.Now), DeviceTypeOther)
 	if err != nil {
 		panic(err)
 	}
@@ -86,7 +86,8 @@ func (t *timeBlockNumberGetter) GetBlockNumber() int64 {
 func MakeTestRaidenProtocol(name string) *RaidenProtocol {
 	////#nosec
 	privkey, _ := crypto.GenerateKey()
-	rp := NewRaidenProtocol(MakeTestXMPPTransport(name, privkey), privkey, &testBlockNumberGetter{})
+	s := signer.NewKeySigner(privkey)
+	rp := NewRaidenProtocol(MakeTestXMPPTransport(name, s), s, &testBlockNumberGetter{}, nil)
 	return rp
 }
 
@@ -94,7 +95,8 @@ func MakeTestRaidenProtocol(name string) *RaidenProtocol {
 func MakeTestDiscardExpiredTransferRaidenProtocol(name string) *RaidenProtocol {
 	//#nosec
 	privkey, _ := crypto.GenerateKey()
-	rp := NewRaidenProtocol(MakeTestXMPPTransport(name, privkey), privkey, newTimeBlockNumberGetter(time.Now()))
+	s := signer.NewKeySigner(privkey)
+	rp := NewRaidenProtocol(MakeTestXMPPTransport(name, s), s, newTimeBlockNumberGetter(time.Now()), nil)
 	return rp
 }
 
@@ -0,0 +1,201 @@
+package dijkstra
+
+import (
+	"container/heap"
+	"sort"
+)
+
+//Arc is a directed edge of the graph
+type Arc struct {
+	From int
+	To   int
+}
+
+/*
+KShortest calculates at most k shortest loopless paths from src to dest (Yen's algorithm),
+ordered by distance. extra is added to the distance of the arcs in it, it's how callers
+make paths through bad edges less attractive without removing them, it can be nil.
+paths never go through verticies in avoid.
+Ties are broken by the path itself, so the result is the same for the same graph.
+*/
+func (g *Graph) KShortest(src, dest, k int, extra map[Arc]int64, avoid ...int) ([]BestPath, error) {
+	if k <= 0 {
+		return nil, nil
+	}
+	if src < 0 || dest < 0 || src >= len(g.Verticies) || dest >= len(g.Verticies) || src == dest {
+		return nil, ErrNoPath
+	}
+	avoided := make(map[int]bool)
+	for _, n := range avoid {
+		avoided[n] = true
+	}
+	if avoided[src] || avoided[dest] {
+		return nil, ErrNoPath
+	}
+	first, ok := g.restrictedShortest(src, dest, avoided, nil, extra)
+	if !ok {
+		return nil, ErrNoPath
+	}
+	paths := []BestPath{first}
+	var candidates []BestPath
+	seen := map[string]bool{pathKey(first.Path): true}
+	for len(paths) < k {
+		last := paths[len(paths)-1].Path
+		for i := 0; i < len(last)-1; i++ {
+			spur := last[i]
+			root := last[:i+1]
+			removedArcs := make(map[Arc]bool)
+			for _, p := range paths {
+				if len(p.Path) > i+1 && samePrefix(p.Path, root) {
+					removedArcs[Arc{p.Path[i], p.Path[i+1]}] = true
+				}
+			}
+			removedNodes := make(map[int]bool)
+			for n := range avoided {
+				removedNodes[n] = true
+			}
+			for _, n := range root[:i] {
+				removedNodes[n] = true
+			}
+			spurPath, ok := g.restrictedShortest(spur, dest, removedNodes, removedArcs, extra)
+			if !ok {
+				continue
+			}
+			path := make([]int, 0, i+len(spurPath.Path))
+			path = append(path, root[:i]...)
+			path = append(path, spurPath.Path...)
+			key := pathKey(path)
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			candidates = append(candidates, BestPath{g.pathDistance(path, extra), path})
+		}
+		if len(candidates) == 0 {
+			break
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			return lessPath(candidates[i], candidates[j])
+		})
+		paths = append(paths, candidates[0])
+		candidates = candidates[1:]
+	}
+	return paths, nil
+}
+
+func (g *Graph) arcDistance(from, to int, extra map[Arc]int64) int64 {
+	return g.Verticies[from].arcs[to] + extra[Arc{from, to}]
+}
+
+func (g *Graph) pathDistance(path []int, extra map[Arc]int64) (d int64) {
+	for i := 0; i < len(path)-1; i++ {
+		d += g.arcDistance(path[i], path[i+1], extra)
+	}
+	return
+}
+
+//restrictedShortest is a plain dijkstra which ignores removed nodes and arcs, it doesn't touch state of the graph.
+func (g *Graph) restrictedShortest(src, dest int, removedNodes map[int]bool, removedArcs map[Arc]bool, extra map[Arc]int64) (BestPath, bool) {
+	dist := map[int]int64{src: 0}
+	prev := make(map[int]int)
+	done := make(map[int]bool)
+	q := &arcQueue{{src, 0}}
+	for q.Len() > 0 {
+		cur := heap.Pop(q).(queueItem)
+		if done[cur.id] {
+			continue
+		}
+		done[cur.id] = true
+		if cur.id == dest {
+			break
+		}
+		//visit arcs in order so that equal paths are always chosen the same way
+		next := make([]int, 0, len(g.Verticies[cur.id].arcs))
+		for to := range g.Verticies[cur.id].arcs {
+			next = append(next, to)
+		}
+		sort.Ints(next)
+		for _, to := range next {
+			if done[to] || removedNodes[to] || removedArcs[Arc{cur.id, to}] {
+				continue
+			}
+			d := cur.distance + g.arcDistance(cur.id, to, extra)
+			old, ok := dist[to]
+			if !ok || d < old {
+				dist[to] = d
+				prev[to] = cur.id
+				heap.Push(q, queueItem{to, d})
+			}
+		}
+	}
+	if !done[dest] {
+		return BestPath{}, false
+	}
+	var path []int
+	for n := dest; n != src; n = prev[n] {
+		path = append(path, n)
+	}
+	path = append(path, src)
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return BestPath{dist[dest], path}, true
+}
+
+func samePrefix(path, prefix []int) bool {
+	for i, n := range prefix {
+		if path[i] != n {
+			return false
+		}
+	}
+	return true
+}
+
+func pathKey(path []int) string {
+	b := make([]byte, 0, len(path)*4)
+	for _, n := range path {
+		b = append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
+	return string(b)
+}
+
+//lessPath orders by distance, then by hops, then by nodes
+func lessPath(a, b BestPath) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	if len(a.Path) != len(b.Path) {
+		return len(a.Path) < len(b.Path)
+	}
+	for i := range a.Path {
+		if a.Path[i] != b.Path[i] {
+			return a.Path[i] < b.Path[i]
+		}
+	}
+	return false
+}
+
+type queueItem struct {
+	id       int
+	distance int64
+}
+
+//arcQueue is a min heap of vertex by distance
+type arcQueue []queueItem
+
+func (q arcQueue) Len() int { return len(q) }
+func (q arcQueue) Less(i, j int) bool {
+	if q[i].distance != q[j].distance {
+		return q[i].distance < q[j].distance
+	}
+	return q[i].id < q[j].id
+}
+func (q arcQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *arcQueue) Push(x interface{}) { *q = append(*q, x.(queueItem)) }
+func (q *arcQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	x := old[n-1]
+	*q = old[:n-1]
+	return x
+}
@@ -0,0 +1,114 @@
+package dijkstra
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+/*
+0 -> 1 -> 3
+0 -> 2 -> 3
+0 -> 1 -> 2 -> 3
+*/
+func newDiamondGraph() *Graph {
+	g := NewGraph()
+	for i := 0; i < 4; i++ {
+		g.AddVertex(i)
+	}
+	g.AddArc(0, 1, 1)
+	g.AddArc(0, 2, 2)
+	g.AddArc(1, 3, 1)
+	g.AddArc(1, 2, 1)
+	g.AddArc(2, 3, 1)
+	return g
+}
+
+func TestKShortest(t *testing.T) {
+	g := newDiamondGraph()
+	paths, err := g.KShortest(0, 3, 5, nil)
+	testErrors(t, nil, err, "diamond")
+	want := []BestPath{
+		{2, []int{0, 1, 3}},
+		{3, []int{0, 2, 3}},
+		{3, []int{0, 1, 2, 3}},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+	paths, err = g.KShortest(0, 3, 1, nil)
+	testErrors(t, nil, err, "diamond")
+	if !reflect.DeepEqual(paths, want[:1]) {
+		t.Errorf("got %v, want %v", paths, want[:1])
+	}
+	_, err = g.KShortest(3, 0, 2, nil)
+	testErrors(t, ErrNoPath, err, "diamond")
+	//state of the graph is not touched
+	best, err := g.Shortest(0, 3)
+	testErrors(t, nil, err, "diamond")
+	testResults(t, best, want[0], true, "diamond")
+}
+
+func TestKShortestExtra(t *testing.T) {
+	g := newDiamondGraph()
+	paths, err := g.KShortest(0, 3, 2, map[Arc]int64{{1, 3}: 10})
+	testErrors(t, nil, err, "diamond")
+	want := []BestPath{
+		{3, []int{0, 2, 3}},
+		{3, []int{0, 1, 2, 3}},
+	}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("got %v, want %v", paths, want)
+	}
+	paths, err = g.KShortest(0, 3, 3, nil, 1)
+	testErrors(t, nil, err, "diamond")
+	if !reflect.DeepEqual(paths, want[:1]) {
+		t.Errorf("got %v, want %v", paths, want[:1])
+	}
+}
+
+func TestKShortestGenerated(t *testing.T) {
+	g := Generate(20)
+	best, err := g.Shortest(0, 19)
+	testErrors(t, nil, err, "generated")
+	paths, err := g.KShortest(0, 19, 10, nil)
+	testErrors(t, nil, err, "generated")
+	if len(paths) != 10 {
+		t.Fatalf("got %d paths", len(paths))
+	}
+	if paths[0].Distance != best.Distance {
+		t.Errorf("first path %v is not the shortest %v", paths[0], best)
+	}
+	seen := make(map[string]bool)
+	for i, p := range paths {
+		if i > 0 && p.Distance < paths[i-1].Distance {
+			t.Errorf("paths not ordered %v", paths)
+		}
+		key := pathKey(p.Path)
+		if seen[key] {
+			t.Errorf("duplicate path %v", p.Path)
+		}
+		seen[key] = true
+		nodes := make(map[int]bool)
+		for _, n := range p.Path {
+			if nodes[n] {
+				t.Errorf("loop in path %v", p.Path)
+			}
+			nodes[n] = true
+		}
+	}
+}
+
+func BenchmarkKShortest(b *testing.B) {
+	for _, nodes := range []int{16, 64, 256} {
+		graph := Generate(nodes)
+		for _, k := range []int{1, 3, 10} {
+			b.Run(strconv.Itoa(nodes)+"Nodes/K"+strconv.Itoa(k), func(b *testing.B) {
+				b.ResetTimer()
+				for i := 0; i < b.N; i++ {
+					graph.KShortest(0, nodes-1, k, nil)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package dijkstra
+
+import "sort"
+
+/*
+topology of the graph, arcs are taken as undirected and their distance is ignored.
+all of them visit every vertex, it's O(V*(V+E)) for Diameter, so they are only for inspection, not for routing.
+*/
+
+//Degree is how many other verticies v has arcs to
+func (g *Graph) Degree(v int) int {
+	if v < 0 || v >= len(g.Verticies) {
+		return 0
+	}
+	return len(g.Verticies[v].arcs)
+}
+
+//neighbors of v in order, including verticies which only have arcs to v
+func (g *Graph) undirectedNeighbors() [][]int {
+	sets := make([]map[int]bool, len(g.Verticies))
+	for i := range sets {
+		sets[i] = make(map[int]bool)
+	}
+	for i, v := range g.Verticies {
+		for to := range v.arcs {
+			if to == i || to < 0 || to >= len(g.Verticies) {
+				continue
+			}
+			sets[i][to] = true
+			sets[to][i] = true
+		}
+	}
+	neighbors := make([][]int, len(g.Verticies))
+	for i, s := range sets {
+		for n := range s {
+			neighbors[i] = append(neighbors[i], n)
+		}
+		sort.Ints(neighbors[i])
+	}
+	return neighbors
+}
+
+/*
+Diameter is the most hops of all shortest paths between two connected verticies,
+verticies in different components are not counted.
+*/
+func (g *Graph) Diameter() (diameter int) {
+	neighbors := g.undirectedNeighbors()
+	hops := make([]int, len(neighbors))
+	for src := range neighbors {
+		for i := range hops {
+			hops[i] = -1
+		}
+		hops[src] = 0
+		queue := []int{src}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			if hops[cur] > diameter {
+				diameter = hops[cur]
+			}
+			for _, n := range neighbors[cur] {
+				if hops[n] < 0 {
+					hops[n] = hops[cur] + 1
+					queue = append(queue, n)
+				}
+			}
+		}
+	}
+	return
+}
+
+/*
+ArticulationPoints returns verticies whose removal disconnects others in their component, in order.
+for a channel network they are nodes all payments between two parts have to go through.
+*/
+func (g *Graph) ArticulationPoints() (points []int) {
+	neighbors := g.undirectedNeighbors()
+	n := len(neighbors)
+	disc := make([]int, n) //discovery time, 0 is not visited
+	low := make([]int, n)
+	parent := make([]int, n)
+	isPoint := make([]bool, n)
+	time := 0
+	type frame struct {
+		v    int
+		next int //index of the next neighbor to visit
+	}
+	for root := 0; root < n; root++ {
+		if disc[root] != 0 {
+			continue
+		}
+		time++
+		disc[root], low[root], parent[root] = time, time, -1
+		children := 0
+		//iterative dfs, a large graph would overflow a recursive one
+		stack := []*frame{{v: root}}
+		for len(stack) > 0 {
+			f := stack[len(stack)-1]
+			if f.next < len(neighbors[f.v]) {
+				u := neighbors[f.v][f.next]
+				f.next++
+				if disc[u] == 0 {
+					parent[u] = f.v
+					time++
+					disc[u], low[u] = time, time
+					if f.v == root {
+						children++
+					}
+					stack = append(stack, &frame{v: u})
+				} else if u != parent[f.v] && disc[u] < low[f.v] {
+					low[f.v] = disc[u]
+				}
+				continue
+			}
+			stack = stack[:len(stack)-1]
+			p := parent[f.v]
+			if p < 0 {
+				continue
+			}
+			if low[f.v] < low[p] {
+				low[p] = low[f.v]
+			}
+			if p != root && low[f.v] >= disc[p] {
+				isPoint[p] = true
+			}
+		}
+		if children > 1 {
+			isPoint[root] = true
+		}
+	}
+	for v, ok := range isPoint {
+		if ok {
+			points = append(points, v)
+		}
+	}
+	return
+}
@@ -0,0 +1,57 @@
+package dijkstra
+
+import (
+	"reflect"
+	"testing"
+)
+
+//newUndirectedGraph creates a graph of n verticies with arcs both ways of every edge
+func newUndirectedGraph(n int, edges [][2]int) *Graph {
+	g := NewGraph()
+	for i := 0; i < n; i++ {
+		g.AddVertex(i)
+	}
+	for _, e := range edges {
+		g.AddArc(e[0], e[1], 1)
+		g.AddArc(e[1], e[0], 1)
+	}
+	return g
+}
+
+func TestTopology(t *testing.T) {
+	cases := []struct {
+		name     string
+		n        int
+		edges    [][2]int
+		diameter int
+		points   []int
+		degree0  int
+	}{
+		{"empty", 0, nil, 0, nil, 0},
+		{"line", 4, [][2]int{{0, 1}, {1, 2}, {2, 3}}, 3, []int{1, 2}, 1},
+		{"ring", 4, [][2]int{{0, 1}, {1, 2}, {2, 3}, {3, 0}}, 2, nil, 2},
+		{"star", 4, [][2]int{{0, 1}, {0, 2}, {0, 3}}, 2, []int{0}, 3},
+		//two triangles sharing vertex 2, and an isolated vertex
+		{"bowtie", 6, [][2]int{{0, 1}, {1, 2}, {2, 0}, {2, 3}, {3, 4}, {4, 2}}, 2, []int{2}, 2},
+	}
+	for _, c := range cases {
+		g := newUndirectedGraph(c.n, c.edges)
+		if d := g.Diameter(); d != c.diameter {
+			t.Errorf("%s diameter=%d,want %d", c.name, d, c.diameter)
+		}
+		if points := g.ArticulationPoints(); !reflect.DeepEqual(points, c.points) {
+			t.Errorf("%s articulation points=%v,want %v", c.name, points, c.points)
+		}
+		if d := g.Degree(0); d != c.degree0 {
+			t.Errorf("%s degree=%d,want %d", c.name, d, c.degree0)
+		}
+	}
+}
+
+func BenchmarkArticulationPoints(b *testing.B) {
+	g := Generate(256)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		g.ArticulationPoints()
+	}
+}
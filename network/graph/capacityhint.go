@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/dijkstra"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+capacity hints are what nodes tell their partners about how much they can send through their channels.
+they are coarse and may be stale, so a channel known to be too small for the amount is avoided,
+a channel which may be too small is only less preferred.
+*/
+
+//hintUncertainWeight is added to an arc which may not have enough capacity
+const hintUncertainWeight = 1
+
+//hintDepletedWeight is added to an arc which doesn't have enough capacity, paths through it are dropped
+const hintDepletedWeight = int64(1) << 40
+
+type capacityHint struct {
+	min        *big.Int //capacity is at least min
+	max        *big.Int //capacity is less than max
+	expiration time.Time
+}
+
+//CapacityToBucket returns the bucket of capacity, a bucket covers precision powers of two
+func CapacityToBucket(capacity *big.Int, precision int) uint8 {
+	if precision <= 0 {
+		precision = 1
+	}
+	bits := capacity.BitLen()
+	bucket := (bits + precision - 1) / precision * precision
+	if bucket > 255 { //no token has so much
+		bucket = 255
+	}
+	return uint8(bucket)
+}
+
+//CapacityBucketRange returns the range [min,max) of capacity in bucket
+func CapacityBucketRange(bucket uint8, precision int) (min, max *big.Int) {
+	if bucket == 0 {
+		return big.NewInt(0), big.NewInt(1)
+	}
+	max = new(big.Int).Lsh(big.NewInt(1), uint(bucket))
+	low := int(bucket) - precision
+	if low < 0 {
+		low = 0
+	}
+	min = new(big.Int).Lsh(big.NewInt(1), uint(low))
+	return
+}
+
+/*
+SetCapacityHint saves what `from` told about its channel with `to`, until expiration.
+a hint expires earlier than the saved one is ignored, it's an old one.
+*/
+func (cg *ChannelGraph) SetCapacityHint(from, to common.Address, min, max *big.Int, expiration time.Time) bool {
+	if !cg.hasEdge(from, to) {
+		return false
+	}
+	e := route.Edge{From: from, To: to}
+	if h, ok := cg.hints[e]; ok && h.expiration.After(expiration) {
+		return false
+	}
+	cg.hints[e] = &capacityHint{min, max, expiration}
+	return true
+}
+
+//hasEdge returns true if there is a channel between from and to, HasChannel only tells whether there is a path
+func (cg *ChannelGraph) hasEdge(from, to common.Address) bool {
+	fromIndex, ok := cg.address2index[from]
+	if !ok {
+		return false
+	}
+	toIndex, ok := cg.address2index[to]
+	if !ok {
+		return false
+	}
+	_, ok = cg.g.Verticies[fromIndex].GetArc(toIndex)
+	return ok
+}
+
+//RemoveExpiredCapacityHints removes hints expired before now
+func (cg *ChannelGraph) RemoveExpiredCapacityHints(now time.Time) (n int) {
+	for e, h := range cg.hints {
+		if !h.expiration.After(now) {
+			delete(cg.hints, e)
+			n++
+		}
+	}
+	return
+}
+
+/*
+SetChannelCapacity sets the most either side of channel between a and b can send, it's the total deposit of the channel.
+it's known from blockchain and never expires, nil removes it.
+*/
+func (cg *ChannelGraph) SetChannelCapacity(a, b common.Address, capacity *big.Int) {
+	for _, e := range []route.Edge{{From: a, To: b}, {From: b, To: a}} {
+		if capacity == nil {
+			delete(cg.capacities, e)
+		} else {
+			cg.capacities[e] = capacity
+		}
+	}
+}
+
+//CapacityHintCount is the number of hints known
+func (cg *ChannelGraph) CapacityHintCount() int {
+	return len(cg.hints)
+}
+
+func (cg *ChannelGraph) removeCapacityHints(a, b common.Address) {
+	delete(cg.hints, route.Edge{From: a, To: b})
+	delete(cg.hints, route.Edge{From: b, To: a})
+	cg.SetChannelCapacity(a, b, nil)
+}
+
+//capacityHintWeights returns extra weight of arcs by their hints about amount
+func (cg *ChannelGraph) capacityHintWeights(amount *big.Int, now time.Time) map[dijkstra.Arc]int64 {
+	if len(cg.hints) == 0 && len(cg.capacities) == 0 {
+		return nil
+	}
+	extra := make(map[dijkstra.Arc]int64)
+	for e, c := range cg.capacities {
+		if amount.Cmp(c) > 0 {
+			extra[dijkstra.Arc{From: cg.address2index[e.From], To: cg.address2index[e.To]}] = hintDepletedWeight
+		}
+	}
+	for e, h := range cg.hints {
+		if !h.expiration.After(now) {
+			continue
+		}
+		arc := dijkstra.Arc{From: cg.address2index[e.From], To: cg.address2index[e.To]}
+		if extra[arc] == hintDepletedWeight {
+			continue
+		}
+		if amount.Cmp(h.max) >= 0 {
+			extra[arc] = hintDepletedWeight
+		} else if amount.Cmp(h.min) > 0 {
+			extra[arc] = hintUncertainWeight
+		}
+	}
+	return extra
+}
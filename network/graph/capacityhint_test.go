@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type noFeeCharger struct{}
+
+func (noFeeCharger) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	return big.NewInt(0)
+}
+
+func TestCapacityBucket(t *testing.T) {
+	cases := []struct {
+		capacity  int64
+		precision int
+		bucket    uint8
+	}{
+		{0, 4, 0},
+		{1, 4, 4},
+		{15, 4, 4},
+		{16, 4, 8},
+		{255, 4, 8},
+		{256, 4, 12},
+		{1000, 1, 10},
+	}
+	for _, c := range cases {
+		b := CapacityToBucket(big.NewInt(c.capacity), c.precision)
+		assert.EqualValues(t, c.bucket, b, "capacity %d", c.capacity)
+		min, max := CapacityBucketRange(b, c.precision)
+		assert.True(t, min.Int64() <= c.capacity, "capacity %d min %s", c.capacity, min)
+		assert.True(t, max.Int64() > c.capacity, "capacity %d max %s", c.capacity, max)
+	}
+	assert.EqualValues(t, 255, CapacityToBucket(new(big.Int).Lsh(big.NewInt(1), 300), 4))
+}
+
+func TestCapacityHintRouting(t *testing.T) {
+	us, a, b, target := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	cg := NewChannelGraph(us, utils.NewRandomAddress(), []common.Address{us, a, us, b, a, target, b, target})
+	amount := big.NewInt(100)
+	now := time.Now()
+	nws := cg.orderedNeighbours(us, target, amount, noFeeCharger{})
+	assert.Equal(t, 2, len(nws))
+
+	//a may not have enough
+	assert.True(t, cg.SetCapacityHint(a, target, big.NewInt(64), big.NewInt(1024), now.Add(time.Minute)))
+	nws = cg.orderedNeighbours(us, target, amount, noFeeCharger{})
+	assert.Equal(t, 2, len(nws))
+	assert.Equal(t, b, nws[0].neighbor)
+	assert.EqualValues(t, 1, nws[1].weight)
+
+	//a doesn't have enough, an older hint is ignored
+	assert.True(t, cg.SetCapacityHint(a, target, big.NewInt(0), big.NewInt(1), now.Add(2*time.Minute)))
+	assert.False(t, cg.SetCapacityHint(a, target, big.NewInt(1024), big.NewInt(2048), now.Add(time.Minute)))
+	nws = cg.orderedNeighbours(us, target, amount, noFeeCharger{})
+	assert.Equal(t, 1, len(nws))
+	assert.Equal(t, b, nws[0].neighbor)
+
+	//no channel between them
+	assert.False(t, cg.SetCapacityHint(a, b, big.NewInt(0), big.NewInt(1), now.Add(time.Minute)))
+
+	assert.Equal(t, 1, cg.RemoveExpiredCapacityHints(now.Add(3*time.Minute)))
+	assert.Equal(t, 0, cg.CapacityHintCount())
+	nws = cg.orderedNeighbours(us, target, amount, noFeeCharger{})
+	assert.Equal(t, 2, len(nws))
+}
+
+type constFeeCharger int64
+
+func (c constFeeCharger) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	return big.NewInt(int64(c))
+}
+
+func TestFindPathsCapacity(t *testing.T) {
+	from, a, b, c, target := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	cg := NewChannelGraph(utils.EmptyAddress, utils.NewRandomAddress(), []common.Address{from, a, a, target, from, b, b, c, c, target})
+	paths, err := cg.FindPaths(from, target, big.NewInt(10), 3, constFeeCharger(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(paths))
+	assert.Equal(t, []common.Address{from, a, target}, paths[0].Nodes)
+	//fee of from is not counted
+	assert.EqualValues(t, 5, paths[0].Weight)
+	assert.EqualValues(t, 10, paths[1].Weight)
+
+	//deposit of a-target is too small
+	cg.SetChannelCapacity(a, target, big.NewInt(9))
+	paths, err = cg.FindPaths(from, target, big.NewInt(10), 3, constFeeCharger(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(paths))
+	assert.Equal(t, []common.Address{from, b, c, target}, paths[0].Nodes)
+
+	cg.RemovePath(b, c)
+	_, err = cg.FindPaths(from, target, big.NewInt(10), 3, constFeeCharger(5))
+	assert.NotNil(t, err)
+	_, err = cg.FindPaths(from, target, big.NewInt(9), 3, constFeeCharger(5))
+	assert.Nil(t, err)
+}
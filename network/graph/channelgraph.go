@@ -9,6 +9,8 @@ import (
 
 	"strings"
 
+	"time"
+
 	"math/big"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/channel"
@@ -49,10 +51,13 @@ type ChannelGraph struct {
 	g                       *dijkstra.Graph
 	OurAddress              common.Address
 	TokenAddress            common.Address
+	RegistryAddress         common.Address //registry the token is registered on
 	PartenerAddress2Channel map[common.Address]*channel.Channel
 	ChannelAddress2Channel  map[common.Hash]*channel.Channel
 	address2index           map[common.Address]int
 	index2address           map[int]common.Address
+	hints                   map[route.Edge]*capacityHint //capacity hints received from partners
+	capacities              map[route.Edge]*big.Int      //total deposit of channels known from blockchain
 }
 
 /*
@@ -67,6 +72,8 @@ func NewChannelGraph(ourAddress, tokenAddress common.Address, edges []common.Add
 		address2index:           make(map[common.Address]int),
 		index2address:           make(map[int]common.Address),
 		g:                       dijkstra.NewGraph(),
+		hints:                   make(map[route.Edge]*capacityHint),
+		capacities:              make(map[route.Edge]*big.Int),
 	}
 	cg.makeGraph(edges)
 	cg.printGraph()
@@ -195,23 +202,102 @@ func (cg *ChannelGraph) ShortestPath(source, target common.Address, amount *big.
 	if sourceIndex == targetIndex {
 		return 0, nil
 	}
-	var g2 *dijkstra.Graph
-	if false { //make sure only be called in one thread.
-		g2 = cg.g.CloneGraph()
-	} else {
-		g2 = cg.g
+	cg.setFeeWeights(amount, feeCharger)
+	path, err := cg.g.Shortest(sourceIndex, targetIndex)
+	if err != nil {
+		return
 	}
-	for _, v := range g2.Verticies {
+	return path.Distance, nil
+}
+
+//setFeeWeights sets weight of arcs from every node to the fee it charges
+func (cg *ChannelGraph) setFeeWeights(amount *big.Int, feeCharger fee.Charger) {
+	for _, v := range cg.g.Verticies {
 		w := feeCharger.GetNodeChargeFee(cg.index2address[v.ID], cg.TokenAddress, amount).Int64()
 		if w > 0 { //for no fee policy, all nodes charge 0 ,so use the shortest path first.
 			v.SetWeight(w) // from v's fee is w.
 		}
 	}
-	path, err := g2.Shortest(sourceIndex, targetIndex)
+}
+
+/*
+kShortestPaths returns at most k loopless paths from neighbor to target which don't go back through ourAddress,
+every path starts with ourAddress. extra is added to weights when finding paths, paths through depleted channels are dropped.
+make sure only be called in one thread.
+*/
+func (cg *ChannelGraph) kShortestPaths(ourAddress, neighbor, target common.Address, k int, extra map[dijkstra.Arc]int64) (paths []*route.Path, distance int64, err error) {
+	if neighbor == target {
+		return []*route.Path{{Nodes: []common.Address{ourAddress, target}}}, 0, nil
+	}
+	ourIndex, ok1 := cg.address2index[ourAddress]
+	neighborIndex, ok2 := cg.address2index[neighbor]
+	targetIndex, ok3 := cg.address2index[target]
+	if !ok1 || !ok2 || !ok3 {
+		err = errAddressNotFoundInGraph
+		return
+	}
+	bests, err := cg.g.KShortest(neighborIndex, targetIndex, k, extra, ourIndex)
 	if err != nil {
 		return
 	}
-	return path.Distance, nil
+	paths = cg.bestsToPaths(bests, []common.Address{ourAddress}, 0)
+	if len(paths) == 0 {
+		err = dijkstra.ErrNoPath
+		return
+	}
+	distance = bests[0].Distance
+	return
+}
+
+/*
+bestsToPaths converts paths found to addresses, paths through depleted channels are dropped.
+weight of a path is sum of arcs from the `skip`th.
+*/
+func (cg *ChannelGraph) bestsToPaths(bests []dijkstra.BestPath, prefix []common.Address, skip int) (paths []*route.Path) {
+	for _, b := range bests {
+		if b.Distance >= hintDepletedWeight {
+			break
+		}
+		p := &route.Path{
+			Nodes: append([]common.Address{}, prefix...),
+		}
+		for j, i := range b.Path {
+			p.Nodes = append(p.Nodes, cg.index2address[i])
+			if j > skip {
+				w, _ := cg.g.Verticies[b.Path[j-1]].GetArc(i)
+				p.Weight += w
+			}
+		}
+		paths = append(paths, p)
+	}
+	return
+}
+
+/*
+FindPaths returns at most k loopless paths from source to target which may carry amount by what's known,
+weight of a path doesn't count fee of source. make sure only be called in one thread.
+*/
+func (cg *ChannelGraph) FindPaths(source, target common.Address, amount *big.Int, k int, feeCharger fee.Charger) (paths []*route.Path, err error) {
+	sourceIndex, ok := cg.address2index[source]
+	if !ok {
+		err = errAddressNotFoundInGraph
+		return
+	}
+	targetIndex, ok := cg.address2index[target]
+	if !ok {
+		err = errAddressNotFoundInGraph
+		return
+	}
+	cg.setFeeWeights(amount, feeCharger)
+	bests, err := cg.g.KShortest(sourceIndex, targetIndex, k, cg.capacityHintWeights(amount, time.Now()))
+	if err != nil {
+		return
+	}
+	paths = cg.bestsToPaths(bests, nil, 1)
+	if len(paths) == 0 {
+		err = dijkstra.ErrNoPath
+	}
+	return
 }
 
 //RemoveChannel remove a channel from graph,and i'm a participant of this channel
@@ -231,6 +317,7 @@ func (cg *ChannelGraph) RemovePath(source, target common.Address) {
 	if !ok {
 		return
 	}
+	cg.removeCapacityHints(source, target)
 	err := cg.g.DeleteArc(sourceIndex, targetIndex)
 	if err != nil {
 		log.Error(fmt.Sprintf("remove arc %d-%d err %s", sourceIndex, targetIndex, err))
@@ -264,7 +351,9 @@ func (cg *ChannelGraph) getNeighbours() []common.Address {
 
 type neighborWeight struct {
 	neighbor common.Address
-	weight   int64 //nerghbor to target's hops
+	weight   int64         //nerghbor to target's hops
+	distance int64         //weight and penalty of capacity hints
+	paths    []*route.Path //shortest paths to target through neighbor
 }
 type neighborWeightList []*neighborWeight
 
@@ -272,7 +361,7 @@ func (nw neighborWeightList) Len() int {
 	return len(nw)
 }
 func (nw neighborWeightList) Less(i, j int) bool {
-	return nw[i].weight < nw[j].weight
+	return nw[i].distance < nw[j].distance
 }
 func (nw neighborWeightList) Swap(i, j int) {
 	var temp *neighborWeight
@@ -282,21 +371,24 @@ func (nw neighborWeightList) Swap(i, j int) {
 }
 
 /*
-all the neighbors that can reach target
-they are ordered by hops to the target
+all the neighbors that can reach target without going back through us
+they are ordered by hops to the target of their shortest path, channels may be too small by capacity hints count more.
+only the shortest path of every neighbor is found, more are found by its route only when it's penalized.
 */
 func (cg *ChannelGraph) orderedNeighbours(ourAddress, targetAddress common.Address, amount *big.Int, charger fee.Charger) neighborWeightList {
 
 	neighbors := cg.getNeighbours()
 	var nws neighborWeightList
+	cg.setFeeWeights(amount, charger)
+	extra := cg.capacityHintWeights(amount, time.Now())
 	for _, n := range neighbors {
-		w, err := cg.ShortestPath(n, targetAddress, amount, charger)
+		paths, distance, err := cg.kShortestPaths(ourAddress, n, targetAddress, 1, extra)
 		if err != nil {
 			continue
 		}
-		nws = append(nws, &neighborWeight{n, w})
+		nws = append(nws, &neighborWeight{n, paths[0].Weight, distance, paths})
 	}
-	sort.Sort(nws)
+	sort.Stable(nws)
 	return nws
 }
 
@@ -324,35 +416,91 @@ func (cg *ChannelGraph) GetBestRoutes(nodesStatus NodesStatusGetter, ourAddress
 		return
 	}
 	for _, nw := range nws {
-		c := cg.GetPartenerAddress2Channel(nw.neighbor)
 		//don't send the message backwards
 		if excludeAddresses[nw.neighbor] {
 			continue
 		}
-		if !c.CanTransfer() {
-			log.Debug(fmt.Sprintf("channel %s-%s cannot transfer ,ignoring ..", utils.APex(ourAddress), utils.APex(nw.neighbor)))
+		routeState := cg.neighborRoute(nodesStatus, ourAddress, nw.neighbor, targetAdress, amount, feeCharger)
+		if routeState == nil {
+			continue
+		}
+		routeState.Paths = nw.paths
+		neighbor := nw.neighbor
+		routeState.SetMorePaths(func(k int) []*route.Path {
+			cg.setFeeWeights(amount, feeCharger)
+			paths, _, err := cg.kShortestPaths(ourAddress, neighbor, targetAdress, k, cg.capacityHintWeights(amount, time.Now()))
+			if err != nil {
+				return nil
+			}
+			return paths
+		})
+		if routeState.Fee.Cmp(utils.BigInt0) > 0 {
+			routeState.TotalFee = big.NewInt(int64(nw.weight))
+		} else { //no fee policy,
+			routeState.TotalFee = utils.BigInt0
+		}
+
+		onlineNodes = append(onlineNodes, routeState)
+	}
+	return
+}
+
+//neighborRoute returns route through neighbor, nil if the channel can't transfer amount or neighbor is offline
+func (cg *ChannelGraph) neighborRoute(nodesStatus NodesStatusGetter, ourAddress, neighbor, targetAdress common.Address, amount *big.Int, feeCharger fee.Charger) *route.State {
+	c := cg.GetPartenerAddress2Channel(neighbor)
+	if c == nil {
+		return nil
+	}
+	if !c.CanTransfer() {
+		log.Debug(fmt.Sprintf("channel %s-%s cannot transfer ,ignoring ..", utils.APex(ourAddress), utils.APex(neighbor)))
+		return nil
+	}
+	if amount.Cmp(c.Distributable()) > 0 {
+		log.Debug(fmt.Sprintf("channel %s-%s doesn't have enough funds[%d],ignoring...", utils.APex(ourAddress), utils.APex(neighbor), amount))
+		return nil
+	}
+	deviceType, isOnline := nodesStatus.GetNetworkStatus(neighbor)
+	if !isOnline || (deviceType == xmpptransport.TypeMobile && neighbor != targetAdress) {
+		log.Debug(fmt.Sprintf("partener %s network ignored.. isOnline:%v,deviceType:%s", utils.APex(neighbor), isOnline, deviceType))
+		return nil
+	}
+	return Channel2RouteState(c, neighbor, amount, feeCharger)
+}
+
+/*
+GetRoutesByPaths returns routes by paths found by others, for example a pathfinding service,
+paths must start with ourAddress, they are grouped by the first hop and keep their order.
+*/
+func (cg *ChannelGraph) GetRoutesByPaths(nodesStatus NodesStatusGetter, ourAddress common.Address,
+	targetAdress common.Address, amount *big.Int, paths []*route.Path, feeCharger fee.Charger) (onlineNodes []*route.State) {
+	m := make(map[common.Address]*route.State)
+	for _, p := range paths {
+		if len(p.Nodes) < 2 || p.Nodes[0] != ourAddress || p.Nodes[len(p.Nodes)-1] != targetAdress {
 			continue
 		}
-		if amount.Cmp(c.Distributable()) > 0 {
-			log.Debug(fmt.Sprintf("channel %s-%s doesn't have enough funds[%d],ignoring...", utils.APex(ourAddress), utils.APex(nw.neighbor), amount))
+		neighbor := p.Nodes[1]
+		if r, ok := m[neighbor]; ok {
+			if r != nil {
+				r.Paths = append(r.Paths, p)
+			}
 			continue
 		}
-		deviceType, isOnline := nodesStatus.GetNetworkStatus(nw.neighbor)
-		if !isOnline || (deviceType == xmpptransport.TypeMobile && nw.neighbor != targetAdress) {
-			log.Debug(fmt.Sprintf("partener %s network ignored.. isOnline:%v,deviceType:%s", utils.APex(nw.neighbor), isOnline, deviceType))
+		routeState := cg.neighborRoute(nodesStatus, ourAddress, neighbor, targetAdress, amount, feeCharger)
+		m[neighbor] = routeState
+		if routeState == nil {
 			continue
 		}
-		routeState := Channel2RouteState(c, nw.neighbor, amount, feeCharger)
+		routeState.Paths = []*route.Path{p}
 		if routeState.Fee.Cmp(utils.BigInt0) > 0 {
-			routeState.TotalFee = big.NewInt(int64(nw.weight))
-		} else { //no fee policy,
+			routeState.TotalFee = big.NewInt(p.Weight)
+		} else {
 			routeState.TotalFee = utils.BigInt0
 		}
-
 		onlineNodes = append(onlineNodes, routeState)
 	}
 	return
 }
+
 func (cg *ChannelGraph) haveNodes() bool {
 	return len(cg.g.Verticies) > 0
 }
@@ -0,0 +1,178 @@
+package graph
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/dijkstra"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//Node is a participant of channels in exported graph
+type Node struct {
+	Address    common.Address `json:"address"`
+	Degree     int            `json:"degree"`
+	IsOnline   bool           `json:"is_online"`
+	DeviceType string         `json:"device_type,omitempty"`
+	IsMe       bool           `json:"is_me,omitempty"`
+}
+
+/*
+Edge is what From can send to To through their channel, every channel has two edges.
+only channels of this node have a state and capacity, others are just known to be open,
+capacity hints received are given as a range instead.
+*/
+type Edge struct {
+	From        common.Address `json:"from"`
+	To          common.Address `json:"to"`
+	State       string         `json:"state"`
+	Capacity    *big.Int       `json:"capacity,omitempty"`     //how much From can send now
+	CapacityMin *big.Int       `json:"capacity_min,omitempty"` //from capacity hint of From
+	CapacityMax *big.Int       `json:"capacity_max,omitempty"`
+	IsOnline    bool           `json:"is_online"` //both From and To are online
+}
+
+//Stats of the channel network
+type Stats struct {
+	Nodes              int              `json:"nodes"`
+	Channels           int              `json:"channels"`
+	Diameter           int              `json:"diameter"`            //most hops of all shortest paths
+	DegreeDistribution map[int]int      `json:"degree_distribution"` //degree => how many nodes
+	ArticulationPoints []common.Address `json:"articulation_points"` //nodes which disconnect the network when gone
+}
+
+//Export is a snapshot of ChannelGraph
+type Export struct {
+	Token common.Address `json:"token"`
+	Nodes []*Node        `json:"nodes"`
+	Edges []*Edge        `json:"edges"`
+	Stats *Stats         `json:"stats,omitempty"` //nil until CalcStats
+}
+
+/*
+Export takes a snapshot of the graph, online status of every node is from nodesStatus.
+nodes without any channel left are not exported. make sure only be called in one thread.
+it only copies nodes and edges, statistics are left to CalcStats, which needs no lock of the graph.
+*/
+func (cg *ChannelGraph) Export(nodesStatus NodesStatusGetter) *Export {
+	e := &Export{
+		Token: cg.TokenAddress,
+	}
+	online := make(map[common.Address]bool)
+	for i := 0; i < len(cg.g.Verticies); i++ {
+		addr := cg.index2address[i]
+		degree := cg.g.Degree(i)
+		if degree == 0 && addr != cg.OurAddress {
+			continue
+		}
+		n := &Node{
+			Address: addr,
+			Degree:  degree,
+			IsMe:    addr == cg.OurAddress,
+		}
+		if n.IsMe {
+			n.IsOnline = true
+		} else {
+			n.DeviceType, n.IsOnline = nodesStatus.GetNetworkStatus(addr)
+		}
+		online[addr] = n.IsOnline
+		e.Nodes = append(e.Nodes, n)
+	}
+	now := time.Now()
+	for i := 0; i < len(cg.g.Verticies); i++ {
+		neighbors, err := cg.g.GetAllNeighbors(i)
+		if err != nil {
+			continue
+		}
+		sort.Ints(neighbors)
+		for _, j := range neighbors {
+			edge := &Edge{
+				From:     cg.index2address[i],
+				To:       cg.index2address[j],
+				State:    "opened",
+				IsOnline: online[cg.index2address[i]] && online[cg.index2address[j]],
+			}
+			if edge.From == cg.OurAddress {
+				if c := cg.PartenerAddress2Channel[edge.To]; c != nil {
+					edge.State = c.State.String()
+					edge.Capacity = c.Distributable()
+				}
+			} else if edge.To == cg.OurAddress {
+				if c := cg.PartenerAddress2Channel[edge.From]; c != nil {
+					edge.State = c.State.String()
+					edge.Capacity = c.PartnerState.Distributable(c.OurState)
+				}
+			} else if h := cg.hints[route.Edge{From: edge.From, To: edge.To}]; h != nil && h.expiration.After(now) {
+				edge.CapacityMin, edge.CapacityMax = h.min, h.max
+			}
+			e.Edges = append(e.Edges, edge)
+		}
+	}
+	return e
+}
+
+/*
+CalcStats fills Stats from nodes and edges of the snapshot.
+Diameter is O(V*(V+E)), so call it outside of the main loop.
+*/
+func (e *Export) CalcStats() {
+	stats := &Stats{
+		Nodes:              len(e.Nodes),
+		Channels:           len(e.Edges) / 2,
+		DegreeDistribution: make(map[int]int),
+		ArticulationPoints: []common.Address{},
+	}
+	g := dijkstra.NewGraph()
+	index := make(map[common.Address]int)
+	for i, n := range e.Nodes {
+		g.AddVertex(i)
+		index[n.Address] = i
+		stats.DegreeDistribution[n.Degree]++
+	}
+	for _, edge := range e.Edges {
+		from, ok1 := index[edge.From]
+		to, ok2 := index[edge.To]
+		if ok1 && ok2 {
+			g.AddArc(from, to, 1)
+		}
+	}
+	stats.Diameter = g.Diameter()
+	for _, i := range g.ArticulationPoints() {
+		stats.ArticulationPoints = append(stats.ArticulationPoints, e.Nodes[i].Address)
+	}
+	e.Stats = stats
+}
+
+//DOT is the graph in graphviz dot language, one undirected edge for every channel
+func (e *Export) DOT() string {
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "graph \"%s\" {\n", e.Token.String())
+	for _, n := range e.Nodes {
+		attrs := fmt.Sprintf("label=\"%s\"", utils.APex2(n.Address))
+		if n.IsMe {
+			attrs += ",shape=doublecircle"
+		}
+		if !n.IsOnline {
+			attrs += ",style=dashed"
+		}
+		fmt.Fprintf(buf, "  \"%s\" [%s];\n", n.Address.String(), attrs)
+	}
+	for _, edge := range e.Edges {
+		//edges of the other direction are the same channel
+		if bytes.Compare(edge.From[:], edge.To[:]) > 0 {
+			continue
+		}
+		attrs := fmt.Sprintf("label=\"%s\"", edge.State)
+		if !edge.IsOnline {
+			attrs += ",style=dashed"
+		}
+		fmt.Fprintf(buf, "  \"%s\" -- \"%s\" [%s];\n", edge.From.String(), edge.To.String(), attrs)
+	}
+	buf.WriteString("}\n")
+	return buf.String()
+}
@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type testNodesStatus map[common.Address]bool
+
+func (s testNodesStatus) GetNetworkStatus(addr common.Address) (deviceType string, isOnline bool) {
+	return "", s[addr]
+}
+
+func TestExport(t *testing.T) {
+	us, a, b, c := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	//a is the only way from us to b and c
+	cg := NewChannelGraph(us, utils.NewRandomAddress(), []common.Address{us, a, a, b, a, c, b, c})
+	assert.True(t, cg.SetCapacityHint(a, b, big.NewInt(16), big.NewInt(256), time.Now().Add(time.Minute)))
+	e := cg.Export(testNodesStatus{a: true, b: true})
+	assert.Nil(t, e.Stats)
+	e.CalcStats()
+	assert.Equal(t, 4, e.Stats.Nodes)
+	assert.Equal(t, 4, e.Stats.Channels)
+	assert.Equal(t, 8, len(e.Edges))
+	assert.Equal(t, 2, e.Stats.Diameter)
+	assert.Equal(t, []common.Address{a}, e.Stats.ArticulationPoints)
+	assert.Equal(t, map[int]int{1: 1, 2: 2, 3: 1}, e.Stats.DegreeDistribution)
+	for _, n := range e.Nodes {
+		assert.Equal(t, n.Address != c, n.IsOnline)
+		assert.Equal(t, n.Address == us, n.IsMe)
+	}
+	for _, edge := range e.Edges {
+		assert.Equal(t, edge.From != c && edge.To != c, edge.IsOnline)
+		if edge.From == a && edge.To == b {
+			assert.EqualValues(t, 16, edge.CapacityMin.Int64())
+			assert.EqualValues(t, 256, edge.CapacityMax.Int64())
+		} else {
+			assert.Nil(t, edge.CapacityMax)
+		}
+	}
+	_, err := json.Marshal(e)
+	assert.Nil(t, err)
+	dot := e.DOT()
+	assert.True(t, strings.HasPrefix(dot, "graph "))
+	assert.Equal(t, 4, strings.Count(dot, " -- "))
+
+	//nodes without channels are gone
+	cg.RemovePath(us, a)
+	e = cg.Export(testNodesStatus{})
+	e.CalcStats()
+	assert.Equal(t, 4, e.Stats.Nodes) //we are always there
+	cg.RemovePath(a, c)
+	cg.RemovePath(b, c)
+	e = cg.Export(testNodesStatus{})
+	e.CalcStats()
+	assert.Equal(t, 3, e.Stats.Nodes)
+	assert.Equal(t, 1, e.Stats.Channels)
+}
@@ -0,0 +1,452 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/fatedier/frp/src/utils/log"
+)
+
+//endpoint is one eth rpc server, its health is updated by every check
+type endpoint struct {
+	url         string
+	client      *ethclient.Client
+	status      netshare.Status
+	blockNumber int64
+	latency     time.Duration //moving average of checks
+	errors      int           //successive failures
+	lastError   string
+}
+
+//EndpointStatus is health of one eth rpc endpoint
+type EndpointStatus struct {
+	URL         string          `json:"url"`
+	Status      netshare.Status `json:"status"`
+	Primary     bool            `json:"primary"`
+	BlockNumber int64           `json:"block_number"`
+	LatencyMs   int64           `json:"latency_ms"`
+	Errors      int             `json:"errors"`
+	LastError   string          `json:"last_error,omitempty"`
+	Score       int64           `json:"score"`
+}
+
+//splitEndpoints returns urls of `rawurl`, which are separated by comma
+func splitEndpoints(rawurl string) (urls []string) {
+	for _, u := range strings.Split(rawurl, ",") {
+		u = strings.TrimSpace(u)
+		if len(u) > 0 {
+			urls = append(urls, u)
+		}
+	}
+	return
+}
+
+/*
+score is health of `ep`, 0 if it's not usable,
+the higher its block number, the fewer its errors and the lower its latency, the higher its score.
+*/
+func (ep *endpoint) score(maxBlockNumber int64) int64 {
+	if ep.status != netshare.Connected {
+		return 0
+	}
+	s := 1000 - (maxBlockNumber-ep.blockNumber)*100 - int64(ep.errors)*200 - int64(ep.latency/time.Millisecond)
+	if s < 1 {
+		s = 1
+	}
+	return s
+}
+
+//healthy returns false if `ep` is disconnected, fails too much or lags too far behind
+func (ep *endpoint) healthy(maxBlockNumber int64) bool {
+	return ep.status == netshare.Connected && ep.errors < params.EthEndpointMaxErrors &&
+		maxBlockNumber-ep.blockNumber <= params.EthEndpointMaxLag
+}
+
+func (ep *endpoint) fail(err error) {
+	ep.errors++
+	ep.lastError = err.Error()
+}
+
+//maxBlockNumber is the highest block of connected endpoints, caller must hold epLock
+func (c *SafeEthClient) maxBlockNumber() (n int64) {
+	for _, ep := range c.endpoints {
+		if ep.status == netshare.Connected && ep.blockNumber > n {
+			n = ep.blockNumber
+		}
+	}
+	return
+}
+
+//rankedEndpoints returns connected endpoints ordered by score, caller must hold epLock
+func (c *SafeEthClient) rankedEndpoints() (eps []*endpoint) {
+	max := c.maxBlockNumber()
+	for _, ep := range c.endpoints {
+		if ep.status == netshare.Connected {
+			eps = append(eps, ep)
+		}
+	}
+	sort.SliceStable(eps, func(i, j int) bool {
+		return eps[i].score(max) > eps[j].score(max)
+	})
+	return
+}
+
+//availableEndpoints returns endpoints answered the last check ordered by score, caller must hold epLock
+func (c *SafeEthClient) availableEndpoints() (eps []*endpoint) {
+	for _, ep := range c.rankedEndpoints() {
+		if ep.errors == 0 {
+			eps = append(eps, ep)
+		}
+	}
+	return
+}
+
+//checkEndpoint dials `ep` if it's disconnected, and updates its block number and latency
+func (c *SafeEthClient) checkEndpoint(ep *endpoint) {
+	c.epLock.Lock()
+	client := ep.client
+	c.epLock.Unlock()
+	if client == nil {
+		cl, err := ethclient.Dial(ep.url)
+		c.epLock.Lock()
+		if err != nil {
+			ep.status = netshare.Disconnected
+			ep.fail(err)
+			c.epLock.Unlock()
+			return
+		}
+		//dial over http always succeeds, it's connected only after answering
+		ep.client = cl
+		ep.latency = 0
+		c.epLock.Unlock()
+		client = cl
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), params.EthHealthCheckTimeout)
+	defer cancel()
+	start := time.Now()
+	h, err := client.HeaderByNumber(ctx, nil)
+	c.epLock.Lock()
+	defer c.epLock.Unlock()
+	if ep.client != client {
+		//closed by failover during the check
+		return
+	}
+	if err != nil {
+		ep.fail(err)
+		//the primary is closed when failing over, or its subscriptions would be broken
+		if ep.errors >= params.EthEndpointMaxErrors && ep != c.primary {
+			ep.client.Close()
+			ep.client = nil
+			ep.status = netshare.Disconnected
+		}
+		return
+	}
+	latency := time.Since(start)
+	if ep.latency == 0 {
+		ep.latency = latency
+	} else {
+		ep.latency = (ep.latency*3 + latency) / 4
+	}
+	ep.blockNumber = h.Number.Int64()
+	ep.errors = 0
+	ep.status = netshare.Connected
+}
+
+//checkEndpoints checks all endpoints at the same time
+func (c *SafeEthClient) checkEndpoints() {
+	c.checkLock.Lock()
+	defer c.checkLock.Unlock()
+	wg := sync.WaitGroup{}
+	for _, ep := range c.endpoints {
+		wg.Add(1)
+		go func(ep *endpoint) {
+			defer wg.Done()
+			c.checkEndpoint(ep)
+		}(ep)
+	}
+	wg.Wait()
+}
+
+//setPrimary makes `ep` the endpoint used by all calls, returns the client replaced
+func (c *SafeEthClient) setPrimary(ep *endpoint) (old *ethclient.Client) {
+	c.lock.Lock()
+	old = c.Client
+	c.Client = ep.client
+	c.lock.Unlock()
+	c.epLock.Lock()
+	c.primary = ep
+	c.epLock.Unlock()
+	return
+}
+
+/*
+failover replaces the primary endpoint by a healthy one when it fails too much or lags too far behind.
+the unhealthy primary is closed, so subscriptions on it are broken and made again on the new one,
+as they are after a reconnect.
+*/
+func (c *SafeEthClient) failover() {
+	c.epLock.Lock()
+	primary := c.primary
+	max := c.maxBlockNumber()
+	if primary == nil || primary.healthy(max) {
+		c.epLock.Unlock()
+		return
+	}
+	var best *endpoint
+	for _, ep := range c.rankedEndpoints() {
+		if ep != primary && ep.healthy(max) {
+			best = ep
+			break
+		}
+	}
+	if best == nil {
+		c.epLock.Unlock()
+		return
+	}
+	log.Warn("eth rpc endpoint %s is unhealthy (block %d, highest %d, errors %d), failover to %s",
+		primary.url, primary.blockNumber, max, primary.errors, best.url)
+	primary.client = nil
+	primary.status = netshare.Disconnected
+	c.epLock.Unlock()
+	old := c.setPrimary(best)
+	if old != nil {
+		old.Close()
+	}
+}
+
+//monitorEndpoints checks health of endpoints periodically, and fails over when the primary is unhealthy
+func (c *SafeEthClient) monitorEndpoints() {
+	for {
+		select {
+		case <-c.quitChan:
+			return
+		case <-time.After(params.EthHealthCheckInterval):
+		}
+		c.checkEndpoints()
+		if c.Status == netshare.Connected {
+			c.failover()
+		}
+	}
+}
+
+//EndpointStatus returns health of every eth rpc endpoint
+func (c *SafeEthClient) EndpointStatus() (ss []*EndpointStatus) {
+	c.epLock.Lock()
+	defer c.epLock.Unlock()
+	max := c.maxBlockNumber()
+	for _, ep := range c.endpoints {
+		ss = append(ss, &EndpointStatus{
+			URL:         ep.url,
+			Status:      ep.status,
+			Primary:     ep == c.primary,
+			BlockNumber: ep.blockNumber,
+			LatencyMs:   int64(ep.latency / time.Millisecond),
+			Errors:      ep.errors,
+			LastError:   ep.lastError,
+			Score:       ep.score(max),
+		})
+	}
+	return
+}
+
+//primaryURL returns url of the primary endpoint, empty if not connected
+func (c *SafeEthClient) primaryURL() string {
+	c.epLock.Lock()
+	defer c.epLock.Unlock()
+	if c.primary == nil {
+		return ""
+	}
+	return c.primary.url
+}
+
+//isTransportError returns true if `err` means the endpoint is not reachable, instead of an answer of it
+func isTransportError(err error) bool {
+	if err == nil || err == ethereum.NotFound {
+		return false
+	}
+	_, ok := err.(rpc.Error)
+	return !ok
+}
+
+/*
+sendTransactionFailover sends `tx` to other endpoints in order of score, after the primary fails to.
+a transaction already known by an endpoint was sent by the primary before the failure.
+clients are copied under epLock, checks and failover may close an endpoint and clear its client meanwhile.
+*/
+func (c *SafeEthClient) sendTransactionFailover(ctx context.Context, tx *types.Transaction, err error) error {
+	type backup struct {
+		ep     *endpoint
+		client *ethclient.Client
+	}
+	c.epLock.Lock()
+	if c.primary != nil {
+		c.primary.fail(err)
+	}
+	var backups []backup
+	for _, ep := range c.rankedEndpoints() {
+		if ep != c.primary && ep.client != nil {
+			backups = append(backups, backup{ep, ep.client})
+		}
+	}
+	c.epLock.Unlock()
+	for _, b := range backups {
+		err2 := b.client.SendTransaction(ctx, tx)
+		if err2 == nil || strings.Contains(err2.Error(), "known transaction") {
+			log.Warn("send tx %s by %s failed %s, sent by %s", tx.Hash().String(), c.primaryURL(), err, b.ep.url)
+			return nil
+		}
+		if !isTransportError(err2) {
+			return err2
+		}
+		c.epLock.Lock()
+		if b.ep.client == b.client {
+			b.ep.fail(err2)
+		}
+		c.epLock.Unlock()
+	}
+	return err
+}
+
+/*
+quorumRead calls `read` on all connected endpoints,
+and returns the result which at least quorum of them agree on, compared by json.
+*/
+func (c *SafeEthClient) quorumRead(name string, read func(client *ethclient.Client) (interface{}, error)) (interface{}, error) {
+	c.epLock.Lock()
+	eps := c.rankedEndpoints()
+	var clients []*ethclient.Client
+	for _, ep := range eps {
+		if ep.client != nil {
+			clients = append(clients, ep.client)
+		}
+	}
+	c.epLock.Unlock()
+	if len(clients) < c.quorum {
+		return nil, fmt.Errorf("%s needs %d eth rpc endpoints, only %d connected", name, c.quorum, len(clients))
+	}
+	type result struct {
+		v   interface{}
+		key []byte
+		err error
+	}
+	results := make([]result, len(clients))
+	wg := sync.WaitGroup{}
+	for i, client := range clients {
+		wg.Add(1)
+		go func(i int, client *ethclient.Client) {
+			defer wg.Done()
+			r := &results[i]
+			r.v, r.err = read(client)
+			if r.err == nil {
+				r.key, r.err = json.Marshal(r.v)
+			}
+		}(i, client)
+	}
+	wg.Wait()
+	var lastErr error
+	for i, r := range results {
+		if r.err != nil {
+			lastErr = r.err
+			continue
+		}
+		votes := 0
+		for _, r2 := range results[i:] {
+			if r2.err == nil && bytes.Equal(r.key, r2.key) {
+				votes++
+			}
+		}
+		if votes >= c.quorum {
+			return r.v, nil
+		}
+	}
+	if lastErr != nil {
+		return nil, fmt.Errorf("%s has no quorum of %d eth rpc endpoints, last err %s", name, c.quorum, lastErr)
+	}
+	return nil, fmt.Errorf("%s has no quorum of %d eth rpc endpoints, results differ", name, c.quorum)
+}
+
+/*
+quorumBlockNumber is the highest block at least quorum of connected endpoints have, nil means the latest.
+it asks endpoints for their latest block now, blocks of the last check may be far behind.
+an endpoint lagging far behind doesn't hold others back, it just fails to vote.
+*/
+func (c *SafeEthClient) quorumBlockNumber(ctx context.Context) *big.Int {
+	c.epLock.Lock()
+	var clients []*ethclient.Client
+	for _, ep := range c.rankedEndpoints() {
+		if ep.client != nil {
+			clients = append(clients, ep.client)
+		}
+	}
+	c.epLock.Unlock()
+	if len(clients) < c.quorum {
+		return nil
+	}
+	var numbers []int64
+	lock := sync.Mutex{}
+	wg := sync.WaitGroup{}
+	for _, client := range clients {
+		wg.Add(1)
+		go func(client *ethclient.Client) {
+			defer wg.Done()
+			h, err := client.HeaderByNumber(ctx, nil)
+			if err != nil {
+				return
+			}
+			lock.Lock()
+			numbers = append(numbers, h.Number.Int64())
+			lock.Unlock()
+		}(client)
+	}
+	wg.Wait()
+	if len(numbers) < c.quorum {
+		return nil
+	}
+	sort.Slice(numbers, func(i, j int) bool {
+		return numbers[i] > numbers[j]
+	})
+	n := numbers[c.quorum-1]
+	if n <= 0 {
+		return nil
+	}
+	return big.NewInt(n)
+}
+
+//quorumCallContract calls contract on a block quorum of endpoints have, so the results are comparable
+func (c *SafeEthClient) quorumCallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if blockNumber == nil {
+		blockNumber = c.quorumBlockNumber(ctx)
+	}
+	v, err := c.quorumRead("CallContract", func(client *ethclient.Client) (interface{}, error) {
+		return client.CallContract(ctx, msg, blockNumber)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.([]byte), nil
+}
+
+//quorumTransactionReceipt returns the receipt only after quorum of endpoints have the same one
+func (c *SafeEthClient) quorumTransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	v, err := c.quorumRead("TransactionReceipt", func(client *ethclient.Client) (interface{}, error) {
+		return client.TransactionReceipt(ctx, txHash)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*types.Receipt), nil
+}
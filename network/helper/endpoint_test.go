@@ -0,0 +1,185 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+//newFakeEthServers starts `n` fake endpoints, all at block 10
+func newFakeEthServers(t *testing.T, n int) (fs []*FakeEth, hs []*httptest.Server, urls string) {
+	var us []string
+	for i := 0; i < n; i++ {
+		f := &FakeEth{number: 10}
+		srv := rpc.NewServer()
+		err := srv.RegisterName("eth", f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := httptest.NewServer(srv)
+		fs = append(fs, f)
+		hs = append(hs, h)
+		us = append(us, h.URL)
+	}
+	urls = strings.Join(us, ", ")
+	return
+}
+
+func TestEndpointFailover(t *testing.T) {
+	fs, hs, urls := newFakeEthServers(t, 2)
+	for _, h := range hs {
+		defer h.Close()
+	}
+	c, err := NewSafeClient(urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ss := c.EndpointStatus()
+	assert.Len(t, ss, 2)
+	assert.EqualValues(t, netshare.Connected, ss[0].Status)
+	assert.EqualValues(t, netshare.Connected, ss[1].Status)
+	assert.EqualValues(t, 10, ss[0].BlockNumber)
+	primary := hs[0].URL
+	if ss[1].Primary {
+		primary = hs[1].URL
+	}
+	//primary lags behind
+	if primary == hs[0].URL {
+		fs[1].mine(10)
+	} else {
+		fs[0].mine(10)
+	}
+	c.checkEndpoints()
+	c.failover()
+	assert.NotEqual(t, primary, c.primaryURL())
+	h, err := c.HeaderByNumber(context.Background(), nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 20, h.Number.Int64())
+	//the old primary is redialed as a backup
+	c.checkEndpoints()
+	for _, s := range c.EndpointStatus() {
+		assert.EqualValues(t, netshare.Connected, s.Status)
+	}
+
+	//new primary is down
+	for _, h := range hs {
+		if h.URL == c.primaryURL() {
+			h.Close()
+		}
+	}
+	c.RecoverDisconnect()
+	assert.Equal(t, primary, c.primaryURL())
+}
+
+func TestEndpointRecoverDisconnect(t *testing.T) {
+	_, hs, urls := newFakeEthServers(t, 2)
+	defer hs[1].Close()
+	c, err := NewSafeClient(urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	hs[0].Close()
+	c.RecoverDisconnect()
+	assert.Equal(t, hs[1].URL, c.primaryURL())
+	assert.True(t, c.IsConnected())
+	_, err = c.HeaderByNumber(context.Background(), nil)
+	assert.Nil(t, err)
+	ss := c.EndpointStatus()
+	assert.Equal(t, 1, ss[0].Errors)
+	assert.True(t, ss[0].Score < ss[1].Score)
+	assert.True(t, ss[1].Primary)
+}
+
+func TestEndpointQuorum(t *testing.T) {
+	fs, hs, urls := newFakeEthServers(t, 3)
+	for _, h := range hs {
+		defer h.Close()
+	}
+	_, err := NewSafeClientWithQuorum(urls, 4)
+	assert.NotNil(t, err)
+	c, err := NewSafeClientWithQuorum(urls, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	//the lowest endpoint doesn't hold back the other two
+	fs[0].mine(2)
+	fs[1].mine(1)
+	c.checkEndpoints()
+	assert.EqualValues(t, 11, c.quorumBlockNumber(context.Background()).Int64())
+	//blocks mined after the last check are used too
+	fs[1].mine(1)
+	fs[2].mine(3)
+	assert.EqualValues(t, 12, c.quorumBlockNumber(context.Background()).Int64())
+	fs[0].result = []byte{1}
+	fs[1].result = []byte{2}
+	fs[2].result = []byte{3}
+	_, err = c.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	assert.NotNil(t, err, "results differ")
+	fs[2].result = []byte{2}
+	r, err := c.CallContract(context.Background(), ethereum.CallMsg{}, nil)
+	assert.Nil(t, err)
+	assert.EqualValues(t, []byte{2}, r)
+}
+
+//run with -race, backups are closed and redialed while the transaction is sent to them
+func TestEndpointSendTransactionFailover(t *testing.T) {
+	fs, hs, urls := newFakeEthServers(t, 3)
+	for _, h := range hs {
+		defer h.Close()
+	}
+	c, err := NewSafeClient(urls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	tx := types.NewTransaction(0, common.Address{}, big.NewInt(0), 0, big.NewInt(0), nil)
+	primaryErr := errors.New("primary failed")
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			c.epLock.Lock()
+			for _, ep := range c.endpoints {
+				if ep != c.primary && ep.client != nil {
+					ep.client.Close()
+					ep.client = nil
+					ep.status = netshare.Disconnected
+				}
+			}
+			c.epLock.Unlock()
+			c.checkEndpoints()
+		}
+	}()
+	for i := 0; i < 20; i++ {
+		c.sendTransactionFailover(context.Background(), tx, primaryErr)
+	}
+	<-done
+	c.checkEndpoints()
+	assert.Nil(t, c.sendTransactionFailover(context.Background(), tx, primaryErr))
+	txs := 0
+	for _, f := range fs {
+		f.lock.Lock()
+		txs += f.txs
+		f.lock.Unlock()
+	}
+	assert.True(t, txs > 0)
+}
+
+func TestSplitEndpoints(t *testing.T) {
+	assert.EqualValues(t, []string{"ws://a:8546", "http://b:8545"}, splitEndpoints(" ws://a:8546,,http://b:8545 "))
+	_, err := NewSafeClient(" , ")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,170 @@
+package helper
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/fatedier/frp/src/utils/log"
+)
+
+//modes of how SafeEthClient gets new blocks and logs
+const (
+	//ModeAuto subscribes, and polls when the endpoint doesn't support notifications, such as http
+	ModeAuto = "auto"
+	//ModePush always subscribes
+	ModePush = "push"
+	//ModePoll always polls
+	ModePoll = "poll"
+)
+
+//PollConfig is how SafeEthClient polls new blocks and logs
+type PollConfig struct {
+	Mode      string
+	Interval  time.Duration //between two polls
+	ChunkSize int64         //max blocks of one FilterLogs
+}
+
+//DefaultPollConfig subscribes if possible
+var DefaultPollConfig = PollConfig{
+	Mode:      ModeAuto,
+	Interval:  params.DefaultEthPollInterval,
+	ChunkSize: params.DefaultEthPollChunkSize,
+}
+
+//SetPollConfig changes how new blocks and logs are got, it takes effect on subscriptions made after
+func (c *SafeEthClient) SetPollConfig(cfg PollConfig) error {
+	if cfg.Mode != ModeAuto && cfg.Mode != ModePush && cfg.Mode != ModePoll {
+		return fmt.Errorf("unknown eth rpc mode %s", cfg.Mode)
+	}
+	if cfg.Interval <= 0 || cfg.ChunkSize <= 0 {
+		return fmt.Errorf("poll interval and chunk size must be positive")
+	}
+	c.pollConfig = cfg
+	return nil
+}
+
+//IsPolling returns true if new blocks and logs are polled instead of subscribed
+func (c *SafeEthClient) IsPolling() bool {
+	return c.pollConfig.Mode == ModePoll || atomic.LoadInt32(&c.polling) == 1
+}
+
+/*
+fallbackToPoll returns true if subscription failed by `err` should be polled instead,
+the endpoint is remembered as not supporting notifications until reconnected.
+*/
+func (c *SafeEthClient) fallbackToPoll(err error) bool {
+	if c.pollConfig.Mode != ModeAuto || err != rpc.ErrNotificationsUnsupported {
+		return false
+	}
+	if atomic.CompareAndSwapInt32(&c.polling, 0, 1) {
+		log.Info("eth rpc endpoint %s doesn't support notifications, poll new blocks and logs every %s", c.primaryURL(), c.pollConfig.Interval)
+	}
+	return true
+}
+
+//wait returns false if `quit` is closed before next poll
+func (c *SafeEthClient) wait(quit <-chan struct{}) bool {
+	select {
+	case <-quit:
+		return false
+	case <-time.After(c.pollConfig.Interval):
+		return true
+	}
+}
+
+/*
+pollNewHead sends header of every block after the latest one to `ch`, like a subscription does.
+after a failed poll, it resumes from the last header sent, so no block is skipped.
+*/
+func (c *SafeEthClient) pollNewHead(ch chan<- *types.Header) (ethereum.Subscription, error) {
+	h, err := c.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	last := h.Number
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		for {
+			h, err := c.HeaderByNumber(context.Background(), nil)
+			if err != nil {
+				log.Warn("poll latest header err %s", err)
+			}
+			for n := new(big.Int).Add(last, big.NewInt(1)); err == nil && n.Cmp(h.Number) <= 0; n.Add(n, big.NewInt(1)) {
+				h2 := h
+				if n.Cmp(h.Number) < 0 {
+					h2, err = c.HeaderByNumber(context.Background(), n)
+					if err != nil {
+						log.Warn("poll header %s err %s", n, err)
+						break
+					}
+				}
+				select {
+				case ch <- h2:
+					last = h2.Number
+				case <-quit:
+					return nil
+				}
+			}
+			if !c.wait(quit) {
+				return nil
+			}
+		}
+	}), nil
+}
+
+/*
+pollFilterLogs sends logs selected by `q` of blocks after the latest one to `ch`, like a subscription does.
+logs are got by FilterLogs of at most ChunkSize blocks,
+after a failed poll, it resumes from the first block not got, so no log is skipped.
+*/
+func (c *SafeEthClient) pollFilterLogs(q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	h, err := c.HeaderByNumber(context.Background(), nil)
+	if err != nil {
+		return nil, err
+	}
+	next := h.Number.Int64() + 1
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		for {
+			h, err := c.HeaderByNumber(context.Background(), nil)
+			if err != nil {
+				log.Warn("poll latest header err %s", err)
+			}
+			for err == nil && next <= h.Number.Int64() {
+				to := next + c.pollConfig.ChunkSize - 1
+				if to > h.Number.Int64() {
+					to = h.Number.Int64()
+				}
+				q2 := q
+				q2.FromBlock = big.NewInt(next)
+				q2.ToBlock = big.NewInt(to)
+				var logs []types.Log
+				logs, err = c.FilterLogs(context.Background(), q2)
+				if err != nil {
+					log.Warn("poll logs of blocks %d-%d err %s", next, to, err)
+					break
+				}
+				for _, l := range logs {
+					if l.Removed {
+						continue
+					}
+					select {
+					case ch <- l:
+					case <-quit:
+						return nil
+					}
+				}
+				next = to + 1
+			}
+			if !c.wait(quit) {
+				return nil
+			}
+		}
+	}), nil
+}
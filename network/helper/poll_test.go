@@ -0,0 +1,150 @@
+package helper
+
+import (
+	"context"
+	"math/big"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+//FakeEth is an http eth rpc endpoint, rpc services must be exported, a log is in every block, requests of logs are recorded
+type FakeEth struct {
+	lock   sync.Mutex
+	number int64
+	ranges [][2]int64
+	failed bool   //fail the next request of logs
+	result []byte //result of every contract call
+	txs    int    //number of transactions received
+}
+
+func (f *FakeEth) mine(n int64) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.number += n
+}
+
+func (f *FakeEth) GetBlockByNumber(number string, full bool) (*types.Header, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	n := f.number
+	if number != "latest" {
+		n = int64(hexutil.MustDecodeUint64(number))
+	}
+	return &types.Header{Number: big.NewInt(n), Difficulty: big.NewInt(1), Time: big.NewInt(n), Extra: []byte{}}, nil
+}
+
+func (f *FakeEth) GetLogs(q map[string]interface{}) (logs []types.Log, err error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.failed {
+		f.failed = false
+		return nil, ethereum.NotFound
+	}
+	from := int64(hexutil.MustDecodeUint64(q["fromBlock"].(string)))
+	to := int64(hexutil.MustDecodeUint64(q["toBlock"].(string)))
+	f.ranges = append(f.ranges, [2]int64{from, to})
+	for n := from; n <= to; n++ {
+		logs = append(logs, types.Log{BlockNumber: uint64(n), Topics: []common.Hash{}, Data: []byte{}})
+	}
+	return
+}
+
+func (f *FakeEth) Call(msg map[string]interface{}, number string) (hexutil.Bytes, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.result, nil
+}
+
+func (f *FakeEth) SendRawTransaction(data hexutil.Bytes) (common.Hash, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.txs++
+	return common.BytesToHash(data), nil
+}
+
+func newFakeEthClient(t *testing.T, cfg PollConfig) (*SafeEthClient, *FakeEth, func()) {
+	f := &FakeEth{number: 10}
+	srv := rpc.NewServer()
+	err := srv.RegisterName("eth", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := httptest.NewServer(srv)
+	c, err := NewSafeClient(hs.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.SetPollConfig(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, f, func() {
+		c.Close()
+		hs.Close()
+	}
+}
+
+func TestPollNewHead(t *testing.T) {
+	c, f, closer := newFakeEthClient(t, PollConfig{Mode: ModeAuto, Interval: 10 * time.Millisecond, ChunkSize: 3})
+	defer closer()
+	ch := make(chan *types.Header, 10)
+	sub, err := c.SubscribeNewHead(context.Background(), ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	assert.True(t, c.IsPolling())
+	f.mine(3)
+	for n := int64(11); n <= 13; n++ {
+		select {
+		case h := <-ch:
+			assert.EqualValues(t, n, h.Number.Int64())
+		case <-time.After(time.Second):
+			t.Fatalf("block %d not polled", n)
+		}
+	}
+}
+
+func TestPollFilterLogs(t *testing.T) {
+	c, f, closer := newFakeEthClient(t, PollConfig{Mode: ModePoll, Interval: 10 * time.Millisecond, ChunkSize: 3})
+	defer closer()
+	ch := make(chan types.Log, 10)
+	sub, err := c.SubscribeFilterLogs(context.Background(), ethereum.FilterQuery{}, ch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Unsubscribe()
+	f.lock.Lock()
+	f.failed = true
+	f.number = 17
+	f.lock.Unlock()
+	//logs of every block after subscription, resumed after the failed poll
+	for n := uint64(11); n <= 17; n++ {
+		select {
+		case l := <-ch:
+			assert.EqualValues(t, n, l.BlockNumber)
+		case <-time.After(time.Second):
+			t.Fatalf("log of block %d not polled", n)
+		}
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	assert.EqualValues(t, [][2]int64{{11, 13}, {14, 16}, {17, 17}}, f.ranges)
+}
+
+func TestSetPollConfig(t *testing.T) {
+	c := &SafeEthClient{}
+	assert.NotNil(t, c.SetPollConfig(PollConfig{Mode: "sometimes", Interval: time.Second, ChunkSize: 1}))
+	assert.NotNil(t, c.SetPollConfig(PollConfig{Mode: ModePoll, ChunkSize: 1}))
+	assert.Nil(t, c.SetPollConfig(DefaultPollConfig))
+	assert.False(t, c.IsPolling())
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"math/big"
 	"sync"
+	"sync/atomic"
 
 	"fmt"
 
@@ -20,41 +21,78 @@ import (
 
 var errNotConnectd = errors.New("eth not connected")
 
-//SafeEthClient how to recover from a restart of geth
+/*
+SafeEthClient how to recover from a restart of geth,
+it may have several endpoints, calls are made by the healthiest one, which is the primary,
+and another one takes over when the primary fails.
+*/
 type SafeEthClient struct {
 	*ethclient.Client
 	lock       sync.Mutex
-	url        string
 	ReConnect  map[string]chan struct{}
 	Status     netshare.Status
 	StatusChan chan netshare.Status
 	quitChan   chan struct{}
+	pollConfig PollConfig
+	polling    int32 //atomic, 1 after the endpoint is found not supporting notifications in ModeAuto
+	endpoints  []*endpoint
+	primary    *endpoint
+	epLock     sync.Mutex //protects primary and state of endpoints
+	checkLock  sync.Mutex //only one check of endpoints at a time
+	quorum     int
 }
 
-//NewSafeClient create safeclient
+//NewSafeClient create safeclient, `rawurl` may be several endpoints separated by comma
 func NewSafeClient(rawurl string) (*SafeEthClient, error) {
+	return NewSafeClientWithQuorum(rawurl, 1)
+}
+
+/*
+NewSafeClientWithQuorum is NewSafeClient whose critical reads, which are contract calls and receipts,
+return only results agreed by `quorum` endpoints.
+quorum is fixed before endpoints are monitored, so it needs no lock.
+*/
+func NewSafeClientWithQuorum(rawurl string, quorum int) (*SafeEthClient, error) {
 	c := &SafeEthClient{
 		ReConnect:  make(map[string]chan struct{}),
-		url:        rawurl,
 		StatusChan: make(chan netshare.Status, 10),
 		quitChan:   make(chan struct{}),
+		pollConfig: DefaultPollConfig,
+		quorum:     quorum,
+	}
+	for _, u := range splitEndpoints(rawurl) {
+		c.endpoints = append(c.endpoints, &endpoint{url: u, status: netshare.Disconnected})
+	}
+	if len(c.endpoints) == 0 {
+		return nil, errors.New("no eth rpc endpoint")
+	}
+	if quorum < 1 || quorum > len(c.endpoints) {
+		return nil, fmt.Errorf("quorum must be between 1 and the number of eth rpc endpoints %d", len(c.endpoints))
 	}
-	var err error
-	c.Client, err = ethclient.Dial(rawurl)
-	if err == nil {
+	c.checkEndpoints()
+	c.epLock.Lock()
+	eps := c.availableEndpoints()
+	c.epLock.Unlock()
+	if len(eps) > 0 {
+		c.setPrimary(eps[0])
 		c.changeStatus(netshare.Connected)
 	} else {
 		//c.changeStatus(xmpptransport.Disconnected)
 		go c.RecoverDisconnect()
 	}
+	go c.monitorEndpoints()
 	return c, nil
 }
 
 //Close connection when destroy raiden service
 func (c *SafeEthClient) Close() {
-	if c.Client != nil {
-		c.Client.Close()
+	c.epLock.Lock()
+	for _, ep := range c.endpoints {
+		if ep.client != nil {
+			ep.client.Close()
+		}
 	}
+	c.epLock.Unlock()
 	close(c.quitChan)
 }
 
@@ -86,10 +124,8 @@ func (c *SafeEthClient) changeStatus(newStatus netshare.Status) {
 	}
 }
 
-//RecoverDisconnect try to reconnect with geth after a restart of geth
+//RecoverDisconnect try to reconnect with geth after a restart of geth, the healthiest endpoint becomes the primary
 func (c *SafeEthClient) RecoverDisconnect() {
-	var err error
-	var client *ethclient.Client
 	c.changeStatus(netshare.Reconnecting)
 	for {
 		log.Info("tyring to reconnect geth ...")
@@ -99,13 +135,18 @@ func (c *SafeEthClient) RecoverDisconnect() {
 		default:
 			//never block
 		}
-		client, err = ethclient.Dial(c.url)
-		if err != nil {
-			log.Info(fmt.Sprintf("reconnect to geth error: %s", err))
+		c.checkEndpoints()
+		c.epLock.Lock()
+		eps := c.availableEndpoints()
+		c.epLock.Unlock()
+		if len(eps) == 0 {
+			log.Info(fmt.Sprintf("reconnect to geth error: no eth rpc endpoint available"))
 			time.Sleep(time.Second * 3)
 		} else {
 			//reconnect ok
-			c.Client = client
+			c.setPrimary(eps[0])
+			//the endpoint may support notifications now
+			atomic.StoreInt32(&c.polling, 0)
 			c.changeStatus(netshare.Connected)
 			c.lock.Lock()
 			var keys []string
@@ -202,8 +243,11 @@ func (c *SafeEthClient) TransactionInBlock(ctx context.Context, blockHash common
 	return c.Client.TransactionInBlock(ctx, blockHash, index)
 }
 
-//TransactionReceipt wrappper of TransactionReceipt
+//TransactionReceipt wrappper of TransactionReceipt, the receipt must be agreed by quorum of endpoints
 func (c *SafeEthClient) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	if c.quorum > 1 {
+		return c.quorumTransactionReceipt(ctx, txHash)
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if c.Client == nil {
@@ -222,8 +266,19 @@ func (c *SafeEthClient) SyncProgress(ctx context.Context) (*ethereum.SyncProgres
 	return c.Client.SyncProgress(ctx)
 }
 
-//SubscribeNewHead wrapper of SubscribeNewHead
+//SubscribeNewHead wrapper of SubscribeNewHead, new heads are polled if the endpoint doesn't support notifications
 func (c *SafeEthClient) SubscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
+	if c.IsPolling() {
+		return c.pollNewHead(ch)
+	}
+	sub, err := c.subscribeNewHead(ctx, ch)
+	if err != nil && c.fallbackToPoll(err) {
+		return c.pollNewHead(ch)
+	}
+	return sub, err
+}
+
+func (c *SafeEthClient) subscribeNewHead(ctx context.Context, ch chan<- *types.Header) (ethereum.Subscription, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if c.Client == nil {
@@ -292,8 +347,19 @@ func (c *SafeEthClient) FilterLogs(ctx context.Context, q ethereum.FilterQuery)
 	return c.Client.FilterLogs(ctx, q)
 }
 
-//SubscribeFilterLogs wrapper of SubscribeFilterLogs
+//SubscribeFilterLogs wrapper of SubscribeFilterLogs, logs are polled if the endpoint doesn't support notifications
 func (c *SafeEthClient) SubscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
+	if c.IsPolling() {
+		return c.pollFilterLogs(q, ch)
+	}
+	sub, err := c.subscribeFilterLogs(ctx, q, ch)
+	if err != nil && c.fallbackToPoll(err) {
+		return c.pollFilterLogs(q, ch)
+	}
+	return sub, err
+}
+
+func (c *SafeEthClient) subscribeFilterLogs(ctx context.Context, q ethereum.FilterQuery, ch chan<- types.Log) (ethereum.Subscription, error) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if c.Client == nil {
@@ -352,8 +418,11 @@ func (c *SafeEthClient) PendingTransactionCount(ctx context.Context) (uint, erro
 	return c.Client.PendingTransactionCount(ctx)
 }
 
-//CallContract wrapper of CallContract
+//CallContract wrapper of CallContract, the result must be agreed by quorum of endpoints
 func (c *SafeEthClient) CallContract(ctx context.Context, msg ethereum.CallMsg, blockNumber *big.Int) ([]byte, error) {
+	if c.quorum > 1 {
+		return c.quorumCallContract(ctx, msg, blockNumber)
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if c.Client == nil {
@@ -392,8 +461,16 @@ func (c *SafeEthClient) EstimateGas(ctx context.Context, msg ethereum.CallMsg) (
 	return c.Client.EstimateGas(ctx, msg)
 }
 
-//SendTransaction wrapper of SendTransaction
+//SendTransaction wrapper of SendTransaction, `tx` is sent by other endpoints if the primary is unreachable
 func (c *SafeEthClient) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	err := c.sendTransaction(ctx, tx)
+	if isTransportError(err) {
+		return c.sendTransactionFailover(ctx, tx, err)
+	}
+	return err
+}
+
+func (c *SafeEthClient) sendTransaction(ctx context.Context, tx *types.Transaction) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if c.Client == nil {
@@ -3,14 +3,15 @@ package network
 import (
 	"fmt"
 
-	"crypto/ecdsa"
-
 	"errors"
 
+	"time"
+
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -28,7 +29,7 @@ type MixTransporter struct {
 }
 
 //NewMixTranspoter create a MixTransporter and discover
-func NewMixTranspoter(name, xmppServer, host string, port int, key *ecdsa.PrivateKey, protocol ProtocolReceiver, policy Policier, deviceType string) (t *MixTransporter, err error) {
+func NewMixTranspoter(name, xmppServer, host string, port int, s signer.Signer, protocol ProtocolReceiver, policy Policier, deviceType string) (t *MixTransporter, err error) {
 	t = &MixTransporter{
 		name:     name,
 		protocol: protocol,
@@ -37,7 +38,7 @@ func NewMixTranspoter(name, xmppServer, host string, port int, key *ecdsa.Privat
 	if err != nil {
 		return
 	}
-	t.xmpp = NewXMPPTransport(name, xmppServer, key, deviceType)
+	t.xmpp = NewXMPPTransport(name, xmppServer, s, deviceType)
 	t.RegisterProtocol(protocol)
 	return
 }
@@ -45,12 +46,24 @@ func NewMixTranspoter(name, xmppServer, host string, port int, key *ecdsa.Privat
 /*
 Send message
 优先选择局域网,在局域网走不通的情况下,才会考虑 xmpp
+如果知道对方的公网地址,但是还没有收到过对方从该地址发来的消息,那么 udp 和 xmpp 同时发送,
+重复的消息会被对方忽略.
 */
 func (t *MixTransporter) Send(receiver common.Address, data []byte) error {
 	_, isOnline := t.udp.NodeStatus(receiver)
 	if isOnline {
 		return t.udp.Send(receiver, data)
-	} else if t.xmpp != nil {
+	}
+	if t.udp.HasNodeEndpoint(receiver) {
+		err := t.udp.Send(receiver, data)
+		if err != nil {
+			log.Info(fmt.Sprintf("send to %s by endpoint err %s", utils.APex2(receiver), err))
+		}
+		if t.xmpp == nil {
+			return err
+		}
+	}
+	if t.xmpp != nil {
 		return t.xmpp.Send(receiver, data)
 	} else {
 		err := fmt.Errorf("no valid %s send to %s , message=%s,response hash=%s", t.name, utils.APex2(receiver), encoding.MessageType(data[0]), utils.HPex(utils.Sha3(data, receiver[:])))
@@ -109,6 +122,16 @@ func (t *MixTransporter) NodeStatus(addr common.Address) (deviceType string, isO
 	return t.xmpp.NodeStatus(addr)
 }
 
+//ConnectionStatus status of connection to xmpp server
+func (t *MixTransporter) ConnectionStatus() netshare.Status {
+	return t.xmpp.ConnectionStatus()
+}
+
+//SetNodeEndpoint `addr` can be reached at `hostport` by udp until `expiration`
+func (t *MixTransporter) SetNodeEndpoint(addr common.Address, hostport, deviceType string, expiration time.Time) error {
+	return t.udp.SetNodeEndpoint(addr, hostport, deviceType, expiration)
+}
+
 //GetNotify notification of connection status change
 func (t *MixTransporter) GetNotify() (notify <-chan netshare.Status, err error) {
 	if t.xmpp.conn != nil {
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -16,17 +17,17 @@ func TestNewMixTransport(t *testing.T) {
 	key1, _ := utils.MakePrivateKeyAddress()
 	key2, _ := utils.MakePrivateKeyAddress()
 	key3, _ := utils.MakePrivateKeyAddress()
-	m1, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40001, key1, newDummyProtocol("m1"), &dummyPolicy{}, DeviceTypeMobile)
+	m1, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40001, signer.NewKeySigner(key1), newDummyProtocol("m1"), &dummyPolicy{}, DeviceTypeMobile)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	m2, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40002, key2, newDummyProtocol("m2"), &dummyPolicy{}, DeviceTypeOther)
+	m2, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40002, signer.NewKeySigner(key2), newDummyProtocol("m2"), &dummyPolicy{}, DeviceTypeOther)
 	if err != nil {
 		t.Error(err)
 		return
 	}
-	m3, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40003, key3, newDummyProtocol("m3"), &dummyPolicy{}, DeviceTypeMobile)
+	m3, err := NewMixTranspoter("m1", params.DefaultTestXMPPServer, "127.0.0.1", 40003, signer.NewKeySigner(key3), newDummyProtocol("m3"), &dummyPolicy{}, DeviceTypeMobile)
 	if err != nil {
 		t.Error(err)
 		return
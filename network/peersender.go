@@ -0,0 +1,247 @@
+package network
+
+import (
+	"container/heap"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+priority of outgoing messages, larger is sent first.
+secret and unlock messages make locks settle off-chain, they are much more urgent than a new transfer.
+*/
+const (
+	priorityLow = iota
+	priorityNormal
+	priorityHigh
+)
+
+func messagePriority(msg encoding.Messager) int {
+	switch msg.(type) {
+	case *encoding.RevealSecret, *encoding.UnLock, *encoding.SecretRequest,
+		*encoding.AnnounceDisposed, *encoding.AnnounceDisposedResponse,
+		*encoding.RemoveExpiredHashlockTransfer:
+		return priorityHigh
+	case *encoding.MediatedTransfer, *encoding.DirectTransfer, *encoding.CapacityHint:
+		return priorityLow
+	}
+	return priorityNormal
+}
+
+/*
+messageLockExpiration returns expiration block of the lock this message carries,
+0 if message has no lock.
+*/
+func messageLockExpiration(msg encoding.Messager) int64 {
+	switch msg2 := msg.(type) {
+	case *encoding.MediatedTransfer:
+		return msg2.Expiration
+	case *encoding.AnnounceDisposed:
+		if msg2.Lock != nil {
+			return msg2.Lock.Expiration
+		}
+	}
+	return 0
+}
+
+//transmission is one try of sending a message
+type transmission struct {
+	priority int
+	seq      uint64 //same priority, first in first out
+	echoHash common.Hash
+	data     []byte
+}
+
+type transmissionQueue []*transmission
+
+func (q transmissionQueue) Len() int { return len(q) }
+func (q transmissionQueue) Less(i, j int) bool {
+	if q[i].priority != q[j].priority {
+		return q[i].priority > q[j].priority
+	}
+	return q[i].seq < q[j].seq
+}
+func (q transmissionQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+//Push is heap.Interface
+func (q *transmissionQueue) Push(x interface{}) {
+	*q = append(*q, x.(*transmission))
+}
+
+//Pop is heap.Interface
+func (q *transmissionQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	t := old[n-1]
+	*q = old[:n-1]
+	return t
+}
+
+/*
+peerSender sends all messages to one peer,
+every peer has its own token bucket, so a slow peer never blocks others,
+and messages waiting for tokens are sent by priority.
+*/
+type peerSender struct {
+	receiver common.Address
+	policy   Policier
+	lock     sync.Mutex
+	queue    transmissionQueue
+	queued   map[common.Hash]bool //a retry of a message still waiting should be ignored
+	seq      uint64
+	notify   chan struct{}
+	closed   bool //removed after idle, messages must be pushed to a new one
+}
+
+func newPeerSender(receiver common.Address, policy Policier) *peerSender {
+	return &peerSender{
+		receiver: receiver,
+		policy:   policy,
+		queued:   make(map[common.Hash]bool),
+		notify:   make(chan struct{}, 1),
+	}
+}
+
+//push returns false if `ps` is closed
+func (ps *peerSender) push(priority int, echoHash common.Hash, data []byte) bool {
+	ps.lock.Lock()
+	if ps.closed {
+		ps.lock.Unlock()
+		return false
+	}
+	if ps.queued[echoHash] {
+		ps.lock.Unlock()
+		return true
+	}
+	ps.seq++
+	heap.Push(&ps.queue, &transmission{
+		priority: priority,
+		seq:      ps.seq,
+		echoHash: echoHash,
+		data:     data,
+	})
+	ps.queued[echoHash] = true
+	ps.lock.Unlock()
+	select {
+	case ps.notify <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (ps *peerSender) pop() *transmission {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if ps.queue.Len() == 0 {
+		return nil
+	}
+	t := heap.Pop(&ps.queue).(*transmission)
+	delete(ps.queued, t.echoHash)
+	return t
+}
+
+func (ps *peerSender) len() int {
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	return ps.queue.Len()
+}
+
+func (p *RaidenProtocol) getPeerSender(receiver common.Address) *peerSender {
+	p.mapLock.Lock()
+	defer p.mapLock.Unlock()
+	ps, ok := p.peerSenders[receiver]
+	if ok {
+		return ps
+	}
+	ps = newPeerSender(receiver, NewTokenBucket(p.throttleCapacity, p.throttleFillRate))
+	p.peerSenders[receiver] = ps
+	go p.peerSenderLoop(ps)
+	return ps
+}
+
+//removeIdlePeerSender removes `ps` unless a message is pushed to it meanwhile, returns true if removed
+func (p *RaidenProtocol) removeIdlePeerSender(ps *peerSender) bool {
+	p.mapLock.Lock()
+	defer p.mapLock.Unlock()
+	ps.lock.Lock()
+	defer ps.lock.Unlock()
+	if ps.queue.Len() > 0 {
+		return false
+	}
+	ps.closed = true
+	if p.peerSenders[ps.receiver] == ps {
+		delete(p.peerSenders, ps.receiver)
+	}
+	return true
+}
+
+func (p *RaidenProtocol) peerSenderLoop(ps *peerSender) {
+	defer rpanic.PanicRecover(fmt.Sprintf("peer sender %s", utils.APex2(ps.receiver)))
+	for {
+		if ps.len() == 0 {
+			select {
+			case <-ps.notify:
+			case <-time.After(p.peerSenderIdle):
+				if p.removeIdlePeerSender(ps) {
+					return
+				}
+			case <-p.quitChan:
+				return
+			}
+			continue
+		}
+		wait := ps.policy.Consume(1)
+		if wait > 0 {
+			select {
+			case <-time.After(wait):
+			case <-p.quitChan:
+				return
+			}
+		}
+		//pop after waiting, an urgent message may arrive during waiting
+		t := ps.pop()
+		if t == nil {
+			continue
+		}
+		err := p.sendRawWitNoAck(ps.receiver, t.data)
+		if err != nil {
+			p.log.Info(fmt.Sprintf("send to %s err %s", utils.APex2(ps.receiver), err))
+		}
+		p.onTransmitted(ps.receiver, t.echoHash)
+	}
+}
+
+//transmit queue one try of `msgState`, it will be sent when it's receiver's turn
+func (p *RaidenProtocol) transmit(msgState *SentMessageState) {
+	for !p.getPeerSender(msgState.ReceiverAddress).push(messagePriority(msgState.Message), msgState.EchoHash, msgState.Data) {
+		//removed after idle just now, a new one is created
+	}
+}
+
+/*
+retryBudget how many times `msg` is retried every `interval` before backing off.
+a message carries a lock is useless after the lock expired, so it's retried every `interval` until then,
+other messages use the configured budget.
+*/
+func (p *RaidenProtocol) retryBudget(msg encoding.Messager, interval time.Duration) int {
+	expiration := messageLockExpiration(msg)
+	if expiration <= 0 || p.BlockNumberGetter == nil {
+		return p.retryTimes
+	}
+	blocksLeft := expiration - p.BlockNumberGetter.GetBlockNumber()
+	if blocksLeft <= 0 {
+		return 1
+	}
+	budget := int(time.Duration(blocksLeft) * params.ExpectedBlockPeriod / interval)
+	if budget < 1 {
+		budget = 1
+	}
+	return budget
+}
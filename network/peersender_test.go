@@ -0,0 +1,86 @@
+package network
+
+import (
+	"testing"
+
+	"math/big"
+
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerSenderPriority(t *testing.T) {
+	ps := newPeerSender(utils.NewRandomAddress(), &dummyPolicy{})
+	mt := utils.NewRandomHash()
+	ps.push(priorityLow, mt, []byte("mediatedtransfer"))
+	ps.push(priorityNormal, utils.NewRandomHash(), []byte("normal"))
+	ps.push(priorityHigh, utils.NewRandomHash(), []byte("revealsecret"))
+	ps.push(priorityHigh, utils.NewRandomHash(), []byte("unlock"))
+	//a retry of a waiting message should be ignored
+	ps.push(priorityLow, mt, []byte("mediatedtransfer"))
+	assert.EqualValues(t, ps.len(), 4)
+	var sent []string
+	for tr := ps.pop(); tr != nil; tr = ps.pop() {
+		sent = append(sent, string(tr.data))
+	}
+	assert.EqualValues(t, sent, []string{"revealsecret", "unlock", "normal", "mediatedtransfer"})
+}
+
+func TestPeerSenderIdle(t *testing.T) {
+	p := &RaidenProtocol{
+		peerSenders:    make(map[common.Address]*peerSender),
+		peerSenderIdle: 10 * time.Millisecond,
+		quitChan:       make(chan struct{}),
+	}
+	defer close(p.quitChan)
+	receiver := utils.NewRandomAddress()
+	ps := p.getPeerSender(receiver)
+	time.Sleep(100 * time.Millisecond)
+	p.mapLock.Lock()
+	assert.Empty(t, p.peerSenders)
+	p.mapLock.Unlock()
+	//messages go to a new sender after the idle one is removed
+	assert.False(t, ps.push(priorityNormal, utils.NewRandomHash(), []byte("normal")))
+	ps2 := p.getPeerSender(receiver)
+	assert.True(t, ps != ps2)
+	//a sender with messages waiting is not removed
+	ps3 := newPeerSender(utils.NewRandomAddress(), &dummyPolicy{})
+	p.mapLock.Lock()
+	p.peerSenders[ps3.receiver] = ps3
+	p.mapLock.Unlock()
+	ps3.push(priorityNormal, utils.NewRandomHash(), []byte("normal"))
+	assert.False(t, p.removeIdlePeerSender(ps3))
+	ps3.pop()
+	assert.True(t, p.removeIdlePeerSender(ps3))
+	p.mapLock.Lock()
+	assert.Nil(t, p.peerSenders[ps3.receiver])
+	p.mapLock.Unlock()
+}
+
+func TestMessagePriority(t *testing.T) {
+	assert.EqualValues(t, messagePriority(encoding.NewRevealSecret(utils.NewRandomHash())), priorityHigh)
+	assert.EqualValues(t, messagePriority(encoding.NewSecretRequest(utils.NewRandomHash(), big.NewInt(1))), priorityHigh)
+	assert.EqualValues(t, messagePriority(new(encoding.MediatedTransfer)), priorityLow)
+	assert.EqualValues(t, messagePriority(encoding.NewPing(1)), priorityNormal)
+}
+
+func TestRetryBudget(t *testing.T) {
+	p := &RaidenProtocol{
+		retryTimes:        10,
+		BlockNumberGetter: &testBlockNumberGetter{},
+	}
+	interval := time.Second * 6
+	assert.EqualValues(t, p.retryBudget(encoding.NewRevealSecret(utils.NewRandomHash()), interval), 10)
+	mt := new(encoding.MediatedTransfer)
+	mt.Expiration = 4
+	//4 blocks, 15 seconds each, retry every 6 seconds
+	assert.EqualValues(t, p.retryBudget(mt, interval), 10)
+	mt.Expiration = 40
+	assert.EqualValues(t, p.retryBudget(mt, interval), 100)
+	mt.Expiration = -1
+	assert.EqualValues(t, p.retryBudget(mt, interval), 10)
+}
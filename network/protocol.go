@@ -1,8 +1,6 @@
 package network
 
 import (
-	"crypto/ecdsa"
-
 	"encoding/hex"
 
 	"reflect"
@@ -20,10 +18,11 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 var errTimeout = errors.New("wait timeout")
@@ -47,6 +46,9 @@ type SentMessageState struct {
 	Message  encoding.Messager //message to send
 	EchoHash common.Hash       //message echo hash
 	Data     []byte            //packed message
+
+	tries    int       //how many times has been transmitted
+	lastSent time.Time //when transmitted last time
 }
 
 //PingSender do send ping task
@@ -98,11 +100,17 @@ every message needs a ack to make sure sent success.
 */
 type RaidenProtocol struct {
 	Transport           Transporter
-	privKey             *ecdsa.PrivateKey
+	signer              signer.Signer
 	nodeAddr            common.Address
 	SentHashesToChannel map[common.Hash]*SentMessageState
 	retryTimes          int
 	retryInterval       time.Duration
+	throttleCapacity    float64
+	throttleFillRate    float64
+	peerSenders         map[common.Address]*peerSender
+	peerSenderIdle      time.Duration //idle peer senders are removed after it
+	peerStats           map[common.Address]*PeerStats
+	statsLock           sync.Mutex
 	mapLock             sync.Mutex
 	statusLock          sync.RWMutex
 	/*
@@ -124,13 +132,21 @@ type RaidenProtocol struct {
 	log         log.Logger
 }
 
-//NewRaidenProtocol create RaidenProtocol
-func NewRaidenProtocol(transport Transporter, privKey *ecdsa.PrivateKey, blockNumberGetter BlockNumberGetter) *RaidenProtocol {
+//NewRaidenProtocol create RaidenProtocol, use default configuration if `config` is nil
+func NewRaidenProtocol(transport Transporter, s signer.Signer, blockNumberGetter BlockNumberGetter, config *params.ProtocolConfig) *RaidenProtocol {
+	if config == nil {
+		config = &params.DefaultConfig.Protocol
+	}
 	rp := &RaidenProtocol{
 		Transport:                 transport,
-		privKey:                   privKey,
-		retryTimes:                10,
-		retryInterval:             time.Millisecond * 6000,
+		signer:                    s,
+		retryTimes:                config.RetriesBeforeBackoff,
+		retryInterval:             config.RetryInterval,
+		throttleCapacity:          config.ThrottleCapacity,
+		throttleFillRate:          config.ThrottleFillRate,
+		peerSenders:               make(map[common.Address]*peerSender),
+		peerSenderIdle:            params.PeerSenderIdleTimeout,
+		peerStats:                 make(map[common.Address]*PeerStats),
 		SentHashesToChannel:       make(map[common.Hash]*SentMessageState),
 		ReceivedMessageChan:       make(chan *MessageToRaiden),
 		ReceivedMessageResultChan: make(chan error),
@@ -139,7 +155,7 @@ func NewRaidenProtocol(transport Transporter, privKey *ecdsa.PrivateKey, blockNu
 		quitChan:                  make(chan struct{}),
 		receiveChan:               make(chan []byte, 20),
 	}
-	rp.nodeAddr = crypto.PubkeyToAddress(privKey.PublicKey)
+	rp.nodeAddr = s.Address()
 	transport.RegisterProtocol(rp)
 	rp.log = log.New("name", utils.APex2(rp.nodeAddr))
 	go rp.loop()
@@ -168,6 +184,7 @@ func (p *RaidenProtocol) sendAck(receiver common.Address, ack *encoding.Ack) {
 	if err != nil {
 		log.Warn(fmt.Sprintf("sesendRawWitNoAck err %s ", err))
 	}
+	metrics.AcksSent.Inc()
 }
 func (p *RaidenProtocol) sendRawAck(receiver common.Address, data []byte) {
 	p.log.Trace(fmt.Sprintf("send to %s raw ack", utils.APex2(receiver)))
@@ -183,7 +200,7 @@ func (p *RaidenProtocol) sendRawWitNoAck(receiver common.Address, data []byte) e
 //SendPing PingSender
 func (p *RaidenProtocol) SendPing(receiver common.Address) error {
 	ping := encoding.NewPing(utils.NewRandomInt64())
-	err := ping.Sign(p.privKey, ping)
+	err := ping.Sign(p.signer, ping)
 	if err != nil {
 		return err
 	}
@@ -193,6 +210,7 @@ func (p *RaidenProtocol) SendPing(receiver common.Address) error {
 
 /*
 message mediatedTransfer  can safely be discarded when expired.
+expired endpoint announcement and capacity hint are useless too.
 如果丢弃,意味着通道状态将不再同步,通道只能关闭,无法起作用了.
 */
 func (p *RaidenProtocol) messageCanBeSent(msg encoding.Messager) bool {
@@ -200,6 +218,11 @@ func (p *RaidenProtocol) messageCanBeSent(msg encoding.Messager) bool {
 	switch msg2 := msg.(type) {
 	case *encoding.MediatedTransfer:
 		expired = msg2.Expiration
+	case *encoding.NodeEndpoint:
+		//endpoint expires by time, not by block number
+		return msg2.Expiration > time.Now().Unix()
+	case *encoding.CapacityHint:
+		return msg2.Expiration > time.Now().Unix()
 	}
 	if expired > 0 && expired <= p.BlockNumberGetter.GetBlockNumber() {
 		return false
@@ -232,7 +255,9 @@ func (p *RaidenProtocol) getChannelQueue(receiver common.Address, channelAddr co
 		defer rpanic.PanicRecover(fmt.Sprintf("protocol ChannelQueue %s", key))
 		/*
 			1. if p packet is on sending, retry send immediately
-			2. retry infinite, until receive a ack
+			2. retry infinite, until receive a ack,
+			   retry at receiver's retransmit timeout `retryBudget` times, then back off.
+			   every try waits in receiver's queue and is sent by priority.
 			3. p message should be sent by caller after restart.
 
 			caller can read from chan reusltChannel to get if p packet is successfully sent to receiver
@@ -254,17 +279,15 @@ func (p *RaidenProtocol) getChannelQueue(receiver common.Address, channelAddr co
 			p.log.Trace(fmt.Sprintf("send to %s,msg=%s, echoash=%s",
 				utils.APex2(msgState.ReceiverAddress), msgState.Message,
 				utils.HPex(msgState.EchoHash)))
+			rto := p.retransmitTimeout(receiver)
+			nextTimeout := timeoutExponentialBackoff(p.retryBudget(msgState.Message, rto), rto, rto*10)
 			for {
 				if !p.messageCanBeSent(msgState.Message) {
 					p.log.Info(fmt.Sprintf("message cannot be send because of expired msg=%s", msgState.Message))
 					msgState.AsyncResult.Result <- errExpired
 					break
 				}
-				nextTimeout := timeoutExponentialBackoff(p.retryTimes, p.retryInterval, p.retryInterval*10)
-				err := p.sendRawWitNoAck(receiver, msgState.Data)
-				if err != nil {
-					p.log.Info(fmt.Sprintf("sendRawWitNoAck %s msg error %s", key, err.Error()))
-				}
+				p.transmit(msgState)
 				timeout := time.After(nextTimeout())
 				select {
 				case _, ok = <-msgState.AckChannel:
@@ -342,6 +365,7 @@ func (p *RaidenProtocol) sendWithResult(receiver common.Address,
 	}
 	p.SentHashesToChannel[echohash] = msgState
 	p.mapLock.Unlock()
+	metrics.MessagesSent.Inc(encoding.MessageType(msg.Cmd()).String())
 	result = msgState.AsyncResult
 	channelAddress := getMessageChannelAddress(msg)
 	//make sure not block
@@ -415,6 +439,7 @@ func (p *RaidenProtocol) receiveInternal(data []byte) {
 		p.log.Warn(fmt.Sprintf("message unpack error : %s", err))
 		return
 	}
+	metrics.MessagesReceived.Inc(encoding.MessageType(messager.Cmd()).String())
 	echohash := utils.Sha3(data, p.nodeAddr[:])
 	if p.receivedMessageSaver != nil && messager.Cmd() != encoding.AckCmdID {
 		ackdata := p.receivedMessageSaver.GetAck(echohash)
@@ -434,6 +459,8 @@ func (p *RaidenProtocol) receiveInternal(data []byte) {
 		p.mapLock.Lock()
 		msgState, ok := p.SentHashesToChannel[ackMsg.Echo]
 		if ok && msgState.Success == false {
+			p.onAcked(msgState)
+			metrics.AcksReceived.Inc()
 			msgState.AckChannel <- nil
 			close(msgState.AckChannel)
 			msgState.Success = true
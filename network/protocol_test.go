@@ -32,7 +32,7 @@ func TestRaidenProtocolSendReceive(t *testing.T) {
 	p1.Start()
 	p2.Start()
 	ping := encoding.NewPing(32)
-	ping.Sign(p1.privKey, ping)
+	ping.Sign(p1.signer, ping)
 	err := p1.SendAndWait(p2.nodeAddr, ping, time.Minute)
 	if err != nil {
 		t.Error(err)
@@ -57,7 +57,7 @@ func TestRaidenProtocolSendReceiveTimeout(t *testing.T) {
 	//}
 	p1.Start()
 	ping := encoding.NewPing(32)
-	ping.Sign(p1.privKey, ping)
+	ping.Sign(p1.signer, ping)
 	err = p1.SendAndWait(p2.nodeAddr, ping, time.Second*2)
 	if err == nil {
 		t.Error(errors.New("should timeout"))
@@ -71,7 +71,7 @@ func TestRaidenProtocolSendReceiveNormalMessage(t *testing.T) {
 	p1.Start()
 	p2.Start()
 	revealSecretMsg := encoding.NewRevealSecret(utils.Sha3([]byte{12}))
-	revealSecretMsg.Sign(p1.privKey, revealSecretMsg)
+	revealSecretMsg.Sign(p1.signer, revealSecretMsg)
 	go func() {
 		m := <-p2.ReceivedMessageChan
 		t.Logf("received msg :%#v", m)
@@ -113,14 +113,14 @@ func TestRaidenProtocolSendReceiveNormalMessage2(t *testing.T) {
 	p1.Start()
 	p2.Start()
 	revealSecretMsg := encoding.NewRevealSecret(utils.Sha3([]byte{12}))
-	revealSecretMsg.Sign(p1.privKey, revealSecretMsg)
+	revealSecretMsg.Sign(p1.signer, revealSecretMsg)
 	go func() {
 		m := <-p2.ReceivedMessageChan
 		t.Logf("client2 received msg :%#v", m)
 		msg = m.Msg
 		p2.ReceivedMessageResultChan <- nil
 		secretRequest := encoding.NewSecretRequest(utils.EmptyHash, big.NewInt(12))
-		secretRequest.Sign(p2.privKey, secretRequest)
+		secretRequest.Sign(p2.signer, secretRequest)
 		err := p2.SendAndWait(p1.nodeAddr, secretRequest, time.Minute)
 		if err != nil {
 			t.Error(err)
@@ -167,7 +167,7 @@ func TestRaidenProtocolSendMediatedTransferExpired(t *testing.T) {
 	})
 	mtr := encoding.NewMediatedTransfer(bp, &lock,
 		utils.NewRandomAddress(), utils.NewRandomAddress(), utils.BigInt0)
-	mtr.Sign(p1.privKey, mtr)
+	mtr.Sign(p1.signer, mtr)
 	err := p1.SendAndWait(reciever, mtr, time.Second*5)
 	if err != errTimeout {
 		t.Errorf("should time out but get %s", err)
@@ -176,7 +176,7 @@ func TestRaidenProtocolSendMediatedTransferExpired(t *testing.T) {
 	lock.Expiration = 3
 	mtr2 := encoding.NewMediatedTransfer(bp, &lock,
 		utils.NewRandomAddress(), utils.NewRandomAddress(), utils.BigInt0)
-	mtr2.Sign(p1.privKey, mtr2)
+	mtr2.Sign(p1.signer, mtr2)
 	err = p1.SendAndWait(reciever, mtr2, time.Second*5)
 	if err != errExpired {
 		t.Error(errors.New("should expired before timeout"))
@@ -9,16 +9,17 @@ import (
 
 	"fmt"
 
-	"crypto/ecdsa"
+	"sync"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 )
 
 //GetCallContext context for tx
@@ -29,6 +30,13 @@ func GetCallContext() context.Context {
 	return ctx
 }
 
+//waitMined wait `tx` to be mined, it's a pending transaction until then
+func waitMined(ctx context.Context, b bind.DeployBackend, tx *types.Transaction) (*types.Receipt, error) {
+	metrics.PendingTransactions.Inc()
+	defer metrics.PendingTransactions.Dec()
+	return bind.WaitMined(ctx, b, tx)
+}
+
 //GetQueryConext context for query on chain
 func GetQueryConext() context.Context {
 	ctx, cf := context.WithDeadline(context.Background(), time.Now().Add(params.DefaultPollTimeout))
@@ -41,34 +49,42 @@ func GetQueryConext() context.Context {
 BlockChainService provides quering on blockchain.
 */
 type BlockChainService struct {
-	//PrivKey of this node, todo remove this
-	PrivKey *ecdsa.PrivateKey
+	//Signer signs transactions of this node
+	Signer signer.Signer
 	//NodeAddress is address of this node
 	NodeAddress common.Address
 	//RegistryAddress registy contract address
-	RegistryAddress     common.Address
+	RegistryAddress common.Address
+	//SecretRegistryProxy and RegistryProxy are proxies of the default registry
 	SecretRegistryProxy *SecretRegistryProxy
 	RegistryProxy       *RegistryProxy
+	registryLock        sync.Mutex
+	registries          map[common.Address]*RegistryProxy
+	secretRegistries    map[common.Address]*SecretRegistryProxy //registry => its secret registry
 	//Client if eth rpc client
 	Client          *helper.SafeEthClient
 	addressTokens   map[common.Address]*TokenProxy
 	addressChannels map[common.Address]*TokenNetworkProxy
 	//Auth needs by call on blockchain todo remove this
-	Auth      *bind.TransactOpts
+	Auth *bind.TransactOpts
+	//GasPricer decides gas price of every transaction
+	GasPricer *GasPricer
 	queryOpts *bind.CallOpts
 }
 
 //NewBlockChainService create BlockChainService
-func NewBlockChainService(privKey *ecdsa.PrivateKey, registryAddress common.Address, client *helper.SafeEthClient) *BlockChainService {
+func NewBlockChainService(s signer.Signer, registryAddress common.Address, client *helper.SafeEthClient) *BlockChainService {
 	bcs := &BlockChainService{
-		PrivKey:         privKey,
-		NodeAddress:     crypto.PubkeyToAddress(privKey.PublicKey),
+		Signer:          s,
+		NodeAddress:     s.Address(),
 		RegistryAddress: registryAddress,
 		Client:          client,
 		addressTokens:   make(map[common.Address]*TokenProxy),
 		addressChannels: make(map[common.Address]*TokenNetworkProxy),
-		Auth:            bind.NewKeyedTransactor(privKey),
+		Auth:            signer.NewTransactor(s),
 	}
+	bcs.registries = make(map[common.Address]*RegistryProxy)
+	bcs.secretRegistries = make(map[common.Address]*SecretRegistryProxy)
 	bcs.queryOpts = &bind.CallOpts{
 		Pending: false,
 		From:    bcs.NodeAddress,
@@ -77,8 +93,38 @@ func NewBlockChainService(privKey *ecdsa.PrivateKey, registryAddress common.Addr
 	//It needs to be set up, otherwise, even the contract revert will not report wrong.
 	bcs.Auth.GasLimit = uint64(params.GasLimit)
 	bcs.Auth.GasPrice = big.NewInt(params.GasPrice)
+	bcs.GasPricer = NewGasPricer(client)
 	return bcs
 }
+
+//transactOpts returns options of a transaction of `token` and `urgency`, `deadline` is 0 if it has none
+func (bcs *BlockChainService) transactOpts(token common.Address, urgency Urgency, deadline int64) (*bind.TransactOpts, error) {
+	var currentBlock int64
+	if deadline > 0 {
+		n, err := bcs.blockNumber()
+		if err != nil {
+			return nil, err
+		}
+		currentBlock = n.Int64()
+	}
+	return bcs.GasPricer.TransactOpts(bcs.Auth, token, urgency, deadline, currentBlock)
+}
+
+/*
+waitMined waits `tx` sent with `opts` of `token` to be mined, and adds its gas to spending of `token`.
+a transaction with a deadline is re-sent at a higher price as the deadline gets closer.
+*/
+func (bcs *BlockChainService) waitMined(token common.Address, opts *bind.TransactOpts, tx *types.Transaction) (receipt *types.Receipt, err error) {
+	if d := bcs.GasPricer.deadlineOf(opts); d != nil {
+		tx, receipt, err = bcs.GasPricer.waitMinedRepricing(GetCallContext(), bcs.Client, opts, tx, d)
+	} else {
+		receipt, err = waitMined(GetCallContext(), bcs.Client, tx)
+	}
+	if err == nil {
+		bcs.GasPricer.AddSpent(token, tx, receipt)
+	}
+	return receipt, err
+}
 func (bcs *BlockChainService) getQueryOpts() *bind.CallOpts {
 	return &bind.CallOpts{
 		Pending: false,
@@ -174,8 +220,10 @@ func (bcs *BlockChainService) TokenNetworkWithoutCheck(address common.Address) (
 
 // Registry Return a proxy to interact with Registry.
 func (bcs *BlockChainService) Registry(address common.Address) (t *RegistryProxy) {
-	if bcs.RegistryProxy != nil {
-		return bcs.RegistryProxy
+	bcs.registryLock.Lock()
+	defer bcs.registryLock.Unlock()
+	if r, ok := bcs.registries[address]; ok {
+		return r
 	}
 	reg, err := contracts.NewTokenNetworkRegistry(address, bcs.Client)
 	if err != nil {
@@ -193,11 +241,27 @@ func (bcs *BlockChainService) Registry(address common.Address) (t *RegistryProxy
 		log.Error(fmt.Sprintf("NewSecretRegistry err %s", err))
 		return
 	}
-	bcs.RegistryProxy = r
-	bcs.SecretRegistryProxy = &SecretRegistryProxy{
-		Address:  secAddr,
-		bcs:      bcs,
-		registry: s,
+	sr := &SecretRegistryProxy{
+		Address:          secAddr,
+		bcs:              bcs,
+		registry:         s,
+		RegisteredSecret: make(map[common.Hash]*sync.Mutex),
+	}
+	bcs.registries[address] = r
+	bcs.secretRegistries[address] = sr
+	if address == bcs.RegistryAddress {
+		bcs.RegistryProxy = r
+		bcs.SecretRegistryProxy = sr
+	}
+	return r
+}
+
+//SecretRegistry returns proxy of secret registry used by token networks of `registryAddress`, nil if the registry is unreachable
+func (bcs *BlockChainService) SecretRegistry(registryAddress common.Address) *SecretRegistryProxy {
+	if bcs.Registry(registryAddress) == nil {
+		return nil
 	}
-	return bcs.RegistryProxy
+	bcs.registryLock.Lock()
+	defer bcs.registryLock.Unlock()
+	return bcs.secretRegistries[registryAddress]
 }
@@ -13,6 +13,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -48,7 +49,7 @@ func MakeTestBlockChainService() *BlockChainService {
 	if err != nil {
 		fmt.Printf("Failed to connect to the Ethereum client: %s\n", err)
 	}
-	return NewBlockChainService(TestPrivKey, PrivateRopstenRegistryAddress, conn)
+	return NewBlockChainService(signer.NewKeySigner(TestPrivKey), PrivateRopstenRegistryAddress, conn)
 }
 
 //GetTestChannelUniqueID for test only,get from env
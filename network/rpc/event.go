@@ -80,9 +80,13 @@ func EventSubscribeInternal(ctx context.Context, contractAddress common.Address,
 	//node.DefaultIPCEndpoint("geth")
 }
 
-//EventSubscribe subscribe events of future
+//EventSubscribe subscribe events of future, they are polled when the endpoint is http
 func EventSubscribe(contractAddress common.Address,
 	eventName string, abistr string, client *helper.SafeEthClient, ch chan types.Log) (ethereum.Subscription, error) {
-	return EventSubscribeInternal(context.Background(), contractAddress, rpc.EarliestBlockNumber, rpc.LatestBlockNumber,
-		eventName, abistr, client.Client, ch)
+	q, err := buildQuery(contractAddress, rpc.EarliestBlockNumber, rpc.LatestBlockNumber, eventName, abistr)
+	if err != nil {
+		return nil, err
+	}
+	//SafeEthClient polls logs if the endpoint doesn't support subscription
+	return client.SubscribeFilterLogs(context.Background(), *q, ch)
 }
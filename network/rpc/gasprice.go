@@ -0,0 +1,419 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+//Urgency is what a transaction is for, which decides how much it's worth paying for
+type Urgency string
+
+//urgencies of transactions
+const (
+	UrgencyDeposit     Urgency = "deposit"
+	UrgencyClose       Urgency = "close"
+	UrgencyUpdateProof Urgency = "update_proof"
+	UrgencyUnlock      Urgency = "unlock"
+	UrgencySettle      Urgency = "settle"
+	UrgencyPunish      Urgency = "punish"
+	UrgencyOther       Urgency = "other"
+)
+
+//ErrGasSpendingCap transaction is not sent because spending cap of its token is reached
+var ErrGasSpendingCap = errors.New("gas spending cap of token reached")
+
+//GasPriceStrategy decides gas price of a transaction, `blocksLeft` is blocks before its deadline, negative if it has none
+type GasPriceStrategy interface {
+	GasPrice(urgency Urgency, blocksLeft int64) (*big.Int, error)
+}
+
+type fixedGasPrice struct {
+	price *big.Int
+}
+
+func (s *fixedGasPrice) GasPrice(urgency Urgency, blocksLeft int64) (*big.Int, error) {
+	return new(big.Int).Set(s.price), nil
+}
+
+type suggestedGasPrice struct {
+	client     *helper.SafeEthClient
+	multiplier float64
+}
+
+func (s *suggestedGasPrice) GasPrice(urgency Urgency, blocksLeft int64) (*big.Int, error) {
+	price, err := s.client.SuggestGasPrice(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return mulBig(price, s.multiplier), nil
+}
+
+/*
+deadlineGasPrice raises price of `base` linearly in the last escalationBlocks blocks before deadline,
+up to maxEscalation times at the deadline.
+*/
+type deadlineGasPrice struct {
+	base             GasPriceStrategy
+	escalationBlocks int64
+	maxEscalation    float64
+}
+
+func (s *deadlineGasPrice) GasPrice(urgency Urgency, blocksLeft int64) (*big.Int, error) {
+	price, err := s.base.GasPrice(urgency, blocksLeft)
+	if err != nil || blocksLeft < 0 || blocksLeft >= s.escalationBlocks {
+		return price, err
+	}
+	factor := 1 + (s.maxEscalation-1)*float64(s.escalationBlocks-blocksLeft)/float64(s.escalationBlocks)
+	return mulBig(price, factor), nil
+}
+
+func mulBig(i *big.Int, f float64) *big.Int {
+	r, _ := new(big.Float).Mul(new(big.Float).SetInt(i), big.NewFloat(f)).Int(nil)
+	return r
+}
+
+//GasPriceStore saves gas price config and gas spent on every token
+type GasPriceStore interface {
+	GetGasPriceConfig() (*params.GasPriceConfig, error)
+	SaveGasPriceConfig(c *params.GasPriceConfig) error
+	GetGasSpent(token common.Address) *big.Int
+	AddGasSpent(token common.Address, amount *big.Int) error
+}
+
+/*
+cappedUrgencies are transactions stopped by spending cap of their token.
+the others defend our tokens in a closed channel before settle, losing them costs much more than gas.
+*/
+var cappedUrgencies = map[Urgency]bool{
+	UrgencyDeposit: true,
+	UrgencyClose:   true,
+	UrgencyOther:   true,
+}
+
+//gasReservation is the most a transaction sent but not mined yet may spend
+type gasReservation struct {
+	token common.Address
+	cost  *big.Int
+}
+
+//deadlineTx is what is needed to price a transaction again as its deadline gets closer
+type deadlineTx struct {
+	urgency  Urgency
+	deadline int64
+}
+
+/*
+GasPricer decides gas price of every transaction by its urgency, and keeps gas spent of every token under its cap.
+transactions not mined yet are counted at their most possible cost, so concurrent ones can't overshoot the cap together.
+*/
+type GasPricer struct {
+	client       *helper.SafeEthClient
+	store        GasPriceStore
+	lock         sync.Mutex
+	config       *params.GasPriceConfig
+	strategy     GasPriceStrategy
+	reservations map[*bind.TransactOpts]*gasReservation
+	deadlines    map[*bind.TransactOpts]*deadlineTx
+}
+
+//NewGasPricer create a gas pricer of default config, gas spent is not saved until a store is set
+func NewGasPricer(client *helper.SafeEthClient) *GasPricer {
+	g := &GasPricer{
+		client:       client,
+		reservations: make(map[*bind.TransactOpts]*gasReservation),
+		deadlines:    make(map[*bind.TransactOpts]*deadlineTx),
+	}
+	err := g.setConfig(params.DefaultGasPriceConfig())
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+//SetStore loads config saved in `store`, and saves config and gas spent to it from now on
+func (g *GasPricer) SetStore(store GasPriceStore) error {
+	g.lock.Lock()
+	g.store = store
+	g.lock.Unlock()
+	c, err := store.GetGasPriceConfig()
+	if err != nil {
+		//never configured
+		return nil
+	}
+	return g.setConfig(c)
+}
+
+//newGasPriceStrategy returns strategy of `c`, error if `c` is invalid
+func (g *GasPricer) newGasPriceStrategy(c *params.GasPriceConfig) (s GasPriceStrategy, err error) {
+	if c.Multiplier <= 0 {
+		return nil, errors.New("multiplier must be positive")
+	}
+	for u, m := range c.UrgencyMultipliers {
+		if m <= 0 {
+			return nil, fmt.Errorf("multiplier of urgency %s must be positive", u)
+		}
+	}
+	switch c.Strategy {
+	case params.GasPriceFixed:
+		if c.FixedPrice == nil || c.FixedPrice.Sign() <= 0 {
+			return nil, errors.New("fixed price must be positive")
+		}
+		s = &fixedGasPrice{c.FixedPrice}
+	case params.GasPriceSuggest:
+		s = &suggestedGasPrice{g.client, c.Multiplier}
+	case params.GasPriceDeadline:
+		if c.EscalationBlocks <= 0 || c.MaxEscalation < 1 {
+			return nil, errors.New("escalation blocks must be positive and max escalation must not be less than 1")
+		}
+		s = &deadlineGasPrice{&suggestedGasPrice{g.client, c.Multiplier}, c.EscalationBlocks, c.MaxEscalation}
+	default:
+		return nil, fmt.Errorf("unknown gas price strategy %s", c.Strategy)
+	}
+	return
+}
+
+func (g *GasPricer) setConfig(c *params.GasPriceConfig) error {
+	s, err := g.newGasPriceStrategy(c)
+	if err != nil {
+		return err
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	g.config = c
+	g.strategy = s
+	return nil
+}
+
+//Config returns current gas price config
+func (g *GasPricer) Config() *params.GasPriceConfig {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.config
+}
+
+//SetConfig validates and saves `c`, it's used by transactions sent after
+func (g *GasPricer) SetConfig(c *params.GasPriceConfig) error {
+	err := g.setConfig(c)
+	if err != nil {
+		return err
+	}
+	if g.store != nil {
+		return g.store.SaveGasPriceConfig(c)
+	}
+	return nil
+}
+
+//Spent returns wei spent on gas of transactions of `token`
+func (g *GasPricer) Spent(token common.Address) *big.Int {
+	if g.store == nil {
+		return new(big.Int)
+	}
+	return g.store.GetGasSpent(token)
+}
+
+//GasPrice returns price of a transaction of `urgency`, which has `blocksLeft` blocks before its deadline, negative if no deadline
+func (g *GasPricer) GasPrice(urgency Urgency, blocksLeft int64) (price *big.Int, err error) {
+	g.lock.Lock()
+	c, s := g.config, g.strategy
+	g.lock.Unlock()
+	price, err = s.GasPrice(urgency, blocksLeft)
+	if err != nil {
+		return
+	}
+	if m, ok := c.UrgencyMultipliers[string(urgency)]; ok {
+		price = mulBig(price, m)
+	}
+	if c.MaxPrice != nil && c.MaxPrice.Sign() > 0 && price.Cmp(c.MaxPrice) > 0 {
+		price = new(big.Int).Set(c.MaxPrice)
+	}
+	return
+}
+
+/*
+TransactOpts returns a copy of `auth` with gas price of a transaction of `token` and `urgency`,
+`deadline` is the last block the transaction must be mined in, 0 if it has none.
+the returned opts must be given to Release after the transaction is mined or fails to be sent.
+*/
+func (g *GasPricer) TransactOpts(auth *bind.TransactOpts, token common.Address, urgency Urgency, deadline int64, currentBlock int64) (*bind.TransactOpts, error) {
+	var blocksLeft int64 = -1
+	if deadline > 0 {
+		blocksLeft = deadline - currentBlock
+		if blocksLeft < 0 {
+			blocksLeft = 0
+		}
+	}
+	price, err := g.GasPrice(urgency, blocksLeft)
+	if err != nil {
+		return nil, err
+	}
+	opts := *auth
+	opts.GasPrice = price
+	err = g.reserve(&opts, token, urgency)
+	if err != nil {
+		return nil, err
+	}
+	if deadline > 0 {
+		g.lock.Lock()
+		g.deadlines[&opts] = &deadlineTx{urgency, deadline}
+		g.lock.Unlock()
+	}
+	if urgency != UrgencyOther && urgency != UrgencyDeposit {
+		log.Info(fmt.Sprintf("%s tx gas price %s, blocks left %d", urgency, price, blocksLeft))
+	}
+	return &opts, nil
+}
+
+//reserve checks spending cap of `token` and reserves the most `opts` may cost, in one lock
+func (g *GasPricer) reserve(opts *bind.TransactOpts, token common.Address, urgency Urgency) error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	limit, ok := g.config.SpendingCaps[token]
+	if !ok {
+		return nil
+	}
+	spent := new(big.Int)
+	if g.store != nil {
+		spent.Set(g.store.GetGasSpent(token))
+	}
+	for _, r := range g.reservations {
+		if r.token == token {
+			spent.Add(spent, r.cost)
+		}
+	}
+	if spent.Cmp(limit) >= 0 {
+		if !cappedUrgencies[urgency] {
+			log.Warn(fmt.Sprintf("%s tx of %s exceeds gas spending cap %s, it's sent anyway to protect tokens in channel", urgency, utils.APex(token), limit))
+			return nil
+		}
+		return fmt.Errorf("%s %s: %s", urgency, utils.APex(token), ErrGasSpendingCap)
+	}
+	g.reservations[opts] = &gasReservation{
+		token: token,
+		cost:  new(big.Int).Mul(opts.GasPrice, new(big.Int).SetUint64(opts.GasLimit)),
+	}
+	return nil
+}
+
+//Release gives up what is reserved for the transaction of `opts`, it's safe to be called more than once
+func (g *GasPricer) Release(opts *bind.TransactOpts) {
+	g.lock.Lock()
+	delete(g.reservations, opts)
+	delete(g.deadlines, opts)
+	g.lock.Unlock()
+}
+
+//deadlineOf returns deadline of the transaction of `opts`, nil if it has none
+func (g *GasPricer) deadlineOf(opts *bind.TransactOpts) *deadlineTx {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.deadlines[opts]
+}
+
+//txPollInterval time between two checks whether a transaction with a deadline is mined
+var txPollInterval = time.Second
+
+//repricingBackend is what re-sending a transaction at a higher price needs, SafeEthClient is one
+type repricingBackend interface {
+	TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error)
+	HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error)
+	SendTransaction(ctx context.Context, tx *types.Transaction) error
+}
+
+/*
+waitMinedRepricing waits `tx` of `opts` with deadline `d` to be mined.
+its price is computed again at every new block, and it's re-sent with the same nonce when the price rises enough to replace it,
+so the price keeps rising while it's not mined as the deadline gets closer.
+returns the one of transactions sent which is mined.
+*/
+func (g *GasPricer) waitMinedRepricing(ctx context.Context, b repricingBackend, opts *bind.TransactOpts, tx *types.Transaction, d *deadlineTx) (*types.Transaction, *types.Receipt, error) {
+	metrics.PendingTransactions.Inc()
+	defer metrics.PendingTransactions.Dec()
+	txs := []*types.Transaction{tx}
+	var lastBlock int64 = -1
+	ticker := time.NewTicker(txPollInterval)
+	defer ticker.Stop()
+	for {
+		for _, t := range txs {
+			receipt, err := b.TransactionReceipt(ctx, t.Hash())
+			if err == nil && receipt != nil {
+				return t, receipt, nil
+			}
+		}
+		h, err := b.HeaderByNumber(ctx, nil)
+		if err == nil && h.Number.Int64() > lastBlock {
+			lastBlock = h.Number.Int64()
+			if t := g.reprice(ctx, b, opts, txs[len(txs)-1], d, lastBlock); t != nil {
+				txs = append(txs, t)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+//reprice re-sends `last` at price of `block` if it's high enough to replace `last` in tx pool, nil if it's not re-sent
+func (g *GasPricer) reprice(ctx context.Context, b repricingBackend, opts *bind.TransactOpts, last *types.Transaction, d *deadlineTx, block int64) *types.Transaction {
+	if last.To() == nil {
+		return nil
+	}
+	blocksLeft := d.deadline - block
+	if blocksLeft < 0 {
+		blocksLeft = 0
+	}
+	price, err := g.GasPrice(d.urgency, blocksLeft)
+	if err != nil {
+		log.Warn(fmt.Sprintf("%s tx %s price err %s", d.urgency, last.Hash().String(), err))
+		return nil
+	}
+	//a pending transaction is replaced only by one at least 10% more expensive
+	least := new(big.Int).Div(new(big.Int).Mul(last.GasPrice(), big.NewInt(110)), big.NewInt(100))
+	if price.Cmp(least) <= 0 {
+		return nil
+	}
+	tx, err := opts.Signer(types.HomesteadSigner{}, opts.From,
+		types.NewTransaction(last.Nonce(), *last.To(), last.Value(), last.Gas(), price, last.Data()))
+	if err == nil {
+		err = b.SendTransaction(ctx, tx)
+	}
+	if err != nil {
+		log.Warn(fmt.Sprintf("re-send %s tx %s err %s", d.urgency, last.Hash().String(), err))
+		return nil
+	}
+	g.lock.Lock()
+	if r, ok := g.reservations[opts]; ok {
+		r.cost = new(big.Int).Mul(price, new(big.Int).SetUint64(tx.Gas()))
+	}
+	g.lock.Unlock()
+	log.Info(fmt.Sprintf("%s tx %s re-sent as %s, gas price %s, blocks left %d", d.urgency, last.Hash().String(), tx.Hash().String(), price, blocksLeft))
+	return tx
+}
+
+//AddSpent adds gas used by mined transaction `tx` to spending of `token`
+func (g *GasPricer) AddSpent(token common.Address, tx *types.Transaction, receipt *types.Receipt) {
+	if g.store == nil || receipt == nil {
+		return
+	}
+	spent := new(big.Int).Mul(tx.GasPrice(), new(big.Int).SetUint64(receipt.GasUsed))
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	err := g.store.AddGasSpent(token, spent)
+	if err != nil {
+		log.Error(fmt.Sprintf("AddGasSpent %s err %s", utils.APex(token), err))
+	}
+}
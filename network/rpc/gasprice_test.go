@@ -0,0 +1,232 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+type memGasPriceStore struct {
+	config *params.GasPriceConfig
+	spent  map[common.Address]*big.Int
+}
+
+func (m *memGasPriceStore) GetGasPriceConfig() (*params.GasPriceConfig, error) {
+	if m.config == nil {
+		return nil, errors.New("not found")
+	}
+	return m.config, nil
+}
+
+func (m *memGasPriceStore) SaveGasPriceConfig(c *params.GasPriceConfig) error {
+	m.config = c
+	return nil
+}
+
+func (m *memGasPriceStore) GetGasSpent(token common.Address) *big.Int {
+	if m.spent[token] == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(m.spent[token])
+}
+
+func (m *memGasPriceStore) AddGasSpent(token common.Address, amount *big.Int) error {
+	m.spent[token] = new(big.Int).Add(m.GetGasSpent(token), amount)
+	return nil
+}
+
+func TestDeadlineGasPrice(t *testing.T) {
+	s := &deadlineGasPrice{&fixedGasPrice{big.NewInt(100)}, 10, 3}
+	cases := []struct {
+		blocksLeft int64
+		price      int64
+	}{
+		{-1, 100}, //no deadline
+		{20, 100},
+		{10, 100},
+		{5, 200},
+		{0, 300},
+	}
+	for _, c := range cases {
+		price, err := s.GasPrice(UrgencyUpdateProof, c.blocksLeft)
+		assert.Nil(t, err)
+		assert.EqualValues(t, c.price, price.Int64(), "blocks left %d", c.blocksLeft)
+	}
+}
+
+func TestGasPricer(t *testing.T) {
+	g := NewGasPricer(nil)
+	store := &memGasPriceStore{spent: make(map[common.Address]*big.Int)}
+	assert.Nil(t, g.SetStore(store))
+	auth := &bind.TransactOpts{GasLimit: uint64(params.GasLimit)}
+	opts, err := g.TransactOpts(auth, utils.NewRandomAddress(), UrgencyClose, 0, 0)
+	assert.Nil(t, err)
+	assert.EqualValues(t, params.GasPrice, opts.GasPrice.Int64())
+	assert.Nil(t, auth.GasPrice, "auth is not changed")
+
+	c := params.DefaultGasPriceConfig()
+	c.Strategy = "cheapest"
+	assert.NotNil(t, g.SetConfig(c))
+	c.Strategy = params.GasPriceFixed
+	c.FixedPrice = big.NewInt(100)
+	c.UrgencyMultipliers = map[string]float64{string(UrgencyPunish): 2.5}
+	c.MaxPrice = big.NewInt(200)
+	token := utils.NewRandomAddress()
+	c.SpendingCaps = map[common.Address]*big.Int{token: big.NewInt(1000)}
+	assert.Nil(t, g.SetConfig(c))
+	assert.Equal(t, c, store.config)
+	price, err := g.GasPrice(UrgencyPunish, -1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 200, price.Int64(), "limited by max price")
+	price, err = g.GasPrice(UrgencySettle, -1)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 100, price.Int64())
+
+	tx := types.NewTransaction(0, token, nil, 0, big.NewInt(100), nil)
+	g.AddSpent(token, tx, &types.Receipt{GasUsed: 9})
+	auth2 := &bind.TransactOpts{GasLimit: 1}
+	opts, err = g.TransactOpts(auth2, token, UrgencyDeposit, 0, 0)
+	assert.Nil(t, err)
+	_, err = g.TransactOpts(auth2, token, UrgencyDeposit, 0, 0)
+	assert.NotNil(t, err, "the first one may spend the rest")
+	g.Release(opts)
+	g.Release(opts)
+	opts, err = g.TransactOpts(auth2, token, UrgencyDeposit, 0, 0)
+	assert.Nil(t, err)
+	g.AddSpent(token, tx, &types.Receipt{GasUsed: 1})
+	g.Release(opts)
+	assert.EqualValues(t, 1000, g.Spent(token).Int64())
+	for _, u := range []Urgency{UrgencyDeposit, UrgencyClose, UrgencyOther} {
+		_, err = g.TransactOpts(auth, token, u, 0, 0)
+		assert.NotNil(t, err, "spending cap reached")
+	}
+	for _, u := range []Urgency{UrgencyUpdateProof, UrgencyUnlock, UrgencySettle, UrgencyPunish} {
+		_, err = g.TransactOpts(auth, token, u, 0, 0)
+		assert.Nil(t, err, "%s is sent anyway", u)
+	}
+
+	//config saved is loaded
+	g2 := NewGasPricer(nil)
+	assert.Nil(t, g2.SetStore(store))
+	assert.Equal(t, c, g2.Config())
+}
+
+//fakeTxBackend is a chain which mines a transaction only when it's told to
+type fakeTxBackend struct {
+	lock   sync.Mutex
+	number int64
+	sent   []*types.Transaction
+	mined  map[common.Hash]bool
+}
+
+func (b *fakeTxBackend) TransactionReceipt(ctx context.Context, txHash common.Hash) (*types.Receipt, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if !b.mined[txHash] {
+		return nil, errors.New("not found")
+	}
+	return &types.Receipt{Status: types.ReceiptStatusSuccessful, TxHash: txHash}, nil
+}
+
+func (b *fakeTxBackend) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return &types.Header{Number: big.NewInt(b.number)}, nil
+}
+
+func (b *fakeTxBackend) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sent = append(b.sent, tx)
+	return nil
+}
+
+func (b *fakeTxBackend) setNumber(number int64) {
+	b.lock.Lock()
+	b.number = number
+	b.lock.Unlock()
+}
+
+func (b *fakeTxBackend) mine(tx *types.Transaction) {
+	b.lock.Lock()
+	b.mined[tx.Hash()] = true
+	b.lock.Unlock()
+}
+
+//waitSent waits `n` transactions to be sent and returns them
+func (b *fakeTxBackend) waitSent(t *testing.T, n int) []*types.Transaction {
+	for i := 0; i < 200; i++ {
+		b.lock.Lock()
+		sent := b.sent
+		b.lock.Unlock()
+		if len(sent) >= n {
+			return sent
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("%d transactions are not sent", n)
+	return nil
+}
+
+func TestWaitMinedRepricing(t *testing.T) {
+	interval := txPollInterval
+	txPollInterval = 10 * time.Millisecond
+	defer func() { txPollInterval = interval }()
+	g := NewGasPricer(nil)
+	g.strategy = &deadlineGasPrice{&fixedGasPrice{big.NewInt(100)}, 10, 3}
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeTxBackend{number: 5, mined: make(map[common.Hash]bool)}
+	opts, err := g.TransactOpts(bind.NewKeyedTransactor(key), utils.NewRandomAddress(), UrgencyUpdateProof, 20, b.number)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 100, opts.GasPrice.Int64())
+	d := g.deadlineOf(opts)
+	if !assert.NotNil(t, d) {
+		return
+	}
+	tx, err := opts.Signer(types.HomesteadSigner{}, opts.From, types.NewTransaction(3, utils.NewRandomAddress(), big.NewInt(0), 100000, opts.GasPrice, nil))
+	assert.Nil(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan *types.Transaction)
+	go func() {
+		mined, receipt, err := g.waitMinedRepricing(ctx, b, opts, tx, d)
+		assert.Nil(t, err)
+		assert.NotNil(t, receipt)
+		done <- mined
+	}()
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, b.waitSent(t, 0), 0, "price doesn't rise far from the deadline")
+	//5 blocks left
+	b.setNumber(15)
+	sent := b.waitSent(t, 1)
+	assert.EqualValues(t, 200, sent[0].GasPrice().Int64())
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, b.waitSent(t, 1), 1, "re-sent only once a block")
+	//2 blocks left
+	b.setNumber(18)
+	sent = b.waitSent(t, 2)
+	assert.EqualValues(t, 260, sent[1].GasPrice().Int64())
+	for _, s := range sent {
+		assert.EqualValues(t, tx.Nonce(), s.Nonce())
+		assert.EqualValues(t, tx.Data(), s.Data())
+	}
+	//a replaced transaction may still be mined
+	b.mine(sent[0])
+	assert.EqualValues(t, sent[0].Hash(), (<-done).Hash())
+	g.Release(opts)
+	assert.Nil(t, g.deadlineOf(opts))
+}
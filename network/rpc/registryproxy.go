@@ -8,7 +8,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -38,11 +37,16 @@ func (r *RegistryProxy) GetContract() *contracts.TokenNetworkRegistry {
 
 //AddToken register a new token,this token must be a valid erc20
 func (r *RegistryProxy) AddToken(tokenAddress common.Address) (tokenNetworkAddress common.Address, err error) {
-	tx, err := r.registry.CreateERC20TokenNetwork(r.bcs.Auth, tokenAddress)
+	opts, err := r.bcs.transactOpts(tokenAddress, UrgencyOther, 0)
 	if err != nil {
 		return
 	}
-	receipt, err := bind.WaitMined(GetCallContext(), r.bcs.Client, tx)
+	defer r.bcs.GasPricer.Release(opts)
+	tx, err := r.registry.CreateERC20TokenNetwork(opts, tokenAddress)
+	if err != nil {
+		return
+	}
+	receipt, err := r.bcs.waitMined(tokenAddress, opts, tx)
 	if err != nil {
 		return
 	}
@@ -8,7 +8,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -22,8 +21,11 @@ type SecretRegistryProxy struct {
 	RegisteredSecret map[common.Hash]*sync.Mutex
 }
 
-//RegisterSecret register secret on chain 有可能被重复调用,但是保证不会并发注册同一个密码
-func (s *SecretRegistryProxy) RegisterSecret(secret common.Hash) (err error) {
+/*
+RegisterSecret register secret on chain 有可能被重复调用,但是保证不会并发注册同一个密码
+`lockExpiration` is the block the lock expires, registration must be mined before it, 0 if unknown.
+*/
+func (s *SecretRegistryProxy) RegisterSecret(secret common.Hash, lockExpiration int64) (err error) {
 	s.lock.Lock()
 	sp := s.RegisteredSecret[secret]
 	if sp == nil {
@@ -33,11 +35,16 @@ func (s *SecretRegistryProxy) RegisterSecret(secret common.Hash) (err error) {
 	s.lock.Unlock()
 	sp.Lock()
 	defer sp.Unlock()
-	tx, err := s.registry.RegisterSecret(s.bcs.Auth, secret)
+	opts, err := s.bcs.transactOpts(utils.EmptyAddress, UrgencyUnlock, lockExpiration)
+	if err != nil {
+		return
+	}
+	defer s.bcs.GasPricer.Release(opts)
+	tx, err := s.registry.RegisterSecret(opts, secret)
 	if err != nil {
 		return err
 	}
-	receipt, err := bind.WaitMined(GetCallContext(), s.bcs.Client, tx)
+	receipt, err := s.bcs.waitMined(utils.EmptyAddress, opts, tx)
 	if err != nil {
 		return err
 	}
@@ -50,10 +57,10 @@ func (s *SecretRegistryProxy) RegisterSecret(secret common.Hash) (err error) {
 }
 
 //RegisterSecretAsync 异步注册一个密码
-func (s *SecretRegistryProxy) RegisterSecretAsync(secret common.Hash) (result *utils.AsyncResult) {
+func (s *SecretRegistryProxy) RegisterSecretAsync(secret common.Hash, lockExpiration int64) (result *utils.AsyncResult) {
 	result = utils.NewAsyncResult()
 	go func() {
-		err := s.RegisterSecret(secret)
+		err := s.RegisterSecret(secret, lockExpiration)
 		result.Result <- err
 	}()
 	return result
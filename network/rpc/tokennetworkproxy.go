@@ -7,6 +7,8 @@ import (
 
 	"bytes"
 
+	"sync"
+
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mtree"
@@ -21,16 +23,88 @@ type TokenNetworkProxy struct {
 	Address common.Address //this contract address
 	bcs     *BlockChainService
 	ch      *contracts.TokenNetwork
+	lock    sync.Mutex
+	token   common.Address //token of this contract, got when it's needed
+	punish  uint64         //punish_block_number of this contract, 0 until it's needed
+}
+
+//tokenAddress returns token of this token network
+func (t *TokenNetworkProxy) tokenAddress() (token common.Address, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.token == utils.EmptyAddress {
+		t.token, err = t.ch.Token(nil)
+	}
+	return t.token, err
+}
+
+/*
+CachedPunishBlockNumber returns punish blocks read by PunishBlockNumber before without blocking,
+ok is false if it's never read.
+*/
+func (t *TokenNetworkProxy) CachedPunishBlockNumber() (n uint64, ok bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.punish, t.punish != 0
+}
+
+//PunishBlockNumber returns blocks after settle timeout reserved for punishment, it never changes once deployed
+func (t *TokenNetworkProxy) PunishBlockNumber() (n uint64, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	if t.punish == 0 {
+		t.punish, err = t.ch.Punish_block_number(nil)
+	}
+	return t.punish, err
+}
+
+//transactOpts returns options of a transaction of `urgency` on this token network, `deadline` is 0 if it has none
+func (t *TokenNetworkProxy) transactOpts(urgency Urgency, deadline int64) (*bind.TransactOpts, error) {
+	token, err := t.tokenAddress()
+	if err != nil {
+		return nil, err
+	}
+	return t.bcs.transactOpts(token, urgency, deadline)
+}
+
+//waitMined waits `tx` sent with `opts` to be mined, and adds its gas to spending of token of this token network
+func (t *TokenNetworkProxy) waitMined(opts *bind.TransactOpts, tx *types.Transaction) (*types.Receipt, error) {
+	token, err := t.tokenAddress()
+	if err != nil {
+		return nil, err
+	}
+	return t.bcs.waitMined(token, opts, tx)
+}
+
+/*
+disputeDeadline returns the block settle can be called at of closed channel between `p1` and `p2`,
+update balance proof, unlock and punish must be mined before it. 0 if the channel is not closed.
+*/
+func (t *TokenNetworkProxy) disputeDeadline(p1, p2 common.Address) int64 {
+	_, settleBlockNumber, _, state, _, err := t.GetChannelInfo(p1, p2)
+	if err != nil {
+		log.Warn(fmt.Sprintf("GetChannelInfo %s-%s err %s", utils.APex(p1), utils.APex(p2), err))
+		return 0
+	}
+	if state != contracts.ChannelStateClosed {
+		return 0
+	}
+	return int64(settleBlockNumber)
 }
 
 //NewChannel create new channel ,block until a new channel create
 func (t *TokenNetworkProxy) NewChannel(participantAddress, partnerAddress common.Address, settleTimeout int) (err error) {
-	tx, err := t.ch.OpenChannel(t.bcs.Auth, participantAddress, partnerAddress, uint64(settleTimeout))
+	opts, err := t.transactOpts(UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.ch.OpenChannel(opts, participantAddress, partnerAddress, uint64(settleTimeout))
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("NewChannel txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return
 	}
@@ -81,12 +155,17 @@ func (t *TokenNetworkProxy) newChannelAndDepositByApprove(token *TokenProxy, par
 	if err != nil {
 		return err
 	}
-	tx, err := t.GetContract().OpenChannelWithDeposit(t.bcs.Auth, participantAddress, partnerAddress, uint64(settleTimeout), amount)
+	opts, err := t.transactOpts(UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().OpenChannelWithDeposit(opts, participantAddress, partnerAddress, uint64(settleTimeout), amount)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("OpenChannelWithDeposit  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -156,12 +235,17 @@ func (t *TokenNetworkProxy) GetContract() *contracts.TokenNetwork {
 
 //CloseChannel close channel
 func (t *TokenNetworkProxy) CloseChannel(partnerAddr common.Address, transferAmount *big.Int, locksRoot common.Hash, nonce int64, extraHash common.Hash, signature []byte) (err error) {
-	tx, err := t.GetContract().CloseChannel(t.bcs.Auth, partnerAddr, transferAmount, locksRoot, uint64(nonce), extraHash, signature)
+	opts, err := t.transactOpts(UrgencyClose, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().CloseChannel(opts, partnerAddr, transferAmount, locksRoot, uint64(nonce), extraHash, signature)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("CloseChannel  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -185,12 +269,17 @@ func (t *TokenNetworkProxy) CloseChannelAsync(partnerAddr common.Address, transf
 
 //UpdateBalanceProof update balance proof of partner
 func (t *TokenNetworkProxy) UpdateBalanceProof(partnerAddr common.Address, transferAmount *big.Int, locksRoot common.Hash, nonce int64, extraHash common.Hash, signature []byte) (err error) {
-	tx, err := t.GetContract().UpdateBalanceProof(t.bcs.Auth, partnerAddr, transferAmount, locksRoot, uint64(nonce), extraHash, signature)
+	opts, err := t.transactOpts(UrgencyUpdateProof, t.disputeDeadline(t.bcs.NodeAddress, partnerAddr))
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().UpdateBalanceProof(opts, partnerAddr, transferAmount, locksRoot, uint64(nonce), extraHash, signature)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("UpdateBalanceProof  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -215,12 +304,17 @@ func (t *TokenNetworkProxy) UpdateBalanceProofAsync(partnerAddr common.Address,
 
 //Unlock a partner's lock
 func (t *TokenNetworkProxy) Unlock(partnerAddr common.Address, transferAmount *big.Int, lock *mtree.Lock, proof []byte) (err error) {
-	tx, err := t.GetContract().Unlock(t.bcs.Auth, partnerAddr, transferAmount, big.NewInt(lock.Expiration), lock.Amount, lock.LockSecretHash, proof)
+	opts, err := t.transactOpts(UrgencyUnlock, t.disputeDeadline(t.bcs.NodeAddress, partnerAddr))
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().Unlock(opts, partnerAddr, transferAmount, big.NewInt(lock.Expiration), lock.Amount, lock.LockSecretHash, proof)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("Unlock  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -244,12 +338,17 @@ func (t *TokenNetworkProxy) UnlockAsync(partnerAddr common.Address, transferAmou
 
 //SettleChannel settle a channel
 func (t *TokenNetworkProxy) SettleChannel(p1Addr, p2Addr common.Address, p1Amount, p2Amount *big.Int, p1Locksroot, p2Locksroot common.Hash) (err error) {
-	tx, err := t.GetContract().SettleChannel(t.bcs.Auth, p1Addr, p1Amount, p2Locksroot, p2Addr, p2Amount, p2Locksroot)
+	opts, err := t.transactOpts(UrgencySettle, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().SettleChannel(opts, p1Addr, p1Amount, p2Locksroot, p2Addr, p2Amount, p2Locksroot)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("SettleChannel  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -294,12 +393,17 @@ func (t *TokenNetworkProxy) depositByApprove(token *TokenProxy, participant, par
 	if err != nil {
 		return
 	}
-	tx, err := t.GetContract().Deposit(t.bcs.Auth, participant, partner, amount)
+	opts, err := t.transactOpts(UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().Deposit(opts, participant, partner, amount)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("Deposit  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -347,7 +451,12 @@ func (t *TokenNetworkProxy) DepositAsync(participant, partner common.Address, am
 //Withdraw  to  a channel
 func (t *TokenNetworkProxy) Withdraw(p1Addr, p2Addr common.Address, p1Balance, p2Balance *big.Int,
 	p1Withdraw, p2Withdraw *big.Int, p1Signature, p2Signature []byte) (err error) {
-	tx, err := t.GetContract().WithDraw(t.bcs.Auth, p1Addr, p1Balance, p1Withdraw,
+	opts, err := t.transactOpts(UrgencyOther, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().WithDraw(opts, p1Addr, p1Balance, p1Withdraw,
 		p2Addr, p2Balance, p2Withdraw,
 		p1Signature, p2Signature,
 	)
@@ -355,7 +464,7 @@ func (t *TokenNetworkProxy) Withdraw(p1Addr, p2Addr common.Address, p1Balance, p
 		return
 	}
 	log.Info(fmt.Sprintf("Withdraw  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -380,12 +489,17 @@ func (t *TokenNetworkProxy) WithdrawAsync(p1Addr, p2Addr common.Address, p1Balan
 
 //PunishObsoleteUnlock  to  a channel
 func (t *TokenNetworkProxy) PunishObsoleteUnlock(beneficiary, cheater common.Address, lockhash, extraHash common.Hash, cheaterSignature []byte) (err error) {
-	tx, err := t.GetContract().PunishObsoleteUnlock(t.bcs.Auth, beneficiary, cheater, lockhash, extraHash, cheaterSignature)
+	opts, err := t.transactOpts(UrgencyPunish, t.disputeDeadline(beneficiary, cheater))
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().PunishObsoleteUnlock(opts, beneficiary, cheater, lockhash, extraHash, cheaterSignature)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("PunishObsoleteUnlock  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -409,12 +523,17 @@ func (t *TokenNetworkProxy) PunishObsoleteUnlockAsync(beneficiary, cheater commo
 
 //CooperativeSettle  settle  a channel
 func (t *TokenNetworkProxy) CooperativeSettle(p1Addr, p2Addr common.Address, p1Balance, p2Balance *big.Int, p1Signature, p2Signatue []byte) (err error) {
-	tx, err := t.GetContract().CooperativeSettle(t.bcs.Auth, p1Addr, p1Balance, p2Addr, p2Balance, p1Signature, p2Signatue)
+	opts, err := t.transactOpts(UrgencySettle, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.GetContract().CooperativeSettle(opts, p1Addr, p1Balance, p2Addr, p2Balance, p1Signature, p2Signatue)
 	if err != nil {
 		return
 	}
 	log.Info(fmt.Sprintf("CooperativeSettle  txhash=%s", tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.waitMined(opts, tx)
 	if err != nil {
 		return err
 	}
@@ -8,7 +8,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
-	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 )
@@ -52,12 +51,17 @@ func (t *TokenProxy) Allowance(owner, spender common.Address) (int64, error) {
 // @param _spender The address of the account able to transfer the tokens
 // @param _value The amount of wei to be approved for transfer
 func (t *TokenProxy) Approve(spender common.Address, value *big.Int) (err error) {
-	tx, err := t.Token.Approve(t.bcs.Auth, spender, value)
+	opts, err := t.bcs.transactOpts(t.Address, UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.Token.Approve(opts, spender, value)
 	if err != nil {
 		return err
 	}
 	log.Info(fmt.Sprintf("Approve %s, txhash=%s", utils.APex(spender), tx.Hash().String()))
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.bcs.waitMined(t.Address, opts, tx)
 	if err != nil {
 		return err
 	}
@@ -79,11 +83,16 @@ func (t *TokenProxy) Transfer(spender common.Address, value *big.Int) (err error
 	if err != nil {
 		return
 	}
-	tx, err := t.Token.TransferFrom(t.bcs.Auth, t.bcs.Auth.From, spender, value)
+	opts, err := t.bcs.transactOpts(t.Address, UrgencyOther, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.Token.TransferFrom(opts, t.bcs.Auth.From, spender, value)
 	if err != nil {
 		return err
 	}
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.bcs.waitMined(t.Address, opts, tx)
 	if err != nil {
 		return err
 	}
@@ -107,11 +116,16 @@ func (t *TokenProxy) TransferAsync(spender common.Address, value *big.Int) (resu
 
 //TransferWithFallback ERC223 TokenFallback
 func (t *TokenProxy) TransferWithFallback(to common.Address, value *big.Int, extraData []byte) (err error) {
-	tx, err := t.Token.Transfer(t.bcs.Auth, to, value, extraData)
+	opts, err := t.bcs.transactOpts(t.Address, UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.Token.Transfer(opts, to, value, extraData)
 	if err != nil {
 		return err
 	}
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.bcs.waitMined(t.Address, opts, tx)
 	if err != nil {
 		return err
 	}
@@ -125,11 +139,16 @@ func (t *TokenProxy) TransferWithFallback(to common.Address, value *big.Int, ext
 
 //ApproveAndCall ERC20 extend
 func (t *TokenProxy) ApproveAndCall(spender common.Address, value *big.Int, extraData []byte) (err error) {
-	tx, err := t.Token.ApproveAndCall(t.bcs.Auth, spender, value, extraData)
+	opts, err := t.bcs.transactOpts(t.Address, UrgencyDeposit, 0)
+	if err != nil {
+		return
+	}
+	defer t.bcs.GasPricer.Release(opts)
+	tx, err := t.Token.ApproveAndCall(opts, spender, value, extraData)
 	if err != nil {
 		return err
 	}
-	receipt, err := bind.WaitMined(GetCallContext(), t.bcs.Client, tx)
+	receipt, err := t.bcs.waitMined(t.Address, opts, tx)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,141 @@
+package network
+
+import (
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//bounds of the adaptive retransmit timeout
+var (
+	minRetransmitTimeout = time.Second
+	maxRetransmitTimeout = time.Minute
+)
+
+//clock granularity of rto calculation
+const rttGranularity = 10 * time.Millisecond
+
+/*
+PeerStats is statistics of messages sent to one peer.
+SRTT and RTTVar are estimated from the round trip of messages which were acked without retransmission,
+the same as TCP(RFC 6298).
+*/
+type PeerStats struct {
+	Address       common.Address
+	SRTT          time.Duration //smoothed round trip time
+	RTTVar        time.Duration //round trip time variation
+	RTO           time.Duration //retransmit timeout
+	Samples       int64         //how many round trips measured
+	Sent          int64         //messages sent
+	Acked         int64         //messages acked
+	Transmissions int64         //transmissions, including retransmissions
+	Retransmits   int64
+}
+
+//LossRate transmissions not acked
+func (s *PeerStats) LossRate() float64 {
+	if s.Transmissions == 0 {
+		return 0
+	}
+	return float64(s.Retransmits) / float64(s.Transmissions)
+}
+
+func newPeerStats(addr common.Address, initialRTO time.Duration) *PeerStats {
+	return &PeerStats{
+		Address: addr,
+		RTO:     initialRTO,
+	}
+}
+
+//addSample update rtt estimation with a new measured round trip `r`
+func (s *PeerStats) addSample(r time.Duration) {
+	if s.Samples == 0 {
+		s.SRTT = r
+		s.RTTVar = r / 2
+	} else {
+		delta := s.SRTT - r
+		if delta < 0 {
+			delta = -delta
+		}
+		s.RTTVar = (3*s.RTTVar + delta) / 4
+		s.SRTT = (7*s.SRTT + r) / 8
+	}
+	s.Samples++
+	k := 4 * s.RTTVar
+	if k < rttGranularity {
+		k = rttGranularity
+	}
+	s.RTO = s.SRTT + k
+	if s.RTO < minRetransmitTimeout {
+		s.RTO = minRetransmitTimeout
+	}
+	if s.RTO > maxRetransmitTimeout {
+		s.RTO = maxRetransmitTimeout
+	}
+}
+
+//caller must hold statsLock
+func (p *RaidenProtocol) getPeerStats(addr common.Address) *PeerStats {
+	s, ok := p.peerStats[addr]
+	if !ok {
+		s = newPeerStats(addr, p.retryInterval)
+		p.peerStats[addr] = s
+	}
+	return s
+}
+
+//retransmitTimeout returns current retransmit timeout of `addr`
+func (p *RaidenProtocol) retransmitTimeout(addr common.Address) time.Duration {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	return p.getPeerStats(addr).RTO
+}
+
+//onTransmitted a message has been written to transport
+func (p *RaidenProtocol) onTransmitted(receiver common.Address, echohash common.Hash) {
+	p.mapLock.Lock()
+	msgState, ok := p.SentHashesToChannel[echohash]
+	p.mapLock.Unlock()
+	if !ok {
+		return
+	}
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	s := p.getPeerStats(receiver)
+	s.Transmissions++
+	if msgState.tries > 0 {
+		s.Retransmits++
+		metrics.MessageRetries.Inc(encoding.MessageType(msgState.Message.Cmd()).String())
+	} else {
+		s.Sent++
+	}
+	msgState.tries++
+	msgState.lastSent = time.Now()
+}
+
+/*
+onAcked a message has been acked,
+by Karn's algorithm, round trip of retransmitted message is ambiguous and ignored.
+*/
+func (p *RaidenProtocol) onAcked(msgState *SentMessageState) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	s := p.getPeerStats(msgState.ReceiverAddress)
+	s.Acked++
+	if msgState.tries == 1 {
+		s.addSample(time.Since(msgState.lastSent))
+	}
+}
+
+//GetPeerStats returns a copy of statistics of all peers we have sent messages to
+func (p *RaidenProtocol) GetPeerStats() (stats []*PeerStats) {
+	p.statsLock.Lock()
+	defer p.statsLock.Unlock()
+	for _, s := range p.peerStats {
+		s2 := *s
+		stats = append(stats, &s2)
+	}
+	return
+}
@@ -0,0 +1,56 @@
+package network
+
+import (
+	"testing"
+
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPeerStatsAddSample(t *testing.T) {
+	s := newPeerStats(utils.NewRandomAddress(), time.Second*6)
+	s.addSample(time.Second * 2)
+	assert.EqualValues(t, s.SRTT, time.Second*2)
+	assert.EqualValues(t, s.RTTVar, time.Second)
+	assert.EqualValues(t, s.RTO, time.Second*6)
+	for i := 0; i < 100; i++ {
+		s.addSample(time.Millisecond * 100)
+	}
+	//stable round trip, rto goes down to the lower bound
+	assert.EqualValues(t, s.RTO, minRetransmitTimeout)
+	s.addSample(time.Hour)
+	assert.EqualValues(t, s.RTO, maxRetransmitTimeout)
+}
+
+func TestRaidenProtocolPeerStats(t *testing.T) {
+	p := &RaidenProtocol{
+		retryInterval:       time.Second * 6,
+		SentHashesToChannel: make(map[common.Hash]*SentMessageState),
+		peerStats:           make(map[common.Address]*PeerStats),
+	}
+	receiver := utils.NewRandomAddress()
+	assert.EqualValues(t, p.retransmitTimeout(receiver), time.Second*6)
+	ms1 := &SentMessageState{ReceiverAddress: receiver, EchoHash: utils.NewRandomHash(), Message: encoding.NewPing(1)}
+	ms2 := &SentMessageState{ReceiverAddress: receiver, EchoHash: utils.NewRandomHash(), Message: encoding.NewPing(2)}
+	p.SentHashesToChannel[ms1.EchoHash] = ms1
+	p.SentHashesToChannel[ms2.EchoHash] = ms2
+	p.onTransmitted(receiver, ms1.EchoHash)
+	p.onTransmitted(receiver, ms2.EchoHash)
+	p.onTransmitted(receiver, ms2.EchoHash)
+	p.onAcked(ms1)
+	p.onAcked(ms2)
+	stats := p.GetPeerStats()
+	assert.EqualValues(t, len(stats), 1)
+	s := stats[0]
+	assert.EqualValues(t, s.Sent, 2)
+	assert.EqualValues(t, s.Transmissions, 3)
+	assert.EqualValues(t, s.Retransmits, 1)
+	assert.EqualValues(t, s.Acked, 2)
+	//retransmitted message is not sampled
+	assert.EqualValues(t, s.Samples, 1)
+	assert.EqualValues(t, s.RTO, minRetransmitTimeout)
+}
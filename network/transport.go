@@ -12,6 +12,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -53,6 +54,27 @@ type Transporter interface {
 	NodeStatus(addr common.Address) (deviceType string, isOnline bool)
 }
 
+/*
+EndpointRegister is a Transporter which can reach nodes directly by their public udp endpoint,
+endpoints are learned from the signed announcements of other nodes.
+*/
+type EndpointRegister interface {
+	//SetNodeEndpoint `addr` can be reached at `hostport` until `expiration`
+	SetNodeEndpoint(addr common.Address, hostport, deviceType string, expiration time.Time) error
+}
+
+//ConnectionStatuser is a Transporter which relies on a server connection, such as xmpp
+type ConnectionStatuser interface {
+	//ConnectionStatus status of connection to server
+	ConnectionStatus() netshare.Status
+}
+
+/*
+EndpointReachableTimeout how long an endpoint is treated as reachable after we received data from it.
+before that, messages are sent by udp and fallback transport both.
+*/
+var EndpointReachableTimeout = 2 * time.Minute
+
 type dummyPolicy struct {
 }
 
@@ -116,6 +138,22 @@ type ProtocolReceiver interface {
 	receive(data []byte)
 }
 
+//nodeEndpoint is public udp address of a node learned from the endpoint registry
+type nodeEndpoint struct {
+	ua         *net.UDPAddr
+	deviceType string
+	expiration time.Time
+	lastSeen   time.Time //last time we received data from this endpoint
+}
+
+func (e *nodeEndpoint) isValid() bool {
+	return time.Now().Before(e.expiration)
+}
+
+func (e *nodeEndpoint) isReachable() bool {
+	return e.isValid() && time.Since(e.lastSeen) < EndpointReachableTimeout
+}
+
 //
 /*
 UDPTransport represents a UDP server
@@ -131,6 +169,8 @@ type UDPTransport struct {
 	stopped       bool
 	stopReceiving bool //todo use atomic to replace
 	intranetNodes map[common.Address]*net.UDPAddr
+	endpoints     map[common.Address]*nodeEndpoint
+	endpointAddrs map[string]common.Address //udp address -> node address
 	lock          sync.RWMutex
 	name          string
 	log           log.Logger
@@ -147,6 +187,8 @@ func NewUDPTransport(name, host string, port int, protocol ProtocolReceiver, pol
 		policy:        policy,
 		log:           log.New("name", name),
 		intranetNodes: make(map[common.Address]*net.UDPAddr),
+		endpoints:     make(map[common.Address]*nodeEndpoint),
+		endpointAddrs: make(map[string]common.Address),
 	}
 	return
 }
@@ -183,6 +225,7 @@ func (ut *UDPTransport) Start() {
 				}
 				ut.log.Trace(fmt.Sprintf("receive from %s ,message=%s,hash=%s", remoteAddr,
 					encoding.MessageType(data[0]), utils.HPex(utils.Sha3(data[:read]))))
+				ut.markEndpointSeen(remoteAddr)
 				err = ut.Receive(data[:read])
 			}
 		}
@@ -236,9 +279,56 @@ func (ut *UDPTransport) getHostPort(addr common.Address) (ua *net.UDPAddr, err e
 	if ok {
 		return
 	}
+	e, ok := ut.endpoints[addr]
+	if ok && e.isValid() {
+		return e.ua, nil
+	}
 	err = fmt.Errorf("%s host port not found", utils.APex(addr))
 	return
 }
+
+//SetNodeEndpoint `addr` can be reached at `hostport` until `expiration`
+func (ut *UDPTransport) SetNodeEndpoint(addr common.Address, hostport, deviceType string, expiration time.Time) error {
+	ua, err := net.ResolveUDPAddr("udp", hostport)
+	if err != nil {
+		return err
+	}
+	ut.lock.Lock()
+	defer ut.lock.Unlock()
+	e := &nodeEndpoint{
+		ua:         ua,
+		deviceType: deviceType,
+		expiration: expiration,
+	}
+	old, ok := ut.endpoints[addr]
+	if ok {
+		delete(ut.endpointAddrs, old.ua.String())
+		if old.ua.String() == ua.String() {
+			e.lastSeen = old.lastSeen
+		}
+	}
+	ut.endpoints[addr] = e
+	ut.endpointAddrs[ua.String()] = addr
+	return nil
+}
+
+//HasNodeEndpoint returns true when `addr` has a valid public endpoint
+func (ut *UDPTransport) HasNodeEndpoint(addr common.Address) bool {
+	ut.lock.RLock()
+	defer ut.lock.RUnlock()
+	e, ok := ut.endpoints[addr]
+	return ok && e.isValid()
+}
+
+func (ut *UDPTransport) markEndpointSeen(remoteAddr *net.UDPAddr) {
+	ut.lock.Lock()
+	defer ut.lock.Unlock()
+	addr, ok := ut.endpointAddrs[remoteAddr.String()]
+	if !ok {
+		return
+	}
+	ut.endpoints[addr].lastSeen = time.Now()
+}
 func (ut *UDPTransport) setHostPort(nodes map[common.Address]*net.UDPAddr) {
 	ut.lock.Lock()
 	defer ut.lock.Unlock()
@@ -268,12 +358,18 @@ func (ut *UDPTransport) StopAccepting() {
 	ut.stopReceiving = true
 }
 
-//NodeStatus always mark the node offline
+/*
+NodeStatus nodes in the intranet are always online,
+nodes with public endpoint are online only when we heard from them recently.
+*/
 func (ut *UDPTransport) NodeStatus(addr common.Address) (deviceType string, isOnline bool) {
 	ut.lock.RLock()
 	defer ut.lock.RUnlock()
 	if _, ok := ut.intranetNodes[addr]; ok {
 		return DeviceTypeMobile, true
 	}
+	if e, ok := ut.endpoints[addr]; ok && e.isReachable() {
+		return e.deviceType, true
+	}
 	return DeviceTypeOther, false
 }
@@ -72,3 +72,76 @@ func TestUDPTransport(t *testing.T) {
 		}
 	}
 }
+
+func TestUDPTransportNodeEndpoint(t *testing.T) {
+	udp1 := MakeTestUDPTransport("u1", 40002)
+	udp2 := MakeTestUDPTransport("u2", 40003)
+	addr1 := utils.NewRandomAddress()
+	addr2 := utils.NewRandomAddress()
+	d1 := newDummyProtocol("u1")
+	d2 := newDummyProtocol("u2")
+	udp1.RegisterProtocol(d1)
+	udp2.RegisterProtocol(d2)
+	udp1.Start()
+	udp2.Start()
+	defer udp1.Stop()
+	defer udp2.Stop()
+	if udp1.HasNodeEndpoint(addr2) {
+		t.Error("should not have endpoint")
+		return
+	}
+	err := udp1.SetNodeEndpoint(addr2, udp2.UAddr.String(), DeviceTypeMobile, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = udp2.SetNodeEndpoint(addr1, udp1.UAddr.String(), DeviceTypeOther, time.Now().Add(time.Minute))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !udp1.HasNodeEndpoint(addr2) {
+		t.Error("should have endpoint")
+		return
+	}
+	_, isOnline := udp1.NodeStatus(addr2)
+	if isOnline {
+		t.Error("never heard from addr2, should not online")
+		return
+	}
+	data := []byte("abc")
+	err = udp1.Send(addr2, data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	select {
+	case <-time.After(time.Millisecond * 100):
+		t.Error("timeout")
+		return
+	case <-d2.data:
+	}
+	err = udp2.Send(addr1, data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	select {
+	case <-time.After(time.Millisecond * 100):
+		t.Error("timeout")
+		return
+	case <-d1.data:
+	}
+	deviceType, isOnline := udp1.NodeStatus(addr2)
+	if !isOnline || deviceType != DeviceTypeMobile {
+		t.Error("addr2 should be online")
+	}
+	err = udp1.SetNodeEndpoint(addr2, udp2.UAddr.String(), DeviceTypeMobile, time.Now().Add(-time.Second))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if udp1.HasNodeEndpoint(addr2) {
+		t.Error("endpoint expired")
+	}
+}
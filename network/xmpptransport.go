@@ -1,7 +1,6 @@
 package network
 
 import (
-	"crypto/ecdsa"
 	"fmt"
 	"time"
 
@@ -12,9 +11,9 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport/xmpppass"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/go-errors/errors"
 )
 
@@ -29,7 +28,7 @@ type XMPPTransport struct {
 	log           log.Logger
 	protocol      ProtocolReceiver
 	NodeAddress   common.Address
-	key           *ecdsa.PrivateKey
+	signer        signer.Signer
 	statusChan    chan netshare.Status
 }
 
@@ -37,14 +36,14 @@ type XMPPTransport struct {
 NewXMPPTransport create xmpp transporter,
 if not success ,for example cannot connect to xmpp server, will try background
 */
-func NewXMPPTransport(name, ServerURL string, key *ecdsa.PrivateKey, deviceType string) (x *XMPPTransport) {
+func NewXMPPTransport(name, ServerURL string, s signer.Signer, deviceType string) (x *XMPPTransport) {
 	x = &XMPPTransport{
 		quitChan:    make(chan struct{}),
-		NodeAddress: crypto.PubkeyToAddress(key.PublicKey),
-		key:         key,
+		NodeAddress: s.Address(),
+		signer:      s,
 		statusChan:  make(chan netshare.Status, 10),
 	}
-	addr := crypto.PubkeyToAddress(key.PublicKey)
+	addr := s.Address()
 	x.log = log.New("name", name)
 	wg := sync.WaitGroup{}
 	wg.Add(1)
@@ -81,7 +80,7 @@ func NewXMPPTransport(name, ServerURL string, key *ecdsa.PrivateKey, deviceType
 
 //GetPassWord returns current login password
 func (x *XMPPTransport) GetPassWord() string {
-	pass, err := xmpppass.CreatePassword(x.key)
+	pass, err := xmpppass.CreatePassword(x.signer)
 	if err != nil {
 		log.Error(fmt.Sprintf("GetPassWord for %s err %s", utils.APex2(x.NodeAddress), err))
 	}
@@ -136,6 +135,14 @@ func (x *XMPPTransport) RegisterProtocol(protcol ProtocolReceiver) {
 	x.protocol = protcol
 }
 
+//ConnectionStatus status of connection to xmpp server
+func (x *XMPPTransport) ConnectionStatus() netshare.Status {
+	if x.conn == nil {
+		return netshare.Disconnected
+	}
+	return x.conn.Status()
+}
+
 //NodeStatus get node's status and is online right now
 func (x *XMPPTransport) NodeStatus(addr common.Address) (deviceType string, isOnline bool) {
 	if x.conn == nil {
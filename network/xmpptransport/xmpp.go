@@ -354,6 +354,11 @@ func (x *XMPPConnection) Connected() bool {
 	return x.status == netshare.Connected
 }
 
+//Status of this connection
+func (x *XMPPConnection) Status() netshare.Status {
+	return x.status
+}
+
 //SendData to peer
 func (x *XMPPConnection) SendData(addr common.Address, data []byte) error {
 	chat := &xmpp.Chat{
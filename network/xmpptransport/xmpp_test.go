@@ -15,6 +15,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/xmpptransport/xmpppass"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -29,7 +30,7 @@ type testPasswordGeter struct {
 }
 
 func (t *testPasswordGeter) GetPassWord() string {
-	pass, _ := xmpppass.CreatePassword(t.key)
+	pass, _ := xmpppass.CreatePassword(signer.NewKeySigner(t.key))
 	return pass
 }
 
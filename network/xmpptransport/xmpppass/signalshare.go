@@ -1,14 +1,13 @@
 package xmpppass
 
 import (
-	"crypto/ecdsa"
-
 	"time"
 
 	"encoding/hex"
 
 	"errors"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/crypto"
 )
@@ -17,12 +16,12 @@ import (
 const passwordFormat = "2006-01-02"
 
 //CreatePassword is helper function for login to xmpp server
-func CreatePassword(privKey *ecdsa.PrivateKey) (sig string, err error) {
+func CreatePassword(s signer.Signer) (sig string, err error) {
 	t := time.Now().UTC()
 	data := []byte(t.Format(passwordFormat))
-	hash := crypto.Keccak256Hash(data)
-	signature, err := crypto.Sign(hash[:], privKey)
+	signature, err := s.SignData(data)
 	if err == nil {
+		signature[len(signature)-1] -= 27 //xmpp server expects v of 0 or 1
 		sig = hex.EncodeToString(signature)
 	}
 	return
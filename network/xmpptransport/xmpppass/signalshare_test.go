@@ -5,12 +5,13 @@ import (
 
 	"fmt"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/ethereum/go-ethereum/crypto"
 )
 
 func TestCreatePasswordAndVerify(t *testing.T) {
 	key, _ := crypto.GenerateKey()
-	sig, err := CreatePassword(key)
+	sig, err := CreatePassword(signer.NewKeySigner(key))
 	if err != nil {
 		t.Error(err)
 		return
@@ -5,14 +5,15 @@ import (
 	"testing"
 	"time"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 )
 
 func TestXMPPTransport(t *testing.T) {
 	key1, _ := utils.MakePrivateKeyAddress()
 	key2, _ := utils.MakePrivateKeyAddress()
-	x1 := MakeTestXMPPTransport("x1", key1)
-	x2 := MakeTestXMPPTransport("x2", key2)
+	x1 := MakeTestXMPPTransport("x1", signer.NewKeySigner(key1))
+	x2 := MakeTestXMPPTransport("x2", signer.NewKeySigner(key2))
 	d1 := newDummyProtocol("x1")
 	d2 := newDummyProtocol("x2")
 	x1.RegisterProtocol(d1)
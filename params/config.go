@@ -1,7 +1,7 @@
 package params
 
 import (
-	"crypto/ecdsa"
+	"math/big"
 	"os"
 	"os/user"
 	"path/filepath"
@@ -13,11 +13,19 @@ import (
 	"github.com/ethereum/go-ethereum/node"
 )
 
-type protocolConfig struct {
-	RetryInterval        int
-	RetriesBeforeBackoff int
-	ThrottleCapacity     float64
-	ThrottleFillRate     float64
+//ProtocolConfig is configuration of message sending between nodes
+type ProtocolConfig struct {
+	RetryInterval        time.Duration //initial retransmit timeout, before any round trip is measured
+	RetriesBeforeBackoff int           //retries at the retransmit timeout before backing off
+	ThrottleCapacity     float64       //token bucket capacity of every peer
+	ThrottleFillRate     float64       //tokens per second of every peer
+}
+
+//CapacityHintConfig controls capacity hints of channels exchanged with partners
+type CapacityHintConfig struct {
+	Enable    bool                    //send hints of my channels to partners and use hints received in path finding
+	Precision int                     //powers of two one bucket covers, larger is coarser and tells less
+	Hidden    map[common.Address]bool //channels with these partners are never advertised
 }
 
 //NetworkMode is transport status
@@ -38,18 +46,17 @@ const (
 type Config struct {
 	Host                      string
 	Port                      int
-	PrivateKeyHex             string
-	PrivateKey                *ecdsa.PrivateKey
 	RevealTimeout             int
 	SettleTimeout             int
 	DataBasePath              string
 	MsgTimeout                time.Duration
-	Protocol                  protocolConfig
+	Protocol                  ProtocolConfig
 	UseRPC                    bool
 	UseConsole                bool
 	APIHost                   string
 	APIPort                   int
 	RegistryAddress           common.Address
+	RegistryAddresses         []common.Address //all registries attached, RegistryAddress is the default one of them
 	DataDir                   string
 	MyAddress                 common.Address
 	DebugCrash                bool          //for test only,work with conditionQuit
@@ -59,16 +66,27 @@ type Config struct {
 	IgnoreMediatedNodeRequest bool // true: this node will ignore any mediated transfer who's target is not me.
 	EnableHealthCheck         bool //send ping periodically?
 	XMPPServer                string
-	IsMeshNetwork             bool //is mesh now?
+	IsMeshNetwork             bool                        //is mesh now?
+	PublicAddress             string                      //"host:port" announced to channel partners,empty means don't announce
+	TokenPerEther             map[common.Address]*big.Int //token => how many smallest unit of token one ether is worth, used to skip locks not worth unlocking
+	APIAuth                   bool                        //restful api requires bearer token
+	APITLSCert                string                      //restful api serves https when not empty
+	APITLSKey                 string
+	EncryptDb                 bool   //encrypt values of db by a key derived from DbPassword
+	DbPassword                string //account password, needed to open an encrypted db, cleared after db is opened
+	ArchiveAckAge             int64  //acks saved more than this many blocks ago are archived, 0 means never
+	CapacityHints             CapacityHintConfig
+	PathfinderURL             string   //pathfinding service queried for routes of mediated transfers, empty means find routes by myself
+	PathfinderMaxQueryFee     *big.Int //the most paid for one query of pathfinding service, nil means never pay
+	PathfinderPostHints       bool     //tell pathfinding service capacity of my channels, a third party learns them then
 }
 
 //DefaultConfig default config
 var DefaultConfig = Config{
 	Port:          InitialPort,
-	PrivateKeyHex: "",
 	RevealTimeout: DefaultRevealTimeout,
 	SettleTimeout: DefaultSettleTimeout,
-	Protocol: protocolConfig{
+	Protocol: ProtocolConfig{
 		RetryInterval:        defaultprotocolRetryInterval,
 		RetriesBeforeBackoff: defaultProtocolRetiesBeforeBackoff,
 		ThrottleCapacity:     defaultProtocolRhrottleCapacity,
@@ -80,6 +98,9 @@ var DefaultConfig = Config{
 	MsgTimeout:        100 * time.Second,
 	EnableHealthCheck: false,
 	XMPPServer:        DefaultXMPPServer,
+	CapacityHints: CapacityHintConfig{
+		Precision: DefaultCapacityHintPrecision,
+	},
 }
 
 //ConditionQuit is for test
@@ -130,3 +151,41 @@ func homeDir() string {
 func DefaultKeyStoreDir() string {
 	return filepath.Join(node.DefaultDataDir(), "keystore")
 }
+
+//strategies of gas price
+const (
+	//GasPriceFixed always uses FixedPrice
+	GasPriceFixed = "fixed"
+	//GasPriceSuggest uses price suggested by ethereum node times Multiplier
+	GasPriceSuggest = "suggest"
+	//GasPriceDeadline uses suggested price, and raises it as the deadline of a dispute transaction approaches
+	GasPriceDeadline = "deadline"
+)
+
+//GasPriceConfig is how gas price of transactions is decided, it's configured by restful api and saved in db
+type GasPriceConfig struct {
+	Strategy   string   `json:"strategy"`
+	FixedPrice *big.Int `json:"fixed_price"`
+	Multiplier float64  `json:"multiplier"` //of suggested price
+	//EscalationBlocks price of a transaction with a deadline starts to rise this many blocks before the deadline
+	EscalationBlocks int64 `json:"escalation_blocks"`
+	//MaxEscalation price of a transaction with a deadline is multiplied by this at the deadline
+	MaxEscalation float64 `json:"max_escalation"`
+	//UrgencyMultipliers multiplies price of transactions of some urgencies, such as close or deposit
+	UrgencyMultipliers map[string]float64 `json:"urgency_multipliers,omitempty"`
+	//MaxPrice no transaction pays a higher price, nil means no limit
+	MaxPrice *big.Int `json:"max_price,omitempty"`
+	//SpendingCaps no more deposit, close or other transaction of a token is sent after this much wei is spent on its gas
+	SpendingCaps map[common.Address]*big.Int `json:"spending_caps,omitempty"`
+}
+
+//DefaultGasPriceConfig pays GasPrice for every transaction
+func DefaultGasPriceConfig() *GasPriceConfig {
+	return &GasPriceConfig{
+		Strategy:         GasPriceFixed,
+		FixedPrice:       big.NewInt(GasPrice),
+		Multiplier:       1,
+		EscalationBlocks: DefaultRevealTimeout * 4,
+		MaxEscalation:    3,
+	}
+}
@@ -18,11 +18,17 @@ const GasLimit = 3141592 //den's gasLimit.
 //GasPrice from ethereum
 const GasPrice = params.Shannon * 20
 
+//UnlockGas estimated gas used by one unlock transaction
+const UnlockGas = 150000
+
 //defaultProtocolRetiesBeforeBackoff
-const defaultProtocolRetiesBeforeBackoff = 5
+const defaultProtocolRetiesBeforeBackoff = 10
 const defaultProtocolRhrottleCapacity = 10.
 const defaultProtocolThrottleFillRate = 10.
-const defaultprotocolRetryInterval = 1.
+const defaultprotocolRetryInterval = 6 * time.Second
+
+//PeerSenderIdleTimeout the goroutine sending messages to a peer quits after nothing is sent to it for so long
+const PeerSenderIdleTimeout = 5 * time.Minute
 
 //DefaultRevealTimeout blocks needs to update transfer
 const DefaultRevealTimeout = 5
@@ -30,6 +36,27 @@ const DefaultRevealTimeout = 5
 //DefaultSettleTimeout settle time of channel
 const DefaultSettleTimeout = 600
 
+//DbArchiveInterval blocks between two archives of completed records of db
+const DbArchiveInterval = 1000
+
+//DefaultEthPollInterval time between two polls of new blocks and logs, when eth rpc endpoint doesn't support notifications
+const DefaultEthPollInterval = 5 * time.Second
+
+//DefaultEthPollChunkSize max blocks of logs got by one poll
+const DefaultEthPollChunkSize = 1000
+
+//EthHealthCheckInterval time between two health checks of every eth rpc endpoint
+var EthHealthCheckInterval = 10 * time.Second
+
+//EthHealthCheckTimeout an eth rpc endpoint fails a health check if it doesn't answer in this time
+var EthHealthCheckTimeout = 5 * time.Second
+
+//EthEndpointMaxLag an eth rpc endpoint more than this many blocks behind the highest one is unhealthy
+const EthEndpointMaxLag = 3
+
+//EthEndpointMaxErrors an eth rpc endpoint is unhealthy after this many successive failures
+const EthEndpointMaxErrors = 3
+
 //DefaultPollTimeout  request wait time
 const DefaultPollTimeout = 180 * time.Second
 
@@ -39,6 +66,9 @@ const DefaultJoinableFundsTarget = 0.4
 //DefaultInitialChannelTarget channels to create
 const DefaultInitialChannelTarget = 3
 
+//ExpectedBlockPeriod average time for one block, used to estimate time left before a lock expires
+const ExpectedBlockPeriod = 15 * time.Second
+
 //DefaultTxTimeout args
 const DefaultTxTimeout = 5 * time.Minute //15seconds for one block,it may take sever minutes
 //MaxRequestTimeout args
@@ -60,9 +90,45 @@ ChannelSettleTimeoutMax The maximum settle timeout is chosen as something above
 */
 const ChannelSettleTimeoutMax = 2700000
 
+//SettleRetryMaxBlocks longest wait before retrying a failed settle transaction
+const SettleRetryMaxBlocks = 64
+
+//RoutePaths how many shortest loopless paths to the target are found for a first hop of a mediated transfer after it's penalized
+const RoutePaths = 3
+
 //UDPMaxMessageSize message size
 const UDPMaxMessageSize = 1200
 
+/*
+EndpointExpiration how long an announced public endpoint is valid,
+it will be announced again when half of it passed.
+*/
+var EndpointExpiration = time.Hour
+
+//MaxEndpointExpiration endpoint announcements valid longer than this will be refused
+const MaxEndpointExpiration = 24 * time.Hour
+
+//CapacityHintExpiration how long capacity hints sent to partners are valid, they are sent again when half of it passed
+var CapacityHintExpiration = 10 * time.Minute
+
+//MaxCapacityHintExpiration capacity hints valid longer than this will be refused
+const MaxCapacityHintExpiration = time.Hour
+
+//DefaultCapacityHintPrecision how many powers of two one capacity bucket covers
+const DefaultCapacityHintPrecision = 4
+
+//MaxCapacityBucketsPerHint channels in one CapacityHint message, so that it fits in one udp packet
+const MaxCapacityBucketsPerHint = 40
+
+//MaxBlockAge node is not ready when no new block is received for this long
+var MaxBlockAge = 8 * ExpectedBlockPeriod
+
+//HealthCheckTimeout the event loop of node is treated as unresponsive if it doesn't answer in this time
+var HealthCheckTimeout = 5 * time.Second
+
+//DbWriteCheckInterval health check writes to db at most once in this time, checks between only read it
+var DbWriteCheckInterval = time.Minute
+
 //DefaultXMPPServer xmpp server
 const DefaultXMPPServer = "193.112.248.133:5222"
 
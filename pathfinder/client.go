@@ -0,0 +1,214 @@
+package pathfinder
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//clientTimeout is how long to wait for pathfinding service
+const clientTimeout = 10 * time.Second
+
+/*
+HTTPClient queries a pathfinding service by http,
+it pays for queries by IOUs signed by the node when the service charges no more than maxQueryFee.
+*/
+type HTTPClient struct {
+	url         string
+	s           signer.Signer
+	maxQueryFee *big.Int
+	store       IOUStore
+	lock        sync.Mutex //one paid query at a time, or the amount signed would race
+	client      *http.Client
+	//IOUs sent by queries failed, the service may have accepted them before failing, receiver and token => amount
+	pending map[[2]common.Address]*big.Int
+}
+
+/*
+NewHTTPClient create a client of service at `url`, nil or zero maxQueryFee means never pay.
+amount of IOUs signed is saved in `store`, it's only kept in memory if `store` is nil.
+*/
+func NewHTTPClient(url string, s signer.Signer, maxQueryFee *big.Int, store IOUStore) *HTTPClient {
+	if store == nil {
+		store = newMemIOUStore()
+	}
+	return &HTTPClient{
+		url:         strings.TrimRight(url, "/"),
+		s:           s,
+		maxQueryFee: maxQueryFee,
+		store:       store,
+		client:      &http.Client{Timeout: clientTimeout},
+		pending:     make(map[[2]common.Address]*big.Int),
+	}
+}
+
+func (c *HTTPClient) do(method, path, contentType string, body []byte, result interface{}) error {
+	req, err := http.NewRequest(method, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if len(contentType) > 0 {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		if resp.StatusCode == http.StatusPaymentRequired {
+			return errPaymentRequired
+		}
+		return fmt.Errorf("pathfinder %s %s status=%d,err=%s", method, path, resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	if result == nil {
+		return nil
+	}
+	return json.Unmarshal(data, result)
+}
+
+/*
+pay signs an IOU for one more query if the service charges.
+the amount is what we have signed to the service plus the fee, it's saved only after the service accepts the IOU.
+the service must not claim more than we have signed, unless it's the IOU of a failed query.
+if it claims less, e.g. it lost IOUs after a restart, we go on from what we have signed,
+IOUs are cumulative, the service can't collect more than the latest one.
+*/
+func (c *HTTPClient) pay(req *Request) error {
+	if c.maxQueryFee == nil || c.maxQueryFee.Sign() <= 0 {
+		return nil
+	}
+	var info serviceInfo
+	err := c.do(http.MethodGet, fmt.Sprintf("/api/1/info?payer=%s&token=%s", c.s.Address().String(), req.Token.String()), "", nil, &info)
+	if err != nil {
+		return err
+	}
+	if info.Fee == nil || info.Fee.Sign() <= 0 {
+		return nil
+	}
+	if info.Fee.Cmp(c.maxQueryFee) > 0 {
+		return errQueryFeeTooHigh
+	}
+	key := [2]common.Address{info.Address, req.Token}
+	signed := c.store.GetIOUAmount(info.Address, req.Token)
+	if pending := c.pending[key]; pending != nil && info.LastAmount != nil && info.LastAmount.Cmp(pending) == 0 {
+		err = c.store.SaveIOUAmount(info.Address, req.Token, pending)
+		if err != nil {
+			return err
+		}
+		signed = pending
+	}
+	delete(c.pending, key)
+	if info.LastAmount == nil || info.LastAmount.Cmp(signed) > 0 {
+		log.Warn(fmt.Sprintf("pathfinder %s says it has received %s of %s, but we have signed %s",
+			utils.APex(info.Address), info.LastAmount, utils.APex(req.Token), signed))
+		return errIOUMismatch
+	}
+	if info.LastAmount.Cmp(signed) < 0 {
+		log.Warn(fmt.Sprintf("pathfinder %s says it has received %s of %s, less than %s we have signed, pay from what we have signed",
+			utils.APex(info.Address), info.LastAmount, utils.APex(req.Token), signed))
+	}
+	amount := new(big.Int).Add(signed, info.Fee)
+	iou, err := NewIOU(c.s, req.Token, info.Address, amount)
+	if err != nil {
+		return err
+	}
+	req.IOU = iou
+	return nil
+}
+
+//paid saves amount of `iou` if the query is done, or keeps it pending till the service tells whether it has it
+func (c *HTTPClient) paid(iou *IOU, queryErr error) error {
+	if iou == nil {
+		return nil
+	}
+	if queryErr != nil {
+		c.pending[[2]common.Address{iou.Receiver, iou.Token}] = iou.Amount
+		return nil
+	}
+	return c.store.SaveIOUAmount(iou.Receiver, iou.Token, iou.Amount)
+}
+
+//FindPaths queries paths, paying for it when needed
+func (c *HTTPClient) FindPaths(req *Request) (paths []*route.Path, err error) {
+	r := *req
+	r.IOU = nil
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	err = c.pay(&r)
+	if err != nil {
+		return
+	}
+	body, err := json.Marshal(&r)
+	if err != nil {
+		return
+	}
+	var resp pathsResponse
+	err = c.do(http.MethodPost, "/api/1/paths", "application/json", body, &resp)
+	err2 := c.paid(r.IOU, err)
+	if err != nil {
+		return
+	}
+	if err2 != nil {
+		return nil, err2
+	}
+	for _, p := range resp.Paths {
+		if len(p.Nodes) < 2 || p.Nodes[0] != req.From || p.Nodes[len(p.Nodes)-1] != req.To {
+			return nil, fmt.Errorf("pathfinder returned an invalid path %v", p.Nodes)
+		}
+		path := &route.Path{Nodes: p.Nodes}
+		if p.Fee != nil {
+			path.Weight = p.Fee.Int64()
+		}
+		paths = append(paths, path)
+	}
+	return
+}
+
+//SendCapacityHint posts the packed hint, the service verifies the signature
+func (c *HTTPClient) SendCapacityHint(msg *encoding.CapacityHint) error {
+	return c.do(http.MethodPost, "/api/1/capacity", "application/octet-stream", msg.Pack(), nil)
+}
+
+//memIOUStore keeps amount of IOUs in memory only
+type memIOUStore struct {
+	lock    sync.Mutex
+	amounts map[[2]common.Address]*big.Int
+}
+
+func newMemIOUStore() *memIOUStore {
+	return &memIOUStore{amounts: make(map[[2]common.Address]*big.Int)}
+}
+
+func (s *memIOUStore) GetIOUAmount(receiver, token common.Address) *big.Int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if a := s.amounts[[2]common.Address{receiver, token}]; a != nil {
+		return new(big.Int).Set(a)
+	}
+	return new(big.Int)
+}
+
+func (s *memIOUStore) SaveIOUAmount(receiver, token common.Address, amount *big.Int) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.amounts[[2]common.Address{receiver, token}] = new(big.Int).Set(amount)
+	return nil
+}
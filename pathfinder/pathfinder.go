@@ -0,0 +1,119 @@
+/*
+Package pathfinder finds routes for nodes which don't keep a full channel graph, mobile nodes for example.
+a pathfinding service builds the graph of a token from registry events and capacity hints of nodes,
+the initiator asks it for paths to the target, and pays for queries by a signed IOU if the service charges.
+*/
+package pathfinder
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+//MaxPathLimit is the most paths returned for one query
+const MaxPathLimit = 10
+
+var (
+	errNoPath          = errors.New("no path found")
+	errUnknownToken    = errors.New("unknown token")
+	errPaymentRequired = errors.New("payment required")
+	errQueryFeeTooHigh = errors.New("query fee higher than max query fee")
+	errIOUMismatch     = errors.New("amount paid the service claims is not what we signed")
+)
+
+/*
+Request asks for paths of a transfer from `From` to `To`
+*/
+type Request struct {
+	Token  common.Address `json:"token"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Amount *big.Int       `json:"amount"`
+	MaxFee *big.Int       `json:"max_fee,omitempty"` //paths charging more are not returned, nil means any
+	Limit  int            `json:"limit,omitempty"`   //most paths wanted, 0 means the service's default
+	IOU    *IOU           `json:"iou,omitempty"`     //payment of this query
+}
+
+//pathResult is one path in response
+type pathResult struct {
+	Nodes []common.Address `json:"nodes"` //from `From` to `To`
+	Fee   *big.Int         `json:"fee"`   //fee all mediators charge
+}
+
+type pathsResponse struct {
+	Paths []*pathResult `json:"paths"`
+}
+
+//serviceInfo tells what the service charges and how much the payer has paid of a token
+type serviceInfo struct {
+	Address    common.Address `json:"address"`     //receiver of IOUs
+	Fee        *big.Int       `json:"fee"`         //price of one query
+	LastAmount *big.Int       `json:"last_amount"` //amount of the latest IOU received from the payer
+}
+
+/*
+IOUStore keeps amount of the latest IOU signed to every receiver of every token,
+so the payer never trusts how much the service says it has been paid.
+*/
+type IOUStore interface {
+	GetIOUAmount(receiver, token common.Address) *big.Int
+	SaveIOUAmount(receiver, token common.Address, amount *big.Int) error
+}
+
+/*
+IOU promises to pay `Amount` of `Token` to `Receiver`, the amount is cumulative,
+every query signs a new IOU which is larger than the last one by the query fee,
+so the receiver only needs to keep the latest one of every payer.
+*/
+type IOU struct {
+	Token     common.Address `json:"token"`
+	Receiver  common.Address `json:"receiver"`
+	Amount    *big.Int       `json:"amount"`
+	Signature hexutil.Bytes  `json:"signature"`
+}
+
+func (iou *IOU) signData() []byte {
+	buf := new(bytes.Buffer)
+	buf.Write(iou.Token[:])
+	buf.Write(iou.Receiver[:])
+	buf.Write(utils.BigIntTo32Bytes(iou.Amount))
+	return buf.Bytes()
+}
+
+//NewIOU creates an IOU signed by `s`
+func NewIOU(s signer.Signer, token, receiver common.Address, amount *big.Int) (iou *IOU, err error) {
+	iou = &IOU{
+		Token:    token,
+		Receiver: receiver,
+		Amount:   new(big.Int).Set(amount),
+	}
+	iou.Signature, err = s.SignData(iou.signData())
+	return
+}
+
+//Payer recovers who signed this IOU
+func (iou *IOU) Payer() (common.Address, error) {
+	if iou.Amount == nil || iou.Amount.Sign() < 0 {
+		return utils.EmptyAddress, fmt.Errorf("invalid amount %s", iou.Amount)
+	}
+	return utils.Ecrecover(utils.Sha3(iou.signData()), iou.Signature)
+}
+
+/*
+Client queries a pathfinding service
+*/
+type Client interface {
+	//FindPaths returns paths from req.From to req.To, every path starts with req.From
+	FindPaths(req *Request) ([]*route.Path, error)
+	//SendCapacityHint tells the service capacity of channels of the signer
+	SendCapacityHint(msg *encoding.CapacityHint) error
+}
@@ -0,0 +1,233 @@
+package pathfinder
+
+import (
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestSigner() signer.Signer {
+	key, _ := crypto.GenerateKey()
+	return signer.NewKeySigner(key)
+}
+
+//from reaches to through a or b, every channel has a deposit of 100
+func newTestServer(queryFee int64) (s *Server, token common.Address, from, a, b signer.Signer, to common.Address) {
+	s = NewServer(utils.NewRandomAddress(), big.NewInt(2), big.NewInt(queryFee))
+	token = utils.NewRandomAddress()
+	from, a, b, to = newTestSigner(), newTestSigner(), newTestSigner(), utils.NewRandomAddress()
+	channels := [][2]common.Address{
+		{from.Address(), a.Address()},
+		{a.Address(), to},
+		{from.Address(), b.Address()},
+		{b.Address(), to},
+	}
+	for _, c := range channels {
+		id := utils.NewRandomHash()
+		s.AddChannel(token, id, c[0], c[1])
+		s.SetDeposit(id, c[0], big.NewInt(100))
+	}
+	return
+}
+
+func TestFindPaths(t *testing.T) {
+	s, token, from, a, b, to := newTestServer(0)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	c := NewHTTPClient(ts.URL, from, nil, nil)
+	req := &Request{
+		Token:  token,
+		From:   from.Address(),
+		To:     to,
+		Amount: big.NewInt(10),
+	}
+	paths, err := c.FindPaths(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, len(paths))
+	for _, p := range paths {
+		assert.Equal(t, 3, len(p.Nodes))
+		assert.Equal(t, from.Address(), p.Nodes[0])
+		assert.Equal(t, to, p.Nodes[2])
+		assert.EqualValues(t, 2, p.Weight)
+	}
+	req.MaxFee = big.NewInt(1)
+	_, err = c.FindPaths(req)
+	assert.NotNil(t, err)
+	req.MaxFee = nil
+
+	//more than all deposits
+	req.Amount = big.NewInt(101)
+	_, err = c.FindPaths(req)
+	assert.NotNil(t, err)
+
+	//a tells it can't send 10 to target
+	req.Amount = big.NewInt(10)
+	msg := encoding.NewCapacityHint(token, time.Now().Add(time.Minute).Unix(), 1, []encoding.CapacityBucket{
+		{Partner: to, Bucket: graph.CapacityToBucket(big.NewInt(3), 1)},
+	})
+	err = msg.Sign(a, msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = NewHTTPClient(ts.URL, a, nil, nil).SendCapacityHint(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	paths, err = c.FindPaths(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 1, len(paths))
+	assert.Equal(t, b.Address(), paths[0].Nodes[1])
+
+	req.Token = utils.NewRandomAddress()
+	_, err = c.FindPaths(req)
+	assert.NotNil(t, err)
+}
+
+//failPaths fails the next query of paths, after the service handles it if `handled`
+type failPaths struct {
+	*Server
+	lock    sync.Mutex
+	fail    bool
+	handled bool
+}
+
+func (f *failPaths) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	fail := f.fail && r.URL.Path == "/api/1/paths"
+	if fail {
+		f.fail = false
+	}
+	f.lock.Unlock()
+	if !fail {
+		f.Server.ServeHTTP(w, r)
+		return
+	}
+	if f.handled {
+		f.Server.ServeHTTP(httptest.NewRecorder(), r)
+	}
+	http.Error(w, "timeout", http.StatusGatewayTimeout)
+}
+
+func (f *failPaths) failNext(handled bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.fail = true
+	f.handled = handled
+}
+
+func TestPaidQueryFailed(t *testing.T) {
+	s, token, from, _, _, to := newTestServer(5)
+	f := &failPaths{Server: s}
+	ts := httptest.NewServer(f)
+	defer ts.Close()
+	req := &Request{
+		Token:  token,
+		From:   from.Address(),
+		To:     to,
+		Amount: big.NewInt(10),
+	}
+	store := newMemIOUStore()
+	c := NewHTTPClient(ts.URL, from, big.NewInt(5), store)
+	paid := func() int64 {
+		s.lock.Lock()
+		defer s.lock.Unlock()
+		return s.paid[payerToken{from.Address(), token}].Int64()
+	}
+	_, err := c.FindPaths(req)
+	assert.Nil(t, err)
+	//the IOU never reaches the service
+	f.failNext(false)
+	_, err = c.FindPaths(req)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 5, store.GetIOUAmount(s.address, token).Int64())
+	_, err = c.FindPaths(req)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 10, paid())
+	assert.EqualValues(t, 10, store.GetIOUAmount(s.address, token).Int64())
+	//the service accepts the IOU, but the response is lost
+	f.failNext(true)
+	_, err = c.FindPaths(req)
+	assert.NotNil(t, err)
+	assert.EqualValues(t, 15, paid())
+	_, err = c.FindPaths(req)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 20, paid())
+	assert.EqualValues(t, 20, store.GetIOUAmount(s.address, token).Int64())
+	//the service restarts and forgets IOUs
+	s.lock.Lock()
+	s.paid = make(map[payerToken]*big.Int)
+	s.lock.Unlock()
+	_, err = c.FindPaths(req)
+	assert.Nil(t, err)
+	assert.EqualValues(t, 25, paid())
+	assert.EqualValues(t, 25, store.GetIOUAmount(s.address, token).Int64())
+}
+
+func TestPaidQuery(t *testing.T) {
+	s, token, from, _, _, to := newTestServer(5)
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	req := &Request{
+		Token:  token,
+		From:   from.Address(),
+		To:     to,
+		Amount: big.NewInt(10),
+	}
+	_, err := NewHTTPClient(ts.URL, from, nil, nil).FindPaths(req)
+	assert.Equal(t, errPaymentRequired, err)
+	_, err = NewHTTPClient(ts.URL, from, big.NewInt(4), nil).FindPaths(req)
+	assert.Equal(t, errQueryFeeTooHigh, err)
+
+	store := newMemIOUStore()
+	c := NewHTTPClient(ts.URL, from, big.NewInt(5), store)
+	for i := 1; i <= 2; i++ {
+		_, err = c.FindPaths(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.EqualValues(t, 5*i, s.paid[payerToken{from.Address(), token}].Int64())
+	}
+	assert.EqualValues(t, 10, store.GetIOUAmount(s.address, token).Int64())
+	//what we signed is remembered by the store, not the client
+	_, err = NewHTTPClient(ts.URL, from, big.NewInt(5), store).FindPaths(req)
+	assert.Nil(t, err)
+	//never sign what the service claims
+	_, err = NewHTTPClient(ts.URL, from, big.NewInt(5), nil).FindPaths(req)
+	assert.Equal(t, errIOUMismatch, err)
+	s.lock.Lock()
+	s.paid[payerToken{from.Address(), token}] = big.NewInt(1000)
+	s.lock.Unlock()
+	_, err = c.FindPaths(req)
+	assert.Equal(t, errIOUMismatch, err)
+	assert.EqualValues(t, 15, store.GetIOUAmount(s.address, token).Int64())
+
+	//an old IOU can't pay again
+	iou, err := NewIOU(from, token, s.address, big.NewInt(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.IOU = iou
+	assert.Equal(t, errPaymentRequired, s.checkPayment(req))
+	//others can't pay for from
+	iou, err = NewIOU(newTestSigner(), token, s.address, big.NewInt(100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.IOU = iou
+	assert.Equal(t, errPaymentRequired, s.checkPayment(req))
+}
@@ -0,0 +1,290 @@
+package pathfinder
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//maxCapacityHintSize is the most bytes of a CapacityHint posted
+const maxCapacityHintSize = 64 * 1024
+
+//hopFeeCharger charges the same fee on every mediator
+type hopFeeCharger struct {
+	fee *big.Int
+}
+
+func (c *hopFeeCharger) GetNodeChargeFee(nodeAddress, tokenAddress common.Address, amount *big.Int) *big.Int {
+	return c.fee
+}
+
+type channelInfo struct {
+	token        common.Address
+	participants [2]common.Address
+	deposits     [2]*big.Int
+}
+
+/*
+Server is a reference pathfinding service,
+it knows channels and deposits from blockchain and capacity of channels from hints signed by their participants.
+IOUs are only checked and kept in memory, redeeming them is up to the operator.
+*/
+type Server struct {
+	lock     sync.Mutex
+	address  common.Address //receiver of IOUs
+	queryFee *big.Int
+	charger  *hopFeeCharger
+	graphs   map[common.Address]*graph.ChannelGraph //token => graph of all channels of the token
+	channels map[common.Hash]*channelInfo
+	paid     map[payerToken]*big.Int //amount of the latest IOU of every payer and token
+	mux      *http.ServeMux
+}
+
+type payerToken struct {
+	payer common.Address
+	token common.Address
+}
+
+/*
+NewServer create a service which receives IOUs as `address`,
+charges `queryFee` for every query and estimates `hopFee` for every mediator.
+*/
+func NewServer(address common.Address, hopFee, queryFee *big.Int) *Server {
+	s := &Server{
+		address:  address,
+		queryFee: queryFee,
+		charger:  &hopFeeCharger{hopFee},
+		graphs:   make(map[common.Address]*graph.ChannelGraph),
+		channels: make(map[common.Hash]*channelInfo),
+		paid:     make(map[payerToken]*big.Int),
+		mux:      http.NewServeMux(),
+	}
+	s.mux.HandleFunc("/api/1/info", s.handleInfo)
+	s.mux.HandleFunc("/api/1/paths", s.handlePaths)
+	s.mux.HandleFunc("/api/1/capacity", s.handleCapacity)
+	return s
+}
+
+//AddToken creates the graph of token if not exist
+func (s *Server) AddToken(token common.Address) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.addToken(token)
+}
+
+func (s *Server) addToken(token common.Address) *graph.ChannelGraph {
+	g := s.graphs[token]
+	if g == nil {
+		g = graph.NewChannelGraph(utils.EmptyAddress, token, nil)
+		s.graphs[token] = g
+	}
+	return g
+}
+
+//AddChannel adds a channel opened between p1 and p2
+func (s *Server) AddChannel(token common.Address, channelIdentifier common.Hash, p1, p2 common.Address) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.channels[channelIdentifier] != nil {
+		return
+	}
+	s.channels[channelIdentifier] = &channelInfo{
+		token:        token,
+		participants: [2]common.Address{p1, p2},
+		deposits:     [2]*big.Int{big.NewInt(0), big.NewInt(0)},
+	}
+	g := s.addToken(token)
+	g.AddPath(p1, p2)
+	g.SetChannelCapacity(p1, p2, big.NewInt(0))
+}
+
+//SetDeposit updates how much participant has in the channel, nobody can send more than all deposits of a channel
+func (s *Server) SetDeposit(channelIdentifier common.Hash, participant common.Address, deposit *big.Int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.channels[channelIdentifier]
+	if c == nil {
+		return
+	}
+	for i, p := range c.participants {
+		if p == participant {
+			c.deposits[i] = new(big.Int).Set(deposit)
+		}
+	}
+	capacity := new(big.Int).Add(c.deposits[0], c.deposits[1])
+	s.graphs[c.token].SetChannelCapacity(c.participants[0], c.participants[1], capacity)
+}
+
+//RemoveChannel removes a channel closed or settled
+func (s *Server) RemoveChannel(channelIdentifier common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	c := s.channels[channelIdentifier]
+	if c == nil {
+		return
+	}
+	delete(s.channels, channelIdentifier)
+	s.graphs[c.token].RemovePath(c.participants[0], c.participants[1])
+}
+
+//ServeHTTP is http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Error(fmt.Sprintf("write response err %s", err))
+	}
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	payer, token := r.URL.Query().Get("payer"), r.URL.Query().Get("token")
+	if len(payer) > 0 && !common.IsHexAddress(payer) || len(token) > 0 && !common.IsHexAddress(token) {
+		http.Error(w, fmt.Sprintf("payer %s or token %s is not an address", payer, token), http.StatusBadRequest)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	info := &serviceInfo{
+		Address:    s.address,
+		Fee:        s.queryFee,
+		LastAmount: big.NewInt(0),
+	}
+	if last := s.paid[payerToken{common.HexToAddress(payer), common.HexToAddress(token)}]; last != nil {
+		info.LastAmount = last
+	}
+	writeJSON(w, info)
+}
+
+//checkPayment accepts iou if it pays for one more query of `from`
+func (s *Server) checkPayment(req *Request) error {
+	if s.queryFee == nil || s.queryFee.Sign() <= 0 {
+		return nil
+	}
+	if req.IOU == nil {
+		return errPaymentRequired
+	}
+	payer, err := req.IOU.Payer()
+	if err != nil || payer != req.From || req.IOU.Receiver != s.address || req.IOU.Token != req.Token {
+		return errPaymentRequired
+	}
+	key := payerToken{payer, req.Token}
+	expect := new(big.Int).Set(s.queryFee)
+	if last := s.paid[key]; last != nil {
+		expect.Add(expect, last)
+	}
+	if req.IOU.Amount.Cmp(expect) < 0 {
+		return errPaymentRequired
+	}
+	s.paid[key] = req.IOU.Amount
+	return nil
+}
+
+func (s *Server) handlePaths(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	var req Request
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Amount == nil || req.Amount.Sign() <= 0 || req.From == req.To {
+		http.Error(w, "invalid request", http.StatusBadRequest)
+		return
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = params.RoutePaths
+	}
+	if limit > MaxPathLimit {
+		limit = MaxPathLimit
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	err = s.checkPayment(&req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusPaymentRequired)
+		return
+	}
+	g := s.graphs[req.Token]
+	if g == nil {
+		http.Error(w, errUnknownToken.Error(), http.StatusNotFound)
+		return
+	}
+	g.RemoveExpiredCapacityHints(time.Now())
+	paths, err := g.FindPaths(req.From, req.To, req.Amount, limit, s.charger)
+	if err != nil {
+		http.Error(w, errNoPath.Error(), http.StatusNotFound)
+		return
+	}
+	resp := &pathsResponse{}
+	for _, p := range paths {
+		fee := new(big.Int).Mul(s.charger.fee, big.NewInt(int64(len(p.Nodes)-2)))
+		if req.MaxFee != nil && fee.Cmp(req.MaxFee) > 0 {
+			continue
+		}
+		resp.Paths = append(resp.Paths, &pathResult{Nodes: p.Nodes, Fee: fee})
+	}
+	if len(resp.Paths) == 0 {
+		http.Error(w, errNoPath.Error(), http.StatusNotFound)
+		return
+	}
+	writeJSON(w, resp)
+}
+
+func (s *Server) handleCapacity(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(r.Body, maxCapacityHintSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	msg := new(encoding.CapacityHint)
+	err = msg.UnPack(data)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	now := time.Now()
+	expiration := time.Unix(msg.Expiration, 0)
+	if !expiration.After(now) || expiration.Sub(now) > params.MaxCapacityHintExpiration || msg.Precision == 0 {
+		http.Error(w, "invalid capacity hint", http.StatusBadRequest)
+		return
+	}
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	g := s.graphs[msg.Token]
+	if g == nil {
+		http.Error(w, errUnknownToken.Error(), http.StatusNotFound)
+		return
+	}
+	for _, b := range msg.Buckets {
+		min, max := graph.CapacityBucketRange(b.Bucket, int(msg.Precision))
+		g.SetCapacityHint(msg.Sender, b.Partner, min, max, expiration)
+	}
+}
@@ -0,0 +1,55 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/pathfinder"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//errNoAvailableRoute neither my graph nor paths given can reach the target
+var errNoAvailableRoute = errors.New("no available route")
+
+/*
+findPaths asks pathfinding service for paths to target, it's called out of the main loop, so waiting for the service doesn't block others.
+it's only called when my graph has no route to target, nil if there is no service or it fails.
+*/
+func (rs *RaidenService) findPaths(tokenAddress, target common.Address, amount *big.Int, fee *big.Int) []*route.Path {
+	if rs.Pathfinder == nil {
+		return nil
+	}
+	req := &pathfinder.Request{
+		Token:  tokenAddress,
+		From:   rs.NodeAddress,
+		To:     target,
+		Amount: amount,
+		Limit:  params.RoutePaths,
+	}
+	if fee != nil && fee.Cmp(utils.BigInt0) > 0 {
+		req.MaxFee = fee
+	}
+	paths, err := rs.Pathfinder.FindPaths(req)
+	if err != nil {
+		log.Warn(fmt.Sprintf("find paths to %s by pathfinder err %s", utils.APex2(target), err))
+		return nil
+	}
+	return paths
+}
+
+//postCapacityHints tells pathfinding service capacity of my channels, never call it in the main loop.
+func (rs *RaidenService) postCapacityHints(msgs []*encoding.CapacityHint) {
+	for _, msg := range msgs {
+		err := rs.Pathfinder.SendCapacityHint(msg)
+		if err != nil {
+			log.Warn(fmt.Sprintf("send capacity hint to pathfinder err %s", err))
+			return
+		}
+	}
+}
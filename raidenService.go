@@ -1,7 +1,6 @@
 package smartraiden
 
 import (
-	"crypto/ecdsa"
 	"errors"
 
 	"fmt"
@@ -26,6 +25,7 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/encoding"
 	"github.com/SmartMeshFoundation/SmartRaiden/internal/rpanic"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
 	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/network"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
@@ -34,6 +34,9 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/fee"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/pathfinder"
+	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer/initiator"
@@ -43,7 +46,6 @@ import (
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/theckman/go-flock"
 )
 
@@ -73,18 +75,20 @@ most of raidenService's member is not thread safe, and should not visit outside
 */
 type RaidenService struct {
 	Chain                 *rpc.BlockChainService
-	Registry              *rpc.RegistryProxy
-	SecretRegistryAddress common.Address
-	RegistryAddress       common.Address
-	PrivateKey            *ecdsa.PrivateKey
+	Registry              *rpc.RegistryProxy //proxy of the default registry
+	SecretRegistryAddress common.Address     //secret registry of the default registry
+	RegistryAddress       common.Address     //the default registry, tokens are registered on it when no registry is given
+	RegistryAddresses     []common.Address   //all registries attached, including the default one
+	Signer                signer.Signer
 	Transport             network.Transporter
 	Config                *params.Config
 	Protocol              *network.RaidenProtocol
 	NodeAddress           common.Address
-	Token2ChannelGraph    map[common.Address]*graph.ChannelGraph
+	//a token can be registered on more than one registry, so token networks and graphs are keyed by both
+	RegistryToken2ChannelGraph map[models.RegistryToken]*graph.ChannelGraph
+	RegistryToken2TokenNetwork map[models.RegistryToken]common.Address
+	TokenNetwork2RegistryToken models.RegistryTokenMap
 
-	TokenNetwork2Token    map[common.Address]common.Address
-	Token2TokenNetwork    map[common.Address]common.Address
 	Transfer2StateManager map[common.Hash]*transfer.StateManager
 	Transfer2Result       map[common.Hash]*utils.AsyncResult
 	SwapKey2TokenSwap     map[swapKey]*TokenSwap
@@ -113,7 +117,8 @@ type RaidenService struct {
 	*/
 	UserReqChan                 chan *apiReq
 	ProtocolMessageSendComplete chan *protocolMessage
-	FeePolicy                   fee.Charger //Mediation fee
+	FeePolicy                   fee.Charger       //Mediation fee
+	Pathfinder                  pathfinder.Client //nil if no pathfinding service
 	/*
 		these four maps designed for token swap,but it can be extended for purpose usage.
 		for example:
@@ -132,10 +137,16 @@ type RaidenService struct {
 	ethInited                           bool
 	EthConnectionStatus                 chan netshare.Status
 	ChanStartupComplete                 chan struct{}
+	lastEndpointAnnounce                time.Time //when my endpoint announced to partners last time
+	lastCapacityHint                    time.Time //when capacity hints of my channels sent to partners last time
+	settleScheduler                     *settleScheduler
+	reconciler                          *reconciler
+	metricsCollector                    int   //id of collectMetrics in metrics.DefaultRegistry
+	archiving                           int32 //atomic, 1 while archiveDb is running
 }
 
 //NewRaidenService create raiden service
-func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey, transport network.Transporter, config *params.Config) (rs *RaidenService, err error) {
+func NewRaidenService(chain *rpc.BlockChainService, s signer.Signer, transport network.Transporter, config *params.Config) (rs *RaidenService, err error) {
 	if config.SettleTimeout < params.ChannelSettleTimeoutMin || config.SettleTimeout > params.ChannelSettleTimeoutMax {
 		err = fmt.Errorf("settle timeout must be in range %d-%d",
 			params.ChannelSettleTimeoutMin, params.ChannelSettleTimeoutMax)
@@ -145,13 +156,13 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 		Chain:                               chain,
 		Registry:                            chain.Registry(chain.RegistryAddress),
 		RegistryAddress:                     chain.RegistryAddress,
-		PrivateKey:                          privateKey,
+		Signer:                              s,
 		Config:                              config,
 		Transport:                           transport,
-		NodeAddress:                         crypto.PubkeyToAddress(privateKey.PublicKey),
-		Token2ChannelGraph:                  make(map[common.Address]*graph.ChannelGraph),
-		TokenNetwork2Token:                  make(map[common.Address]common.Address),
-		Token2TokenNetwork:                  make(map[common.Address]common.Address),
+		NodeAddress:                         s.Address(),
+		RegistryToken2ChannelGraph:          make(map[models.RegistryToken]*graph.ChannelGraph),
+		RegistryToken2TokenNetwork:          make(map[models.RegistryToken]common.Address),
+		TokenNetwork2RegistryToken:          make(models.RegistryTokenMap),
 		Transfer2StateManager:               make(map[common.Hash]*transfer.StateManager),
 		Transfer2Result:                     make(map[common.Hash]*utils.AsyncResult),
 		Token2Hashlock2Channels:             make(map[common.Address]map[common.Hash][]*channel.Channel),
@@ -172,15 +183,25 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 		ChanStartupComplete:                 make(chan struct{}),
 	}
 	rs.BlockNumber.Store(int64(0))
+	rs.settleScheduler = newSettleScheduler(rs)
+	rs.reconciler = newReconciler(rs)
 	rs.MessageHandler = newRaidenMessageHandler(rs)
 	rs.StateMachineEventHandler = newStateMachineEventHandler(rs)
-	rs.Protocol = network.NewRaidenProtocol(transport, privateKey, rs)
-	rs.db, err = models.OpenDb(config.DataBasePath)
+	rs.Protocol = network.NewRaidenProtocol(transport, s, rs, &config.Protocol)
+	rs.db, err = models.OpenDbWithPassword(config.DataBasePath, config.DbPassword, config.EncryptDb)
+	config.DbPassword = ""
 	if err != nil {
 		err = fmt.Errorf("open db error %s", err)
 		return
 	}
 	rs.Protocol.SetReceivedMessageSaver(NewAckHelper(rs.db))
+	if len(config.PathfinderURL) > 0 {
+		rs.Pathfinder = pathfinder.NewHTTPClient(config.PathfinderURL, s, config.PathfinderMaxQueryFee, rs.db)
+	}
+	err = chain.GasPricer.SetStore(rs.db)
+	if err != nil {
+		log.Error(fmt.Sprintf("gas price config saved is invalid, use default, err %s", err))
+	}
 	/*
 		only one instance for one data directory
 	*/
@@ -192,30 +213,116 @@ func NewRaidenService(chain *rpc.BlockChainService, privateKey *ecdsa.PrivateKey
 	}
 	rs.SnapshortDir = filepath.Join(config.DataBasePath)
 	log.Info(fmt.Sprintf("create raiden service registry=%s,node=%s", rs.RegistryAddress.String(), rs.NodeAddress.String()))
-	if rs.Registry != nil {
-		//我已经连接到以太坊全节点
-		rs.SecretRegistryAddress, err = rs.Registry.GetContract().Secret_registry_address(nil)
+	rs.RegistryAddresses = []common.Address{rs.RegistryAddress}
+	for _, r := range config.RegistryAddresses {
+		if r != rs.RegistryAddress {
+			rs.RegistryAddresses = append(rs.RegistryAddresses, r)
+		}
+	}
+	secretRegistries := make(map[common.Address]common.Address)
+	for _, r := range rs.RegistryAddresses {
+		secretRegistries[r], err = rs.getSecretRegistryAddress(r)
 		if err != nil {
 			return
 		}
-		rs.db.SaveSecretRegistryAddress(rs.SecretRegistryAddress)
-	} else {
-		//读取数据库中存放的 SecretRegistryAddress, 如果没有,说明系统没有初始化过,只能退出.
-		rs.SecretRegistryAddress = rs.db.GetSecretRegistryAddress()
-		if rs.SecretRegistryAddress == utils.EmptyAddress {
-			err = fmt.Errorf("first startup without ethereum rpc connection")
+	}
+	rs.SecretRegistryAddress = secretRegistries[rs.RegistryAddress]
+	rs.TokenNetwork2RegistryToken, err = rs.db.GetRegistryTokens()
+	if err != nil {
+		return
+	}
+	var tokenNetworks []common.Address
+	for tn, key := range rs.TokenNetwork2RegistryToken {
+		rs.RegistryToken2TokenNetwork[key] = tn
+		tokenNetworks = append(tokenNetworks, tn)
+	}
+	rs.BlockChainEvents = blockchain.NewBlockChainEvents(chain.Client, chain.RegistryAddress, rs.SecretRegistryAddress, tokenNetworks)
+	for r, sr := range secretRegistries {
+		rs.BlockChainEvents.AddRegistry(r, sr)
+	}
+	rs.BlockChainEvents.Indexer = rs.db
+	return rs, nil
+}
+
+/*
+getSecretRegistryAddress returns secret registry of `registry`, from blockchain if connected, otherwise from db.
+*/
+func (rs *RaidenService) getSecretRegistryAddress(registry common.Address) (secretRegistry common.Address, err error) {
+	if r := rs.Chain.Registry(registry); r != nil {
+		//我已经连接到以太坊全节点
+		secretRegistry, err = r.GetContract().Secret_registry_address(nil)
+		if err != nil {
 			return
 		}
+		if registry == rs.RegistryAddress {
+			rs.db.SaveSecretRegistryAddress(secretRegistry)
+		}
+		err = rs.db.SaveSecretRegistryOf(registry, secretRegistry)
+		return
 	}
-	rs.Token2TokenNetwork, err = rs.db.GetAllTokens()
+	//读取数据库中存放的 SecretRegistryAddress, 如果没有,说明系统没有初始化过,只能退出.
+	m, err := rs.db.GetSecretRegistries()
 	if err != nil {
 		return
 	}
-	for t, tn := range rs.Token2TokenNetwork {
-		rs.TokenNetwork2Token[tn] = t
+	secretRegistry = m[registry]
+	if secretRegistry == utils.EmptyAddress && registry == rs.RegistryAddress {
+		//saved before there are more than one registry
+		secretRegistry = rs.db.GetSecretRegistryAddress()
 	}
-	rs.BlockChainEvents = blockchain.NewBlockChainEvents(chain.Client, chain.RegistryAddress, rs.SecretRegistryAddress, rs.Token2TokenNetwork)
-	return rs, nil
+	if secretRegistry == utils.EmptyAddress {
+		err = fmt.Errorf("first startup of registry %s without ethereum rpc connection", registry.String())
+	}
+	return
+}
+
+//isOurRegistry returns true if `registry` is one of the registries attached
+func (rs *RaidenService) isOurRegistry(registry common.Address) bool {
+	for _, r := range rs.RegistryAddresses {
+		if r == registry {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+tokenKey returns the key of `token` registered on `registry`.
+when `registry` is empty, it's the default registry if `token` is registered on it,
+otherwise the first registry attached `token` is registered on.
+must run in the main loop.
+*/
+func (rs *RaidenService) tokenKey(registry, token common.Address) models.RegistryToken {
+	return resolveTokenKey(rs.RegistryAddresses, registry, token, func(key models.RegistryToken) bool {
+		_, ok := rs.RegistryToken2TokenNetwork[key]
+		return ok
+	})
+}
+
+//resolveTokenKey is tokenKey on `registries`, `registered` tells whether a token is registered on a registry
+func resolveTokenKey(registries []common.Address, registry, token common.Address, registered func(key models.RegistryToken) bool) models.RegistryToken {
+	if registry != utils.EmptyAddress {
+		return models.RegistryToken{Registry: registry, Token: token}
+	}
+	for _, r := range registries {
+		key := models.RegistryToken{Registry: r, Token: token}
+		if registered(key) {
+			return key
+		}
+	}
+	return models.RegistryToken{Registry: registries[0], Token: token}
+}
+
+//secretRegistryOf returns proxy of the secret registry of `registry`, the default registry if it's empty
+func (rs *RaidenService) secretRegistryOf(registry common.Address) (*rpc.SecretRegistryProxy, error) {
+	if registry == utils.EmptyAddress {
+		registry = rs.RegistryAddress
+	}
+	s := rs.Chain.SecretRegistry(registry)
+	if s == nil {
+		return nil, fmt.Errorf("secret registry of registry %s unavailable", registry.String())
+	}
+	return s, nil
 }
 
 // Start the node.
@@ -226,7 +333,15 @@ func (rs *RaidenService) Start() (err error) {
 		return rs.setBlockNumber(number)
 	})
 	rs.registerRegistry()
+	err = rs.checkRestoredChannels()
+	if err != nil {
+		return
+	}
+	rs.reconciler.blockLastConflicts()
+	rs.archiveDb()
+	rs.loadNodeEndpoints()
 	rs.Protocol.Start()
+	rs.metricsCollector = metrics.AddCollector(rs.collectMetrics)
 
 	go func() {
 		if rs.Config.ConditionQuit.RandomQuit {
@@ -277,6 +392,8 @@ func (rs *RaidenService) Start() (err error) {
 func (rs *RaidenService) Stop() {
 	log.Info("raiden service stop...")
 	close(rs.quitChan)
+	//collectMetrics reads db, which is closed below
+	metrics.RemoveCollector(rs.metricsCollector)
 	rs.AlarmTask.Stop()
 	rs.Protocol.StopAndWait()
 	rs.BlockChainEvents.Stop()
@@ -384,17 +501,17 @@ func (rs *RaidenService) loop() {
 //for init,read db history,只要是我还没处理的链上事件,都还在队列中等着发给我.
 func (rs *RaidenService) registerRegistry() {
 	dbRegistry := rs.db.GetRegistryAddress()
-	if dbRegistry != rs.RegistryAddress && dbRegistry != utils.EmptyAddress {
+	if !rs.isOurRegistry(dbRegistry) && dbRegistry != utils.EmptyAddress {
 		log.Crit(fmt.Sprintf("db mismatch, db's registry=%s,now registry=%s",
 			dbRegistry, rs.RegistryAddress))
 	}
-	token2TokenNetworks, err := rs.db.GetAllTokens()
-	if err != nil {
-		err = fmt.Errorf("registerRegistry err:%s", err)
-		return
-	}
-	for token, tokenNetwork := range token2TokenNetworks {
-		err = rs.registerTokenNetwork(token, tokenNetwork)
+	for tokenNetwork, key := range rs.TokenNetwork2RegistryToken {
+		if !rs.isOurRegistry(key.Registry) {
+			//events of its channels would be missed
+			log.Crit(fmt.Sprintf("db mismatch, token %s is registered on registry %s, which is not attached any more",
+				key.Token.String(), key.Registry.String()))
+		}
+		err := rs.registerTokenNetwork(key.Registry, key.Token, tokenNetwork)
 		if err != nil {
 			err = fmt.Errorf("registerTokenNetwork err:%s", err)
 			return
@@ -408,7 +525,7 @@ i'm one of the channel participants
 收到来自链上的事件,新创建了 channel
 但是事件有可能重复
 */
-func (rs *RaidenService) newChannelFromEvent(tokenNetwork *rpc.TokenNetworkProxy, tokenAddress common.Address, partnerAddress common.Address, channelIdentifier *contracts.ChannelUniqueID, settleTimeout int) (ch *channel.Channel, err error) {
+func (rs *RaidenService) newChannelFromEvent(tokenNetwork *rpc.TokenNetworkProxy, key models.RegistryToken, partnerAddress common.Address, channelIdentifier *contracts.ChannelUniqueID, settleTimeout int) (ch *channel.Channel, err error) {
 	/*
 		因为有可能在我离线的时候收到一堆事件,所以通道的信息不一定就是新创建时候的状态,
 		但是保证后续的事件会继续收到,所以应该按照新通道处理.
@@ -417,8 +534,13 @@ func (rs *RaidenService) newChannelFromEvent(tokenNetwork *rpc.TokenNetworkProxy
 	ourState := channel.NewChannelEndState(rs.NodeAddress, big.NewInt(0), nil, mtree.NewMerkleTree(nil))
 	partenerState := channel.NewChannelEndState(partnerAddress, big.NewInt(0), nil, mtree.NewMerkleTree(nil))
 
-	externState := channel.NewChannelExternalState(rs.registerChannelForHashlock, tokenNetwork, channelIdentifier, rs.PrivateKey, rs.Chain.Client, rs.db, 0, rs.NodeAddress, partnerAddress)
-	ch, err = channel.NewChannel(ourState, partenerState, externState, tokenAddress, channelIdentifier, rs.Config.RevealTimeout, settleTimeout)
+	externState := channel.NewChannelExternalState(rs.registerChannelForHashlock, tokenNetwork, channelIdentifier, rs.Signer, rs.Chain.Client, rs.db, 0, rs.NodeAddress, partnerAddress)
+	externState.UnlockCoster = rs
+	ch, err = channel.NewChannel(ourState, partenerState, externState, key.Token, channelIdentifier, rs.Config.RevealTimeout, settleTimeout)
+	if err != nil {
+		return
+	}
+	ch.RegistryAddress = key.Registry
 	return
 }
 
@@ -439,7 +561,7 @@ func (rs *RaidenService) handleBlockNumber(blocknumber int64) {
 			when currentState==nil && StateManager.ManagerState!=StateManagerStateInit ,should delete rs statemanager.
 	*/
 	rs.StateMachineEventHandler.dispatchToAllTasks(statechange)
-	for _, cg := range rs.Token2ChannelGraph {
+	for _, cg := range rs.RegistryToken2ChannelGraph {
 		for _, c := range cg.ChannelAddress2Channel {
 			err := rs.StateMachineEventHandler.ChannelStateTransition(c, statechange)
 			if err != nil {
@@ -447,17 +569,38 @@ func (rs *RaidenService) handleBlockNumber(blocknumber int64) {
 			}
 		}
 	}
-
+	rs.announceNodeEndpoint()
+	rs.advertiseCapacityHints()
+	if rs.reconciler.shouldStart() {
+		rs.reconciler.start()
+	}
+	if blocknumber%params.DbArchiveInterval == 0 {
+		go rs.archiveDb()
+	}
 	return
 }
 
+//archiveDb moves completed StateManagers and old acks out of live records of db
+//it may take more than one block, an archive which is still running is not started again.
+func (rs *RaidenService) archiveDb() {
+	if !atomic.CompareAndSwapInt32(&rs.archiving, 0, 1) {
+		log.Info("archive db is still running, skip")
+		return
+	}
+	defer atomic.StoreInt32(&rs.archiving, 0)
+	_, err := rs.db.Archive(rs.Config.ArchiveAckAge)
+	if err != nil {
+		log.Error(fmt.Sprintf("archive db err %s", err))
+	}
+}
+
 //GetBlockNumber return latest blocknumber of ethereum
 func (rs *RaidenService) GetBlockNumber() int64 {
 	return rs.BlockNumber.Load().(int64)
 }
 
 func (rs *RaidenService) findChannelByAddress(nettingChannelAddress common.Hash) (*channel.Channel, error) {
-	for _, g := range rs.Token2ChannelGraph {
+	for _, g := range rs.RegistryToken2ChannelGraph {
 		ch := g.GetChannelAddress2Channel(nettingChannelAddress)
 		if ch != nil {
 			return ch, nil
@@ -578,9 +721,10 @@ func (rs *RaidenService) channelSerilization2Channel(c *channeltype.Serializatio
 		c.PartnerContractBalance,
 		c.PartnerBalanceProof, mtree.NewMerkleTree(c.PartnerLeaves))
 	ExternState := channel.NewChannelExternalState(rs.registerChannelForHashlock, tokenNetwork,
-		c.ChannelIdentifier, rs.PrivateKey,
+		c.ChannelIdentifier, rs.Signer,
 		rs.Chain.Client, rs.db, c.ClosedBlock,
 		c.OurAddress, c.PartnerAddress())
+	ExternState.UnlockCoster = rs
 	ch, err = channel.NewChannel(OurState, PartnerState, ExternState, c.TokenAddress(), c.ChannelIdentifier, c.RevealTimeout, c.SettleTimeout)
 	if err != nil {
 		return
@@ -595,20 +739,23 @@ func (rs *RaidenService) channelSerilization2Channel(c *channeltype.Serializatio
 	ch.PartnerState.ContractBalance = c.PartnerContractBalance
 	ch.ExternState.ClosedBlock = c.ClosedBlock
 	ch.ExternState.SettledBlock = c.SettledBlock
+	ch.RegistryAddress = c.RegistryAddress
 	return
 }
 
 //read a token network info from db
-func (rs *RaidenService) registerTokenNetwork(tokenAddress, tokenNetworkAddress common.Address) (err error) {
+func (rs *RaidenService) registerTokenNetwork(registry, tokenAddress, tokenNetworkAddress common.Address) (err error) {
 	tokenNetwork, err := rs.Chain.TokenNetworkWithoutCheck(tokenNetworkAddress)
-	edges, err := rs.db.GetAllNonParticipantChannel(tokenAddress)
+	edges, err := rs.db.GetAllNonParticipantChannel(tokenNetworkAddress)
 	if err != nil {
 		return
 	}
 	g := graph.NewChannelGraph(rs.NodeAddress, tokenAddress, edges)
-	rs.TokenNetwork2Token[tokenNetworkAddress] = tokenAddress
-	rs.Token2TokenNetwork[tokenAddress] = tokenNetworkAddress
-	rs.Token2ChannelGraph[tokenAddress] = g
+	g.RegistryAddress = registry
+	key := models.RegistryToken{Registry: registry, Token: tokenAddress}
+	rs.TokenNetwork2RegistryToken[tokenNetworkAddress] = key
+	rs.RegistryToken2TokenNetwork[key] = tokenNetworkAddress
+	rs.RegistryToken2ChannelGraph[key] = g
 	//add channel I participant
 	css, err := rs.db.GetChannelList(tokenAddress, utils.EmptyAddress)
 
@@ -617,6 +764,13 @@ func (rs *RaidenService) registerTokenNetwork(tokenAddress, tokenNetworkAddress
 		if cs.State == channeltype.StateSettled {
 			continue
 		}
+		//saved before a token can be registered on more than one registry, it's of the only one
+		if cs.RegistryAddress == utils.EmptyAddress {
+			cs.RegistryAddress = rs.tokenKey(utils.EmptyAddress, tokenAddress).Registry
+		}
+		if cs.RegistryAddress != registry {
+			continue
+		}
 		ch, err := rs.channelSerilization2Channel(cs, tokenNetwork)
 		if err != nil {
 			return err
@@ -639,17 +793,17 @@ func (rs *RaidenService) registerChannel(tokenNetworkAddress common.Address, par
 			utils.APex2(tokenNetworkAddress), utils.APex2(partnerAddress), err,
 		))
 	}
-	tokenAddress := rs.TokenNetwork2Token[tokenNetworkAddress]
-	if rs.getChannel(tokenAddress, partnerAddress) != nil {
-		log.Error(fmt.Sprintf("receive new channel %s-%s,but this channel already exist, maybe a duplicate channel event", utils.APex2(tokenAddress), utils.APex2(partnerAddress)))
+	key := rs.TokenNetwork2RegistryToken[tokenNetworkAddress]
+	if rs.getChannel(key.Registry, key.Token, partnerAddress) != nil {
+		log.Error(fmt.Sprintf("receive new channel %s-%s,but this channel already exist, maybe a duplicate channel event", key, utils.APex2(partnerAddress)))
 		return
 	}
-	ch, err := rs.newChannelFromEvent(tokenNetwork, tokenAddress, partnerAddress, channelIdentifier, settleTimeout)
+	ch, err := rs.newChannelFromEvent(tokenNetwork, key, partnerAddress, channelIdentifier, settleTimeout)
 	if err != nil {
 		log.Error(fmt.Sprintf("newChannelFromEvent err %s", err))
 		return
 	}
-	g := rs.getToken2ChannelGraph(tokenAddress)
+	g := rs.getRegistryToken2ChannelGraph(key)
 	err = g.AddChannel(ch)
 	if err != nil {
 		log.Error(err.Error())
@@ -684,9 +838,8 @@ Do a direct tranfer with target.
        are required to complete the transfer (from the payer's perspective),
        whereas the mediated transfer requires 6 messages.
 */
-func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address, amount *big.Int) (result *utils.AsyncResult) {
-	g := rs.getToken2ChannelGraph(tokenAddress)
-	directChannel := g.GetPartenerAddress2Channel(target)
+func (rs *RaidenService) directTransferAsync(registry, tokenAddress, target common.Address, amount *big.Int) (result *utils.AsyncResult) {
+	directChannel := rs.getChannel(registry, tokenAddress, target)
 	result = utils.NewAsyncResult()
 	if directChannel == nil || !directChannel.CanTransfer() || directChannel.Distributable().Cmp(amount) < 0 {
 		result.Result <- errors.New("no available direct channel")
@@ -697,7 +850,7 @@ func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address
 		result.Result <- err
 		return
 	}
-	err = tr.Sign(rs.PrivateKey, tr)
+	err = tr.Sign(rs.Signer, tr)
 	err = directChannel.RegisterTransfer(rs.GetBlockNumber(), tr)
 	if err != nil {
 		result.Result <- err
@@ -711,6 +864,7 @@ func (rs *RaidenService) directTransferAsync(tokenAddress, target common.Address
 		ChannelIdentifier: directChannel.ChannelIdentifier.ChannelIdentifier,
 		Token:             tokenAddress,
 	}
+	metrics.TransfersInitiated.Inc(tokenLabel(tokenAddress), "direct")
 	result = rs.Protocol.SendAsync(directChannel.PartnerState.Address, tr)
 	err = rs.StateMachineEventHandler.OnEvent(transferSuccess, nil)
 	if err != nil {
@@ -725,7 +879,7 @@ we must make sure that taker use the maker's secret.
 and taker's lock expiration should be short than maker's todo(fix this)
 */
 func (rs *RaidenService) startTakerMediatedTransfer(tokenAddress, target common.Address, amount *big.Int, lockSecretHash common.Hash, hashlock common.Hash, expiration int64) (result *utils.AsyncResult, stateManager *transfer.StateManager) {
-	return rs.startMediatedTransferInternal(tokenAddress, target, amount, utils.BigInt0, lockSecretHash, hashlock, expiration)
+	return rs.startMediatedTransferInternal(utils.EmptyAddress, tokenAddress, target, amount, utils.BigInt0, lockSecretHash, hashlock, expiration, nil)
 }
 
 /*
@@ -733,13 +887,26 @@ lauch a new mediated trasfer
 Args:
  hashlock: caller can specify a hashlock or use empty ,when empty, will generate a random secret.
  expiration: caller can specify a valid blocknumber or 0, when 0 ,will calculate based on settle timeout of channel.
+ paths: paths found by pathfinding service, routes are found in my graph when none of them can be used.
+ pathfinding service only knows the default registry, paths are ignored on other registries.
 */
-func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, hashlock common.Hash, expiration int64) (result *utils.AsyncResult, stateManager *transfer.StateManager) {
-	g := rs.getToken2ChannelGraph(tokenAddress)
-	availableRoutes := g.GetBestRoutes(rs.Protocol, rs.NodeAddress, target, amount, graph.EmptyExlude, rs)
+func (rs *RaidenService) startMediatedTransferInternal(registry, tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, hashlock common.Hash, expiration int64, paths []*route.Path) (result *utils.AsyncResult, stateManager *transfer.StateManager) {
+	key := rs.tokenKey(registry, tokenAddress)
+	g := rs.getRegistryToken2ChannelGraph(key)
 	result = utils.NewAsyncResult()
+	if g == nil {
+		result.Result <- rerr.UnknownTokenAddress(key.String())
+		return
+	}
+	var availableRoutes []*route.State
+	if len(paths) > 0 && key.Registry == rs.RegistryAddress {
+		availableRoutes = g.GetRoutesByPaths(rs.Protocol, rs.NodeAddress, target, amount, paths, rs)
+	}
 	if len(availableRoutes) <= 0 {
-		result.Result <- errors.New("no available route")
+		availableRoutes = g.GetBestRoutes(rs.Protocol, rs.NodeAddress, target, amount, graph.EmptyExlude, rs)
+	}
+	if len(availableRoutes) <= 0 {
+		result.Result <- errNoAvailableRoute
 		return
 	}
 	if rs.Config.IsMeshNetwork {
@@ -784,6 +951,7 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 		Db:             rs.db,
 	}
 	stateManager = transfer.NewStateManager(initiator.StateTransition, nil, initiator.NameInitiatorTransition, lockSecretHash, transferState.Token)
+	stateManager.RegistryAddress = key.Registry
 	smkey := utils.Sha3(lockSecretHash[:], tokenAddress[:])
 	manager := rs.Transfer2StateManager[smkey]
 	if manager != nil {
@@ -791,6 +959,7 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 	}
 	rs.Transfer2StateManager[smkey] = stateManager
 	rs.Transfer2Result[smkey] = result
+	metrics.TransfersInitiated.Inc(tokenLabel(tokenAddress), "mediated")
 	//rs.db.AddStateManager(stateManager)
 	rs.StateMachineEventHandler.dispatch(stateManager, initInitiator)
 	return
@@ -800,8 +969,8 @@ func (rs *RaidenService) startMediatedTransferInternal(tokenAddress, target comm
 1. user start a mediated transfer
 2. user start a maker mediated transfer
 */
-func (rs *RaidenService) startMediatedTransfer(tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash) (result *utils.AsyncResult) {
-	result, _ = rs.startMediatedTransferInternal(tokenAddress, target, amount, fee, lockSecretHash, utils.EmptyHash, 0)
+func (rs *RaidenService) startMediatedTransfer(registry, tokenAddress, target common.Address, amount *big.Int, fee *big.Int, lockSecretHash common.Hash, paths []*route.Path) (result *utils.AsyncResult) {
+	result, _ = rs.startMediatedTransferInternal(registry, tokenAddress, target, amount, fee, lockSecretHash, utils.EmptyHash, 0, paths)
 	return
 }
 
@@ -822,7 +991,7 @@ func (rs *RaidenService) mediateMediatedTransfer(msg *encoding.MediatedTransfer,
 	}
 	amount := msg.PaymentAmount
 	targetAddr := msg.Target
-	g := rs.getToken2ChannelGraph(ch.TokenAddress) //must exist
+	g := rs.getRegistryToken2ChannelGraph(rs.channelKey(ch)) //must exist
 	fromChannel := ch
 	fromRoute := graph.Channel2RouteState(fromChannel, msg.Sender, amount, rs)
 	fromTransfer := mediatedtransfer.LockedTransferFromMessage(msg, ch.TokenAddress)
@@ -854,8 +1023,10 @@ func (rs *RaidenService) mediateMediatedTransfer(msg *encoding.MediatedTransfer,
 			Db:          rs.db,
 		}
 		stateManager = transfer.NewStateManager(mediator.StateTransition, nil, mediator.NameMediatorTransition, fromTransfer.LockSecretHash, fromTransfer.Token)
+		stateManager.RegistryAddress = rs.channelKey(ch).Registry
 		//rs.db.AddStateManager(stateManager)
 		rs.Transfer2StateManager[smkey] = stateManager //for path A-B-C-F-B-D-E ,node B will have two StateManagers for one identifier
+		metrics.TransfersMediated.Inc(tokenLabel(tokenAddress))
 		rs.StateMachineEventHandler.dispatch(stateManager, initMediator)
 	}
 }
@@ -883,7 +1054,7 @@ func (rs *RaidenService) targetMediatedTransfer(msg *encoding.MediatedTransfer,
 			msg, utils.StringInterface(stateManager, 3)))
 		return
 	}
-	g := rs.getToken2ChannelGraph(ch.TokenAddress)
+	g := rs.getRegistryToken2ChannelGraph(rs.channelKey(ch))
 	fromChannel := g.GetPartenerAddress2Channel(msg.Sender)
 	fromRoute := graph.Channel2RouteState(fromChannel, msg.Sender, msg.PaymentAmount, rs)
 	fromTransfer := mediatedtransfer.LockedTransferFromMessage(msg, ch.TokenAddress)
@@ -896,6 +1067,7 @@ func (rs *RaidenService) targetMediatedTransfer(msg *encoding.MediatedTransfer,
 		Db:          rs.db,
 	}
 	stateManager = transfer.NewStateManager(target.StateTransiton, nil, target.NameTargetTransition, fromTransfer.LockSecretHash, fromTransfer.Token)
+	stateManager.RegistryAddress = rs.channelKey(ch).Registry
 	//rs.db.AddStateManager(stateManager)
 	rs.Transfer2StateManager[smkey] = stateManager
 	rs.StateMachineEventHandler.dispatch(stateManager, initTarget)
@@ -924,7 +1096,7 @@ func (rs *RaidenService) startHealthCheckFor(address common.Address) {
 }
 
 func (rs *RaidenService) startNeighboursHealthCheck() {
-	for _, g := range rs.Token2ChannelGraph {
+	for _, g := range rs.RegistryToken2ChannelGraph {
 		for addr := range g.PartenerAddress2Channel {
 			rs.startHealthCheckFor(addr)
 		}
@@ -937,23 +1109,24 @@ func (rs *RaidenService) startSubscribeNeighborStatus() error {
 	}
 	return mt.SubscribeNeighbor(rs.db)
 }
-func (rs *RaidenService) getToken2ChannelGraph(tokenAddress common.Address) (cg *graph.ChannelGraph) {
-	cg = rs.Token2ChannelGraph[tokenAddress]
+func (rs *RaidenService) getRegistryToken2ChannelGraph(key models.RegistryToken) (cg *graph.ChannelGraph) {
+	cg = rs.RegistryToken2ChannelGraph[key]
 	if cg == nil {
-		log.Error(fmt.Sprintf("%s token doesn't exist ", utils.APex(tokenAddress)))
+		log.Error(fmt.Sprintf("%s token doesn't exist ", key))
 	}
 	return
 }
+
+//channelKey returns key of token network of `ch`
+func (rs *RaidenService) channelKey(ch *channel.Channel) models.RegistryToken {
+	return rs.tokenKey(ch.RegistryAddress, ch.TokenAddress)
+}
 func (rs *RaidenService) getChannelGraph(channelIdentifier common.Hash) (cg *graph.ChannelGraph) {
 	ch, err := rs.findChannelByAddress(channelIdentifier)
 	if err != nil {
 		return
 	}
-	cg = rs.Token2ChannelGraph[ch.TokenAddress]
-	if cg == nil {
-		log.Error(fmt.Sprintf("%s token doesn't exist ", utils.APex(ch.TokenAddress)))
-	}
-	return
+	return rs.getRegistryToken2ChannelGraph(rs.channelKey(ch))
 }
 func (rs *RaidenService) getTokenForChannelIdentifier(channelidentifier common.Hash) (token common.Address) {
 	ch, err := rs.findChannelByAddress(channelidentifier)
@@ -973,9 +1146,9 @@ func (rs *RaidenService) getChannelWithAddr(channelAddr common.Hash) *channel.Ch
 	return c
 }
 
-//for test
-func (rs *RaidenService) getChannel(tokenAddr, partnerAddr common.Address) *channel.Channel {
-	g := rs.getToken2ChannelGraph(tokenAddr)
+//getChannel returns channel with `partnerAddr` of `tokenAddr` on `registry`, see tokenKey if `registry` is empty
+func (rs *RaidenService) getChannel(registry, tokenAddr, partnerAddr common.Address) *channel.Channel {
+	g := rs.RegistryToken2ChannelGraph[rs.tokenKey(registry, tokenAddr)]
 	if g == nil {
 		return nil
 	}
@@ -985,8 +1158,8 @@ func (rs *RaidenService) getChannel(tokenAddr, partnerAddr common.Address) *chan
 /*
 Process user's new channel request
 */
-func (rs *RaidenService) newChannel(token, partner common.Address, settleTimeout int) (result *utils.AsyncResult) {
-	tokenNetwork, err := rs.Chain.TokenNetwork(rs.Token2TokenNetwork[token])
+func (rs *RaidenService) newChannel(registry, token, partner common.Address, settleTimeout int) (result *utils.AsyncResult) {
+	tokenNetwork, err := rs.Chain.TokenNetwork(rs.RegistryToken2TokenNetwork[rs.tokenKey(registry, token)])
 	if err != nil {
 		result = utils.NewAsyncResultWithError(err)
 		return
@@ -998,8 +1171,8 @@ func (rs *RaidenService) newChannel(token, partner common.Address, settleTimeout
 /*
 Process user's new channel request
 */
-func (rs *RaidenService) newChannelAndDeposit(token, partner common.Address, settleTimeout int, amount *big.Int) (result *utils.AsyncResult) {
-	tokenNetwork, err := rs.Chain.TokenNetwork(rs.Token2TokenNetwork[token])
+func (rs *RaidenService) newChannelAndDeposit(registry, token, partner common.Address, settleTimeout int, amount *big.Int) (result *utils.AsyncResult) {
+	tokenNetwork, err := rs.Chain.TokenNetwork(rs.RegistryToken2TokenNetwork[rs.tokenKey(registry, token)])
 	if err != nil {
 		result = utils.NewAsyncResultWithError(err)
 		return
@@ -1065,7 +1238,7 @@ func (rs *RaidenService) cooperativeSettleChannel(channelAddress common.Hash) (r
 	if err != nil {
 		result.Result <- err
 	}
-	err = s.Sign(rs.PrivateKey, s)
+	err = s.Sign(rs.Signer, s)
 	err = rs.sendAsync(c.PartnerState.Address, s)
 	result.Result <- err
 	return
@@ -1128,7 +1301,7 @@ func (rs *RaidenService) withdraw(channelAddress common.Hash, amount *big.Int) (
 	if err != nil {
 		result.Result <- err
 	}
-	err = s.Sign(rs.PrivateKey, s)
+	err = s.Sign(rs.Signer, s)
 	err = rs.sendAsync(c.PartnerState.Address, s)
 	result.Result <- err
 	return
@@ -1197,7 +1370,7 @@ func (rs *RaidenService) tokenSwapMaker(tokenswap *TokenSwap) (result *utils.Asy
 	}
 	rs.SentMediatedTransferListenerMap[&sentMtrHook] = true
 	rs.ReceivedMediatedTrasnferListenerMap[&receiveMtrHook] = true
-	result = rs.startMediatedTransfer(tokenswap.FromToken, tokenswap.ToNodeAddress, tokenswap.FromAmount, utils.BigInt0, tokenswap.LockSecretHash)
+	result = rs.startMediatedTransfer(utils.EmptyAddress, tokenswap.FromToken, tokenswap.ToNodeAddress, tokenswap.FromAmount, utils.BigInt0, tokenswap.LockSecretHash, nil)
 	return
 }
 
@@ -1323,7 +1496,7 @@ func (rs *RaidenService) handleEthRRCConnectionOK() {
 			rs.BlockNumber.Store(n)
 		} else {
 			//must have a valid blocknumber before any transfer operation
-			rs.BlockNumber.Store(rs.AlarmTask.LastBlockNumber)
+			rs.BlockNumber.Store(rs.AlarmTask.LastBlockNumber())
 		}
 	}
 	/*
@@ -1343,16 +1516,16 @@ func (rs *RaidenService) handleReq(req *apiReq) {
 	case transferReqName: //mediated transfer only
 		r := req.Req.(*transferReq)
 		if r.IsDirectTransfer {
-			result = rs.directTransferAsync(r.TokenAddress, r.Target, r.Amount)
+			result = rs.directTransferAsync(r.RegistryAddress, r.TokenAddress, r.Target, r.Amount)
 		} else {
-			result = rs.startMediatedTransfer(r.TokenAddress, r.Target, r.Amount, r.Fee, r.LockSecretHash)
+			result = rs.startMediatedTransfer(r.RegistryAddress, r.TokenAddress, r.Target, r.Amount, r.Fee, r.LockSecretHash, r.Paths)
 		}
 	case newChannelReqName:
 		r := req.Req.(*newChannelReq)
 		if r.amount != nil && r.amount.Cmp(utils.BigInt0) > 0 {
-			result = rs.newChannelAndDeposit(r.tokenAddress, r.partnerAddress, r.settleTimeout, r.amount)
+			result = rs.newChannelAndDeposit(r.registry, r.tokenAddress, r.partnerAddress, r.settleTimeout, r.amount)
 		} else {
-			result = rs.newChannel(r.tokenAddress, r.partnerAddress, r.settleTimeout)
+			result = rs.newChannel(r.registry, r.tokenAddress, r.partnerAddress, r.settleTimeout)
 		}
 	case depositChannelReqName:
 		r := req.Req.(*depositChannelReq)
@@ -1387,6 +1560,22 @@ func (rs *RaidenService) handleReq(req *apiReq) {
 	case cancelPrepareWithdrawReqName:
 		r := req.Req.(*closeSettleChannelReq)
 		result = rs.cancelPrepareForCooperativeSettleChannelOrWithdraw(r.addr)
+	case pingReqName:
+		result = utils.NewAsyncResultWithError(nil)
+	case reconcileReqName:
+		result = rs.reconciler.start()
+	case reconcileApplyReqName:
+		r := req.Req.(*reconcileResult)
+		result = utils.NewAsyncResultWithError(rs.reconciler.apply(r))
+	case graphReqName:
+		r := req.Req.(*graphReq)
+		g := rs.RegistryToken2ChannelGraph[rs.tokenKey(r.registry, r.tokenAddress)]
+		if g == nil {
+			result = utils.NewAsyncResultWithError(rerr.UnknownTokenAddress(r.tokenAddress.String()))
+		} else {
+			result = utils.NewAsyncResultWithError(nil)
+			result.Tag = g.Export(rs.Protocol)
+		}
 	default:
 		panic("unkown req")
 	}
@@ -13,13 +13,19 @@ import (
 
 	"bytes"
 	"encoding/binary"
+	"sort"
 
-	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"os"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/graph"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/rerr"
+	"github.com/SmartMeshFoundation/SmartRaiden/signer"
 	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
@@ -76,40 +82,112 @@ func (r *RaidenAPI) GetChannel(channelAddress common.Hash) (c *channeltype.Seria
 	return r.Raiden.db.GetChannelByAddress(channelAddress)
 }
 
+//tokenKey is RaidenService.tokenKey on tokens saved in db, for callers out of the main loop
+func (r *RaidenAPI) tokenKey(registry, token common.Address) (key models.RegistryToken, err error) {
+	key, _, err = r.tokenNetworkOf(registry, token)
+	return
+}
+
+//tokenNetworkOf is tokenKey with token network of the key
+func (r *RaidenAPI) tokenNetworkOf(registry, token common.Address) (key models.RegistryToken, tokenNetwork common.Address, err error) {
+	registryTokens, err := r.Raiden.db.GetRegistryTokens()
+	if err != nil {
+		return
+	}
+	registered := make(map[models.RegistryToken]common.Address)
+	for tn, rt := range registryTokens {
+		registered[rt] = tn
+	}
+	key = resolveTokenKey(r.Raiden.RegistryAddresses, registry, token, func(key models.RegistryToken) bool {
+		_, ok := registered[key]
+		return ok
+	})
+	tokenNetwork, ok := registered[key]
+	if !ok {
+		err = rerr.UnknownTokenAddress(key.String())
+	}
+	return
+}
+
+//getChannel returns channel with `partnerAddress` of `tokenAddress` on the token's first registry
+func (r *RaidenAPI) getChannel(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
+	key, err := r.tokenKey(utils.EmptyAddress, tokenAddress)
+	if err != nil {
+		return
+	}
+	return r.Raiden.db.GetRegistryChannel(key.Registry, tokenAddress, partnerAddress)
+}
+
 /*
 TokenAddressIfTokenRegistered return the channel manager address,If the token is registered then
 Also make sure that the channel manager is registered with the node.
 */
 func (r *RaidenAPI) TokenAddressIfTokenRegistered(tokenAddress common.Address) (mgrAddr common.Address, err error) {
-	mgrAddr, err = r.Raiden.Registry.TokenNetworkByToken(tokenAddress)
+	key, err := r.tokenKey(utils.EmptyAddress, tokenAddress)
 	if err != nil {
+		key.Registry = r.Raiden.RegistryAddress
+	}
+	registry := r.Raiden.Chain.Registry(key.Registry)
+	if registry == nil {
+		err = errors.New("registry unavailable")
 		return
 	}
-	return
+	return registry.TokenNetworkByToken(tokenAddress)
 }
 
 /*
-RegisterToken Will register the token at `token_address` with raiden. If it's already
+RegisterToken Will register the token at `token_address` with raiden on the default registry. If it's already
     registered, will throw an exception.
 */
 func (r *RaidenAPI) RegisterToken(tokenAddress common.Address) (mgrAddr common.Address, err error) {
-	mgrAddr, err = r.Raiden.Registry.TokenNetworkByToken(tokenAddress)
+	return r.RegisterTokenOnRegistry(r.Raiden.RegistryAddress, tokenAddress)
+}
+
+/*
+RegisterTokenOnRegistry registers the token at `tokenAddress` on `registryAddress`, which must be attached to this node.
+a token can be registered on more than one registry.
+*/
+func (r *RaidenAPI) RegisterTokenOnRegistry(registryAddress, tokenAddress common.Address) (mgrAddr common.Address, err error) {
+	if !r.Raiden.isOurRegistry(registryAddress) {
+		err = rerr.InvalidAddress(fmt.Sprintf("registry %s is not attached", registryAddress.String()))
+		return
+	}
+	registry := r.Raiden.Chain.Registry(registryAddress)
+	if registry == nil {
+		err = errors.New("registry unavailable")
+		return
+	}
+	mgrAddr, err = registry.TokenNetworkByToken(tokenAddress)
 	if err == nil && mgrAddr != utils.EmptyAddress {
 		err = errors.New("TokenNetworkAddres already registered")
 		return
 	}
 	//for non exist tokenaddress, ChannelManagerByToken will return a error: `abi : unmarshalling empty output`
 	if err == rerr.ErrNoTokenManager {
-		return r.Raiden.Registry.AddToken(tokenAddress)
+		return registry.AddToken(tokenAddress)
 	}
 	return
 }
 
+//Registries returns all registries attached, the first one is the default
+func (r *RaidenAPI) Registries() []common.Address {
+	return r.Raiden.RegistryAddresses
+}
+
 /*
 Open a channel with the peer at `partner_address`
-    with the given `token_address`.
+    with the given `token_address` on the token's first registry.
 */
 func (r *RaidenAPI) Open(tokenAddress, partnerAddress common.Address, settleTimeout, revealTimeout int, deposit *big.Int) (ch *channeltype.Serialization, err error) {
+	return r.OpenOnRegistry(utils.EmptyAddress, tokenAddress, partnerAddress, settleTimeout, revealTimeout, deposit)
+}
+
+//OpenOnRegistry opens a channel of `tokenAddress` registered on `registryAddress`, see Open
+func (r *RaidenAPI) OpenOnRegistry(registryAddress, tokenAddress, partnerAddress common.Address, settleTimeout, revealTimeout int, deposit *big.Int) (ch *channeltype.Serialization, err error) {
+	key, err := r.tokenKey(registryAddress, tokenAddress)
+	if err != nil {
+		return
+	}
 	if revealTimeout <= 0 {
 		revealTimeout = r.Raiden.Config.RevealTimeout
 	}
@@ -123,20 +201,20 @@ func (r *RaidenAPI) Open(tokenAddress, partnerAddress common.Address, settleTime
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	r.Raiden.db.RegisterNewChannellCallback(func(c *channeltype.Serialization) (remove bool) {
-		if c.TokenAddress() == tokenAddress && c.PartnerAddress() == partnerAddress {
+		if c.TokenAddress() == tokenAddress && c.PartnerAddress() == partnerAddress && c.RegistryAddress == key.Registry {
 			wg.Done()
 			return true
 		}
 		return false
 	})
-	result := r.Raiden.newChannelClient(tokenAddress, partnerAddress, settleTimeout, deposit)
+	result := r.Raiden.newChannelClient(key.Registry, tokenAddress, partnerAddress, settleTimeout, deposit)
 	err = <-result.Result
 	if err != nil {
 		return
 	}
 	//wait
 	wg.Wait()
-	ch, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	ch, err = r.Raiden.db.GetRegistryChannel(key.Registry, tokenAddress, partnerAddress)
 	if err == nil {
 		//must be success, no need to wait event and register a callback
 		ch.OurContractBalance = deposit
@@ -162,10 +240,20 @@ Deposit `amount` in the channel with the peer at `partner_address` and the
         execution.
 */
 func (r *RaidenAPI) Deposit(tokenAddress, partnerAddress common.Address, amount *big.Int, pollTimeout time.Duration) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
 	if err != nil {
 		return
 	}
+	return r.DepositChannel(c.ChannelIdentifier.ChannelIdentifier, amount, pollTimeout)
+}
+
+//DepositChannel is Deposit on channel `channelIdentifier`
+func (r *RaidenAPI) DepositChannel(channelIdentifier common.Hash, amount *big.Int, pollTimeout time.Duration) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
+	tokenAddress := c.TokenAddress()
 	token, err := r.Raiden.Chain.Token(tokenAddress)
 	if err != nil {
 		return
@@ -315,9 +403,42 @@ func (r *RaidenAPI) GetTokenTokenNetorks() (tokens models.AddressMap) {
 	return
 }
 
-//TransferAndWait Do a transfer with `target` with the given `amount` of `token_address`.
+//TokenInfo is a token and the registry it's registered on
+type TokenInfo struct {
+	TokenAddress        common.Address `json:"token_address"`
+	TokenNetworkAddress common.Address `json:"token_network_address"`
+	RegistryAddress     common.Address `json:"registry_address"`
+}
+
+//GetRegistryTokens returns tokens registered on `registry`, tokens of all registries if it's empty
+func (r *RaidenAPI) GetRegistryTokens(registry common.Address) (tokens []*TokenInfo, err error) {
+	registryTokens, err := r.Raiden.db.GetRegistryTokens()
+	if err != nil {
+		return
+	}
+	for tn, rt := range registryTokens {
+		if registry != utils.EmptyAddress && rt.Registry != registry {
+			continue
+		}
+		tokens = append(tokens, &TokenInfo{rt.Token, tn, rt.Registry})
+	}
+	sort.Slice(tokens, func(i, j int) bool {
+		if tokens[i].TokenAddress != tokens[j].TokenAddress {
+			return bytes.Compare(tokens[i].TokenAddress[:], tokens[j].TokenAddress[:]) < 0
+		}
+		return bytes.Compare(tokens[i].RegistryAddress[:], tokens[j].RegistryAddress[:]) < 0
+	})
+	return
+}
+
+//TransferAndWait Do a transfer with `target` with the given `amount` of `token_address` on the token's first registry.
 func (r *RaidenAPI) TransferAndWait(token common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, timeout time.Duration, isDirectTransfer bool) (err error) {
-	result, err := r.transferAsync(token, amount, fee, target, lockSecretHash, isDirectTransfer)
+	return r.TransferOnRegistry(utils.EmptyAddress, token, amount, fee, target, lockSecretHash, timeout, isDirectTransfer)
+}
+
+//TransferOnRegistry is TransferAndWait of `token` registered on `registry`
+func (r *RaidenAPI) TransferOnRegistry(registry, token common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, timeout time.Duration, isDirectTransfer bool) (err error) {
+	result, err := r.transferAsync(registry, token, amount, fee, target, lockSecretHash, isDirectTransfer)
 	if err != nil {
 		return err
 	}
@@ -340,22 +461,15 @@ func (r *RaidenAPI) Transfer(token common.Address, amount *big.Int, fee *big.Int
 }
 
 //transferAsync
-func (r *RaidenAPI) transferAsync(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, isDirectTransfer bool) (result *utils.AsyncResult, err error) {
-	tokens := r.Tokens()
-	found := false
-	for _, t := range tokens {
-		if t == tokenAddress {
-			found = true
-			break
-		}
-	}
-	if !found {
+func (r *RaidenAPI) transferAsync(registry, tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, isDirectTransfer bool) (result *utils.AsyncResult, err error) {
+	key, err := r.tokenKey(registry, tokenAddress)
+	if err != nil {
 		err = errors.New("token not exist")
 		return
 	}
 	if isDirectTransfer {
 		var c *channeltype.Serialization
-		c, err = r.Raiden.db.GetChannel(tokenAddress, target)
+		c, err = r.Raiden.db.GetRegistryChannel(key.Registry, tokenAddress, target)
 		if err != nil {
 			err = fmt.Errorf("no direct channel token:%s,partner:%s", tokenAddress.String(), target.String())
 			return
@@ -371,13 +485,22 @@ func (r *RaidenAPI) transferAsync(tokenAddress common.Address, amount *big.Int,
 	}
 	log.Debug(fmt.Sprintf("initiating transfer initiator=%s target=%s token=%s amount=%d lockSecretHash=%s",
 		r.Raiden.NodeAddress.String(), target.String(), tokenAddress.String(), amount, lockSecretHash.String()))
-	result = r.Raiden.transferAsyncClient(tokenAddress, amount, fee, target, lockSecretHash, isDirectTransfer)
+	result = r.Raiden.transferAsyncClient(key.Registry, tokenAddress, amount, fee, target, lockSecretHash, isDirectTransfer)
 	return
 }
 
 //Close a channel opened with `partner_address` for the given `token_address`. return when state has been updated to database
 func (r *RaidenAPI) Close(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.CloseChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//CloseChannel is Close on channel `channelIdentifier`
+func (r *RaidenAPI) CloseChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
 	if err != nil {
 		return
 	}
@@ -405,7 +528,19 @@ func (r *RaidenAPI) Close(tokenAddress, partnerAddress common.Address) (c *chann
 
 //Settle a closed channel with `partner_address` for the given `token_address`.return when state has been updated to database
 func (r *RaidenAPI) Settle(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.SettleChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//SettleChannel is Settle on channel `channelIdentifier`
+func (r *RaidenAPI) SettleChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State == channeltype.StateOpened {
 		err = rerr.InvalidState("channel is still open")
 		return
@@ -435,7 +570,19 @@ func (r *RaidenAPI) Settle(tokenAddress, partnerAddress common.Address) (c *chan
 
 //CooperativeSettle a channel opened with `partner_address` for the given `token_address`. return when state has been updated to database
 func (r *RaidenAPI) CooperativeSettle(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.CooperativeSettleChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//CooperativeSettleChannel is CooperativeSettle on channel `channelIdentifier`
+func (r *RaidenAPI) CooperativeSettleChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StateOpened && c.State != channeltype.StatePrepareForCooperativeSettle {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -453,7 +600,19 @@ func (r *RaidenAPI) CooperativeSettle(tokenAddress, partnerAddress common.Addres
 
 //PrepareForCooperativeSettle  mark a channel prepared for settle,  return when state has been updated to database
 func (r *RaidenAPI) PrepareForCooperativeSettle(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.PrepareForCooperativeSettleChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//PrepareForCooperativeSettleChannel is PrepareForCooperativeSettle on channel `channelIdentifier`
+func (r *RaidenAPI) PrepareForCooperativeSettleChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StateOpened {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -471,7 +630,19 @@ func (r *RaidenAPI) PrepareForCooperativeSettle(tokenAddress, partnerAddress com
 
 //CancelPrepareForCooperativeSettle  cancel a mark. return when state has been updated to database
 func (r *RaidenAPI) CancelPrepareForCooperativeSettle(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.CancelPrepareForCooperativeSettleChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//CancelPrepareForCooperativeSettleChannel is CancelPrepareForCooperativeSettle on channel `channelIdentifier`
+func (r *RaidenAPI) CancelPrepareForCooperativeSettleChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StatePrepareForCooperativeSettle {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -489,7 +660,19 @@ func (r *RaidenAPI) CancelPrepareForCooperativeSettle(tokenAddress, partnerAddre
 
 //Withdraw on a channel opened with `partner_address` for the given `token_address`. return when state has been updated to database
 func (r *RaidenAPI) Withdraw(tokenAddress, partnerAddress common.Address, amount *big.Int) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.WithdrawChannel(c.ChannelIdentifier.ChannelIdentifier, amount)
+}
+
+//WithdrawChannel is Withdraw on channel `channelIdentifier`
+func (r *RaidenAPI) WithdrawChannel(channelIdentifier common.Hash, amount *big.Int) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StateOpened && c.State != channeltype.StatePrepareForWithdraw {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -511,7 +694,19 @@ func (r *RaidenAPI) Withdraw(tokenAddress, partnerAddress common.Address, amount
 
 //PrepareForWithdraw  mark a channel prepared for withdraw,  return when state has been updated to database
 func (r *RaidenAPI) PrepareForWithdraw(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.PrepareForWithdrawChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//PrepareForWithdrawChannel is PrepareForWithdraw on channel `channelIdentifier`
+func (r *RaidenAPI) PrepareForWithdrawChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StateOpened {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -529,7 +724,19 @@ func (r *RaidenAPI) PrepareForWithdraw(tokenAddress, partnerAddress common.Addre
 
 //CancelPrepareForWithdraw  cancel a mark. return when state has been updated to database
 func (r *RaidenAPI) CancelPrepareForWithdraw(tokenAddress, partnerAddress common.Address) (c *channeltype.Serialization, err error) {
-	c, err = r.Raiden.db.GetChannel(tokenAddress, partnerAddress)
+	c, err = r.getChannel(tokenAddress, partnerAddress)
+	if err != nil {
+		return
+	}
+	return r.CancelPrepareForWithdrawChannel(c.ChannelIdentifier.ChannelIdentifier)
+}
+
+//CancelPrepareForWithdrawChannel is CancelPrepareForWithdraw on channel `channelIdentifier`
+func (r *RaidenAPI) CancelPrepareForWithdrawChannel(channelIdentifier common.Hash) (c *channeltype.Serialization, err error) {
+	c, err = r.Raiden.db.GetChannelByAddress(channelIdentifier)
+	if err != nil {
+		return
+	}
 	if c.State != channeltype.StatePrepareForWithdraw {
 		err = rerr.InvalidState("channel must be  open")
 		return
@@ -545,152 +752,58 @@ func (r *RaidenAPI) CancelPrepareForWithdraw(tokenAddress, partnerAddress common
 	return r.Raiden.db.GetChannelByAddress(c.ChannelIdentifier.ChannelIdentifier)
 }
 
-//GetTokenNetworkEvents return events about this token
-func (r *RaidenAPI) GetTokenNetworkEvents(tokenAddress common.Address, fromBlock, toBlock int64) (data []interface{}, err error) {
-	//type eventData struct {
-	//	/*
-	//				 {
-	//		        "event_type": "ChannelNew",
-	//		        "settle_timeout": 10,
-	//		        "netting_channel": "0xc0ea08a2d404d3172d2add29a45be56da40e2949",
-	//		        "participant1": "0x4894a542053248e0c504e3def2048c08f73e1ca6",
-	//		        "participant2": "0x356857Cd22CBEFccDa4e96AF13b408623473237A"
-	//		    }
-	//	*/
-	//	EventType      string `json:"event_type"`
-	//	SettleTimeout  int    `json:"settle_timeout"`
-	//	NettingChannel string `json:"netting_channel"`
-	//	Participant1   string `json:"participant1"`
-	//	Participant2   string `json:"participant2"`
-	//	TokenAddress   string `json:"token_address"`
-	//}
-	//tokens, err := r.Raiden.db.GetAllTokens()
-	//if err != nil {
-	//	return
-	//}
-	//for t, manager := range tokens {
-	//	if tokenAddress == utils.EmptyAddress || t == tokenAddress {
-	//		events, err := r.Raiden.BlockChainEvents.GetAllChannelManagerEvents(manager, fromBlock, toBlock)
-	//		if err != nil {
-	//			return nil, err
-	//		}
-	//		for _, e := range events {
-	//			e2 := e.(*blockchain.EventChannelOpen)
-	//			ed := &eventData{
-	//				EventType:      e2.EventName,
-	//				SettleTimeout:  e2.SettleTimeout,
-	//				NettingChannel: e2.NettingChannelAddress.String(),
-	//				Participant1:   e2.Participant1.String(),
-	//				Participant2:   e2.Participant2.String(),
-	//				TokenAddress:   t.String(),
-	//			}
-	//			data = append(data, ed)
-	//		}
-	//	}
-	//}
-	return
+/*
+GetTokenNetworkEvents returns events of token network of `tokenAddress` on `registry` selected by `f`, from the local index of contract events.
+empty `registry` means the token's first registry.
+*/
+func (r *RaidenAPI) GetTokenNetworkEvents(registry, tokenAddress common.Address, f *models.ContractEventFilter) (events []*models.ContractEvent, err error) {
+	_, tokenNetwork, err := r.tokenNetworkOf(registry, tokenAddress)
+	if err != nil {
+		return
+	}
+	nf := *f
+	nf.Contract = tokenNetwork
+	return r.Raiden.db.FindContractEvents(&nf)
 }
 
-//GetNetworkEvents all raiden events
-func (r *RaidenAPI) GetNetworkEvents(fromBlock, toBlock int64) ([]interface{}, error) {
-	//type eventData struct {
-	//	/*
-	//				 "event_type": "TokenAdded",
-	//		        "token_address": "0xea674fdde714fd979de3edf0f56aa9716b898ec8",
-	//		        "channel_manager_address": "0xc0ea08a2d404d3172d2add29a45be56da40e2949"
-	//	*/
-	//	EventType             string `json:"event_type"`
-	//	TokenAddress          string `json:"token_address"`
-	//	ChannelManagerAddress string `json:"channel_manager_address"`
-	//}
-	//events, err := r.Raiden.BlockChainEvents.GetAllRegistryEvents(r.Raiden.RegistryAddress, fromBlock, toBlock)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//var data []interface{}
-	//for _, e := range events {
-	//	e2 := e.(*blockchain.EventTokenNetworkCreated)
-	//	ed := &eventData{
-	//		EventType:             e2.EventName,
-	//		TokenAddress:          e2.TokenAddress.String(),
-	//		ChannelManagerAddress: e2.TokenNetworkAddress.String(),
-	//	}
-	//	data = append(data, ed)
-	//}
-	return nil, nil
+/*
+GetNetworkEvents returns events of registry and secret registry selected by `f`, from the local index of contract events.
+*/
+func (r *RaidenAPI) GetNetworkEvents(f *models.ContractEventFilter) ([]*models.ContractEvent, error) {
+	nf := *f
+	if len(nf.EventTypes) == 0 {
+		nf.EventTypes = []string{params.NameTokenNetworkCreated, params.NameSecretRevealed}
+	}
+	for _, t := range nf.EventTypes {
+		if t != params.NameTokenNetworkCreated && t != params.NameSecretRevealed {
+			return nil, fmt.Errorf("%s is not an event of registry", t)
+		}
+	}
+	return r.Raiden.db.FindContractEvents(&nf)
 }
 
-//GetChannelEvents events of this channel
-func (r *RaidenAPI) GetChannelEvents(channelAddress common.Hash, fromBlock, toBlock int64) (data []transfer.Event, err error) {
-
-	//var events []transfer.Event
-	//events, err = r.Raiden.BlockChainEvents.GetAllNettingChannelEvents(channelAddress, fromBlock, toBlock)
-	//if err != nil {
-	//	return
-	//}
-	//for _, e := range events {
-	//	m := make(map[string]interface{})
-	//	switch e2 := e.(type) {
-	//	case *blockchain.EventChannelNewBalance:
-	//		m["event_type"] = e2.EventName
-	//		m["participant"] = e2.ParticipantAddress.String()
-	//		m["balance"] = e2.Balance
-	//		m["block_number"] = e2.BlockNumber
-	//		data = append(data, m)
-	//	case *blockchain.EventChannelClosed:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["closing_address"] = e2.ClosingAddress.String()
-	//		data = append(data, m)
-	//	case *blockchain.EventChannelSettled:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["block_number"] = e2.BlockNumber
-	//		data = append(data, m)
-	//	case *blockchain.EventSecretRevealed:
-	//		m["event_type"] = e2.EventName
-	//		m["netting_channel_address"] = e2.ContractAddress.String()
-	//		m["secret"] = e2.Secret.String()
-	//		data = append(data, m)
-	//		//case *blockchain.EventNonClosingBalanceProofUpdated:
-	//		//	m["event_type"] = e2.EventName
-	//		//	m["token_address"] = t.String()
-	//		//	m["channel_manager_address"] = graph.TokenAddress.String()
-	//	}
-	//
-	//}
-	//
-	//var raidenEvents []*models.InternalEvent
-	//raidenEvents, err = r.Raiden.db.GetEventsInBlockRange(fromBlock, toBlock)
-	//if err != nil {
-	//	return
-	//}
-	////Here choose which raiden internal events we want to expose to the end user
-	//for _, ev := range raidenEvents {
-	//	m := make(map[string]interface{})
-	//	switch e2 := ev.EventObject.(type) {
-	//	case *transfer.EventTransferSentSuccess:
-	//		m["event_type"] = "EventTransferSentSuccess"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["amount"] = e2.Amount
-	//		m["target"] = e2.Target
-	//		data = append(data, m)
-	//	case *transfer.EventTransferSentFailed:
-	//		m["event_type"] = "EventTransferSentFailed"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["reason"] = e2.Reason
-	//		data = append(data, m)
-	//	case *transfer.EventTransferReceivedSuccess:
-	//		m["event_type"] = "EventTransferReceivedSuccess"
-	//		m["identifier"] = e2.LockSecretHash
-	//		m["block_number"] = ev.BlockNumber
-	//		m["amount"] = e2.Amount
-	//		m["initiator"] = e2.Initiator.String()
-	//		data = append(data, m)
-	//	}
-	//}
+/*
+GetChannelEvents returns events of this channel selected by `f`, from the local index of contract events,
+followed by the outcome of every lock unlocked on chain.
+*/
+func (r *RaidenAPI) GetChannelEvents(channelAddress common.Hash, f *models.ContractEventFilter) (data []transfer.Event, err error) {
+	nf := *f
+	nf.ChannelIdentifier = channelAddress
+	events, err := r.Raiden.db.FindContractEvents(&nf)
+	if err != nil {
+		return
+	}
+	for _, e := range events {
+		data = append(data, e)
+	}
+	//unlock outcomes have no block number, so they are always returned
+	outcomes, err := r.Raiden.db.GetUnlockOutcomes(channelAddress)
+	if err != nil {
+		return
+	}
+	for _, o := range outcomes {
+		data = append(data, &EventUnlockOutcomeWrapper{*o, "EventUnlockOutcome"})
+	}
 	return
 }
 
@@ -708,6 +821,142 @@ func (r *RaidenAPI) GetReceivedTransfers(from, to int64) ([]*models.ReceivedTran
 	return r.Raiden.db.GetReceivedTransferInBlockRange(from, to)
 }
 
+/*
+FindSentTransfers returns a page of sent transfers selected by `f`,
+and the cursor of the next page, which is empty if it's the last page.
+*/
+func (r *RaidenAPI) FindSentTransfers(f *models.TransferFilter) ([]*models.SentTransfer, string, error) {
+	return r.Raiden.db.FindSentTransfers(f)
+}
+
+/*
+FindReceivedTransfers returns a page of received transfers selected by `f`,
+and the cursor of the next page, which is empty if it's the last page.
+*/
+func (r *RaidenAPI) FindReceivedTransfers(f *models.TransferFilter) ([]*models.ReceivedTransfer, string, error) {
+	return r.Raiden.db.FindReceivedTransfers(f)
+}
+
+/*
+CreateAPIToken create a restful api token named `name` which can call api of `scopes`.
+the token is returned only once, only its hash is saved.
+*/
+func (r *RaidenAPI) CreateAPIToken(name string, scopes []string) (token string, err error) {
+	if len(name) == 0 || len(scopes) == 0 {
+		return "", errors.New("name and scopes are required")
+	}
+	for _, s := range scopes {
+		if !models.IsValidAPIScope(s) {
+			return "", fmt.Errorf("unknown scope %s", s)
+		}
+	}
+	buf := make([]byte, 32)
+	_, err = rand.Read(buf)
+	if err != nil {
+		return
+	}
+	token = hex.EncodeToString(buf)
+	_, err = r.Raiden.db.NewAPIToken(name, token, scopes)
+	return
+}
+
+//GetAPITokens returns all restful api tokens, without the token itself
+func (r *RaidenAPI) GetAPITokens() ([]*models.APIToken, error) {
+	return r.Raiden.db.GetAllAPITokens()
+}
+
+//RemoveAPIToken revoke restful api token named `name`
+func (r *RaidenAPI) RemoveAPIToken(name string) error {
+	return r.Raiden.db.RemoveAPIToken(name)
+}
+
+//CheckAPIToken returns the api token of `token`, error if it's not a valid token
+func (r *RaidenAPI) CheckAPIToken(token string) (*models.APIToken, error) {
+	return r.Raiden.db.GetAPITokenByToken(token)
+}
+
+/*
+Backup writes a consistent snapshot of db into backup directory next to db while running,
+returns path of the snapshot.
+*/
+func (r *RaidenAPI) Backup() (path string, err error) {
+	db := r.Raiden.db
+	err = os.MkdirAll(db.BackupDir(), 0700)
+	if err != nil {
+		return
+	}
+	path = db.NewBackupPath()
+	err = db.Backup(path)
+	if err != nil {
+		err = fmt.Errorf("backup db to %s err %s", path, err)
+		return
+	}
+	log.Info(fmt.Sprintf("db backup to %s", path))
+	return
+}
+
+//StorageStats returns size and number of records of db
+func (r *RaidenAPI) StorageStats() (*models.StorageStats, error) {
+	return r.Raiden.db.Stats()
+}
+
+//GasPriceStatus is gas price config and gas spent on every token
+type GasPriceStatus struct {
+	Config *params.GasPriceConfig `json:"config"`
+	//Spent is wei spent on gas of transactions of every registered token
+	Spent map[common.Address]*big.Int `json:"spent"`
+}
+
+//GetGasPrice returns gas price config and gas spent on every token
+func (r *RaidenAPI) GetGasPrice() (s *GasPriceStatus, err error) {
+	g := r.Raiden.Chain.GasPricer
+	tokens, err := r.Raiden.db.GetAllTokens()
+	if err != nil {
+		return
+	}
+	s = &GasPriceStatus{
+		Config: g.Config(),
+		Spent:  make(map[common.Address]*big.Int),
+	}
+	for token := range tokens {
+		s.Spent[token] = g.Spent(token)
+	}
+	return
+}
+
+//SetGasPrice validates and saves gas price config `c`, which is used by transactions sent after
+func (r *RaidenAPI) SetGasPrice(c *params.GasPriceConfig) error {
+	return r.Raiden.Chain.GasPricer.SetConfig(c)
+}
+
+//ReconcileReport returns report of the last reconciliation of channels against blockchain, nil if never reconciled
+func (r *RaidenAPI) ReconcileReport() *models.ReconcileReport {
+	return r.Raiden.reconciler.Report()
+}
+
+//Reconcile checks every channel against blockchain again, repairs what it can and blocks channels in conflict
+func (r *RaidenAPI) Reconcile() (*models.ReconcileReport, error) {
+	result := r.Raiden.reconcileClient()
+	err := <-result.Result
+	if err != nil {
+		return nil, err
+	}
+	return r.Raiden.reconciler.Report(), nil
+}
+
+//ChannelGraph returns a snapshot of channel graph of token on `registry`, with online status of nodes and statistics of the network
+func (r *RaidenAPI) ChannelGraph(registry, tokenAddress common.Address) (*graph.Export, error) {
+	result := r.Raiden.graphClient(registry, tokenAddress)
+	err := <-result.Result
+	if err != nil {
+		return nil, err
+	}
+	//statistics are expensive for a large network, don't block the main loop with them
+	e := result.Tag.(*graph.Export)
+	e.CalcStats()
+	return e, nil
+}
+
 //Stop stop for mobile app
 func (r *RaidenAPI) Stop() {
 	log.Info("calling api stop..")
@@ -781,7 +1030,7 @@ func (r *RaidenAPI) ChannelInformationFor3rdParty(channelAddr common.Hash, third
 	c3.UpdateTransfer.Locksroot = c.PartnerBalanceProof.LocksRoot.String()
 	c3.UpdateTransfer.ExtraHash = c.PartnerBalanceProof.MessageHash.String()
 	c3.UpdateTransfer.ClosingSignature = common.Bytes2Hex(c.PartnerBalanceProof.Signature)
-	sig, err = signFor3rd(c, thirdAddr, r.Raiden.PrivateKey)
+	sig, err = signFor3rd(c, thirdAddr, r.Raiden.Signer)
 	if err != nil {
 		return
 	}
@@ -806,7 +1055,7 @@ func (r *RaidenAPI) ChannelInformationFor3rdParty(channelAddr common.Hash, third
 }
 
 //make sure PartnerBalanceProof is not nil
-func signFor3rd(c *channeltype.Serialization, thirdAddr common.Address, privkey *ecdsa.PrivateKey) (sig []byte, err error) {
+func signFor3rd(c *channeltype.Serialization, thirdAddr common.Address, s signer.Signer) (sig []byte, err error) {
 	if c.PartnerBalanceProof == nil {
 		log.Error(fmt.Sprintf("PartnerBalanceProof is nil,must ber a error"))
 		return nil, errors.New("empty PartnerBalanceProof")
@@ -823,7 +1072,7 @@ func signFor3rd(c *channeltype.Serialization, thirdAddr common.Address, privkey
 		log.Error(fmt.Sprintf("buf write error %s", err))
 	}
 	dataToSign := buf.Bytes()
-	return utils.SignData(privkey, dataToSign)
+	return s.SignData(dataToSign)
 }
 
 //EventTransferSentSuccessWrapper wrapper
@@ -840,6 +1089,12 @@ type EventTransferSentFailedWrapper struct {
 	Name        string
 }
 
+//EventUnlockOutcomeWrapper wrapper
+type EventUnlockOutcomeWrapper struct {
+	channeltype.UnlockOutcome
+	Name string
+}
+
 //EventEventTransferReceivedSuccessWrapper wrapper
 type EventEventTransferReceivedSuccessWrapper struct {
 	transfer.EventTransferReceivedSuccess
@@ -15,6 +15,7 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/channel"
 	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/fatedier/frp/src/utils/log"
@@ -25,7 +26,7 @@ var big1 = big.NewInt(1)
 
 //a valid channel address onchain
 func getAChannel(api *RaidenAPI) common.Hash {
-	for _, g := range api.Raiden.Token2ChannelGraph {
+	for _, g := range api.Raiden.RegistryToken2ChannelGraph {
 		for addr := range g.ChannelAddress2Channel {
 			return addr
 		}
@@ -33,8 +34,8 @@ func getAChannel(api *RaidenAPI) common.Hash {
 	panic("no channel")
 }
 func getAToken(api *RaidenAPI) common.Address {
-	for t := range api.Raiden.Token2ChannelGraph {
-		return t
+	for key := range api.Raiden.RegistryToken2ChannelGraph {
+		return key.Token
 	}
 	panic("no token")
 }
@@ -54,7 +55,7 @@ func TestSwapKeyAsMapKey(t *testing.T) {
 }
 
 func testRaidenAPIGetNetworkEvents(t *testing.T, api *RaidenAPI) {
-	events, err := api.GetNetworkEvents(-1, -1)
+	events, err := api.GetNetworkEvents(&models.ContractEventFilter{})
 	if err != nil {
 		t.Error(err)
 		return
@@ -63,7 +64,7 @@ func testRaidenAPIGetNetworkEvents(t *testing.T, api *RaidenAPI) {
 	wg.Add(repeatCount)
 	for i := 0; i < repeatCount; i++ {
 		go func() {
-			ev2, err := api.GetNetworkEvents(-1, -1)
+			ev2, err := api.GetNetworkEvents(&models.ContractEventFilter{})
 			if err != nil {
 				t.Error(err)
 			}
@@ -78,7 +79,7 @@ func testRaidenAPIGetNetworkEvents(t *testing.T, api *RaidenAPI) {
 
 func testRaidenAPIGetChannelEvents(t *testing.T, api *RaidenAPI) {
 	addr := getAChannel(api)
-	events, err := api.GetChannelEvents(addr, -1, -1)
+	events, err := api.GetChannelEvents(addr, &models.ContractEventFilter{})
 	if err != nil {
 		t.Error(err)
 		return
@@ -87,7 +88,7 @@ func testRaidenAPIGetChannelEvents(t *testing.T, api *RaidenAPI) {
 	wg.Add(repeatCount)
 	for i := 0; i < repeatCount; i++ {
 		go func() {
-			ev2, err := api.GetChannelEvents(addr, -1, -1)
+			ev2, err := api.GetChannelEvents(addr, &models.ContractEventFilter{})
 			if err != nil {
 				t.Error(err)
 			}
@@ -101,7 +102,7 @@ func testRaidenAPIGetChannelEvents(t *testing.T, api *RaidenAPI) {
 }
 func testGetTokenNetworkEvents(t *testing.T, api *RaidenAPI) {
 	addr := getAToken(api)
-	events, err := api.GetTokenNetworkEvents(addr, -1, -1)
+	events, err := api.GetTokenNetworkEvents(utils.EmptyAddress, addr, &models.ContractEventFilter{})
 	if err != nil {
 		t.Error(err)
 		return
@@ -110,7 +111,7 @@ func testGetTokenNetworkEvents(t *testing.T, api *RaidenAPI) {
 	wg.Add(repeatCount)
 	for i := 0; i < repeatCount; i++ {
 		go func() {
-			ev2, err := api.GetTokenNetworkEvents(addr, -1, -1)
+			ev2, err := api.GetTokenNetworkEvents(utils.EmptyAddress, addr, &models.ContractEventFilter{})
 			if err != nil {
 				t.Error(err)
 			}
@@ -192,7 +193,7 @@ func TestRaidenAPICloseAndSettle(t *testing.T) {
 }
 
 func findAValidChannel(ra, rb *RaidenAPI) (addr common.Hash, money *big.Int) {
-	for _, g := range ra.Raiden.Token2ChannelGraph {
+	for _, g := range ra.Raiden.RegistryToken2ChannelGraph {
 		c := g.GetPartenerAddress2Channel(rb.Raiden.NodeAddress)
 		if c != nil && c.Balance().Cmp(big.NewInt(10)) > 0 && c.State == channeltype.StateOpened {
 			return c.ChannelIdentifier.ChannelIdentifier, c.Balance()
@@ -211,7 +212,7 @@ func findAllCanTransferChannel(ra, rb, rc *RaidenAPI) map[common.Hash]common.Add
 		rc.Raiden.NodeAddress: true,
 	}
 	m := make(map[common.Hash]common.Address)
-	for _, g := range ra.Raiden.Token2ChannelGraph {
+	for _, g := range ra.Raiden.RegistryToken2ChannelGraph {
 		for addr, c := range g.ChannelAddress2Channel {
 			if c.Balance().Cmp(utils.BigInt0) > 0 && c.State == channeltype.StateOpened && allAddresses[c.PartnerState.Address] {
 				if m[addr] == utils.EmptyAddress {
@@ -220,7 +221,7 @@ func findAllCanTransferChannel(ra, rb, rc *RaidenAPI) map[common.Hash]common.Add
 			}
 		}
 	}
-	for _, g := range rb.Raiden.Token2ChannelGraph {
+	for _, g := range rb.Raiden.RegistryToken2ChannelGraph {
 		for addr, c := range g.ChannelAddress2Channel {
 			if c.Balance().Cmp(utils.BigInt0) > 0 && c.State == channeltype.StateOpened && allAddresses[c.PartnerState.Address] {
 				if m[addr] == utils.EmptyAddress {
@@ -0,0 +1,503 @@
+package smartraiden
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/mediatedtransfer"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//errReconcileRunning a reconciliation is requested when another one is running
+var errReconcileRunning = errors.New("reconciliation is running")
+
+/*
+reconciler compares every channel in db with `GetChannelInfo` and `GetChannelParticipantInfo` on chain,
+once after all events happened when this node was offline are handled, and again when user requests.
+partners in ChannelOpened events with us on every token network are checked too, in case a channel with us is missed.
+events of channels closed, settled, withdrawn or opened without our knowledge are replayed from blockchain,
+events can duplicate, so replaying is safe. deposits behind chain are taken from chain.
+anything else can't be repaired safely, it's reported as a conflict and new transfers on the channel are blocked.
+chain is read without blocking the main loop, channels are only changed in the main loop.
+*/
+type reconciler struct {
+	raiden  *RaidenService
+	lock    sync.Mutex
+	started bool //the pass after startup is started
+	running bool
+	report  *models.ReconcileReport
+}
+
+func newReconciler(raiden *RaidenService) *reconciler {
+	return &reconciler{raiden: raiden}
+}
+
+//channelFacts is what's compared of a channel, in db or on chain
+type channelFacts struct {
+	ChannelIdentifier common.Hash
+	OpenBlockNumber   int64
+	State             channeltype.State //only opened, closed or settled
+	OurDeposit        *big.Int
+	PartnerDeposit    *big.Int
+	OurNonce          int64
+	PartnerNonce      int64
+}
+
+func localChannelFacts(ch *channel.Channel) *channelFacts {
+	state := channeltype.State(channeltype.StateOpened)
+	switch ch.State {
+	case channeltype.StateClosed, channeltype.StateBalanceProofUpdated, channeltype.StateSettling:
+		state = channeltype.StateClosed
+	case channeltype.StateSettled:
+		state = channeltype.StateSettled
+	}
+	return &channelFacts{
+		ChannelIdentifier: ch.ChannelIdentifier.ChannelIdentifier,
+		OpenBlockNumber:   ch.ChannelIdentifier.OpenBlockNumber,
+		State:             state,
+		OurDeposit:        new(big.Int).Set(ch.OurState.ContractBalance),
+		PartnerDeposit:    new(big.Int).Set(ch.PartnerState.ContractBalance),
+		OurNonce:          balanceProofNonce(ch.OurState.BalanceProofState),
+		PartnerNonce:      balanceProofNonce(ch.PartnerState.BalanceProofState),
+	}
+}
+
+//chainChannelFacts returns channel between `us` and `partner` on chain, deposits and nonces are nil and 0 if it's settled
+func chainChannelFacts(tokenNetwork *rpc.TokenNetworkProxy, us, partner common.Address) (f *channelFacts, err error) {
+	id, _, openBlockNumber, state, _, err := tokenNetwork.GetChannelInfo(us, partner)
+	if err != nil {
+		return
+	}
+	f = &channelFacts{
+		ChannelIdentifier: id,
+		OpenBlockNumber:   int64(openBlockNumber),
+		State:             channeltype.StateSettled,
+	}
+	switch state {
+	case contracts.ChannelStateOpened:
+		f.State = channeltype.StateOpened
+	case contracts.ChannelStateClosed:
+		f.State = channeltype.StateClosed
+	default:
+		return
+	}
+	var ourNonce, partnerNonce uint64
+	f.OurDeposit, _, ourNonce, err = tokenNetwork.GetChannelParticipantInfo(us, partner)
+	if err != nil {
+		return
+	}
+	f.PartnerDeposit, _, partnerNonce, err = tokenNetwork.GetChannelParticipantInfo(partner, us)
+	if err != nil {
+		return
+	}
+	f.OurNonce = int64(ourNonce)
+	f.PartnerNonce = int64(partnerNonce)
+	return
+}
+
+//needsReplay returns true if events of the channel are missed, `local` is nil if the channel is unknown in db
+func needsReplay(local, chain *channelFacts) bool {
+	if local == nil {
+		return chain.State != channeltype.StateSettled
+	}
+	return chain.ChannelIdentifier != local.ChannelIdentifier || chain.OpenBlockNumber != local.OpenBlockNumber || chain.State != local.State
+}
+
+/*
+compareChannel returns differences between channel in db and on chain, `local` is nil if the channel is unknown in db.
+differences repaired by replaying events are marked repaired, compare again after replaying to know whether they are.
+*/
+func compareChannel(local, chain *channelFacts) (ds []*models.ChannelDiscrepancy) {
+	add := func(field string, l, c interface{}, action string) {
+		ds = append(ds, &models.ChannelDiscrepancy{
+			Field:  field,
+			Local:  fmt.Sprintf("%v", l),
+			Chain:  fmt.Sprintf("%v", c),
+			Action: action,
+		})
+	}
+	if local == nil {
+		if chain.State != channeltype.StateSettled {
+			add("state", "none", chain.State, models.ReconcileRepaired)
+		}
+		return
+	}
+	if chain.State == channeltype.StateSettled || chain.ChannelIdentifier != local.ChannelIdentifier {
+		if local.State != channeltype.StateSettled {
+			add("state", local.State, channeltype.State(channeltype.StateSettled), models.ReconcileRepaired)
+		}
+		return
+	}
+	if chain.OpenBlockNumber != local.OpenBlockNumber {
+		//withdrawn
+		add("open_block_number", local.OpenBlockNumber, chain.OpenBlockNumber, models.ReconcileRepaired)
+		return
+	}
+	if chain.State != local.State {
+		action := models.ReconcileRepaired
+		if local.State == channeltype.StateClosed {
+			//a closed channel never opens again
+			action = models.ReconcileConflict
+		}
+		add("state", local.State, chain.State, action)
+	}
+	//deposits never decrease unless withdrawn
+	if c := chain.OurDeposit.Cmp(local.OurDeposit); c > 0 {
+		add("our_deposit", local.OurDeposit, chain.OurDeposit, models.ReconcileRepaired)
+	} else if c < 0 {
+		add("our_deposit", local.OurDeposit, chain.OurDeposit, models.ReconcileConflict)
+	}
+	if c := chain.PartnerDeposit.Cmp(local.PartnerDeposit); c > 0 {
+		add("partner_deposit", local.PartnerDeposit, chain.PartnerDeposit, models.ReconcileRepaired)
+	} else if c < 0 {
+		add("partner_deposit", local.PartnerDeposit, chain.PartnerDeposit, models.ReconcileConflict)
+	}
+	//a balance proof newer than ours on chain means we miss transfers, sending old ones may cheat partner
+	if chain.OurNonce > local.OurNonce {
+		add("our_nonce", local.OurNonce, chain.OurNonce, models.ReconcileConflict)
+	}
+	if chain.PartnerNonce > local.PartnerNonce {
+		add("partner_nonce", local.PartnerNonce, chain.PartnerNonce, models.ReconcileConflict)
+	}
+	return
+}
+
+//reconcileChannel is a channel with `partner` to check, `local` is nil if no channel with `partner` in db
+type reconcileChannel struct {
+	registry     common.Address
+	token        common.Address
+	partner      common.Address
+	tokenNetwork *rpc.TokenNetworkProxy
+	local        *channelFacts
+	chain        *channelFacts
+	err          error
+}
+
+//reconcileTokenNetwork is a token network to look for channels with us unknown in db
+type reconcileTokenNetwork struct {
+	registry     common.Address
+	token        common.Address
+	tokenNetwork *rpc.TokenNetworkProxy
+	partners     map[common.Address]bool //partners of channels in db
+}
+
+//reconcileResult is what's read from chain in a reconciliation pass
+type reconcileResult struct {
+	blockNumber   int64
+	tokenNetworks []*reconcileTokenNetwork
+	channels      []*reconcileChannel
+	replay        []mediatedtransfer.ContractStateChange
+	errors        []string
+}
+
+//shouldStart returns true if the pass after startup can start, all events happened when offline must have been handled
+func (r *reconciler) shouldStart() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	rs := r.raiden
+	return !r.started && !rs.BlockChainEvents.IsCatchingUp() && len(rs.BlockChainEvents.StateChangeChannel) == 0
+}
+
+//start a reconciliation pass, must run in the main loop
+func (r *reconciler) start() (result *utils.AsyncResult) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.running {
+		return utils.NewAsyncResultWithError(errReconcileRunning)
+	}
+	r.started = true
+	r.running = true
+	res := r.snapshot()
+	result = utils.NewAsyncResult()
+	go func() {
+		r.collect(res)
+		req := &apiReq{
+			ReqID:  utils.RandomString(10),
+			Name:   reconcileApplyReqName,
+			Req:    res,
+			result: make(chan *utils.AsyncResult, 1),
+		}
+		select {
+		case r.raiden.UserReqChan <- req:
+		case <-r.raiden.quitChan:
+			result.Result <- errors.New("raiden is stopped")
+			return
+		}
+		result.Result <- <-(<-req.result).Result
+	}()
+	return
+}
+
+//snapshot channels to check, must run in the main loop
+func (r *reconciler) snapshot() (res *reconcileResult) {
+	rs := r.raiden
+	res = &reconcileResult{blockNumber: rs.GetBlockNumber()}
+	for key, g := range rs.RegistryToken2ChannelGraph {
+		tokenNetwork, err := rs.Chain.TokenNetworkWithoutCheck(rs.RegistryToken2TokenNetwork[key])
+		if err != nil {
+			res.errors = append(res.errors, fmt.Sprintf("token %s: %s", key, err))
+			continue
+		}
+		tn := &reconcileTokenNetwork{
+			registry:     key.Registry,
+			token:        key.Token,
+			tokenNetwork: tokenNetwork,
+			partners:     make(map[common.Address]bool),
+		}
+		res.tokenNetworks = append(res.tokenNetworks, tn)
+		for _, ch := range g.ChannelAddress2Channel {
+			tn.partners[ch.PartnerState.Address] = true
+			res.channels = append(res.channels, &reconcileChannel{
+				registry:     key.Registry,
+				token:        key.Token,
+				partner:      ch.PartnerState.Address,
+				tokenNetwork: tokenNetwork,
+				local:        localChannelFacts(ch),
+			})
+		}
+	}
+	return
+}
+
+/*
+openedPartners returns everyone who ever opened a channel with us on `tokenNetwork`.
+participants of ChannelOpened are not indexed, so all of them are read and filtered here,
+it's two log queries for a token network instead of a GetChannelInfo for every node.
+*/
+func (r *reconciler) openedPartners(tokenNetwork common.Address) (partners map[common.Address]bool, err error) {
+	us := r.raiden.NodeAddress
+	partners = make(map[common.Address]bool)
+	add := func(p1, p2 common.Address) {
+		if p1 == us {
+			partners[p2] = true
+		} else if p2 == us {
+			partners[p1] = true
+		}
+	}
+	opened, err := r.raiden.BlockChainEvents.GetChannelNew(0, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, ev := range opened {
+		add(ev.Participant1, ev.Participant2)
+	}
+	openedAndDeposit, err := r.raiden.BlockChainEvents.GetChannelNewAndDeposit(0, tokenNetwork)
+	if err != nil {
+		return
+	}
+	for _, ev := range openedAndDeposit {
+		add(ev.Participant1, ev.Participant2)
+	}
+	return
+}
+
+//collect channels on chain and events to replay, it doesn't run in the main loop
+func (r *reconciler) collect(res *reconcileResult) {
+	rs := r.raiden
+	replayFrom := make(map[common.Address]int64) //token network => first block to replay
+	replayChannels := make(map[common.Hash]*reconcileChannel)
+	for _, tn := range res.tokenNetworks {
+		partners, err := r.openedPartners(tn.tokenNetwork.Address)
+		if err != nil {
+			res.errors = append(res.errors, fmt.Sprintf("channels opened on token %s: %s", tn.token.String(), err))
+			continue
+		}
+		for p := range partners {
+			if tn.partners[p] {
+				continue
+			}
+			res.channels = append(res.channels, &reconcileChannel{
+				registry:     tn.registry,
+				token:        tn.token,
+				partner:      p,
+				tokenNetwork: tn.tokenNetwork,
+			})
+		}
+	}
+	for _, c := range res.channels {
+		c.chain, c.err = chainChannelFacts(c.tokenNetwork, rs.NodeAddress, c.partner)
+		if c.err != nil {
+			res.errors = append(res.errors, fmt.Sprintf("channel of token %s with %s: %s", c.token.String(), c.partner.String(), c.err))
+			continue
+		}
+		if !needsReplay(c.local, c.chain) {
+			continue
+		}
+		from, ok := replayFrom[c.tokenNetwork.Address]
+		if c.local != nil {
+			replayChannels[c.local.ChannelIdentifier] = c
+			if !ok || c.local.OpenBlockNumber < from {
+				from, ok = c.local.OpenBlockNumber, true
+			}
+		}
+		if c.chain.State != channeltype.StateSettled {
+			replayChannels[c.chain.ChannelIdentifier] = c
+			if !ok || c.chain.OpenBlockNumber < from {
+				from = c.chain.OpenBlockNumber
+			}
+		}
+		replayFrom[c.tokenNetwork.Address] = from
+	}
+	for tokenNetwork, from := range replayFrom {
+		sts, err := rs.BlockChainEvents.GetTokenNetworkStateChangesSince(from, tokenNetwork)
+		if err != nil {
+			res.errors = append(res.errors, fmt.Sprintf("events of token network %s: %s", tokenNetwork.String(), err))
+			//not checked, otherwise it's a conflict
+			for _, c := range replayChannels {
+				if c.tokenNetwork.Address == tokenNetwork {
+					c.err = err
+				}
+			}
+			continue
+		}
+		for _, st := range sts {
+			if _, ok := replayChannels[stateChangeChannel(st)]; ok {
+				res.replay = append(res.replay, st)
+			}
+		}
+	}
+}
+
+//stateChangeChannel returns channel of a contract statechange, empty if it's not about a channel
+func stateChangeChannel(st mediatedtransfer.ContractStateChange) common.Hash {
+	switch st2 := st.(type) {
+	case *mediatedtransfer.ContractNewChannelStateChange:
+		return st2.ChannelIdentifier.ChannelIdentifier
+	case *mediatedtransfer.ContractChannelWithdrawStateChange:
+		return st2.ChannelIdentifier.ChannelIdentifier
+	case *mediatedtransfer.ContractBalanceStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractClosedStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractBalanceProofUpdatedStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractUnlockStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractPunishedStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractSettledStateChange:
+		return st2.ChannelIdentifier
+	case *mediatedtransfer.ContractCooperativeSettledStateChange:
+		return st2.ChannelIdentifier
+	}
+	return utils.EmptyHash
+}
+
+//apply replays missed events, repairs deposits and blocks channels in conflict, must run in the main loop
+func (r *reconciler) apply(res *reconcileResult) error {
+	rs := r.raiden
+	before := make(map[*reconcileChannel][]*models.ChannelDiscrepancy)
+	for _, c := range res.channels {
+		if c.err == nil {
+			before[c] = compareChannel(c.local, c.chain)
+		}
+	}
+	for _, st := range res.replay {
+		log.Info(fmt.Sprintf("reconcile replay missed event of channel %s", utils.HPex(stateChangeChannel(st))))
+		err := rs.StateMachineEventHandler.OnBlockchainStateChange(st)
+		if err != nil {
+			log.Error(fmt.Sprintf("reconcile OnBlockchainStateChange err %s", err))
+		}
+	}
+	report := &models.ReconcileReport{
+		BlockNumber: res.blockNumber,
+		Time:        time.Now(),
+		Errors:      res.errors,
+	}
+	for _, c := range res.channels {
+		if c.err != nil {
+			continue
+		}
+		report.Checked++
+		ch := rs.getChannel(c.registry, c.token, c.partner)
+		var local *channelFacts
+		if ch != nil {
+			local = localChannelFacts(ch)
+		}
+		after := compareChannel(local, c.chain)
+		changed := make(map[string]bool)
+		blocked := false
+		for _, d := range after {
+			changed[d.Field] = true
+			switch {
+			case d.Action == models.ReconcileConflict:
+			case d.Field == "our_deposit":
+				ch.OurState.ContractBalance = new(big.Int).Set(c.chain.OurDeposit)
+			case d.Field == "partner_deposit":
+				ch.PartnerState.ContractBalance = new(big.Int).Set(c.chain.PartnerDeposit)
+			default:
+				//replaying events doesn't repair it
+				d.Action = models.ReconcileConflict
+			}
+			blocked = blocked || d.Action == models.ReconcileConflict
+		}
+		for _, d := range before[c] {
+			if d.Action == models.ReconcileRepaired && !changed[d.Field] {
+				after = append(after, d)
+			}
+		}
+		id := c.chain.ChannelIdentifier
+		if local != nil {
+			id = local.ChannelIdentifier
+		}
+		for _, d := range after {
+			d.ChannelIdentifier = id
+			d.TokenAddress = c.token
+			d.PartnerAddress = c.partner
+			log.Warn(fmt.Sprintf("reconcile channel %s %s in db is %s, on chain is %s, %s", utils.HPex(id), d.Field, d.Local, d.Chain, d.Action))
+		}
+		report.Discrepancies = append(report.Discrepancies, after...)
+		if ch == nil {
+			continue
+		}
+		if changed["our_deposit"] || changed["partner_deposit"] {
+			err := rs.db.UpdateChannelContractBalance(channel.NewChannelSerialization(ch))
+			if err != nil {
+				log.Error(fmt.Sprintf("reconcile UpdateChannelContractBalance %s err %s", utils.HPex(id), err))
+			}
+		}
+		ch.Blocked = blocked
+	}
+	log.Info(fmt.Sprintf("reconcile %d channels at block %d, %d discrepancies, %d errors", report.Checked, report.BlockNumber, len(report.Discrepancies), len(report.Errors)))
+	r.lock.Lock()
+	r.report = report
+	r.running = false
+	r.lock.Unlock()
+	return rs.db.SaveReconcileReport(report)
+}
+
+//blockLastConflicts blocks channels in conflict in the last report until they are checked again
+func (r *reconciler) blockLastConflicts() {
+	rs := r.raiden
+	report, err := rs.db.GetReconcileReport()
+	if err != nil {
+		return
+	}
+	r.lock.Lock()
+	r.report = report
+	r.lock.Unlock()
+	for id := range report.Conflicts() {
+		ch := rs.getChannelWithAddr(id)
+		if ch != nil {
+			log.Warn(fmt.Sprintf("channel %s is inconsistent with blockchain, transfers are blocked", utils.HPex(id)))
+			ch.Blocked = true
+		}
+	}
+}
+
+//Report returns report of the last reconciliation, nil if never reconciled
+func (r *reconciler) Report() *models.ReconcileReport {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.report
+}
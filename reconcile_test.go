@@ -0,0 +1,80 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func newChannelFacts(state channeltype.State, ourDeposit, partnerDeposit int64) *channelFacts {
+	return &channelFacts{
+		ChannelIdentifier: utils.Sha3([]byte("channel")),
+		OpenBlockNumber:   10,
+		State:             state,
+		OurDeposit:        big.NewInt(ourDeposit),
+		PartnerDeposit:    big.NewInt(partnerDeposit),
+	}
+}
+
+func TestCompareChannel(t *testing.T) {
+	local := newChannelFacts(channeltype.StateOpened, 10, 20)
+	chain := newChannelFacts(channeltype.StateOpened, 10, 20)
+	assert(t, 0, len(compareChannel(local, chain)))
+	assert(t, false, needsReplay(local, chain))
+
+	//partner deposited without our knowledge, our deposit on chain is less than ours
+	chain.PartnerDeposit = big.NewInt(30)
+	chain.OurDeposit = big.NewInt(5)
+	ds := compareChannel(local, chain)
+	assert(t, 2, len(ds))
+	assert(t, "our_deposit", ds[0].Field)
+	assert(t, models.ReconcileConflict, ds[0].Action)
+	assert(t, "partner_deposit", ds[1].Field)
+	assert(t, models.ReconcileRepaired, ds[1].Action)
+	assert(t, "30", ds[1].Chain)
+	assert(t, false, needsReplay(local, chain))
+
+	//closed and partner's balance proof on chain is newer than ours
+	chain = newChannelFacts(channeltype.StateClosed, 10, 20)
+	chain.PartnerNonce = 3
+	local.PartnerNonce = 2
+	ds = compareChannel(local, chain)
+	assert(t, 2, len(ds))
+	assert(t, "state", ds[0].Field)
+	assert(t, models.ReconcileRepaired, ds[0].Action)
+	assert(t, "partner_nonce", ds[1].Field)
+	assert(t, models.ReconcileConflict, ds[1].Action)
+	assert(t, true, needsReplay(local, chain))
+
+	//closed locally but opened on chain
+	local.State = channeltype.StateClosed
+	chain.State = channeltype.StateOpened
+	ds = compareChannel(local, chain)
+	assert(t, models.ReconcileConflict, ds[0].Action)
+
+	//withdrawn
+	chain = newChannelFacts(channeltype.StateOpened, 10, 20)
+	chain.OpenBlockNumber = 30
+	ds = compareChannel(local, chain)
+	assert(t, 1, len(ds))
+	assert(t, "open_block_number", ds[0].Field)
+	assert(t, true, needsReplay(local, chain))
+
+	//settled and a new channel is opened
+	chain.ChannelIdentifier = utils.NewRandomHash()
+	ds = compareChannel(local, chain)
+	assert(t, 1, len(ds))
+	assert(t, "settled", ds[0].Chain)
+
+	//unknown in db
+	ds = compareChannel(nil, chain)
+	assert(t, 1, len(ds))
+	assert(t, "none", ds[0].Local)
+	assert(t, true, needsReplay(nil, chain))
+	chain.State = channeltype.StateSettled
+	assert(t, 0, len(compareChannel(nil, chain)))
+	assert(t, false, needsReplay(nil, chain))
+}
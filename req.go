@@ -3,6 +3,7 @@ package smartraiden
 import (
 	"math/big"
 
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer/route"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -42,23 +43,38 @@ const cancelPrepareWithdrawReqName = "cancel mark withdraw"
 const depositChannelReqName = "deposit"
 const tokenSwapMakerReqName = "tokenswapmaker"
 const tokenSwapTakerReqName = "tokenswaptaker"
+const pingReqName = "ping" //health check of event loop
+const reconcileReqName = "reconcile"
+const reconcileApplyReqName = "apply reconcile" //chain is read, apply it in the main loop
+const graphReqName = "graph"                    //snapshot of channel graph, it's only read in the main loop
 
 /*
 transfer api
 */
 type transferReq struct {
+	RegistryAddress  common.Address
 	TokenAddress     common.Address
 	Amount           *big.Int
 	Target           common.Address
 	Fee              *big.Int
 	LockSecretHash   common.Hash
 	IsDirectTransfer bool
+	Paths            []*route.Path //found by pathfinding service, nil if not used
+}
+
+/*
+channel graph api
+*/
+type graphReq struct {
+	registry     common.Address
+	tokenAddress common.Address
 }
 
 /*
 new channel api
 */
 type newChannelReq struct {
+	registry       common.Address
 	tokenAddress   common.Address
 	partnerAddress common.Address
 	settleTimeout  int
@@ -121,20 +137,46 @@ Transfer `amount` between this node and `target`.
            - Network speed, making the transfer sufficiently fast so it doesn't
              expire.
 */
-func (rs *RaidenService) transferAsyncClient(tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, isDirectTransfer bool) *utils.AsyncResult {
-	req := &apiReq{
+func (rs *RaidenService) transferAsyncClient(registry, tokenAddress common.Address, amount *big.Int, fee *big.Int, target common.Address, lockSecretHash common.Hash, isDirectTransfer bool) *utils.AsyncResult {
+	r := &transferReq{
+		RegistryAddress:  registry,
+		TokenAddress:     tokenAddress,
+		Amount:           amount,
+		Target:           target,
+		LockSecretHash:   lockSecretHash,
+		Fee:              fee,
+		IsDirectTransfer: isDirectTransfer,
+	}
+	result := rs.sendReqClient(&apiReq{
 		ReqID: utils.RandomString(10),
 		Name:  transferReqName,
-		Req: &transferReq{
-			TokenAddress:     tokenAddress,
-			Amount:           amount,
-			Target:           target,
-			LockSecretHash:   lockSecretHash,
-			Fee:              fee,
-			IsDirectTransfer: isDirectTransfer,
-		},
+		Req:   r,
+	})
+	if isDirectTransfer || rs.Pathfinder == nil {
+		return result
 	}
-	return rs.sendReqClient(req)
+	/*
+		pathfinding service is only asked when my own graph has no route,
+		which is known at once, a transfer started doesn't have its result yet.
+	*/
+	select {
+	case err := <-result.Result:
+		result.Result <- err
+		if err != errNoAvailableRoute {
+			return result
+		}
+	default:
+		return result
+	}
+	r.Paths = rs.findPaths(tokenAddress, target, amount, fee)
+	if len(r.Paths) == 0 {
+		return result
+	}
+	return rs.sendReqClient(&apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  transferReqName,
+		Req:   r,
+	})
 	//return rs.startMediatedTransfer(tokenAddress, target, amount, identifier)
 }
 func (rs *RaidenService) sendReqClient(req *apiReq) *utils.AsyncResult {
@@ -143,11 +185,12 @@ func (rs *RaidenService) sendReqClient(req *apiReq) *utils.AsyncResult {
 	ar := <-req.result
 	return ar
 }
-func (rs *RaidenService) newChannelClient(token, partner common.Address, settleTimeout int, deposit *big.Int) *utils.AsyncResult {
+func (rs *RaidenService) newChannelClient(registry, token, partner common.Address, settleTimeout int, deposit *big.Int) *utils.AsyncResult {
 	req := &apiReq{
 		ReqID: utils.RandomString(10),
 		Name:  newChannelReqName,
 		Req: &newChannelReq{
+			registry:       registry,
 			tokenAddress:   token,
 			partnerAddress: partner,
 			settleTimeout:  settleTimeout,
@@ -264,3 +307,18 @@ func (rs *RaidenService) tokenSwapTakerClient(tokenswap *TokenSwap) *utils.Async
 	}
 	return rs.sendReqClient(req)
 }
+func (rs *RaidenService) reconcileClient() *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  reconcileReqName,
+	}
+	return rs.sendReqClient(req)
+}
+func (rs *RaidenService) graphClient(registry, token common.Address) *utils.AsyncResult {
+	req := &apiReq{
+		ReqID: utils.RandomString(10),
+		Name:  graphReqName,
+		Req:   &graphReq{registry: registry, tokenAddress: token},
+	}
+	return rs.sendReqClient(req)
+}
@@ -0,0 +1,225 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//adminTokenFile is where the first admin token is written when api auth is enabled
+const adminTokenFile = "api_admin_token"
+
+//auditLogFile records every authenticated call which may change the node
+const auditLogFile = "api_audit.log"
+
+/*
+auditRecord is one line of audit log
+*/
+type auditRecord struct {
+	Time       time.Time `json:"time"`
+	Token      string    `json:"token"`
+	Method     string    `json:"method"`
+	Path       string    `json:"path"`
+	RemoteAddr string    `json:"remote_addr"`
+	Status     int       `json:"status"`
+}
+
+type auditLogger struct {
+	lock sync.Mutex
+	f    *os.File
+}
+
+var audit *auditLogger
+
+func newAuditLogger(path string) (*auditLogger, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	return &auditLogger{f: f}, nil
+}
+
+func (a *auditLogger) write(rec *auditRecord) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	data, err := json.Marshal(rec)
+	if err != nil {
+		log.Error(fmt.Sprintf("audit log marshal err %s", err))
+		return
+	}
+	_, err = a.f.Write(append(data, '\n'))
+	if err != nil {
+		log.Error(fmt.Sprintf("audit log write err %s", err))
+	}
+}
+
+//statusWriter remembers the status code of response for audit log
+type statusWriter struct {
+	rest.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	return w.ResponseWriter.(http.ResponseWriter).Write(b)
+}
+
+/*
+setupAuth prepares audit log and the first admin token when api auth is enabled.
+the admin token is written to datadir only when there is no token at all.
+*/
+func setupAuth() error {
+	dir := filepath.Dir(Config.DataBasePath)
+	a, err := newAuditLogger(filepath.Join(dir, auditLogFile))
+	if err != nil {
+		return err
+	}
+	audit = a
+	ts, err := RaidenAPI.GetAPITokens()
+	if err != nil {
+		return err
+	}
+	if len(ts) > 0 {
+		return nil
+	}
+	token, err := RaidenAPI.CreateAPIToken("admin", []string{models.APIScopeAdmin})
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(dir, adminTokenFile)
+	err = ioutil.WriteFile(p, []byte(token), 0600)
+	if err != nil {
+		return err
+	}
+	log.Info(fmt.Sprintf("api admin token is written to %s", p))
+	return nil
+}
+
+func bearerToken(r *rest.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(h[len("Bearer "):])
+}
+
+/*
+requireScope only allows tokens of `scope` to call `h` when api auth is enabled.
+calls of any scope except read are written to audit log.
+*/
+func requireScope(scope string, h rest.HandlerFunc) rest.HandlerFunc {
+	return func(w rest.ResponseWriter, r *rest.Request) {
+		if !Config.APIAuth {
+			h(w, r)
+			return
+		}
+		token := bearerToken(r)
+		if len(token) == 0 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			rest.Error(w, "api token required", http.StatusUnauthorized)
+			return
+		}
+		t, err := RaidenAPI.CheckAPIToken(token)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			rest.Error(w, "invalid api token", http.StatusUnauthorized)
+			return
+		}
+		if !t.HasScope(scope) {
+			rest.Error(w, fmt.Sprintf("api token %s has no scope %s", t.Name, scope), http.StatusForbidden)
+			return
+		}
+		if scope == models.APIScopeRead {
+			h(w, r)
+			return
+		}
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		h(sw, r)
+		audit.write(&auditRecord{
+			Time:       time.Now(),
+			Token:      t.Name,
+			Method:     r.Method,
+			Path:       r.URL.RequestURI(),
+			RemoteAddr: r.RemoteAddr,
+			Status:     sw.status,
+		})
+	}
+}
+
+/*
+APITokenData is a restful api token, Token is returned only when it's created
+*/
+type APITokenData struct {
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Token     string    `json:"token,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+/*
+CreateAPIToken create a new api token
+*/
+func CreateAPIToken(w rest.ResponseWriter, r *rest.Request) {
+	req := &APITokenData{}
+	err := r.DecodeJsonPayload(req)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token, err := RaidenAPI.CreateAPIToken(req.Name, req.Scopes)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	req.Token = token
+	req.CreatedAt = time.Now()
+	err = w.WriteJson(req)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+APITokens list all api tokens, without token itself
+*/
+func APITokens(w rest.ResponseWriter, r *rest.Request) {
+	ts, err := RaidenAPI.GetAPITokens()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	datas := []*APITokenData{}
+	for _, t := range ts {
+		datas = append(datas, &APITokenData{Name: t.Name, Scopes: t.Scopes, CreatedAt: t.CreatedAt})
+	}
+	err = w.WriteJson(datas)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+RemoveAPIToken revoke an api token
+*/
+func RemoveAPIToken(w rest.ResponseWriter, r *rest.Request) {
+	err := RaidenAPI.RemoveAPIToken(r.PathParam("name"))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//BackupData is the result of backup
+type BackupData struct {
+	Path string `json:"path"`
+}
+
+//StorageStats reports size of db and number of records in every bucket or table
+func StorageStats(w rest.ResponseWriter, r *rest.Request) {
+	s, err := RaidenAPI.StorageStats()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(s)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+Backup writes a consistent snapshot of db while running,
+the snapshot can be restored by `smartraiden db restore`.
+*/
+func Backup(w rest.ResponseWriter, r *rest.Request) {
+	path, err := RaidenAPI.Backup()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(&BackupData{Path: path})
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
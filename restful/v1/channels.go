@@ -29,6 +29,7 @@ type ChannelData struct {
 	LockedAmount        *big.Int          `json:"locked_amount"`
 	PartnerLockedAmount *big.Int          `json:"partner_locked_amount"`
 	TokenAddress        string            `json:"token_address"`
+	RegistryAddress     string            `json:"registry_address"`
 	State               channeltype.State `json:"state"`
 	StateString         string
 	SettleTimeout       int `json:"settle_timeout"`
@@ -45,6 +46,7 @@ type ChannelDataDetail struct {
 	LockedAmount        *big.Int          `json:"locked_amount"`
 	PartnerLockedAmount *big.Int          `json:"partner_locked_amount"`
 	TokenAddress        string            `json:"token_address"`
+	RegistryAddress     string            `json:"registry_address"`
 	State               channeltype.State `json:"state"`
 	StateString         string
 	SettleTimeout       int `json:"settle_timeout"`
@@ -67,15 +69,23 @@ type ChannelDataDetail struct {
 }
 
 /*
-GetChannelList list all my channels
+GetChannelList list all my channels, or those of `registry` in query string
 */
 func GetChannelList(w rest.ResponseWriter, r *rest.Request) {
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	chs, err := RaidenAPI.GetChannelList(utils.EmptyAddress, utils.EmptyAddress)
 	if err != nil {
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 	var datas []*ChannelData
 	for _, c := range chs {
+		if registry != utils.EmptyAddress && c.RegistryAddress != registry {
+			continue
+		}
 		d := &ChannelData{
 			ChannelAddress:      c.ChannelIdentifier.ChannelIdentifier.String(),
 			OpenBlockNumber:     c.ChannelIdentifier.OpenBlockNumber,
@@ -85,6 +95,7 @@ func GetChannelList(w rest.ResponseWriter, r *rest.Request) {
 			State:               c.State,
 			StateString:         c.State.String(),
 			TokenAddress:        c.TokenAddress().String(),
+			RegistryAddress:     c.RegistryAddress.String(),
 			SettleTimeout:       c.SettleTimeout,
 			RevealTimeout:       c.RevealTimeout,
 			LockedAmount:        c.OurAmountLocked(),
@@ -143,6 +154,7 @@ func SpecifiedChannel(w rest.ResponseWriter, r *rest.Request) {
 		StateString:              c.State.String(),
 		SettleTimeout:            c.SettleTimeout,
 		TokenAddress:             c.TokenAddress().String(),
+		RegistryAddress:          c.RegistryAddress.String(),
 		LockedAmount:             c.OurAmountLocked(),
 		PartnerLockedAmount:      c.PartnerAmountLocked(),
 		ClosedBlock:              c.ClosedBlock,
@@ -164,7 +176,7 @@ func SpecifiedChannel(w rest.ResponseWriter, r *rest.Request) {
 
 /*
 OpenChannel open a channel with partner.
-token must exist
+token must exist, on `registry_address` if it's given, otherwise on the token's first registry
 partner maybe an invalid address
 */
 func OpenChannel(w rest.ResponseWriter, r *rest.Request) {
@@ -178,7 +190,7 @@ func OpenChannel(w rest.ResponseWriter, r *rest.Request) {
 	partnerAddr := common.HexToAddress(req.PartnerAddrses)
 	tokenAddr := common.HexToAddress(req.TokenAddress)
 	if req.State == 0 { //open channel
-		c, err := RaidenAPI.Open(tokenAddr, partnerAddr, req.SettleTimeout, params.DefaultRevealTimeout, req.Balance)
+		c, err := RaidenAPI.OpenOnRegistry(common.HexToAddress(req.RegistryAddress), tokenAddr, partnerAddr, req.SettleTimeout, params.DefaultRevealTimeout, req.Balance)
 		if err != nil {
 			log.Error(err.Error())
 			rest.Error(w, err.Error(), http.StatusConflict)
@@ -194,6 +206,7 @@ func OpenChannel(w rest.ResponseWriter, r *rest.Request) {
 			StateString:         c.State.String(),
 			SettleTimeout:       c.SettleTimeout,
 			TokenAddress:        c.TokenAddress().String(),
+			RegistryAddress:     c.RegistryAddress.String(),
 			LockedAmount:        c.OurAmountLocked(),
 			PartnerLockedAmount: c.PartnerAmountLocked(),
 		}
@@ -239,7 +252,7 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	if req.Balance != nil && req.Balance.Cmp(utils.BigInt0) > 0 { //deposit
-		c, err = RaidenAPI.Deposit(c.TokenAddress(), c.PartnerAddress(), req.Balance, params.DefaultPollTimeout)
+		c, err = RaidenAPI.DepositChannel(chAddr, req.Balance, params.DefaultPollTimeout)
 		if err != nil {
 			rest.Error(w, err.Error(), http.StatusRequestTimeout)
 			return
@@ -259,7 +272,7 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 		}
 		if req.StateInt == channeltype.StateClosed {
 			if req.Force {
-				c, err = RaidenAPI.Close(c.TokenAddress(), c.PartnerAddress())
+				c, err = RaidenAPI.CloseChannel(chAddr)
 				if err != nil {
 					log.Error(err.Error())
 					rest.Error(w, err.Error(), http.StatusConflict)
@@ -267,7 +280,7 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 				}
 			} else {
 				//cooperative settle channel
-				c, err = RaidenAPI.CooperativeSettle(c.TokenAddress(), c.PartnerAddress())
+				c, err = RaidenAPI.CooperativeSettleChannel(chAddr)
 				if err != nil {
 					log.Error(err.Error())
 					rest.Error(w, err.Error(), http.StatusConflict)
@@ -276,7 +289,7 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 			}
 
 		} else if req.StateInt == channeltype.StateSettled {
-			c, err = RaidenAPI.Settle(c.TokenAddress(), c.PartnerAddress())
+			c, err = RaidenAPI.SettleChannel(chAddr)
 			if err != nil {
 				log.Error(err.Error())
 				rest.Error(w, err.Error(), http.StatusConflict)
@@ -294,6 +307,7 @@ func CloseSettleDepositChannel(w rest.ResponseWriter, r *rest.Request) {
 		StateString:         c.State.String(),
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 		RevealTimeout:       c.RevealTimeout,
@@ -331,16 +345,16 @@ func withdraw(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	if req.Amount != nil && req.Amount.Cmp(utils.BigInt0) > 0 { //deposit
-		c, err = RaidenAPI.Withdraw(c.TokenAddress(), c.PartnerAddress(), req.Amount)
+		c, err = RaidenAPI.WithdrawChannel(chAddr, req.Amount)
 		if err != nil {
 			rest.Error(w, err.Error(), http.StatusBadRequest)
 			return
 		}
 	} else {
 		if req.Op == OpPrepareWithdraw {
-			c, err = RaidenAPI.PrepareForWithdraw(c.TokenAddress(), c.PartnerAddress())
+			c, err = RaidenAPI.PrepareForWithdrawChannel(chAddr)
 		} else if req.Op == OpCancelPrepare {
-			c, err = RaidenAPI.CancelPrepareForWithdraw(c.TokenAddress(), c.PartnerAddress())
+			c, err = RaidenAPI.CancelPrepareForWithdrawChannel(chAddr)
 		} else {
 			err = fmt.Errorf("unkown operation %s", req.Op)
 		}
@@ -359,6 +373,7 @@ func withdraw(w rest.ResponseWriter, r *rest.Request) {
 		StateString:         c.State.String(),
 		SettleTimeout:       c.SettleTimeout,
 		TokenAddress:        c.TokenAddress().String(),
+		RegistryAddress:     c.RegistryAddress.String(),
 		LockedAmount:        c.OurAmountLocked(),
 		PartnerLockedAmount: c.PartnerAmountLocked(),
 		RevealTimeout:       c.RevealTimeout,
@@ -8,26 +8,36 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
 	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/ethereum/go-ethereum/common"
 )
 
 /*
 RegisterToken register a new token to the raiden network.
-this address must be a valid ERC20 token
+this address must be a valid ERC20 token, it's registered on `registry` in query string, or the default registry.
 */
 func RegisterToken(w rest.ResponseWriter, r *rest.Request) {
 	token := r.PathParam("token")
 	tokenAddr := common.HexToAddress(token)
-	mgr, err := RaidenAPI.RegisterToken(tokenAddr)
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if registry == utils.EmptyAddress {
+		registry = RaidenAPI.Raiden.RegistryAddress
+	}
+	mgr, err := RaidenAPI.RegisterTokenOnRegistry(registry, tokenAddr)
 	type Ret struct {
 		ChannelManagerAddress string `json:"channel_manager_address"`
+		RegistryAddress       string `json:"registry_address"`
 	}
 	if err != nil {
 		log.Error(fmt.Sprintf("RegisterToken %s err:%s", tokenAddr.String(), err))
 		rest.Error(w, err.Error(), http.StatusConflict)
 	} else {
-		ret := &Ret{ChannelManagerAddress: mgr.String()}
+		ret := &Ret{ChannelManagerAddress: mgr.String(), RegistryAddress: registry.String()}
 		err = w.WriteJson(ret)
 		if err != nil {
 			log.Warn(fmt.Sprintf("writejson err %s", err))
@@ -4,10 +4,14 @@ import (
 	"fmt"
 	"math/big"
 	"net/http"
+	"time"
 
 	"context"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/metrics"
+	"github.com/SmartMeshFoundation/SmartRaiden/network"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/helper"
 	"github.com/SmartMeshFoundation/SmartRaiden/network/netshare"
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/ethereum/go-ethereum/common"
@@ -92,6 +96,8 @@ type ConnectionStatus struct {
 	XMPPStatus    netshare.Status
 	EthStatus     netshare.Status
 	LastBlockTime string
+	//EthEndpoints health of every eth rpc endpoint, EthStatus is of the primary one
+	EthEndpoints []*helper.EndpointStatus
 }
 
 /*
@@ -103,13 +109,67 @@ func EthereumStatus(w rest.ResponseWriter, r *rest.Request) {
 		XMPPStatus:    netshare.Disconnected,
 		LastBlockTime: RaidenAPI.Raiden.GetDb().GetLastBlockNumberTime().Format(BlockTimeFormat),
 	}
+	if t, ok := RaidenAPI.Raiden.Transport.(network.ConnectionStatuser); ok {
+		cs.XMPPStatus = t.ConnectionStatus()
+	}
 	if c != nil && c.Client.Status == netshare.Connected {
 		cs.EthStatus = netshare.Connected
 	} else {
 		cs.EthStatus = netshare.Disconnected
 	}
+	if c != nil {
+		cs.EthEndpoints = c.Client.EndpointStatus()
+	}
 	err := w.WriteJson(cs)
 	if err != nil {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
 }
+
+//PeerStat round trip and retransmission statistics of one peer, durations are in milliseconds
+type PeerStat struct {
+	Address       string  `json:"address"`
+	SRTT          int64   `json:"srtt"`
+	RTTVar        int64   `json:"rttvar"`
+	RTO           int64   `json:"rto"`
+	Sent          int64   `json:"sent"`
+	Acked         int64   `json:"acked"`
+	Transmissions int64   `json:"transmissions"`
+	Retransmits   int64   `json:"retransmits"`
+	LossRate      float64 `json:"loss_rate"`
+}
+
+/*
+PeerStats query round trip time and retransmissions of all peers this node has sent messages to
+*/
+func PeerStats(w rest.ResponseWriter, r *rest.Request) {
+	var stats []*PeerStat
+	for _, s := range RaidenAPI.Raiden.Protocol.GetPeerStats() {
+		stats = append(stats, &PeerStat{
+			Address:       s.Address.String(),
+			SRTT:          int64(s.SRTT / time.Millisecond),
+			RTTVar:        int64(s.RTTVar / time.Millisecond),
+			RTO:           int64(s.RTO / time.Millisecond),
+			Sent:          s.Sent,
+			Acked:         s.Acked,
+			Transmissions: s.Transmissions,
+			Retransmits:   s.Retransmits,
+			LossRate:      s.LossRate(),
+		})
+	}
+	err := w.WriteJson(stats)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+Metrics all metrics of this node in prometheus text format
+*/
+func Metrics(w rest.ResponseWriter, r *rest.Request) {
+	w.Header().Set("Content-Type", metrics.ContentType)
+	err := metrics.WriteText(w.(http.ResponseWriter))
+	if err != nil {
+		log.Warn(fmt.Sprintf("write metrics err %s", err))
+	}
+}
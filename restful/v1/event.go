@@ -4,10 +4,12 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"net/url"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/ant0ine/go-json-rest/rest"
 	"github.com/ethereum/go-ethereum/common"
 )
@@ -44,11 +46,37 @@ func getFromTo(r *rest.Request) (fromBlock, toBlock int64) {
 }
 
 /*
-EventNetwork returns all events related to raiden network
+getEventFilter returns filter of contract events from query string,
+`type` is comma separated event types, `participant` selects events naming it or of its channels.
+*/
+func getEventFilter(r *rest.Request) (f *models.ContractEventFilter, err error) {
+	f = new(models.ContractEventFilter)
+	f.FromBlock, f.ToBlock = getFromTo(r)
+	m := r.URL.Query()
+	for _, t := range strings.Split(m.Get("type"), ",") {
+		if len(t) > 0 {
+			f.EventTypes = append(f.EventTypes, t)
+		}
+	}
+	if len(m.Get("participant")) > 0 {
+		if !common.IsHexAddress(m.Get("participant")) {
+			return nil, fmt.Errorf("invalid participant %s", m.Get("participant"))
+		}
+		f.Participant = common.HexToAddress(m.Get("participant"))
+	}
+	return
+}
+
+/*
+EventNetwork returns events of registry and secret registry selected by filter of getEventFilter
 */
 func EventNetwork(w rest.ResponseWriter, r *rest.Request) {
-	fromBlock, toBlock := getFromTo(r)
-	events, err := RaidenAPI.GetNetworkEvents(fromBlock, toBlock)
+	f, err := getEventFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	events, err := RaidenAPI.GetNetworkEvents(f)
 	if err != nil {
 		log.Error(err.Error())
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
@@ -61,10 +89,20 @@ func EventNetwork(w rest.ResponseWriter, r *rest.Request) {
 }
 
 /*
-EventTokens returns all events about the token specified
+EventTokens returns events of token network of the token specified, selected by filter of getEventFilter,
+the token network is on `registry` in query string, or the token's first registry.
 */
 func EventTokens(w rest.ResponseWriter, r *rest.Request) {
-	fromBlock, toBlock := getFromTo(r)
+	f, err := getEventFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	var token common.Address
 	tokenstr := r.PathParam("token")
 	fmt.Println("tokenstr ", tokenstr)
@@ -73,7 +111,7 @@ func EventTokens(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	token = common.HexToAddress(tokenstr)
-	events, err := RaidenAPI.GetTokenNetworkEvents(token, fromBlock, toBlock)
+	events, err := RaidenAPI.GetTokenNetworkEvents(registry, token, f)
 	if err != nil {
 		log.Error(err.Error())
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
@@ -86,11 +124,15 @@ func EventTokens(w rest.ResponseWriter, r *rest.Request) {
 }
 
 /*
-EventChannels returns all events about the channel specified
+EventChannels returns events of the channel specified selected by filter of getEventFilter, and outcomes of its locks unlocked on chain
 */
 func EventChannels(w rest.ResponseWriter, r *rest.Request) {
-	fromBlock, toBlock := getFromTo(r)
-	log.Trace(fmt.Sprintf("from=%d,toblock=%d", fromBlock, toBlock))
+	f, err := getEventFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.Trace(fmt.Sprintf("from=%d,toblock=%d", f.FromBlock, f.ToBlock))
 	var channel common.Hash
 	channelstr := r.PathParam("channel")
 	log.Trace(fmt.Sprintf("channels %s", channelstr))
@@ -99,7 +141,7 @@ func EventChannels(w rest.ResponseWriter, r *rest.Request) {
 		return
 	}
 	channel = common.HexToHash(channelstr)
-	events, err := RaidenAPI.GetChannelEvents(channel, fromBlock, toBlock)
+	events, err := RaidenAPI.GetChannelEvents(channel, f)
 	if err != nil {
 		log.Error(err.Error())
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
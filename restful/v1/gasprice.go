@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//GetGasPrice returns gas price config and gas spent on every token
+func GetGasPrice(w rest.ResponseWriter, r *rest.Request) {
+	s, err := RaidenAPI.GetGasPrice()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(s)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+SetGasPrice changes gas price config, fields not in request keep their values.
+{
+	"strategy":"deadline",
+	"multiplier":1.2,
+	"spending_caps":{"0x...":10000000000000000}
+}
+*/
+func SetGasPrice(w rest.ResponseWriter, r *rest.Request) {
+	s, err := RaidenAPI.GetGasPrice()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	//a deep copy, decoding into maps of current config changes it
+	data, err := json.Marshal(s.Config)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	c := new(params.GasPriceConfig)
+	err = json.Unmarshal(data, c)
+	if err == nil {
+		err = r.DecodeJsonPayload(c)
+	}
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = RaidenAPI.SetGasPrice(c)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = w.WriteJson(c)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
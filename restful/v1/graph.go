@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+ChannelGraph returns nodes, channels and statistics of the network of a token as json,
+or in graphviz dot language with `?format=dot`, `?registry=` selects the registry of the token.
+*/
+func ChannelGraph(w rest.ResponseWriter, r *rest.Request) {
+	token := r.PathParam("token")
+	if !common.IsHexAddress(token) {
+		rest.Error(w, fmt.Sprintf("token %s is not an address", token), http.StatusBadRequest)
+		return
+	}
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	e, err := RaidenAPI.ChannelGraph(registry, common.HexToAddress(token))
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if r.URL.Query().Get("format") == "dot" {
+		w.Header().Set("Content-Type", "text/vnd.graphviz; charset=utf-8")
+		_, err = io.WriteString(w.(http.ResponseWriter), e.DOT())
+		if err != nil {
+			log.Warn(fmt.Sprintf("write dot err %s", err))
+		}
+		return
+	}
+	err = w.WriteJson(e)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -0,0 +1,36 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+func writeHealthReport(w rest.ResponseWriter, r *smartraiden.HealthReport) {
+	if !r.Healthy {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	err := w.WriteJson(r)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+Healthz is the liveness probe,
+503 when db cannot be written or the event loop is blocked, the node should be restarted.
+*/
+func Healthz(w rest.ResponseWriter, r *rest.Request) {
+	writeHealthReport(w, RaidenAPI.Raiden.Liveness())
+}
+
+/*
+Readyz is the readiness probe,
+503 when the node cannot make transfers now, such as ethereum or xmpp disconnected, or catching up events on chain.
+*/
+func Readyz(w rest.ResponseWriter, r *rest.Request) {
+	writeHealthReport(w, RaidenAPI.Raiden.Readiness())
+}
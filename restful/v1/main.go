@@ -7,6 +7,7 @@ import (
 
 	"github.com/SmartMeshFoundation/SmartRaiden"
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/ant0ine/go-json-rest/rest"
 )
@@ -28,34 +29,43 @@ Start the restful server
 */
 func Start() {
 
+	if Config.APIAuth {
+		err := setupAuth()
+		if err != nil {
+			log.Crit(fmt.Sprintf("setup api auth err %s", err))
+		}
+	}
 	api := rest.NewApi()
 	api.Use(rest.DefaultDevStack...)
 	router, err := rest.MakeRouter(
-		rest.Get("/api/1/address", Address),
-		rest.Get("/api/1/tokens", Tokens),
-		rest.Get("/api/1/tokens/:token/partners", TokenPartners),
-		rest.Put("/api/1/tokens/:token", RegisterToken),
+		rest.Get("/api/1/address", requireScope(models.APIScopeRead, Address)),
+		rest.Get("/api/1/registries", requireScope(models.APIScopeRead, Registries)),
+		rest.Get("/api/1/tokens", requireScope(models.APIScopeRead, Tokens)),
+		rest.Get("/api/1/tokens/:token/partners", requireScope(models.APIScopeRead, TokenPartners)),
+		rest.Put("/api/1/tokens/:token", requireScope(models.APIScopeChannels, RegisterToken)),
 		/*
 			transfer
 		*/
-		rest.Put("/api/1/token_swaps/:target/:id", TokenSwap),
-		rest.Post("/api/1/transfers/:token/:target", Transfers),
-		rest.Get("/api/1/querysenttransfer", GetSentTransfers),
-		rest.Get("/api/1/queryreceivedtransfer", GetReceivedTransfers),
+		rest.Put("/api/1/token_swaps/:target/:id", requireScope(models.APIScopePayments, TokenSwap)),
+		rest.Post("/api/1/transfers/:token/:target", requireScope(models.APIScopePayments, Transfers)),
+		rest.Get("/api/1/querysenttransfer", requireScope(models.APIScopeRead, GetSentTransfers)),
+		rest.Get("/api/1/queryreceivedtransfer", requireScope(models.APIScopeRead, GetReceivedTransfers)),
+		rest.Get("/api/1/exportsenttransfer", requireScope(models.APIScopeRead, ExportSentTransfers)),
+		rest.Get("/api/1/exportreceivedtransfer", requireScope(models.APIScopeRead, ExportReceivedTransfers)),
 		/*
 			test
 		*/
-		rest.Get("/api/1/stop", Stop),
-		rest.Get("/api/1/switch/:mesh", SwitchNetwork),
-		rest.Post("/api/1/updatenodes", UpdateMeshNetworkNodes),
+		rest.Get("/api/1/stop", requireScope(models.APIScopeAdmin, Stop)),
+		rest.Get("/api/1/switch/:mesh", requireScope(models.APIScopeAdmin, SwitchNetwork)),
+		rest.Post("/api/1/updatenodes", requireScope(models.APIScopeAdmin, UpdateMeshNetworkNodes)),
 		/*
 			channels
 		*/
-		rest.Get("/api/1/channels/:channel", SpecifiedChannel),
-		rest.Get("/api/1/channels", GetChannelList),
-		rest.Put("/api/1/channels", OpenChannel),
-		rest.Patch("/api/1/channels/:channel", CloseSettleDepositChannel),
-		rest.Get("/api/1/thirdparty/:channel/:3rd", ChannelFor3rdParty),
+		rest.Get("/api/1/channels/:channel", requireScope(models.APIScopeRead, SpecifiedChannel)),
+		rest.Get("/api/1/channels", requireScope(models.APIScopeRead, GetChannelList)),
+		rest.Put("/api/1/channels", requireScope(models.APIScopeChannels, OpenChannel)),
+		rest.Patch("/api/1/channels/:channel", requireScope(models.APIScopeChannels, CloseSettleDepositChannel)),
+		rest.Get("/api/1/thirdparty/:channel/:3rd", requireScope(models.APIScopeRead, ChannelFor3rdParty)),
 		/*
 			1. withdraw
 			{ "amount":3333,}
@@ -64,7 +74,7 @@ func Start() {
 			3. cancel prepare:
 			{"op": "cancelprepare"}
 		*/
-		rest.Put("/api/1/withdraw/:channel", withdraw),
+		rest.Put("/api/1/withdraw/:channel", requireScope(models.APIScopeChannels, withdraw)),
 		/*
 			1. prepare for withdraw:
 			{"op":"preparesettle",}
@@ -75,21 +85,60 @@ func Start() {
 		/*
 			events
 		*/
-		rest.Get("/api/1/events/network", EventNetwork),
-		rest.Get("/api/1/events/tokens/:token", EventTokens),
-		rest.Get("/api/1/events/channels/:channel", EventChannels),
+		rest.Get("/api/1/events/network", requireScope(models.APIScopeRead, EventNetwork)),
+		rest.Get("/api/1/events/tokens/:token", requireScope(models.APIScopeRead, EventTokens)),
+		rest.Get("/api/1/events/channels/:channel", requireScope(models.APIScopeRead, EventChannels)),
 		/*
 			for debug only
 		*/
-		rest.Get("/api/1/debug/balance/:token/:addr", Balance),
-		rest.Get("/api/1/debug/transfer/:token/:addr/:value", TransferToken),
-		rest.Get("/api/1/debug/ethbalance/:addr", EthBalance),
-		rest.Get("/api/1/debug/ethstatus", EthereumStatus),
+		rest.Get("/api/1/debug/balance/:token/:addr", requireScope(models.APIScopeAdmin, Balance)),
+		rest.Get("/api/1/debug/transfer/:token/:addr/:value", requireScope(models.APIScopeAdmin, TransferToken)),
+		rest.Get("/api/1/debug/ethbalance/:addr", requireScope(models.APIScopeAdmin, EthBalance)),
+		rest.Get("/api/1/debug/ethstatus", requireScope(models.APIScopeAdmin, EthereumStatus)),
+		rest.Get("/api/1/debug/peers", requireScope(models.APIScopeAdmin, PeerStats)),
+		/*
+			api tokens
+		*/
+		rest.Post("/api/1/apitokens", requireScope(models.APIScopeAdmin, CreateAPIToken)),
+		rest.Get("/api/1/apitokens", requireScope(models.APIScopeAdmin, APITokens)),
+		rest.Delete("/api/1/apitokens/:name", requireScope(models.APIScopeAdmin, RemoveAPIToken)),
+		/*
+			hot backup and statistics of db
+		*/
+		rest.Post("/api/1/admin/backup", requireScope(models.APIScopeAdmin, Backup)),
+		rest.Get("/api/1/admin/storage", requireScope(models.APIScopeAdmin, StorageStats)),
+		/*
+			channel graph and topology of a token
+		*/
+		rest.Get("/api/1/graph/:token", requireScope(models.APIScopeRead, ChannelGraph)),
+		/*
+			reconciliation of channels against blockchain
+		*/
+		rest.Get("/api/1/reconcile", requireScope(models.APIScopeRead, GetReconcileReport)),
+		rest.Post("/api/1/reconcile", requireScope(models.APIScopeAdmin, Reconcile)),
+		/*
+			gas price of transactions
+		*/
+		rest.Get("/api/1/gasprice", requireScope(models.APIScopeRead, GetGasPrice)),
+		rest.Put("/api/1/gasprice", requireScope(models.APIScopeAdmin, SetGasPrice)),
+		/*
+			metrics in prometheus text format
+		*/
+		rest.Get("/metrics", requireScope(models.APIScopeRead, Metrics)),
+		/*
+			probes for orchestrators, no api token needed
+		*/
+		rest.Get("/healthz", Healthz),
+		rest.Get("/readyz", Readyz),
 	)
 	if err != nil {
 		log.Crit(fmt.Sprintf("maker router :%s", err))
 	}
 	api.SetApp(router)
 	listen := fmt.Sprintf("%s:%d", Config.APIHost, Config.APIPort)
+	if len(Config.APITLSCert) > 0 {
+		log.Crit(fmt.Sprintf("https listen and serve :%s", http.ListenAndServeTLS(listen, Config.APITLSCert, Config.APITLSKey, api.MakeHandler())))
+		return
+	}
 	log.Crit(fmt.Sprintf("http listen and serve :%s", http.ListenAndServe(listen, api.MakeHandler())))
 }
@@ -25,10 +25,43 @@ func Address(w rest.ResponseWriter, r *rest.Request) {
 }
 
 /*
-Tokens is api of /api/1/tokens
+Tokens is api of /api/1/tokens, tokens of all registries, or of `registry` in query string
 */
 func Tokens(w rest.ResponseWriter, r *rest.Request) {
-	err := w.WriteJson(RaidenAPI.GetTokenTokenNetorks())
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	tokens, err := RaidenAPI.GetRegistryTokens(registry)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	err = w.WriteJson(tokens)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+//getRegistry returns `registry` in query string, empty if not given
+func getRegistry(r *rest.Request) (registry common.Address, err error) {
+	s := r.URL.Query().Get("registry")
+	if len(s) == 0 {
+		return
+	}
+	if !common.IsHexAddress(s) {
+		err = fmt.Errorf("invalid registry %s", s)
+		return
+	}
+	return common.HexToAddress(s), nil
+}
+
+/*
+Registries is api of /api/1/registries, the first one is the default registry
+*/
+func Registries(w rest.ResponseWriter, r *rest.Request) {
+	err := w.WriteJson(RaidenAPI.Registries())
 	if err != nil {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
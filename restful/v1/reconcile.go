@@ -0,0 +1,38 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/ant0ine/go-json-rest/rest"
+)
+
+//GetReconcileReport returns discrepancies found by the last reconciliation of channels against blockchain
+func GetReconcileReport(w rest.ResponseWriter, r *rest.Request) {
+	report := RaidenAPI.ReconcileReport()
+	if report == nil {
+		rest.Error(w, "channels are not reconciled yet", http.StatusNotFound)
+		return
+	}
+	err := w.WriteJson(report)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
+
+/*
+Reconcile checks every channel against blockchain again and returns the new report,
+channels no longer in conflict can transfer again.
+*/
+func Reconcile(w rest.ResponseWriter, r *rest.Request) {
+	report, err := RaidenAPI.Reconcile()
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	err = w.WriteJson(report)
+	if err != nil {
+		log.Warn(fmt.Sprintf("writejson err %s", err))
+	}
+}
@@ -1,11 +1,16 @@
 package v1
 
 import (
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
 	"github.com/SmartMeshFoundation/SmartRaiden/params"
 	"github.com/SmartMeshFoundation/SmartRaiden/utils"
 	"github.com/ant0ine/go-json-rest/rest"
@@ -24,7 +29,7 @@ type TransferData struct {
 }
 
 /*
-Transfers is the api of /transfer/:token/:partner
+Transfers is the api of /transfer/:token/:partner, `?registry=` selects the registry of the token
 */
 func Transfers(w rest.ResponseWriter, r *rest.Request) {
 	token := r.PathParam("token")
@@ -49,7 +54,12 @@ func Transfers(w rest.ResponseWriter, r *rest.Request) {
 		rest.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	err = RaidenAPI.Transfer(tokenAddr, req.Amount, req.Fee, targetAddr, common.HexToHash(req.LockSecretHash), params.MaxRequestTimeout, req.IsDirect)
+	registry, err := getRegistry(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	err = RaidenAPI.TransferOnRegistry(registry, tokenAddr, req.Amount, req.Fee, targetAddr, common.HexToHash(req.LockSecretHash), params.MaxRequestTimeout, req.IsDirect)
 	if err != nil {
 		rest.Error(w, err.Error(), http.StatusConflict)
 		return
@@ -63,17 +73,82 @@ func Transfers(w rest.ResponseWriter, r *rest.Request) {
 	}
 }
 
+//nextCursorHeader is where the cursor of the next page of history is, the body is a list of transfers like before
+const nextCursorHeader = "X-Next-Cursor"
+
+//exportPageSize is how many transfers are loaded from db at a time when exporting
+const exportPageSize = 1000
+
+/*
+getTransferFilter parses filter of history from query,
+`from_block`, `to_block`, `from_time`, `to_time`, `token`, `partner`, `channel`, `min_amount`, `max_amount`, `cursor` and `limit`, all optional.
+*/
+func getTransferFilter(r *rest.Request) (f *models.TransferFilter, err error) {
+	f = new(models.TransferFilter)
+	f.FromBlock, f.ToBlock = getFromTo(r)
+	m := r.URL.Query()
+	for _, name := range []string{"from_time", "to_time", "limit"} {
+		if len(m.Get(name)) == 0 {
+			continue
+		}
+		var n int64
+		n, err = strconv.ParseInt(m.Get(name), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s %s", name, m.Get(name))
+		}
+		switch name {
+		case "from_time":
+			f.FromTime = n
+		case "to_time":
+			f.ToTime = n
+		case "limit":
+			f.Limit = int(n)
+		}
+	}
+	for name, addr := range map[string]*common.Address{"token": &f.Token, "partner": &f.Partner} {
+		if len(m.Get(name)) == 0 {
+			continue
+		}
+		if !common.IsHexAddress(m.Get(name)) {
+			return nil, fmt.Errorf("invalid %s %s", name, m.Get(name))
+		}
+		*addr = common.HexToAddress(m.Get(name))
+	}
+	if len(m.Get("channel")) > 0 {
+		f.ChannelIdentifier = common.HexToHash(m.Get("channel"))
+	}
+	for name, amount := range map[string]**big.Int{"min_amount": &f.MinAmount, "max_amount": &f.MaxAmount} {
+		if len(m.Get(name)) == 0 {
+			continue
+		}
+		v, ok := new(big.Int).SetString(m.Get(name), 10)
+		if !ok {
+			return nil, fmt.Errorf("invalid %s %s", name, m.Get(name))
+		}
+		*amount = v
+	}
+	f.Cursor = m.Get("cursor")
+	return
+}
+
 /*
-GetSentTransfers retuns list of sent transfer between `from_block` and `to_block`
+GetSentTransfers retuns list of sent transfer selected by filter of getTransferFilter,
+ordered by block number, cursor of the next page is in header X-Next-Cursor when `limit` is reached.
 */
 func GetSentTransfers(w rest.ResponseWriter, r *rest.Request) {
-	from, to := getFromTo(r)
-	log.Trace(fmt.Sprintf("from=%d,to=%d\n", from, to))
-	trs, err := RaidenAPI.GetSentTransfers(from, to)
+	f, err := getTransferFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trs, next, err := RaidenAPI.FindSentTransfers(f)
 	if err != nil {
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if len(next) > 0 {
+		w.Header().Set(nextCursorHeader, next)
+	}
 	err = w.WriteJson(trs)
 	if err != nil {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
@@ -81,18 +156,140 @@ func GetSentTransfers(w rest.ResponseWriter, r *rest.Request) {
 }
 
 /*
-GetReceivedTransfers retuns list of received transfer between `from_block` and `to_block`
-it contains token swap
+GetReceivedTransfers retuns list of received transfer selected by filter of getTransferFilter,
+it contains token swap, cursor of the next page is in header X-Next-Cursor when `limit` is reached.
 */
 func GetReceivedTransfers(w rest.ResponseWriter, r *rest.Request) {
-	from, to := getFromTo(r)
-	trs, err := RaidenAPI.GetReceivedTransfers(from, to)
+	f, err := getTransferFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	trs, next, err := RaidenAPI.FindReceivedTransfers(f)
 	if err != nil {
 		rest.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
+	if len(next) > 0 {
+		w.Header().Set(nextCursorHeader, next)
+	}
 	err = w.WriteJson(trs)
 	if err != nil {
 		log.Warn(fmt.Sprintf("writejson err %s", err))
 	}
 }
+
+//transferTime formats unix time of a transfer for csv, empty if it's not recorded
+func transferTime(t int64) string {
+	if t == 0 {
+		return ""
+	}
+	return time.Unix(t, 0).UTC().Format(time.RFC3339)
+}
+
+//transferAmount formats amount of a transfer for csv, 0 if it's not recorded
+func transferAmount(a *big.Int) string {
+	if a == nil {
+		return "0"
+	}
+	return a.String()
+}
+
+/*
+transferPage loads the page after `f.Cursor`,
+and returns its transfers as csv rows and as values for json, and the cursor of the next page.
+*/
+type transferPage func(f *models.TransferFilter) (rows [][]string, values []interface{}, next string, err error)
+
+/*
+exportTransfers writes every transfer selected by filter of getTransferFilter, as csv if `format` is csv, otherwise as a json list.
+transfers are loaded page by page, `cursor` and `limit` of the query are ignored.
+*/
+func exportTransfers(w rest.ResponseWriter, r *rest.Request, name string, header []string, page transferPage) {
+	f, err := getTransferFilter(r)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.Cursor = ""
+	f.Limit = exportPageSize
+	isCSV := r.URL.Query().Get("format") == "csv"
+	rows, values, next, err := page(f)
+	if err != nil {
+		rest.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	hw := w.(http.ResponseWriter)
+	var cw *csv.Writer
+	if isCSV {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.csv", name))
+		cw = csv.NewWriter(hw)
+		err = cw.Write(header)
+	} else {
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%s.json", name))
+		_, err = hw.Write([]byte("["))
+	}
+	count := 0
+	for err == nil {
+		if isCSV {
+			err = cw.WriteAll(rows)
+		} else {
+			for _, v := range values {
+				var data []byte
+				data, err = json.Marshal(v)
+				if err != nil {
+					break
+				}
+				if count > 0 {
+					data = append([]byte(","), data...)
+				}
+				_, err = hw.Write(data)
+				if err != nil {
+					break
+				}
+				count++
+			}
+		}
+		if err != nil || len(next) == 0 {
+			break
+		}
+		f.Cursor = next
+		rows, values, next, err = page(f)
+	}
+	if err == nil && !isCSV {
+		_, err = hw.Write([]byte("]"))
+	}
+	if err != nil {
+		//status is sent already
+		log.Warn(fmt.Sprintf("export %s err %s", name, err))
+	}
+}
+
+//ExportSentTransfers writes all sent transfers selected by filter as csv or json
+func ExportSentTransfers(w rest.ResponseWriter, r *rest.Request) {
+	header := []string{"key", "block_number", "time", "channel_identifier", "token_address", "to_address", "nonce", "amount"}
+	exportTransfers(w, r, "sent_transfers", header, func(f *models.TransferFilter) (rows [][]string, values []interface{}, next string, err error) {
+		trs, next, err := RaidenAPI.FindSentTransfers(f)
+		for _, t := range trs {
+			rows = append(rows, []string{t.Key, strconv.FormatInt(t.BlockNumber, 10), transferTime(t.Time), t.ChannelIdentifier.String(),
+				t.TokenAddress.String(), t.ToAddress.String(), strconv.FormatInt(t.Nonce, 10), transferAmount(t.Amount)})
+			values = append(values, t)
+		}
+		return
+	})
+}
+
+//ExportReceivedTransfers writes all received transfers selected by filter as csv or json
+func ExportReceivedTransfers(w rest.ResponseWriter, r *rest.Request) {
+	header := []string{"key", "block_number", "time", "channel_identifier", "token_address", "from_address", "nonce", "amount"}
+	exportTransfers(w, r, "received_transfers", header, func(f *models.TransferFilter) (rows [][]string, values []interface{}, next string, err error) {
+		trs, next, err := RaidenAPI.FindReceivedTransfers(f)
+		for _, t := range trs {
+			rows = append(rows, []string{t.Key, strconv.FormatInt(t.BlockNumber, 10), transferTime(t.Time), t.ChannelIdentifier.String(),
+				t.TokenAddress.String(), t.FromAddress.String(), strconv.FormatInt(t.Nonce, 10), transferAmount(t.Amount)})
+			values = append(values, t)
+		}
+		return
+	})
+}
@@ -0,0 +1,86 @@
+package smartraiden
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/channel/channeltype"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc/contracts"
+	"github.com/SmartMeshFoundation/SmartRaiden/transfer"
+)
+
+/*
+checkRestoredChannels compares every channel of a db restored from backup with `GetChannelInfo` and `GetChannelParticipantInfo` on chain.
+deposits are taken from chain,
+channels closed or settled after backup are left to the contract events replayed from the block number in backup,
+but a balance proof nonce on chain larger than ours means the backup misses transfers,
+using it may send balance proofs partner has already seen or close channel with an old one, so refuse to start.
+*/
+func (rs *RaidenService) checkRestoredChannels() error {
+	if !rs.db.IsRestored() {
+		return nil
+	}
+	log.Info("db is restored from backup, check channels on chain")
+	var errs []string
+	for _, g := range rs.RegistryToken2ChannelGraph {
+		for _, ch := range g.ChannelAddress2Channel {
+			err := rs.checkRestoredChannel(ch)
+			if err != nil {
+				errs = append(errs, err.Error())
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("restored db doesn't match blockchain, please restore a newer backup:\n%s", strings.Join(errs, "\n"))
+	}
+	return rs.db.MarkRestoreChecked()
+}
+
+func (rs *RaidenService) checkRestoredChannel(ch *channel.Channel) error {
+	tokenNetwork := ch.ExternState.TokenNetwork
+	partner := ch.PartnerState.Address
+	id, _, openBlockNumber, state, _, err := tokenNetwork.GetChannelInfo(rs.NodeAddress, partner)
+	if err != nil {
+		return fmt.Errorf("channel %s GetChannelInfo err %s", ch.ChannelIdentifier.String(), err)
+	}
+	if state == contracts.ChannelStateSettledOrNotExist || id != ch.ChannelIdentifier.ChannelIdentifier ||
+		int64(openBlockNumber) != ch.ChannelIdentifier.OpenBlockNumber {
+		log.Warn(fmt.Sprintf("channel %s is settled on chain after backup", ch.ChannelIdentifier.String()))
+		return nil
+	}
+	if state == contracts.ChannelStateClosed && ch.State == channeltype.StateOpened {
+		log.Warn(fmt.Sprintf("channel %s is closed on chain after backup", ch.ChannelIdentifier.String()))
+	}
+	ourDeposit, _, ourNonce, err := tokenNetwork.GetChannelParticipantInfo(rs.NodeAddress, partner)
+	if err != nil {
+		return fmt.Errorf("channel %s GetChannelParticipantInfo err %s", ch.ChannelIdentifier.String(), err)
+	}
+	partnerDeposit, _, partnerNonce, err := tokenNetwork.GetChannelParticipantInfo(partner, rs.NodeAddress)
+	if err != nil {
+		return fmt.Errorf("channel %s GetChannelParticipantInfo err %s", ch.ChannelIdentifier.String(), err)
+	}
+	if int64(ourNonce) > balanceProofNonce(ch.OurState.BalanceProofState) {
+		return fmt.Errorf("channel %s our nonce on chain=%d,in db=%d", ch.ChannelIdentifier.String(), ourNonce, balanceProofNonce(ch.OurState.BalanceProofState))
+	}
+	if int64(partnerNonce) > balanceProofNonce(ch.PartnerState.BalanceProofState) {
+		return fmt.Errorf("channel %s partner nonce on chain=%d,in db=%d", ch.ChannelIdentifier.String(), partnerNonce, balanceProofNonce(ch.PartnerState.BalanceProofState))
+	}
+	if ourDeposit.Cmp(ch.OurState.ContractBalance) != 0 || partnerDeposit.Cmp(ch.PartnerState.ContractBalance) != 0 {
+		log.Warn(fmt.Sprintf("channel %s deposit in db is %s-%s, on chain is %s-%s, use deposit on chain", ch.ChannelIdentifier.String(),
+			ch.OurState.ContractBalance, ch.PartnerState.ContractBalance, ourDeposit, partnerDeposit))
+		ch.OurState.ContractBalance = ourDeposit
+		ch.PartnerState.ContractBalance = partnerDeposit
+		return rs.db.UpdateChannelContractBalance(channel.NewChannelSerialization(ch))
+	}
+	log.Trace(fmt.Sprintf("restored channel %s matches chain, our balance=%s", ch.ChannelIdentifier.String(), ch.Balance()))
+	return nil
+}
+
+func balanceProofNonce(bp *transfer.BalanceProofState) int64 {
+	if bp == nil {
+		return 0
+	}
+	return bp.Nonce
+}
@@ -0,0 +1,164 @@
+package smartraiden
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/channel"
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+settleScheduler settles closed channels once the settle window expires.
+every closed channel has a settle job saved in db, so channels closed before restart or when offline are settled too.
+settle transactions are sent without blocking the main loop, and retried with backoff when failed.
+a job is removed only when the settled event is received.
+*/
+type settleScheduler struct {
+	raiden  *RaidenService
+	lock    sync.Mutex
+	jobs    map[common.Hash]*models.SettleJob
+	running map[common.Hash]bool
+	reading map[*rpc.TokenNetworkProxy]bool //punish blocks of these token networks are being read
+}
+
+func newSettleScheduler(raiden *RaidenService) *settleScheduler {
+	return &settleScheduler{
+		raiden:  raiden,
+		jobs:    make(map[common.Hash]*models.SettleJob),
+		running: make(map[common.Hash]bool),
+		reading: make(map[*rpc.TokenNetworkProxy]bool),
+	}
+}
+
+/*
+settleBlock returns the first block settle can be mined in, ok is false if punish blocks are not read yet.
+partner may punish my obsolete unlock during the punish blocks after settle timeout, settle is refused by contract until then.
+punish blocks are read from the token network contract once out of the main loop, caller must hold the lock.
+*/
+func (s *settleScheduler) settleBlock(c *channel.Channel) (block int64, ok bool) {
+	tokenNetwork := c.ExternState.TokenNetwork
+	punish, ok := tokenNetwork.CachedPunishBlockNumber()
+	if !ok {
+		if !s.reading[tokenNetwork] {
+			s.reading[tokenNetwork] = true
+			go s.readPunishBlockNumber(tokenNetwork)
+		}
+		return
+	}
+	return c.ExternState.ClosedBlock + int64(c.SettleTimeout) + int64(punish), true
+}
+
+func (s *settleScheduler) readPunishBlockNumber(tokenNetwork *rpc.TokenNetworkProxy) {
+	_, err := tokenNetwork.PunishBlockNumber()
+	if err != nil {
+		//try again on next block
+		log.Warn(fmt.Sprintf("get punish block number of %s err %s", utils.APex(tokenNetwork.Address), err))
+	}
+	s.lock.Lock()
+	delete(s.reading, tokenNetwork)
+	s.lock.Unlock()
+}
+
+//retryBlocks how many blocks to wait before the next try after `tries` failures
+func retryBlocks(tries int) int64 {
+	if tries > 6 {
+		return params.SettleRetryMaxBlocks
+	}
+	n := int64(1) << uint(tries)
+	if n > params.SettleRetryMaxBlocks {
+		n = params.SettleRetryMaxBlocks
+	}
+	return n
+}
+
+//getJob caller must hold the lock
+func (s *settleScheduler) getJob(c *channel.Channel) *models.SettleJob {
+	id := c.ChannelIdentifier.ChannelIdentifier
+	j, ok := s.jobs[id]
+	if ok {
+		return j
+	}
+	j, err := s.raiden.db.GetSettleJob(id)
+	if err != nil {
+		block, ok := s.settleBlock(c)
+		if !ok {
+			//try again on next block
+			return nil
+		}
+		j = models.NewSettleJob(id, c.TokenAddress, c.PartnerState.Address, block)
+		log.Info(fmt.Sprintf("channel %s closed, will settle at block %d", utils.HPex(id), j.SettleBlock))
+		err = s.raiden.db.SaveSettleJob(j)
+		if err != nil {
+			return nil
+		}
+	}
+	s.jobs[id] = j
+	return j
+}
+
+/*
+schedule is called on every new block for closed channel `c`,
+send settle transaction when it's time. must run in the main loop, because channel is read.
+*/
+func (s *settleScheduler) schedule(c *channel.Channel, blockNumber int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	j := s.getJob(c)
+	if j == nil {
+		return
+	}
+	id := j.ChannelIdentifier
+	if blockNumber < j.SettleBlock || blockNumber < j.NextTryBlock || s.running[id] {
+		return
+	}
+	s.running[id] = true
+	log.Info(fmt.Sprintf("auto settle channel %s, tries=%d", utils.HPex(id), j.Tries))
+	result := c.Settle()
+	go func() {
+		err := <-result.Result
+		s.onSettleResult(id, blockNumber, err)
+	}()
+}
+
+func (s *settleScheduler) onSettleResult(id common.Hash, blockNumber int64, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.running, id)
+	j, ok := s.jobs[id]
+	if !ok {
+		//settled event already received
+		return
+	}
+	if err != nil {
+		j.Tries++
+		j.LastError = err.Error()
+		j.NextTryBlock = blockNumber + retryBlocks(j.Tries)
+		log.Warn(fmt.Sprintf("auto settle channel %s err %s, retry at block %d", utils.HPex(id), err, j.NextTryBlock))
+	} else {
+		//wait for the settled event, don't send again unless it's lost
+		j.LastError = ""
+		j.NextTryBlock = blockNumber + params.SettleRetryMaxBlocks
+		log.Info(fmt.Sprintf("auto settle channel %s success", utils.HPex(id)))
+	}
+	err = s.raiden.db.SaveSettleJob(j)
+	if err != nil {
+		log.Error(fmt.Sprintf("save settle job of %s err %s", utils.HPex(id), err))
+	}
+}
+
+//remove settle job of a settled channel
+func (s *settleScheduler) remove(id common.Hash) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.jobs, id)
+	err := s.raiden.db.RemoveSettleJob(id)
+	if err != nil {
+		log.Error(fmt.Sprintf("RemoveSettleJob %s err %s", utils.HPex(id), err))
+	}
+}
@@ -0,0 +1,56 @@
+package smartraiden
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/models"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+)
+
+func TestRetryBlocks(t *testing.T) {
+	assert(t, int64(2), retryBlocks(1))
+	assert(t, int64(32), retryBlocks(5))
+	assert(t, int64(params.SettleRetryMaxBlocks), retryBlocks(7))
+	assert(t, int64(params.SettleRetryMaxBlocks), retryBlocks(1000))
+}
+
+func TestSettleSchedulerResult(t *testing.T) {
+	dbPath := path.Join(os.TempDir(), "testsettlescheduler.db")
+	os.Remove(dbPath)
+	os.Remove(dbPath + ".lock")
+	db, err := models.OpenDb(dbPath)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.CloseDB()
+	s := newSettleScheduler(&RaidenService{db: db})
+	id := utils.NewRandomHash()
+	j := models.NewSettleJob(id, utils.NewRandomAddress(), utils.NewRandomAddress(), 90)
+	s.jobs[id] = j
+	s.running[id] = true
+	s.onSettleResult(id, 100, errors.New("gas too low"))
+	assert(t, false, s.running[id])
+	j2, err := db.GetSettleJob(id)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	assert(t, 1, j2.Tries)
+	assert(t, int64(102), j2.NextTryBlock)
+	assert(t, "gas too low", j2.LastError)
+	s.onSettleResult(id, 102, nil)
+	j2, _ = db.GetSettleJob(id)
+	assert(t, int64(102+params.SettleRetryMaxBlocks), j2.NextTryBlock)
+	s.remove(id)
+	_, err = db.GetSettleJob(id)
+	assert(t, true, err != nil)
+	//result after settled event is ignored
+	s.onSettleResult(id, 103, nil)
+	_, err = db.GetSettleJob(id)
+	assert(t, true, err != nil)
+}
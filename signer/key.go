@@ -0,0 +1,49 @@
+package signer
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+/*
+KeySigner signs with a private key in memory
+*/
+type KeySigner struct {
+	key  *ecdsa.PrivateKey
+	addr common.Address
+}
+
+//NewKeySigner create a signer of private key `key`
+func NewKeySigner(key *ecdsa.PrivateKey) *KeySigner {
+	return &KeySigner{
+		key:  key,
+		addr: crypto.PubkeyToAddress(key.PublicKey),
+	}
+}
+
+//Address of the key
+func (s *KeySigner) Address() common.Address {
+	return s.addr
+}
+
+//SignData signs keccak256(data) in ethereum format
+func (s *KeySigner) SignData(data []byte) (sig []byte, err error) {
+	return utils.SignData(s.key, data)
+}
+
+//SignTx signs transaction
+func (s *KeySigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return types.SignTx(tx, txSigner(chainID), s.key)
+}
+
+func txSigner(chainID *big.Int) types.Signer {
+	if chainID != nil {
+		return types.NewEIP155Signer(chainID)
+	}
+	return types.HomesteadSigner{}
+}
@@ -0,0 +1,59 @@
+package signer
+
+import (
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+/*
+KeystoreSigner signs with an account unlocked in ethereum keystore directory,
+the decrypted key is kept by keystore and never handed to raiden.
+*/
+type KeystoreSigner struct {
+	ks      *keystore.KeyStore
+	account accounts.Account
+}
+
+//NewKeystoreSigner unlock account `addr` in keystore directory `keystorePath` by `password`
+func NewKeystoreSigner(keystorePath string, addr common.Address, password string) (s *KeystoreSigner, err error) {
+	ks := keystore.NewKeyStore(keystorePath, keystore.StandardScryptN, keystore.StandardScryptP)
+	account, err := ks.Find(accounts.Account{Address: addr})
+	if err != nil {
+		return
+	}
+	err = ks.Unlock(account, password)
+	if err != nil {
+		return
+	}
+	s = &KeystoreSigner{
+		ks:      ks,
+		account: account,
+	}
+	return
+}
+
+//Address of the account
+func (s *KeystoreSigner) Address() common.Address {
+	return s.account.Address
+}
+
+//SignData signs keccak256(data) in ethereum format
+func (s *KeystoreSigner) SignData(data []byte) (sig []byte, err error) {
+	hash := utils.Sha3(data)
+	sig, err = s.ks.SignHash(s.account, hash[:])
+	if err == nil {
+		sig[len(sig)-1] += byte(27)
+	}
+	return
+}
+
+//SignTx signs transaction
+func (s *KeystoreSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	return s.ks.SignTx(s.account, tx, chainID)
+}
+
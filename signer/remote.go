@@ -0,0 +1,89 @@
+package signer
+
+import (
+	"context"
+	"math/big"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//remoteTimeout is how long to wait for remote signer
+const remoteTimeout = 10 * time.Second
+
+/*
+RemoteSigner signs by a signer daemon speaking json-rpc over unix socket or http,
+the key never enters raiden's process.
+*/
+type RemoteSigner struct {
+	client *rpc.Client
+	addr   common.Address
+}
+
+/*
+NewRemoteSigner connect to signer daemon at `endpoint`,
+endpoint is a http url or path of unix socket.
+*/
+func NewRemoteSigner(endpoint string) (s *RemoteSigner, err error) {
+	client, err := rpc.Dial(endpoint)
+	if err != nil {
+		return
+	}
+	return newRemoteSigner(client)
+}
+
+func newRemoteSigner(client *rpc.Client) (s *RemoteSigner, err error) {
+	s = &RemoteSigner{client: client}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	err = client.CallContext(ctx, &s.addr, ServiceName+"_address")
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+	return
+}
+
+//Address of the remote signer, fetched when connected
+func (s *RemoteSigner) Address() common.Address {
+	return s.addr
+}
+
+//SignData signs keccak256(data) in ethereum format
+func (s *RemoteSigner) SignData(data []byte) (sig []byte, err error) {
+	var result hexutil.Bytes
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	err = s.client.CallContext(ctx, &result, ServiceName+"_signData", hexutil.Bytes(data))
+	return result, err
+}
+
+//SignTx signs transaction
+func (s *RemoteSigner) SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+	data, err := rlp.EncodeToBytes(tx)
+	if err != nil {
+		return nil, err
+	}
+	var result hexutil.Bytes
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	err = s.client.CallContext(ctx, &result, ServiceName+"_signTx", hexutil.Bytes(data), (*hexutil.Big)(chainID))
+	if err != nil {
+		return nil, err
+	}
+	signed := new(types.Transaction)
+	err = rlp.DecodeBytes(result, signed)
+	if err != nil {
+		return nil, err
+	}
+	return signed, nil
+}
+
+//Close the connection to signer daemon
+func (s *RemoteSigner) Close() {
+	s.client.Close()
+}
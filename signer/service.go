@@ -0,0 +1,58 @@
+package signer
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+//ServiceName is the json-rpc namespace of signer, methods are signer_address, signer_signData and signer_signTx
+const ServiceName = "signer"
+
+/*
+Service exports a Signer by json-rpc, used by signer daemon.
+*/
+type Service struct {
+	s Signer
+}
+
+//NewService create json-rpc service of `s`
+func NewService(s Signer) *Service {
+	return &Service{s: s}
+}
+
+//Address of the signer
+func (srv *Service) Address() common.Address {
+	return srv.s.Address()
+}
+
+//SignData signs keccak256(data) in ethereum format
+func (srv *Service) SignData(data hexutil.Bytes) (hexutil.Bytes, error) {
+	return srv.s.SignData(data)
+}
+
+//SignTx signs rlp encoded transaction `tx`, returns rlp encoded signed transaction
+func (srv *Service) SignTx(tx hexutil.Bytes, chainID *hexutil.Big) (hexutil.Bytes, error) {
+	t := new(types.Transaction)
+	err := rlp.DecodeBytes(tx, t)
+	if err != nil {
+		return nil, err
+	}
+	t, err = srv.s.SignTx(t, chainID.ToInt())
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(t)
+}
+
+//NewServer create a json-rpc server which serves `s`
+func NewServer(s Signer) (*rpc.Server, error) {
+	server := rpc.NewServer()
+	err := server.RegisterName(ServiceName, NewService(s))
+	if err != nil {
+		return nil, err
+	}
+	return server, nil
+}
@@ -0,0 +1,48 @@
+/*
+Package signer signs messages and transactions of this node,
+so the node key can live in a keystore or another process instead of raiden's memory.
+*/
+package signer
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+var errNotAuthorized = errors.New("not authorized to sign this account")
+
+/*
+Signer signs for the node address
+*/
+type Signer interface {
+	//Address of the key
+	Address() common.Address
+	/*
+		SignData signs keccak256(data) in ethereum format, the last byte of signature is 27 or 28,
+		the same as utils.SignData
+	*/
+	SignData(data []byte) (sig []byte, err error)
+	//SignTx signs transaction, EIP155 when chainID is not nil, otherwise homestead
+	SignTx(tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
+}
+
+/*
+NewTransactor create a TransactOpts which signs transactions by `s`,
+bind always signs with homestead signer, so chainID is nil.
+*/
+func NewTransactor(s Signer) *bind.TransactOpts {
+	addr := s.Address()
+	return &bind.TransactOpts{
+		From: addr,
+		Signer: func(signer types.Signer, address common.Address, tx *types.Transaction) (*types.Transaction, error) {
+			if address != addr {
+				return nil, errNotAuthorized
+			}
+			return s.SignTx(tx, nil)
+		},
+	}
+}
@@ -0,0 +1,139 @@
+package signer
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/accounts/keystore"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func testSigner(t *testing.T, s Signer, addr common.Address) {
+	data := []byte("hello raiden")
+	sig, err := s.SignData(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := utils.Ecrecover(utils.Sha3(data), sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer != addr || s.Address() != addr {
+		t.Errorf("signer=%s,address=%s,expect=%s", signer.String(), s.Address().String(), addr.String())
+	}
+	tx := types.NewTransaction(1, utils.NewRandomAddress(), big.NewInt(10), 21000, big.NewInt(1), nil)
+	for _, chainID := range []*big.Int{nil, big.NewInt(8888)} {
+		signed, err := s.SignTx(tx, chainID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sender, err := types.Sender(txSigner(chainID), signed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if sender != addr {
+			t.Errorf("tx sender=%s,expect=%s", sender.String(), addr.String())
+		}
+	}
+	opts := NewTransactor(s)
+	signed, err := opts.Signer(types.HomesteadSigner{}, addr, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sender, err := types.Sender(types.HomesteadSigner{}, signed)
+	if err != nil || sender != addr {
+		t.Errorf("transactor sender=%s,err=%v", sender.String(), err)
+	}
+	_, err = opts.Signer(types.HomesteadSigner{}, utils.NewRandomAddress(), tx)
+	if err == nil {
+		t.Error("should not sign for other address")
+	}
+}
+
+func TestKeySigner(t *testing.T) {
+	key, addr := utils.MakePrivateKeyAddress()
+	s := NewKeySigner(key)
+	testSigner(t, s, addr)
+	sig, err := utils.SignData(key, []byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig2, err := s.SignData([]byte("abc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(sig) != string(sig2) {
+		t.Error("should be the same as utils.SignData")
+	}
+}
+
+func TestKeystoreSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keystore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ks := keystore.NewKeyStore(dir, keystore.LightScryptN, keystore.LightScryptP)
+	account, err := ks.NewAccount("123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = NewKeystoreSigner(dir, account.Address, "wrong")
+	if err == nil {
+		t.Error("should fail with wrong password")
+	}
+	s, err := NewKeystoreSigner(dir, account.Address, "123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	testSigner(t, s, account.Address)
+}
+
+func TestRemoteSigner(t *testing.T) {
+	key, addr := utils.MakePrivateKeyAddress()
+	server, err := NewServer(NewKeySigner(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	s, err := newRemoteSigner(rpc.DialInProc(server))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testSigner(t, s, addr)
+}
+
+func TestRemoteSignerIPC(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	key, _ := crypto.GenerateKey()
+	server, err := NewServer(NewKeySigner(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Stop()
+	endpoint := filepath.Join(dir, "signer.ipc")
+	l, err := rpc.CreateIPCListener(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go server.ServeListener(l)
+	s, err := NewRemoteSigner(endpoint)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	testSigner(t, s, crypto.PubkeyToAddress(key.PublicKey))
+}
@@ -108,6 +108,8 @@ type StateManager struct {
 	ManagerState        string      `storm:"index"` //state for initiator and target ,distingush operation from crash
 	Identifier          common.Hash //transfer identifier
 	TokenAddress        common.Address
+	//RegistryAddress is registry of token network of this transfer, it's empty if saved before a token can be registered on more than one registry
+	RegistryAddress     common.Address
 	ChannelAddress      common.Hash //channel address from initiator A-B-C channel A-B
 	ChannelAddressTo    common.Hash //mediated transfer will send to. A-B-C channel B-C
 	ChannelAddresRefund common.Hash //node received a refund transfer, should save and forget.
@@ -172,6 +174,10 @@ func (sm *StateManager) Dispatch(stateChange StateChange) (events []Event) {
 	*/
 	transitionResult := sm.FuncStateTransition(sm.CurrentState, stateChange)
 	sm.CurrentState, events = transitionResult.NewState, transitionResult.Events
+	//initiator, mediator and target all clear their state when the transfer succeeds or is cancelled
+	if sm.CurrentState == nil {
+		sm.ManagerState = StateManagerTransferComplete
+	}
 	return
 }
 
@@ -139,7 +139,8 @@ EventContractSendRegisterSecret emitted to register a secret on chain
     on-chain.
 */
 type EventContractSendRegisterSecret struct {
-	Secret common.Hash
+	Secret         common.Hash
+	LockExpiration int64 //registration must be mined before the lock expires
 }
 
 /*
@@ -173,6 +174,7 @@ type EventUnlockFailed struct {
 //EventWithdrawSuccess emitted when a lock withdraw succeded.
 type EventWithdrawSuccess struct {
 	LockSecretHash common.Hash
+	Fee            *big.Int //fee earned by mediator, nil for target
 }
 
 /*
@@ -137,6 +137,7 @@ func TestInitWithoutRoutes(t *testing.T) {
 
 	assert(t, len(events), 2)
 	assert(t, initiatorStateMachine.CurrentState, nil)
+	assert(t, initiatorStateMachine.ManagerState, transfer.StateManagerTransferComplete)
 	_, ok := events[0].(*transfer.EventTransferSentFailed)
 	assert(t, ok, true)
 }
@@ -285,6 +286,43 @@ func TestRefundTransferNextRoute(t *testing.T) {
 	assert(t, sm.CurrentState != nil, true)
 	//assert(t, currentState.Routes.CanceledRoutes[0], priorState.Route)
 }
+func TestRefundTransferAvoidFailedPath(t *testing.T) {
+	amount := utest.UnitTransferAmount
+	ourAddress := utest.ADDR
+	targetAddress := utils.NewRandomAddress()
+	token := utest.UnitTokenAddress
+	makePath := func(nodes ...common.Address) []*route.Path {
+		return []*route.Path{{Nodes: append([]common.Address{ourAddress}, append(nodes, targetAddress)...)}}
+	}
+	routes := []*route.State{
+		utest.MakeRoute(utest.HOP1, amount, utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash()),
+		utest.MakeRoute(utest.HOP2, amount, utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash()),
+		utest.MakeRoute(utest.HOP4, amount, utest.UnitSettleTimeout, utest.UnitRevealTimeout, 0, utils.NewRandomHash()),
+	}
+	routes[0].Paths = makePath(utest.HOP1, utest.HOP3)
+	routes[1].Paths = makePath(utest.HOP2, utest.HOP3)
+	routes[2].Paths = makePath(utest.HOP4, utest.HOP5)
+	currentState := makeInitiatorState(routes, targetAddress, amount, utest.UnitBlockNumber, ourAddress, token)
+	assert(t, currentState.Route.HopNode(), utest.HOP1)
+	stateChange := &mediatedtransfer.ReceiveAnnounceDisposedStateChange{
+		Sender: utest.HOP1,
+		Token:  token,
+		Lock: &mtree.Lock{
+			Expiration:     currentState.Transfer.Expiration,
+			LockSecretHash: currentState.LockSecretHash,
+			Amount:         amount,
+		},
+	}
+	it := StateTransition(currentState, stateChange)
+	state := it.NewState.(*mediatedtransfer.InitiatorState)
+	//HOP3 to target failed, HOP2 would go through it again
+	assert(t, state.Route.HopNode(), utest.HOP4)
+
+	//timeout of HOP4, the whole path failed, HOP2 is the last
+	it = StateTransition(state, &mediatedtransfer.ActionCancelRouteStateChange{LockSecretHash: state.LockSecretHash})
+	state = it.NewState.(*mediatedtransfer.InitiatorState)
+	assert(t, state.Route.HopNode(), utest.HOP2)
+}
 func TestRefundTransferNoMoreRoutes(t *testing.T) {
 	amount := utest.UnitTransferAmount
 	blockNumber := utest.UnitBlockNumber
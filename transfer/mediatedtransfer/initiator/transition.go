@@ -58,10 +58,31 @@ func userCancelTransfer(state *mt.InitiatorState) *transfer.TransitionResult {
 	}
 }
 
+/*
+penalizeCurrentRoute remembers edges of the path the current route was expected to take.
+when hop returned the transfer, the edge to hop worked, it's somewhere after hop that failed.
+mediators choose their own next hops, so we never know the path the transfer really took,
+Paths[0] is assumed because it's the best one the hop would take as we found it.
+*/
+func penalizeCurrentRoute(state *mt.InitiatorState, hopWorked bool) {
+	if state.Route == nil || len(state.Route.Paths) == 0 {
+		return
+	}
+	if state.Penalties == nil {
+		state.Penalties = make(route.Penalties)
+	}
+	edges := state.Route.Paths[0].Edges()
+	if hopWorked {
+		edges = edges[1:]
+	}
+	state.Penalties.Add(edges)
+}
+
 func tryNewRoute(state *mt.InitiatorState) *transfer.TransitionResult {
 	if state.Route != nil {
 		panic("cannot try a new route while one is being used")
 	}
+	state.Penalties.Rank(state.Routes.AvailableRoutes)
 	var tryRoute *route.State
 	for len(state.Routes.AvailableRoutes) > 0 {
 		r := state.Routes.AvailableRoutes[0]
@@ -164,6 +185,7 @@ func handleBlock(state *mt.InitiatorState, stateChange *transfer.BlockStateChang
 
 func handleRefund(state *mt.InitiatorState, stateChange *mt.ReceiveAnnounceDisposedStateChange) *transfer.TransitionResult {
 	if mediator.IsValidRefund(state.Transfer, state.Route, stateChange) {
+		penalizeCurrentRoute(state, true)
 		it := cancelCurrentRoute(state)
 		ev := &mt.EventSendAnnounceDisposedResponse{
 			LockSecretHash: stateChange.Lock.LockSecretHash,
@@ -181,6 +203,8 @@ func handleRefund(state *mt.InitiatorState, stateChange *mt.ReceiveAnnounceDispo
 
 func handleCancelRoute(state *mt.InitiatorState, stateChange *mt.ActionCancelRouteStateChange) *transfer.TransitionResult {
 	if stateChange.LockSecretHash == state.Transfer.LockSecretHash {
+		//maybe timeout, we don't know which edge failed
+		penalizeCurrentRoute(state, false)
 		return cancelCurrentRoute(state)
 	}
 	return &transfer.TransitionResult{
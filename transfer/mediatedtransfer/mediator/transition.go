@@ -553,7 +553,8 @@ func eventsForRegisterSecret(transfersPair []*mediatedtransfer.MediationPairStat
 				needRegisterSecret = true
 				pair.PayerState = mediatedtransfer.StatePayerWaitingRegisterSecret
 				registerSecretEvent := &mediatedtransfer.EventContractSendRegisterSecret{
-					Secret: pair.PayeeTransfer.Secret,
+					Secret:         pair.PayeeTransfer.Secret,
+					LockExpiration: pair.PayerTransfer.Expiration,
 				}
 				events = append(events, registerSecretEvent)
 			}
@@ -796,6 +797,7 @@ func handleBalanceProof(state *mediatedtransfer.MediatorState, st *mediatedtrans
 		if pair.PayerRoute.HopNode() == st.NodeAddress {
 			withdraw := &mediatedtransfer.EventWithdrawSuccess{
 				LockSecretHash: pair.PayeeTransfer.LockSecretHash,
+				Fee:            new(big.Int).Sub(pair.PayerTransfer.Amount, pair.PayeeTransfer.Amount),
 			}
 			events = append(events, withdraw)
 			pair.PayerState = mediatedtransfer.StatePayerBalanceProof
@@ -65,6 +65,7 @@ type InitiatorState struct {
 	SecretRequest     *encoding.SecretRequest
 	RevealSecret      *EventSendRevealSecret
 	CanceledTransfers []*EventSendMediatedTransfer
+	Penalties         route.Penalties //edges failed for this transfer, routes through them are tried last
 	Db                channeltype.Db
 }
 
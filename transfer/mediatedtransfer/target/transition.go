@@ -28,7 +28,8 @@ func eventsForRegisterSecret(state *mediatedtransfer.TargetState) (events []tran
 	if !safeToWait && secretKnown {
 		state.State = mediatedtransfer.StateWaitingRegisterSecret
 		channelClose := &mediatedtransfer.EventContractSendRegisterSecret{
-			Secret: fromTransfer.Secret,
+			Secret:         fromTransfer.Secret,
+			LockExpiration: fromTransfer.Expiration,
 		}
 		events = append(events, channelClose)
 	}
@@ -0,0 +1,85 @@
+package route
+
+import (
+	"sort"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+//Edge is a directed hop between two nodes
+type Edge struct {
+	From common.Address
+	To   common.Address
+}
+
+//Path is one of the paths to the target a route is expected to take
+type Path struct {
+	Nodes  []common.Address //from our address to the target
+	Weight int64            //fee or hops of this path when it was found
+}
+
+//Edges of this path, in order
+func (p *Path) Edges() (edges []Edge) {
+	for i := 0; i < len(p.Nodes)-1; i++ {
+		edges = append(edges, Edge{p.Nodes[i], p.Nodes[i+1]})
+	}
+	return
+}
+
+/*
+Penalties counts how many times an edge failed for one payment,
+only the initiator keeps it, and forgets it when the payment ends.
+mediators don't rank their routes by penalties, a refund only tells them their next hop failed,
+they try the next route as they always did.
+*/
+type Penalties map[Edge]int64
+
+//Add penalizes edges of a path which failed
+func (p Penalties) Add(edges []Edge) {
+	for _, e := range edges {
+		p[e]++
+	}
+}
+
+//Of returns the sum of penalties of all edges in path
+func (p Penalties) Of(path *Path) (n int64) {
+	for _, e := range path.Edges() {
+		n += p[e]
+	}
+	return
+}
+
+/*
+score of a route is the lowest penalty of all its paths, not only Paths[0],
+the hop may still take a path avoiding the failed edges, so the route isn't given up too early.
+a route which doesn't know its paths is never penalized.
+*/
+func (p Penalties) score(r *State) (penalty int64) {
+	for i, path := range r.Paths {
+		n := p.Of(path)
+		if i == 0 || n < penalty {
+			penalty = n
+		}
+	}
+	return
+}
+
+/*
+Rank reorders routes so that routes whose paths went through failed edges are tried last.
+a penalized route finds params.RoutePaths paths through its hop first, one of them may avoid the failed edges.
+routes with the same penalty keep their order, which is already by weight.
+*/
+func (p Penalties) Rank(routes []*State) {
+	if len(p) == 0 {
+		return
+	}
+	for _, r := range routes {
+		if p.score(r) > 0 {
+			r.findMorePaths(params.RoutePaths)
+		}
+	}
+	sort.SliceStable(routes, func(i, j int) bool {
+		return p.score(routes[i]) < p.score(routes[j])
+	})
+}
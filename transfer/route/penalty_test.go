@@ -0,0 +1,64 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/SmartMeshFoundation/SmartRaiden/utils"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPenalties(t *testing.T) {
+	us, hop1, hop2, hop3, target := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	path := &Path{Nodes: []common.Address{us, hop1, hop3, target}}
+	assert.EqualValues(t, []Edge{{us, hop1}, {hop1, hop3}, {hop3, target}}, path.Edges())
+	assert.Empty(t, (&Path{Nodes: []common.Address{us}}).Edges())
+
+	p := make(Penalties)
+	//hop1 returned the transfer, the edge to it worked
+	p.Add(path.Edges()[1:])
+	assert.EqualValues(t, 0, p[Edge{us, hop1}])
+	assert.EqualValues(t, 2, p.Of(path))
+	p.Add([]Edge{{hop3, target}})
+	assert.EqualValues(t, 3, p.Of(path))
+	assert.EqualValues(t, 2, p.Of(&Path{Nodes: []common.Address{us, hop2, hop3, target}}))
+
+	//the lowest penalty of all paths counts
+	r := &State{Paths: []*Path{path, {Nodes: []common.Address{us, hop1, hop2, target}}}}
+	assert.EqualValues(t, 0, p.score(r))
+	//a route without paths is never penalized
+	assert.EqualValues(t, 0, p.score(&State{}))
+}
+
+func TestPenaltiesRank(t *testing.T) {
+	us, hop1, hop2, hop3, hop4, target := utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress(), utils.NewRandomAddress()
+	r1 := &State{Paths: []*Path{{Nodes: []common.Address{us, hop1, hop3, target}}}}
+	r2 := &State{Paths: []*Path{{Nodes: []common.Address{us, hop2, hop3, target}}}}
+	r3 := &State{Paths: []*Path{{Nodes: []common.Address{us, hop4, target}}}}
+	r4 := &State{}
+	routes := []*State{r1, r2, r3, r4}
+
+	var p Penalties
+	p.Rank(routes)
+	assert.EqualValues(t, []*State{r1, r2, r3, r4}, routes)
+
+	p = make(Penalties)
+	p.Add([]Edge{{hop3, target}})
+	p.Rank(routes)
+	//routes with the same penalty keep their order
+	assert.EqualValues(t, []*State{r3, r4, r1, r2}, routes)
+
+	//a penalized route finds more paths, one of them avoids the failed edge
+	calls := 0
+	r2.SetMorePaths(func(k int) []*Path {
+		calls++
+		assert.EqualValues(t, params.RoutePaths, k)
+		return []*Path{r2.Paths[0], {Nodes: []common.Address{us, hop2, hop1, target}}}
+	})
+	p.Rank(routes)
+	assert.EqualValues(t, []*State{r3, r4, r2, r1}, routes)
+	assert.Len(t, r2.Paths, 2)
+	p.Rank(routes)
+	assert.EqualValues(t, 1, calls)
+}
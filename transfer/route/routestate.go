@@ -16,11 +16,13 @@ State describes a route state
 路由状态我如何收到的或者发送MediatedTransfer
 */
 type State struct {
-	ch                *channel.Channel //don't save pointer
-	ChannelIdentifier common.Hash      //崩溃恢复的时候需要
-	IsSend            bool             //用这个 route 来发送还是接收?
-	Fee               *big.Int         // how much fee to this channel charge charge .
-	TotalFee          *big.Int         // how much fee for all path when initiator use this route
+	ch                *channel.Channel    //don't save pointer
+	ChannelIdentifier common.Hash         //崩溃恢复的时候需要
+	IsSend            bool                //用这个 route 来发送还是接收?
+	Fee               *big.Int            // how much fee to this channel charge charge .
+	TotalFee          *big.Int            // how much fee for all path when initiator use this route
+	Paths             []*Path             //shortest paths to the target through this hop, best first
+	morePaths         func(k int) []*Path //finds k shortest paths through this hop, only the best one is found at first
 }
 
 //NewState create route state
@@ -41,6 +43,27 @@ func (rs *State) CanContinueTransfer() bool {
 	return rs.ch.CanContinueTransfer()
 }
 
+/*
+SetMorePaths sets how to find more paths through this hop, it's called only when Paths are penalized,
+finding k shortest paths of every hop is too slow for every transfer.
+it's lost after restart, the route keeps paths it has then.
+*/
+func (rs *State) SetMorePaths(f func(k int) []*Path) {
+	rs.morePaths = f
+}
+
+//findMorePaths replaces Paths by k shortest paths through this hop, only once
+func (rs *State) findMorePaths(k int) {
+	if rs.morePaths == nil {
+		return
+	}
+	f := rs.morePaths
+	rs.morePaths = nil
+	if paths := f(k); len(paths) > 0 {
+		rs.Paths = paths
+	}
+}
+
 //SettleTimeout settle timeout of this channel
 func (rs *State) SettleTimeout() int {
 	return rs.ch.SettleTimeout
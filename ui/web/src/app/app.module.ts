@@ -22,6 +22,7 @@ import { TransferDialogComponent } from './components/transfer-dialog/transfer-d
 import { JoinDialogComponent } from './components/join-dialog/join-dialog.component';
 import { RegisterDialogComponent } from './components/register-dialog/register-dialog.component';
 import { OpenDialogComponent } from './components/open-dialog/open-dialog.component';
+import { ChannelGraphComponent } from './components/channel-graph/channel-graph.component';
 
 import { SharedService } from './services/shared.service';
 import { SmartRaidenInterceptor } from './services/smartraiden.interceptor';
@@ -36,6 +37,7 @@ const appRoutes: Routes = [
     { path: 'home', component: HomeComponent },
     { path: 'tokens', component: TokenNetworkComponent },
     { path: 'channels', component: ChannelTableComponent },
+    { path: 'graph', component: ChannelGraphComponent },
 ];
 
 export function ConfigLoader(smartraidenConfig: SmartRaidenConfig) {
@@ -55,6 +57,7 @@ export function ConfigLoader(smartraidenConfig: SmartRaidenConfig) {
         JoinDialogComponent,
         RegisterDialogComponent,
         OpenDialogComponent,
+        ChannelGraphComponent,
         KeysPipe,
         SubsetPipe,
         TokenPipe,
@@ -0,0 +1,283 @@
+import { Component, OnInit, OnDestroy } from '@angular/core';
+import { ActivatedRoute, Router } from '@angular/router';
+import { Subscription } from 'rxjs/Subscription';
+import { SelectItem } from 'primeng/primeng';
+
+import { SmartRaidenService } from '../../services/smartraiden.service';
+import { ChannelGraph, GraphNode, GraphEdge } from '../../models/graph';
+
+interface Point {
+    node: GraphNode;
+    x: number;
+    y: number;
+    vx: number;
+    vy: number;
+}
+
+interface Link {
+    edge: GraphEdge;
+    reverse?: GraphEdge;
+    source: Point;
+    target: Point;
+}
+
+const WIDTH = 800;
+const HEIGHT = 600;
+
+@Component({
+    selector: 'app-channel-graph',
+    templateUrl: './channel-graph.component.html',
+    styleUrls: ['./channel-graph.component.css']
+})
+export class ChannelGraphComponent implements OnInit, OnDestroy {
+
+    public readonly width = WIDTH;
+    public readonly height = HEIGHT;
+
+    public tokens: SelectItem[] = [];
+    public token: string;
+    public registry: string;
+    public graph: ChannelGraph;
+    public points: Point[] = [];
+    public links: Link[] = [];
+    public selected: Point;
+    public refreshing = false;
+
+    private articulation: { [address: string]: boolean } = {};
+    private dragging: Point;
+    private subs: Subscription[] = [];
+
+    constructor(private smartraidenService: SmartRaidenService,
+        private route: ActivatedRoute,
+        private router: Router) { }
+
+    ngOnInit() {
+        this.subs.push(this.smartraidenService.getTokens()
+            .subscribe((userTokens) => {
+                // the same token on another registry is another graph
+                const manyRegistries = userTokens.some((userToken) => userToken.registry !== userTokens[0].registry);
+                this.tokens = userTokens.map((userToken) => ({
+                    label: `[${userToken.symbol}] ${userToken.address}` +
+                        (manyRegistries ? ` @ ${userToken.registry}` : ''),
+                    value: this.selectionOf(userToken.address, userToken.registry),
+                }));
+                if (!this.token && this.tokens.length > 0) {
+                    this.selectToken(this.tokens[0].value);
+                }
+            }));
+        this.subs.push(this.route.queryParams
+            .subscribe((params) => {
+                const registry = params['registry'] || '';
+                if (params['token'] && (params['token'] !== this.token || registry !== this.registry)) {
+                    this.token = params['token'];
+                    this.registry = registry;
+                    this.refresh();
+                }
+            }));
+    }
+
+    ngOnDestroy() {
+        this.subs.forEach((sub) => sub.unsubscribe());
+    }
+
+    public get selection(): string {
+        return this.selectionOf(this.token, this.registry);
+    }
+
+    public selectToken(selection: string) {
+        const [registry, token] = selection.split('/');
+        this.router.navigate([], { relativeTo: this.route, queryParams: registry ? { token, registry } : { token } });
+    }
+
+    public refresh() {
+        if (!this.token) {
+            return;
+        }
+        this.refreshing = true;
+        this.smartraidenService.getChannelGraph(this.token, this.registry)
+            .finally(() => this.refreshing = false)
+            .subscribe((graph) => this.setGraph(graph));
+    }
+
+    public downloadDot() {
+        this.smartraidenService.getChannelGraphDot(this.token, this.registry)
+            .subscribe((dot) => {
+                const blob = new Blob([dot], { type: 'text/vnd.graphviz' });
+                const a = document.createElement('a');
+                a.href = URL.createObjectURL(blob);
+                a.download = `${this.token}.dot`;
+                document.body.appendChild(a);
+                a.click();
+                document.body.removeChild(a);
+                URL.revokeObjectURL(a.href);
+            });
+    }
+
+    // value of the token picker, a token and its registry
+    private selectionOf(token: string, registry?: string): string {
+        return `${registry || ''}/${token}`;
+    }
+
+    private setGraph(graph: ChannelGraph) {
+        // keep positions of nodes already shown, so a refresh doesn't shuffle the view
+        const old: { [address: string]: Point } = {};
+        for (const p of this.points) {
+            old[p.node.address] = p;
+        }
+        this.graph = graph;
+        this.articulation = {};
+        for (const address of graph.stats.articulation_points) {
+            this.articulation[address] = true;
+        }
+        const byAddress: { [address: string]: Point } = {};
+        this.points = graph.nodes.map((node, i) => {
+            const angle = 2 * Math.PI * i / graph.nodes.length;
+            const p = old[node.address];
+            byAddress[node.address] = {
+                node,
+                x: p ? p.x : WIDTH / 2 + WIDTH / 3 * Math.cos(angle),
+                y: p ? p.y : HEIGHT / 2 + HEIGHT / 3 * Math.sin(angle),
+                vx: 0,
+                vy: 0,
+            };
+            return byAddress[node.address];
+        });
+        // one link for every channel, the other direction is kept as reverse
+        const links: { [key: string]: Link } = {};
+        for (const edge of graph.edges) {
+            const source = byAddress[edge.from], target = byAddress[edge.to];
+            if (!source || !target) {
+                continue;
+            }
+            const key = edge.from < edge.to ? `${edge.from}-${edge.to}` : `${edge.to}-${edge.from}`;
+            if (links[key]) {
+                links[key].reverse = edge;
+            } else {
+                links[key] = { edge, source, target };
+            }
+        }
+        this.links = Object.keys(links).map((key) => links[key]);
+        if (this.selected) {
+            this.selected = byAddress[this.selected.node.address];
+        }
+        this.layout(Object.keys(old).length > 0 ? 100 : 300);
+    }
+
+    /**
+     * simple force directed layout: nodes repel each other, channels pull their ends together
+     */
+    private layout(iterations: number) {
+        const k = Math.sqrt(WIDTH * HEIGHT / Math.max(this.points.length, 1));
+        for (let it = 0; it < iterations; it++) {
+            const temperature = 10 * (1 - it / iterations);
+            for (const a of this.points) {
+                a.vx = a.vy = 0;
+                for (const b of this.points) {
+                    if (a === b) {
+                        continue;
+                    }
+                    const dx = a.x - b.x, dy = a.y - b.y;
+                    const d2 = Math.max(dx * dx + dy * dy, 0.01);
+                    a.vx += dx * k * k / d2 / 100;
+                    a.vy += dy * k * k / d2 / 100;
+                }
+            }
+            for (const l of this.links) {
+                const dx = l.target.x - l.source.x, dy = l.target.y - l.source.y;
+                const d = Math.max(Math.sqrt(dx * dx + dy * dy), 0.1);
+                const f = d / k;
+                l.source.vx += dx * f / 10;
+                l.source.vy += dy * f / 10;
+                l.target.vx -= dx * f / 10;
+                l.target.vy -= dy * f / 10;
+            }
+            for (const p of this.points) {
+                if (p === this.dragging) {
+                    continue;
+                }
+                // pull towards center so components don't drift apart
+                p.vx += (WIDTH / 2 - p.x) / 50;
+                p.vy += (HEIGHT / 2 - p.y) / 50;
+                const v = Math.sqrt(p.vx * p.vx + p.vy * p.vy);
+                if (v > temperature) {
+                    p.vx = p.vx / v * temperature;
+                    p.vy = p.vy / v * temperature;
+                }
+                p.x = Math.min(WIDTH - 20, Math.max(20, p.x + p.vx));
+                p.y = Math.min(HEIGHT - 20, Math.max(20, p.y + p.vy));
+            }
+        }
+    }
+
+    public startDrag(event: MouseEvent, p: Point) {
+        event.preventDefault();
+        this.dragging = p;
+        this.selected = p;
+    }
+
+    public drag(event: MouseEvent, svg: SVGSVGElement) {
+        if (!this.dragging) {
+            return;
+        }
+        const ctm = svg.getScreenCTM();
+        if (!ctm) {
+            return;
+        }
+        this.dragging.x = (event.clientX - ctm.e) / ctm.a;
+        this.dragging.y = (event.clientY - ctm.f) / ctm.d;
+    }
+
+    public endDrag() {
+        this.dragging = null;
+    }
+
+    public isArticulation(p: Point): boolean {
+        return !!this.articulation[p.node.address];
+    }
+
+    public isSelectedLink(l: Link): boolean {
+        return !!this.selected && (l.source === this.selected || l.target === this.selected);
+    }
+
+    public selectedEdges(): GraphEdge[] {
+        if (!this.selected || !this.graph) {
+            return [];
+        }
+        return this.graph.edges.filter((e) => e.from === this.selected.node.address);
+    }
+
+    public capacity(edge: GraphEdge): string {
+        if (!edge) {
+            return '';
+        }
+        if (edge.capacity !== undefined && edge.capacity !== null) {
+            return `${edge.capacity}`;
+        }
+        if (edge.capacity_max !== undefined && edge.capacity_max !== null) {
+            return `${edge.capacity_min} ~ ${edge.capacity_max}`;
+        }
+        return 'unknown';
+    }
+
+    public linkTitle(l: Link): string {
+        let title = `${l.edge.from} -> ${l.edge.to}: ${l.edge.state}, capacity ${this.capacity(l.edge)}`;
+        if (l.reverse) {
+            title += `\n${l.reverse.from} -> ${l.reverse.to}: ${l.reverse.state}, capacity ${this.capacity(l.reverse)}`;
+        }
+        return title;
+    }
+
+    public degrees(): Array<{ degree: number, count: number }> {
+        if (!this.graph) {
+            return [];
+        }
+        const dist = this.graph.stats.degree_distribution;
+        return Object.keys(dist)
+            .map((degree) => ({ degree: +degree, count: dist[degree] }))
+            .sort((a, b) => a.degree - b.degree);
+    }
+
+    public short(address: string): string {
+        return address ? address.substr(0, 8) : '';
+    }
+}
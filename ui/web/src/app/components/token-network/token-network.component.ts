@@ -1,5 +1,6 @@
 import { Component, OnInit, Input } from '@angular/core';
 import { FormControl } from '@angular/forms';
+import { Router } from '@angular/router';
 import { Observable } from 'rxjs/Observable';
 import { BehaviorSubject } from 'rxjs/BehaviorSubject';
 import { MenuItem } from 'primeng/primeng';
@@ -34,7 +35,8 @@ export class TokenNetworkComponent implements OnInit {
 
     constructor(private smartraidenService: SmartRaidenService,
         private sharedService: SharedService,
-        private confirmationService: ConfirmationService) { }
+        private confirmationService: ConfirmationService,
+        private router: Router) { }
 
     ngOnInit() {
         this.tokensBalances$ = this.tokensSubject
@@ -57,6 +59,11 @@ export class TokenNetworkComponent implements OnInit {
                 icon: 'fa-sign-in',
                 command: () => this.showJoinDialog(userToken),
             },
+            {
+                label: 'View Graph',
+                icon: 'fa-share-alt',
+                command: () => this.router.navigate(['/graph'], { queryParams: { token: userToken.address } }),
+            },
             // {
             //     label: 'Leave Network',
             //     icon: 'fa-sign-out',
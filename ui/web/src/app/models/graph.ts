@@ -0,0 +1,32 @@
+export interface GraphNode {
+    address: string;
+    degree: number;
+    is_online: boolean;
+    device_type?: string;
+    is_me?: boolean;
+}
+
+export interface GraphEdge {
+    from: string;
+    to: string;
+    state: string;
+    capacity?: number;
+    capacity_min?: number;
+    capacity_max?: number;
+    is_online: boolean;
+}
+
+export interface GraphStats {
+    nodes: number;
+    channels: number;
+    diameter: number;
+    degree_distribution: { [degree: string]: number };
+    articulation_points: Array<string>;
+}
+
+export interface ChannelGraph {
+    token: string;
+    nodes: Array<GraphNode>;
+    edges: Array<GraphEdge>;
+    stats: GraphStats;
+}
@@ -5,5 +5,13 @@ export interface Usertoken {
     symbol: string;
     name: string;
     balance: number;
+    registry?: string;
     connected?: Connection;
 }
+
+// an item of /tokens, a token registered on several registries is there once for each
+export interface TokenInfo {
+    token_address: string;
+    token_network_address: string;
+    registry_address: string;
+}
@@ -32,10 +32,13 @@ export class TokenPipe implements PipeTransform {
   }
 
   tokensToSelectItems(tokens: Array<Usertoken>): Array<SelectItem> {
-    return tokens.map((token) => ({
-      value: token.address,
-      label: this.tokenToString(token)
-    }));
+    // a token registered on several registries is listed once
+    return tokens
+      .filter((token, i) => tokens.findIndex((t) => t.address === token.address) === i)
+      .map((token) => ({
+        value: token.address,
+        label: this.tokenToString(token)
+      }));
   }
 
   transform(address: string, args?: any): Observable<string> {
@@ -7,11 +7,12 @@ import { SmartRaidenConfig } from './smartraiden.config';
 import { SharedService } from './shared.service';
 import { tokenabi } from './tokenabi';
 
-import { Usertoken } from '../models/usertoken';
+import { Usertoken, TokenInfo } from '../models/usertoken';
 import { Channel } from '../models/channel';
 import { Event, EventsParam } from '../models/event';
 import { SwapToken } from '../models/swaptoken';
 import { Connection, Connections } from '../models/connection';
+import { ChannelGraph } from '../models/graph';
 
 type CallbackFunc = (error: Error, result: any) => void;
 
@@ -60,20 +61,21 @@ export class SmartRaidenService {
     }
 
     public getTokens(refresh: boolean = false): Observable<Array<Usertoken>> {
-        return this.http.get<Array<string>>(`${this.smartraidenConfig.api}/tokens`)
+        return this.http.get<Array<TokenInfo>>(`${this.smartraidenConfig.api}/tokens`)
             .combineLatest(refresh ?
                 this.http.get<Connections>(`${this.smartraidenConfig.api}/connections`) :
                 Observable.of(null))
-            .map(([tokenArray, connections]): Array<Observable<Usertoken>> =>
-                tokenArray
-                    .map((token) =>
-                        this.getUsertoken(token, refresh)
-                            .map((userToken) => userToken && connections ?
-                                Object.assign(
-                                    userToken,
-                                    { connected: connections[token] }
-                                ) : userToken
-                            )
+            .map(([tokenInfos, connections]): Array<Observable<Usertoken>> =>
+                (tokenInfos || [])
+                    .map((info) =>
+                        this.getUsertoken(info.token_address, refresh)
+                            // a copy, the cached token is shared by its registries
+                            .map((userToken) => userToken && Object.assign(
+                                {},
+                                userToken,
+                                { registry: info.registry_address },
+                                connections ? { connected: connections[info.token_address] } : {}
+                            ))
                     )
             )
             .switchMap((obsArray) => obsArray && obsArray.length ?
@@ -202,6 +204,24 @@ export class SmartRaidenService {
             .catch((error) => this.handleError(error));
     }
 
+    public getChannelGraph(tokenAddress: string, registry?: string): Observable<ChannelGraph> {
+        let params = new HttpParams();
+        if (registry) {
+            params = params.set('registry', registry);
+        }
+        return this.http.get<ChannelGraph>(`${this.smartraidenConfig.api}/graph/${tokenAddress}`, { params })
+            .catch((error) => this.handleError(error));
+    }
+
+    public getChannelGraphDot(tokenAddress: string, registry?: string): Observable<string> {
+        let params = new HttpParams().set('format', 'dot');
+        if (registry) {
+            params = params.set('registry', registry);
+        }
+        return this.http.get(`${this.smartraidenConfig.api}/graph/${tokenAddress}`, { params, responseType: 'text' })
+            .catch((error) => this.handleError(error));
+    }
+
     public sha3(data: string): string {
         return this.smartraidenConfig.web3.sha3(data, { encoding: 'hex' });
     }
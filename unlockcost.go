@@ -0,0 +1,41 @@
+package smartraiden
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/SmartMeshFoundation/SmartRaiden/log"
+	"github.com/SmartMeshFoundation/SmartRaiden/network/rpc"
+	"github.com/SmartMeshFoundation/SmartRaiden/params"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+/*
+UnlockCost implements channel.UnlockCoster.
+the gas of one unlock is priced by the GasPricer of the chain as an unlock transaction,
+and converted to token by `Config.TokenPerEther`, tokens without a configured price are always unlocked.
+the price rising as the settle block gets closer is not counted, so no lock is skipped for it.
+*/
+func (rs *RaidenService) UnlockCost(tokenNetwork common.Address) *big.Int {
+	key, ok := rs.TokenNetwork2RegistryToken[tokenNetwork]
+	if !ok {
+		return nil
+	}
+	tokenPerEther, ok := rs.Config.TokenPerEther[key.Token]
+	if !ok {
+		return nil
+	}
+	gasPrice, err := rs.Chain.GasPricer.GasPrice(rpc.UrgencyUnlock, -1)
+	if err != nil {
+		log.Warn(fmt.Sprintf("get gas price of unlock err %s, unlock all locks", err))
+		return nil
+	}
+	return unlockCost(gasPrice, tokenPerEther)
+}
+
+//unlockCost is the gas cost of one unlock in smallest unit of token
+func unlockCost(gasPrice, tokenPerEther *big.Int) *big.Int {
+	cost := new(big.Int).Mul(gasPrice, big.NewInt(params.UnlockGas))
+	cost.Mul(cost, tokenPerEther)
+	return cost.Div(cost, big.NewInt(1e18)) //wei per ether
+}
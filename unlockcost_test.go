@@ -0,0 +1,14 @@
+package smartraiden
+
+import (
+	"math/big"
+	"testing"
+)
+
+func TestUnlockCost(t *testing.T) {
+	//20 gwei * 150000 gas = 0.003 ether
+	cost := unlockCost(big.NewInt(20000000000), big.NewInt(1000))
+	assert(t, int64(3), cost.Int64())
+	cost = unlockCost(big.NewInt(20000000000), big.NewInt(1))
+	assert(t, int64(0), cost.Int64())
+}